@@ -6,6 +6,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -17,6 +18,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/shini4i/openfortivpn-gui/internal/helper/alwayson"
 	"github.com/shini4i/openfortivpn-gui/internal/helper/manager"
 	"github.com/shini4i/openfortivpn-gui/internal/helper/protocol"
 	"github.com/shini4i/openfortivpn-gui/internal/helper/server"
@@ -34,6 +36,7 @@ func main() {
 	// Parse command line flags
 	socketPath := flag.String("socket", server.DefaultSocketPath, "Path to the UNIX socket")
 	openfortivpnPath := flag.String("openfortivpn", defaultOpenfortivpnPath, "Path to openfortivpn binary")
+	alwaysOnPath := flag.String("always-on", alwayson.DefaultPath, "Path to the always-on system profile")
 	showVersion := flag.Bool("version", false, "Show version and exit")
 	flag.Parse()
 
@@ -58,13 +61,27 @@ func main() {
 	// Create thread-safe broadcaster to avoid race condition during initialization
 	broadcaster := &safeBroadcaster{}
 
+	// Load the always-on system profile, if root defined one
+	var opts []manager.Option
+	cfg, err := alwayson.Load(*alwaysOnPath)
+	switch {
+	case errors.Is(err, alwayson.ErrNotConfigured):
+	case err != nil:
+		slog.Error("Ignoring always-on profile", "path", *alwaysOnPath, "error", err)
+	default:
+		opts = append(opts, manager.WithAlwaysOn(cfg.Profile, cfg.Password))
+	}
+
 	// Create manager and server
-	mgr := manager.NewManager(*openfortivpnPath, broadcaster.Broadcast)
+	mgr := manager.NewManager(*openfortivpnPath, broadcaster.Broadcast, opts...)
 	srv := server.NewServer(*socketPath, mgr.HandleRequest)
 
 	// Now that server is created, set it in the broadcaster
 	broadcaster.SetServer(srv)
 
+	// Undo what openfortivpn left behind if the previous helper was killed, before any client connects
+	mgr.RestoreNetwork()
+
 	// Start server
 	if err := srv.Start(); err != nil {
 		slog.Error("Failed to start server", "error", err)
@@ -74,6 +91,9 @@ func main() {
 	// Notify systemd that we're ready
 	notifySystemd("READY=1")
 
+	// Bring up the always-on profile without waiting for a GUI session
+	mgr.StartAlwaysOn()
+
 	// Set up signal handling
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
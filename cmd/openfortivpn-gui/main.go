@@ -4,10 +4,17 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/shini4i/openfortivpn-gui/internal/config"
+	"github.com/shini4i/openfortivpn-gui/internal/diagnose"
 	"github.com/shini4i/openfortivpn-gui/internal/logging"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
 	"github.com/shini4i/openfortivpn-gui/internal/ui"
 )
 
@@ -15,6 +22,11 @@ func main() {
 	// Initialize structured logging
 	logging.SetupFromEnv()
 
+	// Command line subcommands run without starting the GUI
+	if len(os.Args) > 1 && os.Args[1] == diagnose.CommandName {
+		os.Exit(runDiagnose(os.Args[2:]))
+	}
+
 	// Create application with default configuration
 	app, err := ui.NewApp(&ui.AppConfig{
 		// Use PATH lookup for openfortivpn by default
@@ -30,3 +42,22 @@ func main() {
 		os.Exit(code)
 	}
 }
+
+// runDiagnose runs gateway diagnostics for a stored profile and returns the exit code.
+func runDiagnose(args []string) int {
+	paths, err := config.GetPaths()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	store, err := profile.NewStore(paths.ProfilesDir, profile.WithSystemDirs(paths.SystemProfilesDirs...))
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return diagnose.RunCommand(ctx, args, store, os.Stdout, os.Stderr)
+}
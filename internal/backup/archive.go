@@ -0,0 +1,158 @@
+// Package backup creates and restores passphrase-encrypted archives of the user's
+// profiles, the application configuration, and optionally their saved passwords.
+//
+// An archive starts with a plain header identifying the format and the key derivation
+// parameters, followed by the JSON contents sealed with AES-256-GCM. The key is
+// derived from the passphrase with PBKDF2-HMAC-SHA256; the header is authenticated
+// along with the contents, so tampering with either is detected.
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FileExtension is the extension suggested for backup archives, including the leading dot.
+const FileExtension = ".ofvbackup"
+
+// MinPassphraseLength is the shortest passphrase accepted for new archives.
+const MinPassphraseLength = 8
+
+const (
+	// magic identifies backup archives.
+	magic = "OFVPNBAK"
+	// formatVersion is the archive layout written by this version of the application.
+	formatVersion byte = 1
+
+	saltSize  = 16
+	keySize   = 32
+	nonceSize = 12
+	// headerSize covers magic, format version, iteration count, salt, and nonce.
+	headerSize = len(magic) + 1 + 4 + saltSize + nonceSize
+
+	// maxKDFIterations bounds the work an archive can demand before the passphrase is checked.
+	maxKDFIterations = 10_000_000
+)
+
+// kdfIterations is the PBKDF2 iteration count for new archives, following the
+// OWASP recommendation for HMAC-SHA256. The count is stored in each archive.
+var kdfIterations = 600_000
+
+var (
+	// ErrNotArchive is returned for data that is not a backup archive.
+	ErrNotArchive = errors.New("file is not an openfortivpn-gui backup")
+	// ErrUnsupportedVersion is returned for archives written in a newer format.
+	ErrUnsupportedVersion = errors.New("backup was created by a newer version of openfortivpn-gui")
+	// ErrWrongPassphrase is returned when the archive cannot be decrypted,
+	// either because the passphrase is wrong or the file was modified.
+	ErrWrongPassphrase = errors.New("wrong passphrase or damaged backup")
+	// ErrPassphraseTooShort is returned when sealing an archive with a short passphrase.
+	ErrPassphraseTooShort = fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLength)
+)
+
+// Archive is the decrypted content of a backup.
+// Profiles and configuration are kept as stored, along with their schema version,
+// so Restore migrates them like the files they were read from.
+type Archive struct {
+	// CreatedAt is when the backup was made.
+	CreatedAt time.Time `json:"created_at"`
+	// Profiles are the user's own profiles as stored, including templates.
+	Profiles []json.RawMessage `json:"profiles"`
+	// Config is the application configuration as stored, if it was included.
+	Config json.RawMessage `json:"config,omitempty"`
+	// Secrets maps profile IDs to saved passwords. It is empty unless passwords were included.
+	Secrets map[string]string `json:"secrets,omitempty"`
+}
+
+// Seal encrypts the archive with a key derived from the passphrase.
+func Seal(a *Archive, passphrase string) ([]byte, error) {
+	if len([]rune(passphrase)) < MinPassphraseLength {
+		return nil, ErrPassphraseTooShort
+	}
+
+	plaintext, err := json.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal backup: %w", err)
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, formatVersion)
+	header = binary.BigEndian.AppendUint32(header, uint32(kdfIterations)) // #nosec G115 -- constant well below 2^32
+	salt := make([]byte, saltSize)
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	header = append(header, salt...)
+	header = append(header, nonce...)
+
+	aead, err := newAEAD(passphrase, salt, kdfIterations)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// Open decrypts an archive created by Seal.
+func Open(data []byte, passphrase string) (*Archive, error) {
+	if len(data) < headerSize || !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, ErrNotArchive
+	}
+
+	header := data[:headerSize]
+	offset := len(magic)
+	if version := header[offset]; version > formatVersion {
+		return nil, fmt.Errorf("%w: format version %d", ErrUnsupportedVersion, version)
+	}
+	offset++
+	iterations := int(binary.BigEndian.Uint32(header[offset:]))
+	offset += 4
+	salt := header[offset : offset+saltSize]
+	offset += saltSize
+	nonce := header[offset : offset+nonceSize]
+
+	if iterations < 1 || iterations > maxKDFIterations {
+		return nil, fmt.Errorf("%w: invalid key derivation parameters", ErrNotArchive)
+	}
+
+	aead, err := newAEAD(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, data[headerSize:], header)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var a Archive
+	if err := json.Unmarshal(plaintext, &a); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal backup: %w", err)
+	}
+	return &a, nil
+}
+
+// newAEAD derives the archive key from the passphrase and returns the AES-GCM cipher.
+func newAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
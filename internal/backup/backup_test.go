@@ -0,0 +1,466 @@
+package backup
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/config"
+	"github.com/shini4i/openfortivpn-gui/internal/keyring"
+	"github.com/shini4i/openfortivpn-gui/internal/migrate"
+	"github.com/shini4i/openfortivpn-gui/internal/netrules"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+const testPassphrase = "correct horse battery"
+
+func TestMain(m *testing.M) {
+	// Key derivation at full strength would dominate the test run
+	kdfIterations = 1000
+	os.Exit(m.Run())
+}
+
+// memKeyring is an in-memory keyring.Store.
+type memKeyring map[string]string
+
+func (k memKeyring) Save(profileID, password string) error {
+	k[profileID] = password
+	return nil
+}
+
+func (k memKeyring) Get(profileID string) (string, error) {
+	password, ok := k[profileID]
+	if !ok {
+		return "", keyring.ErrKeyringCredentialNotFound
+	}
+	return password, nil
+}
+
+func (k memKeyring) Delete(profileID string) error {
+	delete(k, profileID)
+	return nil
+}
+
+// memConfig records the configuration passed to UpdateConfig.
+type memConfig struct {
+	cfg *config.Config
+}
+
+func (c *memConfig) UpdateConfig(cfg *config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	c.cfg = cfg
+	return nil
+}
+
+func newTestStore(t *testing.T) *profile.Store {
+	t.Helper()
+	store, err := profile.NewStore(t.TempDir())
+	require.NoError(t, err)
+	return store
+}
+
+func newTestProfile(name string) *profile.Profile {
+	p := profile.NewProfile(name)
+	p.Host = "vpn.example.com"
+	p.Username = "alice"
+	return p
+}
+
+// archived returns profiles as Collect stores them in an archive.
+// Profiles without a schema version get the current one.
+func archived(t *testing.T, profiles ...*profile.Profile) []json.RawMessage {
+	t.Helper()
+	entries := make([]json.RawMessage, 0, len(profiles))
+	for _, p := range profiles {
+		stored := *p
+		if stored.SchemaVersion == 0 {
+			stored.SchemaVersion = profile.CurrentSchemaVersion()
+		}
+		data, err := json.Marshal(stored)
+		require.NoError(t, err)
+		entries = append(entries, data)
+	}
+	return entries
+}
+
+// archivedConfig returns a configuration as Collect stores it in an archive.
+func archivedConfig(t *testing.T, cfg *config.Config) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	return data
+}
+
+func TestSealOpen_RoundTrip(t *testing.T) {
+	p := newTestProfile("Office")
+	a := &Archive{
+		Profiles: archived(t, p),
+		Config:   archivedConfig(t, config.DefaultConfig()),
+		Secrets:  map[string]string{p.ID: "s3cret"},
+	}
+
+	data, err := Seal(a, testPassphrase)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cret")
+	assert.NotContains(t, string(data), "vpn.example.com")
+
+	opened, err := Open(data, testPassphrase)
+	require.NoError(t, err)
+	require.Len(t, opened.Profiles, 1)
+	assert.JSONEq(t, string(a.Profiles[0]), string(opened.Profiles[0]))
+	assert.Equal(t, "s3cret", opened.Secrets[p.ID])
+	assert.JSONEq(t, string(a.Config), string(opened.Config))
+}
+
+func TestSeal_ShortPassphrase(t *testing.T) {
+	_, err := Seal(&Archive{}, "short")
+	assert.ErrorIs(t, err, ErrPassphraseTooShort)
+}
+
+func TestSeal_UsesFreshSalt(t *testing.T) {
+	first, err := Seal(&Archive{}, testPassphrase)
+	require.NoError(t, err)
+	second, err := Seal(&Archive{}, testPassphrase)
+	require.NoError(t, err)
+	assert.NotEqual(t, first[:headerSize], second[:headerSize])
+}
+
+func TestOpen_Errors(t *testing.T) {
+	sealed, err := Seal(&Archive{}, testPassphrase)
+	require.NoError(t, err)
+
+	tamper := func(offset int) []byte {
+		data := append([]byte(nil), sealed...)
+		data[offset] ^= 0xff
+		return data
+	}
+	newerFormat := append([]byte(nil), sealed...)
+	newerFormat[len(magic)] = formatVersion + 1
+	hugeIterations := append([]byte(nil), sealed...)
+	copy(hugeIterations[len(magic)+1:], []byte{0xff, 0xff, 0xff, 0xff})
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		wantErr    error
+	}{
+		{name: "wrong passphrase", data: sealed, passphrase: "incorrect horse", wantErr: ErrWrongPassphrase},
+		{name: "not an archive", data: []byte(`{"profiles": []}`), passphrase: testPassphrase, wantErr: ErrNotArchive},
+		{name: "truncated", data: sealed[:headerSize-1], passphrase: testPassphrase, wantErr: ErrNotArchive},
+		{name: "newer format", data: newerFormat, passphrase: testPassphrase, wantErr: ErrUnsupportedVersion},
+		{name: "excessive iterations", data: hugeIterations, passphrase: testPassphrase, wantErr: ErrNotArchive},
+		{name: "tampered salt", data: tamper(len(magic) + 5), passphrase: testPassphrase, wantErr: ErrWrongPassphrase},
+		{name: "tampered contents", data: tamper(len(sealed) - 1), passphrase: testPassphrase, wantErr: ErrWrongPassphrase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Open(tt.data, tt.passphrase)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	store := newTestStore(t)
+	withPassword := newTestProfile("Office")
+	withoutPassword := newTestProfile("Lab")
+	require.NoError(t, store.Save(withPassword))
+	require.NoError(t, store.Save(withoutPassword))
+	kr := memKeyring{withPassword.ID: "s3cret"}
+
+	t.Run("without secrets", func(t *testing.T) {
+		a, listErrs, err := Collect(store, config.DefaultConfig(), kr, false)
+		require.NoError(t, err)
+		assert.Empty(t, listErrs)
+		assert.Len(t, a.Profiles, 2)
+		assert.Empty(t, a.Secrets)
+		assert.NotNil(t, a.Config)
+		assert.False(t, a.CreatedAt.IsZero())
+	})
+
+	t.Run("with secrets", func(t *testing.T) {
+		a, _, err := Collect(store, config.DefaultConfig(), kr, true)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{withPassword.ID: "s3cret"}, a.Secrets)
+	})
+}
+
+func TestCollect_SkipsSystemProfiles(t *testing.T) {
+	systemDir := t.TempDir()
+	require.NoError(t, os.WriteFile(systemDir+"/6ba7b810-9dad-11d1-80b4-00c04fd430c8.json",
+		[]byte(`{"schema_version": 1, "name": "Corporate", "host": "vpn.corp.example.com", "port": 443, "auth_method": "password"}`), 0644))
+	store, err := profile.NewStore(t.TempDir(), profile.WithSystemDirs(systemDir))
+	require.NoError(t, err)
+	own := newTestProfile("Own")
+	require.NoError(t, store.Save(own))
+
+	a, _, err := Collect(store, nil, memKeyring{}, true)
+	require.NoError(t, err)
+	require.Len(t, a.Profiles, 1)
+	assert.Contains(t, string(a.Profiles[0]), own.ID)
+	assert.Nil(t, a.Config)
+}
+
+func TestRestore_NewProfiles(t *testing.T) {
+	source := newTestStore(t)
+	p := newTestProfile("Office")
+	require.NoError(t, source.Save(p))
+	a, _, err := Collect(source, nil, memKeyring{p.ID: "s3cret"}, true)
+	require.NoError(t, err)
+
+	target := newTestStore(t)
+	kr := memKeyring{}
+	result, err := Restore(a, target, kr, nil, RestoreOptions{})
+	require.NoError(t, err)
+
+	require.Len(t, result.Restored, 1)
+	assert.Empty(t, result.Skipped)
+	assert.Empty(t, result.Renamed)
+	assert.Equal(t, 1, result.SecretsRestored)
+	assert.Equal(t, "s3cret", kr[p.ID])
+
+	loaded, err := target.Load(p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Office", loaded.Name)
+}
+
+func TestRestore_Collisions(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      CollisionPolicy
+		wantCount   int
+		wantRenamed bool
+	}{
+		{name: "keep both", policy: KeepBoth, wantCount: 2, wantRenamed: true},
+		{name: "replace", policy: Replace, wantCount: 1, wantRenamed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			existing := newTestProfile("Office")
+			require.NoError(t, store.Save(existing))
+
+			backedUp := *existing
+			backedUp.Host = "vpn2.example.com"
+			a := &Archive{
+				Profiles: archived(t, &backedUp),
+				Secrets:  map[string]string{existing.ID: "from-backup"},
+			}
+			kr := memKeyring{existing.ID: "current"}
+
+			result, err := Restore(a, store, kr, nil, RestoreOptions{Collisions: tt.policy})
+			require.NoError(t, err)
+			require.Len(t, result.Restored, 1)
+
+			list, err := store.List()
+			require.NoError(t, err)
+			assert.Len(t, list.Profiles, tt.wantCount)
+
+			restored := result.Restored[0]
+			if tt.wantRenamed {
+				assert.NotEqual(t, existing.ID, restored.ID)
+				assert.Equal(t, restored.ID, result.Renamed[existing.ID])
+				assert.Equal(t, "Office (Restored)", restored.Name)
+				assert.Equal(t, "current", kr[existing.ID])
+				assert.Equal(t, "from-backup", kr[restored.ID])
+
+				original, err := store.Load(existing.ID)
+				require.NoError(t, err)
+				assert.Equal(t, "vpn.example.com", original.Host)
+			} else {
+				assert.Equal(t, existing.ID, restored.ID)
+				assert.Equal(t, "Office", restored.Name)
+				assert.Equal(t, "from-backup", kr[existing.ID])
+
+				loaded, err := store.Load(existing.ID)
+				require.NoError(t, err)
+				assert.Equal(t, "vpn2.example.com", loaded.Host)
+			}
+		})
+	}
+}
+
+func TestRestore_SystemProfileCollisionKeepsBoth(t *testing.T) {
+	const id = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	systemDir := t.TempDir()
+	require.NoError(t, os.WriteFile(systemDir+"/"+id+".json",
+		[]byte(`{"schema_version": 1, "name": "Corporate", "host": "vpn.corp.example.com", "port": 443, "auth_method": "password"}`), 0644))
+	store, err := profile.NewStore(t.TempDir(), profile.WithSystemDirs(systemDir))
+	require.NoError(t, err)
+
+	backedUp := newTestProfile("Corporate")
+	backedUp.ID = id
+	result, err := Restore(&Archive{Profiles: archived(t, backedUp)}, store, nil, nil,
+		RestoreOptions{Collisions: Replace})
+	require.NoError(t, err)
+
+	require.Len(t, result.Restored, 1)
+	assert.NotEqual(t, id, result.Restored[0].ID)
+	system, err := store.Load(id)
+	require.NoError(t, err)
+	assert.Equal(t, "vpn.corp.example.com", system.Host)
+}
+
+func TestRestore_SkipsInvalidProfiles(t *testing.T) {
+	valid := newTestProfile("Office")
+	noHost := newTestProfile("No Host")
+	noHost.Host = ""
+	newer := newTestProfile("Newer")
+	newer.SchemaVersion = profile.CurrentSchemaVersion() + 1
+	badID := newTestProfile("Bad ID")
+	badID.ID = "../escape"
+	orphan := newTestProfile("Orphan")
+	orphan.ParentID = "11111111-1111-1111-1111-111111111111"
+
+	a := &Archive{Profiles: archived(t, valid, noHost, newer, badID, orphan, valid)}
+	store := newTestStore(t)
+
+	result, err := Restore(a, store, nil, nil, RestoreOptions{})
+	require.NoError(t, err)
+
+	require.Len(t, result.Restored, 1)
+	assert.Equal(t, valid.ID, result.Restored[0].ID)
+	require.Len(t, result.Skipped, 5)
+	skipped := make(map[string]error)
+	for _, s := range result.Skipped {
+		skipped[s.Name] = s.Err
+	}
+	assert.Error(t, skipped["No Host"])
+	assert.ErrorIs(t, skipped["Newer"], migrate.ErrNewerVersion)
+	assert.ErrorIs(t, skipped["Bad ID"], profile.ErrStoreInvalidID)
+	assert.ErrorIs(t, skipped["Orphan"], profile.ErrStoreNotFound)
+	assert.Error(t, skipped["Office"])
+
+	list, err := store.List()
+	require.NoError(t, err)
+	assert.Len(t, list.Profiles, 1)
+}
+
+func TestRestore_ChildrenFollowRenamedTemplate(t *testing.T) {
+	store := newTestStore(t)
+	template := newTestProfile("Corporate")
+	template.IsTemplate = true
+	require.NoError(t, store.Save(template))
+
+	child := profile.NewProfile("Office")
+	child.ParentID = template.ID
+	child.Username = "alice"
+
+	result, err := Restore(&Archive{Profiles: archived(t, template, child)}, store, nil, nil,
+		RestoreOptions{Collisions: KeepBoth})
+	require.NoError(t, err)
+	require.Len(t, result.Restored, 2)
+	assert.Empty(t, result.Skipped)
+
+	newTemplateID := result.Renamed[template.ID]
+	require.NotEmpty(t, newTemplateID)
+	loaded, err := store.Load(child.ID)
+	require.NoError(t, err)
+	assert.Equal(t, newTemplateID, loaded.ParentID)
+}
+
+func TestRestore_ChildOfStoredTemplate(t *testing.T) {
+	store := newTestStore(t)
+	template := newTestProfile("Corporate")
+	template.IsTemplate = true
+	require.NoError(t, store.Save(template))
+
+	child := profile.NewProfile("Office")
+	child.ParentID = template.ID
+
+	result, err := Restore(&Archive{Profiles: archived(t, child)}, store, nil, nil, RestoreOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Restored, 1)
+	assert.Equal(t, template.ID, result.Restored[0].ParentID)
+}
+
+func TestRestore_Config(t *testing.T) {
+	p := newTestProfile("Office")
+	backedUp := config.DefaultConfig()
+	backedUp.SchemaVersion = config.CurrentSchemaVersion()
+	backedUp.DefaultProfileID = p.ID
+	backedUp.MaxReconnectAttempts = 7
+	backedUp.NetworkRules = []netrules.Rule{
+		{Name: "Home", Match: netrules.MatchSSID, Value: "home", Action: netrules.ActionConnect, ProfileID: p.ID},
+		{Name: "Office", Match: netrules.MatchSSID, Value: "corp", Action: netrules.ActionDisconnect},
+	}
+
+	t.Run("not requested", func(t *testing.T) {
+		cfg := &memConfig{}
+		result, err := Restore(&Archive{Config: archivedConfig(t, backedUp)}, newTestStore(t), nil, cfg, RestoreOptions{})
+		require.NoError(t, err)
+		assert.False(t, result.ConfigRestored)
+		assert.Nil(t, cfg.cfg)
+	})
+
+	t.Run("default profile follows rename", func(t *testing.T) {
+		store := newTestStore(t)
+		require.NoError(t, store.Save(p))
+		cfg := &memConfig{}
+
+		result, err := Restore(&Archive{Profiles: archived(t, p), Config: archivedConfig(t, backedUp)}, store, nil, cfg,
+			RestoreOptions{Collisions: KeepBoth, RestoreConfig: true})
+		require.NoError(t, err)
+		assert.True(t, result.ConfigRestored)
+		require.NotNil(t, cfg.cfg)
+		assert.Equal(t, 7, cfg.cfg.MaxReconnectAttempts)
+		assert.Equal(t, result.Renamed[p.ID], cfg.cfg.DefaultProfileID)
+		require.Len(t, cfg.cfg.NetworkRules, 2)
+		assert.Equal(t, result.Renamed[p.ID], cfg.cfg.NetworkRules[0].ProfileID)
+		assert.Empty(t, cfg.cfg.NetworkRules[1].ProfileID)
+	})
+
+	t.Run("newer schema", func(t *testing.T) {
+		newer := *backedUp
+		newer.SchemaVersion = config.CurrentSchemaVersion() + 1
+		cfg := &memConfig{}
+
+		result, err := Restore(&Archive{Config: archivedConfig(t, &newer)}, newTestStore(t), nil, cfg, RestoreOptions{RestoreConfig: true})
+		require.NoError(t, err)
+		assert.False(t, result.ConfigRestored)
+		require.Len(t, result.Errors, 1)
+		assert.ErrorIs(t, result.Errors[0], migrate.ErrNewerVersion)
+		assert.Nil(t, cfg.cfg)
+	})
+
+	t.Run("older schema", func(t *testing.T) {
+		cfg := &memConfig{}
+		a := &Archive{Config: json.RawMessage(`{"max_reconnect_attempts": 7}`)}
+
+		result, err := Restore(a, newTestStore(t), nil, cfg, RestoreOptions{RestoreConfig: true})
+		require.NoError(t, err)
+		assert.True(t, result.ConfigRestored)
+		require.NotNil(t, cfg.cfg)
+		assert.Equal(t, 7, cfg.cfg.MaxReconnectAttempts)
+		assert.Equal(t, config.DefaultConfig().ReconnectDelaySeconds, cfg.cfg.ReconnectDelaySeconds)
+	})
+}
+
+func TestRestore_MigratesOlderProfiles(t *testing.T) {
+	const id = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	a := &Archive{Profiles: []json.RawMessage{
+		json.RawMessage(`{"id": "` + id + `", "name": "Old", "host": "vpn.example.com", "username": "alice"}`),
+	}}
+	store := newTestStore(t)
+
+	result, err := Restore(a, store, nil, nil, RestoreOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Restored, 1)
+	assert.Empty(t, result.Skipped)
+
+	// Settings missing from the old profile get their defaults, as when loading its file
+	loaded, err := store.Load(id)
+	require.NoError(t, err)
+	assert.Equal(t, 443, loaded.Port)
+	assert.Equal(t, profile.AuthMethodPassword, loaded.AuthMethod)
+	assert.True(t, loaded.SetDNS)
+	assert.True(t, loaded.SetRoutes)
+}
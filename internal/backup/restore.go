@@ -0,0 +1,302 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shini4i/openfortivpn-gui/internal/config"
+	"github.com/shini4i/openfortivpn-gui/internal/keyring"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// restoredSuffix is appended to the name of a profile restored alongside an existing one.
+const restoredSuffix = " (Restored)"
+
+// CollisionPolicy decides what happens to a backed up profile whose ID is already in use.
+type CollisionPolicy int
+
+const (
+	// KeepBoth restores the profile under a new ID and leaves the existing one untouched.
+	KeepBoth CollisionPolicy = iota
+	// Replace overwrites the existing profile with the backed up one.
+	Replace
+)
+
+// ConfigUpdater applies a restored configuration. config.Manager satisfies it.
+type ConfigUpdater interface {
+	UpdateConfig(cfg *config.Config) error
+}
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	// Collisions decides how profiles whose ID is already in use are restored.
+	// System profiles are never replaced; backed up copies of them are always kept alongside.
+	Collisions CollisionPolicy
+	// RestoreConfig replaces the application configuration with the one in the archive.
+	RestoreConfig bool
+}
+
+// SkippedProfile is a backed up profile that was not restored.
+type SkippedProfile struct {
+	ID   string
+	Name string
+	Err  error
+}
+
+// RestoreResult summarizes a restore.
+type RestoreResult struct {
+	// Restored are the profiles saved to the store, with their final IDs.
+	Restored []*profile.Profile
+	// Renamed maps archive IDs to the new IDs of profiles kept alongside existing ones.
+	Renamed map[string]string
+	// Skipped are the profiles that failed validation or could not be saved.
+	Skipped []SkippedProfile
+	// SecretsRestored is the number of passwords saved to the keyring.
+	SecretsRestored int
+	// ConfigRestored reports whether the configuration was replaced.
+	ConfigRestored bool
+	// Errors are failures that did not prevent the profiles from being restored,
+	// such as a password the keyring refused.
+	Errors []error
+}
+
+// Collect gathers the user's profiles, the configuration, and optionally the
+// profiles' saved passwords into an archive. System profiles are provisioned by an
+// administrator and are not included. Profiles that could not be read are returned
+// alongside the archive, as Store.List reports them.
+func Collect(store profile.StoreInterface, cfg *config.Config, kr keyring.Store, includeSecrets bool) (*Archive, []profile.ListError, error) {
+	result, err := store.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	a := &Archive{
+		CreatedAt: time.Now().UTC(),
+		Profiles:  make([]json.RawMessage, 0, len(result.Profiles)),
+	}
+	if cfg != nil {
+		stored := *cfg
+		stored.SchemaVersion = config.CurrentSchemaVersion()
+		a.Config, err = json.Marshal(stored)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal config: %w", err)
+		}
+	}
+
+	for _, p := range result.Profiles {
+		if p.System {
+			continue
+		}
+		stored := *p
+		stored.SchemaVersion = profile.CurrentSchemaVersion()
+		data, err := json.Marshal(stored)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal profile %s: %w", p.Name, err)
+		}
+		a.Profiles = append(a.Profiles, data)
+
+		// Templates cannot be connected and never have a saved password
+		if !includeSecrets || p.IsTemplate {
+			continue
+		}
+		password, err := kr.Get(p.ID)
+		if errors.Is(err, keyring.ErrKeyringCredentialNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read password for %s: %w", p.Name, err)
+		}
+		if a.Secrets == nil {
+			a.Secrets = make(map[string]string)
+		}
+		a.Secrets[p.ID] = password
+	}
+
+	return a, result.Errors, nil
+}
+
+// Restore saves the archive's profiles to the store and, if requested, its
+// passwords to the keyring and its configuration through cfg.
+// Every profile is validated first, with inherited settings resolved from templates
+// in the archive or the store; profiles that fail validation are skipped.
+// kr and cfg may be nil when the archive's secrets or configuration are not restored.
+func Restore(a *Archive, store profile.StoreInterface, kr keyring.Store, cfg ConfigUpdater, opts RestoreOptions) (*RestoreResult, error) {
+	result := &RestoreResult{Renamed: make(map[string]string)}
+
+	pending, err := prepareProfiles(a, store, opts.Collisions, result)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range pending {
+		if err := validateRestored(p, pending, store); err != nil {
+			result.skip(p, err)
+			continue
+		}
+		if err := store.Save(p); err != nil {
+			result.skip(p, err)
+			continue
+		}
+		result.Restored = append(result.Restored, p)
+	}
+
+	if kr != nil {
+		for _, p := range result.Restored {
+			password, ok := a.Secrets[archiveID(p.ID, result.Renamed)]
+			if !ok {
+				continue
+			}
+			if err := kr.Save(p.ID, password); err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("failed to save password for %s: %w", p.Name, err))
+				continue
+			}
+			result.SecretsRestored++
+		}
+	}
+
+	if opts.RestoreConfig && len(a.Config) > 0 && cfg != nil {
+		if err := restoreConfig(a.Config, result.Renamed, cfg); err != nil {
+			result.Errors = append(result.Errors, err)
+		} else {
+			result.ConfigRestored = true
+		}
+	}
+
+	return result, nil
+}
+
+// prepareProfiles decodes the archive's profiles, migrating those backed up by older
+// versions, resolves ID collisions according to the policy, and points children at
+// the new IDs of renamed templates.
+func prepareProfiles(a *Archive, store profile.StoreInterface, policy CollisionPolicy, result *RestoreResult) ([]*profile.Profile, error) {
+	pending := make([]*profile.Profile, 0, len(a.Profiles))
+	seen := make(map[string]bool, len(a.Profiles))
+
+	for _, data := range a.Profiles {
+		if len(data) == 0 || string(data) == "null" {
+			continue
+		}
+		p, err := profile.Decode(data)
+		if err != nil {
+			result.skipUndecodable(data, err)
+			continue
+		}
+
+		if seen[p.ID] {
+			result.skip(p, errors.New("duplicate profile ID in backup"))
+			continue
+		}
+		seen[p.ID] = true
+
+		exists, err := store.Exists(p.ID)
+		if errors.Is(err, profile.ErrStoreInvalidID) {
+			result.skip(p, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check profile %s: %w", p.Name, err)
+		}
+		if exists && (policy == KeepBoth || isSystemProfile(store, p.ID)) {
+			newID := uuid.New().String()
+			result.Renamed[p.ID] = newID
+			p.ID = newID
+			p.Name += restoredSuffix
+		}
+
+		pending = append(pending, p)
+	}
+
+	for _, p := range pending {
+		if newID, ok := result.Renamed[p.ParentID]; ok {
+			p.ParentID = newID
+		}
+	}
+
+	return pending, nil
+}
+
+// isSystemProfile reports whether the stored profile with the given ID is provided by an administrator.
+func isSystemProfile(store profile.StoreInterface, id string) bool {
+	p, err := store.Load(id)
+	return err == nil && p.System
+}
+
+// validateRestored validates a profile about to be restored, resolving inherited
+// settings from the profiles being restored before those already stored.
+func validateRestored(p *profile.Profile, pending []*profile.Profile, store profile.StoreInterface) error {
+	resolved, err := profile.Resolve(p, restoreLoader{pending: pending, store: store})
+	if err != nil {
+		return err
+	}
+	return resolved.Validate()
+}
+
+// restoreLoader loads templates for validation from the profiles being restored,
+// falling back to the store.
+type restoreLoader struct {
+	pending []*profile.Profile
+	store   profile.StoreInterface
+}
+
+// Load implements profile.Loader.
+func (l restoreLoader) Load(id string) (*profile.Profile, error) {
+	for _, p := range l.pending {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return l.store.Load(id)
+}
+
+// restoreConfig applies the backed up configuration, migrating it if it was backed up
+// by an older version, and points the default profile and the network rules at the
+// new IDs of renamed profiles.
+func restoreConfig(data json.RawMessage, renamed map[string]string, cfg ConfigUpdater) error {
+	restored, err := config.Decode(data)
+	if err != nil {
+		return fmt.Errorf("failed to restore settings: %w", err)
+	}
+
+	if newID, ok := renamed[restored.DefaultProfileID]; ok {
+		restored.DefaultProfileID = newID
+	}
+	for i, rule := range restored.NetworkRules {
+		if newID, ok := renamed[rule.ProfileID]; ok {
+			restored.NetworkRules[i].ProfileID = newID
+		}
+	}
+	if err := cfg.UpdateConfig(restored); err != nil {
+		return fmt.Errorf("failed to restore settings: %w", err)
+	}
+	return nil
+}
+
+// archiveID returns the ID a restored profile had in the archive.
+func archiveID(id string, renamed map[string]string) string {
+	for oldID, newID := range renamed {
+		if newID == id {
+			return oldID
+		}
+	}
+	return id
+}
+
+// skip records a profile that was not restored.
+func (r *RestoreResult) skip(p *profile.Profile, err error) {
+	r.Skipped = append(r.Skipped, SkippedProfile{ID: p.ID, Name: p.Name, Err: err})
+}
+
+// skipUndecodable records a backed up profile that could not be decoded,
+// identified by whatever ID and name can still be read from it.
+func (r *RestoreResult) skipUndecodable(data json.RawMessage, err error) {
+	var header struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	_ = json.Unmarshal(data, &header)
+	r.Skipped = append(r.Skipped, SkippedProfile{ID: header.ID, Name: header.Name, Err: err})
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"log/slog"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -39,6 +40,8 @@ type HelperClient struct {
 	state         vpn.ConnectionState
 	assignedIP    string
 	interfaceName string
+	phase         vpn.Phase
+	alwaysOn      *protocol.AlwaysOnStatus
 	onStateChange func(old, new vpn.ConnectionState)
 	onOutput      func(line string)
 	onEvent       func(event *vpn.OutputEvent)
@@ -139,6 +142,13 @@ func (c *HelperClient) GetInterface() string {
 	return c.interfaceName
 }
 
+// GetPhase returns the most recent connection phase reported by the helper.
+func (c *HelperClient) GetPhase() vpn.Phase {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.phase
+}
+
 // detectInterface attempts to detect the VPN interface by the assigned IP.
 // It uses DetectInterfaceWithRetry for retry logic, then verifies the connection
 // state is still valid before setting the interface name.
@@ -195,25 +205,75 @@ func (c *HelperClient) Connect(ctx context.Context, p *profile.Profile, opts *vp
 		SetDNS:             p.SetDNS,
 		SetRoutes:          p.SetRoutes,
 		HalfInternetRoutes: p.HalfInternetRoutes,
+		NoFTMPush:          p.NoFTMPush,
+	}
+	if p.KillSwitch != nil {
+		// Allow all endpoints so failing over to a backup gateway is not blocked
+		params.KillSwitch = &protocol.KillSwitchParams{AllowedLANs: p.KillSwitch.AllowedLANs}
+		for _, gw := range p.Endpoints() {
+			params.KillSwitch.Gateways = append(params.KillSwitch.Gateways, net.JoinHostPort(gw.Host, strconv.Itoa(gw.Port)))
+		}
 	}
 
 	_, err := c.sendRequest(ctx, protocol.CommandConnect, params)
 	return err
 }
 
-// Disconnect terminates the active VPN connection.
+// Disconnect terminates the active VPN connection and removes the kill switch.
+// If the tunnel already dropped, only the kill switch is removed.
 // If ctx is nil, a default timeout context will be used.
 func (c *HelperClient) Disconnect(ctx context.Context) error {
+	return c.disconnect(ctx, protocol.DisconnectParams{})
+}
+
+// DisconnectForReconnect terminates the active VPN connection but keeps the kill switch.
+// If ctx is nil, a default timeout context will be used.
+func (c *HelperClient) DisconnectForReconnect(ctx context.Context) error {
+	return c.disconnect(ctx, protocol.DisconnectParams{KeepKillSwitch: true})
+}
+
+func (c *HelperClient) disconnect(ctx context.Context, params protocol.DisconnectParams) error {
 	if ctx == nil {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout)
 		defer cancel()
 	}
 
-	_, err := c.sendRequest(ctx, protocol.CommandDisconnect, protocol.DisconnectParams{})
+	_, err := c.sendRequest(ctx, protocol.CommandDisconnect, params)
 	return err
 }
 
+// ReleaseKillSwitch removes the kill switch while no tunnel is active.
+// If ctx is nil, a default timeout context will be used.
+func (c *HelperClient) ReleaseKillSwitch(ctx context.Context) error {
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout)
+		defer cancel()
+	}
+
+	_, err := c.sendRequest(ctx, protocol.CommandReleaseKillSwitch, protocol.ReleaseKillSwitchParams{})
+	return err
+}
+
+// AlwaysOnProfile returns the always-on profile the helper keeps connected, or nil if none is configured.
+// The helper owns its settings, so all of them are locked.
+func (c *HelperClient) AlwaysOnProfile() *profile.Profile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.alwaysOn == nil {
+		return nil
+	}
+	return &profile.Profile{
+		ID:     c.alwaysOn.ProfileID,
+		Name:   c.alwaysOn.Name,
+		Host:   c.alwaysOn.Host,
+		Port:   c.alwaysOn.Port,
+		System: true,
+		Locked: []string{"username"},
+	}
+}
+
 // OnStateChange registers a callback for state changes.
 func (c *HelperClient) OnStateChange(callback func(old, new vpn.ConnectionState)) {
 	c.mu.Lock()
@@ -259,6 +319,8 @@ func (c *HelperClient) syncState() error {
 	c.mu.Lock()
 	c.state = vpn.ConnectionState(status.State)
 	c.assignedIP = status.AssignedIP
+	c.phase = vpn.Phase(status.Phase)
+	c.alwaysOn = status.AlwaysOn
 	assignedIP := status.AssignedIP
 	c.mu.Unlock()
 
@@ -407,11 +469,16 @@ func (c *HelperClient) handleEvent(event *protocol.Event) {
 		c.mu.Lock()
 		oldState := c.state
 		c.state = vpn.ConnectionState(data.To)
+		c.alwaysOn = data.AlwaysOn
 		// Clear interface and IP on disconnect.
 		if vpn.ConnectionState(data.To) == vpn.StateDisconnected {
 			c.assignedIP = ""
 			c.interfaceName = ""
 		}
+		// Phases only describe progress within a single connection attempt.
+		if newState := vpn.ConnectionState(data.To); newState == vpn.StateConnecting || !newState.IsTransitioning() {
+			c.phase = vpn.PhaseNone
+		}
 		callback := c.onStateChange
 		c.mu.Unlock()
 
@@ -419,6 +486,23 @@ func (c *HelperClient) handleEvent(event *protocol.Event) {
 			callback(oldState, vpn.ConnectionState(data.To))
 		}
 
+	case protocol.EventNetworkRestored:
+		var data protocol.NetworkRestoredData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			slog.Warn("Invalid network restored event", "error", err)
+			return
+		}
+		c.mu.RLock()
+		callback := c.onOutput
+		c.mu.RUnlock()
+
+		// Show in the connection log what the helper fixed after openfortivpn
+		if callback != nil {
+			for _, line := range networkRestoredLines(data) {
+				callback(line)
+			}
+		}
+
 	case protocol.EventOutput:
 		var data protocol.OutputData
 		if err := json.Unmarshal(event.Data, &data); err != nil {
@@ -455,6 +539,15 @@ func (c *HelperClient) handleEvent(event *protocol.Event) {
 			}
 		}
 
+		// Track connection progress
+		if data.EventType == string(vpn.EventPhase) {
+			c.mu.Lock()
+			if c.state.IsTransitioning() {
+				c.phase = vpn.Phase(data.Data[vpn.DataKeyPhase])
+			}
+			c.mu.Unlock()
+		}
+
 		c.mu.RLock()
 		callback := c.onEvent
 		c.mu.RUnlock()
@@ -478,10 +571,39 @@ func (c *HelperClient) handleEvent(event *protocol.Event) {
 		c.mu.RUnlock()
 
 		if callback != nil {
-			callback(errors.New(data.Message))
+			if data.Code != "" {
+				callback(&vpn.ConnectionError{
+					Code:    vpn.ErrorCode(data.Code),
+					Message: data.Message,
+				})
+			} else {
+				callback(errors.New(data.Message))
+			}
 		}
 	}
 }
 
-// Ensure HelperClient implements VPNController interface.
-var _ vpn.VPNController = (*HelperClient)(nil)
+// networkRestoredLines describes a network_restored event as connection log lines.
+func networkRestoredLines(data protocol.NetworkRestoredData) []string {
+	var lines []string
+	if data.ResolvConf {
+		lines = append(lines, "Helper: restored /etc/resolv.conf left changed by openfortivpn")
+	}
+	for _, route := range data.AddedRoutes {
+		lines = append(lines, "Helper: restored route "+route)
+	}
+	for _, route := range data.RemovedRoutes {
+		lines = append(lines, "Helper: removed leftover route "+route)
+	}
+	if data.Error != "" {
+		lines = append(lines, "Helper: could not restore the network settings: "+data.Error)
+	}
+	return lines
+}
+
+// Ensure HelperClient implements the controller interfaces.
+var (
+	_ vpn.VPNController        = (*HelperClient)(nil)
+	_ vpn.KillSwitchController = (*HelperClient)(nil)
+	_ vpn.AlwaysOnReporter     = (*HelperClient)(nil)
+)
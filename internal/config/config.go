@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 
 	"github.com/shini4i/openfortivpn-gui/internal/fileutil"
+	"github.com/shini4i/openfortivpn-gui/internal/migrate"
+	"github.com/shini4i/openfortivpn-gui/internal/netrules"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
 )
 
 const (
@@ -18,16 +22,49 @@ const (
 	ConfigFileName = "config.json"
 	// ProfilesDirName is the name of the directory containing profile files.
 	ProfilesDirName = "profiles"
+	// SystemProfilesDir is the directory administrators provision read-only profiles in.
+	SystemProfilesDir = "/etc/" + AppName + "/" + ProfilesDirName
+	// defaultConfigDirs is the XDG_CONFIG_DIRS default from the XDG Base Directory spec.
+	defaultConfigDirs = "/etc/xdg"
 )
 
+// configMigrations upgrades config files written by older versions.
+// Steps are only ever appended; each one upgrades by a single schema version.
+var configMigrations = migrate.NewPipeline("config",
+	migrate.Step{
+		// Settings missing from the file already fall back to DefaultConfig
+		Description: "record the schema version",
+		Apply:       func(migrate.Document) error { return nil },
+	},
+)
+
+// CurrentSchemaVersion returns the config schema version written by this version of the application.
+func CurrentSchemaVersion() int {
+	return configMigrations.Current()
+}
+
 // Config represents the application configuration.
 type Config struct {
+	// SchemaVersion is the schema version the config was stored with. It is set by Save.
+	SchemaVersion int `json:"schema_version"`
+
 	DefaultProfileID      string `json:"default_profile_id,omitempty"`
 	ReconnectDelaySeconds int    `json:"reconnect_delay_seconds"`
 	MaxReconnectAttempts  int    `json:"max_reconnect_attempts"`
 	ShowNotifications     bool   `json:"show_notifications"`
 	AutoConnect           bool   `json:"auto_connect"`
 	OpenFortiVPNPath      string `json:"openfortivpn_path"`
+	// ProfileSortOrder is how profiles are ordered within each group of the profile list.
+	ProfileSortOrder profile.SortOrder `json:"profile_sort_order,omitempty"`
+	// NetworkRules connect or disconnect the VPN depending on the network, checked in order.
+	NetworkRules []netrules.Rule `json:"network_rules,omitempty"`
+}
+
+// clone returns a deep copy of the configuration.
+func (c *Config) clone() *Config {
+	cfg := *c
+	cfg.NetworkRules = slices.Clone(c.NetworkRules)
+	return &cfg
 }
 
 // DefaultConfig returns a configuration with sensible defaults.
@@ -38,6 +75,7 @@ func DefaultConfig() *Config {
 		ShowNotifications:     true,
 		AutoConnect:           false,
 		OpenFortiVPNPath:      "/usr/bin/openfortivpn",
+		ProfileSortOrder:      profile.SortByName,
 	}
 }
 
@@ -46,6 +84,9 @@ type Paths struct {
 	ConfigDir   string
 	ProfilesDir string
 	ConfigFile  string
+	// SystemProfilesDirs hold read-only profiles provided by an administrator,
+	// in order of precedence. They are not created and may not exist.
+	SystemProfilesDirs []string
 }
 
 // GetPaths returns the configuration paths following XDG Base Directory spec.
@@ -61,12 +102,35 @@ func GetPaths() (*Paths, error) {
 
 	configDir := filepath.Join(configHome, AppName)
 	return &Paths{
-		ConfigDir:   configDir,
-		ProfilesDir: filepath.Join(configDir, ProfilesDirName),
-		ConfigFile:  filepath.Join(configDir, ConfigFileName),
+		ConfigDir:          configDir,
+		ProfilesDir:        filepath.Join(configDir, ProfilesDirName),
+		ConfigFile:         filepath.Join(configDir, ConfigFileName),
+		SystemProfilesDirs: systemProfilesDirs(),
 	}, nil
 }
 
+// systemProfilesDirs returns /etc/openfortivpn-gui/profiles followed by the
+// profiles directories within XDG_CONFIG_DIRS. Relative entries are ignored,
+// as the XDG Base Directory spec requires.
+func systemProfilesDirs() []string {
+	dirs := []string{SystemProfilesDir}
+
+	configDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if configDirs == "" {
+		configDirs = defaultConfigDirs
+	}
+	for _, dir := range filepath.SplitList(configDirs) {
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		dir = filepath.Join(dir, AppName, ProfilesDirName)
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
 // EnsurePaths creates all necessary configuration directories.
 func (p *Paths) EnsurePaths() error {
 	if err := os.MkdirAll(p.ConfigDir, 0700); err != nil {
@@ -79,6 +143,8 @@ func (p *Paths) EnsurePaths() error {
 }
 
 // Load reads the configuration from disk.
+// Files written by older versions are migrated and rewritten; files written by a
+// newer version are rejected with an error wrapping migrate.ErrNewerVersion.
 func Load(path string) (*Config, error) {
 	// #nosec G304 -- path is from GetPaths() using XDG config directory
 	data, err := os.ReadFile(path)
@@ -89,6 +155,11 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	data, err = configMigrations.MigrateFile(path, data, 0600)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := DefaultConfig()
 	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
@@ -97,9 +168,27 @@ func Load(path string) (*Config, error) {
 	return cfg, nil
 }
 
+// Decode decodes a configuration kept outside the config file, such as in a backup archive.
+// Data written by older versions is migrated as Load migrates the file; data written
+// by a newer version is rejected with an error wrapping migrate.ErrNewerVersion.
+func Decode(data []byte) (*Config, error) {
+	migrated, _, _, err := configMigrations.Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(migrated, cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return cfg, nil
+}
+
 // Save writes the configuration to disk using atomic write (write to temp, then rename).
 func Save(path string, cfg *Config) error {
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	stored := *cfg
+	stored.SchemaVersion = CurrentSchemaVersion()
+	data, err := json.MarshalIndent(stored, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -124,7 +213,11 @@ func (c *Config) Validate() error {
 	if c.OpenFortiVPNPath == "" {
 		return fmt.Errorf("openfortivpn path must not be empty")
 	}
-	return nil
+	// An empty sort order falls back to sorting by name
+	if c.ProfileSortOrder != "" && !slices.Contains(profile.ValidSortOrders(), c.ProfileSortOrder) {
+		return fmt.Errorf("invalid profile sort order: %s", c.ProfileSortOrder)
+	}
+	return netrules.Validate(c.NetworkRules)
 }
 
 // Manager provides high-level configuration management.
@@ -164,8 +257,7 @@ func (m *Manager) GetConfig() *Config {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	// Return a copy to prevent race conditions on the config fields
-	cfg := *m.config
-	return &cfg
+	return m.config.clone()
 }
 
 // GetProfilesPath returns the path to the profiles directory.
@@ -173,6 +265,11 @@ func (m *Manager) GetProfilesPath() string {
 	return m.paths.ProfilesDir
 }
 
+// GetSystemProfilesPaths returns the directories holding read-only system profiles.
+func (m *Manager) GetSystemProfilesPaths() []string {
+	return slices.Clone(m.paths.SystemProfilesDirs)
+}
+
 // GetConfigDir returns the path to the configuration directory.
 func (m *Manager) GetConfigDir() string {
 	return m.paths.ConfigDir
@@ -205,13 +302,13 @@ func (m *Manager) UpdateField(mutator func(cfg *Config)) error {
 	defer m.mu.Unlock()
 
 	// Create a copy to apply mutation and validate before committing
-	configCopy := *m.config
-	mutator(&configCopy)
+	configCopy := m.config.clone()
+	mutator(configCopy)
 	if err := configCopy.Validate(); err != nil {
 		return err
 	}
 
 	// Validation passed, apply the change
-	*m.config = configCopy
+	m.config = configCopy
 	return Save(m.paths.ConfigFile, m.config)
 }
@@ -9,6 +9,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/migrate"
+	"github.com/shini4i/openfortivpn-gui/internal/netrules"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -20,6 +24,7 @@ func TestDefaultConfig(t *testing.T) {
 	assert.False(t, cfg.AutoConnect)
 	assert.Equal(t, "/usr/bin/openfortivpn", cfg.OpenFortiVPNPath)
 	assert.Empty(t, cfg.DefaultProfileID)
+	assert.Equal(t, profile.SortByName, cfg.ProfileSortOrder)
 }
 
 func TestGetPaths(t *testing.T) {
@@ -56,6 +61,44 @@ func TestGetPaths(t *testing.T) {
 	})
 }
 
+func TestGetPaths_SystemProfilesDirs(t *testing.T) {
+	tests := []struct {
+		name       string
+		configDirs string
+		want       []string
+	}{
+		{
+			name:       "default XDG_CONFIG_DIRS",
+			configDirs: "",
+			want:       []string{SystemProfilesDir, "/etc/xdg/openfortivpn-gui/profiles"},
+		},
+		{
+			name:       "custom XDG_CONFIG_DIRS in order",
+			configDirs: "/opt/conf:/usr/local/etc",
+			want: []string{
+				SystemProfilesDir,
+				"/opt/conf/openfortivpn-gui/profiles",
+				"/usr/local/etc/openfortivpn-gui/profiles",
+			},
+		},
+		{
+			name:       "relative and duplicate entries are ignored",
+			configDirs: "relative:/etc:/opt/conf:/opt/conf",
+			want:       []string{SystemProfilesDir, "/opt/conf/openfortivpn-gui/profiles"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_DIRS", tt.configDirs)
+
+			paths, err := GetPaths()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, paths.SystemProfilesDirs)
+		})
+	}
+}
+
 func TestPaths_EnsurePaths(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "config-ensure-test")
 	require.NoError(t, err)
@@ -217,6 +260,41 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: "openfortivpn path must not be empty",
 		},
+		{
+			name: "sort by host",
+			config: &Config{
+				OpenFortiVPNPath: "/usr/bin/openfortivpn",
+				ProfileSortOrder: profile.SortByHost,
+			},
+			wantErr: "",
+		},
+		{
+			name: "invalid profile sort order",
+			config: &Config{
+				OpenFortiVPNPath: "/usr/bin/openfortivpn",
+				ProfileSortOrder: "random",
+			},
+			wantErr: "invalid profile sort order",
+		},
+		{
+			name: "network rules",
+			config: &Config{
+				OpenFortiVPNPath: "/usr/bin/openfortivpn",
+				NetworkRules: []netrules.Rule{
+					{Name: "Office", Match: netrules.MatchSSID, Value: "Office", Action: netrules.ActionDisconnect},
+					{Match: netrules.MatchAny, Action: netrules.ActionConnect, ProfileID: "work"},
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "invalid network rule",
+			config: &Config{
+				OpenFortiVPNPath: "/usr/bin/openfortivpn",
+				NetworkRules:     []netrules.Rule{{Match: netrules.MatchAny, Action: netrules.ActionConnect}},
+			},
+			wantErr: "connect rule needs a profile",
+		},
 	}
 
 	for _, tt := range tests {
@@ -322,6 +400,14 @@ func TestManager_GetConfigReturnsCopy(t *testing.T) {
 	cfg2 := manager.GetConfig()
 	assert.Equal(t, originalDelay, cfg2.ReconnectDelaySeconds)
 	assert.NotEqual(t, 999, cfg2.ReconnectDelaySeconds)
+
+	// Rules are copied too
+	require.NoError(t, manager.UpdateField(func(cfg *Config) {
+		cfg.NetworkRules = []netrules.Rule{{Match: netrules.MatchSSID, Value: "Office", Action: netrules.ActionDisconnect}}
+	}))
+	cfg3 := manager.GetConfig()
+	cfg3.NetworkRules[0].Value = "Café"
+	assert.Equal(t, "Office", manager.GetConfig().NetworkRules[0].Value)
 }
 
 func TestManager_GetProfilesPath(t *testing.T) {
@@ -544,3 +630,52 @@ func TestManager_UpdateField(t *testing.T) {
 		assert.Equal(t, "test-profile-123", loaded.DefaultProfileID)
 	})
 }
+
+func TestLoad_MigratesUnversionedConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	original := `{"reconnect_delay_seconds": 10, "openfortivpn_path": "/opt/openfortivpn"}`
+	require.NoError(t, os.WriteFile(configPath, []byte(original), 0600))
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion(), cfg.SchemaVersion)
+	assert.Equal(t, 10, cfg.ReconnectDelaySeconds)
+	// Settings missing from the file keep their defaults
+	assert.Equal(t, 3, cfg.MaxReconnectAttempts)
+	assert.True(t, cfg.ShowNotifications)
+
+	backup, err := os.ReadFile(migrate.BackupPath(configPath, 0))
+	require.NoError(t, err)
+	assert.Equal(t, original, string(backup))
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"schema_version": 1`)
+}
+
+func TestLoad_RejectsNewerSchemaVersion(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"schema_version": 999, "auto_connect": true}`
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0600))
+
+	_, err := Load(configPath)
+	require.ErrorIs(t, err, migrate.ErrNewerVersion)
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data), "file from a newer version must not be modified")
+}
+
+func TestSave_WritesSchemaVersion(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cfg := DefaultConfig()
+	require.NoError(t, Save(configPath, cfg))
+	assert.Zero(t, cfg.SchemaVersion, "Save must not modify the caller's config")
+
+	loaded, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion(), loaded.SchemaVersion)
+
+	_, err = os.Stat(migrate.BackupPath(configPath, 0))
+	assert.True(t, os.IsNotExist(err), "current files must not be backed up")
+}
@@ -0,0 +1,147 @@
+package diagnose
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// CommandName is the command line subcommand that runs diagnostics.
+const CommandName = "diagnose"
+
+// statusMarkers are the prefixes used for each status in text output.
+var statusMarkers = map[Status]string{
+	StatusPass:    "[ OK ]",
+	StatusWarn:    "[WARN]",
+	StatusFail:    "[FAIL]",
+	StatusSkipped: "[SKIP]",
+}
+
+// RunCommand runs the diagnose subcommand with the arguments following its name.
+// It looks up the profile by ID or name in the store, diagnoses each of its gateways
+// and writes the report to stdout. It returns the process exit code: 0 if every
+// gateway passed, 1 if a step failed, and 2 for usage or profile errors.
+func RunCommand(ctx context.Context, args []string, store profile.StoreInterface, stdout, stderr io.Writer, opts ...Option) int {
+	flags := flag.NewFlagSet(CommandName, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	jsonOutput := flags.Bool("json", false, "Write the report as JSON")
+	timeout := flags.Duration("timeout", DefaultTimeout, "Timeout for each network step")
+	noCaptiveCheck := flags.Bool("no-captive-check", false, "Skip the captive portal check")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "Usage: openfortivpn-gui %s [options] <profile name or ID>\n\n", CommandName)
+		_, _ = fmt.Fprintln(stderr, "Checks that a profile's gateways can be reached without connecting.")
+		_, _ = fmt.Fprintln(stderr, "\nOptions:")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	p, err := findProfile(store, flags.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if p.IsTemplate {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", profile.ErrTemplateNotConnectable)
+		return 2
+	}
+	resolved, err := profile.Resolve(p, store)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if len(resolved.Endpoints()) == 0 {
+		_, _ = fmt.Fprintf(stderr, "Error: profile %q has no gateway host\n", resolved.Name)
+		return 2
+	}
+
+	opts = append([]Option{WithTimeout(*timeout)}, opts...)
+	if *noCaptiveCheck {
+		opts = append(opts, WithCaptivePortalCheck("", ""))
+	}
+	reports := NewDiagnoser(opts...).Run(ctx, resolved)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+	} else if err := WriteText(stdout, reports); err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	for _, r := range reports {
+		if !r.OK() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// findProfile returns the profile with the given ID, or the only profile with the given name
+// (case-insensitive).
+func findProfile(store profile.StoreInterface, query string) (*profile.Profile, error) {
+	result, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	var matches []*profile.Profile
+	for _, p := range result.Profiles {
+		if p.ID == query {
+			return p, nil
+		}
+		if strings.EqualFold(p.Name, query) {
+			matches = append(matches, p)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no profile named %q", query)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d profiles are named %q; use the profile ID instead", len(matches), query)
+	}
+}
+
+// WriteText writes reports in a human-readable form, one line per step followed by a summary.
+func WriteText(w io.Writer, reports []*Report) error {
+	var b strings.Builder
+	for i, r := range reports {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s (%s)\n", r.Profile, r.Gateway)
+		for _, s := range r.Steps {
+			fmt.Fprintf(&b, "  %s %-15s %s", statusMarkers[s.Status], s.Name.Label(), s.Detail)
+			if s.Duration > 0 && s.Name != StepLatency {
+				fmt.Fprintf(&b, " (%s)", roundLatency(s.Duration))
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "  %s\n", r.Summary())
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
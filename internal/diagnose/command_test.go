@@ -0,0 +1,99 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+func newCommandStore(t *testing.T, profiles ...*profile.Profile) *profile.Store {
+	t.Helper()
+	store, err := profile.NewStore(t.TempDir())
+	require.NoError(t, err)
+	for _, p := range profiles {
+		require.NoError(t, store.Save(p))
+	}
+	return store
+}
+
+func runCommand(t *testing.T, store profile.StoreInterface, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := RunCommand(context.Background(), args, store, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunCommand(t *testing.T) {
+	server, gw := newGateway(t)
+
+	office := profile.NewProfile("Office")
+	office.Host = gw.Host
+	office.Port = gw.Port
+	office.Username = "jdoe"
+	office.TrustedCert = CertificateDigest(server.Certificate())
+	store := newCommandStore(t, office)
+
+	t.Run("text report by name", func(t *testing.T) {
+		code, stdout, stderr := runCommand(t, store, "-no-captive-check", "office")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, stdout, "Office ("+gw.String()+")")
+		assert.Contains(t, stdout, "[ OK ] TLS handshake")
+		assert.Contains(t, stdout, "[SKIP] Captive portal")
+		assert.Contains(t, stdout, "check your credentials")
+	})
+
+	t.Run("JSON report by ID", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, store, "-json", "-no-captive-check", office.ID)
+		assert.Equal(t, 0, code)
+
+		var reports []*Report
+		require.NoError(t, json.Unmarshal([]byte(stdout), &reports))
+		require.Len(t, reports, 1)
+		assert.Equal(t, StatusPass, reports[0].Step(StepCertificate).Status)
+	})
+
+	t.Run("failing step exits with 1", func(t *testing.T) {
+		office.TrustedCert = "00ff"
+		mismatch := newCommandStore(t, office)
+		code, stdout, _ := runCommand(t, mismatch, "-no-captive-check", "Office")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stdout, "[FAIL] Certificate")
+	})
+}
+
+func TestRunCommand_Errors(t *testing.T) {
+	first := profile.NewProfile("Twin")
+	first.Host = "vpn.example.com"
+	second := profile.NewProfile("twin")
+	second.Host = "vpn.example.com"
+	template := profile.NewProfile("Template")
+	template.IsTemplate = true
+	template.Host = "vpn.example.com"
+	store := newCommandStore(t, first, second, template)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStderr string
+	}{
+		{name: "missing profile argument", args: nil, wantStderr: "Usage"},
+		{name: "unknown profile", args: []string{"Missing"}, wantStderr: `no profile named "Missing"`},
+		{name: "ambiguous name", args: []string{"TWIN"}, wantStderr: "use the profile ID"},
+		{name: "template", args: []string{"Template"}, wantStderr: "template"},
+		{name: "unknown flag", args: []string{"-verbose", "Twin"}, wantStderr: "flag provided but not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCommand(t, store, tt.args...)
+			assert.Equal(t, 2, code)
+			assert.Contains(t, stderr, tt.wantStderr)
+		})
+	}
+}
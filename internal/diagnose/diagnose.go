@@ -0,0 +1,511 @@
+// Package diagnose checks whether a VPN gateway can be reached without bringing up a tunnel.
+//
+// A diagnosis runs a fixed sequence of steps against one gateway endpoint: name
+// resolution, a TCP connection, a TLS handshake, certificate verification, a
+// captive portal check and a latency measurement. Each step reports its own result,
+// so a gateway that is down can be told apart from rejected credentials or a
+// network that intercepts traffic.
+package diagnose
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+const (
+	// DefaultTimeout bounds each network step.
+	DefaultTimeout = 5 * time.Second
+	// DefaultCaptivePortalURL is the connectivity check used by NetworkManager on GNOME.
+	DefaultCaptivePortalURL = "http://nmcheck.gnome.org/check_network_status.txt"
+	// DefaultCaptivePortalResponse is the body returned by DefaultCaptivePortalURL
+	// when traffic is not intercepted.
+	DefaultCaptivePortalResponse = "NetworkManager is online"
+
+	// defaultLatencySamples is the number of TCP connections used to measure latency.
+	defaultLatencySamples = 3
+	// highLatency is the average connection time above which latency is reported as a warning.
+	highLatency = 300 * time.Millisecond
+	// maxCaptiveResponse limits how much of the connectivity check response is read.
+	maxCaptiveResponse = 4096
+)
+
+// StepName identifies a diagnostic step.
+type StepName string
+
+const (
+	// StepDNS resolves the gateway host name.
+	StepDNS StepName = "dns"
+	// StepTCP opens a TCP connection to the gateway.
+	StepTCP StepName = "tcp"
+	// StepTLS completes a TLS handshake with the gateway.
+	StepTLS StepName = "tls"
+	// StepCertificate checks the gateway certificate against the system CAs or the trusted certificate.
+	StepCertificate StepName = "certificate"
+	// StepCaptivePortal checks whether web traffic is intercepted by a captive portal.
+	StepCaptivePortal StepName = "captive_portal"
+	// StepLatency measures the TCP connection time to the gateway.
+	StepLatency StepName = "latency"
+)
+
+// stepLabels holds the human-readable step names.
+var stepLabels = map[StepName]string{
+	StepDNS:           "DNS lookup",
+	StepTCP:           "TCP connection",
+	StepTLS:           "TLS handshake",
+	StepCertificate:   "Certificate",
+	StepCaptivePortal: "Captive portal",
+	StepLatency:       "Latency",
+}
+
+// Label returns a human-readable name for the step.
+func (s StepName) Label() string {
+	if label, ok := stepLabels[s]; ok {
+		return label
+	}
+	return string(s)
+}
+
+// Status is the outcome of a diagnostic step.
+type Status string
+
+const (
+	// StatusPass indicates the step succeeded.
+	StatusPass Status = "pass"
+	// StatusWarn indicates the step succeeded with a possible problem.
+	StatusWarn Status = "warn"
+	// StatusFail indicates the step failed.
+	StatusFail Status = "fail"
+	// StatusSkipped indicates the step did not run, usually because an earlier step failed.
+	StatusSkipped Status = "skipped"
+)
+
+// Step is the result of a single diagnostic step.
+type Step struct {
+	Name     StepName      `json:"name"`
+	Status   Status        `json:"status"`
+	Detail   string        `json:"detail"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Report is the result of diagnosing one gateway endpoint.
+type Report struct {
+	Profile string  `json:"profile"`
+	Gateway string  `json:"gateway"`
+	Steps   []*Step `json:"steps"`
+}
+
+// Step returns the result of the named step, or nil if the report does not contain it.
+func (r *Report) Step(name StepName) *Step {
+	for _, s := range r.Steps {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// OK reports whether no step failed.
+func (r *Report) OK() bool {
+	for _, s := range r.Steps {
+		if s.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary explains the most likely cause of a failure, or confirms that the
+// gateway is reachable so remaining problems are with the credentials.
+func (r *Report) Summary() string {
+	failed := func(name StepName) bool {
+		s := r.Step(name)
+		return s != nil && s.Status == StatusFail
+	}
+
+	switch {
+	case failed(StepCaptivePortal):
+		return "This network intercepts web traffic, likely with a captive portal such as a hotel or airport sign-in page. Sign in with a browser, then try again."
+	case failed(StepDNS):
+		return "The gateway host name could not be resolved. Check the host and your network's DNS."
+	case failed(StepTCP):
+		return "The gateway is unreachable. It may be down, or a firewall blocks the port."
+	case failed(StepTLS):
+		return "The gateway accepted the connection but the TLS handshake failed. Check that the port belongs to the SSL VPN."
+	case failed(StepCertificate):
+		return "The gateway certificate is not trusted. Verify it with your administrator and set it as the trusted certificate."
+	default:
+		return "The gateway is reachable. If connecting still fails, check your credentials."
+	}
+}
+
+// Option configures a Diagnoser.
+type Option func(*Diagnoser)
+
+// WithTimeout sets the timeout for each network step.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Diagnoser) {
+		d.timeout = timeout
+	}
+}
+
+// WithRootCAs sets the CAs used to verify gateway certificates instead of the system CAs.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(d *Diagnoser) {
+		d.rootCAs = pool
+	}
+}
+
+// WithResolver sets the resolver used for the DNS step.
+func WithResolver(resolver *net.Resolver) Option {
+	return func(d *Diagnoser) {
+		d.resolver = resolver
+	}
+}
+
+// WithCaptivePortalCheck sets the connectivity check URL and the response body
+// expected when traffic is not intercepted. An empty URL disables the check.
+func WithCaptivePortalCheck(url, expected string) Option {
+	return func(d *Diagnoser) {
+		d.captiveURL = url
+		d.captiveExpected = expected
+	}
+}
+
+// WithLatencySamples sets the number of TCP connections used to measure latency.
+func WithLatencySamples(n int) Option {
+	return func(d *Diagnoser) {
+		d.latencySamples = n
+	}
+}
+
+// Diagnoser runs gateway diagnostics.
+type Diagnoser struct {
+	timeout         time.Duration
+	rootCAs         *x509.CertPool
+	resolver        *net.Resolver
+	captiveURL      string
+	captiveExpected string
+	latencySamples  int
+}
+
+// NewDiagnoser creates a Diagnoser with the given options.
+func NewDiagnoser(opts ...Option) *Diagnoser {
+	d := &Diagnoser{
+		timeout:         DefaultTimeout,
+		resolver:        net.DefaultResolver,
+		captiveURL:      DefaultCaptivePortalURL,
+		captiveExpected: DefaultCaptivePortalResponse,
+		latencySamples:  defaultLatencySamples,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run diagnoses every gateway endpoint of a resolved profile, primary gateway first.
+func (d *Diagnoser) Run(ctx context.Context, p *profile.Profile) []*Report {
+	endpoints := p.Endpoints()
+	reports := make([]*Report, 0, len(endpoints))
+	for _, gw := range endpoints {
+		report := d.Diagnose(ctx, gw, p.TrustedCert)
+		report.Profile = p.Name
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// Diagnose runs all steps against a gateway. trustedCert is the SHA-256 digest of the
+// certificate to accept, as passed to openfortivpn's --trusted-cert; if empty, the
+// certificate must be valid for the host according to the configured CAs.
+func (d *Diagnoser) Diagnose(ctx context.Context, gw profile.Gateway, trustedCert string) *Report {
+	report := &Report{Gateway: gw.String()}
+	add := func(s *Step) { report.Steps = append(report.Steps, s) }
+
+	addrs, step := d.resolve(ctx, gw.Host)
+	add(step)
+
+	var conn net.Conn
+	var addr string
+	if step.Status == StatusPass {
+		addr = net.JoinHostPort(addrs[0], strconv.Itoa(gw.Port))
+		conn, step = d.dial(ctx, addr)
+		add(step)
+	} else {
+		add(skipped(StepTCP, "host name was not resolved"))
+	}
+
+	var state *tls.ConnectionState
+	if conn != nil {
+		state, step = d.handshake(ctx, conn, gw.Host)
+		_ = conn.Close()
+		add(step)
+	} else {
+		add(skipped(StepTLS, "no TCP connection"))
+	}
+
+	if state != nil {
+		add(d.verifyCertificate(state, gw.Host, trustedCert))
+	} else {
+		add(skipped(StepCertificate, "no TLS handshake"))
+	}
+
+	add(d.checkCaptivePortal(ctx))
+
+	if conn != nil {
+		add(d.measureLatency(ctx, addr))
+	} else {
+		add(skipped(StepLatency, "no TCP connection"))
+	}
+
+	return report
+}
+
+// skipped returns a step that did not run.
+func skipped(name StepName, reason string) *Step {
+	return &Step{Name: name, Status: StatusSkipped, Detail: "Skipped: " + reason}
+}
+
+// resolve looks up the gateway host. IP addresses are used as is.
+func (d *Diagnoser) resolve(ctx context.Context, host string) ([]string, *Step) {
+	step := &Step{Name: StepDNS}
+	if net.ParseIP(host) != nil {
+		step.Status = StatusPass
+		step.Detail = host + " is an IP address"
+		return []string{host}, step
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := d.resolver.LookupHost(ctx, host)
+	step.Duration = time.Since(start)
+	if err == nil && len(addrs) == 0 {
+		err = errors.New("no addresses found")
+	}
+	if err != nil {
+		step.Status = StatusFail
+		step.Detail = fmt.Sprintf("Could not resolve %s: %v", host, err)
+		return nil, step
+	}
+
+	step.Status = StatusPass
+	step.Detail = fmt.Sprintf("%s resolved to %s", host, strings.Join(addrs, ", "))
+	return addrs, step
+}
+
+// dial opens a TCP connection to the gateway.
+func (d *Diagnoser) dial(ctx context.Context, addr string) (net.Conn, *Step) {
+	step := &Step{Name: StepTCP}
+	dialer := &net.Dialer{Timeout: d.timeout}
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	step.Duration = time.Since(start)
+	if err != nil {
+		step.Status = StatusFail
+		step.Detail = fmt.Sprintf("Could not connect to %s: %v", addr, err)
+		return nil, step
+	}
+
+	step.Status = StatusPass
+	step.Detail = "Connected to " + addr
+	return conn, step
+}
+
+// handshake completes a TLS handshake on the connection without verifying the
+// certificate, so verification can be reported as a separate step.
+func (d *Diagnoser) handshake(ctx context.Context, conn net.Conn, host string) (*tls.ConnectionState, *Step) {
+	step := &Step{Name: StepTLS}
+	cfg := &tls.Config{
+		// #nosec G402 -- the certificate is verified explicitly in the certificate step
+		InsecureSkipVerify: true,
+	}
+	if net.ParseIP(host) == nil {
+		cfg.ServerName = host
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	tlsConn := tls.Client(conn, cfg)
+	start := time.Now()
+	err := tlsConn.HandshakeContext(ctx)
+	step.Duration = time.Since(start)
+	if err != nil {
+		step.Status = StatusFail
+		step.Detail = fmt.Sprintf("TLS handshake failed: %v", err)
+		return nil, step
+	}
+
+	state := tlsConn.ConnectionState()
+	step.Status = StatusPass
+	step.Detail = fmt.Sprintf("%s, %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	return &state, step
+}
+
+// verifyCertificate checks the gateway certificate the way openfortivpn does:
+// a valid chain for the host is accepted, and only when it is not does the
+// certificate have to match the configured trusted certificate digest.
+func (d *Diagnoser) verifyCertificate(state *tls.ConnectionState, host, trustedCert string) *Step {
+	step := &Step{Name: StepCertificate}
+	if len(state.PeerCertificates) == 0 {
+		step.Status = StatusFail
+		step.Detail = "The gateway did not present a certificate"
+		return step
+	}
+
+	leaf := state.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         d.rootCAs,
+		Intermediates: intermediates,
+	})
+	if err == nil {
+		step.Status = StatusPass
+		step.Detail = fmt.Sprintf("Valid certificate for %s issued by %s", host, leaf.Issuer.CommonName)
+		return step
+	}
+
+	digest := CertificateDigest(leaf)
+	switch {
+	case trustedCert == "":
+		step.Status = StatusFail
+		step.Detail = fmt.Sprintf("%v. To trust this certificate, use digest %s", err, digest)
+	case normalizeDigest(trustedCert) == digest:
+		step.Status = StatusPass
+		step.Detail = "Certificate matches the trusted certificate"
+	default:
+		step.Status = StatusFail
+		step.Detail = fmt.Sprintf("%v, and digest %s does not match the trusted certificate", err, digest)
+	}
+	return step
+}
+
+// CertificateDigest returns the SHA-256 digest of the certificate in the
+// hexadecimal form used by openfortivpn's --trusted-cert option.
+func CertificateDigest(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeDigest lowercases a digest and removes the colons some tools insert between bytes.
+func normalizeDigest(digest string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(digest), ":", ""))
+}
+
+// checkCaptivePortal requests the connectivity check URL without following redirects.
+// A redirect or an unexpected response means web traffic is intercepted.
+func (d *Diagnoser) checkCaptivePortal(ctx context.Context) *Step {
+	step := &Step{Name: StepCaptivePortal}
+	if d.captiveURL == "" {
+		return skipped(StepCaptivePortal, "check is disabled")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.captiveURL, nil)
+	if err != nil {
+		return skipped(StepCaptivePortal, err.Error())
+	}
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	step.Duration = time.Since(start)
+	if err != nil {
+		step.Status = StatusWarn
+		step.Detail = fmt.Sprintf("Could not reach the connectivity check: %v", err)
+		return step
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if location := resp.Header.Get("Location"); resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		step.Status = StatusFail
+		step.Detail = fmt.Sprintf("Web traffic is redirected to %s", location)
+		return step
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxCaptiveResponse))
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(strings.TrimSpace(string(body)), d.captiveExpected) {
+		step.Status = StatusFail
+		step.Detail = fmt.Sprintf("Web traffic is intercepted: the connectivity check returned %s with unexpected content", resp.Status)
+		return step
+	}
+
+	step.Status = StatusPass
+	step.Detail = "No captive portal detected"
+	return step
+}
+
+// measureLatency opens several TCP connections to the gateway and reports the connection times.
+func (d *Diagnoser) measureLatency(ctx context.Context, addr string) *Step {
+	step := &Step{Name: StepLatency}
+	dialer := &net.Dialer{Timeout: d.timeout}
+
+	var total, best time.Duration
+	samples := 0
+	for i := 0; i < d.latencySamples; i++ {
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		elapsed := time.Since(start)
+		if err != nil {
+			continue
+		}
+		_ = conn.Close()
+
+		total += elapsed
+		if samples == 0 || elapsed < best {
+			best = elapsed
+		}
+		samples++
+	}
+
+	if samples == 0 {
+		step.Status = StatusFail
+		step.Detail = "No connection succeeded while measuring latency"
+		return step
+	}
+
+	avg := total / time.Duration(samples)
+	step.Duration = avg
+	step.Detail = fmt.Sprintf("Average %s, best %s over %d connections", roundLatency(avg), roundLatency(best), samples)
+	if avg > highLatency {
+		step.Status = StatusWarn
+		step.Detail += "; high latency may cause timeouts"
+	} else {
+		step.Status = StatusPass
+	}
+	return step
+}
+
+// roundLatency rounds a duration for display.
+func roundLatency(d time.Duration) time.Duration {
+	if d >= 10*time.Millisecond {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
@@ -0,0 +1,278 @@
+package diagnose
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// newGateway starts a local TLS server and returns it with its endpoint.
+func newGateway(t *testing.T) (*httptest.Server, profile.Gateway) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	return server, endpoint(t, server.Listener.Addr().String())
+}
+
+func endpoint(t *testing.T, addr string) profile.Gateway {
+	t.Helper()
+	host, portStr, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+	return profile.Gateway{Host: host, Port: port}
+}
+
+// newConnectivityCheck starts a local server answering like the connectivity check.
+func newConnectivityCheck(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func onlineCheck(t *testing.T) Option {
+	return WithCaptivePortalCheck(newConnectivityCheck(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("NetworkManager is online\n"))
+	}), DefaultCaptivePortalResponse)
+}
+
+func rootsFor(server *httptest.Server) *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	return pool
+}
+
+func statuses(r *Report) map[StepName]Status {
+	result := make(map[StepName]Status, len(r.Steps))
+	for _, s := range r.Steps {
+		result[s.Name] = s.Status
+	}
+	return result
+}
+
+func TestDiagnose_HealthyGateway(t *testing.T) {
+	server, gw := newGateway(t)
+	d := NewDiagnoser(WithRootCAs(rootsFor(server)), onlineCheck(t), WithTimeout(2*time.Second))
+
+	report := d.Diagnose(context.Background(), gw, "")
+
+	assert.Equal(t, map[StepName]Status{
+		StepDNS:           StatusPass,
+		StepTCP:           StatusPass,
+		StepTLS:           StatusPass,
+		StepCertificate:   StatusPass,
+		StepCaptivePortal: StatusPass,
+		StepLatency:       StatusPass,
+	}, statuses(report))
+	assert.True(t, report.OK())
+	assert.Equal(t, gw.String(), report.Gateway)
+	assert.Contains(t, report.Summary(), "check your credentials")
+	assert.Contains(t, report.Step(StepTLS).Detail, "TLS 1.3")
+}
+
+func TestDiagnose_StepOrder(t *testing.T) {
+	_, gw := newGateway(t)
+	report := NewDiagnoser(WithCaptivePortalCheck("", "")).Diagnose(context.Background(), gw, "")
+
+	var names []StepName
+	for _, s := range report.Steps {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []StepName{StepDNS, StepTCP, StepTLS, StepCertificate, StepCaptivePortal, StepLatency}, names)
+}
+
+func TestDiagnose_Certificate(t *testing.T) {
+	server, gw := newGateway(t)
+	digest := CertificateDigest(server.Certificate())
+
+	tests := []struct {
+		name        string
+		trustedCert string
+		roots       *x509.CertPool
+		want        Status
+		wantDetail  string
+	}{
+		{name: "trusted digest matches", trustedCert: digest, want: StatusPass, wantDetail: "trusted certificate"},
+		{name: "trusted digest with colons and uppercase", trustedCert: colonize(digest), want: StatusPass},
+		{name: "trusted digest mismatch", trustedCert: "00ff", want: StatusFail, wantDetail: digest},
+		{name: "valid chain", roots: rootsFor(server), want: StatusPass},
+		{name: "valid chain despite stale digest", trustedCert: "00ff", roots: rootsFor(server), want: StatusPass, wantDetail: "Valid certificate"},
+		{name: "untrusted chain reports digest", roots: x509.NewCertPool(), want: StatusFail, wantDetail: digest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDiagnoser(WithRootCAs(tt.roots), WithCaptivePortalCheck("", ""))
+			report := d.Diagnose(context.Background(), gw, tt.trustedCert)
+
+			step := report.Step(StepCertificate)
+			require.NotNil(t, step)
+			assert.Equal(t, tt.want, step.Status, step.Detail)
+			assert.Contains(t, step.Detail, tt.wantDetail)
+			if tt.want == StatusFail {
+				assert.Contains(t, report.Summary(), "certificate is not trusted")
+			}
+		})
+	}
+}
+
+// colonize formats a hex digest as uppercase byte pairs separated by colons.
+func colonize(digest string) string {
+	pairs := make([]string, 0, len(digest)/2)
+	for i := 0; i+1 < len(digest); i += 2 {
+		pairs = append(pairs, strings.ToUpper(digest[i:i+2]))
+	}
+	return strings.Join(pairs, ":")
+}
+
+func TestDiagnose_GatewayDown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	gw := endpoint(t, listener.Addr().String())
+	require.NoError(t, listener.Close())
+
+	report := NewDiagnoser(onlineCheck(t)).Diagnose(context.Background(), gw, "")
+
+	assert.Equal(t, map[StepName]Status{
+		StepDNS:           StatusPass,
+		StepTCP:           StatusFail,
+		StepTLS:           StatusSkipped,
+		StepCertificate:   StatusSkipped,
+		StepCaptivePortal: StatusPass,
+		StepLatency:       StatusSkipped,
+	}, statuses(report))
+	assert.False(t, report.OK())
+	assert.Contains(t, report.Summary(), "unreachable")
+}
+
+func TestDiagnose_NotTLS(t *testing.T) {
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	gw := endpoint(t, plain.Listener.Addr().String())
+
+	report := NewDiagnoser(WithCaptivePortalCheck("", ""), WithTimeout(time.Second)).Diagnose(context.Background(), gw, "")
+
+	assert.Equal(t, StatusPass, report.Step(StepTCP).Status)
+	assert.Equal(t, StatusFail, report.Step(StepTLS).Status)
+	assert.Equal(t, StatusSkipped, report.Step(StepCertificate).Status)
+	assert.Contains(t, report.Summary(), "TLS handshake failed")
+}
+
+func TestDiagnose_DNSFailure(t *testing.T) {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("no name server")
+		},
+	}
+	d := NewDiagnoser(WithResolver(resolver), WithCaptivePortalCheck("", ""))
+
+	report := d.Diagnose(context.Background(), profile.Gateway{Host: "vpn.diagnose-test.invalid", Port: 443}, "")
+
+	assert.Equal(t, StatusFail, report.Step(StepDNS).Status)
+	assert.Equal(t, StatusSkipped, report.Step(StepTCP).Status)
+	assert.Equal(t, StatusSkipped, report.Step(StepTLS).Status)
+	assert.Contains(t, report.Summary(), "could not be resolved")
+}
+
+func TestDiagnose_CaptivePortal(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    Status
+	}{
+		{
+			name: "online",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("NetworkManager is online\n"))
+			},
+			want: StatusPass,
+		},
+		{
+			name: "redirect to login page",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://login.hotel.example/", http.StatusFound)
+			},
+			want: StatusFail,
+		},
+		{
+			name: "login page served in place",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("<html>Welcome to Hotel Wi-Fi</html>"))
+			},
+			want: StatusFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, gw := newGateway(t)
+			url := newConnectivityCheck(t, tt.handler)
+			d := NewDiagnoser(WithCaptivePortalCheck(url, DefaultCaptivePortalResponse))
+
+			report := d.Diagnose(context.Background(), gw, "")
+			assert.Equal(t, tt.want, report.Step(StepCaptivePortal).Status)
+			if tt.want == StatusFail {
+				assert.Contains(t, report.Summary(), "captive portal")
+			}
+		})
+	}
+
+	t.Run("check unreachable is a warning", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		url := "http://" + listener.Addr().String() + "/"
+		require.NoError(t, listener.Close())
+
+		_, gw := newGateway(t)
+		report := NewDiagnoser(WithCaptivePortalCheck(url, DefaultCaptivePortalResponse)).Diagnose(context.Background(), gw, "")
+		assert.Equal(t, StatusWarn, report.Step(StepCaptivePortal).Status)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, gw := newGateway(t)
+		report := NewDiagnoser(WithCaptivePortalCheck("", "")).Diagnose(context.Background(), gw, "")
+		assert.Equal(t, StatusSkipped, report.Step(StepCaptivePortal).Status)
+	})
+}
+
+func TestDiagnoser_Run(t *testing.T) {
+	server, primary := newGateway(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	backup := endpoint(t, listener.Addr().String())
+	require.NoError(t, listener.Close())
+
+	p := profile.NewProfile("HA")
+	p.Host = primary.Host
+	p.Port = primary.Port
+	p.Gateways = []profile.Gateway{backup}
+	p.TrustedCert = CertificateDigest(server.Certificate())
+
+	reports := NewDiagnoser(WithCaptivePortalCheck("", "")).Run(context.Background(), p)
+
+	require.Len(t, reports, 2)
+	assert.Equal(t, "HA", reports[0].Profile)
+	assert.Equal(t, primary.String(), reports[0].Gateway)
+	assert.True(t, reports[0].OK())
+	assert.Equal(t, backup.String(), reports[1].Gateway)
+	assert.False(t, reports[1].OK())
+}
+
+func TestStepName_Label(t *testing.T) {
+	assert.Equal(t, "TLS handshake", StepTLS.Label())
+	assert.Equal(t, "custom", StepName("custom").Label())
+}
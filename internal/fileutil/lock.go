@@ -0,0 +1,65 @@
+package fileutil
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// FileLock is an advisory lock held on a file with flock(2).
+// Advisory locks only coordinate processes that also take the lock;
+// they do not prevent other writers from modifying the file.
+type FileLock struct {
+	file *os.File
+}
+
+// LockShared acquires a shared lock on the file at path, creating it if needed.
+// Any number of shared locks can be held at once, but none while an exclusive
+// lock is held. The call blocks until the lock is available.
+func LockShared(path string) (*FileLock, error) {
+	return lock(path, syscall.LOCK_SH)
+}
+
+// LockExclusive acquires an exclusive lock on the file at path, creating it if needed.
+// The call blocks until no other process holds a shared or exclusive lock.
+func LockExclusive(path string) (*FileLock, error) {
+	return lock(path, syscall.LOCK_EX)
+}
+
+func lock(path string, how int) (*FileLock, error) {
+	// #nosec G304 -- lock files live in directories chosen by the caller
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+
+	for {
+		err = syscall.Flock(int(f.Fd()), how)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+
+	return &FileLock{file: f}, nil
+}
+
+// Unlock releases the lock. It is safe to call more than once.
+func (l *FileLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	f := l.file
+	l.file = nil
+
+	// Closing the descriptor releases the lock as well, but unlocking first
+	// keeps the release independent of other descriptors sharing the file.
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
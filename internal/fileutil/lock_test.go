@@ -0,0 +1,115 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockExclusive_CreatesLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+
+	l, err := LockExclusive(path)
+	require.NoError(t, err)
+	defer func() { _ = l.Unlock() }()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestLockExclusive_BlocksUntilUnlocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+
+	first, err := LockExclusive(path)
+	require.NoError(t, err)
+
+	acquired := make(chan *FileLock)
+	go func() {
+		second, err := LockExclusive(path)
+		assert.NoError(t, err)
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second exclusive lock acquired while the first was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, first.Unlock())
+
+	select {
+	case second := <-acquired:
+		require.NoError(t, second.Unlock())
+	case <-time.After(2 * time.Second):
+		t.Fatal("second exclusive lock not acquired after unlock")
+	}
+}
+
+func TestLockShared_AllowsConcurrentReaders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+
+	first, err := LockShared(path)
+	require.NoError(t, err)
+	defer func() { _ = first.Unlock() }()
+
+	done := make(chan struct{})
+	go func() {
+		second, err := LockShared(path)
+		assert.NoError(t, err)
+		_ = second.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("shared lock blocked by another shared lock")
+	}
+}
+
+func TestLockShared_BlockedByExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+
+	writer, err := LockExclusive(path)
+	require.NoError(t, err)
+
+	acquired := make(chan *FileLock)
+	go func() {
+		reader, err := LockShared(path)
+		assert.NoError(t, err)
+		acquired <- reader
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("shared lock acquired while an exclusive lock was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, writer.Unlock())
+	reader := <-acquired
+	require.NoError(t, reader.Unlock())
+}
+
+func TestUnlock_Idempotent(t *testing.T) {
+	l, err := LockShared(filepath.Join(t.TempDir(), ".lock"))
+	require.NoError(t, err)
+
+	require.NoError(t, l.Unlock())
+	assert.NoError(t, l.Unlock())
+
+	var nilLock *FileLock
+	assert.NoError(t, nilLock.Unlock())
+}
+
+func TestLock_DirectoryNotExist(t *testing.T) {
+	_, err := LockExclusive("/nonexistent/dir/.lock")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "open lock file")
+}
@@ -0,0 +1,112 @@
+// Package alwayson loads the always-on system profile, which the helper daemon
+// connects at boot and keeps up without any user session, such as on kiosk and
+// build machines nobody logs in to.
+//
+// Root defines the profile in a file of the usual profile format that also sets
+// the profile ID. Password authentication reads the password from a file next
+// to it with the .password extension; certificate authentication may omit it.
+// Both files must belong to the user the helper runs as and must not be
+// writable by anyone else, and the password file must not be readable either.
+package alwayson
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// DefaultPath is where root defines the always-on profile.
+const DefaultPath = "/etc/openfortivpn-gui/always-on.json"
+
+// ErrNotConfigured is returned by Load if no always-on profile is defined.
+var ErrNotConfigured = errors.New("no always-on profile configured")
+
+// Config is the always-on profile with its credentials.
+type Config struct {
+	// Profile is the resolved and validated always-on profile.
+	Profile *profile.Profile
+	// Password authenticates the profile; it is empty for certificate authentication without one.
+	Password string
+}
+
+// PasswordPath returns the password file belonging to the profile file at path.
+func PasswordPath(path string) string {
+	return strings.TrimSuffix(path, ".json") + ".password"
+}
+
+// Load reads the always-on profile at path and its password file.
+// It returns ErrNotConfigured if the profile file does not exist.
+func Load(path string) (*Config, error) {
+	data, err := readOwnFile(path, 0o022)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotConfigured
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := profile.DecodeSystemFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid always-on profile %s: %w", path, err)
+	}
+	if p.IsTemplate || p.ParentID != "" {
+		return nil, fmt.Errorf("always-on profile %s cannot be a template or based on one", path)
+	}
+	switch p.AuthMethod {
+	case profile.AuthMethodPassword, profile.AuthMethodCertificate:
+	default:
+		return nil, fmt.Errorf("always-on profile %s must use password or certificate authentication, since nobody is there to complete %s", path, p.AuthMethod)
+	}
+
+	// Nothing to inherit; this marks the profile as resolved for validation
+	resolved, err := profile.Resolve(p, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolved.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid always-on profile %s: %w", path, err)
+	}
+	// Kept up no matter what the file says
+	resolved.AutoReconnect = true
+
+	cfg := &Config{Profile: resolved}
+	passwordPath := PasswordPath(path)
+	password, err := readOwnFile(passwordPath, 0o077)
+	switch {
+	case err == nil:
+		cfg.Password = strings.TrimRight(string(password), "\r\n")
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+	if cfg.Password == "" && resolved.AuthMethod == profile.AuthMethodPassword {
+		return nil, fmt.Errorf("always-on profile %s uses password authentication, but %s holds no password", path, passwordPath)
+	}
+
+	return cfg, nil
+}
+
+// readOwnFile reads a file that belongs to the current user and has none of the
+// forbidden permission bits, so other users cannot change or read what they must not.
+func readOwnFile(path string, forbidden fs.FileMode) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s must be a regular file", path)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Geteuid() {
+		return nil, fmt.Errorf("%s must be owned by uid %d, not %d", path, os.Geteuid(), stat.Uid)
+	}
+	if perm := info.Mode().Perm(); perm&forbidden != 0 {
+		return nil, fmt.Errorf("%s has permissions %04o, remove %04o", path, perm, perm&forbidden)
+	}
+
+	// #nosec G304 -- the path is configured by root and its ownership was checked
+	return os.ReadFile(path)
+}
@@ -0,0 +1,120 @@
+package alwayson
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+const testProfileID = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+// writeProfile writes an always-on profile file with the given fields over the defaults.
+func writeProfile(t *testing.T, fields map[string]any, perm os.FileMode) string {
+	t.Helper()
+	doc := map[string]any{
+		"schema_version": profile.CurrentSchemaVersion(),
+		"id":             testProfileID,
+		"name":           "Kiosk",
+		"host":           "vpn.example.com",
+		"port":           443,
+		"username":       "kiosk",
+		"auth_method":    "password",
+	}
+	for k, v := range fields {
+		doc[k] = v
+	}
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "always-on.json")
+	require.NoError(t, os.WriteFile(path, data, perm))
+	return path
+}
+
+func TestLoad_NotConfigured(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "always-on.json"))
+	assert.ErrorIs(t, err, ErrNotConfigured)
+}
+
+func TestLoad_Password(t *testing.T) {
+	path := writeProfile(t, map[string]any{"auto_reconnect": false}, 0o644)
+	require.NoError(t, os.WriteFile(PasswordPath(path), []byte("s3cret\n"), 0o600))
+
+	cfg, err := Load(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, testProfileID, cfg.Profile.ID)
+	assert.Equal(t, "Kiosk", cfg.Profile.Name)
+	assert.True(t, cfg.Profile.System)
+	assert.True(t, cfg.Profile.AutoReconnect, "always-on profiles are always reconnected")
+	assert.Equal(t, "s3cret", cfg.Password)
+}
+
+func TestLoad_CertificateWithoutPassword(t *testing.T) {
+	path := writeProfile(t, map[string]any{
+		"auth_method":      "certificate",
+		"client_cert_path": "/etc/openfortivpn-gui/kiosk.pem",
+		"client_key_path":  "/etc/openfortivpn-gui/kiosk.key",
+	}, 0o644)
+
+	cfg, err := Load(path)
+
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Password)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		fields       map[string]any
+		perm         os.FileMode
+		password     string
+		passwordPerm os.FileMode
+		wantErr      string
+	}{
+		{name: "missing password", perm: 0o644, wantErr: "holds no password"},
+		{name: "readable password", perm: 0o644, password: "s3cret", passwordPerm: 0o640, wantErr: "permissions 0640"},
+		{name: "writable profile", perm: 0o666, password: "s3cret", passwordPerm: 0o600, wantErr: "permissions 0666"},
+		{name: "SAML", fields: map[string]any{"auth_method": "saml"}, perm: 0o644, wantErr: "password or certificate"},
+		{name: "OTP", fields: map[string]any{"auth_method": "otp"}, perm: 0o644, password: "s3cret", passwordPerm: 0o600, wantErr: "password or certificate"},
+		{name: "template", fields: map[string]any{"is_template": true}, perm: 0o644, password: "s3cret", passwordPerm: 0o600, wantErr: "template"},
+		{name: "invalid profile", fields: map[string]any{"host": ""}, perm: 0o644, password: "s3cret", passwordPerm: 0o600, wantErr: "invalid always-on profile"},
+		{name: "no ID", fields: map[string]any{"id": ""}, perm: 0o644, wantErr: "profile ID is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeProfile(t, tt.fields, tt.perm)
+			// WriteFile leaves the permissions of the umask alone
+			require.NoError(t, os.Chmod(path, tt.perm))
+			if tt.password != "" {
+				require.NoError(t, os.WriteFile(PasswordPath(path), []byte(tt.password), tt.passwordPerm))
+				require.NoError(t, os.Chmod(PasswordPath(path), tt.passwordPerm))
+			}
+
+			_, err := Load(path)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoad_Symlink(t *testing.T) {
+	target := writeProfile(t, nil, 0o644)
+	path := filepath.Join(t.TempDir(), "always-on.json")
+	require.NoError(t, os.Symlink(target, path))
+
+	_, err := Load(path)
+	assert.ErrorContains(t, err, "regular file")
+}
+
+func TestPasswordPath(t *testing.T) {
+	assert.Equal(t, "/etc/openfortivpn-gui/always-on.password", PasswordPath(DefaultPath))
+}
@@ -0,0 +1,216 @@
+// Package firewall provides the kill switch of the helper daemon: an nftables
+// table that only lets traffic through the VPN tunnel, to the VPN gateways, on
+// loopback, within allowed LAN ranges and, until the tunnel is up, to the name
+// servers resolving the gateways, so nothing leaks while the tunnel is down.
+//
+// Every base chain at a hook must accept a packet, so the table's drop policy
+// applies regardless of other firewall rules on the system.
+package firewall
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const (
+	// TableName is the name of the kill switch's table in the inet family.
+	TableName = "openfortivpn_gui"
+	// defaultNftPath is the nft binary used unless WithNft sets another.
+	defaultNftPath = "nft"
+)
+
+// Rules describes the traffic the kill switch lets through besides the tunnel and loopback.
+type Rules struct {
+	// Gateways are the addresses and TCP ports of the VPN gateways.
+	Gateways []netip.AddrPort
+	// AllowedLANs are the address ranges reachable outside the tunnel.
+	AllowedLANs []netip.Prefix
+	// Tunnel is the interface of the tunnel once it is up. Until it is set,
+	// nothing goes through any tunnel, and other ppp interfaces stay blocked.
+	Tunnel string
+	// NameServers are reachable on port 53 until the tunnel is up, so
+	// openfortivpn can resolve gateway host names while it is down.
+	NameServers []netip.Addr
+}
+
+// Ruleset returns the nft script creating the kill switch's table.
+func (r Rules) Ruleset() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "table inet %s {\n", TableName)
+
+	b.WriteString("\tchain output {\n")
+	b.WriteString("\t\ttype filter hook output priority 0; policy drop;\n")
+	b.WriteString("\t\toifname \"lo\" accept\n")
+	if r.Tunnel != "" {
+		fmt.Fprintf(&b, "\t\toifname %q accept\n", r.Tunnel)
+	}
+	for _, gw := range r.Gateways {
+		fmt.Fprintf(&b, "\t\t%s daddr %s tcp dport %d accept\n", family(gw.Addr()), gw.Addr().Unmap(), gw.Port())
+	}
+	for _, lan := range r.AllowedLANs {
+		fmt.Fprintf(&b, "\t\t%s daddr %s accept\n", family(lan.Addr()), lan.Masked())
+	}
+	if r.Tunnel == "" {
+		for _, ns := range r.NameServers {
+			fmt.Fprintf(&b, "\t\t%s daddr %s udp dport 53 accept\n", family(ns), ns.Unmap())
+			fmt.Fprintf(&b, "\t\t%s daddr %s tcp dport 53 accept\n", family(ns), ns.Unmap())
+		}
+	}
+	// The uplink keeps its address while the tunnel is down
+	b.WriteString("\t\tudp sport 68 udp dport 67 accept\n")
+	b.WriteString("\t\tudp sport 546 udp dport 547 accept\n")
+	b.WriteString("\t\ticmpv6 type { nd-router-solicit, nd-neighbor-solicit, nd-neighbor-advert } accept\n")
+	b.WriteString("\t}\n\n")
+
+	b.WriteString("\tchain input {\n")
+	b.WriteString("\t\ttype filter hook input priority 0; policy drop;\n")
+	b.WriteString("\t\tiifname \"lo\" accept\n")
+	if r.Tunnel != "" {
+		fmt.Fprintf(&b, "\t\tiifname %q accept\n", r.Tunnel)
+	}
+	b.WriteString("\t\tct state established,related accept\n")
+	for _, lan := range r.AllowedLANs {
+		fmt.Fprintf(&b, "\t\t%s saddr %s accept\n", family(lan.Addr()), lan.Masked())
+	}
+	b.WriteString("\t\tudp sport 67 udp dport 68 accept\n")
+	b.WriteString("\t\tudp sport 547 udp dport 546 accept\n")
+	b.WriteString("\t\ticmpv6 type { nd-router-advert, nd-neighbor-solicit, nd-neighbor-advert } accept\n")
+	b.WriteString("\t}\n\n")
+
+	// Containers and virtual machines must not get around the tunnel either
+	b.WriteString("\tchain forward {\n")
+	b.WriteString("\t\ttype filter hook forward priority 0; policy drop;\n")
+	if r.Tunnel != "" {
+		fmt.Fprintf(&b, "\t\toifname %q accept\n", r.Tunnel)
+		fmt.Fprintf(&b, "\t\tiifname %q accept\n", r.Tunnel)
+	}
+	for _, lan := range r.AllowedLANs {
+		fmt.Fprintf(&b, "\t\t%s daddr %s accept\n", family(lan.Addr()), lan.Masked())
+		fmt.Fprintf(&b, "\t\t%s saddr %s accept\n", family(lan.Addr()), lan.Masked())
+	}
+	b.WriteString("\t}\n")
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// family returns the nft payload protocol matching the address.
+func family(addr netip.Addr) string {
+	if addr.Unmap().Is4() {
+		return "ip"
+	}
+	return "ip6"
+}
+
+// removeTable is the nft script deleting the table. Declaring the table first
+// makes deleting it succeed when it does not exist.
+var removeTable = fmt.Sprintf("table inet %[1]s\ndelete table inet %[1]s\n", TableName)
+
+// Runner runs nft with the given arguments and standard input, returning its output.
+type Runner func(ctx context.Context, stdin string, args ...string) ([]byte, error)
+
+// Option configures a KillSwitch.
+type Option func(*KillSwitch)
+
+// WithNft sets the nft binary the kill switch runs.
+func WithNft(path string) Option {
+	return func(k *KillSwitch) {
+		k.run = execRunner(path)
+	}
+}
+
+// WithRunner sets how the kill switch runs nft. Tests use it to run nft in a
+// network namespace or to record the scripts.
+func WithRunner(run Runner) Option {
+	return func(k *KillSwitch) {
+		k.run = run
+	}
+}
+
+// KillSwitch installs and removes the kill switch's table.
+// It is safe for concurrent use.
+type KillSwitch struct {
+	run Runner
+
+	mu     sync.Mutex
+	active bool
+}
+
+// NewKillSwitch creates a kill switch. Nothing is blocked until Enable is called.
+func NewKillSwitch(opts ...Option) *KillSwitch {
+	k := &KillSwitch{run: execRunner(defaultNftPath)}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+// Enable installs the table for the given rules, replacing the rules of an
+// active kill switch in a single transaction so nothing leaks in between.
+func (k *KillSwitch) Enable(ctx context.Context, rules Rules) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, err := k.run(ctx, removeTable+rules.Ruleset(), "-f", "-"); err != nil {
+		return fmt.Errorf("failed to install kill switch: %w", err)
+	}
+	k.active = true
+	return nil
+}
+
+// Disable removes the table, letting traffic outside the tunnel through again.
+// It succeeds if no kill switch is active.
+func (k *KillSwitch) Disable(ctx context.Context) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, err := k.run(ctx, removeTable, "-f", "-"); err != nil {
+		return fmt.Errorf("failed to remove kill switch: %w", err)
+	}
+	k.active = false
+	return nil
+}
+
+// Active reports whether the kill switch blocks traffic outside the tunnel.
+func (k *KillSwitch) Active() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.active
+}
+
+// Detect picks up a table left by a helper that did not shut down cleanly, so
+// it stays in place until the kill switch is disabled. It reports whether one exists.
+func (k *KillSwitch) Detect(ctx context.Context) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, err := k.run(ctx, "", "list", "table", "inet", TableName); err != nil {
+		return false
+	}
+	k.active = true
+	return true
+}
+
+// execRunner returns a Runner executing the nft binary at path.
+func execRunner(path string) Runner {
+	return func(ctx context.Context, stdin string, args ...string) ([]byte, error) {
+		// #nosec G204 -- the binary is configured by the administrator and the arguments are fixed
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Stdin = strings.NewReader(stdin)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return out, fmt.Errorf("%w: %s", err, msg)
+			}
+			return out, err
+		}
+		return out, nil
+	}
+}
@@ -0,0 +1,190 @@
+package firewall
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingRunner records the nft invocations of a kill switch.
+type recordingRunner struct {
+	stdins []string
+	args   [][]string
+	err    error
+}
+
+func (r *recordingRunner) run(_ context.Context, stdin string, args ...string) ([]byte, error) {
+	r.stdins = append(r.stdins, stdin)
+	r.args = append(r.args, args)
+	return nil, r.err
+}
+
+func testRules() Rules {
+	return Rules{
+		Gateways: []netip.AddrPort{
+			netip.MustParseAddrPort("203.0.113.10:443"),
+			netip.MustParseAddrPort("[2001:db8::10]:10443"),
+		},
+		AllowedLANs: []netip.Prefix{
+			netip.MustParsePrefix("192.168.1.7/24"),
+			netip.MustParsePrefix("fd00::/64"),
+		},
+		Tunnel: "ppp0",
+	}
+}
+
+func TestRules_Ruleset(t *testing.T) {
+	ruleset := testRules().Ruleset()
+
+	assert.True(t, strings.HasPrefix(ruleset, "table inet openfortivpn_gui {\n"))
+	for _, rule := range []string{
+		"type filter hook output priority 0; policy drop;",
+		"type filter hook input priority 0; policy drop;",
+		"type filter hook forward priority 0; policy drop;",
+		`oifname "lo" accept`,
+		`oifname "ppp0" accept`,
+		`iifname "ppp0" accept`,
+		"ip daddr 203.0.113.10 tcp dport 443 accept",
+		"ip6 daddr 2001:db8::10 tcp dport 10443 accept",
+		"ip daddr 192.168.1.0/24 accept",
+		"ip saddr 192.168.1.0/24 accept",
+		"ip6 daddr fd00::/64 accept",
+		"ct state established,related accept",
+	} {
+		assert.Contains(t, ruleset, rule)
+	}
+}
+
+func TestRules_Ruleset_MappedGateway(t *testing.T) {
+	ruleset := Rules{Gateways: []netip.AddrPort{netip.MustParseAddrPort("[::ffff:203.0.113.10]:443")}}.Ruleset()
+
+	assert.Contains(t, ruleset, "ip daddr 203.0.113.10 tcp dport 443 accept")
+}
+
+func TestRules_Ruleset_NoExceptions(t *testing.T) {
+	ruleset := Rules{}.Ruleset()
+
+	assert.NotContains(t, ruleset, "daddr")
+	assert.NotContains(t, ruleset, "saddr")
+	// Nothing goes through a tunnel before it is up
+	assert.NotContains(t, ruleset, "ppp")
+}
+
+func TestRules_Ruleset_NameServers(t *testing.T) {
+	rules := Rules{NameServers: []netip.Addr{netip.MustParseAddr("192.0.2.53"), netip.MustParseAddr("2001:db8::53")}}
+
+	ruleset := rules.Ruleset()
+	assert.Contains(t, ruleset, "ip daddr 192.0.2.53 udp dport 53 accept")
+	assert.Contains(t, ruleset, "ip daddr 192.0.2.53 tcp dport 53 accept")
+	assert.Contains(t, ruleset, "ip6 daddr 2001:db8::53 udp dport 53 accept")
+
+	// Once the tunnel is up, names only resolve through it
+	rules.Tunnel = "ppp0"
+	assert.NotContains(t, rules.Ruleset(), "dport 53")
+}
+
+func TestKillSwitch_EnableDisable(t *testing.T) {
+	runner := &recordingRunner{}
+	k := NewKillSwitch(WithRunner(runner.run))
+	assert.False(t, k.Active())
+
+	require.NoError(t, k.Enable(context.Background(), testRules()))
+	assert.True(t, k.Active())
+	assert.Equal(t, []string{"-f", "-"}, runner.args[0])
+	// The old table is replaced in the same transaction
+	assert.True(t, strings.HasPrefix(runner.stdins[0], "table inet openfortivpn_gui\ndelete table inet openfortivpn_gui\n"))
+	assert.Contains(t, runner.stdins[0], testRules().Ruleset())
+
+	require.NoError(t, k.Disable(context.Background()))
+	assert.False(t, k.Active())
+	assert.Equal(t, "table inet openfortivpn_gui\ndelete table inet openfortivpn_gui\n", runner.stdins[1])
+
+	// Disabling again is harmless
+	require.NoError(t, k.Disable(context.Background()))
+}
+
+func TestKillSwitch_EnableFails(t *testing.T) {
+	runner := &recordingRunner{err: errors.New("nft: command not found")}
+	k := NewKillSwitch(WithRunner(runner.run))
+
+	err := k.Enable(context.Background(), testRules())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nft: command not found")
+	assert.False(t, k.Active())
+}
+
+func TestKillSwitch_DisableFails(t *testing.T) {
+	runner := &recordingRunner{}
+	k := NewKillSwitch(WithRunner(runner.run))
+	require.NoError(t, k.Enable(context.Background(), testRules()))
+
+	runner.err = errors.New("permission denied")
+	require.Error(t, k.Disable(context.Background()))
+
+	// Traffic stays blocked
+	assert.True(t, k.Active())
+}
+
+func TestKillSwitch_Detect(t *testing.T) {
+	runner := &recordingRunner{err: errors.New("no such table")}
+	k := NewKillSwitch(WithRunner(runner.run))
+
+	assert.False(t, k.Detect(context.Background()))
+	assert.False(t, k.Active())
+
+	runner.err = nil
+	assert.True(t, k.Detect(context.Background()))
+	assert.True(t, k.Active())
+	assert.Equal(t, []string{"list", "table", "inet", TableName}, runner.args[1])
+}
+
+// TestKillSwitch_Namespace loads the table into nft inside a fresh network
+// namespace, so the host's firewall is left alone.
+func TestKillSwitch_Namespace(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	nft, err := exec.LookPath("nft")
+	if err != nil {
+		t.Skip("nft not installed")
+	}
+	unshare, err := exec.LookPath("unshare")
+	if err != nil {
+		t.Skip("unshare not installed")
+	}
+	if err := exec.Command(unshare, "--net", "true").Run(); err != nil { // #nosec G204 -- test binary lookup
+		t.Skipf("cannot create network namespace: %v", err)
+	}
+
+	var listing string
+	runner := func(ctx context.Context, stdin string, args ...string) ([]byte, error) {
+		// Apply the script and list the table before the namespace goes away
+		script := nft + " " + strings.Join(args, " ") + " && " + nft + " list table inet " + TableName
+		// #nosec G204 -- the script is built from fixed arguments
+		cmd := exec.CommandContext(ctx, unshare, "--net", "sh", "-c", script)
+		cmd.Stdin = strings.NewReader(stdin)
+		out, err := cmd.CombinedOutput()
+		listing = string(out)
+		if err != nil {
+			return out, errors.New(listing)
+		}
+		return out, nil
+	}
+	k := NewKillSwitch(WithRunner(runner))
+
+	require.NoError(t, k.Enable(context.Background(), testRules()))
+
+	assert.Contains(t, listing, "policy drop")
+	assert.Contains(t, listing, "ip daddr 203.0.113.10 tcp dport 443 accept")
+	assert.Contains(t, listing, "ip6 daddr 2001:db8::10 tcp dport 10443 accept")
+	assert.Contains(t, listing, "ip daddr 192.168.1.0/24 accept")
+	assert.Contains(t, listing, `oifname "ppp0" accept`)
+}
@@ -0,0 +1,187 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/shini4i/openfortivpn-gui/internal/helper/firewall"
+	"github.com/shini4i/openfortivpn-gui/internal/helper/protocol"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+	"github.com/shini4i/openfortivpn-gui/internal/reconnect"
+	"github.com/shini4i/openfortivpn-gui/internal/vpn"
+)
+
+// alwaysOnReconnect is how the always-on profile is reconnected unless it sets
+// a reconnect policy: forever, backing off up to the default maximum delay.
+var alwaysOnReconnect = reconnect.Config{
+	MaxAttempts:   profile.ReconnectForever,
+	DelaySeconds:  5,
+	Backoff:       true,
+	MaxDelay:      profile.DefaultMaxReconnectDelay,
+	JitterPercent: 20,
+}
+
+// alwaysOn keeps the always-on system profile connected.
+type alwaysOn struct {
+	profile   *profile.Profile
+	reconnect *reconnect.Manager
+
+	mu      sync.Mutex
+	started bool // Set by StartAlwaysOn, cleared by Shutdown
+
+	transitions atomic.Uint64 // Counts state changes, to tell whether a failed attempt reached the controller
+}
+
+// staticPassword provides the always-on profile's password to the reconnect manager.
+type staticPassword string
+
+func (s staticPassword) Get(string) (string, error) { return string(s), nil }
+
+// WithAlwaysOn makes the manager keep the profile connected from StartAlwaysOn
+// on, reconnecting it whenever the tunnel drops, until Shutdown. Clients can
+// neither connect nor disconnect meanwhile.
+func WithAlwaysOn(p *profile.Profile, password string) Option {
+	return func(m *Manager) {
+		rm := reconnect.NewManager(alwaysOnReconnect, nil)
+		rm.SetPasswordProvider(staticPassword(password))
+		rm.SetGatewayProber(&vpn.HandshakeProber{})
+		rm.SetCallbacks(reconnect.Callbacks{
+			OnFailed: func(err error) {
+				slog.Error("Cannot reconnect always-on profile", "profile", p.Name, "error", err)
+			},
+		})
+		m.alwaysOn = &alwaysOn{profile: p, reconnect: rm}
+	}
+}
+
+// StartAlwaysOn connects the always-on profile, if one is configured, and keeps it up.
+func (m *Manager) StartAlwaysOn() {
+	a := m.alwaysOn
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.started = true
+	a.mu.Unlock()
+
+	slog.Info("Connecting always-on profile", "profile", a.profile.Name, "host", a.profile.Host)
+	a.reconnect.SetConnectFunc(m.connectAlwaysOn)
+	a.reconnect.StoreConnectedProfile(a.profile)
+	// The first attempt starts right away and failing it continues the sequence
+	a.reconnect.PrepareCycle()
+	a.reconnect.StartReconnect()
+}
+
+// active reports whether the always-on profile is being kept up.
+func (a *alwaysOn) active() bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.started
+}
+
+// stop ends keeping the always-on profile up, so disconnecting it is not reconnected.
+func (a *alwaysOn) stop() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.started = false
+	a.mu.Unlock()
+	a.reconnect.SetUserDisconnect()
+	a.reconnect.Cancel()
+}
+
+// onStateChange reconnects the always-on profile after its tunnel dropped or an attempt failed.
+func (a *alwaysOn) onStateChange(old, new vpn.ConnectionState) {
+	if !a.active() {
+		return
+	}
+	a.transitions.Add(1)
+	switch {
+	case new == vpn.StateConnected:
+		a.reconnect.OnConnectionSucceeded()
+	case a.reconnect.ShouldReconnect(old, new):
+		a.reconnect.StartReconnect()
+	}
+}
+
+// onError stops reconnecting after errors retrying cannot fix, such as rejected credentials.
+func (a *alwaysOn) onError(err error) {
+	var connErr *vpn.ConnectionError
+	if !a.active() || !errors.As(err, &connErr) {
+		return
+	}
+	a.reconnect.OnConnectionError(connErr.Code)
+	if !connErr.Code.Retryable() {
+		slog.Error("Stopped reconnecting always-on profile", "profile", a.profile.Name, "code", connErr.Code)
+	}
+}
+
+// status describes the always-on profile for status requests.
+func (a *alwaysOn) status() *protocol.AlwaysOnStatus {
+	if a == nil {
+		return nil
+	}
+	return &protocol.AlwaysOnStatus{
+		ProfileID:        a.profile.ID,
+		Name:             a.profile.Name,
+		Host:             a.profile.Host,
+		Port:             a.profile.Port,
+		ReconnectAttempt: a.reconnect.GetAttemptCount(),
+	}
+}
+
+// connectAlwaysOn is the reconnect manager's connect function for the always-on profile.
+// An attempt that fails before the tunnel changed state is retried after the
+// next delay; otherwise onStateChange already continued the sequence.
+func (m *Manager) connectAlwaysOn(_ context.Context, p *profile.Profile, password string) error {
+	before := m.alwaysOn.transitions.Load()
+	var rules *firewall.Rules
+	var err error
+	if p.KillSwitch != nil {
+		rules, err = m.alwaysOnKillSwitchRules(p)
+	}
+	if err == nil {
+		_, err = m.connect(p, &vpn.ConnectOptions{Password: password}, rules)
+	}
+	if err != nil && m.alwaysOn.transitions.Load() == before &&
+		m.alwaysOn.active() && m.alwaysOn.reconnect.CanReconnect() {
+		m.alwaysOn.reconnect.StartReconnect()
+	}
+	return err
+}
+
+// alwaysOnKillSwitchRules builds the kill switch rules of the always-on profile.
+func (m *Manager) alwaysOnKillSwitchRules(p *profile.Profile) (*firewall.Rules, error) {
+	if m.killSwitch == nil {
+		return nil, errors.New("kill switch is not available")
+	}
+	params := &protocol.KillSwitchParams{AllowedLANs: p.KillSwitch.AllowedLANs}
+	for _, gw := range p.Endpoints() {
+		params.Gateways = append(params.Gateways, net.JoinHostPort(gw.Host, strconv.Itoa(gw.Port)))
+	}
+	rules, err := m.killSwitchRules(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kill switch: %w", err)
+	}
+	return &rules, nil
+}
+
+// refuseForAlwaysOn returns the error response for client requests that would
+// interfere with the always-on profile, or nil if none is configured.
+func (m *Manager) refuseForAlwaysOn(req *protocol.Request, action string) *protocol.Response {
+	if !m.alwaysOn.active() {
+		return nil
+	}
+	return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInvalidState,
+		fmt.Sprintf("cannot %s: the always-on VPN %q is managed by the system", action, m.alwaysOn.profile.Name))
+}
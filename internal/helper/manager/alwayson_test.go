@@ -0,0 +1,191 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/helper/protocol"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+	"github.com/shini4i/openfortivpn-gui/internal/vpn"
+)
+
+// alwaysOnProfile returns a system profile that reconnects without delay.
+func alwaysOnProfile() *profile.Profile {
+	return &profile.Profile{
+		ID:              "7d5e2c1a-3b4f-4e8a-9c0d-1e2f3a4b5c6d",
+		Name:            "Office",
+		Host:            "vpn.example.com",
+		Port:            443,
+		Username:        "alice",
+		AuthMethod:      profile.AuthMethodPassword,
+		AutoReconnect:   true,
+		ReconnectPolicy: &profile.ReconnectPolicy{MaxAttempts: profile.ReconnectForever},
+	}
+}
+
+func statusRequest(t *testing.T, m *Manager) protocol.StatusResult {
+	t.Helper()
+	req, err := protocol.NewRequest("1", protocol.CommandStatus, protocol.StatusParams{})
+	require.NoError(t, err)
+	resp := m.HandleRequest(req)
+	require.True(t, resp.Success, resp.Error)
+	var status protocol.StatusResult
+	require.NoError(t, json.Unmarshal(resp.Result, &status))
+	return status
+}
+
+func TestManager_AlwaysOn_ConnectsAtStart(t *testing.T) {
+	ctrl := newMockController()
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, WithAlwaysOn(alwaysOnProfile(), "secret"))
+	defer m.Shutdown()
+
+	m.StartAlwaysOn()
+
+	require.Eventually(t, func() bool { return ctrl.connects() == 1 }, time.Second, 5*time.Millisecond)
+	ctrl.mu.Lock()
+	assert.Equal(t, "Office", ctrl.connectedProfile.Name)
+	assert.Equal(t, "secret", ctrl.connectOpts.Password)
+	ctrl.mu.Unlock()
+}
+
+func TestManager_AlwaysOn_ReconnectsAfterDrop(t *testing.T) {
+	ctrl := newMockController()
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, WithAlwaysOn(alwaysOnProfile(), "secret"))
+	defer m.Shutdown()
+
+	m.StartAlwaysOn()
+	require.Eventually(t, func() bool { return ctrl.connects() == 1 }, time.Second, 5*time.Millisecond)
+	ctrl.setState(vpn.StateConnected)
+
+	ctrl.setState(vpn.StateDisconnected)
+
+	require.Eventually(t, func() bool { return ctrl.connects() == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestManager_AlwaysOn_RetriesFailedStart(t *testing.T) {
+	ctrl := newMockController()
+	ctrl.connectErr = errors.New("openfortivpn not found")
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, WithAlwaysOn(alwaysOnProfile(), "secret"))
+	defer m.Shutdown()
+
+	m.StartAlwaysOn()
+
+	require.Eventually(t, func() bool { return ctrl.connects() >= 3 }, time.Second, 5*time.Millisecond)
+}
+
+func TestManager_AlwaysOn_StopsOnRejectedCredentials(t *testing.T) {
+	ctrl := newMockController()
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, WithAlwaysOn(alwaysOnProfile(), "wrong"))
+	defer m.Shutdown()
+
+	m.StartAlwaysOn()
+	require.Eventually(t, func() bool { return ctrl.connects() == 1 }, time.Second, 5*time.Millisecond)
+	ctrl.onError(&vpn.ConnectionError{Code: vpn.ErrorCodeAuthFailed, Message: "Authentication failed"})
+	ctrl.setState(vpn.StateFailed)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, ctrl.connects())
+}
+
+func TestManager_AlwaysOn_RefusesClientRequests(t *testing.T) {
+	ctrl := newMockController()
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast,
+		WithAlwaysOn(alwaysOnProfile(), "secret"), WithKillSwitch(&fakeKillSwitch{}))
+	defer m.Shutdown()
+	m.StartAlwaysOn()
+	require.Eventually(t, func() bool { return ctrl.connects() == 1 }, time.Second, 5*time.Millisecond)
+
+	release, err := protocol.NewRequest("3", protocol.CommandReleaseKillSwitch, protocol.ReleaseKillSwitchParams{})
+	require.NoError(t, err)
+	for _, req := range []*protocol.Request{
+		killSwitchConnectRequest(t, nil),
+		disconnectRequest(t, false),
+		release,
+	} {
+		resp := m.HandleRequest(req)
+		require.False(t, resp.Success, req.Command)
+		assert.Equal(t, protocol.ErrCodeInvalidState, resp.Error.Code)
+		assert.Contains(t, resp.Error.Message, `always-on VPN "Office"`)
+	}
+	assert.Equal(t, 1, ctrl.connects())
+	assert.Zero(t, ctrl.disconnectCalls)
+}
+
+func TestManager_AlwaysOn_Status(t *testing.T) {
+	ctrl := newMockController()
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, WithAlwaysOn(alwaysOnProfile(), "secret"))
+	defer m.Shutdown()
+
+	status := statusRequest(t, m)
+
+	require.NotNil(t, status.AlwaysOn)
+	assert.Equal(t, protocol.AlwaysOnStatus{
+		ProfileID: "7d5e2c1a-3b4f-4e8a-9c0d-1e2f3a4b5c6d",
+		Name:      "Office",
+		Host:      "vpn.example.com",
+		Port:      443,
+	}, *status.AlwaysOn)
+
+	assert.Nil(t, statusRequest(t, NewManagerWithController(newMockController(), (&eventRecorder{}).broadcast)).AlwaysOn)
+}
+
+func TestManager_AlwaysOn_StateChangeEvents(t *testing.T) {
+	ctrl := newMockController()
+	recorder := &eventRecorder{}
+	m := NewManagerWithController(ctrl, recorder.broadcast, WithAlwaysOn(alwaysOnProfile(), "secret"))
+	defer m.Shutdown()
+
+	ctrl.setState(vpn.StateConnecting)
+
+	events := recorder.byName(protocol.EventStateChange)
+	require.Len(t, events, 1)
+	var data protocol.StateChangeData
+	require.NoError(t, json.Unmarshal(events[0].Data, &data))
+	require.NotNil(t, data.AlwaysOn)
+	assert.Equal(t, "Office", data.AlwaysOn.Name)
+}
+
+func TestManager_AlwaysOn_KillSwitch(t *testing.T) {
+	ctrl := newMockController()
+	killSwitch := &fakeKillSwitch{}
+	p := alwaysOnProfile()
+	p.KillSwitch = &profile.KillSwitch{AllowedLANs: []string{"192.168.1.0/24"}}
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, WithAlwaysOn(p, "secret"),
+		WithKillSwitch(killSwitch), WithGatewayResolver(fakeResolver{
+			"vpn.example.com": {netip.MustParseAddr("203.0.113.10")},
+		}.resolve), WithUplinkNameServers(noNameServers))
+
+	m.StartAlwaysOn()
+	require.Eventually(t, func() bool { return ctrl.connects() == 1 }, time.Second, 5*time.Millisecond)
+	ctrl.setState(vpn.StateConnected)
+	assert.True(t, killSwitch.Active())
+	assert.Equal(t, []netip.AddrPort{netip.MustParseAddrPort("203.0.113.10:443")}, killSwitch.rules.Gateways)
+
+	// The kill switch stays across a drop
+	ctrl.setState(vpn.StateDisconnected)
+	require.Eventually(t, func() bool { return ctrl.connects() == 2 }, time.Second, 5*time.Millisecond)
+	assert.True(t, killSwitch.Active())
+
+	m.Shutdown()
+	assert.False(t, killSwitch.Active())
+}
+
+func TestManager_AlwaysOn_ShutdownStopsReconnecting(t *testing.T) {
+	ctrl := newMockController()
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, WithAlwaysOn(alwaysOnProfile(), "secret"))
+	m.StartAlwaysOn()
+	require.Eventually(t, func() bool { return ctrl.connects() == 1 }, time.Second, 5*time.Millisecond)
+	ctrl.setState(vpn.StateConnected)
+
+	m.Shutdown()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, ctrl.connects())
+	assert.Equal(t, 1, ctrl.disconnectCalls)
+}
@@ -0,0 +1,128 @@
+package manager
+
+import (
+	"context"
+	"sync"
+
+	"github.com/shini4i/openfortivpn-gui/internal/helper/protocol"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+	"github.com/shini4i/openfortivpn-gui/internal/vpn"
+)
+
+// mockController implements vpn.VPNController for testing.
+type mockController struct {
+	mu sync.Mutex
+
+	state      vpn.ConnectionState
+	phase      vpn.Phase
+	connectErr error
+
+	connectedProfile *profile.Profile
+	connectOpts      *vpn.ConnectOptions
+	connectCalls     int
+	disconnectCalls  int
+
+	onStateChange func(old, new vpn.ConnectionState)
+	onOutput      func(line string)
+	onEvent       func(event *vpn.OutputEvent)
+	onError       func(err error)
+}
+
+func newMockController() *mockController {
+	return &mockController{state: vpn.StateDisconnected}
+}
+
+func (c *mockController) GetState() vpn.ConnectionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *mockController) GetAssignedIP() string { return "" }
+
+func (c *mockController) GetInterface() string { return "" }
+
+func (c *mockController) GetPhase() vpn.Phase {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.phase
+}
+
+func (c *mockController) CanConnect() bool { return c.GetState().CanConnect() }
+
+func (c *mockController) CanDisconnect() bool { return c.GetState().CanDisconnect() }
+
+func (c *mockController) Connect(_ context.Context, p *profile.Profile, opts *vpn.ConnectOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connectCalls++
+	if c.connectErr != nil {
+		return c.connectErr
+	}
+	c.connectedProfile = p
+	c.connectOpts = opts
+	c.state = vpn.StateConnecting
+	return nil
+}
+
+func (c *mockController) Disconnect(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnectCalls++
+	c.state = vpn.StateDisconnected
+	return nil
+}
+
+func (c *mockController) OnStateChange(callback func(old, new vpn.ConnectionState)) {
+	c.onStateChange = callback
+}
+
+func (c *mockController) OnOutput(callback func(line string)) { c.onOutput = callback }
+
+func (c *mockController) OnEvent(callback func(event *vpn.OutputEvent)) { c.onEvent = callback }
+
+func (c *mockController) OnError(callback func(err error)) { c.onError = callback }
+
+// connects returns how many times Connect was called.
+func (c *mockController) connects() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connectCalls
+}
+
+// setState changes the state and invokes the state change callback.
+func (c *mockController) setState(state vpn.ConnectionState) {
+	c.mu.Lock()
+	old := c.state
+	c.state = state
+	callback := c.onStateChange
+	c.mu.Unlock()
+	if callback != nil {
+		callback(old, state)
+	}
+}
+
+// eventRecorder collects broadcast events for assertions.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []*protocol.Event
+}
+
+func (r *eventRecorder) broadcast(event *protocol.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// byName returns all recorded events with the given name.
+func (r *eventRecorder) byName(name protocol.EventName) []*protocol.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*protocol.Event
+	for _, e := range r.events {
+		if e.Name == name {
+			result = append(result, e)
+		}
+	}
+	return result
+}
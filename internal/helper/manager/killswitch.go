@@ -0,0 +1,208 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/shini4i/openfortivpn-gui/internal/helper/firewall"
+	"github.com/shini4i/openfortivpn-gui/internal/helper/netstate"
+	"github.com/shini4i/openfortivpn-gui/internal/helper/protocol"
+	"github.com/shini4i/openfortivpn-gui/internal/vpn"
+)
+
+// gatewayLookupTimeout bounds resolving a gateway host name.
+const gatewayLookupTimeout = 5 * time.Second
+
+// KillSwitch blocks traffic outside the VPN tunnel.
+// It is implemented by firewall.KillSwitch.
+type KillSwitch interface {
+	// Enable installs or replaces the rules.
+	Enable(ctx context.Context, rules firewall.Rules) error
+	// Disable removes the rules. It succeeds if none are installed.
+	Disable(ctx context.Context) error
+	// Active reports whether traffic outside the tunnel is blocked.
+	Active() bool
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithKillSwitch sets the kill switch used for profiles that request one.
+// Without it, such connect requests are refused.
+func WithKillSwitch(k KillSwitch) Option {
+	return func(m *Manager) {
+		m.killSwitch = k
+	}
+}
+
+// WithGatewayResolver sets how gateway host names are resolved for the kill switch.
+func WithGatewayResolver(resolve func(ctx context.Context, host string) ([]netip.Addr, error)) Option {
+	return func(m *Manager) {
+		m.resolveGateway = resolve
+	}
+}
+
+// WithUplinkNameServers sets how the name servers of the uplink are found.
+// The kill switch lets them through until the tunnel is up, so openfortivpn
+// can resolve gateway host names when it connects or reconnects.
+func WithUplinkNameServers(nameServers func() []netip.Addr) Option {
+	return func(m *Manager) {
+		m.uplinkNameServers = nameServers
+	}
+}
+
+// uplinkNameServers returns the name servers of the system resolver configuration.
+func uplinkNameServers() []netip.Addr {
+	return netstate.NameServers(netstate.ResolvConfPath, netstate.UpstreamResolvConfPath)
+}
+
+// resolveGatewayHost resolves a gateway host name with the system resolver.
+func resolveGatewayHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
+
+// killSwitchRules builds the kill switch rules for the connect params.
+// Gateway host names are resolved now, and the uplink's name servers are let
+// through until the tunnel is up so openfortivpn can resolve them again. The
+// last addresses a name resolved to are reused when it cannot be resolved.
+func (m *Manager) killSwitchRules(params *protocol.KillSwitchParams) (firewall.Rules, error) {
+	var rules firewall.Rules
+	var hostNames bool
+	for _, lan := range params.AllowedLANs {
+		prefix, err := netip.ParsePrefix(lan)
+		if err != nil {
+			return firewall.Rules{}, fmt.Errorf("invalid allowed LAN range %q: %w", lan, err)
+		}
+		rules.AllowedLANs = append(rules.AllowedLANs, prefix)
+	}
+
+	for _, gateway := range params.Gateways {
+		host, portStr, err := net.SplitHostPort(gateway)
+		if err != nil {
+			return firewall.Rules{}, fmt.Errorf("invalid gateway %q: %w", gateway, err)
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil || port == 0 {
+			return firewall.Rules{}, fmt.Errorf("invalid gateway port in %q", gateway)
+		}
+
+		if _, err := netip.ParseAddr(host); err != nil {
+			hostNames = true
+		}
+		for _, addr := range m.gatewayAddrs(host) {
+			rules.Gateways = append(rules.Gateways, netip.AddrPortFrom(addr, uint16(port)))
+		}
+	}
+	if len(rules.Gateways) == 0 {
+		return firewall.Rules{}, fmt.Errorf("none of the gateways %v could be resolved", params.Gateways)
+	}
+	if hostNames {
+		rules.NameServers = m.killSwitchNameServers()
+	}
+
+	return rules, nil
+}
+
+// gatewayAddrs returns the addresses of a gateway host, or nil if it cannot be resolved.
+func (m *Manager) gatewayAddrs(host string) []netip.Addr {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayLookupTimeout)
+	defer cancel()
+	addrs, err := m.resolveGateway(ctx, host)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil || len(addrs) == 0 {
+		if cached := m.resolvedGateways[host]; len(cached) > 0 {
+			slog.Warn("Using previous gateway addresses", "host", host, "error", err)
+			return cached
+		}
+		slog.Warn("Failed to resolve gateway", "host", host, "error", err)
+		return nil
+	}
+	m.resolvedGateways[host] = addrs
+	return addrs
+}
+
+// killSwitchNameServers returns the name servers the kill switch lets through
+// until the tunnel is up. They are recorded when the kill switch is enabled and
+// kept while it stays active, since the resolver configuration may list the
+// VPN's name servers by then.
+func (m *Manager) killSwitchNameServers() []netip.Addr {
+	if m.killSwitchActive() {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return m.blockingRules.NameServers
+	}
+	return m.uplinkNameServers()
+}
+
+// setKillSwitchTunnel re-renders the active kill switch's rules to let traffic
+// through the tunnel interface name, or through no tunnel if name is empty.
+func (m *Manager) setKillSwitchTunnel(name string) {
+	if !m.killSwitchActive() {
+		return
+	}
+	m.mu.Lock()
+	if m.blockingRules.Tunnel == name {
+		m.mu.Unlock()
+		return
+	}
+	m.blockingRules.Tunnel = name
+	rules := m.blockingRules
+	m.mu.Unlock()
+
+	if err := m.killSwitch.Enable(context.Background(), rules); err != nil {
+		slog.Error("Failed to update kill switch tunnel", "interface", name, "error", err)
+	}
+}
+
+// tunnelUp reports whether traffic goes through the tunnel in the state.
+func tunnelUp(s vpn.ConnectionState) bool {
+	return s == vpn.StateConnected || s == vpn.StateDegraded
+}
+
+// killSwitchActive reports whether the kill switch blocks traffic outside the tunnel.
+func (m *Manager) killSwitchActive() bool {
+	return m.killSwitch != nil && m.killSwitch.Active()
+}
+
+// releaseKillSwitch removes the kill switch if it is active.
+func (m *Manager) releaseKillSwitch(ctx context.Context) error {
+	if !m.killSwitchActive() {
+		return nil
+	}
+	if err := m.killSwitch.Disable(ctx); err != nil {
+		return err
+	}
+	slog.Info("Kill switch removed")
+	return nil
+}
+
+func (m *Manager) handleReleaseKillSwitch(req *protocol.Request) *protocol.Response {
+	if resp := m.refuseForAlwaysOn(req, "release the kill switch"); resp != nil {
+		return resp
+	}
+	if m.controller.CanDisconnect() {
+		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInvalidState,
+			fmt.Sprintf("cannot release kill switch: current state is %s", m.controller.GetState()))
+	}
+
+	if err := m.releaseKillSwitch(context.Background()); err != nil {
+		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeKillSwitchFailed, err.Error())
+	}
+
+	resp, err := protocol.NewSuccessResponse(req.ID, nil)
+	if err != nil {
+		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInternalError, err.Error())
+	}
+	return resp
+}
@@ -0,0 +1,361 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/helper/firewall"
+	"github.com/shini4i/openfortivpn-gui/internal/helper/protocol"
+	"github.com/shini4i/openfortivpn-gui/internal/vpn"
+)
+
+// fakeKillSwitch records the rules the manager installs.
+type fakeKillSwitch struct {
+	mu        sync.Mutex
+	active    bool
+	rules     firewall.Rules
+	enables   int
+	enableErr error
+}
+
+func (k *fakeKillSwitch) Enable(_ context.Context, rules firewall.Rules) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.enableErr != nil {
+		return k.enableErr
+	}
+	k.active = true
+	k.rules = rules
+	k.enables++
+	return nil
+}
+
+func (k *fakeKillSwitch) Disable(_ context.Context) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.active = false
+	return nil
+}
+
+func (k *fakeKillSwitch) Active() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.active
+}
+
+// fakeResolver resolves host names from a map, failing for unknown ones.
+type fakeResolver map[string][]netip.Addr
+
+func (r fakeResolver) resolve(_ context.Context, host string) ([]netip.Addr, error) {
+	if addrs, ok := r[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+// noNameServers stands in for an uplink whose name servers are on loopback.
+func noNameServers() []netip.Addr { return nil }
+
+func killSwitchConnectRequest(t *testing.T, killSwitch *protocol.KillSwitchParams) *protocol.Request {
+	t.Helper()
+	req, err := protocol.NewRequest("1", protocol.CommandConnect, protocol.ConnectParams{
+		ProfileID:  "550e8400-e29b-41d4-a716-446655440000",
+		Host:       "vpn.example.com",
+		Port:       443,
+		Username:   "alice",
+		AuthMethod: "password",
+		KillSwitch: killSwitch,
+	})
+	require.NoError(t, err)
+	return req
+}
+
+func disconnectRequest(t *testing.T, keepKillSwitch bool) *protocol.Request {
+	t.Helper()
+	req, err := protocol.NewRequest("2", protocol.CommandDisconnect, protocol.DisconnectParams{KeepKillSwitch: keepKillSwitch})
+	require.NoError(t, err)
+	return req
+}
+
+func newKillSwitchManager(ctrl *mockController, killSwitch *fakeKillSwitch, resolver fakeResolver) *Manager {
+	return NewManagerWithController(ctrl, (&eventRecorder{}).broadcast,
+		WithKillSwitch(killSwitch), WithGatewayResolver(resolver.resolve), WithUplinkNameServers(noNameServers))
+}
+
+func TestManager_KillSwitch_EnabledBeforeConnect(t *testing.T) {
+	ctrl := newMockController()
+	killSwitch := &fakeKillSwitch{}
+	m := newKillSwitchManager(ctrl, killSwitch, fakeResolver{
+		"vpn.example.com": {netip.MustParseAddr("203.0.113.10"), netip.MustParseAddr("2001:db8::10")},
+	})
+
+	resp := m.HandleRequest(killSwitchConnectRequest(t, &protocol.KillSwitchParams{
+		Gateways:    []string{"vpn.example.com:443", "198.51.100.7:10443"},
+		AllowedLANs: []string{"192.168.1.0/24"},
+	}))
+
+	require.True(t, resp.Success, resp.Error)
+	assert.True(t, killSwitch.Active())
+	assert.Equal(t, []netip.AddrPort{
+		netip.MustParseAddrPort("203.0.113.10:443"),
+		netip.MustParseAddrPort("[2001:db8::10]:443"),
+		netip.MustParseAddrPort("198.51.100.7:10443"),
+	}, killSwitch.rules.Gateways)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}, killSwitch.rules.AllowedLANs)
+	assert.NotNil(t, ctrl.connectedProfile)
+}
+
+func TestManager_KillSwitch_StaysAcrossDrops(t *testing.T) {
+	ctrl := newMockController()
+	killSwitch := &fakeKillSwitch{}
+	resolver := fakeResolver{"vpn.example.com": {netip.MustParseAddr("203.0.113.10")}}
+	m := newKillSwitchManager(ctrl, killSwitch, resolver)
+	params := &protocol.KillSwitchParams{Gateways: []string{"vpn.example.com:443"}}
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, params)).Success)
+	ctrl.setState(vpn.StateFailed)
+	assert.True(t, killSwitch.Active(), "the tunnel dropped")
+
+	// Closing the tunnel to reconnect keeps traffic blocked
+	ctrl.setState(vpn.StateConnected)
+	require.True(t, m.HandleRequest(disconnectRequest(t, true)).Success)
+	assert.True(t, killSwitch.Active())
+
+	// The gateway name cannot be resolved while traffic is blocked
+	delete(resolver, "vpn.example.com")
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, params)).Success)
+	assert.Equal(t, []netip.AddrPort{netip.MustParseAddrPort("203.0.113.10:443")}, killSwitch.rules.Gateways)
+	assert.Equal(t, 2, killSwitch.enables)
+}
+
+func TestManager_KillSwitch_KeepsGatewayHost(t *testing.T) {
+	ctrl := newMockController()
+	m := newKillSwitchManager(ctrl, &fakeKillSwitch{}, fakeResolver{
+		"vpn.example.com": {netip.MustParseAddr("203.0.113.10")},
+	})
+	digest := "a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1"
+	req, err := protocol.NewRequest("1", protocol.CommandConnect, protocol.ConnectParams{
+		ProfileID:   "550e8400-e29b-41d4-a716-446655440000",
+		Host:        "vpn.example.com",
+		Port:        443,
+		Username:    "alice",
+		AuthMethod:  "password",
+		TrustedCert: digest,
+		KillSwitch:  &protocol.KillSwitchParams{Gateways: []string{"vpn.example.com:443"}},
+	})
+	require.NoError(t, err)
+
+	require.True(t, m.HandleRequest(req).Success)
+
+	// openfortivpn connects by name, so the gateway sees it in SNI and the
+	// Host header, and checks the certificate the user trusted
+	assert.Equal(t, "vpn.example.com", ctrl.connectedProfile.Host)
+	assert.Equal(t, digest, ctrl.connectedProfile.TrustedCert)
+}
+
+func TestManager_KillSwitch_NameServers(t *testing.T) {
+	ctrl := newMockController()
+	killSwitch := &fakeKillSwitch{}
+	uplink := []netip.Addr{netip.MustParseAddr("192.168.1.1")}
+	current := uplink
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, WithKillSwitch(killSwitch),
+		WithGatewayResolver(fakeResolver{"vpn.example.com": {netip.MustParseAddr("203.0.113.10")}}.resolve),
+		WithUplinkNameServers(func() []netip.Addr { return current }))
+	params := &protocol.KillSwitchParams{Gateways: []string{"vpn.example.com:443"}}
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, params)).Success)
+	assert.Equal(t, uplink, killSwitch.rules.NameServers)
+
+	// The resolver configuration lists the VPN's name servers while the tunnel is up
+	current = []netip.Addr{netip.MustParseAddr("10.0.0.53")}
+	ctrl.setState(vpn.StateFailed)
+
+	// A reconnect keeps the name servers recorded when the kill switch was enabled
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, params)).Success)
+	assert.Equal(t, uplink, killSwitch.rules.NameServers)
+
+	// Gateways given by address need no name servers
+	require.True(t, m.HandleRequest(disconnectRequest(t, false)).Success)
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10:443"}})).Success)
+	assert.Empty(t, killSwitch.rules.NameServers)
+}
+
+func TestManager_KillSwitch_TunnelInterface(t *testing.T) {
+	ctrl := newMockController()
+	killSwitch := &fakeKillSwitch{}
+	m := newKillSwitchManager(ctrl, killSwitch, nil)
+	interfaceUp := &vpn.OutputEvent{Type: vpn.EventPhase, Data: map[string]string{
+		vpn.DataKeyPhase:     string(vpn.PhaseInterfaceUp),
+		vpn.DataKeyInterface: "ppp0",
+	}}
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10:443"}})).Success)
+	assert.Empty(t, killSwitch.rules.Tunnel, "nothing goes through a tunnel before it is up")
+
+	ctrl.onEvent(interfaceUp)
+	ctrl.setState(vpn.StateConnected)
+	assert.Equal(t, "ppp0", killSwitch.rules.Tunnel)
+	assert.Equal(t, []netip.AddrPort{netip.MustParseAddrPort("203.0.113.10:443")}, killSwitch.rules.Gateways)
+	assert.Equal(t, 2, killSwitch.enables)
+
+	// The interface of a dropped tunnel is blocked again
+	ctrl.setState(vpn.StateFailed)
+	assert.True(t, killSwitch.Active())
+	assert.Empty(t, killSwitch.rules.Tunnel)
+	assert.Equal(t, 3, killSwitch.enables)
+}
+
+func TestManager_KillSwitch_ReleasedOnDisconnect(t *testing.T) {
+	ctrl := newMockController()
+	killSwitch := &fakeKillSwitch{}
+	m := newKillSwitchManager(ctrl, killSwitch, nil)
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10:443"}})).Success)
+	require.True(t, m.HandleRequest(disconnectRequest(t, false)).Success)
+
+	assert.False(t, killSwitch.Active())
+	assert.Equal(t, 1, ctrl.disconnectCalls)
+}
+
+func TestManager_KillSwitch_DisconnectAfterDrop(t *testing.T) {
+	ctrl := newMockController()
+	killSwitch := &fakeKillSwitch{}
+	m := newKillSwitchManager(ctrl, killSwitch, nil)
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10:443"}})).Success)
+	ctrl.setState(vpn.StateFailed)
+
+	require.True(t, m.HandleRequest(disconnectRequest(t, false)).Success)
+	assert.False(t, killSwitch.Active())
+	assert.Zero(t, ctrl.disconnectCalls)
+
+	// Without a kill switch there is nothing to disconnect
+	resp := m.HandleRequest(disconnectRequest(t, false))
+	require.False(t, resp.Success)
+	assert.Equal(t, protocol.ErrCodeInvalidState, resp.Error.Code)
+}
+
+func TestManager_KillSwitch_ReleaseCommand(t *testing.T) {
+	ctrl := newMockController()
+	killSwitch := &fakeKillSwitch{}
+	m := newKillSwitchManager(ctrl, killSwitch, nil)
+	release, err := protocol.NewRequest("3", protocol.CommandReleaseKillSwitch, protocol.ReleaseKillSwitchParams{})
+	require.NoError(t, err)
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10:443"}})).Success)
+	resp := m.HandleRequest(release)
+	require.False(t, resp.Success, "the tunnel is active")
+	assert.Equal(t, protocol.ErrCodeInvalidState, resp.Error.Code)
+	assert.True(t, killSwitch.Active())
+
+	ctrl.setState(vpn.StateDisconnected)
+	require.True(t, m.HandleRequest(release).Success)
+	assert.False(t, killSwitch.Active())
+
+	// Releasing again is harmless
+	require.True(t, m.HandleRequest(release).Success)
+}
+
+func TestManager_KillSwitch_ConnectWithoutKillSwitch(t *testing.T) {
+	ctrl := newMockController()
+	killSwitch := &fakeKillSwitch{active: true}
+	m := newKillSwitchManager(ctrl, killSwitch, nil)
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, nil)).Success)
+	assert.False(t, killSwitch.Active())
+}
+
+func TestManager_KillSwitch_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		killSwitch *fakeKillSwitch
+		params     *protocol.KillSwitchParams
+		wantCode   string
+	}{
+		{
+			name:     "not available",
+			params:   &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10:443"}},
+			wantCode: protocol.ErrCodeUnsupportedFeature,
+		},
+		{
+			name:       "invalid LAN",
+			killSwitch: &fakeKillSwitch{},
+			params:     &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10:443"}, AllowedLANs: []string{"printer"}},
+			wantCode:   protocol.ErrCodeInvalidParams,
+		},
+		{
+			name:       "gateway without port",
+			killSwitch: &fakeKillSwitch{},
+			params:     &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10"}},
+			wantCode:   protocol.ErrCodeInvalidParams,
+		},
+		{
+			name:       "unresolvable gateway",
+			killSwitch: &fakeKillSwitch{},
+			params:     &protocol.KillSwitchParams{Gateways: []string{"vpn.example.com:443"}},
+			wantCode:   protocol.ErrCodeInvalidParams,
+		},
+		{
+			name:       "nft fails",
+			killSwitch: &fakeKillSwitch{enableErr: errors.New("nft: command not found")},
+			params:     &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10:443"}},
+			wantCode:   protocol.ErrCodeKillSwitchFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := newMockController()
+			var opts []Option
+			if tt.killSwitch != nil {
+				opts = append(opts, WithKillSwitch(tt.killSwitch))
+			}
+			opts = append(opts, WithGatewayResolver(fakeResolver{}.resolve))
+			m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, opts...)
+
+			resp := m.HandleRequest(killSwitchConnectRequest(t, tt.params))
+
+			require.False(t, resp.Success)
+			assert.Equal(t, tt.wantCode, resp.Error.Code)
+			assert.Nil(t, ctrl.connectedProfile, "must not connect without the kill switch")
+		})
+	}
+}
+
+func TestManager_KillSwitch_FailedConnectReleases(t *testing.T) {
+	ctrl := newMockController()
+	ctrl.connectErr = errors.New("openfortivpn not found")
+	killSwitch := &fakeKillSwitch{}
+	m := newKillSwitchManager(ctrl, killSwitch, nil)
+
+	resp := m.HandleRequest(killSwitchConnectRequest(t, &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10:443"}}))
+
+	require.False(t, resp.Success)
+	assert.False(t, killSwitch.Active())
+}
+
+func TestManager_KillSwitch_StatusAndShutdown(t *testing.T) {
+	ctrl := newMockController()
+	killSwitch := &fakeKillSwitch{}
+	m := newKillSwitchManager(ctrl, killSwitch, nil)
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, &protocol.KillSwitchParams{Gateways: []string{"203.0.113.10:443"}})).Success)
+
+	req, err := protocol.NewRequest("4", protocol.CommandStatus, protocol.StatusParams{})
+	require.NoError(t, err)
+	resp := m.HandleRequest(req)
+	require.True(t, resp.Success)
+	var status protocol.StatusResult
+	require.NoError(t, json.Unmarshal(resp.Result, &status))
+	assert.True(t, status.KillSwitch)
+
+	m.Shutdown()
+	assert.False(t, killSwitch.Active())
+	assert.Equal(t, 1, ctrl.disconnectCalls)
+}
@@ -7,12 +7,15 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/shini4i/openfortivpn-gui/internal/helper/firewall"
+	"github.com/shini4i/openfortivpn-gui/internal/helper/netstate"
 	"github.com/shini4i/openfortivpn-gui/internal/helper/protocol"
 	"github.com/shini4i/openfortivpn-gui/internal/profile"
 	"github.com/shini4i/openfortivpn-gui/internal/vpn"
@@ -44,25 +47,51 @@ type EventBroadcaster func(event *protocol.Event)
 
 // Manager handles VPN operations and translates between the protocol and controller.
 type Manager struct {
-	controller  vpn.VPNController
-	broadcaster EventBroadcaster
+	controller        vpn.VPNController
+	broadcaster       EventBroadcaster
+	killSwitch        KillSwitch
+	resolveGateway    func(ctx context.Context, host string) ([]netip.Addr, error)
+	uplinkNameServers func() []netip.Addr
+	alwaysOn          *alwaysOn
+	network           NetworkGuard
 
 	mu                 sync.RWMutex
 	connectedProfileID string
+	resolvedGateways   map[string][]netip.Addr
+	blockingRules      firewall.Rules // the kill switch rules of the last connect
+	tunnelDown         bool           // openfortivpn reported the tunnel down, having cleaned up
 }
 
-// NewManager creates a new VPN manager with a default controller.
+// NewManager creates a new VPN manager with a default controller, an nftables
+// kill switch and a guard restoring the name servers and routes. A kill switch
+// left by a helper that did not shut down cleanly stays in place until it is released.
 // This is a convenience wrapper around NewManagerWithController.
-func NewManager(openfortivpnPath string, broadcaster EventBroadcaster) *Manager {
-	return NewManagerWithController(vpn.NewController(openfortivpnPath, vpn.WithDirectMode()), broadcaster)
+func NewManager(openfortivpnPath string, broadcaster EventBroadcaster, opts ...Option) *Manager {
+	controller := vpn.NewController(openfortivpnPath,
+		vpn.WithDirectMode(),
+		vpn.WithVersionDetector(vpn.NewVersionDetector(openfortivpnPath)))
+
+	killSwitch := firewall.NewKillSwitch()
+	if killSwitch.Detect(context.Background()) {
+		slog.Warn("Kill switch from a previous run is still active")
+	}
+
+	defaults := []Option{WithKillSwitch(killSwitch), WithNetworkGuard(netstate.NewGuard(netstate.DefaultPath))}
+	return NewManagerWithController(controller, broadcaster, append(defaults, opts...)...)
 }
 
 // NewManagerWithController creates a new VPN manager with the provided controller.
 // This constructor allows injecting a mock controller for testing.
-func NewManagerWithController(controller vpn.VPNController, broadcaster EventBroadcaster) *Manager {
+func NewManagerWithController(controller vpn.VPNController, broadcaster EventBroadcaster, opts ...Option) *Manager {
 	m := &Manager{
-		controller:  controller,
-		broadcaster: broadcaster,
+		controller:        controller,
+		broadcaster:       broadcaster,
+		resolveGateway:    resolveGatewayHost,
+		uplinkNameServers: uplinkNameServers,
+		resolvedGateways:  make(map[string][]netip.Addr),
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
 
 	// Set up callbacks to broadcast events
@@ -83,6 +112,8 @@ func (m *Manager) HandleRequest(req *protocol.Request) *protocol.Response {
 		return m.handleDisconnect(req)
 	case protocol.CommandStatus:
 		return m.handleStatus(req)
+	case protocol.CommandReleaseKillSwitch:
+		return m.handleReleaseKillSwitch(req)
 	default:
 		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInvalidCommand,
 			fmt.Sprintf("unknown command: %s", req.Command))
@@ -95,6 +126,9 @@ func (m *Manager) handleConnect(req *protocol.Request) *protocol.Response {
 		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInvalidParams,
 			"invalid connect params")
 	}
+	if resp := m.refuseForAlwaysOn(req, "connect"); resp != nil {
+		return resp
+	}
 
 	// Validate file paths to prevent path traversal attacks
 	if err := validateFilePath(params.ClientCertPath); err != nil {
@@ -121,6 +155,7 @@ func (m *Manager) handleConnect(req *protocol.Request) *protocol.Response {
 		SetDNS:             params.SetDNS,
 		SetRoutes:          params.SetRoutes,
 		HalfInternetRoutes: params.HalfInternetRoutes,
+		NoFTMPush:          params.NoFTMPush,
 	}
 
 	// Validate profile
@@ -129,36 +164,101 @@ func (m *Manager) handleConnect(req *protocol.Request) *protocol.Response {
 			fmt.Sprintf("invalid profile: %v", err))
 	}
 
+	var rules *firewall.Rules
+	if params.KillSwitch != nil {
+		if m.killSwitch == nil {
+			return protocol.NewErrorResponse(req.ID, protocol.ErrCodeUnsupportedFeature,
+				"kill switch is not available")
+		}
+		r, err := m.killSwitchRules(params.KillSwitch)
+		if err != nil {
+			return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInvalidParams,
+				fmt.Sprintf("invalid kill switch: %v", err))
+		}
+		rules = &r
+	}
+
+	// Build connect options
+	opts := &vpn.ConnectOptions{
+		Password: params.Password,
+		OTP:      params.OTP,
+	}
+
+	if code, err := m.connect(p, opts, rules); err != nil {
+		return protocol.NewErrorResponse(req.ID, code, err.Error())
+	}
+
+	resp, err := protocol.NewSuccessResponse(req.ID, nil)
+	if err != nil {
+		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInternalError, err.Error())
+	}
+	return resp
+}
+
+// connect connects the validated profile after installing the kill switch for
+// rules, or removing it if rules is nil. On failure it returns the protocol
+// error code along with the error.
+func (m *Manager) connect(p *profile.Profile, opts *vpn.ConnectOptions, rules *firewall.Rules) (string, error) {
 	// Check if we can connect and store profile ID atomically to prevent race conditions
 	// where two concurrent connects could both pass CanConnect() check
 	m.mu.Lock()
 	if !m.controller.CanConnect() {
 		m.mu.Unlock()
-		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInvalidState,
-			fmt.Sprintf("cannot connect: current state is %s", m.controller.GetState()))
+		return protocol.ErrCodeInvalidState, fmt.Errorf("cannot connect: current state is %s", m.controller.GetState())
 	}
-	m.connectedProfileID = params.ProfileID
+	m.connectedProfileID = p.ID
+	m.tunnelDown = false
 	m.mu.Unlock()
 
-	// Build connect options
-	opts := &vpn.ConnectOptions{
-		Password: params.Password,
-		OTP:      params.OTP,
+	// Block traffic outside the tunnel before it is established. The rules stay
+	// in place through drops and reconnects until a disconnect releases them.
+	wasBlocking := m.killSwitchActive()
+	var killSwitchErr error
+	if rules != nil {
+		killSwitchErr = m.killSwitch.Enable(context.Background(), *rules)
+	} else {
+		killSwitchErr = m.releaseKillSwitch(context.Background())
+	}
+	if killSwitchErr != nil {
+		m.mu.Lock()
+		m.connectedProfileID = ""
+		m.mu.Unlock()
+		return protocol.ErrCodeKillSwitchFailed, killSwitchErr
+	}
+	if rules != nil {
+		// Nothing goes through the tunnel until its interface is known
+		m.mu.Lock()
+		m.blockingRules = *rules
+		m.mu.Unlock()
 	}
 
+	// Save what openfortivpn is about to change, in case it cannot undo it
+	m.saveNetwork(p, rules)
+
 	// Initiate connection
 	if err := m.controller.Connect(context.Background(), p, opts); err != nil {
 		m.mu.Lock()
 		m.connectedProfileID = ""
 		m.mu.Unlock()
-		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeConnectionFailed, err.Error())
+		if m.network != nil {
+			if err := m.network.Discard(); err != nil {
+				slog.Warn("Failed to discard name servers and routes", "error", err)
+			}
+		}
+		// Nothing was started, so a kill switch installed for it is not needed
+		if rules != nil && !wasBlocking {
+			if err := m.releaseKillSwitch(context.Background()); err != nil {
+				slog.Error("Failed to remove kill switch after failed connect", "error", err)
+			}
+		}
+		var unsupportedErr *vpn.UnsupportedFeatureError
+		if errors.As(err, &unsupportedErr) {
+			return protocol.ErrCodeUnsupportedFeature, err
+		}
+		return protocol.ErrCodeConnectionFailed, err
 	}
 
-	resp, err := protocol.NewSuccessResponse(req.ID, nil)
-	if err != nil {
-		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInternalError, err.Error())
-	}
-	return resp
+	return "", nil
 }
 
 // validateFilePath validates that a file path is safe for use with the VPN client.
@@ -240,25 +340,47 @@ func isSensitivePath(path string) bool {
 }
 
 func (m *Manager) handleDisconnect(req *protocol.Request) *protocol.Response {
+	var params protocol.DisconnectParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInvalidParams,
+				"invalid disconnect params")
+		}
+	}
+	if resp := m.refuseForAlwaysOn(req, "disconnect"); resp != nil {
+		return resp
+	}
+
 	if !m.controller.CanDisconnect() {
+		// A user disconnect after the tunnel dropped only lifts the kill switch
+		if !params.KeepKillSwitch && m.killSwitchActive() {
+			return m.handleReleaseKillSwitch(req)
+		}
 		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInvalidState,
 			fmt.Sprintf("cannot disconnect: current state is %s", m.controller.GetState()))
 	}
 
-	if err := m.controller.Disconnect(context.Background()); err != nil {
-		// Clear connectedProfileID even on error - the connection may be
-		// effectively terminated even if the controller reports failure.
-		// This matches onStateChange cleanup behavior for edge cases.
-		m.mu.Lock()
-		m.connectedProfileID = ""
-		m.mu.Unlock()
-		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeDisconnectFailed, err.Error())
-	}
+	disconnectErr := m.controller.Disconnect(context.Background())
 
+	// Clear connectedProfileID even on error - the connection may be
+	// effectively terminated even if the controller reports failure.
+	// This matches onStateChange cleanup behavior for edge cases.
 	m.mu.Lock()
 	m.connectedProfileID = ""
 	m.mu.Unlock()
 
+	var killSwitchErr error
+	if !params.KeepKillSwitch {
+		killSwitchErr = m.releaseKillSwitch(context.Background())
+	}
+
+	if disconnectErr != nil {
+		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeDisconnectFailed, disconnectErr.Error())
+	}
+	if killSwitchErr != nil {
+		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeKillSwitchFailed, killSwitchErr.Error())
+	}
+
 	resp, err := protocol.NewSuccessResponse(req.ID, nil)
 	if err != nil {
 		return protocol.NewErrorResponse(req.ID, protocol.ErrCodeInternalError, err.Error())
@@ -275,6 +397,9 @@ func (m *Manager) handleStatus(req *protocol.Request) *protocol.Response {
 		State:              string(m.controller.GetState()),
 		AssignedIP:         m.controller.GetAssignedIP(),
 		ConnectedProfileID: profileID,
+		Phase:              string(m.controller.GetPhase()),
+		KillSwitch:         m.killSwitchActive(),
+		AlwaysOn:           m.alwaysOn.status(),
 	}
 
 	resp, err := protocol.NewSuccessResponse(req.ID, result)
@@ -286,8 +411,9 @@ func (m *Manager) handleStatus(req *protocol.Request) *protocol.Response {
 
 func (m *Manager) onStateChange(old, new vpn.ConnectionState) {
 	event, err := protocol.NewEvent(protocol.EventStateChange, protocol.StateChangeData{
-		From: string(old),
-		To:   string(new),
+		From:     string(old),
+		To:       string(new),
+		AlwaysOn: m.alwaysOn.status(),
 	})
 	if err != nil {
 		slog.Error("Failed to create state change event", "error", err)
@@ -301,6 +427,19 @@ func (m *Manager) onStateChange(old, new vpn.ConnectionState) {
 		m.connectedProfileID = ""
 		m.mu.Unlock()
 	}
+
+	// openfortivpn set up its routes before reporting the tunnel up
+	if new == vpn.StateConnected && !tunnelUp(old) && m.network != nil {
+		m.recordGatewayRoutes()
+	}
+
+	// openfortivpn exited; restore what it left behind before reconnecting
+	if old.CanDisconnect() && (new == vpn.StateDisconnected || new == vpn.StateFailed) {
+		m.onTunnelClosed()
+		m.setKillSwitchTunnel("")
+	}
+
+	m.alwaysOn.onStateChange(old, new)
 }
 
 func (m *Manager) onOutput(line string) {
@@ -315,6 +454,17 @@ func (m *Manager) onOutput(line string) {
 }
 
 func (m *Manager) onEvent(e *vpn.OutputEvent) {
+	switch {
+	case e.Type == vpn.EventDisconnected:
+		m.mu.Lock()
+		m.tunnelDown = true
+		m.mu.Unlock()
+	case e.Type == vpn.EventGotIP && m.network != nil:
+		m.recordNameServers(e)
+	case e.Type == vpn.EventPhase && e.GetData(vpn.DataKeyPhase) == string(vpn.PhaseInterfaceUp):
+		m.setKillSwitchTunnel(e.GetData(vpn.DataKeyInterface))
+	}
+
 	data := protocol.VPNEventData{
 		EventType: string(e.Type),
 		Message:   e.Message,
@@ -329,14 +479,21 @@ func (m *Manager) onEvent(e *vpn.OutputEvent) {
 }
 
 func (m *Manager) onError(err error) {
-	event, eventErr := protocol.NewEvent(protocol.EventError, protocol.ErrorData{
+	data := protocol.ErrorData{
 		Message: err.Error(),
-	})
+	}
+	var connErr *vpn.ConnectionError
+	if errors.As(err, &connErr) {
+		data.Code = string(connErr.Code)
+	}
+	event, eventErr := protocol.NewEvent(protocol.EventError, data)
 	if eventErr != nil {
 		slog.Error("Failed to create error event", "error", eventErr)
 		return
 	}
 	m.broadcaster(event)
+
+	m.alwaysOn.onError(err)
 }
 
 // GetState returns the current VPN state.
@@ -344,16 +501,19 @@ func (m *Manager) GetState() vpn.ConnectionState {
 	return m.controller.GetState()
 }
 
-// Shutdown gracefully disconnects the VPN if connected.
+// Shutdown gracefully disconnects the VPN if connected, without reconnecting
+// the always-on profile, and removes the kill switch.
 // Uses a timeout to prevent hanging indefinitely.
 func (m *Manager) Shutdown() {
+	const shutdownTimeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	m.alwaysOn.stop()
+
 	if m.controller.CanDisconnect() {
 		slog.Info("Disconnecting VPN before shutdown")
 
-		const shutdownTimeout = 10 * time.Second
-		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
-		defer cancel()
-
 		if err := m.controller.Disconnect(ctx); err != nil {
 			if errors.Is(err, context.DeadlineExceeded) {
 				slog.Error("Disconnect timed out during shutdown", "timeout", shutdownTimeout)
@@ -362,4 +522,8 @@ func (m *Manager) Shutdown() {
 			}
 		}
 	}
+
+	if err := m.releaseKillSwitch(ctx); err != nil {
+		slog.Error("Failed to remove kill switch during shutdown", "error", err)
+	}
 }
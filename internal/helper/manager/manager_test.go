@@ -2,12 +2,17 @@
 package manager
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/helper/protocol"
+	"github.com/shini4i/openfortivpn-gui/internal/vpn"
 )
 
 // TestValidateFilePath tests the validateFilePath function which is critical for security.
@@ -355,3 +360,87 @@ func TestResolvePathSafely(t *testing.T) {
 		assert.True(t, os.IsNotExist(err))
 	})
 }
+
+func TestManager_HandleStatus_IncludesPhase(t *testing.T) {
+	ctrl := newMockController()
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast)
+	ctrl.setState(vpn.StateConnecting)
+	ctrl.phase = vpn.PhaseNegotiationComplete
+
+	req, err := protocol.NewRequest("1", protocol.CommandStatus, protocol.StatusParams{})
+	require.NoError(t, err)
+
+	resp := m.HandleRequest(req)
+	require.True(t, resp.Success)
+
+	var status protocol.StatusResult
+	require.NoError(t, json.Unmarshal(resp.Result, &status))
+	assert.Equal(t, "connecting", status.State)
+	assert.Equal(t, "negotiation_complete", status.Phase)
+}
+
+func TestManager_OnError_IncludesErrorCode(t *testing.T) {
+	ctrl := newMockController()
+	recorder := &eventRecorder{}
+	NewManagerWithController(ctrl, recorder.broadcast)
+
+	ctrl.onError(&vpn.ConnectionError{
+		Code:    vpn.ErrorCodeCertificateMismatch,
+		Message: "Gateway certificate validation failed",
+	})
+	ctrl.onError(errors.New("plain error"))
+
+	events := recorder.byName(protocol.EventError)
+	require.Len(t, events, 2)
+
+	var data protocol.ErrorData
+	require.NoError(t, json.Unmarshal(events[0].Data, &data))
+	assert.Equal(t, "certificate_mismatch", data.Code)
+	assert.Equal(t, "Gateway certificate validation failed", data.Message)
+
+	data = protocol.ErrorData{}
+	require.NoError(t, json.Unmarshal(events[1].Data, &data))
+	assert.Empty(t, data.Code)
+	assert.Equal(t, "plain error", data.Message)
+}
+
+func TestManager_HandleConnect_UnsupportedFeature(t *testing.T) {
+	ctrl := newMockController()
+	ctrl.connectErr = &vpn.UnsupportedFeatureError{
+		Feature:   vpn.FeatureSAMLLogin,
+		Installed: vpn.Version{Major: 1, Minor: 16},
+	}
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast)
+
+	req, err := protocol.NewRequest("1", protocol.CommandConnect, protocol.ConnectParams{
+		ProfileID:  "550e8400-e29b-41d4-a716-446655440000",
+		Host:       "vpn.example.com",
+		Port:       443,
+		AuthMethod: "saml",
+		NoFTMPush:  true,
+	})
+	require.NoError(t, err)
+
+	resp := m.HandleRequest(req)
+	require.False(t, resp.Success)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, protocol.ErrCodeUnsupportedFeature, resp.Error.Code)
+}
+
+func TestManager_HandleConnect_PassesVersionGatedOptions(t *testing.T) {
+	ctrl := newMockController()
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast)
+
+	req, err := protocol.NewRequest("1", protocol.CommandConnect, protocol.ConnectParams{
+		ProfileID:  "550e8400-e29b-41d4-a716-446655440000",
+		Host:       "vpn.example.com",
+		Port:       443,
+		AuthMethod: "saml",
+		NoFTMPush:  true,
+	})
+	require.NoError(t, err)
+
+	resp := m.HandleRequest(req)
+	require.True(t, resp.Success)
+	assert.True(t, ctrl.connectedProfile.NoFTMPush)
+}
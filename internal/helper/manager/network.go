@@ -0,0 +1,160 @@
+package manager
+
+import (
+	"context"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shini4i/openfortivpn-gui/internal/helper/firewall"
+	"github.com/shini4i/openfortivpn-gui/internal/helper/netstate"
+	"github.com/shini4i/openfortivpn-gui/internal/helper/protocol"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+	"github.com/shini4i/openfortivpn-gui/internal/vpn"
+)
+
+// networkTimeout bounds saving or restoring the name servers and routes.
+const networkTimeout = 10 * time.Second
+
+// NetworkGuard saves the name servers and routes before a connection and
+// restores them if openfortivpn leaves its changes behind.
+// It is implemented by netstate.Guard.
+type NetworkGuard interface {
+	// Save takes a snapshot before connecting to one of the gateway addresses.
+	Save(ctx context.Context, gateways []netip.Addr) error
+	// RecordNameServers adds the name servers the VPN assigned to the snapshot.
+	RecordNameServers(servers []netip.Addr) error
+	// RecordGatewayRoutes adds the gateways openfortivpn added host routes to.
+	RecordGatewayRoutes(ctx context.Context) error
+	// Discard drops the snapshot after openfortivpn cleaned up after itself.
+	Discard() error
+	// Restore puts back what openfortivpn left changed and drops the snapshot.
+	Restore(ctx context.Context) (netstate.Report, error)
+}
+
+// WithNetworkGuard sets the guard restoring the name servers and routes after
+// openfortivpn exits without cleaning up. Without it, nothing is restored.
+func WithNetworkGuard(g NetworkGuard) Option {
+	return func(m *Manager) {
+		m.network = g
+	}
+}
+
+// RestoreNetwork restores the name servers and routes left behind when the
+// previous helper, and openfortivpn with it, did not shut down cleanly.
+// It is called once at startup, before any connection.
+func (m *Manager) RestoreNetwork() {
+	if m.network == nil {
+		return
+	}
+	m.restoreNetwork()
+}
+
+// saveNetwork takes a snapshot of the name servers and routes before connecting.
+// Connecting goes ahead if it fails; there is just nothing to restore.
+//
+// The gateway addresses are those the kill switch rules were built for, or
+// the host if it is an address. Host names are not resolved here, which would
+// delay every connect; the routes openfortivpn adds to the addresses it
+// resolved are recorded once the tunnel is up.
+func (m *Manager) saveNetwork(p *profile.Profile, rules *firewall.Rules) {
+	if m.network == nil {
+		return
+	}
+	var gateways []netip.Addr
+	if rules != nil {
+		for _, gw := range rules.Gateways {
+			if addr := gw.Addr().Unmap(); !slices.Contains(gateways, addr) {
+				gateways = append(gateways, addr)
+			}
+		}
+	} else if addr, err := netip.ParseAddr(p.Host); err == nil {
+		gateways = []netip.Addr{addr.Unmap()}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), networkTimeout)
+	defer cancel()
+	if err := m.network.Save(ctx, gateways); err != nil {
+		slog.Warn("Failed to save name servers and routes", "error", err)
+	}
+}
+
+// recordGatewayRoutes adds the gateways openfortivpn routed over the uplink to the snapshot.
+func (m *Manager) recordGatewayRoutes() {
+	ctx, cancel := context.WithTimeout(context.Background(), networkTimeout)
+	defer cancel()
+	if err := m.network.RecordGatewayRoutes(ctx); err != nil {
+		slog.Warn("Failed to record gateway routes", "error", err)
+	}
+}
+
+// recordNameServers adds the VPN name servers of a got_ip event to the snapshot.
+func (m *Manager) recordNameServers(e *vpn.OutputEvent) {
+	var servers []netip.Addr
+	for _, ns := range strings.Split(e.Data[vpn.DataKeyDNS], ",") {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(ns)); err == nil {
+			servers = append(servers, addr.Unmap())
+		}
+	}
+	if len(servers) == 0 {
+		return
+	}
+	if err := m.network.RecordNameServers(servers); err != nil {
+		slog.Warn("Failed to record VPN name servers", "error", err)
+	}
+}
+
+// onTunnelClosed drops the snapshot if openfortivpn reported the tunnel down,
+// having restored the name servers and routes itself, and restores them otherwise.
+func (m *Manager) onTunnelClosed() {
+	m.mu.Lock()
+	clean := m.tunnelDown
+	m.tunnelDown = false
+	m.mu.Unlock()
+
+	if m.network == nil {
+		return
+	}
+	if !clean {
+		m.restoreNetwork()
+		return
+	}
+	if err := m.network.Discard(); err != nil {
+		slog.Warn("Failed to discard name servers and routes", "error", err)
+	}
+}
+
+// restoreNetwork restores the snapshot and reports what it fixed to clients.
+func (m *Manager) restoreNetwork() {
+	ctx, cancel := context.WithTimeout(context.Background(), networkTimeout)
+	defer cancel()
+
+	report, err := m.network.Restore(ctx)
+	if err != nil {
+		slog.Error("Failed to restore name servers and routes", "error", err)
+	}
+	if report.Empty() && err == nil {
+		return
+	}
+	slog.Warn("Restored name servers and routes left by openfortivpn",
+		"resolv_conf", report.ResolvConf,
+		"added_routes", report.AddedRoutes,
+		"removed_routes", report.RemovedRoutes)
+
+	data := protocol.NetworkRestoredData{
+		ResolvConf:    report.ResolvConf,
+		AddedRoutes:   report.AddedRoutes,
+		RemovedRoutes: report.RemovedRoutes,
+	}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	event, eventErr := protocol.NewEvent(protocol.EventNetworkRestored, data)
+	if eventErr != nil {
+		slog.Error("Failed to create network restored event", "error", eventErr)
+		return
+	}
+	m.broadcaster(event)
+}
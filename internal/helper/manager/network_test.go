@@ -0,0 +1,236 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/helper/netstate"
+	"github.com/shini4i/openfortivpn-gui/internal/helper/protocol"
+	"github.com/shini4i/openfortivpn-gui/internal/vpn"
+)
+
+// fakeNetworkGuard records how the manager uses the snapshot.
+type fakeNetworkGuard struct {
+	mu          sync.Mutex
+	saved       bool
+	gateways    []netip.Addr
+	nameServers []netip.Addr
+	routeChecks int
+	discards    int
+	restores    int
+	report      netstate.Report
+	restoreErr  error
+}
+
+func (g *fakeNetworkGuard) Save(_ context.Context, gateways []netip.Addr) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.saved = true
+	g.gateways = gateways
+	return nil
+}
+
+func (g *fakeNetworkGuard) RecordNameServers(servers []netip.Addr) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nameServers = append(g.nameServers, servers...)
+	return nil
+}
+
+func (g *fakeNetworkGuard) RecordGatewayRoutes(context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.routeChecks++
+	return nil
+}
+
+func (g *fakeNetworkGuard) Discard() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.saved = false
+	g.discards++
+	return nil
+}
+
+func (g *fakeNetworkGuard) Restore(context.Context) (netstate.Report, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.saved = false
+	g.restores++
+	return g.report, g.restoreErr
+}
+
+// countingResolver counts the gateway lookups.
+type countingResolver struct {
+	mu       sync.Mutex
+	lookups  int
+	resolver fakeResolver
+}
+
+func (r *countingResolver) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	r.mu.Lock()
+	r.lookups++
+	r.mu.Unlock()
+	return r.resolver.resolve(ctx, host)
+}
+
+func newNetworkManager(ctrl *mockController, guard *fakeNetworkGuard, recorder *eventRecorder) *Manager {
+	return NewManagerWithController(ctrl, recorder.broadcast, WithNetworkGuard(guard),
+		WithGatewayResolver(fakeResolver{"vpn.example.com": {netip.MustParseAddr("203.0.113.10")}}.resolve))
+}
+
+var leftoverReport = netstate.Report{
+	ResolvConf:    true,
+	AddedRoutes:   []string{"default via 192.168.1.1 dev eth0 proto dhcp metric 100"},
+	RemovedRoutes: []string{"203.0.113.10 via 192.168.1.1 dev eth0"},
+}
+
+func TestManager_Network_SavedBeforeConnect(t *testing.T) {
+	ctrl := newMockController()
+	guard := &fakeNetworkGuard{}
+	resolver := &countingResolver{resolver: fakeResolver{"vpn.example.com": {netip.MustParseAddr("203.0.113.10")}}}
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, WithNetworkGuard(guard),
+		WithGatewayResolver(resolver.resolve))
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, nil)).Success)
+
+	// The gateway host name is left for openfortivpn to resolve
+	assert.True(t, guard.saved)
+	assert.Empty(t, guard.gateways)
+	assert.Zero(t, resolver.lookups)
+
+	ctrl.onEvent(&vpn.OutputEvent{Type: vpn.EventGotIP, Data: map[string]string{
+		"ip":           "10.0.0.2",
+		vpn.DataKeyDNS: "10.0.0.53,10.0.0.54",
+	}})
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("10.0.0.53"), netip.MustParseAddr("10.0.0.54")}, guard.nameServers)
+
+	// Its routes to the gateway are recorded once the tunnel is up
+	ctrl.setState(vpn.StateConnected)
+	ctrl.setState(vpn.StateDegraded)
+	ctrl.setState(vpn.StateConnected)
+	assert.Equal(t, 1, guard.routeChecks)
+}
+
+func TestManager_Network_KillSwitchGateways(t *testing.T) {
+	ctrl := newMockController()
+	guard := &fakeNetworkGuard{}
+	resolver := &countingResolver{resolver: fakeResolver{
+		"vpn.example.com": {netip.MustParseAddr("203.0.113.10"), netip.MustParseAddr("::ffff:203.0.113.11")},
+	}}
+	m := NewManagerWithController(ctrl, (&eventRecorder{}).broadcast, WithNetworkGuard(guard),
+		WithKillSwitch(&fakeKillSwitch{}), WithGatewayResolver(resolver.resolve), WithUplinkNameServers(noNameServers))
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, &protocol.KillSwitchParams{
+		Gateways: []string{"vpn.example.com:443", "vpn.example.com:10443"},
+	})).Success)
+
+	// The addresses resolved for the rules are reused
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("203.0.113.10"), netip.MustParseAddr("203.0.113.11")}, guard.gateways)
+	assert.Equal(t, 2, resolver.lookups)
+}
+
+func TestManager_Network_CleanExitDiscards(t *testing.T) {
+	ctrl := newMockController()
+	guard := &fakeNetworkGuard{report: leftoverReport}
+	recorder := &eventRecorder{}
+	m := newNetworkManager(ctrl, guard, recorder)
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, nil)).Success)
+	ctrl.setState(vpn.StateConnected)
+
+	ctrl.onEvent(&vpn.OutputEvent{Type: vpn.EventDisconnected, Message: "Tunnel is down"})
+	ctrl.setState(vpn.StateDisconnected)
+
+	assert.Equal(t, 1, guard.discards)
+	assert.Zero(t, guard.restores)
+	assert.Empty(t, recorder.byName(protocol.EventNetworkRestored))
+}
+
+func TestManager_Network_AbnormalExitRestores(t *testing.T) {
+	ctrl := newMockController()
+	guard := &fakeNetworkGuard{report: leftoverReport}
+	recorder := &eventRecorder{}
+	m := newNetworkManager(ctrl, guard, recorder)
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, nil)).Success)
+	ctrl.setState(vpn.StateConnected)
+
+	// openfortivpn was killed without reporting the tunnel down
+	ctrl.setState(vpn.StateDisconnected)
+
+	assert.Equal(t, 1, guard.restores)
+	events := recorder.byName(protocol.EventNetworkRestored)
+	require.Len(t, events, 1)
+	var data protocol.NetworkRestoredData
+	require.NoError(t, json.Unmarshal(events[0].Data, &data))
+	assert.Equal(t, protocol.NetworkRestoredData{
+		ResolvConf:    true,
+		AddedRoutes:   leftoverReport.AddedRoutes,
+		RemovedRoutes: leftoverReport.RemovedRoutes,
+	}, data)
+}
+
+func TestManager_Network_CleanFlagResetOnConnect(t *testing.T) {
+	ctrl := newMockController()
+	guard := &fakeNetworkGuard{}
+	m := newNetworkManager(ctrl, guard, &eventRecorder{})
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, nil)).Success)
+	ctrl.onEvent(&vpn.OutputEvent{Type: vpn.EventDisconnected})
+	ctrl.setState(vpn.StateDisconnected)
+	require.Equal(t, 1, guard.discards)
+
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, nil)).Success)
+	ctrl.setState(vpn.StateFailed)
+
+	assert.Equal(t, 1, guard.restores)
+}
+
+func TestManager_Network_NothingToReport(t *testing.T) {
+	ctrl := newMockController()
+	guard := &fakeNetworkGuard{}
+	recorder := &eventRecorder{}
+	m := newNetworkManager(ctrl, guard, recorder)
+	require.True(t, m.HandleRequest(killSwitchConnectRequest(t, nil)).Success)
+
+	ctrl.setState(vpn.StateFailed)
+
+	assert.Equal(t, 1, guard.restores)
+	assert.Empty(t, recorder.byName(protocol.EventNetworkRestored))
+}
+
+func TestManager_Network_FailedConnectDiscards(t *testing.T) {
+	ctrl := newMockController()
+	ctrl.connectErr = errors.New("openfortivpn not found")
+	guard := &fakeNetworkGuard{}
+	m := newNetworkManager(ctrl, guard, &eventRecorder{})
+
+	require.False(t, m.HandleRequest(killSwitchConnectRequest(t, nil)).Success)
+
+	assert.False(t, guard.saved)
+	assert.Equal(t, 1, guard.discards)
+}
+
+func TestManager_RestoreNetwork_AtStartup(t *testing.T) {
+	guard := &fakeNetworkGuard{restoreErr: errors.New("failed to add route: Nexthop has invalid gateway")}
+	recorder := &eventRecorder{}
+	m := newNetworkManager(newMockController(), guard, recorder)
+
+	m.RestoreNetwork()
+
+	assert.Equal(t, 1, guard.restores)
+	events := recorder.byName(protocol.EventNetworkRestored)
+	require.Len(t, events, 1)
+	var data protocol.NetworkRestoredData
+	require.NoError(t, json.Unmarshal(events[0].Data, &data))
+	assert.Equal(t, "failed to add route: Nexthop has invalid gateway", data.Error)
+
+	// Without a guard there is nothing to restore
+	NewManagerWithController(newMockController(), recorder.broadcast).RestoreNetwork()
+}
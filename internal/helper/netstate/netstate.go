@@ -0,0 +1,486 @@
+// Package netstate protects the helper daemon's host from the name servers and
+// routes openfortivpn leaves behind when it is killed or crashes. Before a
+// connection the name resolver configuration and the IPv4 routes are saved to
+// disk; if openfortivpn does not clean up after itself, they are restored.
+//
+// openfortivpn only changes IPv4 routes, and routes on the tunnel interface
+// disappear with it, so only IPv4 routes on other interfaces are saved.
+package netstate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// DefaultPath is where the snapshot is kept while a tunnel is up, in the
+	// helper's state directory so that it survives a crash of the helper.
+	DefaultPath = "/var/lib/openfortivpn-gui/network-snapshot.json"
+	// ResolvConfPath is the name resolver configuration openfortivpn rewrites.
+	ResolvConfPath = "/etc/resolv.conf"
+	// UpstreamResolvConfPath lists the name servers systemd-resolved forwards to
+	// when ResolvConfPath points at its local stub.
+	UpstreamResolvConfPath = "/run/systemd/resolve/resolv.conf"
+	// tunnelInterfacePrefix matches the ppp interfaces openfortivpn creates.
+	tunnelInterfacePrefix = "ppp"
+	// defaultIPPath is the ip binary used unless WithIP sets another.
+	defaultIPPath = "ip"
+	// bootIDPath identifies the current boot; routes of another boot are gone.
+	bootIDPath = "/proc/sys/kernel/random/boot_id"
+)
+
+// Route is a route of the main routing table as reported by ip -json.
+type Route struct {
+	Dst      string `json:"dst"`
+	Gateway  string `json:"gateway,omitempty"`
+	Dev      string `json:"dev"`
+	Protocol string `json:"protocol,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	Prefsrc  string `json:"prefsrc,omitempty"`
+	Metric   int    `json:"metric,omitempty"`
+}
+
+// String returns the route in ip route syntax.
+func (r Route) String() string {
+	return strings.Join(r.args(), " ")
+}
+
+// args returns the arguments selecting the route for ip route.
+func (r Route) args() []string {
+	args := []string{r.Dst}
+	if r.Gateway != "" {
+		args = append(args, "via", r.Gateway)
+	}
+	args = append(args, "dev", r.Dev)
+	if r.Protocol != "" {
+		args = append(args, "proto", r.Protocol)
+	}
+	if r.Scope != "" {
+		args = append(args, "scope", r.Scope)
+	}
+	if r.Prefsrc != "" {
+		args = append(args, "src", r.Prefsrc)
+	}
+	if r.Metric != 0 {
+		args = append(args, "metric", fmt.Sprint(r.Metric))
+	}
+	return args
+}
+
+// key identifies the route regardless of the attributes the kernel fills in.
+func (r Route) key() string {
+	return fmt.Sprintf("%s %s %s %d", r.Dst, r.Gateway, r.Dev, r.Metric)
+}
+
+// snapshot is the saved state, as stored on disk.
+type snapshot struct {
+	BootID string `json:"boot_id"`
+	// ResolvConf is the resolver configuration, nil if it is a symlink and
+	// thus managed by a resolver service rather than edited by openfortivpn.
+	ResolvConf *string `json:"resolv_conf,omitempty"`
+	// ResolvConfMode is the permission bits of the resolver configuration.
+	ResolvConfMode os.FileMode `json:"resolv_conf_mode,omitempty"`
+	Routes         []Route     `json:"routes"`
+	// Gateways are the VPN gateway addresses, which openfortivpn adds host routes to.
+	Gateways []netip.Addr `json:"gateways,omitempty"`
+	// NameServers are the VPN name servers, which openfortivpn adds to the resolver configuration.
+	NameServers []netip.Addr `json:"name_servers,omitempty"`
+}
+
+// Report describes what Restore fixed.
+type Report struct {
+	// ResolvConf reports whether the resolver configuration was restored.
+	ResolvConf bool
+	// AddedRoutes are the saved routes that were missing and added again.
+	AddedRoutes []string
+	// RemovedRoutes are the leftover routes to the VPN gateways that were deleted.
+	RemovedRoutes []string
+}
+
+// Empty reports whether nothing had to be fixed.
+func (r Report) Empty() bool {
+	return !r.ResolvConf && len(r.AddedRoutes) == 0 && len(r.RemovedRoutes) == 0
+}
+
+// Runner runs ip with the given arguments, returning its output.
+type Runner func(ctx context.Context, args ...string) ([]byte, error)
+
+// Option configures a Guard.
+type Option func(*Guard)
+
+// WithIP sets the ip binary the guard runs.
+func WithIP(path string) Option {
+	return func(g *Guard) {
+		g.run = execRunner(path)
+	}
+}
+
+// WithRunner sets how the guard runs ip. Tests use it to fake the routing table.
+func WithRunner(run Runner) Option {
+	return func(g *Guard) {
+		g.run = run
+	}
+}
+
+// WithResolvConf sets the resolver configuration the guard saves and restores.
+func WithResolvConf(path string) Option {
+	return func(g *Guard) {
+		g.resolvConf = path
+	}
+}
+
+// WithBootID sets the identifier of the current boot instead of reading it from the kernel.
+func WithBootID(id string) Option {
+	return func(g *Guard) {
+		g.bootID = func() (string, error) { return id, nil }
+	}
+}
+
+// Guard saves the name servers and routes before a connection and restores them
+// if openfortivpn leaves its changes behind. Calls must not overlap.
+type Guard struct {
+	path       string
+	resolvConf string
+	run        Runner
+	bootID     func() (string, error)
+}
+
+// NewGuard creates a guard keeping its snapshot at path.
+func NewGuard(path string, opts ...Option) *Guard {
+	g := &Guard{
+		path:       path,
+		resolvConf: ResolvConfPath,
+		run:        execRunner(defaultIPPath),
+		bootID:     readBootID,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Save takes a snapshot before connecting to one of the given gateway addresses,
+// replacing any previous one.
+func (g *Guard) Save(ctx context.Context, gateways []netip.Addr) error {
+	bootID, err := g.bootID()
+	if err != nil {
+		return fmt.Errorf("failed to identify boot: %w", err)
+	}
+	routes, err := g.routes(ctx)
+	if err != nil {
+		return err
+	}
+	s := &snapshot{BootID: bootID, Routes: routes, Gateways: gateways}
+
+	info, err := os.Lstat(g.resolvConf)
+	switch {
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("failed to read resolver configuration: %w", err)
+	case err == nil && info.Mode().IsRegular():
+		data, err := os.ReadFile(g.resolvConf)
+		if err != nil {
+			return fmt.Errorf("failed to read resolver configuration: %w", err)
+		}
+		content := string(data)
+		s.ResolvConf = &content
+		s.ResolvConfMode = info.Mode().Perm()
+	}
+
+	return g.write(s)
+}
+
+// RecordNameServers adds the name servers the VPN assigned to the snapshot, so
+// Restore can tell whether they are left in the resolver configuration.
+// It does nothing without a snapshot.
+func (g *Guard) RecordNameServers(servers []netip.Addr) error {
+	s, err := g.read()
+	if err != nil || s == nil {
+		return err
+	}
+	for _, ns := range servers {
+		if !slices.Contains(s.NameServers, ns) {
+			s.NameServers = append(s.NameServers, ns)
+		}
+	}
+	return g.write(s)
+}
+
+// RecordGatewayRoutes adds the destinations of the host routes outside the
+// tunnel that appeared since Save to the snapshot's gateways, so Restore
+// deletes the routes openfortivpn added to gateways it resolved itself.
+// It does nothing without a snapshot.
+func (g *Guard) RecordGatewayRoutes(ctx context.Context) error {
+	s, err := g.read()
+	if err != nil || s == nil {
+		return err
+	}
+	current, err := g.routes(ctx)
+	if err != nil {
+		return err
+	}
+	saved := make(map[string]bool, len(s.Routes))
+	for _, r := range s.Routes {
+		saved[r.key()] = true
+	}
+	for _, r := range current {
+		addr, err := netip.ParseAddr(r.Dst)
+		if saved[r.key()] || err != nil || r.Gateway == "" || slices.Contains(s.Gateways, addr.Unmap()) {
+			continue
+		}
+		s.Gateways = append(s.Gateways, addr.Unmap())
+	}
+	return g.write(s)
+}
+
+// Discard drops the snapshot after openfortivpn cleaned up after itself.
+func (g *Guard) Discard() error {
+	if err := os.Remove(g.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove network snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore compares the system with the snapshot, puts back what openfortivpn
+// left changed and drops the snapshot. The resolver configuration is restored
+// if it still lists a VPN name server, so later changes by other programs are
+// kept. Saved routes that are missing are added again, unless their interface
+// is gone, and leftover host routes to the VPN gateways are deleted. Routes are
+// left alone after a reboot. Without a snapshot, Restore does nothing.
+//
+// The report lists what was fixed even if restoring something else failed.
+func (g *Guard) Restore(ctx context.Context) (Report, error) {
+	var report Report
+	s, err := g.read()
+	if err != nil || s == nil {
+		return report, err
+	}
+
+	var errs []error
+	if restored, err := g.restoreResolvConf(s); err != nil {
+		errs = append(errs, err)
+	} else {
+		report.ResolvConf = restored
+	}
+
+	if bootID, err := g.bootID(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to identify boot: %w", err))
+	} else if bootID == s.BootID {
+		added, removed, err := g.restoreRoutes(ctx, s)
+		report.AddedRoutes, report.RemovedRoutes = added, removed
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := g.Discard(); err != nil {
+		errs = append(errs, err)
+	}
+	return report, errors.Join(errs...)
+}
+
+// restoreResolvConf writes the saved resolver configuration back if the VPN
+// name servers are still listed. It reports whether it did.
+func (g *Guard) restoreResolvConf(s *snapshot) (bool, error) {
+	if s.ResolvConf == nil || len(s.NameServers) == 0 {
+		return false, nil
+	}
+	data, err := os.ReadFile(g.resolvConf)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("failed to read resolver configuration: %w", err)
+	}
+	if string(data) == *s.ResolvConf {
+		return false, nil
+	}
+
+	saved := nameServers(*s.ResolvConf)
+	leftover := false
+	for _, ns := range nameServers(string(data)) {
+		if slices.Contains(s.NameServers, ns) && !slices.Contains(saved, ns) {
+			leftover = true
+			break
+		}
+	}
+	if !leftover {
+		return false, nil
+	}
+
+	// Written in place, since the file may be bind-mounted into the helper's sandbox
+	// #nosec G306 -- the resolver configuration is world-readable by design
+	if err := os.WriteFile(g.resolvConf, []byte(*s.ResolvConf), s.ResolvConfMode); err != nil {
+		return false, fmt.Errorf("failed to restore resolver configuration: %w", err)
+	}
+	return true, nil
+}
+
+// NameServers returns the name servers of the resolver configurations at paths
+// that are reached over the network, skipping local stubs and missing files.
+func NameServers(paths ...string) []netip.Addr {
+	var servers []netip.Addr
+	for _, path := range paths {
+		// #nosec G304 -- the paths are fixed by the caller
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, addr := range nameServers(string(data)) {
+			if !addr.IsLoopback() && !slices.Contains(servers, addr) {
+				servers = append(servers, addr)
+			}
+		}
+	}
+	return servers
+}
+
+// nameServers returns the addresses of the nameserver lines of a resolver configuration.
+func nameServers(resolvConf string) []netip.Addr {
+	var servers []netip.Addr
+	scanner := bufio.NewScanner(strings.NewReader(resolvConf))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(fields[1]); err == nil {
+			servers = append(servers, addr.Unmap())
+		}
+	}
+	return servers
+}
+
+// restoreRoutes adds the saved routes that are missing and deletes host routes
+// to the VPN gateways that were not saved.
+func (g *Guard) restoreRoutes(ctx context.Context, s *snapshot) (added, removed []string, err error) {
+	current, err := g.routes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	present := make(map[string]bool, len(current))
+	for _, r := range current {
+		present[r.key()] = true
+	}
+	saved := make(map[string]bool, len(s.Routes))
+	for _, r := range s.Routes {
+		saved[r.key()] = true
+	}
+
+	var errs []error
+	for _, r := range current {
+		addr, parseErr := netip.ParseAddr(r.Dst)
+		if saved[r.key()] || parseErr != nil || !slices.Contains(s.Gateways, addr.Unmap()) {
+			continue
+		}
+		if _, err := g.run(ctx, append([]string{"-4", "route", "del"}, r.args()...)...); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete route %s: %w", r, err))
+			continue
+		}
+		removed = append(removed, r.String())
+	}
+
+	for _, r := range s.Routes {
+		if present[r.key()] {
+			continue
+		}
+		// Routes of an interface that went away meanwhile are gone for good
+		if _, err := net.InterfaceByName(r.Dev); err != nil {
+			continue
+		}
+		if _, err := g.run(ctx, append([]string{"-4", "route", "add"}, r.args()...)...); err != nil {
+			errs = append(errs, fmt.Errorf("failed to add route %s: %w", r, err))
+			continue
+		}
+		added = append(added, r.String())
+	}
+	return added, removed, errors.Join(errs...)
+}
+
+// routes lists the IPv4 routes of the main table outside the tunnel.
+func (g *Guard) routes(ctx context.Context) ([]Route, error) {
+	out, err := g.run(ctx, "-4", "-json", "route", "show", "table", "main")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+	var all []Route
+	if err := json.Unmarshal(out, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse routes: %w", err)
+	}
+	routes := make([]Route, 0, len(all))
+	for _, r := range all {
+		if !strings.HasPrefix(r.Dev, tunnelInterfacePrefix) {
+			routes = append(routes, r)
+		}
+	}
+	return routes, nil
+}
+
+// read loads the snapshot, returning nil if there is none.
+func (g *Guard) read() (*snapshot, error) {
+	data, err := os.ReadFile(g.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read network snapshot: %w", err)
+	}
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse network snapshot: %w", err)
+	}
+	return &s, nil
+}
+
+// write stores the snapshot atomically, readable by root only.
+func (g *Guard) write(s *snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode network snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(g.path), 0o750); err != nil {
+		return fmt.Errorf("failed to create network snapshot directory: %w", err)
+	}
+	tmp := g.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write network snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, g.path); err != nil {
+		return fmt.Errorf("failed to write network snapshot: %w", err)
+	}
+	return nil
+}
+
+// readBootID returns the kernel's identifier of the current boot.
+func readBootID() (string, error) {
+	data, err := os.ReadFile(bootIDPath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// execRunner returns a Runner executing the ip binary at path.
+func execRunner(path string) Runner {
+	return func(ctx context.Context, args ...string) ([]byte, error) {
+		// #nosec G204 -- the binary is configured by the administrator and the arguments come from ip itself
+		cmd := exec.CommandContext(ctx, path, args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return out, fmt.Errorf("%w: %s", err, msg)
+			}
+			return out, err
+		}
+		return out, nil
+	}
+}
@@ -0,0 +1,263 @@
+package netstate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIP is a main routing table that ip route add and del change.
+type fakeIP struct {
+	routes []Route
+	calls  []string
+}
+
+func (f *fakeIP) run(_ context.Context, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, strings.Join(args, " "))
+	switch {
+	case slices.Equal(args, []string{"-4", "-json", "route", "show", "table", "main"}):
+		return json.Marshal(f.routes)
+	case len(args) > 3 && args[2] == "add":
+		f.routes = append(f.routes, parseRoute(args[3:]))
+	case len(args) > 3 && args[2] == "del":
+		key := parseRoute(args[3:]).key()
+		f.routes = slices.DeleteFunc(f.routes, func(r Route) bool { return r.key() == key })
+	}
+	return nil, nil
+}
+
+// parseRoute parses the arguments Route.args produces.
+func parseRoute(args []string) Route {
+	r := Route{Dst: args[0]}
+	for i := 1; i+1 < len(args); i += 2 {
+		switch args[i] {
+		case "via":
+			r.Gateway = args[i+1]
+		case "dev":
+			r.Dev = args[i+1]
+		case "proto":
+			r.Protocol = args[i+1]
+		case "scope":
+			r.Scope = args[i+1]
+		case "src":
+			r.Prefsrc = args[i+1]
+		case "metric":
+			_, _ = fmt.Sscan(args[i+1], &r.Metric)
+		}
+	}
+	return r
+}
+
+var (
+	defaultRoute = Route{Dst: "default", Gateway: "192.0.2.1", Dev: "lo", Protocol: "dhcp", Metric: 100}
+	lanRoute     = Route{Dst: "192.0.2.0/24", Dev: "lo", Protocol: "kernel", Scope: "link", Prefsrc: "192.0.2.2", Metric: 100}
+	gatewayRoute = Route{Dst: "203.0.113.10", Gateway: "192.0.2.1", Dev: "lo"}
+	tunnelRoute  = Route{Dst: "10.0.0.0/8", Dev: "ppp0", Protocol: "static"}
+)
+
+const resolvConf = "search example.org\nnameserver 192.0.2.53\n"
+
+// newTestGuard returns a guard over a temporary resolver configuration.
+func newTestGuard(t *testing.T, ip *fakeIP, bootID string) (*Guard, string) {
+	t.Helper()
+	dir := t.TempDir()
+	resolv := filepath.Join(dir, "resolv.conf")
+	require.NoError(t, os.WriteFile(resolv, []byte(resolvConf), 0o644))
+	g := NewGuard(filepath.Join(dir, "state", "network-snapshot.json"),
+		WithRunner(ip.run), WithResolvConf(resolv), WithBootID(bootID))
+	return g, resolv
+}
+
+// reopen returns a guard over the same files with another boot identifier, as
+// after a restart of the helper.
+func reopen(g *Guard, ip *fakeIP, bootID string) *Guard {
+	return NewGuard(g.path, WithRunner(ip.run), WithResolvConf(g.resolvConf), WithBootID(bootID))
+}
+
+// crash changes the system the way openfortivpn does without cleaning up.
+func crash(t *testing.T, ip *fakeIP, resolv string) {
+	t.Helper()
+	ip.routes = []Route{lanRoute, gatewayRoute, {Dst: "default", Dev: "ppp0"}}
+	require.NoError(t, os.WriteFile(resolv, []byte("nameserver 10.0.0.53\n"+resolvConf), 0o644))
+}
+
+func TestGuard_RestoreAfterCrash(t *testing.T) {
+	ip := &fakeIP{routes: []Route{defaultRoute, lanRoute, tunnelRoute}}
+	g, resolv := newTestGuard(t, ip, "boot-1")
+
+	require.NoError(t, g.Save(context.Background(), []netip.Addr{netip.MustParseAddr("203.0.113.10")}))
+	require.NoError(t, g.RecordNameServers([]netip.Addr{netip.MustParseAddr("10.0.0.53")}))
+	crash(t, ip, resolv)
+
+	report, err := reopen(g, ip, "boot-1").Restore(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, report.ResolvConf)
+	assert.Equal(t, []string{"default via 192.0.2.1 dev lo proto dhcp metric 100"}, report.AddedRoutes)
+	assert.Equal(t, []string{"203.0.113.10 via 192.0.2.1 dev lo"}, report.RemovedRoutes)
+	data, err := os.ReadFile(resolv)
+	require.NoError(t, err)
+	assert.Equal(t, resolvConf, string(data))
+	assert.ElementsMatch(t, []Route{lanRoute, defaultRoute, {Dst: "default", Dev: "ppp0"}}, ip.routes)
+
+	// The snapshot is used once
+	report, err = g.Restore(context.Background())
+	require.NoError(t, err)
+	assert.True(t, report.Empty())
+}
+
+func TestGuard_RecordGatewayRoutes(t *testing.T) {
+	ip := &fakeIP{routes: []Route{defaultRoute, lanRoute}}
+	g, resolv := newTestGuard(t, ip, "boot-1")
+	require.NoError(t, g.Save(context.Background(), nil))
+
+	// openfortivpn resolved the gateway itself and routed it over the uplink
+	ip.routes = append(ip.routes, gatewayRoute, Route{Dst: "10.0.0.1", Dev: "ppp0"})
+	require.NoError(t, g.RecordGatewayRoutes(context.Background()))
+	require.NoError(t, g.RecordGatewayRoutes(context.Background()))
+
+	s, err := g.read()
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("203.0.113.10")}, s.Gateways)
+
+	crash(t, ip, resolv)
+	report, err := g.Restore(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.10 via 192.0.2.1 dev lo"}, report.RemovedRoutes)
+}
+
+func TestGuard_RestoreNothingChanged(t *testing.T) {
+	ip := &fakeIP{routes: []Route{defaultRoute, lanRoute}}
+	g, _ := newTestGuard(t, ip, "boot-1")
+	require.NoError(t, g.Save(context.Background(), []netip.Addr{netip.MustParseAddr("203.0.113.10")}))
+	require.NoError(t, g.RecordNameServers([]netip.Addr{netip.MustParseAddr("10.0.0.53")}))
+
+	report, err := g.Restore(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, report.Empty())
+	for _, call := range ip.calls {
+		assert.NotContains(t, call, "add")
+		assert.NotContains(t, call, "del")
+	}
+}
+
+func TestGuard_KeepsResolvConfWithoutVPNNameServers(t *testing.T) {
+	ip := &fakeIP{routes: []Route{defaultRoute}}
+	g, resolv := newTestGuard(t, ip, "boot-1")
+	require.NoError(t, g.Save(context.Background(), nil))
+	require.NoError(t, g.RecordNameServers([]netip.Addr{netip.MustParseAddr("10.0.0.53")}))
+
+	// Another program changed the name servers meanwhile
+	require.NoError(t, os.WriteFile(resolv, []byte("nameserver 198.51.100.53\n"), 0o644))
+
+	report, err := g.Restore(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, report.ResolvConf)
+	data, err := os.ReadFile(resolv)
+	require.NoError(t, err)
+	assert.Equal(t, "nameserver 198.51.100.53\n", string(data))
+}
+
+func TestGuard_RestoreAfterReboot(t *testing.T) {
+	ip := &fakeIP{routes: []Route{defaultRoute, lanRoute}}
+	g, resolv := newTestGuard(t, ip, "boot-1")
+	require.NoError(t, g.Save(context.Background(), []netip.Addr{netip.MustParseAddr("203.0.113.10")}))
+	require.NoError(t, g.RecordNameServers([]netip.Addr{netip.MustParseAddr("10.0.0.53")}))
+	crash(t, ip, resolv)
+
+	report, err := reopen(g, ip, "boot-2").Restore(context.Background())
+
+	require.NoError(t, err)
+	// The resolver configuration survived the reboot, the routes did not
+	assert.True(t, report.ResolvConf)
+	assert.Empty(t, report.AddedRoutes)
+	assert.Empty(t, report.RemovedRoutes)
+	assert.Contains(t, ip.routes, gatewayRoute)
+}
+
+func TestGuard_SkipsRoutesOfMissingInterfaces(t *testing.T) {
+	wifi := Route{Dst: "default", Gateway: "198.51.100.1", Dev: "wlan-missing0", Metric: 600}
+	ip := &fakeIP{routes: []Route{defaultRoute, wifi}}
+	g, _ := newTestGuard(t, ip, "boot-1")
+	require.NoError(t, g.Save(context.Background(), nil))
+	ip.routes = nil
+
+	report, err := g.Restore(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default via 192.0.2.1 dev lo proto dhcp metric 100"}, report.AddedRoutes)
+}
+
+func TestGuard_SavesRoutesOutsideTunnel(t *testing.T) {
+	ip := &fakeIP{routes: []Route{defaultRoute, tunnelRoute}}
+	g, _ := newTestGuard(t, ip, "boot-1")
+
+	require.NoError(t, g.Save(context.Background(), nil))
+
+	s, err := g.read()
+	require.NoError(t, err)
+	assert.Equal(t, []Route{defaultRoute}, s.Routes)
+	assert.Equal(t, resolvConf, *s.ResolvConf)
+	info, err := os.Stat(g.path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestGuard_ResolvConfSymlink(t *testing.T) {
+	ip := &fakeIP{}
+	g, resolv := newTestGuard(t, ip, "boot-1")
+	target := filepath.Join(t.TempDir(), "stub-resolv.conf")
+	require.NoError(t, os.WriteFile(target, []byte(resolvConf), 0o644))
+	require.NoError(t, os.Remove(resolv))
+	require.NoError(t, os.Symlink(target, resolv))
+
+	require.NoError(t, g.Save(context.Background(), nil))
+	require.NoError(t, g.RecordNameServers([]netip.Addr{netip.MustParseAddr("10.0.0.53")}))
+	require.NoError(t, os.WriteFile(target, []byte("nameserver 10.0.0.53\n"), 0o644))
+
+	// The resolver service owns the file
+	report, err := g.Restore(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.ResolvConf)
+}
+
+func TestGuard_Discard(t *testing.T) {
+	ip := &fakeIP{routes: []Route{defaultRoute}}
+	g, _ := newTestGuard(t, ip, "boot-1")
+	require.NoError(t, g.Save(context.Background(), nil))
+
+	require.NoError(t, g.Discard())
+	require.NoError(t, g.Discard())
+
+	s, err := g.read()
+	require.NoError(t, err)
+	assert.Nil(t, s)
+	// Without a snapshot there is nothing to record
+	require.NoError(t, g.RecordNameServers([]netip.Addr{netip.MustParseAddr("10.0.0.53")}))
+	s, err = g.read()
+	require.NoError(t, err)
+	assert.Nil(t, s)
+}
+
+func TestNameServers(t *testing.T) {
+	dir := t.TempDir()
+	stub := filepath.Join(dir, "resolv.conf")
+	upstream := filepath.Join(dir, "upstream-resolv.conf")
+	require.NoError(t, os.WriteFile(stub, []byte("nameserver 127.0.0.53\nnameserver 192.0.2.53\n"), 0o644))
+	require.NoError(t, os.WriteFile(upstream, []byte("nameserver 192.0.2.53\nnameserver 2001:db8::53\n"), 0o644))
+
+	servers := NameServers(stub, upstream, filepath.Join(dir, "missing.conf"))
+
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("192.0.2.53"), netip.MustParseAddr("2001:db8::53")}, servers)
+}
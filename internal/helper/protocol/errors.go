@@ -18,4 +18,8 @@ const (
 	ErrCodeInternalError = "INTERNAL_ERROR"
 	// ErrCodeProfileInvalid indicates the profile configuration is invalid.
 	ErrCodeProfileInvalid = "PROFILE_INVALID"
+	// ErrCodeUnsupportedFeature indicates the installed openfortivpn is too old for the request.
+	ErrCodeUnsupportedFeature = "UNSUPPORTED_FEATURE"
+	// ErrCodeKillSwitchFailed indicates the kill switch could not be installed or removed.
+	ErrCodeKillSwitchFailed = "KILL_SWITCH_FAILED"
 )
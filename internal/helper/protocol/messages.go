@@ -31,6 +31,8 @@ const (
 	CommandDisconnect Command = "disconnect"
 	// CommandStatus queries the current VPN status.
 	CommandStatus Command = "status"
+	// CommandReleaseKillSwitch removes the kill switch while no tunnel is active.
+	CommandReleaseKillSwitch Command = "release_kill_switch"
 )
 
 // EventName identifies the type of event.
@@ -45,6 +47,9 @@ const (
 	EventVPN EventName = "vpn_event"
 	// EventError indicates an error occurred.
 	EventError EventName = "error"
+	// EventNetworkRestored reports the name servers and routes the helper
+	// restored after openfortivpn exited without cleaning up.
+	EventNetworkRestored EventName = "network_restored"
 )
 
 // Request represents a command sent from client to server.
@@ -121,11 +126,31 @@ type ConnectParams struct {
 	SetRoutes bool `json:"set_routes"`
 	// HalfInternetRoutes uses /1 routes instead of default route.
 	HalfInternetRoutes bool `json:"half_internet_routes"`
+	// NoFTMPush disables FortiToken Mobile push notifications.
+	NoFTMPush bool `json:"no_ftm_push,omitempty"`
+	// KillSwitch blocks traffic outside the tunnel while the profile is active (nil to allow it).
+	KillSwitch *KillSwitchParams `json:"kill_switch,omitempty"`
+}
+
+// KillSwitchParams contains the kill switch settings of a connect command.
+type KillSwitchParams struct {
+	// Gateways are all gateway endpoints of the profile in host:port form,
+	// so failing over to a backup gateway is not blocked.
+	Gateways []string `json:"gateways"`
+	// AllowedLANs are the address ranges in CIDR notation reachable outside the tunnel.
+	AllowedLANs []string `json:"allowed_lans,omitempty"`
 }
 
 // DisconnectParams contains parameters for the disconnect command.
+type DisconnectParams struct {
+	// KeepKillSwitch leaves the kill switch in place, such as when the tunnel
+	// is closed to reconnect it. It is removed otherwise.
+	KeepKillSwitch bool `json:"keep_kill_switch,omitempty"`
+}
+
+// ReleaseKillSwitchParams contains parameters for the release_kill_switch command.
 // Currently empty but defined for future extensibility.
-type DisconnectParams struct{}
+type ReleaseKillSwitchParams struct{}
 
 // StatusParams contains parameters for the status command.
 // Currently empty but defined for future extensibility.
@@ -139,6 +164,27 @@ type StatusResult struct {
 	AssignedIP string `json:"assigned_ip,omitempty"`
 	// ConnectedProfileID is the ID of the currently connected profile.
 	ConnectedProfileID string `json:"connected_profile_id,omitempty"`
+	// Phase is the most recent connection phase while connecting (empty otherwise).
+	Phase string `json:"phase,omitempty"`
+	// KillSwitch reports whether traffic outside the tunnel is blocked.
+	KillSwitch bool `json:"kill_switch,omitempty"`
+	// AlwaysOn describes the always-on system profile the helper keeps up (nil if none).
+	AlwaysOn *AlwaysOnStatus `json:"always_on,omitempty"`
+}
+
+// AlwaysOnStatus describes the always-on system profile. While it is defined,
+// clients cannot connect or disconnect.
+type AlwaysOnStatus struct {
+	// ProfileID is the ID of the always-on profile.
+	ProfileID string `json:"profile_id"`
+	// Name is the display name of the always-on profile.
+	Name string `json:"name"`
+	// Host is the primary gateway of the always-on profile.
+	Host string `json:"host"`
+	// Port is the port of the primary gateway.
+	Port int `json:"port"`
+	// ReconnectAttempt counts the attempts of the current reconnect sequence.
+	ReconnectAttempt int `json:"reconnect_attempt,omitempty"`
 }
 
 // StateChangeData contains data for state_change events.
@@ -147,6 +193,9 @@ type StateChangeData struct {
 	From string `json:"from"`
 	// To is the new state.
 	To string `json:"to"`
+	// AlwaysOn describes the always-on system profile the helper keeps up (nil if none),
+	// so clients follow it without requesting the status again.
+	AlwaysOn *AlwaysOnStatus `json:"always_on,omitempty"`
 }
 
 // OutputData contains data for output events.
@@ -169,6 +218,21 @@ type VPNEventData struct {
 type ErrorData struct {
 	// Message is the error description.
 	Message string `json:"message"`
+	// Code is the classified openfortivpn error category (e.g., auth_failed).
+	// Empty for errors that did not originate from openfortivpn output.
+	Code string `json:"code,omitempty"`
+}
+
+// NetworkRestoredData contains data for network_restored events.
+type NetworkRestoredData struct {
+	// ResolvConf reports whether /etc/resolv.conf was restored.
+	ResolvConf bool `json:"resolv_conf,omitempty"`
+	// AddedRoutes are the missing routes that were added again, in ip route syntax.
+	AddedRoutes []string `json:"added_routes,omitempty"`
+	// RemovedRoutes are the leftover routes that were deleted, in ip route syntax.
+	RemovedRoutes []string `json:"removed_routes,omitempty"`
+	// Error describes what could not be restored, if anything.
+	Error string `json:"error,omitempty"`
 }
 
 // NewRequest creates a new request with the given command and parameters.
@@ -125,6 +125,28 @@ func TestNewRequest_ParamsMarshaling(t *testing.T) {
 	assert.Equal(t, params.SetDNS, decoded.SetDNS)
 	assert.Equal(t, params.SetRoutes, decoded.SetRoutes)
 	assert.Equal(t, params.HalfInternetRoutes, decoded.HalfInternetRoutes)
+	assert.Nil(t, decoded.KillSwitch)
+}
+
+// TestKillSwitchParams_Marshaling tests the kill switch settings of connect and disconnect requests.
+func TestKillSwitchParams_Marshaling(t *testing.T) {
+	req, err := NewRequest("test-id", CommandConnect, ConnectParams{
+		ProfileID: "test-profile",
+		KillSwitch: &KillSwitchParams{
+			Gateways:    []string{"vpn.example.com:443", "[2001:db8::1]:10443"},
+			AllowedLANs: []string{"192.168.1.0/24"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(req.Params), `"kill_switch":{"gateways":["vpn.example.com:443","[2001:db8::1]:10443"],"allowed_lans":["192.168.1.0/24"]}`)
+
+	req, err = NewRequest("test-id", CommandDisconnect, DisconnectParams{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(req.Params))
+
+	req, err = NewRequest("test-id", CommandDisconnect, DisconnectParams{KeepKillSwitch: true})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"keep_kill_switch":true}`, string(req.Params))
 }
 
 // TestNewSuccessResponse tests the NewSuccessResponse constructor function.
@@ -411,6 +433,24 @@ func TestNewEvent_DataMarshaling(t *testing.T) {
 
 		assert.Equal(t, data.Message, decoded.Message)
 	})
+
+	t.Run("network restored data", func(t *testing.T) {
+		data := NetworkRestoredData{
+			ResolvConf:    true,
+			AddedRoutes:   []string{"default via 192.168.1.1 dev eth0 proto dhcp metric 100"},
+			RemovedRoutes: []string{"203.0.113.10 via 192.168.1.1 dev eth0"},
+		}
+
+		evt, err := NewEvent(EventNetworkRestored, data)
+		require.NoError(t, err)
+		assert.NotContains(t, string(evt.Data), "error")
+
+		var decoded NetworkRestoredData
+		err = json.Unmarshal(evt.Data, &decoded)
+		require.NoError(t, err)
+
+		assert.Equal(t, data, decoded)
+	})
 }
 
 // TestMessageTypes verifies the message type constants are correct.
@@ -425,6 +465,7 @@ func TestCommands(t *testing.T) {
 	assert.Equal(t, Command("connect"), CommandConnect)
 	assert.Equal(t, Command("disconnect"), CommandDisconnect)
 	assert.Equal(t, Command("status"), CommandStatus)
+	assert.Equal(t, Command("release_kill_switch"), CommandReleaseKillSwitch)
 }
 
 // TestEventNames verifies the event name constants are correct.
@@ -433,6 +474,7 @@ func TestEventNames(t *testing.T) {
 	assert.Equal(t, EventName("output"), EventOutput)
 	assert.Equal(t, EventName("vpn_event"), EventVPN)
 	assert.Equal(t, EventName("error"), EventError)
+	assert.Equal(t, EventName("network_restored"), EventNetworkRestored)
 }
 
 // TestRequest_JSONSerialization tests that requests can be serialized and deserialized.
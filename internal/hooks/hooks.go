@@ -0,0 +1,242 @@
+// Package hooks runs the commands a profile configures for points of a
+// connection's lifecycle, such as mounting network shares once the tunnel is up.
+//
+// Hooks run as the user running the application, never as root, through
+// /bin/sh so they can use pipes and &&. Details about the connection are
+// passed in OPENFORTIVPN_GUI_* environment variables.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// Event identifies the point of the connection lifecycle a hook runs at.
+type Event string
+
+const (
+	// PreConnect runs before connecting.
+	PreConnect Event = "pre-connect"
+	// PostConnect runs once the tunnel is up.
+	PostConnect Event = "post-connect"
+	// PreDisconnect runs before a disconnect the user asked for.
+	PreDisconnect Event = "pre-disconnect"
+	// PostDisconnect runs after a connection that was up has gone down.
+	PostDisconnect Event = "post-disconnect"
+)
+
+// Environment variables passed to hooks in addition to the application's environment.
+const (
+	EnvEvent       = "OPENFORTIVPN_GUI_HOOK"
+	EnvProfileID   = "OPENFORTIVPN_GUI_PROFILE_ID"
+	EnvProfileName = "OPENFORTIVPN_GUI_PROFILE_NAME"
+	EnvGateway     = "OPENFORTIVPN_GUI_GATEWAY"
+	EnvIP          = "OPENFORTIVPN_GUI_IP"
+	EnvInterface   = "OPENFORTIVPN_GUI_INTERFACE"
+	// EnvDNS holds the VPN name servers separated by spaces.
+	EnvDNS = "OPENFORTIVPN_GUI_DNS"
+)
+
+const (
+	defaultShell = "/bin/sh"
+
+	// outputWaitDelay is how long to keep reading output after a hook exits.
+	// Processes a hook leaves running in the background may hold its output open.
+	outputWaitDelay = 2 * time.Second
+)
+
+var (
+	// ErrHookFailed is returned when a hook exits with a non-zero status.
+	ErrHookFailed = errors.New("hook failed")
+	// ErrHookTimeout is returned when a hook runs longer than the profile's hook timeout.
+	ErrHookTimeout = errors.New("hook timed out")
+)
+
+// Connection describes the connection a hook runs for.
+// Fields that are not known yet, such as the IP before connecting, are empty.
+type Connection struct {
+	// Gateway is the host:port of the gateway in use.
+	Gateway    string
+	IP         string
+	Interface  string
+	DNSServers []string
+}
+
+// Option configures a Runner.
+type Option func(*Runner)
+
+// WithOutput sets the function receiving each line a hook writes to stdout or stderr.
+// Lines are prefixed with the hook's event.
+func WithOutput(output func(line string)) Option {
+	return func(r *Runner) {
+		r.output = output
+	}
+}
+
+// WithShell sets the shell used to run hook commands (primarily for testing).
+func WithShell(path string) Option {
+	return func(r *Runner) {
+		r.shell = path
+	}
+}
+
+// Runner runs profile hooks.
+type Runner struct {
+	shell  string
+	output func(line string)
+}
+
+// NewRunner creates a hook runner.
+func NewRunner(opts ...Option) *Runner {
+	r := &Runner{shell: defaultShell}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Command returns the profile's command for the event, or "" if none is set.
+func Command(p *profile.Profile, event Event) string {
+	h := p.Hooks
+	if h == nil {
+		return ""
+	}
+	switch event {
+	case PreConnect:
+		return h.PreConnect
+	case PostConnect:
+		return h.PostConnect
+	case PreDisconnect:
+		return h.PreDisconnect
+	case PostDisconnect:
+		return h.PostDisconnect
+	default:
+		return ""
+	}
+}
+
+// Run runs the profile's hook for the event and waits for it to finish.
+// It returns nil without doing anything if the profile has no hook for the event.
+// A hook running longer than the profile's hook timeout is killed along with
+// the processes it started and ErrHookTimeout is returned.
+func (r *Runner) Run(ctx context.Context, event Event, p *profile.Profile, conn Connection) error {
+	command := strings.TrimSpace(Command(p, event))
+	if command == "" {
+		return nil
+	}
+
+	timeout := p.Hooks.Timeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// #nosec G204 -- hook commands are configured by the user to run as themselves
+	cmd := exec.CommandContext(ctx, r.shell, "-c", command)
+	cmd.Env = append(os.Environ(), environment(event, p, conn)...)
+	// A process group lets the timeout kill everything the hook started
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = outputWaitDelay
+
+	out := &lineWriter{prefix: fmt.Sprintf("[%s] ", event), emit: r.emit}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	r.emit(fmt.Sprintf("[%s] Running hook", event))
+	err := cmd.Run()
+	out.flush()
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("%s %w after %s", event, ErrHookTimeout, timeout)
+	case ctx.Err() != nil:
+		err = fmt.Errorf("%s hook cancelled: %w", event, ctx.Err())
+	case errors.Is(err, exec.ErrWaitDelay):
+		// The hook succeeded but left a background process holding its output open
+		err = nil
+	case err != nil:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			err = fmt.Errorf("%s %w with exit status %d", event, ErrHookFailed, exitErr.ExitCode())
+		} else {
+			err = fmt.Errorf("failed to run %s hook: %w", event, err)
+		}
+	}
+
+	if err != nil {
+		r.emit(fmt.Sprintf("[%s] %v", event, err))
+	} else {
+		r.emit(fmt.Sprintf("[%s] Hook finished", event))
+	}
+	return err
+}
+
+// emit passes a line to the output function, if set.
+func (r *Runner) emit(line string) {
+	if r.output != nil {
+		r.output(line)
+	}
+}
+
+// environment returns the variables describing the connection to a hook.
+func environment(event Event, p *profile.Profile, conn Connection) []string {
+	return []string{
+		EnvEvent + "=" + string(event),
+		EnvProfileID + "=" + p.ID,
+		EnvProfileName + "=" + p.Name,
+		EnvGateway + "=" + conn.Gateway,
+		EnvIP + "=" + conn.IP,
+		EnvInterface + "=" + conn.Interface,
+		EnvDNS + "=" + strings.Join(conn.DNSServers, " "),
+	}
+}
+
+// lineWriter splits a hook's output into lines for the connection log.
+type lineWriter struct {
+	prefix string
+	emit   func(line string)
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write implements io.Writer.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+		w.emit(w.prefix + strings.TrimRight(line, "\r\n"))
+	}
+	return len(p), nil
+}
+
+// flush emits a final line that did not end with a newline.
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf.Len() > 0 {
+		w.emit(w.prefix + w.buf.String())
+		w.buf.Reset()
+	}
+}
@@ -0,0 +1,145 @@
+package hooks
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// outputRecorder collects the lines a runner emits.
+type outputRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *outputRecorder) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, line)
+}
+
+func (r *outputRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.lines...)
+}
+
+func newHookProfile(hooks *profile.Hooks) *profile.Profile {
+	p := profile.NewProfile("Office")
+	p.Hooks = hooks
+	return p
+}
+
+func TestRunner_Run_Environment(t *testing.T) {
+	rec := &outputRecorder{}
+	runner := NewRunner(WithOutput(rec.add))
+	p := newHookProfile(&profile.Hooks{
+		PostConnect: `echo "$OPENFORTIVPN_GUI_HOOK|$OPENFORTIVPN_GUI_PROFILE_NAME|$OPENFORTIVPN_GUI_IP|$OPENFORTIVPN_GUI_INTERFACE|$OPENFORTIVPN_GUI_DNS|$OPENFORTIVPN_GUI_GATEWAY"`,
+	})
+
+	err := runner.Run(context.Background(), PostConnect, p, Connection{
+		Gateway:    "vpn.example.com:443",
+		IP:         "10.0.0.100",
+		Interface:  "ppp0",
+		DNSServers: []string{"10.0.0.1", "10.0.0.2"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"[post-connect] Running hook",
+		"[post-connect] post-connect|Office|10.0.0.100|ppp0|10.0.0.1 10.0.0.2|vpn.example.com:443",
+		"[post-connect] Hook finished",
+	}, rec.get())
+}
+
+func TestRunner_Run_SelectsEventCommand(t *testing.T) {
+	p := newHookProfile(&profile.Hooks{
+		PreConnect:     "echo pre-connect",
+		PostConnect:    "echo post-connect",
+		PreDisconnect:  "echo pre-disconnect",
+		PostDisconnect: "echo post-disconnect",
+	})
+
+	for _, event := range []Event{PreConnect, PostConnect, PreDisconnect, PostDisconnect} {
+		t.Run(string(event), func(t *testing.T) {
+			rec := &outputRecorder{}
+			require.NoError(t, NewRunner(WithOutput(rec.add)).Run(context.Background(), event, p, Connection{}))
+			assert.Contains(t, rec.get(), "["+string(event)+"] "+string(event))
+		})
+	}
+}
+
+func TestRunner_Run_NoHook(t *testing.T) {
+	rec := &outputRecorder{}
+	runner := NewRunner(WithOutput(rec.add))
+
+	require.NoError(t, runner.Run(context.Background(), PostConnect, newHookProfile(nil), Connection{}))
+	require.NoError(t, runner.Run(context.Background(), PostConnect, newHookProfile(&profile.Hooks{PreConnect: "true", PostConnect: "  "}), Connection{}))
+	assert.Empty(t, rec.get())
+}
+
+func TestRunner_Run_CapturesStderrAndPartialLines(t *testing.T) {
+	rec := &outputRecorder{}
+	runner := NewRunner(WithOutput(rec.add))
+	p := newHookProfile(&profile.Hooks{PostDisconnect: `echo out; echo err >&2; printf partial`})
+
+	require.NoError(t, runner.Run(context.Background(), PostDisconnect, p, Connection{}))
+
+	lines := rec.get()
+	assert.Contains(t, lines, "[post-disconnect] out")
+	assert.Contains(t, lines, "[post-disconnect] err")
+	assert.Contains(t, lines, "[post-disconnect] partial")
+}
+
+func TestRunner_Run_Failure(t *testing.T) {
+	rec := &outputRecorder{}
+	runner := NewRunner(WithOutput(rec.add))
+	p := newHookProfile(&profile.Hooks{PreConnect: "exit 3"})
+
+	err := runner.Run(context.Background(), PreConnect, p, Connection{})
+	require.ErrorIs(t, err, ErrHookFailed)
+	assert.Contains(t, err.Error(), "exit status 3")
+	assert.Contains(t, rec.get(), "[pre-connect] "+err.Error())
+}
+
+func TestRunner_Run_Timeout(t *testing.T) {
+	p := newHookProfile(&profile.Hooks{PreDisconnect: "sleep 30 & sleep 30", TimeoutSeconds: 1})
+
+	start := time.Now()
+	err := NewRunner().Run(context.Background(), PreDisconnect, p, Connection{})
+	require.ErrorIs(t, err, ErrHookTimeout)
+	// The background sleep is killed along with the shell, so the output closes promptly
+	assert.Less(t, time.Since(start), 1*time.Second+outputWaitDelay)
+}
+
+func TestRunner_Run_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewRunner().Run(ctx, PostConnect, newHookProfile(&profile.Hooks{PostConnect: "sleep 30"}), Connection{})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrHookTimeout)
+}
+
+func TestRunner_Run_BackgroundProcessHoldingOutput(t *testing.T) {
+	rec := &outputRecorder{}
+	p := newHookProfile(&profile.Hooks{PostConnect: "echo started; sleep 10 &"})
+
+	err := NewRunner(WithOutput(rec.add)).Run(context.Background(), PostConnect, p, Connection{})
+	require.NoError(t, err)
+	assert.Contains(t, rec.get(), "[post-connect] started")
+}
+
+func TestRunner_Run_MissingShell(t *testing.T) {
+	runner := NewRunner(WithShell("/nonexistent/sh"))
+	err := runner.Run(context.Background(), PostConnect, newHookProfile(&profile.Hooks{PostConnect: "true"}), Connection{})
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "failed to run post-connect hook"))
+}
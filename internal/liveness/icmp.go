@@ -0,0 +1,118 @@
+package liveness
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ICMP echo message types (RFC 792 and RFC 4443).
+const (
+	icmpv4EchoRequest = 8
+	icmpv4EchoReply   = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// ErrPingNotPermitted is returned when the kernel does not allow unprivileged
+// ICMP echo sockets for this user (see net.ipv4.ping_group_range).
+var ErrPingNotPermitted = errors.New("unprivileged ping is not permitted, see net.ipv4.ping_group_range")
+
+// ICMPProber sends an echo request to a host and waits for the reply.
+// It uses unprivileged ICMP sockets, so it needs no capabilities.
+type ICMPProber struct {
+	Host string
+	seq  uint16
+}
+
+// NewICMPProber creates a prober for a host, checking that unprivileged ping is permitted.
+func NewICMPProber(host string) (*ICMPProber, error) {
+	fd, err := pingSocket(syscall.AF_INET)
+	if err != nil {
+		return nil, err
+	}
+	_ = syscall.Close(fd)
+	return &ICMPProber{Host: host}, nil
+}
+
+// pingSocket opens an unprivileged ICMP echo socket of the given address family.
+func pingSocket(family int) (int, error) {
+	proto := syscall.IPPROTO_ICMP
+	if family == syscall.AF_INET6 {
+		proto = syscall.IPPROTO_ICMPV6
+	}
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC, proto)
+	if errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EPERM) {
+		return -1, ErrPingNotPermitted
+	}
+	if err != nil {
+		return -1, fmt.Errorf("failed to open ICMP socket: %w", err)
+	}
+	return fd, nil
+}
+
+// Probe implements Prober. It is not safe for concurrent use.
+func (p *ICMPProber) Probe(ctx context.Context) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, p.Host)
+	if err != nil {
+		return err
+	}
+	ip := addrs[0].IP
+
+	family, request, reply := syscall.AF_INET, byte(icmpv4EchoRequest), byte(icmpv4EchoReply)
+	if ip.To4() == nil {
+		family, request, reply = syscall.AF_INET6, icmpv6EchoRequest, icmpv6EchoReply
+	}
+
+	fd, err := pingSocket(family)
+	if err != nil {
+		return err
+	}
+	// A non-blocking descriptor is handled by the runtime poller, so deadlines apply
+	file := os.NewFile(uintptr(fd), "icmp")
+	conn, err := net.FilePacketConn(file)
+	_ = file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to open ICMP socket: %w", err)
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(time.Minute)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	// The kernel fills in the identifier and checksum of echo sockets
+	p.seq++
+	msg := make([]byte, 8)
+	msg[0] = request
+	binary.BigEndian.PutUint16(msg[6:], p.seq)
+	if _, err := conn.WriteTo(msg, &net.UDPAddr{IP: ip}); err != nil {
+		return fmt.Errorf("failed to send echo request: %w", err)
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("no echo reply from %s: %w", p.Host, err)
+		}
+		// The kernel only delivers replies to this socket's identifier
+		if n >= 8 && buf[0] == reply && binary.BigEndian.Uint16(buf[6:]) == p.seq {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package liveness detects tunnels that stopped passing traffic.
+//
+// openfortivpn can keep running after the tunnel went dead, for example when
+// the gateway dropped the session without telling the client. A Checker
+// probes a host behind the VPN at an interval and reports the tunnel dead
+// once a number of probes in a row failed.
+package liveness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// ErrNoNameServer is returned for DNS probes when the VPN assigned no name server.
+var ErrNoNameServer = errors.New("the VPN assigned no name server to probe")
+
+// Prober checks once whether traffic gets through the tunnel.
+type Prober interface {
+	// Probe returns an error if no answer arrived before ctx is done.
+	Probe(ctx context.Context) error
+}
+
+// NewProber returns the prober for a profile's liveness probe.
+// nameServers are the name servers the VPN assigned, used by DNS probes.
+func NewProber(l *profile.LivenessProbe, nameServers []string) (Prober, error) {
+	target := strings.TrimSpace(l.Target)
+	switch l.Type {
+	case profile.ProbeTCP:
+		return &TCPProber{Address: target}, nil
+	case profile.ProbeICMP:
+		return NewICMPProber(target)
+	case profile.ProbeDNS:
+		if len(nameServers) == 0 {
+			return nil, ErrNoNameServer
+		}
+		return &DNSProber{Name: target, Server: net.JoinHostPort(nameServers[0], "53")}, nil
+	default:
+		return nil, fmt.Errorf("unknown liveness probe type %q", l.Type)
+	}
+}
+
+// TCPProber connects to a host:port. Only an accepted connection counts as an answer.
+type TCPProber struct {
+	Address string
+}
+
+// Probe implements Prober.
+func (p *TCPProber) Probe(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", p.Address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// DNSProber resolves a name through a specific name server. Any answer,
+// including one saying the name does not exist, proves the tunnel passes traffic.
+type DNSProber struct {
+	Name string
+	// Server is the host:port of the name server.
+	Server string
+}
+
+// Probe implements Prober.
+func (p *DNSProber) Probe(ctx context.Context) error {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, p.Server)
+		},
+	}
+
+	// A fully qualified name is not tried with each search domain in turn
+	name := p.Name
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	_, err := resolver.LookupHost(ctx, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && (dnsErr.IsNotFound || dnsErr.Err == "server misbehaving") {
+		return nil
+	}
+	return err
+}
+
+// Option configures a Checker.
+type Option func(*Checker)
+
+// WithInterval sets the time between probes.
+func WithInterval(d time.Duration) Option {
+	return func(c *Checker) {
+		c.interval = d
+	}
+}
+
+// WithTimeout sets how long a single probe may take.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Checker) {
+		c.timeout = d
+	}
+}
+
+// WithFailureThreshold sets how many probes in a row must fail before the tunnel is reported dead.
+func WithFailureThreshold(n int) Option {
+	return func(c *Checker) {
+		c.threshold = n
+	}
+}
+
+// Checker probes a tunnel at an interval and reports when it goes dead or comes back.
+// It is safe for concurrent use.
+type Checker struct {
+	prober    Prober
+	interval  time.Duration
+	timeout   time.Duration
+	threshold int
+
+	mu       sync.Mutex
+	onChange func(alive bool)
+}
+
+// NewChecker creates a checker using the given prober, with the profile package's
+// defaults unless options override them. Call Start to begin probing.
+func NewChecker(prober Prober, opts ...Option) *Checker {
+	c := &Checker{
+		prober:    prober,
+		interval:  profile.DefaultProbeInterval,
+		timeout:   profile.DefaultProbeTimeout,
+		threshold: profile.DefaultProbeFailures,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// OnChange registers a callback called with false once the failure threshold
+// is reached, and with true when a probe succeeds again afterwards.
+// It is called from a background goroutine.
+func (c *Checker) OnChange(callback func(alive bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = callback
+}
+
+// Start probes the tunnel every interval until ctx is cancelled.
+// The first probe runs one interval after starting.
+func (c *Checker) Start(ctx context.Context) {
+	go c.run(ctx)
+}
+
+// run probes until ctx is cancelled.
+func (c *Checker) run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := c.prober.Probe(probeCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			if failures >= c.threshold {
+				slog.Info("Liveness probe succeeded again, tunnel is alive")
+				c.report(true)
+			}
+			failures = 0
+			continue
+		}
+
+		failures++
+		slog.Debug("Liveness probe failed", "failures", failures, "threshold", c.threshold, "error", err)
+		if failures == c.threshold {
+			slog.Warn("Liveness probes failing, tunnel is not passing traffic", "failures", failures, "error", err)
+			c.report(false)
+		}
+	}
+}
+
+// report calls the change callback.
+func (c *Checker) report(alive bool) {
+	c.mu.Lock()
+	callback := c.onChange
+	c.mu.Unlock()
+
+	if callback != nil {
+		callback(alive)
+	}
+}
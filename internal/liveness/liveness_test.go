@@ -0,0 +1,203 @@
+package liveness
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+func TestTCPProber(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	prober := &TCPProber{Address: listener.Addr().String()}
+	assert.NoError(t, prober.Probe(ctx))
+
+	// Nothing listens once the listener is closed
+	require.NoError(t, listener.Close())
+	assert.Error(t, prober.Probe(ctx))
+}
+
+// serveDNS answers every query on a local UDP socket with the given response code,
+// or not at all if rcode is negative.
+func serveDNS(t *testing.T, rcode int) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if rcode < 0 || n < 12 {
+				continue
+			}
+			// Echo the query back as a response without answers
+			resp := append([]byte(nil), buf[:n]...)
+			resp[2] |= 0x80 // QR
+			resp[3] = 0x80 | byte(rcode)
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNSProber(t *testing.T) {
+	tests := []struct {
+		name    string
+		rcode   int
+		wantErr bool
+	}{
+		{name: "name does not exist", rcode: 3},
+		{name: "server failure", rcode: 2},
+		{name: "no answer", rcode: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prober := &DNSProber{Name: "intranet.corp.example", Server: serveDNS(t, tt.rcode)}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			err := prober.Probe(ctx)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestICMPProber(t *testing.T) {
+	prober, err := NewICMPProber("127.0.0.1")
+	if errors.Is(err, ErrPingNotPermitted) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, prober.Probe(ctx))
+	assert.NoError(t, prober.Probe(ctx), "each probe uses its own sequence number")
+}
+
+func TestNewProber(t *testing.T) {
+	prober, err := NewProber(&profile.LivenessProbe{Type: profile.ProbeTCP, Target: " git:22 "}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &TCPProber{Address: "git:22"}, prober)
+
+	prober, err = NewProber(&profile.LivenessProbe{Type: profile.ProbeDNS, Target: "intranet"}, []string{"10.0.0.53", "10.0.0.54"})
+	require.NoError(t, err)
+	assert.Equal(t, &DNSProber{Name: "intranet", Server: "10.0.0.53:53"}, prober)
+
+	_, err = NewProber(&profile.LivenessProbe{Type: profile.ProbeDNS, Target: "intranet"}, nil)
+	assert.ErrorIs(t, err, ErrNoNameServer)
+
+	_, err = NewProber(&profile.LivenessProbe{Type: "http", Target: "intranet"}, nil)
+	assert.Error(t, err)
+}
+
+// fakeProber returns queued results, then succeeds.
+type fakeProber struct {
+	mu      sync.Mutex
+	results []error
+}
+
+func (f *fakeProber) Probe(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.results) == 0 {
+		return nil
+	}
+	err := f.results[0]
+	f.results = f.results[1:]
+	return err
+}
+
+func TestChecker(t *testing.T) {
+	failed := errors.New("timeout")
+	prober := &fakeProber{results: []error{
+		failed, nil, // A single failure is forgiven
+		failed, failed, failed, failed, // Dead after three, reported once
+		nil, // Alive again
+	}}
+
+	changes := make(chan bool, 4)
+	c := NewChecker(prober, WithInterval(time.Millisecond), WithTimeout(time.Second), WithFailureThreshold(3))
+	c.OnChange(func(alive bool) { changes <- alive })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Start(ctx)
+
+	for _, want := range []bool{false, true} {
+		select {
+		case alive := <-changes:
+			assert.Equal(t, want, alive)
+		case <-time.After(time.Second):
+			t.Fatalf("change to alive=%v was not reported", want)
+		}
+	}
+
+	select {
+	case alive := <-changes:
+		t.Fatalf("unexpected change to alive=%v", alive)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// blockingProber blocks until its context is done.
+type blockingProber struct {
+	started chan struct{}
+}
+
+func (b *blockingProber) Probe(ctx context.Context) error {
+	b.started <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestChecker_StopsWhenCancelled(t *testing.T) {
+	prober := &blockingProber{started: make(chan struct{}, 1)}
+	c := NewChecker(prober, WithInterval(time.Millisecond), WithTimeout(time.Hour), WithFailureThreshold(1))
+
+	reported := make(chan bool, 1)
+	c.OnChange(func(alive bool) { reported <- alive })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Start(ctx)
+	<-prober.started
+
+	// Stopping the checker interrupts the probe without counting it as a failure
+	cancel()
+	select {
+	case alive := <-reported:
+		t.Fatalf("unexpected change to alive=%v", alive)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -0,0 +1,291 @@
+// Package logind follows systemd-logind's suspend and screen lock signals over
+// the system D-Bus, so a tunnel can be closed cleanly before the machine sleeps.
+//
+// Before suspending, logind waits for delay inhibitor locks to be released, for
+// at most its InhibitDelayMaxSec (5 seconds by default). The watcher holds such
+// a lock while awake and hands its release to the sleep handler.
+package logind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	busName          = "org.freedesktop.login1"
+	managerPath      = dbus.ObjectPath("/org/freedesktop/login1")
+	managerInterface = "org.freedesktop.login1.Manager"
+	sessionInterface = "org.freedesktop.login1.Session"
+	propsInterface   = "org.freedesktop.DBus.Properties"
+)
+
+// ErrNoSession is returned when the process does not belong to a logind session,
+// in which case there is no screen lock to follow.
+var ErrNoSession = errors.New("no logind session")
+
+// Handlers are called from a background goroutine when logind reports an event.
+// Any of them may be nil.
+type Handlers struct {
+	// BeforeSleep is called when the system is about to suspend or hibernate.
+	// Suspending waits until release is called or logind's delay runs out.
+	// release may be called more than once.
+	BeforeSleep func(release func())
+	// AfterResume is called once the system woke up again.
+	AfterResume func()
+	// Lock is called when the session's screen gets locked.
+	Lock func()
+	// Unlock is called when the session's screen gets unlocked.
+	Unlock func()
+}
+
+// Watcher follows logind's sleep and session lock signals.
+// It is safe for concurrent use.
+type Watcher struct {
+	mu          sync.Mutex
+	handlers    Handlers
+	inhibit     func() (func(), error) // Takes a delay lock on sleep and returns its release
+	sessionPath dbus.ObjectPath
+	release     func() // Releases the held inhibitor lock; nil when none is held
+	locked      bool
+}
+
+// NewWatcher creates a watcher. Call Start to begin watching.
+func NewWatcher() *Watcher {
+	return &Watcher{}
+}
+
+// SetHandlers sets the event handlers.
+func (w *Watcher) SetHandlers(h Handlers) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = h
+}
+
+// Start connects to the system bus and watches logind until ctx is cancelled.
+// It returns an error if the system bus is not reachable. Sleep is still followed when
+// the process has no session, which only disables the lock handlers.
+func (w *Watcher) Start(ctx context.Context) error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(managerPath),
+		dbus.WithMatchInterface(managerInterface),
+		dbus.WithMatchMember("PrepareForSleep"),
+	); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to subscribe to sleep signals: %w", err)
+	}
+
+	sessionPath, err := findSession(conn)
+	if err == nil {
+		err = w.watchSession(conn, sessionPath)
+	}
+	if err != nil {
+		slog.Warn("Screen lock is not followed", "error", err)
+	}
+
+	w.mu.Lock()
+	w.inhibit = func() (func(), error) { return inhibitSleep(conn) }
+	w.mu.Unlock()
+	w.takeInhibitor()
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	go func() {
+		<-ctx.Done()
+		w.releaseInhibitor()
+		_ = conn.Close()
+	}()
+	go func() {
+		// The channel is closed along with the connection
+		for sig := range signals {
+			w.handle(sig)
+		}
+	}()
+	return nil
+}
+
+// watchSession subscribes to the lock signals and lock hint of a session.
+// Desktops differ in which of them they use, so both are followed.
+func (w *Watcher) watchSession(conn *dbus.Conn, path dbus.ObjectPath) error {
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(sessionInterface),
+	); err != nil {
+		return fmt.Errorf("failed to subscribe to session signals: %w", err)
+	}
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(propsInterface),
+		dbus.WithMatchMember("PropertiesChanged"),
+		dbus.WithMatchArg(0, sessionInterface),
+	); err != nil {
+		return fmt.Errorf("failed to subscribe to session properties: %w", err)
+	}
+
+	var lockedHint bool
+	if v, err := conn.Object(busName, path).GetProperty(sessionInterface + ".LockedHint"); err == nil {
+		_ = v.Store(&lockedHint)
+	}
+
+	w.mu.Lock()
+	w.sessionPath = path
+	w.locked = lockedHint
+	w.mu.Unlock()
+	slog.Debug("Following logind session", "session", path, "locked", lockedHint)
+	return nil
+}
+
+// findSession returns the object path of the session this process runs in.
+func findSession(conn *dbus.Conn) (dbus.ObjectPath, error) {
+	manager := conn.Object(busName, managerPath)
+
+	var path dbus.ObjectPath
+	if id := os.Getenv("XDG_SESSION_ID"); id != "" {
+		if err := manager.Call(managerInterface+".GetSession", 0, id).Store(&path); err == nil {
+			return path, nil
+		}
+	}
+	// Processes started by the user's service manager are outside any session,
+	// so the user's graphical session is looked up as well
+	if err := manager.Call(managerInterface+".GetSessionByPID", 0, uint32(os.Getpid())).Store(&path); err == nil {
+		return path, nil
+	}
+	if err := manager.Call(managerInterface+".GetSession", 0, "auto").Store(&path); err == nil {
+		return path, nil
+	}
+	return "", ErrNoSession
+}
+
+// inhibitSleep takes a delay inhibitor lock on sleep, released by closing its descriptor.
+func inhibitSleep(conn *dbus.Conn) (func(), error) {
+	var fd dbus.UnixFD
+	err := conn.Object(busName, managerPath).Call(managerInterface+".Inhibit", 0,
+		"sleep", "OpenFortiVPN", "Disconnecting the VPN before suspending", "delay").Store(&fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take inhibitor lock: %w", err)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { _ = syscall.Close(int(fd)) })
+	}, nil
+}
+
+// takeInhibitor takes the sleep inhibitor lock unless one is already held.
+func (w *Watcher) takeInhibitor() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.release != nil || w.inhibit == nil {
+		return
+	}
+	release, err := w.inhibit()
+	if err != nil {
+		// Without the lock, suspending does not wait for the disconnect
+		slog.Warn("Failed to delay sleep", "error", err)
+		return
+	}
+	w.release = release
+}
+
+// releaseInhibitor releases the sleep inhibitor lock if one is held.
+func (w *Watcher) releaseInhibitor() {
+	w.mu.Lock()
+	release := w.release
+	w.release = nil
+	w.mu.Unlock()
+
+	if release != nil {
+		release()
+	}
+}
+
+// handle dispatches a logind signal to the handlers.
+func (w *Watcher) handle(sig *dbus.Signal) {
+	w.mu.Lock()
+	handlers := w.handlers
+	sessionPath := w.sessionPath
+	w.mu.Unlock()
+
+	switch {
+	case sig.Path == managerPath && sig.Name == managerInterface+".PrepareForSleep":
+		if len(sig.Body) < 1 {
+			return
+		}
+		if sleeping, ok := sig.Body[0].(bool); ok {
+			w.prepareForSleep(sleeping, handlers)
+		}
+	case sessionPath == "" || sig.Path != sessionPath:
+		return
+	case sig.Name == sessionInterface+".Lock":
+		w.setLocked(true, handlers)
+	case sig.Name == sessionInterface+".Unlock":
+		w.setLocked(false, handlers)
+	case sig.Name == propsInterface+".PropertiesChanged":
+		// Body: interface name, changed properties, invalidated properties
+		if len(sig.Body) < 2 {
+			return
+		}
+		changed, ok := sig.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			return
+		}
+		if v, ok := changed["LockedHint"]; ok {
+			if locked, ok := v.Value().(bool); ok {
+				w.setLocked(locked, handlers)
+			}
+		}
+	}
+}
+
+// prepareForSleep handles the start and end of a sleep.
+func (w *Watcher) prepareForSleep(sleeping bool, handlers Handlers) {
+	if !sleeping {
+		slog.Info("System resumed from sleep")
+		// The next sleep waits for the disconnect again
+		w.takeInhibitor()
+		if handlers.AfterResume != nil {
+			handlers.AfterResume()
+		}
+		return
+	}
+
+	slog.Info("System is about to sleep")
+	if handlers.BeforeSleep == nil {
+		w.releaseInhibitor()
+		return
+	}
+	handlers.BeforeSleep(w.releaseInhibitor)
+}
+
+// setLocked reports a change of the screen lock. Desktops sending both the
+// lock signal and the lock hint are reported once.
+func (w *Watcher) setLocked(locked bool, handlers Handlers) {
+	w.mu.Lock()
+	changed := w.locked != locked
+	w.locked = locked
+	w.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	slog.Debug("Session lock changed", "locked", locked)
+	switch {
+	case locked && handlers.Lock != nil:
+		handlers.Lock()
+	case !locked && handlers.Unlock != nil:
+		handlers.Unlock()
+	}
+}
@@ -0,0 +1,135 @@
+package logind
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSession = dbus.ObjectPath("/org/freedesktop/login1/session/_32")
+
+func sleepSignal(sleeping bool) *dbus.Signal {
+	return &dbus.Signal{Path: managerPath, Name: managerInterface + ".PrepareForSleep", Body: []any{sleeping}}
+}
+
+func sessionSignal(member string, body ...any) *dbus.Signal {
+	return &dbus.Signal{Path: testSession, Name: member, Body: body}
+}
+
+func lockedHint(locked bool) *dbus.Signal {
+	return sessionSignal(propsInterface+".PropertiesChanged",
+		sessionInterface, map[string]dbus.Variant{"LockedHint": dbus.MakeVariant(locked)}, []string{})
+}
+
+// fakeInhibitor counts inhibitor locks taken and released.
+type fakeInhibitor struct {
+	taken, released int
+}
+
+func (f *fakeInhibitor) inhibit() (func(), error) {
+	f.taken++
+	return func() { f.released++ }, nil
+}
+
+func newTestWatcher(inhibitor *fakeInhibitor) *Watcher {
+	w := NewWatcher()
+	w.inhibit = inhibitor.inhibit
+	w.sessionPath = testSession
+	w.takeInhibitor()
+	return w
+}
+
+func TestWatcher_Sleep(t *testing.T) {
+	inhibitor := &fakeInhibitor{}
+	w := newTestWatcher(inhibitor)
+
+	var release func()
+	resumed := 0
+	w.SetHandlers(Handlers{
+		BeforeSleep: func(r func()) { release = r },
+		AfterResume: func() { resumed++ },
+	})
+
+	w.handle(sleepSignal(true))
+	assert.NotNil(t, release)
+	assert.Equal(t, 0, inhibitor.released, "sleep should wait for the handler")
+
+	release()
+	release()
+	assert.Equal(t, 1, inhibitor.released)
+
+	w.handle(sleepSignal(false))
+	assert.Equal(t, 1, resumed)
+	assert.Equal(t, 2, inhibitor.taken, "the next sleep should be delayed again")
+}
+
+func TestWatcher_SleepWithoutHandler(t *testing.T) {
+	inhibitor := &fakeInhibitor{}
+	w := newTestWatcher(inhibitor)
+
+	w.handle(sleepSignal(true))
+	assert.Equal(t, 1, inhibitor.released, "nothing to wait for")
+}
+
+func TestWatcher_Lock(t *testing.T) {
+	w := newTestWatcher(&fakeInhibitor{})
+
+	var events []string
+	w.SetHandlers(Handlers{
+		Lock:   func() { events = append(events, "lock") },
+		Unlock: func() { events = append(events, "unlock") },
+	})
+
+	w.handle(sessionSignal(sessionInterface + ".Lock"))
+	// The lock hint following the lock signal is the same event
+	w.handle(lockedHint(true))
+	w.handle(lockedHint(false))
+	w.handle(sessionSignal(sessionInterface + ".Unlock"))
+	w.handle(lockedHint(true))
+
+	assert.Equal(t, []string{"lock", "unlock", "lock"}, events)
+}
+
+func TestWatcher_IgnoresOtherSessions(t *testing.T) {
+	w := newTestWatcher(&fakeInhibitor{})
+
+	locked := false
+	w.SetHandlers(Handlers{Lock: func() { locked = true }})
+
+	w.handle(&dbus.Signal{Path: "/org/freedesktop/login1/session/c2", Name: sessionInterface + ".Lock"})
+	assert.False(t, locked)
+
+	// Without a session, lock signals are not followed at all
+	w.sessionPath = ""
+	w.handle(&dbus.Signal{Path: "", Name: sessionInterface + ".Lock"})
+	assert.False(t, locked)
+}
+
+func TestWatcher_IgnoresMalformedSignals(t *testing.T) {
+	w := newTestWatcher(&fakeInhibitor{})
+
+	called := false
+	w.SetHandlers(Handlers{
+		BeforeSleep: func(func()) { called = true },
+		Lock:        func() { called = true },
+	})
+
+	w.handle(&dbus.Signal{Path: managerPath, Name: managerInterface + ".PrepareForSleep"})
+	w.handle(&dbus.Signal{Path: managerPath, Name: managerInterface + ".PrepareForSleep", Body: []any{"yes"}})
+	w.handle(sessionSignal(propsInterface+".PropertiesChanged", sessionInterface))
+	w.handle(sessionSignal(propsInterface+".PropertiesChanged",
+		sessionInterface, map[string]dbus.Variant{"Active": dbus.MakeVariant(true)}, []string{}))
+	assert.False(t, called)
+}
+
+func TestWatcher_Start(t *testing.T) {
+	w := NewWatcher()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := w.Start(ctx); err != nil {
+		t.Skipf("logind is not available: %v", err)
+	}
+}
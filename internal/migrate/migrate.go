@@ -0,0 +1,174 @@
+// Package migrate upgrades JSON files written by older versions of the application.
+//
+// Each file stores the schema version it was written with. On load, the migrations
+// newer than that version are applied in order, a one-time backup of the original file
+// is kept, and the file is rewritten atomically. Files written by a newer version are
+// rejected instead of being read with missing or misinterpreted settings.
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/shini4i/openfortivpn-gui/internal/fileutil"
+)
+
+// VersionKey is the JSON key holding a file's schema version.
+const VersionKey = "schema_version"
+
+var (
+	// ErrNewerVersion is returned for files written by a newer version of the application.
+	ErrNewerVersion = errors.New("file was written by a newer version of openfortivpn-gui")
+	// ErrInvalidVersion is returned when the stored schema version is not a non-negative integer.
+	ErrInvalidVersion = errors.New("invalid schema version")
+)
+
+// Document is a decoded JSON object. Numbers are kept as json.Number
+// so values pass through migrations unchanged.
+type Document map[string]any
+
+// SetDefault sets the key to value if the document does not contain it.
+func (d Document) SetDefault(key string, value any) {
+	if _, ok := d[key]; !ok {
+		d[key] = value
+	}
+}
+
+// Step upgrades a document by one schema version.
+type Step struct {
+	// Description explains the change for logs.
+	Description string
+	// Apply modifies the document in place.
+	Apply func(doc Document) error
+}
+
+// Pipeline is an ordered list of migration steps. Step i upgrades
+// from schema version i to i+1, so the current version is the number of steps.
+// Files without a stored version are treated as version 0.
+type Pipeline struct {
+	name  string
+	steps []Step
+}
+
+// NewPipeline creates a migration pipeline. The name identifies the kind of file in logs.
+func NewPipeline(name string, steps ...Step) *Pipeline {
+	return &Pipeline{name: name, steps: steps}
+}
+
+// Current returns the schema version written by this version of the application.
+func (p *Pipeline) Current() int {
+	return len(p.steps)
+}
+
+// Migrate upgrades JSON data to the current schema version.
+// It returns the migrated data, the version the data was stored with, and whether
+// any migration was applied. Up-to-date data is returned unchanged.
+func (p *Pipeline) Migrate(data []byte) ([]byte, int, bool, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, 0, false, fmt.Errorf("failed to unmarshal %s: %w", p.name, err)
+	}
+	if doc == nil {
+		return nil, 0, false, fmt.Errorf("failed to unmarshal %s: expected a JSON object", p.name)
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if version > p.Current() {
+		return nil, version, false, fmt.Errorf("%w: %s schema version %d is newer than supported version %d",
+			ErrNewerVersion, p.name, version, p.Current())
+	}
+	if version == p.Current() {
+		return data, version, false, nil
+	}
+
+	for v := version; v < p.Current(); v++ {
+		step := p.steps[v]
+		if err := step.Apply(doc); err != nil {
+			return nil, version, false, fmt.Errorf("failed to migrate %s to schema version %d (%s): %w",
+				p.name, v+1, step.Description, err)
+		}
+	}
+	doc[VersionKey] = p.Current()
+
+	migrated, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, version, false, fmt.Errorf("failed to marshal migrated %s: %w", p.name, err)
+	}
+	return migrated, version, true, nil
+}
+
+// MigrateFile upgrades the data read from path. If a migration was applied,
+// the original data is backed up next to the file (once per source version)
+// and the file is atomically rewritten with the given permissions.
+func (p *Pipeline) MigrateFile(path string, data []byte, perm os.FileMode) ([]byte, error) {
+	migrated, from, changed, err := p.Migrate(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if !changed {
+		return migrated, nil
+	}
+
+	backup := BackupPath(path, from)
+	if err := writeBackup(backup, data, perm); err != nil {
+		return nil, fmt.Errorf("failed to back up %s before migration: %w", path, err)
+	}
+	if err := fileutil.AtomicWrite(path, migrated, perm); err != nil {
+		return nil, fmt.Errorf("failed to write migrated %s: %w", p.name, err)
+	}
+
+	slog.Info("Migrated file to current schema version",
+		"kind", p.name, "path", path, "from", from, "to", p.Current(), "backup", backup)
+	return migrated, nil
+}
+
+// BackupPath returns the path of the backup kept when migrating a file from the given version.
+func BackupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+// writeBackup creates the backup file unless it already exists,
+// so the first pre-migration copy is never overwritten.
+func writeBackup(path string, data []byte, perm os.FileMode) error {
+	// #nosec G304 -- path is derived from the migrated file's path
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil
+		}
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// documentVersion returns the schema version stored in the document, or 0 if none is stored.
+func documentVersion(doc Document) (int, error) {
+	raw, ok := doc[VersionKey]
+	if !ok {
+		return 0, nil
+	}
+	number, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidVersion, raw)
+	}
+	version, err := number.Int64()
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidVersion, number)
+	}
+	return int(version), nil
+}
@@ -0,0 +1,198 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPipeline() *Pipeline {
+	return NewPipeline("test",
+		Step{Description: "add enabled", Apply: func(doc Document) error {
+			doc.SetDefault("enabled", true)
+			return nil
+		}},
+		Step{Description: "rename title to name", Apply: func(doc Document) error {
+			if title, ok := doc["title"]; ok {
+				doc["name"] = title
+				delete(doc, "title")
+			}
+			return nil
+		}},
+	)
+}
+
+func decode(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(data, &doc))
+	return doc
+}
+
+func TestPipeline_Migrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantFrom    int
+		wantChanged bool
+		want        map[string]any
+	}{
+		{
+			name:        "unversioned file runs all steps",
+			input:       `{"title": "office", "port": 10443}`,
+			wantFrom:    0,
+			wantChanged: true,
+			want:        map[string]any{"name": "office", "port": float64(10443), "enabled": true, VersionKey: float64(2)},
+		},
+		{
+			name:        "existing values are kept",
+			input:       `{"schema_version": 0, "enabled": false}`,
+			wantChanged: true,
+			want:        map[string]any{"enabled": false, VersionKey: float64(2)},
+		},
+		{
+			name:        "partially migrated file runs remaining steps",
+			input:       `{"schema_version": 1, "title": "office"}`,
+			wantFrom:    1,
+			wantChanged: true,
+			want:        map[string]any{"name": "office", VersionKey: float64(2)},
+		},
+		{
+			name:     "current file is unchanged",
+			input:    `{"schema_version": 2, "name": "office"}`,
+			wantFrom: 2,
+			want:     map[string]any{"name": "office", VersionKey: float64(2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, from, changed, err := testPipeline().Migrate([]byte(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFrom, from)
+			assert.Equal(t, tt.wantChanged, changed)
+			assert.Equal(t, tt.want, decode(t, out))
+		})
+	}
+}
+
+func TestPipeline_Migrate_PreservesLargeNumbers(t *testing.T) {
+	out, _, _, err := testPipeline().Migrate([]byte(`{"id": 9007199254740993}`))
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "9007199254740993")
+}
+
+func TestPipeline_Migrate_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{name: "newer version", input: `{"schema_version": 3}`, wantErr: ErrNewerVersion},
+		{name: "negative version", input: `{"schema_version": -1}`, wantErr: ErrInvalidVersion},
+		{name: "fractional version", input: `{"schema_version": 1.5}`, wantErr: ErrInvalidVersion},
+		{name: "string version", input: `{"schema_version": "2"}`, wantErr: ErrInvalidVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := testPipeline().Migrate([]byte(tt.input))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		_, _, _, err := testPipeline().Migrate([]byte(`not json`))
+		assert.Error(t, err)
+	})
+
+	t.Run("null document", func(t *testing.T) {
+		_, _, _, err := testPipeline().Migrate([]byte(`null`))
+		assert.ErrorContains(t, err, "expected a JSON object")
+	})
+
+	t.Run("failing step", func(t *testing.T) {
+		failing := NewPipeline("test", Step{Description: "fail", Apply: func(Document) error {
+			return errors.New("boom")
+		}})
+		_, _, _, err := failing.Migrate([]byte(`{}`))
+		assert.ErrorContains(t, err, "boom")
+	})
+}
+
+func TestPipeline_MigrateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	original := []byte(`{"title": "office"}`)
+	require.NoError(t, os.WriteFile(path, original, 0600))
+
+	out, err := testPipeline().MigrateFile(path, original, 0600)
+	require.NoError(t, err)
+
+	// The file is rewritten with the migrated data
+	onDisk, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, out, onDisk)
+	assert.Equal(t, float64(2), decode(t, onDisk)[VersionKey])
+
+	// The original is kept as a backup
+	backup := BackupPath(path, 0)
+	saved, err := os.ReadFile(backup)
+	require.NoError(t, err)
+	assert.Equal(t, original, saved)
+
+	info, err := os.Stat(backup)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestPipeline_MigrateFile_BackupIsOneTime(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	backup := BackupPath(path, 0)
+	require.NoError(t, os.WriteFile(backup, []byte("first"), 0600))
+
+	data := []byte(`{"title": "office"}`)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	_, err := testPipeline().MigrateFile(path, data, 0600)
+	require.NoError(t, err)
+
+	saved, err := os.ReadFile(backup)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(saved), "existing backup must not be overwritten")
+}
+
+func TestPipeline_MigrateFile_CurrentFileNotRewritten(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	data := []byte(`{"schema_version": 2}`)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	out, err := testPipeline().MigrateFile(path, data, 0600)
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no backup should be created")
+}
+
+func TestPipeline_MigrateFile_NewerVersionLeavesFileUntouched(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	data := []byte(`{"schema_version": 7}`)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	_, err := testPipeline().MigrateFile(path, data, 0600)
+	require.ErrorIs(t, err, ErrNewerVersion)
+	assert.Contains(t, err.Error(), path)
+
+	onDisk, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, data, onDisk)
+}
@@ -0,0 +1,347 @@
+// Package netmon watches the network uplink of the machine through rtnetlink,
+// so reconnecting can wait for a network and react when the uplink changes.
+//
+// The uplink is the interface carrying the preferred default route. Tunnel
+// interfaces such as the VPN's own ppp device are never considered an uplink.
+package netmon
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultDebounce is how long to wait for a burst of netlink notifications to
+// settle before reading the routing table again.
+const defaultDebounce = 500 * time.Millisecond
+
+// rtnetlink multicast groups (linux/rtnetlink.h), which package syscall does not define.
+const (
+	rtmgrpLink       = 0x1
+	rtmgrpIPv4IfAddr = 0x10
+	rtmgrpIPv4Route  = 0x40
+	rtmgrpIPv6IfAddr = 0x100
+	rtmgrpIPv6Route  = 0x400
+)
+
+// Uplink describes the interface carrying the preferred default route.
+// The zero value means there is no usable uplink.
+type Uplink struct {
+	Interface string
+	// Gateway is the next hop of the default route, empty for routes without one.
+	Gateway string
+}
+
+// Available reports whether there is a usable uplink.
+func (u Uplink) Available() bool {
+	return u.Interface != ""
+}
+
+// String implements fmt.Stringer.
+func (u Uplink) String() string {
+	switch {
+	case !u.Available():
+		return "none"
+	case u.Gateway == "":
+		return u.Interface
+	default:
+		return fmt.Sprintf("%s via %s", u.Interface, u.Gateway)
+	}
+}
+
+// Option configures a Monitor.
+type Option func(*Monitor)
+
+// WithDebounce sets how long to wait for notifications to settle before re-reading the uplink.
+func WithDebounce(d time.Duration) Option {
+	return func(m *Monitor) {
+		m.debounce = d
+	}
+}
+
+// withReader sets the function reading the current uplink (for testing).
+func withReader(read func() (Uplink, error)) Option {
+	return func(m *Monitor) {
+		m.read = read
+	}
+}
+
+// Monitor reports changes of the network uplink.
+// It is safe for concurrent use.
+type Monitor struct {
+	debounce time.Duration
+	read     func() (Uplink, error)
+
+	mu       sync.Mutex
+	current  Uplink
+	onChange func(old, new Uplink)
+	timer    *time.Timer
+}
+
+// NewMonitor creates a monitor. Call Start to begin watching.
+func NewMonitor(opts ...Option) *Monitor {
+	m := &Monitor{
+		debounce: defaultDebounce,
+		read:     ReadUplink,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// OnChange registers a callback for uplink changes.
+// It is called from a background goroutine.
+func (m *Monitor) OnChange(callback func(old, new Uplink)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = callback
+}
+
+// Current returns the uplink as of the last change.
+func (m *Monitor) Current() Uplink {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
+
+// Start reads the current uplink and watches link, address and route changes
+// until ctx is cancelled. It returns an error if netlink is not available.
+func (m *Monitor) Start(ctx context.Context) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("failed to open netlink socket: %w", err)
+	}
+
+	groups := uint32(rtmgrpLink | rtmgrpIPv4IfAddr | rtmgrpIPv4Route | rtmgrpIPv6IfAddr | rtmgrpIPv6Route)
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: groups}); err != nil {
+		_ = syscall.Close(fd)
+		return fmt.Errorf("failed to subscribe to netlink notifications: %w", err)
+	}
+
+	// A non-blocking descriptor is handled by the runtime poller, so Close unblocks Read
+	sock := os.NewFile(uintptr(fd), "netlink")
+
+	uplink, err := m.read()
+	if err != nil {
+		_ = sock.Close()
+		return err
+	}
+	m.mu.Lock()
+	m.current = uplink
+	m.mu.Unlock()
+	slog.Debug("Network uplink", "uplink", uplink)
+
+	go func() {
+		<-ctx.Done()
+		_ = sock.Close()
+	}()
+	go m.watch(sock)
+	return nil
+}
+
+// watch re-reads the uplink whenever the kernel reports a change.
+// The notifications themselves are not parsed: the routing table is the source of truth.
+func (m *Monitor) watch(sock *os.File) {
+	buf := make([]byte, os.Getpagesize())
+	for {
+		if _, err := sock.Read(buf); err != nil {
+			if errors.Is(err, os.ErrClosed) {
+				m.stop()
+				return
+			}
+			// ENOBUFS means notifications were dropped, which still calls for a re-read
+			if !errors.Is(err, syscall.ENOBUFS) {
+				slog.Warn("Failed to read netlink notification", "error", err)
+				m.stop()
+				return
+			}
+		}
+		m.notify()
+	}
+}
+
+// notify schedules a re-read of the uplink once notifications settle.
+func (m *Monitor) notify() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.timer != nil {
+		m.timer.Reset(m.debounce)
+		return
+	}
+	m.timer = time.AfterFunc(m.debounce, m.refresh)
+}
+
+// stop cancels a pending re-read.
+func (m *Monitor) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+}
+
+// Refresh re-reads the uplink right away and reports it if it changed.
+// It is meant for when the network may have changed without the monitor
+// noticing yet, such as after the system resumed from sleep.
+func (m *Monitor) Refresh() Uplink {
+	m.stop()
+	m.refresh()
+	return m.Current()
+}
+
+// refresh reads the uplink and reports it if it changed.
+func (m *Monitor) refresh() {
+	m.mu.Lock()
+	m.timer = nil
+	m.mu.Unlock()
+
+	uplink, err := m.read()
+	if err != nil {
+		slog.Warn("Failed to read network uplink", "error", err)
+		return
+	}
+
+	m.mu.Lock()
+	old := m.current
+	m.current = uplink
+	callback := m.onChange
+	m.mu.Unlock()
+
+	if uplink == old {
+		return
+	}
+	slog.Info("Network uplink changed", "old", old, "new", uplink)
+	if callback != nil {
+		callback(old, uplink)
+	}
+}
+
+// route is a default route from the main routing table.
+type route struct {
+	index    int
+	gateway  net.IP
+	priority uint32
+	ipv6     bool
+}
+
+// link is the part of an interface relevant to choosing the uplink.
+type link struct {
+	name  string
+	flags net.Flags
+}
+
+// ReadUplink reads the current uplink from the kernel.
+func ReadUplink() (Uplink, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return Uplink{}, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	links := make(map[int]link, len(interfaces))
+	for _, iface := range interfaces {
+		links[iface.Index] = link{name: iface.Name, flags: iface.Flags}
+	}
+
+	var routes []route
+	for _, family := range []int{syscall.AF_INET, syscall.AF_INET6} {
+		familyRoutes, err := readDefaultRoutes(family)
+		if err != nil {
+			return Uplink{}, err
+		}
+		routes = append(routes, familyRoutes...)
+	}
+
+	return selectUplink(routes, links), nil
+}
+
+// readDefaultRoutes dumps the default routes of an address family from the main table.
+func readDefaultRoutes(family int) ([]route, error) {
+	data, err := syscall.NetlinkRIB(syscall.RTM_GETROUTE, family)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routing table: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse routing table: %w", err)
+	}
+
+	var routes []route
+	for i := range msgs {
+		msg := &msgs[i]
+		if msg.Header.Type != syscall.RTM_NEWROUTE || len(msg.Data) < syscall.SizeofRtMsg {
+			continue
+		}
+		// struct rtmsg: family, dst_len, src_len, tos, table, protocol, scope, type, flags
+		dstLen, table, routeType := msg.Data[1], uint32(msg.Data[4]), msg.Data[7]
+		if dstLen != 0 || routeType != syscall.RTN_UNICAST {
+			continue
+		}
+
+		attrs, err := syscall.ParseNetlinkRouteAttr(msg)
+		if err != nil {
+			continue
+		}
+		r := route{ipv6: family == syscall.AF_INET6}
+		for _, attr := range attrs {
+			switch attr.Attr.Type {
+			case syscall.RTA_OIF:
+				if len(attr.Value) >= 4 {
+					r.index = int(binary.NativeEndian.Uint32(attr.Value))
+				}
+			case syscall.RTA_GATEWAY:
+				r.gateway = net.IP(slices.Clone(attr.Value))
+			case syscall.RTA_PRIORITY:
+				if len(attr.Value) >= 4 {
+					r.priority = binary.NativeEndian.Uint32(attr.Value)
+				}
+			case syscall.RTA_TABLE:
+				if len(attr.Value) >= 4 {
+					table = binary.NativeEndian.Uint32(attr.Value)
+				}
+			}
+		}
+		if table != syscall.RT_TABLE_MAIN || r.index == 0 {
+			continue
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+// selectUplink picks the default route the kernel prefers among those over a
+// usable interface: up, running, and neither loopback nor point-to-point.
+// Routes with a lower metric win; on a tie IPv4 is preferred.
+func selectUplink(routes []route, links map[int]link) Uplink {
+	var best *route
+	for i := range routes {
+		r := &routes[i]
+		l, ok := links[r.index]
+		if !ok || l.flags&net.FlagUp == 0 || l.flags&net.FlagRunning == 0 ||
+			l.flags&(net.FlagLoopback|net.FlagPointToPoint) != 0 {
+			continue
+		}
+		if best == nil || r.priority < best.priority || (r.priority == best.priority && best.ipv6 && !r.ipv6) {
+			best = r
+		}
+	}
+	if best == nil {
+		return Uplink{}
+	}
+
+	uplink := Uplink{Interface: links[best.index].name}
+	if len(best.gateway) > 0 {
+		uplink.Gateway = best.gateway.String()
+	}
+	return uplink
+}
@@ -0,0 +1,153 @@
+package netmon
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const usable = net.FlagUp | net.FlagRunning | net.FlagBroadcast
+
+func TestSelectUplink(t *testing.T) {
+	links := map[int]link{
+		1: {name: "lo", flags: net.FlagUp | net.FlagRunning | net.FlagLoopback},
+		2: {name: "wlan0", flags: usable},
+		3: {name: "eth0", flags: usable},
+		4: {name: "ppp0", flags: net.FlagUp | net.FlagRunning | net.FlagPointToPoint},
+		5: {name: "eth1", flags: net.FlagUp | net.FlagBroadcast}, // No carrier
+	}
+	wifi := route{index: 2, gateway: net.ParseIP("192.168.1.1"), priority: 600}
+	ethernet := route{index: 3, gateway: net.ParseIP("10.1.0.1"), priority: 100}
+
+	tests := []struct {
+		name   string
+		routes []route
+		want   Uplink
+	}{
+		{name: "no default route", routes: nil, want: Uplink{}},
+		{name: "wifi", routes: []route{wifi}, want: Uplink{Interface: "wlan0", Gateway: "192.168.1.1"}},
+		{name: "lower metric wins", routes: []route{wifi, ethernet}, want: Uplink{Interface: "eth0", Gateway: "10.1.0.1"}},
+		{name: "tunnel is not an uplink", routes: []route{{index: 4, priority: 0}, wifi}, want: Uplink{Interface: "wlan0", Gateway: "192.168.1.1"}},
+		{name: "only tunnel", routes: []route{{index: 4}}, want: Uplink{}},
+		{name: "interface without carrier", routes: []route{{index: 5, gateway: net.ParseIP("10.2.0.1")}}, want: Uplink{}},
+		{name: "unknown interface", routes: []route{{index: 42}}, want: Uplink{}},
+		{name: "IPv4 preferred on a tie", routes: []route{
+			{index: 3, gateway: net.ParseIP("fe80::1"), priority: 100, ipv6: true},
+			ethernet,
+		}, want: Uplink{Interface: "eth0", Gateway: "10.1.0.1"}},
+		{name: "IPv6 only", routes: []route{{index: 2, gateway: net.ParseIP("fe80::1"), priority: 600, ipv6: true}}, want: Uplink{Interface: "wlan0", Gateway: "fe80::1"}},
+		{name: "no gateway", routes: []route{{index: 3}}, want: Uplink{Interface: "eth0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, selectUplink(tt.routes, links))
+		})
+	}
+}
+
+func TestUplink_String(t *testing.T) {
+	assert.Equal(t, "none", Uplink{}.String())
+	assert.Equal(t, "eth0", Uplink{Interface: "eth0"}.String())
+	assert.Equal(t, "wlan0 via 192.168.1.1", Uplink{Interface: "wlan0", Gateway: "192.168.1.1"}.String())
+}
+
+// fakeUplink is a settable uplink reader.
+type fakeUplink struct {
+	mu     sync.Mutex
+	uplink Uplink
+	reads  int
+}
+
+func (f *fakeUplink) set(u Uplink) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.uplink = u
+}
+
+func (f *fakeUplink) read() (Uplink, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads++
+	return f.uplink, nil
+}
+
+func (f *fakeUplink) readCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads
+}
+
+func TestMonitor_ReportsChanges(t *testing.T) {
+	fake := &fakeUplink{uplink: Uplink{Interface: "wlan0", Gateway: "192.168.1.1"}}
+	m := NewMonitor(WithDebounce(10*time.Millisecond), withReader(fake.read))
+	m.current = fake.uplink
+
+	changes := make(chan [2]Uplink, 4)
+	m.OnChange(func(old, new Uplink) { changes <- [2]Uplink{old, new} })
+
+	// A change of the underlying network is reported once notifications settle
+	fake.set(Uplink{Interface: "eth0", Gateway: "10.1.0.1"})
+	m.notify()
+	m.notify()
+	m.notify()
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, "wlan0", change[0].Interface)
+		assert.Equal(t, "eth0", change[1].Interface)
+	case <-time.After(time.Second):
+		t.Fatal("change was not reported")
+	}
+	assert.Equal(t, 1, fake.readCount(), "a burst of notifications should cause a single read")
+	assert.Equal(t, "eth0", m.Current().Interface)
+
+	// Notifications that leave the uplink unchanged are not reported
+	m.notify()
+	assert.Eventually(t, func() bool { return fake.readCount() == 2 }, time.Second, 5*time.Millisecond)
+	select {
+	case change := <-changes:
+		t.Fatalf("unexpected change %v", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMonitor_Start(t *testing.T) {
+	fake := &fakeUplink{uplink: Uplink{Interface: "eth0"}}
+	m := NewMonitor(withReader(fake.read))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Start(ctx); err != nil {
+		t.Skipf("netlink is not available: %v", err)
+	}
+	assert.Equal(t, Uplink{Interface: "eth0"}, m.Current())
+}
+
+func TestReadUplink(t *testing.T) {
+	uplink, err := ReadUplink()
+	if err != nil {
+		t.Skipf("routing table is not readable: %v", err)
+	}
+	// Whatever the machine's network, tunnels and loopback are never the uplink
+	require.NotEqual(t, "lo", uplink.Interface)
+}
+
+func TestMonitor_Refresh(t *testing.T) {
+	fake := &fakeUplink{uplink: Uplink{Interface: "wlan0"}}
+	m := NewMonitor(WithDebounce(time.Hour), withReader(fake.read))
+
+	var changes []Uplink
+	m.OnChange(func(_, new Uplink) { changes = append(changes, new) })
+
+	// A pending re-read is replaced by the immediate one
+	m.notify()
+	assert.Equal(t, Uplink{Interface: "wlan0"}, m.Refresh())
+	assert.Equal(t, []Uplink{{Interface: "wlan0"}}, changes)
+	assert.Nil(t, m.timer)
+}
@@ -0,0 +1,303 @@
+package netrules
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/shini4i/openfortivpn-gui/internal/netmon"
+)
+
+const (
+	nmBusName              = "org.freedesktop.NetworkManager"
+	nmPath                 = dbus.ObjectPath("/org/freedesktop/NetworkManager")
+	nmInterface            = "org.freedesktop.NetworkManager"
+	nmDeviceInterface      = nmInterface + ".Device"
+	nmWirelessInterface    = nmInterface + ".Device.Wireless"
+	nmAccessPointInterface = nmInterface + ".AccessPoint"
+	nmIP4ConfigInterface   = nmInterface + ".IP4Config"
+	nmIP6ConfigInterface   = nmInterface + ".IP6Config"
+	propsGet               = "org.freedesktop.DBus.Properties.Get"
+
+	// reachTimeout bounds a TCP connection attempt of a reachable rule.
+	reachTimeout = 3 * time.Second
+	// gatewayResolveTimeout bounds waiting for the gateway's MAC address to be resolved.
+	gatewayResolveTimeout = time.Second
+	// discardPort is the port of the datagram sent to make the kernel resolve the gateway.
+	discardPort = 9
+)
+
+// Neighbor table constants (linux/neighbour.h), which package syscall does not define.
+const (
+	sizeofNdMsg   = 12
+	ndaDst        = 1
+	ndaLLAddr     = 2
+	nudIncomplete = 0x01
+	nudFailed     = 0x20
+)
+
+// Detect describes the network behind the uplink. Facts that cannot be read are
+// left empty, so rules depending on them do not match. The SSID and search domains
+// are read from NetworkManager and stay empty on systems without it.
+func Detect(ctx context.Context, uplink netmon.Uplink) Network {
+	n := Network{Uplink: uplink}
+	if !uplink.Available() {
+		return n
+	}
+	n.Reachable = reachableOver(uplink.Interface)
+
+	if uplink.Gateway != "" {
+		mac, err := gatewayMAC(ctx, uplink)
+		if err != nil {
+			slog.Debug("Failed to read gateway MAC address", "uplink", uplink, "error", err)
+		}
+		n.GatewayMAC = mac
+	}
+	if err := readNetworkManager(ctx, &n); err != nil {
+		slog.Debug("Failed to read network details from NetworkManager", "interface", uplink.Interface, "error", err)
+	}
+
+	slog.Debug("Detected network", "uplink", uplink, "gateway_mac", n.GatewayMAC, "ssid", n.SSID, "search_domains", n.SearchDomains)
+	return n
+}
+
+// reachableOver returns a function connecting to host:port through the interface
+// only, so a host behind the VPN does not count as reachable through the tunnel.
+func reachableOver(iface string) func(ctx context.Context, address string) bool {
+	return func(ctx context.Context, address string) bool {
+		ctx, cancel := context.WithTimeout(ctx, reachTimeout)
+		defer cancel()
+
+		d := net.Dialer{Control: func(_, _ string, c syscall.RawConn) error {
+			var bindErr error
+			if err := c.Control(func(fd uintptr) {
+				bindErr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface)
+			}); err != nil {
+				return err
+			}
+			return bindErr
+		}}
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err != nil {
+			slog.Debug("Host not reachable over the uplink", "address", address, "interface", iface, "error", err)
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}
+}
+
+// neighbor is an entry of the kernel's neighbor (ARP and NDP) table.
+type neighbor struct {
+	index int
+	ip    net.IP
+	mac   net.HardwareAddr
+}
+
+// gatewayMAC returns the MAC address of the uplink's gateway. If the kernel has
+// not resolved it yet, a datagram is sent to the gateway and the table is read
+// again until the address appears.
+func gatewayMAC(ctx context.Context, uplink netmon.Uplink) (string, error) {
+	ip := net.ParseIP(uplink.Gateway)
+	if ip == nil {
+		return "", fmt.Errorf("invalid gateway address %q", uplink.Gateway)
+	}
+	iface, err := net.InterfaceByName(uplink.Interface)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gatewayResolveTimeout)
+	defer cancel()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	poked := false
+	for {
+		neighbors, err := readNeighbors(ip)
+		if err != nil {
+			return "", err
+		}
+		if mac := findNeighbor(neighbors, ip, iface.Index); mac != nil {
+			return mac.String(), nil
+		}
+		if !poked {
+			poke(ip, iface.Name)
+			poked = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("gateway %s was not resolved: %w", ip, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// poke sends a datagram to ip, which makes the kernel resolve its MAC address.
+// Nothing needs to listen there.
+func poke(ip net.IP, iface string) {
+	addr := &net.UDPAddr{IP: ip, Port: discardPort}
+	if ip.IsLinkLocalUnicast() {
+		addr.Zone = iface
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte{0})
+}
+
+// readNeighbors dumps the neighbor table of ip's address family.
+func readNeighbors(ip net.IP) ([]neighbor, error) {
+	family := syscall.AF_INET
+	if ip.To4() == nil {
+		family = syscall.AF_INET6
+	}
+	data, err := syscall.NetlinkRIB(syscall.RTM_GETNEIGH, family)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read neighbor table: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse neighbor table: %w", err)
+	}
+
+	var neighbors []neighbor
+	for _, msg := range msgs {
+		if msg.Header.Type != syscall.RTM_NEWNEIGH {
+			continue
+		}
+		if n, ok := parseNeighbor(msg.Data); ok {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors, nil
+}
+
+// parseNeighbor parses a neighbor message: a struct ndmsg followed by attributes.
+// Entries whose address is unresolved are skipped.
+func parseNeighbor(data []byte) (neighbor, bool) {
+	if len(data) < sizeofNdMsg {
+		return neighbor{}, false
+	}
+	// struct ndmsg: family, pad1, pad2, ifindex, state, flags, type
+	n := neighbor{index: int(int32(binary.NativeEndian.Uint32(data[4:8])))}
+	state := binary.NativeEndian.Uint16(data[8:10])
+	if state&(nudIncomplete|nudFailed) != 0 {
+		return neighbor{}, false
+	}
+
+	// Package syscall only parses the attributes of link, address and route messages
+	attrs := data[sizeofNdMsg:]
+	for len(attrs) >= syscall.SizeofRtAttr {
+		length := int(binary.NativeEndian.Uint16(attrs[0:2]))
+		attrType := binary.NativeEndian.Uint16(attrs[2:4])
+		if length < syscall.SizeofRtAttr || length > len(attrs) {
+			break
+		}
+		value := attrs[syscall.SizeofRtAttr:length]
+		switch attrType {
+		case ndaDst:
+			n.ip = net.IP(slices.Clone(value))
+		case ndaLLAddr:
+			n.mac = net.HardwareAddr(slices.Clone(value))
+		}
+
+		aligned := (length + syscall.NLMSG_ALIGNTO - 1) &^ (syscall.NLMSG_ALIGNTO - 1)
+		if aligned > len(attrs) {
+			break
+		}
+		attrs = attrs[aligned:]
+	}
+
+	if n.ip == nil || len(n.mac) == 0 {
+		return neighbor{}, false
+	}
+	return n, true
+}
+
+// findNeighbor returns the MAC address of ip on the interface, or nil if it is not resolved.
+func findNeighbor(neighbors []neighbor, ip net.IP, index int) net.HardwareAddr {
+	for _, n := range neighbors {
+		if n.index == index && n.ip.Equal(ip) {
+			return n.mac
+		}
+	}
+	return nil
+}
+
+// readNetworkManager fills in the SSID and search domains NetworkManager knows
+// for the uplink's device.
+func readNetworkManager(ctx context.Context, n *Network) error {
+	conn, err := dbus.ConnectSystemBus(dbus.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+	defer conn.Close()
+
+	var devicePath dbus.ObjectPath
+	err = conn.Object(nmBusName, nmPath).
+		CallWithContext(ctx, nmInterface+".GetDeviceByIpIface", 0, n.Uplink.Interface).
+		Store(&devicePath)
+	if err != nil {
+		return fmt.Errorf("no NetworkManager device for %s: %w", n.Uplink.Interface, err)
+	}
+	device := conn.Object(nmBusName, devicePath)
+
+	// Wired devices have no access point
+	var apPath dbus.ObjectPath
+	if getProperty(ctx, device, nmWirelessInterface, "ActiveAccessPoint", &apPath) == nil && apPath != "/" {
+		var ssid []byte
+		if err := getProperty(ctx, conn.Object(nmBusName, apPath), nmAccessPointInterface, "Ssid", &ssid); err == nil {
+			n.SSID = string(ssid)
+		}
+	}
+
+	var errs []error
+	for _, ipConfig := range []struct{ property, iface string }{
+		{"Ip4Config", nmIP4ConfigInterface},
+		{"Ip6Config", nmIP6ConfigInterface},
+	} {
+		var configPath dbus.ObjectPath
+		if err := getProperty(ctx, device, nmDeviceInterface, ipConfig.property, &configPath); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if configPath == "/" {
+			continue
+		}
+		// Domains come from DHCP or router advertisements, Searches from the connection settings
+		for _, property := range []string{"Domains", "Searches"} {
+			var domains []string
+			if err := getProperty(ctx, conn.Object(nmBusName, configPath), ipConfig.iface, property, &domains); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, domain := range domains {
+				if !slices.Contains(n.SearchDomains, domain) {
+					n.SearchDomains = append(n.SearchDomains, domain)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// getProperty reads a D-Bus property into v.
+func getProperty(ctx context.Context, obj dbus.BusObject, iface, property string, v any) error {
+	var variant dbus.Variant
+	if err := obj.CallWithContext(ctx, propsGet, 0, iface, property).Store(&variant); err != nil {
+		return fmt.Errorf("failed to read %s.%s: %w", iface, property, err)
+	}
+	return variant.Store(v)
+}
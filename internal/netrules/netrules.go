@@ -0,0 +1,185 @@
+// Package netrules decides what to do with the VPN on the network the machine
+// is on, such as staying disconnected on the office LAN and connecting a
+// profile on any other network.
+//
+// Rules are checked in order and the first one matching the network applies.
+// Networks are recognized by facts the VPN itself cannot change: the MAC address
+// of the default gateway, the Wi-Fi SSID, the search domains of the uplink, and
+// whether a host answers when reached directly over the uplink.
+package netrules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/shini4i/openfortivpn-gui/internal/netmon"
+)
+
+// MatchType is the fact about the network a rule checks.
+type MatchType string
+
+const (
+	// MatchGatewayMAC matches the MAC address of the default gateway.
+	MatchGatewayMAC MatchType = "gateway_mac"
+	// MatchSSID matches the SSID of the Wi-Fi network the uplink is connected to.
+	MatchSSID MatchType = "ssid"
+	// MatchSearchDomain matches a DNS search domain assigned to the uplink.
+	MatchSearchDomain MatchType = "search_domain"
+	// MatchReachable matches when a TCP connection to host:port over the uplink succeeds.
+	MatchReachable MatchType = "reachable"
+	// MatchAny matches any network. Placed last, it applies to every network no
+	// other rule recognized, that is every untrusted network.
+	MatchAny MatchType = "any"
+)
+
+// Action is what to do with the VPN on a matching network.
+type Action string
+
+const (
+	// ActionConnect connects the rule's profile unless a connection is already active.
+	ActionConnect Action = "connect"
+	// ActionDisconnect disconnects and does not reconnect until the network changes again.
+	ActionDisconnect Action = "disconnect"
+)
+
+// ErrInvalidRule is returned when a rule is incomplete or malformed.
+var ErrInvalidRule = errors.New("invalid network rule")
+
+// Rule maps a network to an action.
+type Rule struct {
+	// Name describes the network, such as "Office LAN".
+	Name  string    `json:"name,omitempty"`
+	Match MatchType `json:"match"`
+	// Value is the MAC address, SSID, search domain or host:port to match.
+	// It is empty for MatchAny.
+	Value  string `json:"value,omitempty"`
+	Action Action `json:"action"`
+	// ProfileID is the profile to connect for ActionConnect.
+	ProfileID string `json:"profile_id,omitempty"`
+}
+
+// String implements fmt.Stringer.
+func (r Rule) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Match == MatchAny {
+		return "any network"
+	}
+	return fmt.Sprintf("%s %s", r.Match, r.Value)
+}
+
+// Validate checks that the rule is complete.
+func (r Rule) Validate() error {
+	value := strings.TrimSpace(r.Value)
+	switch r.Match {
+	case MatchGatewayMAC:
+		if _, err := net.ParseMAC(value); err != nil {
+			return fmt.Errorf("%w: gateway MAC address %q is not valid", ErrInvalidRule, r.Value)
+		}
+	case MatchSSID, MatchSearchDomain:
+		if value == "" {
+			return fmt.Errorf("%w: %s must not be empty", ErrInvalidRule, r.Match)
+		}
+	case MatchReachable:
+		host, port, err := net.SplitHostPort(value)
+		if err != nil || host == "" || port == "" {
+			return fmt.Errorf("%w: reachable host must be host:port, got %q", ErrInvalidRule, r.Value)
+		}
+	case MatchAny:
+	default:
+		return fmt.Errorf("%w: unknown match %q", ErrInvalidRule, r.Match)
+	}
+
+	switch r.Action {
+	case ActionConnect:
+		if r.ProfileID == "" {
+			return fmt.Errorf("%w: connect rule needs a profile", ErrInvalidRule)
+		}
+	case ActionDisconnect:
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidRule, r.Action)
+	}
+	return nil
+}
+
+// Validate checks every rule in a list.
+func Validate(rules []Rule) error {
+	for i, r := range rules {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i+1, r, err)
+		}
+	}
+	return nil
+}
+
+// Network describes the network the machine is connected to.
+type Network struct {
+	Uplink netmon.Uplink
+	// GatewayMAC is the MAC address of the default gateway, empty if unknown.
+	GatewayMAC string
+	// SSID is the Wi-Fi network of the uplink, empty for wired uplinks or if unknown.
+	SSID string
+	// SearchDomains are the DNS search domains assigned to the uplink.
+	SearchDomains []string
+	// Reachable reports whether a TCP connection to host:port over the uplink succeeds.
+	// Nil means no host is reachable.
+	Reachable func(ctx context.Context, address string) bool
+}
+
+// Select returns the first rule matching the network, or nil if none does.
+// No rule matches while there is no uplink.
+func Select(ctx context.Context, rules []Rule, n Network) *Rule {
+	if !n.Uplink.Available() {
+		return nil
+	}
+	for i := range rules {
+		if rules[i].matches(ctx, n) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule matches the network.
+func (r *Rule) matches(ctx context.Context, n Network) bool {
+	value := strings.TrimSpace(r.Value)
+	switch r.Match {
+	case MatchGatewayMAC:
+		return sameMAC(value, n.GatewayMAC)
+	case MatchSSID:
+		return value == n.SSID
+	case MatchSearchDomain:
+		return slices.ContainsFunc(n.SearchDomains, func(domain string) bool {
+			return normalizeDomain(domain) == normalizeDomain(value)
+		})
+	case MatchReachable:
+		return n.Reachable != nil && n.Reachable(ctx, value)
+	case MatchAny:
+		return true
+	default:
+		return false
+	}
+}
+
+// sameMAC reports whether two MAC addresses are equal, whatever their notation.
+func sameMAC(a, b string) bool {
+	macA, err := net.ParseMAC(a)
+	if err != nil {
+		return false
+	}
+	macB, err := net.ParseMAC(b)
+	if err != nil {
+		return false
+	}
+	return macA.String() == macB.String()
+}
+
+// normalizeDomain lowercases a domain and drops the trailing dot of fully qualified names.
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
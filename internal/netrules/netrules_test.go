@@ -0,0 +1,190 @@
+package netrules
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/netmon"
+)
+
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "gateway MAC", rule: Rule{Match: MatchGatewayMAC, Value: "aa:bb:cc:dd:ee:ff", Action: ActionDisconnect}},
+		{name: "invalid MAC", rule: Rule{Match: MatchGatewayMAC, Value: "office", Action: ActionDisconnect}, wantErr: true},
+		{name: "SSID", rule: Rule{Match: MatchSSID, Value: "Office", Action: ActionDisconnect}},
+		{name: "empty SSID", rule: Rule{Match: MatchSSID, Value: " ", Action: ActionDisconnect}, wantErr: true},
+		{name: "search domain", rule: Rule{Match: MatchSearchDomain, Value: "corp.example.com", Action: ActionDisconnect}},
+		{name: "reachable", rule: Rule{Match: MatchReachable, Value: "10.0.0.1:443", Action: ActionDisconnect}},
+		{name: "reachable without port", rule: Rule{Match: MatchReachable, Value: "10.0.0.1", Action: ActionDisconnect}, wantErr: true},
+		{name: "any network", rule: Rule{Match: MatchAny, Action: ActionConnect, ProfileID: "work"}},
+		{name: "connect without profile", rule: Rule{Match: MatchAny, Action: ActionConnect}, wantErr: true},
+		{name: "unknown match", rule: Rule{Match: "bssid", Value: "x", Action: ActionDisconnect}, wantErr: true},
+		{name: "unknown action", rule: Rule{Match: MatchAny, Action: "pause"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(nil))
+
+	err := Validate([]Rule{
+		{Match: MatchSSID, Value: "Office", Action: ActionDisconnect},
+		{Name: "Cafés", Match: MatchAny, Action: ActionConnect},
+	})
+	assert.ErrorIs(t, err, ErrInvalidRule)
+	assert.ErrorContains(t, err, "rule 2 (Cafés)")
+}
+
+func TestSelect(t *testing.T) {
+	office := Rule{Name: "Office", Match: MatchGatewayMAC, Value: "AA-BB-CC-DD-EE-FF", Action: ActionDisconnect}
+	officeWifi := Rule{Match: MatchSSID, Value: "Office", Action: ActionDisconnect}
+	lab := Rule{Match: MatchSearchDomain, Value: "lab.corp.example.com.", Action: ActionDisconnect}
+	intranet := Rule{Match: MatchReachable, Value: "10.0.0.1:443", Action: ActionDisconnect}
+	untrusted := Rule{Match: MatchAny, Action: ActionConnect, ProfileID: "work"}
+	rules := []Rule{office, officeWifi, lab, intranet, untrusted}
+
+	uplink := netmon.Uplink{Interface: "wlan0", Gateway: "192.168.1.1"}
+	reachable := func(hosts ...string) func(context.Context, string) bool {
+		return func(_ context.Context, address string) bool {
+			for _, host := range hosts {
+				if host == address {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	tests := []struct {
+		name    string
+		network Network
+		want    *Rule
+	}{
+		{name: "no uplink", network: Network{GatewayMAC: "aa:bb:cc:dd:ee:ff"}, want: nil},
+		{name: "gateway MAC in another notation", network: Network{Uplink: uplink, GatewayMAC: "aa:bb:cc:dd:ee:ff"}, want: &office},
+		{name: "SSID", network: Network{Uplink: uplink, GatewayMAC: "00:11:22:33:44:55", SSID: "Office"}, want: &officeWifi},
+		{name: "search domain", network: Network{Uplink: uplink, SearchDomains: []string{"home", "Lab.Corp.Example.com"}}, want: &lab},
+		{name: "reachable host", network: Network{Uplink: uplink, Reachable: reachable("10.0.0.1:443")}, want: &intranet},
+		{name: "untrusted network", network: Network{Uplink: uplink, SSID: "Café", Reachable: reachable()}, want: &untrusted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Select(context.Background(), rules, tt.network))
+		})
+	}
+
+	t.Run("no rule matches", func(t *testing.T) {
+		assert.Nil(t, Select(context.Background(), []Rule{office}, Network{Uplink: uplink}))
+	})
+
+	t.Run("first match wins", func(t *testing.T) {
+		got := Select(context.Background(), []Rule{untrusted, office}, Network{Uplink: uplink, GatewayMAC: "aa:bb:cc:dd:ee:ff"})
+		assert.Equal(t, &untrusted, got)
+	})
+}
+
+func TestReachableOver(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	address := listener.Addr().String()
+
+	reachable := reachableOver("lo")
+	assert.True(t, reachable(context.Background(), address))
+
+	// The host is not reachable through another interface
+	assert.False(t, reachableOver("does-not-exist0")(context.Background(), address))
+
+	require.NoError(t, listener.Close())
+	assert.False(t, reachable(context.Background(), address))
+}
+
+// neighborMessage builds a neighbor message as the kernel sends it.
+func neighborMessage(index int, state uint16, ip net.IP, mac net.HardwareAddr) []byte {
+	data := make([]byte, sizeofNdMsg)
+	binary.NativeEndian.PutUint32(data[4:8], uint32(index))
+	binary.NativeEndian.PutUint16(data[8:10], state)
+
+	attr := func(attrType uint16, value []byte) {
+		header := make([]byte, syscall.SizeofRtAttr)
+		binary.NativeEndian.PutUint16(header[0:2], uint16(syscall.SizeofRtAttr+len(value)))
+		binary.NativeEndian.PutUint16(header[2:4], attrType)
+		data = append(data, header...)
+		data = append(data, value...)
+		for len(data)%syscall.NLMSG_ALIGNTO != 0 {
+			data = append(data, 0)
+		}
+	}
+	attr(ndaDst, ip)
+	if mac != nil {
+		attr(ndaLLAddr, mac)
+	}
+	return data
+}
+
+func TestParseNeighbor(t *testing.T) {
+	mac, err := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	require.NoError(t, err)
+	const reachable = 0x02
+
+	n, ok := parseNeighbor(neighborMessage(3, reachable, net.ParseIP("192.168.1.1").To4(), mac))
+	require.True(t, ok)
+	assert.Equal(t, 3, n.index)
+	assert.True(t, n.ip.Equal(net.ParseIP("192.168.1.1")))
+	assert.Equal(t, mac, n.mac)
+
+	n, ok = parseNeighbor(neighborMessage(2, reachable, net.ParseIP("fe80::1"), mac))
+	require.True(t, ok)
+	assert.True(t, n.ip.Equal(net.ParseIP("fe80::1")))
+
+	_, ok = parseNeighbor(neighborMessage(3, nudIncomplete, net.ParseIP("192.168.1.1").To4(), nil))
+	assert.False(t, ok, "unresolved entries are skipped")
+
+	_, ok = parseNeighbor(neighborMessage(3, nudFailed, net.ParseIP("192.168.1.1").To4(), mac))
+	assert.False(t, ok, "failed entries are skipped")
+
+	_, ok = parseNeighbor([]byte{2, 0, 0})
+	assert.False(t, ok, "truncated messages are skipped")
+}
+
+func TestFindNeighbor(t *testing.T) {
+	mac, err := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	require.NoError(t, err)
+	neighbors := []neighbor{
+		{index: 2, ip: net.ParseIP("192.168.1.1"), mac: net.HardwareAddr{1, 2, 3, 4, 5, 6}},
+		{index: 3, ip: net.ParseIP("192.168.1.1"), mac: mac},
+	}
+
+	assert.Equal(t, mac, findNeighbor(neighbors, net.ParseIP("192.168.1.1"), 3))
+	assert.Nil(t, findNeighbor(neighbors, net.ParseIP("192.168.1.2"), 3))
+	assert.Nil(t, findNeighbor(neighbors, net.ParseIP("192.168.1.1"), 4))
+}
@@ -0,0 +1,219 @@
+package profile
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrNoFortiClientTunnels is returned when a FortiClient export contains no SSL-VPN tunnels.
+var ErrNoFortiClientTunnels = errors.New("no SSL-VPN tunnels found in FortiClient configuration")
+
+// maxFortiClientFileSize limits the size of FortiClient exports to prevent excessive memory use.
+const maxFortiClientFileSize = 10 * 1024 * 1024
+
+// forticlientConfig is the subset of a FortiClient XML configuration export used for import.
+type forticlientConfig struct {
+	XMLName xml.Name `xml:"forticlient_configuration"`
+	VPN     struct {
+		SSLVPN struct {
+			Connections []forticlientTunnel `xml:"connections>connection"`
+		} `xml:"sslvpn"`
+		IPSecVPN struct {
+			Connections []struct {
+				Name string `xml:"name"`
+			} `xml:"connections>connection"`
+		} `xml:"ipsecvpn"`
+	} `xml:"vpn"`
+}
+
+// forticlientTunnel is a single SSL-VPN tunnel entry in a FortiClient export.
+type forticlientTunnel struct {
+	Name              string `xml:"name"`
+	Description       string `xml:"description"`
+	Server            string `xml:"server"`
+	Realm             string `xml:"realm"`
+	Username          string `xml:"username"`
+	Password          string `xml:"password"`
+	SSOEnabled        string `xml:"sso_enabled"`
+	PromptCertificate string `xml:"prompt_certificate"`
+	Certificate       string `xml:"certificate"`
+	WarnInvalidCert   string `xml:"warn_invalid_server_certificate"`
+
+	// Other captures all elements without a dedicated field so they can be reported.
+	Other []forticlientElement `xml:",any"`
+}
+
+// forticlientElement is an arbitrary XML element in a tunnel entry.
+type forticlientElement struct {
+	XMLName xml.Name
+	Content string `xml:",innerxml"`
+}
+
+// forticlientIgnoredFields are FortiClient settings that only affect the FortiClient UI
+// or behavior that openfortivpn-gui provides anyway, so they are dropped silently.
+var forticlientIgnoredFields = map[string]bool{
+	"ui":                     true,
+	"single_user_mode":       true,
+	"prompt_username":        true,
+	"use_external_browser":   true,
+	"keep_running":           true,
+	"save_password":          true,
+	"show_remember_password": true,
+}
+
+// ImportFortiClientFile reads a FortiClient XML configuration export from disk.
+func ImportFortiClientFile(path string) (*ImportResult, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is chosen by the user in the import dialog
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FortiClient configuration: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return ParseFortiClientXML(f)
+}
+
+// ParseFortiClientXML converts the SSL-VPN tunnels of a FortiClient XML configuration
+// export into profiles. Settings that cannot be represented are reported as warnings
+// on the corresponding ImportedProfile; IPsec tunnels are skipped and reported in
+// ImportResult.Warnings.
+func ParseFortiClientXML(r io.Reader) (*ImportResult, error) {
+	var cfg forticlientConfig
+	decoder := xml.NewDecoder(io.LimitReader(r, maxFortiClientFileSize))
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse FortiClient configuration: %w", err)
+	}
+
+	result := &ImportResult{}
+
+	for _, ipsec := range cfg.VPN.IPSecVPN.Connections {
+		result.Warnings = append(result.Warnings, ImportWarning{
+			Field:  "ipsecvpn",
+			Reason: fmt.Sprintf("IPsec tunnel %q skipped: only SSL-VPN tunnels are supported", ipsec.Name),
+		})
+	}
+
+	for i, tunnel := range cfg.VPN.SSLVPN.Connections {
+		result.Profiles = append(result.Profiles, convertFortiClientTunnel(tunnel, i+1))
+	}
+
+	if len(result.Profiles) == 0 {
+		return nil, ErrNoFortiClientTunnels
+	}
+
+	return result, nil
+}
+
+// convertFortiClientTunnel maps a FortiClient tunnel entry to a profile.
+// The index is used to name tunnels without a name.
+func convertFortiClientTunnel(tunnel forticlientTunnel, index int) ImportedProfile {
+	name := strings.TrimSpace(tunnel.Name)
+	if name == "" {
+		name = fmt.Sprintf("Imported Tunnel %d", index)
+	}
+
+	p := NewProfile(name)
+	p.Description = strings.TrimSpace(tunnel.Description)
+	p.Username = strings.TrimSpace(tunnel.Username)
+	imported := ImportedProfile{Profile: p, Source: name}
+
+	host, port, realm, err := parseFortiClientServer(tunnel.Server)
+	if err != nil {
+		imported.Warnings = append(imported.Warnings, ImportWarning{Field: "server", Reason: err.Error()})
+	}
+	p.Host = host
+	if port != 0 {
+		p.Port = port
+	}
+	p.Realm = realm
+	if r := strings.TrimSpace(tunnel.Realm); r != "" {
+		p.Realm = r
+	}
+
+	switch {
+	case forticlientBool(tunnel.SSOEnabled):
+		p.AuthMethod = AuthMethodSAML
+	case forticlientBool(tunnel.PromptCertificate) || strings.TrimSpace(tunnel.Certificate) != "":
+		p.AuthMethod = AuthMethodCertificate
+		imported.Warnings = append(imported.Warnings, ImportWarning{
+			Field:  "certificate",
+			Reason: "client certificates from the FortiClient certificate store cannot be imported; set the certificate and key paths manually",
+		})
+	default:
+		p.AuthMethod = AuthMethodPassword
+	}
+
+	if strings.TrimSpace(tunnel.Password) != "" {
+		imported.Warnings = append(imported.Warnings, ImportWarning{
+			Field:  "password",
+			Reason: "saved passwords are encrypted by FortiClient and are not imported",
+		})
+	}
+
+	if tunnel.WarnInvalidCert != "" && !forticlientBool(tunnel.WarnInvalidCert) {
+		imported.Warnings = append(imported.Warnings, ImportWarning{
+			Field:  "warn_invalid_server_certificate",
+			Reason: "disabling server certificate checks is not supported; set a trusted certificate digest instead",
+		})
+	}
+
+	for _, elem := range tunnel.Other {
+		field := elem.XMLName.Local
+		value := strings.TrimSpace(elem.Content)
+		if forticlientIgnoredFields[field] || value == "" || value == "0" {
+			continue
+		}
+		imported.Warnings = append(imported.Warnings, ImportWarning{
+			Field:  field,
+			Reason: "setting is not supported and was not imported",
+		})
+	}
+
+	return imported
+}
+
+// parseFortiClientServer splits a FortiClient server value into host, port, and realm.
+// Accepted forms include "host", "host:port", "host:port/realm", and
+// "https://host:port/realm". A zero port means the value did not specify one.
+func parseFortiClientServer(server string) (host string, port int, realm string, err error) {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return "", 0, "", errors.New("server address is empty")
+	}
+
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+
+	u, err := url.Parse(server)
+	if err != nil || u.Hostname() == "" {
+		return "", 0, "", fmt.Errorf("invalid server address %q", server)
+	}
+
+	host = u.Hostname()
+	if p := u.Port(); p != "" {
+		port, err = strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return host, 0, "", fmt.Errorf("invalid port in server address %q", net.JoinHostPort(host, p))
+		}
+	}
+	realm = strings.Trim(u.Path, "/")
+
+	return host, port, realm, nil
+}
+
+// forticlientBool interprets FortiClient boolean values ("1"/"0", "true"/"false", "yes"/"no").
+func forticlientBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,194 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const forticlientExport = `<?xml version="1.0" encoding="UTF-8" ?>
+<forticlient_configuration>
+  <vpn>
+    <sslvpn>
+      <options>
+        <enabled>1</enabled>
+      </options>
+      <connections>
+        <connection>
+          <name>Office</name>
+          <description>Main office</description>
+          <server>vpn.example.com:10443</server>
+          <username>jdoe</username>
+          <password>Enc 5f3a9c</password>
+          <ui>
+            <show_remember_password>1</show_remember_password>
+          </ui>
+          <prompt_username>1</prompt_username>
+          <sso_enabled>0</sso_enabled>
+        </connection>
+        <connection>
+          <name>SSO Gateway</name>
+          <server>https://sso.example.com/employees</server>
+          <sso_enabled>1</sso_enabled>
+          <use_external_browser>1</use_external_browser>
+        </connection>
+        <connection>
+          <name>Lab</name>
+          <server>10.0.0.1:443</server>
+          <prompt_certificate>1</prompt_certificate>
+          <certificate>CN=jdoe</certificate>
+          <warn_invalid_server_certificate>0</warn_invalid_server_certificate>
+          <on_connect><script><os>windows</os></script></on_connect>
+        </connection>
+      </connections>
+    </sslvpn>
+    <ipsecvpn>
+      <connections>
+        <connection>
+          <name>Legacy IPsec</name>
+        </connection>
+      </connections>
+    </ipsecvpn>
+  </vpn>
+</forticlient_configuration>`
+
+func TestParseFortiClientXML(t *testing.T) {
+	result, err := ParseFortiClientXML(strings.NewReader(forticlientExport))
+	require.NoError(t, err)
+	require.Len(t, result.Profiles, 3)
+
+	t.Run("password tunnel", func(t *testing.T) {
+		imported := result.Profiles[0]
+		p := imported.Profile
+		assert.Equal(t, "Office", imported.Source)
+		assert.Equal(t, "Office", p.Name)
+		assert.Equal(t, "Main office", p.Description)
+		assert.Equal(t, "vpn.example.com", p.Host)
+		assert.Equal(t, 10443, p.Port)
+		assert.Equal(t, "jdoe", p.Username)
+		assert.Equal(t, AuthMethodPassword, p.AuthMethod)
+		assert.NoError(t, p.Validate())
+
+		require.Len(t, imported.Warnings, 1)
+		assert.Equal(t, "password", imported.Warnings[0].Field)
+	})
+
+	t.Run("SAML tunnel with realm", func(t *testing.T) {
+		imported := result.Profiles[1]
+		p := imported.Profile
+		assert.Equal(t, "sso.example.com", p.Host)
+		assert.Equal(t, 443, p.Port)
+		assert.Equal(t, "employees", p.Realm)
+		assert.Equal(t, AuthMethodSAML, p.AuthMethod)
+		assert.Empty(t, imported.Warnings)
+		assert.NoError(t, p.Validate())
+	})
+
+	t.Run("certificate tunnel reports unrepresentable fields", func(t *testing.T) {
+		imported := result.Profiles[2]
+		assert.Equal(t, AuthMethodCertificate, imported.Profile.AuthMethod)
+
+		fields := make([]string, 0, len(imported.Warnings))
+		for _, w := range imported.Warnings {
+			fields = append(fields, w.Field)
+		}
+		assert.ElementsMatch(t, []string{"certificate", "warn_invalid_server_certificate", "on_connect"}, fields)
+	})
+
+	t.Run("IPsec tunnels are skipped", func(t *testing.T) {
+		require.Len(t, result.Warnings, 1)
+		assert.Equal(t, "ipsecvpn", result.Warnings[0].Field)
+		assert.Contains(t, result.Warnings[0].Reason, "Legacy IPsec")
+	})
+
+	t.Run("each profile gets a unique ID", func(t *testing.T) {
+		assert.NotEqual(t, result.Profiles[0].Profile.ID, result.Profiles[1].Profile.ID)
+		assert.NotEqual(t, result.Profiles[1].Profile.ID, result.Profiles[2].Profile.ID)
+	})
+}
+
+func TestParseFortiClientXML_UnnamedTunnel(t *testing.T) {
+	xml := `<forticlient_configuration><vpn><sslvpn><connections>
+		<connection><server>vpn.example.com</server></connection>
+	</connections></sslvpn></vpn></forticlient_configuration>`
+
+	result, err := ParseFortiClientXML(strings.NewReader(xml))
+	require.NoError(t, err)
+	require.Len(t, result.Profiles, 1)
+	assert.Equal(t, "Imported Tunnel 1", result.Profiles[0].Profile.Name)
+}
+
+func TestParseFortiClientXML_InvalidServer(t *testing.T) {
+	xml := `<forticlient_configuration><vpn><sslvpn><connections>
+		<connection><name>Broken</name><server>vpn.example.com:99999</server></connection>
+	</connections></sslvpn></vpn></forticlient_configuration>`
+
+	result, err := ParseFortiClientXML(strings.NewReader(xml))
+	require.NoError(t, err)
+	require.Len(t, result.Profiles, 1)
+	require.Len(t, result.Profiles[0].Warnings, 1)
+	assert.Equal(t, "server", result.Profiles[0].Warnings[0].Field)
+}
+
+func TestParseFortiClientXML_Errors(t *testing.T) {
+	t.Run("malformed XML", func(t *testing.T) {
+		_, err := ParseFortiClientXML(strings.NewReader("<forticlient_configuration>"))
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong root element", func(t *testing.T) {
+		_, err := ParseFortiClientXML(strings.NewReader("<config></config>"))
+		assert.Error(t, err)
+	})
+
+	t.Run("no SSL-VPN tunnels", func(t *testing.T) {
+		_, err := ParseFortiClientXML(strings.NewReader("<forticlient_configuration><vpn/></forticlient_configuration>"))
+		assert.ErrorIs(t, err, ErrNoFortiClientTunnels)
+	})
+}
+
+func TestImportFortiClientFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forticlient.xml")
+	require.NoError(t, os.WriteFile(path, []byte(forticlientExport), 0600))
+
+	result, err := ImportFortiClientFile(path)
+	require.NoError(t, err)
+	assert.Len(t, result.Profiles, 3)
+
+	_, err = ImportFortiClientFile(filepath.Join(t.TempDir(), "missing.xml"))
+	assert.Error(t, err)
+}
+
+func TestParseFortiClientServer(t *testing.T) {
+	tests := []struct {
+		server    string
+		wantHost  string
+		wantPort  int
+		wantRealm string
+	}{
+		{"vpn.example.com", "vpn.example.com", 0, ""},
+		{"vpn.example.com:8443", "vpn.example.com", 8443, ""},
+		{"vpn.example.com:8443/sales", "vpn.example.com", 8443, "sales"},
+		{"https://vpn.example.com/sales/", "vpn.example.com", 0, "sales"},
+		{"[2001:db8::1]:443", "2001:db8::1", 443, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			host, port, realm, err := parseFortiClientServer(tt.server)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHost, host)
+			assert.Equal(t, tt.wantPort, port)
+			assert.Equal(t, tt.wantRealm, realm)
+		})
+	}
+}
+
+func TestImportWarning_String(t *testing.T) {
+	w := ImportWarning{Field: "password", Reason: "not imported"}
+	assert.Equal(t, "password: not imported", w.String())
+}
@@ -0,0 +1,150 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// GatewaySelection determines which gateway endpoint is tried first when connecting.
+type GatewaySelection string
+
+const (
+	// GatewaySelectionOrdered tries the reachable gateways in the configured order.
+	GatewaySelectionOrdered GatewaySelection = "ordered"
+	// GatewaySelectionFastest tries the gateway with the quickest TCP/TLS handshake first.
+	GatewaySelectionFastest GatewaySelection = "fastest"
+
+	// maxGateways limits the number of backup gateways per profile.
+	maxGateways = 10
+)
+
+// Gateway is a VPN gateway endpoint. Profiles list backup gateways in addition
+// to their primary Host/Port, such as the second unit of a high-availability pair.
+type Gateway struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// String returns the endpoint in host:port form.
+func (g Gateway) String() string {
+	return net.JoinHostPort(g.Host, strconv.Itoa(g.Port))
+}
+
+// ParseGateway parses an endpoint in host or host:port form.
+// The default port is used when none is given.
+func ParseGateway(s string, defaultPort int) (Gateway, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Gateway{}, errors.New("gateway is empty")
+	}
+
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		// No port given (bare hostname or IPv6 address)
+		gw := Gateway{Host: strings.Trim(s, "[]"), Port: defaultPort}
+		return gw, gw.validate()
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return Gateway{}, fmt.Errorf("invalid gateway port %q", portStr)
+	}
+	gw := Gateway{Host: host, Port: port}
+	return gw, gw.validate()
+}
+
+// ParseGateways parses a comma-separated list of endpoints in host or host:port form,
+// skipping empty entries. It returns the first invalid entry as an error.
+func ParseGateways(s string, defaultPort int) ([]Gateway, error) {
+	var gateways []Gateway
+	for _, entry := range strings.Split(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		gw, err := ParseGateway(entry, defaultPort)
+		if err != nil {
+			return nil, err
+		}
+		gateways = append(gateways, gw)
+	}
+	return gateways, nil
+}
+
+// FormatGateways formats gateways as a comma-separated host:port list accepted by ParseGateways.
+func FormatGateways(gateways []Gateway) string {
+	entries := make([]string, 0, len(gateways))
+	for _, gw := range gateways {
+		entries = append(entries, gw.String())
+	}
+	return strings.Join(entries, ", ")
+}
+
+// validate checks that the gateway host and port are valid.
+func (g Gateway) validate() error {
+	if err := validateHost(g.Host); err != nil {
+		return fmt.Errorf("gateway %s: %w", g.Host, err)
+	}
+	if g.Port < 1 || g.Port > 65535 {
+		return fmt.Errorf("gateway %s: port must be between 1 and 65535, got %d", g.Host, g.Port)
+	}
+	return nil
+}
+
+// ValidGatewaySelections returns all valid gateway selection modes.
+func ValidGatewaySelections() []GatewaySelection {
+	return []GatewaySelection{
+		GatewaySelectionOrdered,
+		GatewaySelectionFastest,
+	}
+}
+
+// Endpoints returns the profile's gateway endpoints: the primary Host/Port
+// followed by the backup gateways, without duplicates.
+func (p *Profile) Endpoints() []Gateway {
+	endpoints := make([]Gateway, 0, 1+len(p.Gateways))
+	seen := make(map[Gateway]bool, 1+len(p.Gateways))
+	for _, gw := range append([]Gateway{{Host: p.Host, Port: p.Port}}, p.Gateways...) {
+		if gw.Host == "" || seen[gw] {
+			continue
+		}
+		seen[gw] = true
+		endpoints = append(endpoints, gw)
+	}
+	return endpoints
+}
+
+// WithEndpoints returns a copy of the profile that uses the first endpoint
+// as its primary Host/Port and the remaining ones as backup gateways.
+// The profile is returned unchanged if endpoints is empty.
+func (p *Profile) WithEndpoints(endpoints []Gateway) *Profile {
+	c := *p
+	if len(endpoints) == 0 {
+		return &c
+	}
+	c.Host = endpoints[0].Host
+	c.Port = endpoints[0].Port
+	c.Gateways = append([]Gateway(nil), endpoints[1:]...)
+	return &c
+}
+
+// validateGateways checks the backup gateways and the selection mode.
+func (p *Profile) validateGateways() error {
+	if len(p.Gateways) > maxGateways {
+		return fmt.Errorf("too many gateways (max %d)", maxGateways)
+	}
+	for _, gw := range p.Gateways {
+		if err := gw.validate(); err != nil {
+			return err
+		}
+	}
+
+	switch p.GatewaySelection {
+	case "", GatewaySelectionOrdered, GatewaySelectionFastest:
+		return nil
+	default:
+		return fmt.Errorf("invalid gateway selection: %s", p.GatewaySelection)
+	}
+}
@@ -0,0 +1,174 @@
+package profile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGateway(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Gateway
+		wantErr bool
+	}{
+		{name: "host and port", input: "vpn2.example.com:10443", want: Gateway{Host: "vpn2.example.com", Port: 10443}},
+		{name: "host only uses default port", input: " vpn2.example.com ", want: Gateway{Host: "vpn2.example.com", Port: 443}},
+		{name: "IPv4 with port", input: "203.0.113.7:8443", want: Gateway{Host: "203.0.113.7", Port: 8443}},
+		{name: "IPv6 with port", input: "[2001:db8::1]:8443", want: Gateway{Host: "2001:db8::1", Port: 8443}},
+		{name: "bare IPv6", input: "2001:db8::1", want: Gateway{Host: "2001:db8::1", Port: 443}},
+		{name: "empty", input: "  ", wantErr: true},
+		{name: "invalid port", input: "vpn.example.com:abc", wantErr: true},
+		{name: "port out of range", input: "vpn.example.com:70000", wantErr: true},
+		{name: "invalid host", input: "vpn;rm:443", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGateway(tt.input, 443)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseGateways(t *testing.T) {
+	gateways, err := ParseGateways("vpn2.example.com:10443, , 203.0.113.7", 443)
+	require.NoError(t, err)
+	assert.Equal(t, []Gateway{
+		{Host: "vpn2.example.com", Port: 10443},
+		{Host: "203.0.113.7", Port: 443},
+	}, gateways)
+	assert.Equal(t, "vpn2.example.com:10443, 203.0.113.7:443", FormatGateways(gateways))
+
+	empty, err := ParseGateways("  ", 443)
+	require.NoError(t, err)
+	assert.Nil(t, empty)
+
+	_, err = ParseGateways("vpn2.example.com, bad host", 443)
+	assert.Error(t, err)
+}
+
+func TestGateway_String(t *testing.T) {
+	assert.Equal(t, "vpn.example.com:443", Gateway{Host: "vpn.example.com", Port: 443}.String())
+	assert.Equal(t, "[2001:db8::1]:443", Gateway{Host: "2001:db8::1", Port: 443}.String())
+}
+
+func TestProfile_Endpoints(t *testing.T) {
+	p := NewProfile("HA")
+	p.Host = "vpn1.example.com"
+	p.Gateways = []Gateway{
+		{Host: "vpn2.example.com", Port: 443},
+		{Host: "vpn1.example.com", Port: 443},
+		{Host: "vpn3.example.com", Port: 10443},
+	}
+
+	assert.Equal(t, []Gateway{
+		{Host: "vpn1.example.com", Port: 443},
+		{Host: "vpn2.example.com", Port: 443},
+		{Host: "vpn3.example.com", Port: 10443},
+	}, p.Endpoints())
+
+	// Templates may leave the primary host empty
+	empty := NewProfile("Template")
+	assert.Empty(t, empty.Endpoints())
+}
+
+func TestProfile_WithEndpoints(t *testing.T) {
+	p := NewProfile("HA")
+	p.Host = "vpn1.example.com"
+	p.Gateways = []Gateway{{Host: "vpn2.example.com", Port: 8443}}
+
+	swapped := p.WithEndpoints([]Gateway{{Host: "vpn2.example.com", Port: 8443}, {Host: "vpn1.example.com", Port: 443}})
+	assert.Equal(t, "vpn2.example.com", swapped.Host)
+	assert.Equal(t, 8443, swapped.Port)
+	assert.Equal(t, []Gateway{{Host: "vpn1.example.com", Port: 443}}, swapped.Gateways)
+
+	// The original profile is not modified
+	assert.Equal(t, "vpn1.example.com", p.Host)
+	assert.Equal(t, []Gateway{{Host: "vpn2.example.com", Port: 8443}}, p.Gateways)
+
+	unchanged := p.WithEndpoints(nil)
+	assert.NotSame(t, p, unchanged)
+	assert.Equal(t, p.Host, unchanged.Host)
+}
+
+func TestProfile_Validate_Gateways(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(p *Profile)
+		wantErr bool
+	}{
+		{name: "valid backup gateways", modify: func(p *Profile) {
+			p.Gateways = []Gateway{{Host: "vpn2.example.com", Port: 443}}
+			p.GatewaySelection = GatewaySelectionFastest
+		}},
+		{name: "invalid gateway host", modify: func(p *Profile) {
+			p.Gateways = []Gateway{{Host: "vpn2.example.com;reboot", Port: 443}}
+		}, wantErr: true},
+		{name: "invalid gateway port", modify: func(p *Profile) {
+			p.Gateways = []Gateway{{Host: "vpn2.example.com", Port: 0}}
+		}, wantErr: true},
+		{name: "too many gateways", modify: func(p *Profile) {
+			for i := 0; i <= maxGateways; i++ {
+				p.Gateways = append(p.Gateways, Gateway{Host: "vpn.example.com", Port: 1000 + i})
+			}
+		}, wantErr: true},
+		{name: "invalid selection", modify: func(p *Profile) { p.GatewaySelection = "random" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile("HA")
+			p.Host = "vpn1.example.com"
+			p.Username = "user"
+			tt.modify(p)
+
+			err := p.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProfile_Gateways_Inherited(t *testing.T) {
+	template := NewProfile("Template")
+	template.IsTemplate = true
+	template.Gateways = []Gateway{{Host: "vpn2.example.com", Port: 443}}
+	template.GatewaySelection = GatewaySelectionFastest
+
+	child := NewProfile("Child")
+	assert.False(t, child.FieldEqual(template, "gateways"))
+	child.InheritField(template, "gateways")
+	assert.True(t, child.FieldEqual(template, "gateways"))
+
+	// The inherited slice is a copy
+	child.Gateways[0].Port = 8443
+	assert.Equal(t, 443, template.Gateways[0].Port)
+}
+
+func TestProfile_Gateways_JSONRoundTrip(t *testing.T) {
+	p := NewProfile("HA")
+	p.Host = "vpn1.example.com"
+	p.Gateways = []Gateway{{Host: "vpn2.example.com", Port: 10443}}
+	p.GatewaySelection = GatewaySelectionFastest
+
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"gateways":[{"host":"vpn2.example.com","port":10443}]`)
+
+	var decoded Profile
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, p.Gateways, decoded.Gateways)
+	assert.Equal(t, GatewaySelectionFastest, decoded.GatewaySelection)
+}
@@ -0,0 +1,82 @@
+package profile
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultHookTimeout is how long a hook may run when the profile does not set a timeout.
+	DefaultHookTimeout = 30 * time.Second
+
+	// maxHookTimeoutSeconds bounds the configurable hook timeout.
+	maxHookTimeoutSeconds = 600
+	// maxHookCommandLength bounds the length of each hook command.
+	maxHookCommandLength = 4096
+)
+
+// Hooks are shell commands run as the user at points of a connection's lifecycle,
+// such as mounting network shares once the tunnel is up.
+type Hooks struct {
+	// PreConnect runs before connecting. The connection is not started if it fails.
+	PreConnect string `json:"pre_connect,omitempty"`
+	// PostConnect runs once the tunnel is up.
+	PostConnect string `json:"post_connect,omitempty"`
+	// PreDisconnect runs before a disconnect the user asked for, while the tunnel is still up.
+	PreDisconnect string `json:"pre_disconnect,omitempty"`
+	// PostDisconnect runs after a connection that was up has gone down.
+	PostDisconnect string `json:"post_disconnect,omitempty"`
+	// TimeoutSeconds limits how long each hook may run. Zero uses DefaultHookTimeout.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// clone returns a copy of the hooks, or nil if h is nil.
+func (h *Hooks) clone() *Hooks {
+	if h == nil {
+		return nil
+	}
+	c := *h
+	return &c
+}
+
+// IsEmpty reports whether no hook command is set.
+func (h *Hooks) IsEmpty() bool {
+	return h == nil || (h.PreConnect == "" && h.PostConnect == "" && h.PreDisconnect == "" && h.PostDisconnect == "")
+}
+
+// Timeout returns how long each hook may run.
+func (h *Hooks) Timeout() time.Duration {
+	if h == nil || h.TimeoutSeconds == 0 {
+		return DefaultHookTimeout
+	}
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+// validateHooks checks the profile's hook commands and timeout.
+func (p *Profile) validateHooks() error {
+	h := p.Hooks
+	if h == nil {
+		return nil
+	}
+
+	if h.TimeoutSeconds < 0 || h.TimeoutSeconds > maxHookTimeoutSeconds {
+		return fmt.Errorf("hook timeout must be between 0 and %d seconds, got %d", maxHookTimeoutSeconds, h.TimeoutSeconds)
+	}
+
+	for name, command := range map[string]string{
+		"pre-connect":     h.PreConnect,
+		"post-connect":    h.PostConnect,
+		"pre-disconnect":  h.PreDisconnect,
+		"post-disconnect": h.PostDisconnect,
+	} {
+		if len(command) > maxHookCommandLength {
+			return fmt.Errorf("%s hook exceeds maximum length of %d characters", name, maxHookCommandLength)
+		}
+		if strings.ContainsRune(command, 0) {
+			return fmt.Errorf("%s hook contains invalid characters", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package profile
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfile_ValidateHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		hooks   *Hooks
+		wantErr bool
+	}{
+		{name: "no hooks", hooks: nil},
+		{name: "commands", hooks: &Hooks{PostConnect: "kinit alice@EXAMPLE.COM && mount /mnt/share", PostDisconnect: "umount /mnt/share"}},
+		{name: "timeout", hooks: &Hooks{PreConnect: "true", TimeoutSeconds: 120}},
+		{name: "negative timeout", hooks: &Hooks{TimeoutSeconds: -1}, wantErr: true},
+		{name: "timeout too long", hooks: &Hooks{TimeoutSeconds: maxHookTimeoutSeconds + 1}, wantErr: true},
+		{name: "command too long", hooks: &Hooks{PreDisconnect: strings.Repeat("x", maxHookCommandLength+1)}, wantErr: true},
+		{name: "NUL byte", hooks: &Hooks{PostConnect: "echo \x00"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile("Office")
+			p.Host = "vpn.example.com"
+			p.Username = "alice"
+			p.Hooks = tt.hooks
+
+			err := p.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestHooks_Timeout(t *testing.T) {
+	var none *Hooks
+	assert.Equal(t, DefaultHookTimeout, none.Timeout())
+	assert.Equal(t, DefaultHookTimeout, (&Hooks{}).Timeout())
+	assert.Equal(t, 90*time.Second, (&Hooks{TimeoutSeconds: 90}).Timeout())
+}
+
+func TestHooks_IsEmpty(t *testing.T) {
+	var none *Hooks
+	assert.True(t, none.IsEmpty())
+	assert.True(t, (&Hooks{TimeoutSeconds: 10}).IsEmpty())
+	assert.False(t, (&Hooks{PostDisconnect: "umount /mnt/share"}).IsEmpty())
+}
+
+func TestHooks_JSON(t *testing.T) {
+	p := NewProfile("Office")
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hooks")
+
+	p.Hooks = &Hooks{PostConnect: "kubectl config use-context corp"}
+	data, err = json.Marshal(p)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"hooks":{"post_connect":"kubectl config use-context corp"}`)
+
+	var decoded Profile
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, p.Hooks, decoded.Hooks)
+}
+
+func TestResolve_CopiesHooks(t *testing.T) {
+	p := NewProfile("Office")
+	p.Hooks = &Hooks{PostConnect: "true"}
+
+	resolved, err := Resolve(p, nil)
+	require.NoError(t, err)
+	resolved.Hooks.PostConnect = "false"
+	assert.Equal(t, "true", p.Hooks.PostConnect)
+}
+
+func TestResolve_InheritsHooks(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	fields := systemTestFields()
+	fields["is_template"] = true
+	fields["hooks"] = map[string]any{"post_connect": "mount-shares"}
+	writeSystemProfile(t, systemDir, fields)
+
+	child := NewProfile("Mine")
+	child.ParentID = systemTestID
+	resolved, err := Resolve(child, store)
+	require.NoError(t, err)
+	require.NotNil(t, resolved.Hooks)
+	assert.Equal(t, "mount-shares", resolved.Hooks.PostConnect)
+
+	fields["locked"] = []string{"hooks"}
+	writeSystemProfile(t, systemDir, fields)
+	child.Hooks = &Hooks{PostConnect: "true"}
+	child.SetOverridden("hooks", true)
+	resolved, err = Resolve(child, store)
+	require.NoError(t, err)
+	assert.Equal(t, "mount-shares", resolved.Hooks.PostConnect, "locked hooks cannot be overridden")
+}
@@ -0,0 +1,72 @@
+package profile
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultIdleWarning is how long after the idle warning the tunnel is
+	// disconnected, when the profile does not set it.
+	DefaultIdleWarning = time.Minute
+
+	// maxIdleMinutes bounds the configurable idle time.
+	maxIdleMinutes = 24 * 60
+	// maxIdleWarningSeconds bounds the configurable warning time.
+	maxIdleWarningSeconds = 3600
+)
+
+// IdleDisconnect closes a tunnel nobody uses, so a forgotten session does not
+// hold one of the gateway's concurrent tunnel licenses.
+type IdleDisconnect struct {
+	// ThresholdBytesPerSec is the traffic, received and sent together, below
+	// which the tunnel counts as idle.
+	ThresholdBytesPerSec int `json:"threshold_bytes_per_sec"`
+	// Minutes is how long the tunnel must stay idle before the user is warned.
+	Minutes int `json:"minutes"`
+	// WarningSeconds is how long after the warning the tunnel is disconnected
+	// unless it is used again. Zero uses DefaultIdleWarning.
+	WarningSeconds int `json:"warning_seconds,omitempty"`
+}
+
+// clone returns a copy of the idle rule, or nil if d is nil.
+func (d *IdleDisconnect) clone() *IdleDisconnect {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	return &c
+}
+
+// IdleAfter returns how long the tunnel must stay idle before the user is warned.
+func (d *IdleDisconnect) IdleAfter() time.Duration {
+	return time.Duration(d.Minutes) * time.Minute
+}
+
+// Warning returns how long after the warning the tunnel is disconnected.
+func (d *IdleDisconnect) Warning() time.Duration {
+	if d.WarningSeconds == 0 {
+		return DefaultIdleWarning
+	}
+	return time.Duration(d.WarningSeconds) * time.Second
+}
+
+// validateIdleDisconnect checks the profile's idle disconnect rule.
+func (p *Profile) validateIdleDisconnect() error {
+	d := p.IdleDisconnect
+	if d == nil {
+		return nil
+	}
+
+	if d.ThresholdBytesPerSec < 1 {
+		return fmt.Errorf("idle threshold must be at least 1 byte/s, got %d", d.ThresholdBytesPerSec)
+	}
+	if d.Minutes < 1 || d.Minutes > maxIdleMinutes {
+		return fmt.Errorf("idle time must be between 1 and %d minutes, got %d", maxIdleMinutes, d.Minutes)
+	}
+	if d.WarningSeconds < 0 || d.WarningSeconds > maxIdleWarningSeconds {
+		return fmt.Errorf("idle warning must be between 0 and %d seconds, got %d", maxIdleWarningSeconds, d.WarningSeconds)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package profile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfile_ValidateIdleDisconnect(t *testing.T) {
+	tests := []struct {
+		name    string
+		idle    *IdleDisconnect
+		wantErr bool
+	}{
+		{name: "no rule", idle: nil},
+		{name: "idle rule", idle: &IdleDisconnect{ThresholdBytesPerSec: 100, Minutes: 30}},
+		{name: "with warning", idle: &IdleDisconnect{ThresholdBytesPerSec: 100, Minutes: 30, WarningSeconds: 120}},
+		{name: "no threshold", idle: &IdleDisconnect{Minutes: 30}, wantErr: true},
+		{name: "no minutes", idle: &IdleDisconnect{ThresholdBytesPerSec: 100}, wantErr: true},
+		{name: "too many minutes", idle: &IdleDisconnect{ThresholdBytesPerSec: 100, Minutes: maxIdleMinutes + 1}, wantErr: true},
+		{name: "negative warning", idle: &IdleDisconnect{ThresholdBytesPerSec: 100, Minutes: 30, WarningSeconds: -1}, wantErr: true},
+		{name: "warning too long", idle: &IdleDisconnect{ThresholdBytesPerSec: 100, Minutes: 30, WarningSeconds: maxIdleWarningSeconds + 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile("Office")
+			p.Host = "vpn.example.com"
+			p.Username = "alice"
+			p.IdleDisconnect = tt.idle
+
+			err := p.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestIdleDisconnect_Durations(t *testing.T) {
+	d := &IdleDisconnect{ThresholdBytesPerSec: 100, Minutes: 30}
+	assert.Equal(t, 30*time.Minute, d.IdleAfter())
+	assert.Equal(t, DefaultIdleWarning, d.Warning())
+
+	d.WarningSeconds = 120
+	assert.Equal(t, 2*time.Minute, d.Warning())
+}
+
+func TestResolve_CopiesIdleDisconnect(t *testing.T) {
+	p := NewProfile("Office")
+	p.IdleDisconnect = &IdleDisconnect{ThresholdBytesPerSec: 100, Minutes: 30}
+
+	resolved, err := Resolve(p, nil)
+	require.NoError(t, err)
+	resolved.IdleDisconnect.Minutes = 5
+	assert.Equal(t, 30, p.IdleDisconnect.Minutes)
+}
+
+func TestResolve_InheritsIdleDisconnect(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	fields := systemTestFields()
+	fields["is_template"] = true
+	fields["idle_disconnect"] = map[string]any{"threshold_bytes_per_sec": 1024, "minutes": 30}
+	writeSystemProfile(t, systemDir, fields)
+
+	child := NewProfile("Mine")
+	child.ParentID = systemTestID
+	resolved, err := Resolve(child, store)
+	require.NoError(t, err)
+	require.NotNil(t, resolved.IdleDisconnect)
+	assert.Equal(t, 30, resolved.IdleDisconnect.Minutes)
+
+	fields["locked"] = []string{"idle_disconnect"}
+	writeSystemProfile(t, systemDir, fields)
+	child.IdleDisconnect = &IdleDisconnect{ThresholdBytesPerSec: 1024, Minutes: 600}
+	child.SetOverridden("idle_disconnect", true)
+	resolved, err = Resolve(child, store)
+	require.NoError(t, err)
+	assert.Equal(t, 30, resolved.IdleDisconnect.Minutes, "a locked idle rule cannot be overridden")
+}
@@ -0,0 +1,38 @@
+package profile
+
+import "fmt"
+
+// ImportWarning describes a setting from an external configuration that could not be
+// represented in a Profile, or an entry that was skipped during import.
+type ImportWarning struct {
+	// Field is the name of the setting in the source format (e.g., "ipsecvpn", "on_connect").
+	Field string
+	// Reason explains why the setting was not imported.
+	Reason string
+}
+
+// String returns a human-readable description of the warning.
+func (w ImportWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Field, w.Reason)
+}
+
+// ImportedProfile is a profile converted from an external configuration format.
+// Imported profiles have a fresh ID and are not saved; callers decide which ones to
+// persist via Store.Save. They may be incomplete (e.g., missing certificate paths),
+// in which case Warnings explains what needs to be filled in.
+type ImportedProfile struct {
+	// Profile is the converted profile.
+	Profile *Profile
+	// Source identifies the entry in the source file (e.g., the tunnel name).
+	Source string
+	// Warnings lists settings of this entry that could not be represented.
+	Warnings []ImportWarning
+}
+
+// ImportResult contains all profiles converted from a source file.
+type ImportResult struct {
+	// Profiles are the converted entries in source file order.
+	Profiles []ImportedProfile
+	// Warnings lists file-level issues, such as skipped entries.
+	Warnings []ImportWarning
+}
@@ -0,0 +1,51 @@
+package profile
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+)
+
+// maxAllowedLANs bounds the number of LAN ranges reachable outside the tunnel.
+const maxAllowedLANs = 20
+
+// KillSwitch blocks all traffic outside the tunnel while the profile is active,
+// including while it reconnects, so nothing leaks when the tunnel drops.
+// Only the profile's gateways, loopback and the allowed LAN ranges stay reachable.
+type KillSwitch struct {
+	// AllowedLANs are the address ranges in CIDR notation, such as a printer
+	// or NAS network, that stay reachable outside the tunnel.
+	AllowedLANs []string `json:"allowed_lans,omitempty"`
+}
+
+// clone returns a deep copy of the kill switch, or nil if k is nil.
+func (k *KillSwitch) clone() *KillSwitch {
+	if k == nil {
+		return nil
+	}
+	return &KillSwitch{AllowedLANs: slices.Clone(k.AllowedLANs)}
+}
+
+// validateKillSwitch checks the profile's kill switch.
+func (p *Profile) validateKillSwitch() error {
+	k := p.KillSwitch
+	if k == nil {
+		return nil
+	}
+
+	if len(k.AllowedLANs) > maxAllowedLANs {
+		return fmt.Errorf("too many allowed LAN ranges (max %d)", maxAllowedLANs)
+	}
+	for _, lan := range k.AllowedLANs {
+		prefix, err := netip.ParsePrefix(lan)
+		if err != nil {
+			return fmt.Errorf("invalid allowed LAN range %q: %w", lan, err)
+		}
+		// A default route would let everything around the tunnel
+		if prefix.Bits() == 0 {
+			return fmt.Errorf("allowed LAN range %q covers all addresses", lan)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfile_ValidateKillSwitch(t *testing.T) {
+	tests := []struct {
+		name       string
+		killSwitch *KillSwitch
+		wantErr    bool
+	}{
+		{name: "no kill switch", killSwitch: nil},
+		{name: "no LANs", killSwitch: &KillSwitch{}},
+		{name: "IPv4 and IPv6 LANs", killSwitch: &KillSwitch{AllowedLANs: []string{"192.168.1.0/24", "fd00::/64"}}},
+		{name: "host address", killSwitch: &KillSwitch{AllowedLANs: []string{"192.168.1.20/32"}}},
+		{name: "missing prefix length", killSwitch: &KillSwitch{AllowedLANs: []string{"192.168.1.0"}}, wantErr: true},
+		{name: "host name", killSwitch: &KillSwitch{AllowedLANs: []string{"nas.local/24"}}, wantErr: true},
+		{name: "all addresses", killSwitch: &KillSwitch{AllowedLANs: []string{"0.0.0.0/0"}}, wantErr: true},
+		{name: "too many LANs", killSwitch: &KillSwitch{AllowedLANs: make([]string, maxAllowedLANs+1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile("Office")
+			p.Host = "vpn.example.com"
+			p.Username = "alice"
+			p.KillSwitch = tt.killSwitch
+
+			err := p.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestResolve_CopiesKillSwitch(t *testing.T) {
+	p := NewProfile("Office")
+	p.KillSwitch = &KillSwitch{AllowedLANs: []string{"192.168.1.0/24"}}
+
+	resolved, err := Resolve(p, nil)
+	require.NoError(t, err)
+	resolved.KillSwitch.AllowedLANs[0] = "10.0.0.0/8"
+	assert.Equal(t, "192.168.1.0/24", p.KillSwitch.AllowedLANs[0])
+}
+
+func TestResolve_InheritsKillSwitch(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	fields := systemTestFields()
+	fields["is_template"] = true
+	fields["kill_switch"] = map[string]any{"allowed_lans": []string{"192.168.1.0/24"}}
+	fields["locked"] = []string{"kill_switch"}
+	writeSystemProfile(t, systemDir, fields)
+
+	child := NewProfile("Mine")
+	child.ParentID = systemTestID
+	child.SetOverridden("kill_switch", true)
+
+	resolved, err := Resolve(child, store)
+	require.NoError(t, err)
+	require.NotNil(t, resolved.KillSwitch, "a locked kill switch cannot be switched off")
+	assert.Equal(t, []string{"192.168.1.0/24"}, resolved.KillSwitch.AllowedLANs)
+}
@@ -0,0 +1,122 @@
+package profile
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ProbeType is how a liveness probe checks that the tunnel passes traffic.
+type ProbeType string
+
+const (
+	// ProbeTCP opens a TCP connection to a host:port behind the VPN.
+	ProbeTCP ProbeType = "tcp"
+	// ProbeICMP sends an echo request to a host behind the VPN.
+	ProbeICMP ProbeType = "icmp"
+	// ProbeDNS resolves a name through the name server the VPN assigned.
+	ProbeDNS ProbeType = "dns"
+)
+
+const (
+	// DefaultProbeInterval is the time between probes when the profile does not set it.
+	DefaultProbeInterval = 30 * time.Second
+	// DefaultProbeTimeout bounds a single probe when the profile does not set it.
+	DefaultProbeTimeout = 5 * time.Second
+	// DefaultProbeFailures is how many probes in a row must fail before the
+	// tunnel is considered dead, when the profile does not set it.
+	DefaultProbeFailures = 3
+
+	// maxProbeIntervalSeconds bounds the configurable probe interval.
+	maxProbeIntervalSeconds = 3600
+	// maxProbeFailures bounds the configurable failure threshold.
+	maxProbeFailures = 100
+)
+
+// LivenessProbe checks periodically that an established tunnel still passes
+// traffic, since openfortivpn can keep running after the tunnel went dead.
+type LivenessProbe struct {
+	Type ProbeType `json:"type"`
+	// Target is the host:port to connect to for TCP, the host to ping for ICMP,
+	// and the name to look up for DNS.
+	Target string `json:"target"`
+	// IntervalSeconds is the time between probes. Zero uses DefaultProbeInterval.
+	IntervalSeconds int `json:"interval_seconds,omitempty"`
+	// TimeoutSeconds bounds a single probe. Zero uses DefaultProbeTimeout.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// Failures is how many probes in a row must fail before the tunnel is
+	// considered dead. Zero uses DefaultProbeFailures.
+	Failures int `json:"failures,omitempty"`
+}
+
+// clone returns a copy of the probe, or nil if l is nil.
+func (l *LivenessProbe) clone() *LivenessProbe {
+	if l == nil {
+		return nil
+	}
+	c := *l
+	return &c
+}
+
+// Interval returns the time between probes.
+func (l *LivenessProbe) Interval() time.Duration {
+	if l.IntervalSeconds == 0 {
+		return DefaultProbeInterval
+	}
+	return time.Duration(l.IntervalSeconds) * time.Second
+}
+
+// Timeout returns how long a single probe may take.
+func (l *LivenessProbe) Timeout() time.Duration {
+	if l.TimeoutSeconds == 0 {
+		return DefaultProbeTimeout
+	}
+	return time.Duration(l.TimeoutSeconds) * time.Second
+}
+
+// FailureThreshold returns how many probes in a row must fail before the tunnel is considered dead.
+func (l *LivenessProbe) FailureThreshold() int {
+	if l.Failures == 0 {
+		return DefaultProbeFailures
+	}
+	return l.Failures
+}
+
+// validateLivenessProbe checks the profile's liveness probe.
+func (p *Profile) validateLivenessProbe() error {
+	l := p.LivenessProbe
+	if l == nil {
+		return nil
+	}
+
+	target := strings.TrimSpace(l.Target)
+	switch l.Type {
+	case ProbeTCP:
+		host, port, err := net.SplitHostPort(target)
+		if err != nil || host == "" || port == "" {
+			return fmt.Errorf("TCP liveness probe target must be host:port, got %q", l.Target)
+		}
+	case ProbeICMP, ProbeDNS:
+		if target == "" || strings.ContainsAny(target, " /") {
+			return fmt.Errorf("%s liveness probe target must be a host name, got %q", strings.ToUpper(string(l.Type)), l.Target)
+		}
+	default:
+		return fmt.Errorf("unknown liveness probe type %q", l.Type)
+	}
+
+	if l.IntervalSeconds < 0 || l.IntervalSeconds > maxProbeIntervalSeconds {
+		return fmt.Errorf("liveness probe interval must be between 0 and %d seconds, got %d", maxProbeIntervalSeconds, l.IntervalSeconds)
+	}
+	if l.TimeoutSeconds < 0 {
+		return fmt.Errorf("liveness probe timeout must not be negative, got %d", l.TimeoutSeconds)
+	}
+	if l.Timeout() > l.Interval() {
+		return fmt.Errorf("liveness probe timeout (%s) must not be longer than its interval (%s)", l.Timeout(), l.Interval())
+	}
+	if l.Failures < 0 || l.Failures > maxProbeFailures {
+		return fmt.Errorf("liveness probe failures must be between 0 and %d, got %d", maxProbeFailures, l.Failures)
+	}
+
+	return nil
+}
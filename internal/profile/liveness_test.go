@@ -0,0 +1,95 @@
+package profile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfile_ValidateLivenessProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		probe   *LivenessProbe
+		wantErr bool
+	}{
+		{name: "no probe", probe: nil},
+		{name: "tcp", probe: &LivenessProbe{Type: ProbeTCP, Target: "git.corp.example:443"}},
+		{name: "tcp IPv6", probe: &LivenessProbe{Type: ProbeTCP, Target: "[fd00::10]:22"}},
+		{name: "icmp", probe: &LivenessProbe{Type: ProbeICMP, Target: "10.0.0.1", IntervalSeconds: 10, TimeoutSeconds: 2, Failures: 5}},
+		{name: "dns", probe: &LivenessProbe{Type: ProbeDNS, Target: "intranet.corp.example"}},
+		{name: "unknown type", probe: &LivenessProbe{Type: "http", Target: "intranet"}, wantErr: true},
+		{name: "tcp without port", probe: &LivenessProbe{Type: ProbeTCP, Target: "git.corp.example"}, wantErr: true},
+		{name: "tcp without host", probe: &LivenessProbe{Type: ProbeTCP, Target: ":443"}, wantErr: true},
+		{name: "empty target", probe: &LivenessProbe{Type: ProbeICMP, Target: " "}, wantErr: true},
+		{name: "URL as host", probe: &LivenessProbe{Type: ProbeDNS, Target: "https://intranet"}, wantErr: true},
+		{name: "negative interval", probe: &LivenessProbe{Type: ProbeICMP, Target: "10.0.0.1", IntervalSeconds: -1}, wantErr: true},
+		{name: "interval too long", probe: &LivenessProbe{Type: ProbeICMP, Target: "10.0.0.1", IntervalSeconds: maxProbeIntervalSeconds + 1}, wantErr: true},
+		{name: "negative timeout", probe: &LivenessProbe{Type: ProbeICMP, Target: "10.0.0.1", TimeoutSeconds: -1}, wantErr: true},
+		{name: "timeout longer than interval", probe: &LivenessProbe{Type: ProbeICMP, Target: "10.0.0.1", IntervalSeconds: 5, TimeoutSeconds: 10}, wantErr: true},
+		{name: "default timeout longer than interval", probe: &LivenessProbe{Type: ProbeICMP, Target: "10.0.0.1", IntervalSeconds: 2}, wantErr: true},
+		{name: "too many failures", probe: &LivenessProbe{Type: ProbeICMP, Target: "10.0.0.1", Failures: maxProbeFailures + 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile("Office")
+			p.Host = "vpn.example.com"
+			p.Username = "alice"
+			p.LivenessProbe = tt.probe
+
+			err := p.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLivenessProbe_Defaults(t *testing.T) {
+	l := &LivenessProbe{Type: ProbeTCP, Target: "git:22"}
+	assert.Equal(t, DefaultProbeInterval, l.Interval())
+	assert.Equal(t, DefaultProbeTimeout, l.Timeout())
+	assert.Equal(t, DefaultProbeFailures, l.FailureThreshold())
+
+	l = &LivenessProbe{IntervalSeconds: 10, TimeoutSeconds: 2, Failures: 5}
+	assert.Equal(t, 10*time.Second, l.Interval())
+	assert.Equal(t, 2*time.Second, l.Timeout())
+	assert.Equal(t, 5, l.FailureThreshold())
+}
+
+func TestResolve_CopiesLivenessProbe(t *testing.T) {
+	p := NewProfile("Office")
+	p.LivenessProbe = &LivenessProbe{Type: ProbeTCP, Target: "git:22"}
+
+	resolved, err := Resolve(p, nil)
+	require.NoError(t, err)
+	resolved.LivenessProbe.Target = "wiki:443"
+	assert.Equal(t, "git:22", p.LivenessProbe.Target)
+}
+
+func TestResolve_InheritsLivenessProbe(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	fields := systemTestFields()
+	fields["is_template"] = true
+	fields["liveness_probe"] = map[string]any{"type": "tcp", "target": "git.corp.example:22"}
+	writeSystemProfile(t, systemDir, fields)
+
+	child := NewProfile("Mine")
+	child.ParentID = systemTestID
+	resolved, err := Resolve(child, store)
+	require.NoError(t, err)
+	require.NotNil(t, resolved.LivenessProbe)
+	assert.Equal(t, "git.corp.example:22", resolved.LivenessProbe.Target)
+
+	fields["locked"] = []string{"liveness_probe"}
+	writeSystemProfile(t, systemDir, fields)
+	child.LivenessProbe = nil
+	child.SetOverridden("liveness_probe", true)
+	resolved, err = Resolve(child, store)
+	require.NoError(t, err)
+	assert.NotNil(t, resolved.LivenessProbe, "a locked probe cannot be switched off")
+}
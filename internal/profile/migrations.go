@@ -0,0 +1,49 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/shini4i/openfortivpn-gui/internal/migrate"
+)
+
+// profileMigrations upgrades profile files written by older versions.
+// Steps are only ever appended; each one upgrades by a single schema version.
+var profileMigrations = migrate.NewPipeline("profile",
+	migrate.Step{
+		Description: "fill in defaults for settings missing from the file",
+		Apply: func(doc migrate.Document) error {
+			// Profiles based on a template inherit missing settings instead
+			if parentID, _ := doc["parent_id"].(string); parentID != "" {
+				return nil
+			}
+			doc.SetDefault("port", 443)
+			doc.SetDefault("auth_method", string(AuthMethodPassword))
+			doc.SetDefault("set_dns", true)
+			doc.SetDefault("set_routes", true)
+			doc.SetDefault("auto_reconnect", true)
+			return nil
+		},
+	},
+)
+
+// CurrentSchemaVersion returns the profile schema version written by this version of the application.
+func CurrentSchemaVersion() int {
+	return profileMigrations.Current()
+}
+
+// Decode decodes a profile kept outside the store, such as in a backup archive.
+// Data written by older versions is migrated as the store migrates its files;
+// data written by a newer version is rejected with an error wrapping migrate.ErrNewerVersion.
+func Decode(data []byte) (*Profile, error) {
+	migrated, _, _, err := profileMigrations.Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Profile
+	if err := json.Unmarshal(migrated, &p); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profile: %w", err)
+	}
+	return &p, nil
+}
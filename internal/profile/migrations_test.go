@@ -0,0 +1,153 @@
+package profile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/migrate"
+)
+
+const migrationTestID = "550e8400-e29b-41d4-a716-446655440000"
+
+func writeProfileFile(t *testing.T, dir, id, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, id+".json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestStore_Load_MigratesUnversionedProfile(t *testing.T) {
+	dir := t.TempDir()
+	original := `{"id": "` + migrationTestID + `", "name": "Old", "host": "vpn.example.com", "username": "user"}`
+	path := writeProfileFile(t, dir, migrationTestID, original)
+
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+
+	p, err := store.Load(migrationTestID)
+	require.NoError(t, err)
+
+	// Settings missing from the old file get their defaults instead of zero values
+	assert.Equal(t, CurrentSchemaVersion(), p.SchemaVersion)
+	assert.Equal(t, 443, p.Port)
+	assert.Equal(t, AuthMethodPassword, p.AuthMethod)
+	assert.True(t, p.SetDNS)
+	assert.True(t, p.SetRoutes)
+	assert.True(t, p.AutoReconnect)
+	assert.False(t, p.HalfInternetRoutes)
+
+	// The file is rewritten and the original kept as a backup
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, revision(data), p.Revision)
+	var stored map[string]any
+	require.NoError(t, json.Unmarshal(data, &stored))
+	assert.Equal(t, float64(CurrentSchemaVersion()), stored["schema_version"])
+
+	backup, err := os.ReadFile(migrate.BackupPath(path, 0))
+	require.NoError(t, err)
+	assert.Equal(t, original, string(backup))
+
+	// Backups are not listed as profiles
+	result, err := store.List()
+	require.NoError(t, err)
+	assert.Len(t, result.Profiles, 1)
+	assert.Empty(t, result.Errors)
+}
+
+func TestStore_List_MigratesOutdatedProfiles(t *testing.T) {
+	dir := t.TempDir()
+	original := `{"id": "` + migrationTestID + `", "name": "Old", "host": "vpn.example.com", "username": "user"}`
+	path := writeProfileFile(t, dir, migrationTestID, original)
+
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+
+	result, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, result.Profiles, 1)
+	assert.Empty(t, result.Errors)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, revision(data), result.Profiles[0].Revision)
+	_, err = os.Stat(migrate.BackupPath(path, 0))
+	require.NoError(t, err)
+
+	// The listed revision is current, so saving it does not conflict
+	require.NoError(t, store.Save(result.Profiles[0]))
+}
+
+func TestStore_Load_MigrationKeepsExplicitValues(t *testing.T) {
+	dir := t.TempDir()
+	writeProfileFile(t, dir, migrationTestID,
+		`{"id": "`+migrationTestID+`", "name": "Old", "port": 10443, "set_dns": false, "auto_reconnect": false}`)
+
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+
+	p, err := store.Load(migrationTestID)
+	require.NoError(t, err)
+	assert.Equal(t, 10443, p.Port)
+	assert.False(t, p.SetDNS)
+	assert.False(t, p.AutoReconnect)
+}
+
+func TestStore_Load_MigrationKeepsInheritedSettingsUnset(t *testing.T) {
+	dir := t.TempDir()
+	writeProfileFile(t, dir, migrationTestID,
+		`{"id": "`+migrationTestID+`", "name": "Child", "parent_id": "550e8400-e29b-41d4-a716-446655440001", "host": "branch.example.com"}`)
+
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+
+	p, err := store.Load(migrationTestID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"host"}, p.Overrides)
+	assert.False(t, p.IsOverridden("port"))
+}
+
+func TestStore_Load_RejectsNewerSchemaVersion(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"schema_version": 999, "id": "` + migrationTestID + `", "name": "Future"}`
+	path := writeProfileFile(t, dir, migrationTestID, content)
+
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+
+	_, err = store.Load(migrationTestID)
+	require.ErrorIs(t, err, migrate.ErrNewerVersion)
+
+	result, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, result.Profiles)
+	require.Len(t, result.Errors, 1)
+	assert.ErrorIs(t, result.Errors[0], migrate.ErrNewerVersion)
+
+	// The file is left untouched
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestStore_Save_WritesSchemaVersion(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	p := NewProfile("New")
+	require.NoError(t, store.Save(p))
+	assert.Zero(t, p.SchemaVersion, "Save must not modify the caller's profile")
+
+	loaded, err := store.Load(p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion(), loaded.SchemaVersion)
+
+	backups, err := filepath.Glob(filepath.Join(store.baseDir, "*.bak"))
+	require.NoError(t, err)
+	assert.Empty(t, backups, "current files must not be backed up")
+}
@@ -0,0 +1,426 @@
+package profile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/shini4i/openfortivpn-gui/internal/fileutil"
+)
+
+// NetworkManagerServiceType identifies NetworkManager-fortisslvpn VPN connections.
+const NetworkManagerServiceType = "org.freedesktop.NetworkManager.fortisslvpn"
+
+// NetworkManagerSystemConnectionsDir is where NetworkManager stores system-wide keyfiles.
+const NetworkManagerSystemConnectionsDir = "/etc/NetworkManager/system-connections"
+
+// maxKeyfileSize limits the size of NetworkManager keyfiles read during import.
+const maxKeyfileSize = 1024 * 1024
+
+var (
+	// ErrNotFortiSSLVPN is returned when a keyfile is not a NetworkManager-fortisslvpn connection.
+	ErrNotFortiSSLVPN = errors.New("keyfile is not a NetworkManager-fortisslvpn connection")
+
+	// ErrNoNetworkManagerConnections is returned when a directory contains no
+	// NetworkManager-fortisslvpn keyfiles.
+	ErrNoNetworkManagerConnections = errors.New("no NetworkManager-fortisslvpn connections found")
+)
+
+// networkManagerIgnoredKeys are [vpn] keys that are consumed implicitly or only affect
+// how NetworkManager stores secrets, so they are dropped silently.
+var networkManagerIgnoredKeys = map[string]bool{
+	"service-type":    true,
+	"password-flags":  true,
+	"cert-pass-flags": true,
+}
+
+// ImportNetworkManagerFile reads a single NetworkManager keyfile from disk.
+func ImportNetworkManagerFile(path string) (*ImportResult, error) {
+	imported, err := readNetworkManagerFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ImportResult{Profiles: []ImportedProfile{*imported}}, nil
+}
+
+// ImportNetworkManagerDir imports every NetworkManager-fortisslvpn keyfile in dir.
+// Keyfiles of other connection types are skipped silently; files that cannot be read
+// or parsed are reported in ImportResult.Warnings. Subdirectories are not searched.
+func ImportNetworkManagerDir(dir string) (*ImportResult, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connections directory: %w", err)
+	}
+
+	result := &ImportResult{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		imported, err := readNetworkManagerFile(filepath.Join(dir, entry.Name()))
+		if errors.Is(err, ErrNotFortiSSLVPN) {
+			continue
+		}
+		if err != nil {
+			result.Warnings = append(result.Warnings, ImportWarning{
+				Field:  entry.Name(),
+				Reason: fmt.Sprintf("%s skipped: %v", entry.Name(), err),
+			})
+			continue
+		}
+		result.Profiles = append(result.Profiles, *imported)
+	}
+
+	if len(result.Profiles) == 0 {
+		// System keyfiles are usually readable by root only; say so instead of
+		// reporting an empty directory.
+		if len(result.Warnings) > 0 {
+			return nil, fmt.Errorf("%w (%d file(s) could not be read, e.g. %s)",
+				ErrNoNetworkManagerConnections, len(result.Warnings), result.Warnings[0].Reason)
+		}
+		return nil, ErrNoNetworkManagerConnections
+	}
+
+	return result, nil
+}
+
+// readNetworkManagerFile opens and parses a keyfile, using the file name for
+// connections without an id.
+func readNetworkManagerFile(path string) (*ImportedProfile, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is chosen by the user in the import dialog
+	if err != nil {
+		return nil, fmt.Errorf("failed to open NetworkManager keyfile: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	name := strings.TrimSuffix(filepath.Base(path), ".nmconnection")
+	return ParseNetworkManagerKeyfile(f, name)
+}
+
+// ParseNetworkManagerKeyfile converts a NetworkManager-fortisslvpn keyfile into a profile.
+// The connection id is used as the profile name; defaultName is used if it is missing.
+//
+// Secrets stored in the [vpn-secrets] section are never imported; they are reported
+// as warnings along with unsupported vpn.data keys.
+func ParseNetworkManagerKeyfile(r io.Reader, defaultName string) (*ImportedProfile, error) {
+	kf, err := parseKeyfile(io.LimitReader(r, maxKeyfileSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if kf.get("connection", "type") != "vpn" || kf.get("vpn", "service-type") != NetworkManagerServiceType {
+		return nil, ErrNotFortiSSLVPN
+	}
+
+	name := kf.get("connection", "id")
+	if name == "" {
+		name = defaultName
+	}
+
+	p := NewProfile(name)
+	imported := &ImportedProfile{Profile: p, Source: name}
+	hasOTP := false
+
+	for _, entry := range kf.sections["vpn"] {
+		switch entry.key {
+		case "gateway":
+			host, port, err := parseNetworkManagerGateway(entry.value)
+			if err != nil {
+				imported.Warnings = append(imported.Warnings, ImportWarning{Field: entry.key, Reason: err.Error()})
+				continue
+			}
+			p.Host = host
+			if port != 0 {
+				p.Port = port
+			}
+		case "user":
+			p.Username = entry.value
+		case "realm":
+			p.Realm = entry.value
+		case "trusted-cert":
+			p.TrustedCert = entry.value
+		case "cert":
+			p.ClientCertPath = entry.value
+		case "key":
+			p.ClientKeyPath = entry.value
+		case "otp", "otp-flags":
+			hasOTP = true
+		case "2fa":
+			hasOTP = hasOTP || keyfileBool(entry.value)
+		default:
+			if networkManagerIgnoredKeys[entry.key] {
+				continue
+			}
+			imported.Warnings = append(imported.Warnings, ImportWarning{
+				Field:  entry.key,
+				Reason: "setting is not supported and was not imported",
+			})
+		}
+	}
+
+	for _, entry := range kf.sections["vpn-secrets"] {
+		if entry.key == "otp" {
+			hasOTP = true
+		}
+		imported.Warnings = append(imported.Warnings, ImportWarning{
+			Field:  entry.key,
+			Reason: "saved secrets are not imported; you will be asked for them when connecting",
+		})
+	}
+
+	if kf.get("vpn", "gateway") == "" {
+		imported.Warnings = append(imported.Warnings, ImportWarning{
+			Field:  "gateway",
+			Reason: "gateway is not set",
+		})
+	}
+
+	// NetworkManager only disables these when explicitly asked to ignore the
+	// DNS servers and routes pushed by the gateway.
+	p.SetDNS = !keyfileBool(kf.get("ipv4", "ignore-auto-dns"))
+	p.SetRoutes = !keyfileBool(kf.get("ipv4", "ignore-auto-routes"))
+
+	switch {
+	case p.ClientCertPath != "" || p.ClientKeyPath != "":
+		p.AuthMethod = AuthMethodCertificate
+	case hasOTP:
+		p.AuthMethod = AuthMethodOTP
+	default:
+		p.AuthMethod = AuthMethodPassword
+	}
+
+	return imported, nil
+}
+
+// parseNetworkManagerGateway splits a gateway value ("host" or "host:port") into
+// host and port. A zero port means the value did not specify one.
+func parseNetworkManagerGateway(gateway string) (string, int, error) {
+	gateway = strings.TrimSpace(gateway)
+	if gateway == "" {
+		return "", 0, errors.New("gateway is empty")
+	}
+
+	host, portStr, err := net.SplitHostPort(gateway)
+	if err != nil {
+		// No port given
+		return strings.Trim(gateway, "[]"), 0, nil
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in gateway %q", gateway)
+	}
+	return host, port, nil
+}
+
+// WriteNetworkManagerKeyfile writes the profile as a NetworkManager-fortisslvpn keyfile.
+// SECURITY: Passwords are never written; the password is marked as owned by the
+// secret agent so NetworkManager asks for it when connecting.
+// The profile ID is reused as the connection UUID. Settings without a
+// NetworkManager equivalent (half-internet routes, FortiToken push) are omitted.
+func WriteNetworkManagerKeyfile(w io.Writer, p *Profile) error {
+	connUUID := p.ID
+	if _, err := uuid.Parse(connUUID); err != nil {
+		connUUID = uuid.New().String()
+	}
+
+	gateway := p.Host
+	if p.Port != 0 && p.Port != 443 {
+		gateway = net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+	}
+
+	kf := &keyfile{}
+	kf.set("connection", "id", p.Name)
+	kf.set("connection", "uuid", connUUID)
+	kf.set("connection", "type", "vpn")
+	kf.set("connection", "autoconnect", "false")
+
+	kf.set("vpn", "gateway", gateway)
+	kf.set("vpn", "realm", p.Realm)
+	kf.set("vpn", "trusted-cert", p.TrustedCert)
+	switch p.AuthMethod {
+	case AuthMethodPassword, AuthMethodOTP:
+		kf.set("vpn", "user", p.Username)
+		kf.set("vpn", "password-flags", "1")
+		if p.AuthMethod == AuthMethodOTP {
+			// Not saved: NetworkManager asks for a fresh code on every connection
+			kf.set("vpn", "otp-flags", "2")
+		}
+	case AuthMethodCertificate:
+		kf.set("vpn", "cert", p.ClientCertPath)
+		kf.set("vpn", "key", p.ClientKeyPath)
+	}
+	kf.set("vpn", "service-type", NetworkManagerServiceType)
+
+	kf.set("ipv4", "method", "auto")
+	if !p.SetDNS {
+		kf.set("ipv4", "ignore-auto-dns", "true")
+	}
+	if !p.SetRoutes {
+		kf.set("ipv4", "ignore-auto-routes", "true")
+	}
+	kf.set("ipv6", "method", "auto")
+
+	var buf bytes.Buffer
+	if p.AuthMethod == AuthMethodSAML {
+		buf.WriteString("# SAML/SSO authentication is not supported by NetworkManager-fortisslvpn\n\n")
+	}
+	if err := kf.write(&buf); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ExportNetworkManagerFile writes the profile as a NetworkManager keyfile.
+// The file is written atomically with owner-only permissions, which NetworkManager
+// requires before it loads a keyfile.
+func ExportNetworkManagerFile(path string, p *Profile) error {
+	var buf bytes.Buffer
+	if err := WriteNetworkManagerKeyfile(&buf, p); err != nil {
+		return err
+	}
+
+	if err := fileutil.AtomicWrite(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write NetworkManager keyfile: %w", err)
+	}
+	return nil
+}
+
+// keyfile is a minimal GKeyFile representation preserving section and key order.
+type keyfile struct {
+	order    []string
+	sections map[string][]configEntry
+}
+
+// parseKeyfile reads a GKeyFile-formatted document as used by NetworkManager.
+func parseKeyfile(r io.Reader) (*keyfile, error) {
+	kf := &keyfile{}
+	section := ""
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section == "" {
+			return nil, fmt.Errorf("invalid keyfile: malformed line %d", lineNum)
+		}
+		kf.set(section, strings.TrimSpace(key), unescapeKeyfileValue(strings.TrimSpace(value)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NetworkManager keyfile: %w", err)
+	}
+
+	return kf, nil
+}
+
+// get returns the value of key in section, or an empty string if it is not set.
+func (kf *keyfile) get(section, key string) string {
+	for _, entry := range kf.sections[section] {
+		if entry.key == key {
+			return entry.value
+		}
+	}
+	return ""
+}
+
+// set appends key to section. Empty values are skipped.
+func (kf *keyfile) set(section, key, value string) {
+	if value == "" {
+		return
+	}
+	if kf.sections == nil {
+		kf.sections = make(map[string][]configEntry)
+	}
+	if _, ok := kf.sections[section]; !ok {
+		kf.order = append(kf.order, section)
+	}
+	kf.sections[section] = append(kf.sections[section], configEntry{key, value})
+}
+
+// write serializes the keyfile. Returns an error if a value contains a line break,
+// which would inject extra keys.
+func (kf *keyfile) write(w io.Writer) error {
+	var buf bytes.Buffer
+	for i, section := range kf.order {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "[%s]\n", section)
+		for _, entry := range kf.sections[section] {
+			if strings.ContainsAny(entry.value, "\r\n") {
+				return fmt.Errorf("value for %s.%s contains a line break", section, entry.key)
+			}
+			fmt.Fprintf(&buf, "%s=%s\n", entry.key, escapeKeyfileValue(entry.value))
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// escapeKeyfileValue applies GKeyFile escaping to a value without line breaks.
+func escapeKeyfileValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\t", `\t`)
+	if strings.HasPrefix(value, " ") {
+		value = `\s` + value[1:]
+	}
+	return value
+}
+
+// unescapeKeyfileValue reverses GKeyFile escaping.
+func unescapeKeyfileValue(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 's':
+			b.WriteByte(' ')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// keyfileBool interprets GKeyFile boolean values.
+func keyfileBool(value string) bool {
+	return value == "true" || value == "1" || value == "yes"
+}
@@ -0,0 +1,237 @@
+package profile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const networkManagerKeyfile = `[connection]
+id=Office VPN
+uuid=9a1f0c3e-6b1d-4f5e-8e0a-2c7d1b3f4a5e
+type=vpn
+autoconnect=false
+
+[vpn]
+gateway=vpn.example.com:10443
+user=jdoe
+realm=staff
+trusted-cert=e46d4aff08ba6914e64daa85bc6112a422fa7ce16631bff0b592a28556f993db
+otp-flags=2
+password-flags=1
+persistent=yes
+service-type=org.freedesktop.NetworkManager.fortisslvpn
+
+[vpn-secrets]
+password=s3cret
+
+[ipv4]
+method=auto
+ignore-auto-dns=true
+
+[ipv6]
+method=auto
+`
+
+func TestParseNetworkManagerKeyfile(t *testing.T) {
+	imported, err := ParseNetworkManagerKeyfile(strings.NewReader(networkManagerKeyfile), "office")
+	require.NoError(t, err)
+
+	p := imported.Profile
+	assert.Equal(t, "Office VPN", p.Name)
+	assert.Equal(t, "Office VPN", imported.Source)
+	assert.NotEqual(t, "9a1f0c3e-6b1d-4f5e-8e0a-2c7d1b3f4a5e", p.ID)
+	assert.Equal(t, "vpn.example.com", p.Host)
+	assert.Equal(t, 10443, p.Port)
+	assert.Equal(t, "jdoe", p.Username)
+	assert.Equal(t, "staff", p.Realm)
+	assert.Equal(t, "e46d4aff08ba6914e64daa85bc6112a422fa7ce16631bff0b592a28556f993db", p.TrustedCert)
+	assert.Equal(t, AuthMethodOTP, p.AuthMethod)
+	assert.False(t, p.SetDNS)
+	assert.True(t, p.SetRoutes)
+	assert.NoError(t, p.Validate())
+
+	fields := make([]string, 0, len(imported.Warnings))
+	for _, w := range imported.Warnings {
+		fields = append(fields, w.Field)
+	}
+	assert.Equal(t, []string{"persistent", "password"}, fields)
+}
+
+func TestParseNetworkManagerKeyfile_Variants(t *testing.T) {
+	tests := []struct {
+		name     string
+		vpn      string
+		wantHost string
+		wantPort int
+		wantAuth AuthMethod
+	}{
+		{
+			name:     "gateway without port",
+			vpn:      "gateway=vpn.example.com\nuser=jdoe\n",
+			wantHost: "vpn.example.com",
+			wantPort: 443,
+			wantAuth: AuthMethodPassword,
+		},
+		{
+			name:     "IPv6 gateway",
+			vpn:      "gateway=[2001:db8::1]:8443\nuser=jdoe\n",
+			wantHost: "2001:db8::1",
+			wantPort: 8443,
+			wantAuth: AuthMethodPassword,
+		},
+		{
+			name:     "certificate",
+			vpn:      "gateway=10.0.0.1\ncert=/home/jdoe/cert.pem\nkey=/home/jdoe/key.pem\n",
+			wantHost: "10.0.0.1",
+			wantPort: 443,
+			wantAuth: AuthMethodCertificate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyfile := "[connection]\nid=Test\ntype=vpn\n\n[vpn]\n" + tt.vpn +
+				"service-type=org.freedesktop.NetworkManager.fortisslvpn\n"
+
+			imported, err := ParseNetworkManagerKeyfile(strings.NewReader(keyfile), "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHost, imported.Profile.Host)
+			assert.Equal(t, tt.wantPort, imported.Profile.Port)
+			assert.Equal(t, tt.wantAuth, imported.Profile.AuthMethod)
+			assert.Empty(t, imported.Warnings)
+		})
+	}
+}
+
+func TestParseNetworkManagerKeyfile_NotFortiSSLVPN(t *testing.T) {
+	wifi := "[connection]\nid=Home\ntype=wifi\n\n[wifi]\nssid=home\n"
+	_, err := ParseNetworkManagerKeyfile(strings.NewReader(wifi), "")
+	assert.ErrorIs(t, err, ErrNotFortiSSLVPN)
+
+	openvpn := "[connection]\nid=Other\ntype=vpn\n\n[vpn]\nservice-type=org.freedesktop.NetworkManager.openvpn\n"
+	_, err = ParseNetworkManagerKeyfile(strings.NewReader(openvpn), "")
+	assert.ErrorIs(t, err, ErrNotFortiSSLVPN)
+}
+
+func TestParseNetworkManagerKeyfile_Malformed(t *testing.T) {
+	_, err := ParseNetworkManagerKeyfile(strings.NewReader("gateway=vpn.example.com\n"), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "malformed line 1")
+}
+
+func TestWriteNetworkManagerKeyfile(t *testing.T) {
+	p := NewProfile("Office VPN")
+	p.Host = "vpn.example.com"
+	p.Port = 10443
+	p.Username = "jdoe"
+	p.Realm = "staff"
+	p.AuthMethod = AuthMethodOTP
+	p.SetRoutes = false
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteNetworkManagerKeyfile(&buf, p))
+	out := buf.String()
+
+	assert.Contains(t, out, "[connection]\nid=Office VPN\nuuid="+p.ID+"\ntype=vpn\n")
+	assert.Contains(t, out, "gateway=vpn.example.com:10443\n")
+	assert.Contains(t, out, "user=jdoe\n")
+	assert.Contains(t, out, "realm=staff\n")
+	assert.Contains(t, out, "otp-flags=2\n")
+	assert.Contains(t, out, "service-type=org.freedesktop.NetworkManager.fortisslvpn\n")
+	assert.Contains(t, out, "ignore-auto-routes=true\n")
+	assert.NotContains(t, out, "ignore-auto-dns")
+	assert.NotContains(t, out, "vpn-secrets")
+	assert.NotContains(t, out, "password=")
+}
+
+func TestWriteNetworkManagerKeyfile_RoundTrip(t *testing.T) {
+	original := NewProfile(" Lab\\Test")
+	original.Host = "10.0.0.1"
+	original.AuthMethod = AuthMethodCertificate
+	original.ClientCertPath = "/home/jdoe/cert.pem"
+	original.ClientKeyPath = "/home/jdoe/key.pem"
+	original.TrustedCert = "abc123"
+	original.SetDNS = false
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteNetworkManagerKeyfile(&buf, original))
+	assert.NotContains(t, buf.String(), "gateway=10.0.0.1:443")
+
+	imported, err := ParseNetworkManagerKeyfile(&buf, "")
+	require.NoError(t, err)
+	assert.Empty(t, imported.Warnings)
+
+	p := imported.Profile
+	assert.Equal(t, original.Name, p.Name)
+	assert.Equal(t, original.Host, p.Host)
+	assert.Equal(t, original.Port, p.Port)
+	assert.Equal(t, original.AuthMethod, p.AuthMethod)
+	assert.Equal(t, original.ClientCertPath, p.ClientCertPath)
+	assert.Equal(t, original.ClientKeyPath, p.ClientKeyPath)
+	assert.Equal(t, original.TrustedCert, p.TrustedCert)
+	assert.Equal(t, original.SetDNS, p.SetDNS)
+	assert.Equal(t, original.SetRoutes, p.SetRoutes)
+}
+
+func TestWriteNetworkManagerKeyfile_RejectsLineBreaks(t *testing.T) {
+	p := NewProfile("Injected\n[vpn-secrets]")
+	p.Host = "vpn.example.com"
+	p.Username = "jdoe"
+
+	var buf bytes.Buffer
+	require.Error(t, WriteNetworkManagerKeyfile(&buf, p))
+	assert.Empty(t, buf.String())
+}
+
+func TestImportNetworkManagerDir(t *testing.T) {
+	dir := t.TempDir()
+
+	office := NewProfile("Office")
+	office.Host = "vpn.example.com"
+	office.Username = "jdoe"
+	require.NoError(t, ExportNetworkManagerFile(filepath.Join(dir, "Office.nmconnection"), office))
+
+	info, err := os.Stat(filepath.Join(dir, "Office.nmconnection"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Legacy keyfiles have no extension
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Lab"),
+		[]byte("[connection]\ntype=vpn\n\n[vpn]\ngateway=10.0.0.1\nuser=jdoe\nservice-type="+NetworkManagerServiceType+"\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Home.nmconnection"),
+		[]byte("[connection]\nid=Home\ntype=wifi\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.nmconnection"), []byte("not a keyfile\n"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0700))
+
+	result, err := ImportNetworkManagerDir(dir)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(result.Profiles))
+	for _, imported := range result.Profiles {
+		names = append(names, imported.Profile.Name)
+	}
+	assert.ElementsMatch(t, []string{"Office", "Lab"}, names)
+
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, "broken.nmconnection", result.Warnings[0].Field)
+}
+
+func TestImportNetworkManagerDir_NoConnections(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Home.nmconnection"),
+		[]byte("[connection]\nid=Home\ntype=wifi\n"), 0600))
+
+	_, err := ImportNetworkManagerDir(dir)
+	assert.ErrorIs(t, err, ErrNoNetworkManagerConnections)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.nmconnection"), []byte("garbage\n"), 0600))
+	_, err = ImportNetworkManagerDir(dir)
+	require.ErrorIs(t, err, ErrNoNetworkManagerConnections)
+	assert.Contains(t, err.Error(), "could not be read")
+}
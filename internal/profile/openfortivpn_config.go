@@ -0,0 +1,250 @@
+package profile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shini4i/openfortivpn-gui/internal/fileutil"
+)
+
+// ErrConfigMissingHost is returned when an openfortivpn config file does not define a host.
+var ErrConfigMissingHost = errors.New("openfortivpn config does not define a host")
+
+// maxConfigFileSize limits the size of openfortivpn config files read during import.
+const maxConfigFileSize = 1024 * 1024
+
+// openfortivpnConfigName is the conventional file name of openfortivpn configs
+// (e.g., /etc/openfortivpn/config). Such files are named after their host on import.
+const openfortivpnConfigName = "config"
+
+// ImportOpenfortivpnConfigFile reads an openfortivpn config file from disk.
+// The profile is named after the file, or after the host for files named "config".
+func ImportOpenfortivpnConfigFile(path string) (*ImportResult, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is chosen by the user in the import dialog
+	if err != nil {
+		return nil, fmt.Errorf("failed to open openfortivpn config: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if name == openfortivpnConfigName {
+		name = ""
+	}
+
+	imported, err := ParseOpenfortivpnConfig(f, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImportResult{Profiles: []ImportedProfile{*imported}}, nil
+}
+
+// ParseOpenfortivpnConfig converts an openfortivpn key=value config into a profile.
+// If name is empty, the profile is named after the host.
+//
+// Passwords and static OTP codes are never imported; they are reported as warnings
+// along with unsupported keys and malformed lines.
+func ParseOpenfortivpnConfig(r io.Reader, name string) (*ImportedProfile, error) {
+	p := NewProfile(name)
+	imported := &ImportedProfile{Profile: p}
+	hasOTP := false
+
+	scanner := bufio.NewScanner(io.LimitReader(r, maxConfigFileSize))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			imported.Warnings = append(imported.Warnings, ImportWarning{
+				Field:  fmt.Sprintf("line %d", lineNum),
+				Reason: "malformed line ignored (expected key = value)",
+			})
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "host":
+			p.Host = value
+		case "port":
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 1 || port > 65535 {
+				imported.Warnings = append(imported.Warnings, ImportWarning{
+					Field:  key,
+					Reason: fmt.Sprintf("invalid port %q, using default %d", value, p.Port),
+				})
+				continue
+			}
+			p.Port = port
+		case "username":
+			p.Username = value
+		case "realm":
+			p.Realm = value
+		case "trusted-cert":
+			// openfortivpn accepts several trusted-cert lines; profiles store one digest
+			if p.TrustedCert != "" {
+				imported.Warnings = append(imported.Warnings, ImportWarning{
+					Field:  key,
+					Reason: "only the first trusted certificate digest is imported",
+				})
+				continue
+			}
+			p.TrustedCert = value
+		case "user-cert":
+			p.ClientCertPath = value
+		case "user-key":
+			p.ClientKeyPath = value
+		case "set-dns":
+			p.SetDNS = configBool(value)
+		case "set-routes":
+			p.SetRoutes = configBool(value)
+		case "half-internet-routes":
+			p.HalfInternetRoutes = configBool(value)
+		case "no-ftm-push":
+			p.NoFTMPush = configBool(value)
+		case "saml-login":
+			p.AuthMethod = AuthMethodSAML
+		case "password":
+			imported.Warnings = append(imported.Warnings, ImportWarning{
+				Field:  key,
+				Reason: "passwords are not imported; you will be asked for it when connecting",
+			})
+		case "otp":
+			hasOTP = true
+			imported.Warnings = append(imported.Warnings, ImportWarning{
+				Field:  key,
+				Reason: "static one-time passwords are not imported; you will be asked for a code when connecting",
+			})
+		default:
+			imported.Warnings = append(imported.Warnings, ImportWarning{
+				Field:  key,
+				Reason: "setting is not supported and was not imported",
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read openfortivpn config: %w", err)
+	}
+
+	if p.Host == "" {
+		return nil, ErrConfigMissingHost
+	}
+
+	// Derive the auth method unless SAML was requested explicitly
+	if p.AuthMethod != AuthMethodSAML {
+		switch {
+		case p.ClientCertPath != "" || p.ClientKeyPath != "":
+			p.AuthMethod = AuthMethodCertificate
+		case hasOTP:
+			p.AuthMethod = AuthMethodOTP
+		default:
+			p.AuthMethod = AuthMethodPassword
+		}
+	}
+
+	if p.Name == "" {
+		p.Name = p.Host
+	}
+	imported.Source = p.Name
+
+	return imported, nil
+}
+
+// WriteOpenfortivpnConfig writes the profile as an openfortivpn key=value config.
+// SECURITY: Passwords are never written; openfortivpn prompts for them at connect time.
+// Returns an error if a value contains a line break, which would inject extra keys.
+func WriteOpenfortivpnConfig(w io.Writer, p *Profile) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# openfortivpn configuration exported from openfortivpn-gui profile %q\n", p.Name)
+
+	values := []configEntry{
+		{"host", p.Host},
+		{"port", strconv.Itoa(p.Port)},
+		{"realm", p.Realm},
+		{"trusted-cert", p.TrustedCert},
+	}
+
+	switch p.AuthMethod {
+	case AuthMethodPassword, AuthMethodOTP:
+		values = append(values, configEntry{"username", p.Username})
+	case AuthMethodCertificate:
+		values = append(values,
+			configEntry{"user-cert", p.ClientCertPath},
+			configEntry{"user-key", p.ClientKeyPath},
+		)
+	}
+
+	values = append(values,
+		configEntry{"set-dns", configBoolString(p.SetDNS)},
+		configEntry{"set-routes", configBoolString(p.SetRoutes)},
+		configEntry{"half-internet-routes", configBoolString(p.HalfInternetRoutes)},
+	)
+	if p.NoFTMPush {
+		values = append(values, configEntry{"no-ftm-push", "1"})
+	}
+	if p.AuthMethod == AuthMethodSAML {
+		values = append(values, configEntry{"saml-login", samlLoginPort})
+	}
+
+	for _, kv := range values {
+		if kv.value == "" {
+			continue
+		}
+		if strings.ContainsAny(kv.value, "\r\n") {
+			return fmt.Errorf("value for %s contains a line break", kv.key)
+		}
+		fmt.Fprintf(&buf, "%s = %s\n", kv.key, kv.value)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ExportOpenfortivpnConfigFile writes the profile as an openfortivpn config file.
+// The file is written atomically with owner-only permissions.
+func ExportOpenfortivpnConfigFile(path string, p *Profile) error {
+	var buf bytes.Buffer
+	if err := WriteOpenfortivpnConfig(&buf, p); err != nil {
+		return err
+	}
+
+	if err := fileutil.AtomicWrite(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write openfortivpn config: %w", err)
+	}
+	return nil
+}
+
+// samlLoginPort is the port openfortivpn listens on for the SAML login by default.
+const samlLoginPort = "8020"
+
+// configEntry is a single key = value line of an openfortivpn config.
+type configEntry struct {
+	key   string
+	value string
+}
+
+// configBool interprets openfortivpn boolean values ("1"/"0").
+func configBool(value string) bool {
+	return value == "1" || strings.EqualFold(value, "true") || strings.EqualFold(value, "yes")
+}
+
+// configBoolString formats a boolean the way openfortivpn configs expect it.
+func configBoolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
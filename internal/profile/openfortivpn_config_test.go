@@ -0,0 +1,218 @@
+package profile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const openfortivpnConfig = `# Company VPN
+host = vpn.example.com
+port = 10443
+username = jdoe
+password = s3cret
+realm = staff
+trusted-cert = e46d4aff08ba6914e64daa85bc6112a422fa7ce16631bff0b592a28556f993db
+trusted-cert = 5e3b1a0c9f7d
+set-dns = 0
+set-routes = 1
+half-internet-routes = 1
+pppd-use-peerdns = 1
+`
+
+func TestParseOpenfortivpnConfig(t *testing.T) {
+	imported, err := ParseOpenfortivpnConfig(strings.NewReader(openfortivpnConfig), "Company")
+	require.NoError(t, err)
+
+	p := imported.Profile
+	assert.Equal(t, "Company", p.Name)
+	assert.Equal(t, "Company", imported.Source)
+	assert.Equal(t, "vpn.example.com", p.Host)
+	assert.Equal(t, 10443, p.Port)
+	assert.Equal(t, "jdoe", p.Username)
+	assert.Equal(t, "staff", p.Realm)
+	assert.Equal(t, "e46d4aff08ba6914e64daa85bc6112a422fa7ce16631bff0b592a28556f993db", p.TrustedCert)
+	assert.Equal(t, AuthMethodPassword, p.AuthMethod)
+	assert.False(t, p.SetDNS)
+	assert.True(t, p.SetRoutes)
+	assert.True(t, p.HalfInternetRoutes)
+	assert.NoError(t, p.Validate())
+
+	fields := make([]string, 0, len(imported.Warnings))
+	for _, w := range imported.Warnings {
+		fields = append(fields, w.Field)
+	}
+	assert.ElementsMatch(t, []string{"password", "trusted-cert", "pppd-use-peerdns"}, fields)
+}
+
+func TestParseOpenfortivpnConfig_AuthMethods(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   AuthMethod
+	}{
+		{
+			name:   "certificate",
+			config: "host = vpn.example.com\nuser-cert = /etc/vpn/cert.pem\nuser-key = /etc/vpn/key.pem\n",
+			want:   AuthMethodCertificate,
+		},
+		{
+			name:   "otp",
+			config: "host = vpn.example.com\nusername = jdoe\notp = 123456\n",
+			want:   AuthMethodOTP,
+		},
+		{
+			name:   "saml",
+			config: "host = vpn.example.com\nsaml-login = 1\n",
+			want:   AuthMethodSAML,
+		},
+		{
+			name:   "password by default",
+			config: "host = vpn.example.com\nusername = jdoe\n",
+			want:   AuthMethodPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imported, err := ParseOpenfortivpnConfig(strings.NewReader(tt.config), "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, imported.Profile.AuthMethod)
+		})
+	}
+}
+
+func TestParseOpenfortivpnConfig_NamedAfterHost(t *testing.T) {
+	imported, err := ParseOpenfortivpnConfig(strings.NewReader("host = vpn.example.com\n"), "")
+	require.NoError(t, err)
+	assert.Equal(t, "vpn.example.com", imported.Profile.Name)
+}
+
+func TestParseOpenfortivpnConfig_Malformed(t *testing.T) {
+	config := "host = vpn.example.com\nthis is not valid\nport = abc\n"
+
+	imported, err := ParseOpenfortivpnConfig(strings.NewReader(config), "")
+	require.NoError(t, err)
+	assert.Equal(t, 443, imported.Profile.Port)
+
+	require.Len(t, imported.Warnings, 2)
+	assert.Equal(t, "line 2", imported.Warnings[0].Field)
+	assert.Equal(t, "port", imported.Warnings[1].Field)
+}
+
+func TestParseOpenfortivpnConfig_MissingHost(t *testing.T) {
+	_, err := ParseOpenfortivpnConfig(strings.NewReader("username = jdoe\n"), "")
+	assert.ErrorIs(t, err, ErrConfigMissingHost)
+}
+
+func TestWriteOpenfortivpnConfig(t *testing.T) {
+	p := NewProfile("Company")
+	p.Host = "vpn.example.com"
+	p.Port = 10443
+	p.Username = "jdoe"
+	p.Realm = "staff"
+	p.TrustedCert = "abc123"
+	p.SetDNS = false
+	p.NoFTMPush = true
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteOpenfortivpnConfig(&buf, p))
+	out := buf.String()
+
+	assert.Contains(t, out, "host = vpn.example.com\n")
+	assert.Contains(t, out, "port = 10443\n")
+	assert.Contains(t, out, "username = jdoe\n")
+	assert.Contains(t, out, "realm = staff\n")
+	assert.Contains(t, out, "trusted-cert = abc123\n")
+	assert.Contains(t, out, "set-dns = 0\n")
+	assert.Contains(t, out, "set-routes = 1\n")
+	assert.Contains(t, out, "half-internet-routes = 0\n")
+	assert.Contains(t, out, "no-ftm-push = 1\n")
+	assert.NotContains(t, out, "password")
+	assert.NotContains(t, out, "user-cert")
+}
+
+func TestWriteOpenfortivpnConfig_RoundTrip(t *testing.T) {
+	original := NewProfile("Lab")
+	original.Host = "10.0.0.1"
+	original.Port = 8443
+	original.AuthMethod = AuthMethodCertificate
+	original.ClientCertPath = "/etc/vpn/cert.pem"
+	original.ClientKeyPath = "/etc/vpn/key.pem"
+	original.HalfInternetRoutes = true
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteOpenfortivpnConfig(&buf, original))
+
+	imported, err := ParseOpenfortivpnConfig(&buf, "Lab")
+	require.NoError(t, err)
+	assert.Empty(t, imported.Warnings)
+
+	p := imported.Profile
+	assert.Equal(t, original.Host, p.Host)
+	assert.Equal(t, original.Port, p.Port)
+	assert.Equal(t, original.AuthMethod, p.AuthMethod)
+	assert.Equal(t, original.ClientCertPath, p.ClientCertPath)
+	assert.Equal(t, original.ClientKeyPath, p.ClientKeyPath)
+	assert.Equal(t, original.SetDNS, p.SetDNS)
+	assert.Equal(t, original.SetRoutes, p.SetRoutes)
+	assert.Equal(t, original.HalfInternetRoutes, p.HalfInternetRoutes)
+}
+
+func TestWriteOpenfortivpnConfig_RoundTripSAML(t *testing.T) {
+	original := NewProfile("SSO")
+	original.Host = "vpn.example.com"
+	original.AuthMethod = AuthMethodSAML
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteOpenfortivpnConfig(&buf, original))
+	assert.Contains(t, buf.String(), "saml-login = 8020\n")
+
+	imported, err := ParseOpenfortivpnConfig(&buf, "SSO")
+	require.NoError(t, err)
+	assert.Equal(t, AuthMethodSAML, imported.Profile.AuthMethod)
+}
+
+func TestWriteOpenfortivpnConfig_RejectsLineBreaks(t *testing.T) {
+	p := NewProfile("Injected")
+	p.Host = "vpn.example.com"
+	p.Username = "jdoe\npassword = leaked"
+
+	var buf bytes.Buffer
+	err := WriteOpenfortivpnConfig(&buf, p)
+	require.Error(t, err)
+	assert.Empty(t, buf.String())
+}
+
+func TestOpenfortivpnConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	p := NewProfile("Office")
+	p.Host = "vpn.example.com"
+	p.Username = "jdoe"
+
+	exportPath := filepath.Join(dir, "office.conf")
+	require.NoError(t, ExportOpenfortivpnConfigFile(exportPath, p))
+
+	info, err := os.Stat(exportPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	result, err := ImportOpenfortivpnConfigFile(exportPath)
+	require.NoError(t, err)
+	require.Len(t, result.Profiles, 1)
+	assert.Equal(t, "office", result.Profiles[0].Profile.Name)
+	assert.NotEqual(t, p.ID, result.Profiles[0].Profile.ID)
+
+	// Files named "config" are named after their host
+	configPath := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(configPath, []byte("host = gw.example.com\n"), 0600))
+	result, err = ImportOpenfortivpnConfigFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "gw.example.com", result.Profiles[0].Profile.Name)
+}
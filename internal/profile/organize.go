@@ -0,0 +1,152 @@
+package profile
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// SortOrder determines how profiles are ordered within a group.
+type SortOrder string
+
+const (
+	// SortByName orders profiles alphabetically by name.
+	SortByName SortOrder = "name"
+	// SortByHost orders profiles alphabetically by host, then by name.
+	SortByHost SortOrder = "host"
+)
+
+// ValidSortOrders returns all valid sort orders.
+func ValidSortOrders() []SortOrder {
+	return []SortOrder{SortByName, SortByHost}
+}
+
+// ProfileGroup is a named section of profiles as shown in the profile list and tray menu.
+type ProfileGroup struct {
+	// Name is the group name. It is empty for ungrouped profiles.
+	Name string
+	// Favorites is true for the pinned section containing favorite profiles.
+	Favorites bool
+	// Profiles are the group members in sort order.
+	Profiles []*Profile
+}
+
+// GroupProfiles organizes profiles into sections: favorites first, then named groups
+// in alphabetical order, then ungrouped profiles. Favorites are pinned to the top
+// section instead of appearing in their group. Empty sections are omitted.
+// An unknown sort order falls back to SortByName.
+func GroupProfiles(profiles []*Profile, order SortOrder) []ProfileGroup {
+	var favorites, ungrouped []*Profile
+	named := make(map[string][]*Profile)
+	var names []string
+
+	for _, p := range profiles {
+		switch {
+		case p.Favorite:
+			favorites = append(favorites, p)
+		case p.GroupName() == "":
+			ungrouped = append(ungrouped, p)
+		default:
+			name := p.GroupName()
+			if _, ok := named[name]; !ok {
+				names = append(names, name)
+			}
+			named[name] = append(named[name], p)
+		}
+	}
+
+	slices.SortFunc(names, compareFold)
+
+	groups := make([]ProfileGroup, 0, len(names)+2)
+	if len(favorites) > 0 {
+		groups = append(groups, ProfileGroup{Favorites: true, Profiles: SortProfiles(favorites, order)})
+	}
+	for _, name := range names {
+		groups = append(groups, ProfileGroup{Name: name, Profiles: SortProfiles(named[name], order)})
+	}
+	if len(ungrouped) > 0 {
+		groups = append(groups, ProfileGroup{Profiles: SortProfiles(ungrouped, order)})
+	}
+
+	return groups
+}
+
+// SortProfiles sorts profiles in place using the given order and returns the slice.
+// Ties are broken by ID so the order is stable across reloads.
+func SortProfiles(profiles []*Profile, order SortOrder) []*Profile {
+	slices.SortFunc(profiles, func(a, b *Profile) int {
+		if order == SortByHost {
+			if c := compareFold(a.Host, b.Host); c != 0 {
+				return c
+			}
+		}
+		if c := compareFold(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return profiles
+}
+
+// GroupName returns the trimmed group name, or an empty string for ungrouped profiles.
+func (p *Profile) GroupName() string {
+	return strings.TrimSpace(p.Group)
+}
+
+// Matches reports whether the profile matches a search query.
+// The query is split into words; every word must appear (case-insensitively)
+// in the name, host, group, or one of the tags. An empty query matches everything.
+func (p *Profile) Matches(query string) bool {
+	fields := make([]string, 0, len(p.Tags)+3)
+	fields = append(fields, p.Name, p.Host, p.Group)
+	fields = append(fields, p.Tags...)
+	for i, f := range fields {
+		fields[i] = strings.ToLower(f)
+	}
+
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		found := false
+		for _, f := range fields {
+			if strings.Contains(f, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTags splits a comma-separated tag list, trimming whitespace and
+// dropping empty and duplicate (case-insensitive) tags.
+func ParseTags(s string) []string {
+	return NormalizeTags(strings.Split(s, ","))
+}
+
+// NormalizeTags trims tags and drops empty and duplicate (case-insensitive) entries,
+// preserving the order of first occurrence. Returns nil if no tags remain.
+func NormalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// compareFold compares strings case-insensitively, falling back to a
+// case-sensitive comparison for strings that differ only in case.
+func compareFold(a, b string) int {
+	if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
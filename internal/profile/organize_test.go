@@ -0,0 +1,88 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOrganizeProfile(name, host, group string, favorite bool) *Profile {
+	p := NewProfile(name)
+	p.Host = host
+	p.Group = group
+	p.Favorite = favorite
+	return p
+}
+
+func profileNames(profiles []*Profile) []string {
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestGroupProfiles(t *testing.T) {
+	profiles := []*Profile{
+		newOrganizeProfile("zeta", "a.example.com", "", false),
+		newOrganizeProfile("Acme Prod", "z.acme.com", "Acme", false),
+		newOrganizeProfile("Home", "home.example.com", "", true),
+		newOrganizeProfile("Acme Dev", "dev.acme.com", " Acme ", false),
+		newOrganizeProfile("Beta", "beta.example.com", "beta corp", false),
+		newOrganizeProfile("Acme Lab", "lab.acme.com", "Acme", true),
+		newOrganizeProfile("alpha", "b.example.com", "", false),
+	}
+
+	groups := GroupProfiles(profiles, SortByName)
+	require.Len(t, groups, 4)
+
+	assert.True(t, groups[0].Favorites)
+	assert.Equal(t, []string{"Acme Lab", "Home"}, profileNames(groups[0].Profiles))
+
+	assert.Equal(t, "Acme", groups[1].Name)
+	assert.Equal(t, []string{"Acme Dev", "Acme Prod"}, profileNames(groups[1].Profiles))
+
+	assert.Equal(t, "beta corp", groups[2].Name)
+
+	assert.False(t, groups[3].Favorites)
+	assert.Empty(t, groups[3].Name)
+	assert.Equal(t, []string{"alpha", "zeta"}, profileNames(groups[3].Profiles))
+
+	byHost := GroupProfiles(profiles, SortByHost)
+	assert.Equal(t, []string{"zeta", "alpha"}, profileNames(byHost[3].Profiles))
+}
+
+func TestGroupProfiles_Empty(t *testing.T) {
+	assert.Empty(t, GroupProfiles(nil, SortByName))
+}
+
+func TestProfile_Matches(t *testing.T) {
+	p := newOrganizeProfile("Acme Production", "vpn.acme.com", "Customers", false)
+	p.Tags = []string{"EU-West", "critical"}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"acme", true},
+		{"VPN.ACME", true},
+		{"customers", true},
+		{"eu-west", true},
+		{"acme critical", true},
+		{"acme staging", false},
+		{"other", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.Matches(tt.query))
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"prod", "EU", "db"}, ParseTags(" prod, EU,,eu , db ,PROD"))
+	assert.Nil(t, ParseTags(" , "))
+}
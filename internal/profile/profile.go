@@ -22,25 +22,63 @@ const (
 	// Maximum lengths for text fields to prevent UI issues.
 	maxNameLength        = 100
 	maxDescriptionLength = 500
+	maxGroupLength       = 50
+	maxTagLength         = 30
+	maxTags              = 20
 )
 
 // Profile represents a VPN connection configuration.
 type Profile struct {
-	ID                 string     `json:"id"`
-	Name               string     `json:"name"`
-	Description        string     `json:"description,omitempty"`
-	Host               string     `json:"host"`
-	Port               int        `json:"port"`
-	AuthMethod         AuthMethod `json:"auth_method"`
-	Username           string     `json:"username"`
-	Realm              string     `json:"realm,omitempty"`
-	TrustedCert        string     `json:"trusted_cert,omitempty"`
-	ClientCertPath     string     `json:"client_cert_path,omitempty"`
-	ClientKeyPath      string     `json:"client_key_path,omitempty"`
-	SetDNS             bool       `json:"set_dns"`
-	SetRoutes          bool       `json:"set_routes"`
-	HalfInternetRoutes bool       `json:"half_internet_routes"`
-	AutoReconnect      bool       `json:"auto_reconnect"`
+	// SchemaVersion is the schema version the profile was stored with.
+	// It is set by Store.Save and checked when loading.
+	SchemaVersion int `json:"schema_version"`
+
+	ID                 string           `json:"id"`
+	Name               string           `json:"name"`
+	Description        string           `json:"description,omitempty"`
+	Host               string           `json:"host"`
+	Port               int              `json:"port"`
+	Gateways           []Gateway        `json:"gateways,omitempty"`
+	GatewaySelection   GatewaySelection `json:"gateway_selection,omitempty"`
+	AuthMethod         AuthMethod       `json:"auth_method"`
+	Username           string           `json:"username"`
+	Realm              string           `json:"realm,omitempty"`
+	TrustedCert        string           `json:"trusted_cert,omitempty"`
+	ClientCertPath     string           `json:"client_cert_path,omitempty"`
+	ClientKeyPath      string           `json:"client_key_path,omitempty"`
+	SetDNS             bool             `json:"set_dns"`
+	SetRoutes          bool             `json:"set_routes"`
+	HalfInternetRoutes bool             `json:"half_internet_routes"`
+	NoFTMPush          bool             `json:"no_ftm_push,omitempty"`
+	AutoReconnect      bool             `json:"auto_reconnect"`
+	DisconnectOnLock   bool             `json:"disconnect_on_lock,omitempty"`
+	ReconnectPolicy    *ReconnectPolicy `json:"reconnect_policy,omitempty"`
+	LivenessProbe      *LivenessProbe   `json:"liveness_probe,omitempty"`
+	Schedule           *Schedule        `json:"schedule,omitempty"`
+	IdleDisconnect     *IdleDisconnect  `json:"idle_disconnect,omitempty"`
+	KillSwitch         *KillSwitch      `json:"kill_switch,omitempty"`
+	Hooks              *Hooks           `json:"hooks,omitempty"`
+	Group              string           `json:"group,omitempty"`
+	Tags               []string         `json:"tags,omitempty"`
+	Favorite           bool             `json:"favorite,omitempty"`
+	IsTemplate         bool             `json:"is_template,omitempty"`
+	ParentID           string           `json:"parent_id,omitempty"`
+	Locked             []string         `json:"locked,omitempty"`
+
+	// Overrides lists the JSON keys of inheritable settings this profile sets itself.
+	// It is only meaningful when ParentID is set and is derived from the stored file.
+	Overrides []string `json:"-"`
+
+	// System is set for read-only profiles provided by an administrator.
+	// It is derived from where the profile was loaded from, never stored.
+	System bool `json:"-"`
+
+	// Revision identifies the stored file contents this profile was read from.
+	// Store.Save refuses to overwrite a file that changed since, unless it is empty.
+	Revision string `json:"-"`
+
+	// resolved is set by Resolve once inherited settings were filled in.
+	resolved bool
 }
 
 // NewProfile creates a new profile with default values and a generated UUID.
@@ -69,6 +107,16 @@ func (p *Profile) Validate() error {
 	if strings.TrimSpace(p.Name) == "" {
 		return errors.New("profile name is required")
 	}
+
+	// Inherited settings must be filled in from the template before validation
+	if p.ParentID != "" {
+		if p.IsTemplate {
+			return ErrTemplateInheritance
+		}
+		if !p.resolved {
+			return ErrUnresolvedProfile
+		}
+	}
 	if err := validateTextInput(p.Name, "name", maxNameLength); err != nil {
 		return err
 	}
@@ -80,12 +128,34 @@ func (p *Profile) Validate() error {
 		}
 	}
 
-	if strings.TrimSpace(p.Host) == "" {
-		return errors.New("host is required")
+	// Group and tags are optional organization aids
+	if p.Group != "" {
+		if err := validateTextInput(p.Group, "group", maxGroupLength); err != nil {
+			return err
+		}
+	}
+	if len(p.Tags) > maxTags {
+		return fmt.Errorf("too many tags (max %d)", maxTags)
+	}
+	for _, tag := range p.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return errors.New("tags must not be empty")
+		}
+		if strings.Contains(tag, ",") {
+			return fmt.Errorf("tag %q must not contain commas", tag)
+		}
+		if err := validateTextInput(tag, "tag", maxTagLength); err != nil {
+			return err
+		}
 	}
 
-	// Validate host is either a valid hostname or IP address
-	if err := validateHost(p.Host); err != nil {
+	// Templates may leave connection-specific settings for their children to fill in
+	if strings.TrimSpace(p.Host) == "" {
+		if !p.IsTemplate {
+			return errors.New("host is required")
+		}
+	} else if err := validateHost(p.Host); err != nil {
+		// Validate host is either a valid hostname or IP address
 		return err
 	}
 
@@ -93,13 +163,44 @@ func (p *Profile) Validate() error {
 		return fmt.Errorf("port must be between 1 and 65535, got %d", p.Port)
 	}
 
+	if err := p.validateGateways(); err != nil {
+		return err
+	}
+
+	if err := p.validateReconnectPolicy(); err != nil {
+		return err
+	}
+
+	if err := p.validateLivenessProbe(); err != nil {
+		return err
+	}
+
+	if err := p.validateSchedule(); err != nil {
+		return err
+	}
+
+	if err := p.validateIdleDisconnect(); err != nil {
+		return err
+	}
+
+	if err := p.validateKillSwitch(); err != nil {
+		return err
+	}
+
+	if err := p.validateHooks(); err != nil {
+		return err
+	}
+
 	switch p.AuthMethod {
 	case AuthMethodPassword, AuthMethodOTP, AuthMethodSAML:
 		// Username may be optional for SAML
-		if p.AuthMethod != AuthMethodSAML && strings.TrimSpace(p.Username) == "" {
+		if p.AuthMethod != AuthMethodSAML && !p.IsTemplate && strings.TrimSpace(p.Username) == "" {
 			return errors.New("username is required for password/OTP authentication")
 		}
 	case AuthMethodCertificate:
+		if p.IsTemplate {
+			break
+		}
 		if strings.TrimSpace(p.ClientCertPath) == "" {
 			return errors.New("client certificate path is required for certificate authentication")
 		}
@@ -372,6 +372,60 @@ func TestProfile_Validate(t *testing.T) {
 			},
 			wantErr: "description is too long",
 		},
+		{
+			name: "valid profile with group and tags",
+			profile: &Profile{
+				ID:         "550e8400-e29b-41d4-a716-446655440000",
+				Name:       "Work VPN",
+				Host:       "vpn.company.com",
+				Port:       443,
+				AuthMethod: AuthMethodPassword,
+				Username:   "john.doe",
+				Group:      "Customers",
+				Tags:       []string{"prod", "eu-west"},
+				Favorite:   true,
+			},
+			wantErr: "",
+		},
+		{
+			name: "invalid group - too long",
+			profile: &Profile{
+				ID:         "550e8400-e29b-41d4-a716-446655440000",
+				Name:       "Work VPN",
+				Host:       "vpn.company.com",
+				Port:       443,
+				AuthMethod: AuthMethodPassword,
+				Username:   "john.doe",
+				Group:      strings.Repeat("a", 51),
+			},
+			wantErr: "group is too long",
+		},
+		{
+			name: "invalid tag - contains comma",
+			profile: &Profile{
+				ID:         "550e8400-e29b-41d4-a716-446655440000",
+				Name:       "Work VPN",
+				Host:       "vpn.company.com",
+				Port:       443,
+				AuthMethod: AuthMethodPassword,
+				Username:   "john.doe",
+				Tags:       []string{"a,b"},
+			},
+			wantErr: "must not contain commas",
+		},
+		{
+			name: "invalid tag - empty",
+			profile: &Profile{
+				ID:         "550e8400-e29b-41d4-a716-446655440000",
+				Name:       "Work VPN",
+				Host:       "vpn.company.com",
+				Port:       443,
+				AuthMethod: AuthMethodPassword,
+				Username:   "john.doe",
+				Tags:       []string{" "},
+			},
+			wantErr: "tags must not be empty",
+		},
 		{
 			name: "valid profile with description",
 			profile: &Profile{
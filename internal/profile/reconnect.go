@@ -0,0 +1,88 @@
+package profile
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// ReconnectForever as MaxAttempts keeps reconnecting until a connection succeeds or the user cancels.
+	ReconnectForever = -1
+
+	// DefaultMaxReconnectDelay caps the backoff when the policy does not set a cap.
+	DefaultMaxReconnectDelay = 5 * time.Minute
+
+	// maxReconnectDelaySeconds bounds the configurable reconnect delays.
+	maxReconnectDelaySeconds = 3600
+	// maxReconnectResetSeconds bounds the configurable reset window.
+	maxReconnectResetSeconds = 86400
+)
+
+// ReconnectPolicy controls how a dropped connection is re-established.
+// A profile's policy replaces the reconnect settings of the application configuration.
+type ReconnectPolicy struct {
+	// MaxAttempts limits the consecutive reconnect attempts. Zero never reconnects
+	// and ReconnectForever retries until a connection succeeds.
+	MaxAttempts int `json:"max_attempts"`
+	// DelaySeconds is the wait before the first attempt.
+	DelaySeconds int `json:"delay_seconds"`
+	// Backoff doubles the wait with each further attempt, up to MaxDelaySeconds.
+	Backoff bool `json:"backoff,omitempty"`
+	// MaxDelaySeconds caps the backoff. Zero uses DefaultMaxReconnectDelay.
+	MaxDelaySeconds int `json:"max_delay_seconds,omitempty"`
+	// JitterPercent randomly lengthens or shortens each wait by up to this share,
+	// so clients dropped together do not all reconnect at once.
+	JitterPercent int `json:"jitter_percent,omitempty"`
+	// ResetAfterSeconds is how long a connection must stay up before the attempts
+	// count starts over. Zero starts over as soon as a connection succeeds.
+	ResetAfterSeconds int `json:"reset_after_seconds,omitempty"`
+}
+
+// clone returns a copy of the policy, or nil if r is nil.
+func (r *ReconnectPolicy) clone() *ReconnectPolicy {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	return &c
+}
+
+// MaxDelay returns the cap of the backoff.
+func (r *ReconnectPolicy) MaxDelay() time.Duration {
+	if r.MaxDelaySeconds == 0 {
+		return DefaultMaxReconnectDelay
+	}
+	return time.Duration(r.MaxDelaySeconds) * time.Second
+}
+
+// validateReconnectPolicy checks the profile's reconnect policy.
+func (p *Profile) validateReconnectPolicy() error {
+	r := p.ReconnectPolicy
+	if r == nil {
+		return nil
+	}
+
+	if r.MaxAttempts < ReconnectForever {
+		return fmt.Errorf("reconnect attempts must be %d (forever) or more, got %d", ReconnectForever, r.MaxAttempts)
+	}
+	if r.DelaySeconds < 0 || r.DelaySeconds > maxReconnectDelaySeconds {
+		return fmt.Errorf("reconnect delay must be between 0 and %d seconds, got %d", maxReconnectDelaySeconds, r.DelaySeconds)
+	}
+	if r.MaxDelaySeconds < 0 || r.MaxDelaySeconds > maxReconnectDelaySeconds {
+		return fmt.Errorf("maximum reconnect delay must be between 0 and %d seconds, got %d", maxReconnectDelaySeconds, r.MaxDelaySeconds)
+	}
+	if r.MaxDelaySeconds != 0 && r.MaxDelaySeconds < r.DelaySeconds {
+		return fmt.Errorf("maximum reconnect delay (%ds) must not be shorter than the delay (%ds)", r.MaxDelaySeconds, r.DelaySeconds)
+	}
+	if r.Backoff && r.DelaySeconds == 0 {
+		return fmt.Errorf("reconnect backoff requires a delay of at least 1 second")
+	}
+	if r.JitterPercent < 0 || r.JitterPercent > 100 {
+		return fmt.Errorf("reconnect jitter must be between 0 and 100 percent, got %d", r.JitterPercent)
+	}
+	if r.ResetAfterSeconds < 0 || r.ResetAfterSeconds > maxReconnectResetSeconds {
+		return fmt.Errorf("reconnect reset window must be between 0 and %d seconds, got %d", maxReconnectResetSeconds, r.ResetAfterSeconds)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package profile
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfile_ValidateReconnectPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *ReconnectPolicy
+		wantErr bool
+	}{
+		{name: "no policy", policy: nil},
+		{name: "never", policy: &ReconnectPolicy{MaxAttempts: 0}},
+		{name: "forever with cap", policy: &ReconnectPolicy{MaxAttempts: ReconnectForever, DelaySeconds: 5, Backoff: true, MaxDelaySeconds: 300, JitterPercent: 20, ResetAfterSeconds: 120}},
+		{name: "attempts below forever", policy: &ReconnectPolicy{MaxAttempts: -2}, wantErr: true},
+		{name: "negative delay", policy: &ReconnectPolicy{MaxAttempts: 3, DelaySeconds: -1}, wantErr: true},
+		{name: "delay too long", policy: &ReconnectPolicy{MaxAttempts: 3, DelaySeconds: maxReconnectDelaySeconds + 1}, wantErr: true},
+		{name: "cap below delay", policy: &ReconnectPolicy{MaxAttempts: 3, DelaySeconds: 60, MaxDelaySeconds: 30}, wantErr: true},
+		{name: "backoff without delay", policy: &ReconnectPolicy{MaxAttempts: 3, Backoff: true}, wantErr: true},
+		{name: "jitter over 100", policy: &ReconnectPolicy{MaxAttempts: 3, DelaySeconds: 5, JitterPercent: 101}, wantErr: true},
+		{name: "negative reset window", policy: &ReconnectPolicy{MaxAttempts: 3, ResetAfterSeconds: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile("Office")
+			p.Host = "vpn.example.com"
+			p.Username = "alice"
+			p.ReconnectPolicy = tt.policy
+
+			err := p.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestReconnectPolicy_MaxDelay(t *testing.T) {
+	assert.Equal(t, DefaultMaxReconnectDelay, (&ReconnectPolicy{}).MaxDelay())
+	assert.Equal(t, 90*time.Second, (&ReconnectPolicy{MaxDelaySeconds: 90}).MaxDelay())
+}
+
+func TestReconnectPolicy_JSON(t *testing.T) {
+	p := NewProfile("Customer")
+	p.ReconnectPolicy = &ReconnectPolicy{MaxAttempts: 0}
+
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	// "Never" must survive a round trip rather than being dropped as a zero value
+	assert.Contains(t, string(data), `"reconnect_policy":{"max_attempts":0,"delay_seconds":0}`)
+
+	var decoded Profile
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, p.ReconnectPolicy, decoded.ReconnectPolicy)
+}
+
+func TestResolve_CopiesReconnectPolicy(t *testing.T) {
+	p := NewProfile("Office")
+	p.ReconnectPolicy = &ReconnectPolicy{MaxAttempts: 3}
+
+	resolved, err := Resolve(p, nil)
+	require.NoError(t, err)
+	resolved.ReconnectPolicy.MaxAttempts = 5
+	assert.Equal(t, 3, p.ReconnectPolicy.MaxAttempts)
+}
+
+func TestResolve_InheritsReconnectPolicy(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	fields := systemTestFields()
+	fields["is_template"] = true
+	fields["reconnect_policy"] = map[string]any{"max_attempts": ReconnectForever, "delay_seconds": 10}
+	writeSystemProfile(t, systemDir, fields)
+
+	child := NewProfile("Mine")
+	child.ParentID = systemTestID
+	resolved, err := Resolve(child, store)
+	require.NoError(t, err)
+	require.NotNil(t, resolved.ReconnectPolicy)
+	assert.Equal(t, ReconnectForever, resolved.ReconnectPolicy.MaxAttempts)
+
+	fields["locked"] = []string{"reconnect_policy"}
+	writeSystemProfile(t, systemDir, fields)
+	child.ReconnectPolicy = &ReconnectPolicy{MaxAttempts: 0}
+	child.SetOverridden("reconnect_policy", true)
+	resolved, err = Resolve(child, store)
+	require.NoError(t, err)
+	assert.Equal(t, ReconnectForever, resolved.ReconnectPolicy.MaxAttempts, "a locked policy cannot be overridden")
+}
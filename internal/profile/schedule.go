@@ -0,0 +1,130 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// scheduleDays are the day names used by schedules, indexed by time.Weekday.
+var scheduleDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// clockLayout is the format of the times of day in a schedule.
+const clockLayout = "15:04"
+
+// ScheduleDay returns the name a schedule uses for a weekday, such as "mon".
+func ScheduleDay(d time.Weekday) string {
+	return scheduleDays[d]
+}
+
+// Schedule limits when a profile is connected to windows on some weekdays,
+// such as weekdays from 08:30 to 18:00. Times are local.
+type Schedule struct {
+	// Days are the weekdays windows start on, named "mon" to "sun".
+	Days []string `json:"days"`
+	// Start and End are times of day formatted as HH:MM. A window whose end is
+	// not after its start runs past midnight into the next day.
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// clone returns a deep copy of the schedule.
+func (s *Schedule) clone() *Schedule {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.Days = slices.Clone(s.Days)
+	return &c
+}
+
+// Window returns the window t falls in. ok is false outside the schedule's windows.
+func (s *Schedule) Window(t time.Time) (start, end time.Time, ok bool) {
+	// A window running past midnight may have started the day before
+	for offset := -1; offset <= 0; offset++ {
+		start, end, ok := s.windowOn(t, offset)
+		if ok && !t.Before(start) && t.Before(end) {
+			return start, end, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// NextStart returns the start of the first window after t, or the zero time if
+// the schedule has no days.
+func (s *Schedule) NextStart(t time.Time) time.Time {
+	for offset := 0; offset <= 7; offset++ {
+		if start, _, ok := s.windowOn(t, offset); ok && start.After(t) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// windowOn returns the window starting offset days after the day of t.
+// ok is false if no window starts on that day.
+func (s *Schedule) windowOn(t time.Time, offset int) (start, end time.Time, ok bool) {
+	year, month, day := t.Date()
+	// Noon is never skipped or repeated by daylight saving time changes
+	date := time.Date(year, month, day+offset, 12, 0, 0, 0, t.Location())
+	if !slices.Contains(s.Days, ScheduleDay(date.Weekday())) {
+		return time.Time{}, time.Time{}, false
+	}
+
+	startClock, err := time.Parse(clockLayout, s.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	endClock, err := time.Parse(clockLayout, s.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	year, month, day = date.Date()
+	start = time.Date(year, month, day, startClock.Hour(), startClock.Minute(), 0, 0, t.Location())
+	end = time.Date(year, month, day, endClock.Hour(), endClock.Minute(), 0, 0, t.Location())
+	if !end.After(start) {
+		end = time.Date(year, month, day+1, endClock.Hour(), endClock.Minute(), 0, 0, t.Location())
+	}
+	return start, end, true
+}
+
+// String implements fmt.Stringer, such as "mon,tue 08:30–18:00".
+func (s *Schedule) String() string {
+	return fmt.Sprintf("%s %s–%s", strings.Join(s.Days, ","), s.Start, s.End)
+}
+
+// validateSchedule checks the profile's schedule.
+func (p *Profile) validateSchedule() error {
+	s := p.Schedule
+	if s == nil {
+		return nil
+	}
+
+	if len(s.Days) == 0 {
+		return errors.New("schedule needs at least one day")
+	}
+	for i, day := range s.Days {
+		if !slices.Contains(scheduleDays, day) {
+			return fmt.Errorf("invalid schedule day %q, expected one of %s", day, strings.Join(scheduleDays, ", "))
+		}
+		if slices.Contains(s.Days[:i], day) {
+			return fmt.Errorf("schedule day %q is listed twice", day)
+		}
+	}
+
+	start, err := time.Parse(clockLayout, s.Start)
+	if err != nil {
+		return fmt.Errorf("schedule start must be a time of day as HH:MM, got %q", s.Start)
+	}
+	end, err := time.Parse(clockLayout, s.End)
+	if err != nil {
+		return fmt.Errorf("schedule end must be a time of day as HH:MM, got %q", s.End)
+	}
+	if start.Equal(end) {
+		return errors.New("schedule start and end must differ")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package profile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var weekdays = []string{"mon", "tue", "wed", "thu", "fri"}
+
+func TestProfile_ValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		wantErr  bool
+	}{
+		{name: "no schedule", schedule: nil},
+		{name: "weekdays", schedule: &Schedule{Days: weekdays, Start: "08:30", End: "18:00"}},
+		{name: "overnight", schedule: &Schedule{Days: []string{"sat"}, Start: "22:00", End: "06:00"}},
+		{name: "no days", schedule: &Schedule{Start: "08:30", End: "18:00"}, wantErr: true},
+		{name: "unknown day", schedule: &Schedule{Days: []string{"monday"}, Start: "08:30", End: "18:00"}, wantErr: true},
+		{name: "day twice", schedule: &Schedule{Days: []string{"mon", "mon"}, Start: "08:30", End: "18:00"}, wantErr: true},
+		{name: "invalid start", schedule: &Schedule{Days: weekdays, Start: "8.30", End: "18:00"}, wantErr: true},
+		{name: "invalid end", schedule: &Schedule{Days: weekdays, Start: "08:30", End: "24:00"}, wantErr: true},
+		{name: "empty window", schedule: &Schedule{Days: weekdays, Start: "08:30", End: "08:30"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile("Office")
+			p.Host = "vpn.example.com"
+			p.Username = "alice"
+			p.Schedule = tt.schedule
+
+			err := p.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// at returns a local time in October 2026, when the 19th is a Monday.
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, time.October, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestSchedule_Window(t *testing.T) {
+	office := &Schedule{Days: weekdays, Start: "08:30", End: "18:00"}
+	night := &Schedule{Days: []string{"fri"}, Start: "22:00", End: "06:00"}
+
+	tests := []struct {
+		name      string
+		schedule  *Schedule
+		t         time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+		wantOK    bool
+	}{
+		{name: "before start", schedule: office, t: at(19, 8, 29)},
+		{name: "at start", schedule: office, t: at(19, 8, 30), wantStart: at(19, 8, 30), wantEnd: at(19, 18, 0), wantOK: true},
+		{name: "during", schedule: office, t: at(23, 17, 59), wantStart: at(23, 8, 30), wantEnd: at(23, 18, 0), wantOK: true},
+		{name: "at end", schedule: office, t: at(19, 18, 0)},
+		{name: "weekend", schedule: office, t: at(24, 12, 0)},
+		{name: "overnight before midnight", schedule: night, t: at(23, 23, 0), wantStart: at(23, 22, 0), wantEnd: at(24, 6, 0), wantOK: true},
+		{name: "overnight after midnight", schedule: night, t: at(24, 5, 0), wantStart: at(23, 22, 0), wantEnd: at(24, 6, 0), wantOK: true},
+		{name: "overnight on a day without a window", schedule: night, t: at(24, 23, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := tt.schedule.Window(tt.t)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.True(t, tt.wantStart.Equal(start), "start %s", start)
+			assert.True(t, tt.wantEnd.Equal(end), "end %s", end)
+		})
+	}
+}
+
+func TestSchedule_NextStart(t *testing.T) {
+	office := &Schedule{Days: weekdays, Start: "08:30", End: "18:00"}
+
+	assert.True(t, at(19, 8, 30).Equal(office.NextStart(at(19, 7, 0))), "later today")
+	assert.True(t, at(20, 8, 30).Equal(office.NextStart(at(19, 8, 30))), "tomorrow once today's window started")
+	assert.True(t, at(26, 8, 30).Equal(office.NextStart(at(23, 20, 0))), "Monday after the weekend")
+	assert.True(t, (&Schedule{Start: "08:30", End: "18:00"}).NextStart(at(19, 7, 0)).IsZero(), "no days")
+}
+
+func TestSchedule_DaylightSavingTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	// Clocks go back from 03:00 to 02:00 on Sunday, October 25th 2026
+	night := &Schedule{Days: []string{"sat"}, Start: "22:00", End: "06:00"}
+	start, end, ok := night.Window(time.Date(2026, time.October, 25, 1, 0, 0, 0, berlin))
+	require.True(t, ok)
+	assert.Equal(t, 9*time.Hour, end.Sub(start), "the night is an hour longer")
+}
+
+func TestResolve_CopiesSchedule(t *testing.T) {
+	p := NewProfile("Office")
+	p.Schedule = &Schedule{Days: weekdays, Start: "08:30", End: "18:00"}
+
+	resolved, err := Resolve(p, nil)
+	require.NoError(t, err)
+	resolved.Schedule.Days[0] = "sun"
+	assert.Equal(t, "mon", p.Schedule.Days[0])
+}
@@ -3,11 +3,15 @@
 package profile
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
@@ -21,8 +25,21 @@ var (
 	ErrStoreExists = errors.New("profile already exists")
 	// ErrStoreInvalidID is returned when an invalid profile ID is provided.
 	ErrStoreInvalidID = errors.New("invalid profile ID format")
+	// ErrStoreConflict is returned when a profile changed on disk since it was loaded.
+	// Load the profile again to pick up the stored version and its revision.
+	ErrStoreConflict = errors.New("profile was modified by another process")
+	// ErrStoreReadOnly is returned when deleting a profile provided by the system administrator.
+	ErrStoreReadOnly = errors.New("profile is managed by the system administrator")
 )
 
+// lockFileName is the advisory lock file coordinating access between processes.
+// Profile files are replaced by rename on every save, so they cannot carry the lock themselves.
+const lockFileName = ".lock"
+
+// systemOverlayDirName is the subdirectory of the user's profile directory
+// holding the user's own values for system profiles.
+const systemOverlayDirName = "system"
+
 // StoreInterface defines the complete interface for profile storage operations.
 // This interface is implemented by Store and can be used for dependency injection
 // and testing purposes.
@@ -43,18 +60,45 @@ type StoreInterface interface {
 var _ StoreInterface = (*Store)(nil)
 
 // Store manages persistence of VPN profiles.
+// Access is serialized within the process by a mutex and across processes
+// by an advisory lock on a file in the profile directory.
+//
+// Besides the user's own profiles, a store can include read-only system profiles
+// provided by an administrator. Saving a system profile only stores the settings
+// the user may change (see Profile.IsLocked); system profiles cannot be deleted.
 type Store struct {
-	baseDir string
-	mu      sync.RWMutex
+	baseDir    string
+	systemDirs []string
+	mu         sync.RWMutex
+
+	// written maps profile IDs to the revision this store last wrote,
+	// or to an empty string after deleting the profile. Guarded by mu.
+	written map[string]string
+}
+
+// StoreOption configures a Store.
+type StoreOption func(*Store)
+
+// WithSystemDirs adds directories holding read-only profiles provided by an administrator.
+// Directories that do not exist are ignored. If several contain a profile with the same ID,
+// the earliest directory wins; system profiles also take precedence over the user's own.
+func WithSystemDirs(dirs ...string) StoreOption {
+	return func(s *Store) {
+		s.systemDirs = append(s.systemDirs, dirs...)
+	}
 }
 
 // NewStore creates a new profile store at the given directory.
-func NewStore(baseDir string) (*Store, error) {
+func NewStore(baseDir string, opts ...StoreOption) (*Store, error) {
 	if err := os.MkdirAll(baseDir, 0700); err != nil {
 		return nil, fmt.Errorf("failed to create profile directory: %w", err)
 	}
 
-	return &Store{baseDir: baseDir}, nil
+	s := &Store{baseDir: baseDir, written: make(map[string]string)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
 
 // profilePath returns the file path for a profile after validating the ID.
@@ -66,9 +110,78 @@ func (s *Store) profilePath(id string) (string, error) {
 	return filepath.Join(s.baseDir, id+".json"), nil
 }
 
+// Dir returns the directory the profiles are stored in.
+func (s *Store) Dir() string {
+	return s.baseDir
+}
+
+// SystemDirs returns the directories searched for system profiles, in order of precedence.
+func (s *Store) SystemDirs() []string {
+	return slices.Clone(s.systemDirs)
+}
+
+// systemPath returns the file of the system profile with the given validated ID.
+func (s *Store) systemPath(id string) (string, bool) {
+	for _, dir := range s.systemDirs {
+		path := filepath.Join(dir, id+".json")
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// overlayDir returns the directory holding the user's own values for system profiles.
+func (s *Store) overlayDir() string {
+	return filepath.Join(s.baseDir, systemOverlayDirName)
+}
+
+// overlayPath returns the file holding the user's own values for a system profile.
+func (s *Store) overlayPath(id string) string {
+	return filepath.Join(s.overlayDir(), id+".json")
+}
+
+// lockShared takes the cross-process lock for reading.
+func (s *Store) lockShared() (*fileutil.FileLock, error) {
+	l, err := fileutil.LockShared(filepath.Join(s.baseDir, lockFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock profile directory: %w", err)
+	}
+	return l, nil
+}
+
+// lockExclusive takes the cross-process lock for writing.
+func (s *Store) lockExclusive() (*fileutil.FileLock, error) {
+	l, err := fileutil.LockExclusive(filepath.Join(s.baseDir, lockFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock profile directory: %w", err)
+	}
+	return l, nil
+}
+
+// wrote reports whether this store itself produced the given revision of a profile.
+// An empty revision asks whether the store deleted the profile.
+func (s *Store) wrote(id, rev string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	last, ok := s.written[id]
+	return ok && last == rev
+}
+
+// revision returns the revision identifier for stored profile data.
+func revision(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 // Save persists a profile to disk using atomic write (write to temp, then rename).
 // Profile validation is skipped during save to allow saving draft profiles.
 // Validation should be performed before connecting.
+//
+// If p.Revision is set and the stored file no longer matches it, ErrStoreConflict
+// is returned instead of overwriting changes made elsewhere. On success p.Revision
+// is updated to the new file contents.
 func (s *Store) Save(p *Profile) error {
 	// Basic sanity checks (ID must be valid UUID)
 	if p.ID == "" {
@@ -81,7 +194,9 @@ func (s *Store) Save(p *Profile) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	data, err := json.MarshalIndent(p, "", "  ")
+	stored := *p
+	stored.SchemaVersion = CurrentSchemaVersion()
+	data, err := json.MarshalIndent(stored, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal profile: %w", err)
 	}
@@ -91,24 +206,151 @@ func (s *Store) Save(p *Profile) error {
 		return err
 	}
 
+	lock, err := s.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	if systemPath, ok := s.systemPath(p.ID); ok {
+		return s.saveOverlay(p, systemPath)
+	}
+
+	if p.Revision != "" {
+		// #nosec G304 -- path is constructed from UUID-validated id via profilePath()
+		current, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if revision(current) != p.Revision {
+				return ErrStoreConflict
+			}
+		case !os.IsNotExist(err):
+			return fmt.Errorf("failed to read profile file: %w", err)
+		}
+	}
+
 	if err := fileutil.AtomicWrite(path, data, 0600); err != nil {
 		return fmt.Errorf("failed to save profile: %w", err)
 	}
 
+	p.Revision = revision(data)
+	s.written[p.ID] = p.Revision
+	return nil
+}
+
+// saveOverlay stores the user's own values for a system profile (caller must hold locks).
+// Locked settings are left as the administrator provided them. As for user profiles,
+// ErrStoreConflict is returned if the system profile or the stored values changed
+// since p.Revision.
+func (s *Store) saveOverlay(p *Profile, systemPath string) error {
+	// #nosec G304 -- path is within a configured system directory and uses a UUID-validated id
+	systemData, err := os.ReadFile(systemPath)
+	if err != nil {
+		return fmt.Errorf("failed to read system profile: %w", err)
+	}
+	path := s.overlayPath(p.ID)
+	if p.Revision != "" {
+		current, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read user settings of system profile: %w", err)
+		}
+		if revision(slices.Concat(systemData, current)) != p.Revision {
+			return ErrStoreConflict
+		}
+	}
+	system, err := decodeSystemProfile(p.ID, systemData)
+	if err != nil {
+		return err
+	}
+
+	values := *p
+	values.System = true
+	values.Locked = system.Locked
+	overlayData, err := json.MarshalIndent(values.overlay(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	if err := fileutil.AtomicWrite(path, overlayData, 0600); err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	p.Revision = revision(slices.Concat(systemData, overlayData))
+	s.written[p.ID] = p.Revision
 	return nil
 }
 
 // Load retrieves a profile by ID.
 // Draft profiles (incomplete data) are allowed - validation happens at connect time.
+// Files written by older versions are migrated and rewritten, keeping a backup of
+// the original; files written by a newer version are rejected with an error
+// wrapping migrate.ErrNewerVersion.
 func (s *Store) Load(id string) (*Profile, error) {
+	p, outdated, err := s.load(id)
+	if err != nil || !outdated {
+		return p, err
+	}
+	return s.migrate(id)
+}
+
+// load retrieves a profile by ID under the shared locks and reports whether
+// its file was written by an older version.
+func (s *Store) load(id string) (*Profile, bool, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	path, err := s.profilePath(id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	lock, err := s.lockShared()
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	if systemPath, ok := s.systemPath(id); ok {
+		p, err := s.loadSystem(id, systemPath)
+		return p, false, err
+	}
+
+	// #nosec G304 -- path is constructed from UUID-validated id via profilePath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, ErrStoreNotFound
+		}
+		return nil, false, fmt.Errorf("failed to read profile file: %w", err)
+	}
+
+	return decodeProfile(path, data)
+}
+
+// migrate rewrites the file of a user profile written by an older version in the
+// current schema, keeping a backup of the original, and returns the migrated profile.
+// Readers hold only the shared locks, so they hand outdated files over to migrate
+// once they released them. The rewrite is not recorded in written: the contents
+// came from elsewhere, so a Watcher still reports them.
+func (s *Store) migrate(id string) (*Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	path, err := s.profilePath(id)
 	if err != nil {
 		return nil, err
 	}
 
+	lock, err := s.lockExclusive()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	// The file may have changed since it was read, so it is read again
 	// #nosec G304 -- path is constructed from UUID-validated id via profilePath()
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -118,12 +360,13 @@ func (s *Store) Load(id string) (*Profile, error) {
 		return nil, fmt.Errorf("failed to read profile file: %w", err)
 	}
 
-	var p Profile
-	if err := json.Unmarshal(data, &p); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal profile: %w", err)
+	migrated, err := profileMigrations.MigrateFile(path, data, 0600)
+	if err != nil {
+		return nil, err
 	}
 
-	return &p, nil
+	p, _, err := decodeProfile(path, migrated)
+	return p, err
 }
 
 // Delete removes a profile by ID.
@@ -136,6 +379,16 @@ func (s *Store) Delete(id string) error {
 		return err
 	}
 
+	if _, ok := s.systemPath(id); ok {
+		return ErrStoreReadOnly
+	}
+
+	lock, err := s.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Unlock() }()
+
 	if err := os.Remove(path); err != nil {
 		if os.IsNotExist(err) {
 			return ErrStoreNotFound
@@ -143,6 +396,7 @@ func (s *Store) Delete(id string) error {
 		return fmt.Errorf("failed to delete profile file: %w", err)
 	}
 
+	s.written[id] = ""
 	return nil
 }
 
@@ -169,16 +423,52 @@ type ListResult struct {
 }
 
 // List returns all stored profiles along with any errors encountered.
+// Files written by older versions are migrated and rewritten as by Load.
 func (s *Store) List() (*ListResult, error) {
+	result, outdated, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+
+	// Outdated files are rewritten once the shared locks are released
+	for _, id := range outdated {
+		i := slices.IndexFunc(result.Profiles, func(p *Profile) bool { return p.ID == id })
+		p, err := s.migrate(id)
+		if err != nil {
+			result.Profiles = slices.Delete(result.Profiles, i, i+1)
+			result.Errors = append(result.Errors, ListError{
+				ProfileID: id,
+				Err:       fmt.Errorf("failed to load profile: %w", err),
+			})
+			continue
+		}
+		result.Profiles[i] = p
+	}
+
+	return result, nil
+}
+
+// list loads all stored profiles under the shared locks and returns the IDs of
+// the user profiles whose files were written by an older version.
+func (s *Store) list() (*ListResult, []string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	lock, err := s.lockShared()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = lock.Unlock() }()
+
 	entries, err := os.ReadDir(s.baseDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read profile directory: %w", err)
+		return nil, nil, fmt.Errorf("failed to read profile directory: %w", err)
 	}
 
 	result := &ListResult{}
+	var outdated []string
+	system := s.listSystem(result)
+
 	for _, entry := range entries {
 		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
 			continue
@@ -195,7 +485,15 @@ func (s *Store) List() (*ListResult, error) {
 			continue
 		}
 
-		p, err := s.loadUnsafe(id)
+		if system[id] {
+			result.Errors = append(result.Errors, ListError{
+				ProfileID: id,
+				Err:       errors.New("hidden by a system profile with the same ID"),
+			})
+			continue
+		}
+
+		p, old, err := s.loadUnsafe(id)
 		if err != nil {
 			result.Errors = append(result.Errors, ListError{
 				ProfileID: id,
@@ -204,28 +502,150 @@ func (s *Store) List() (*ListResult, error) {
 			continue
 		}
 		result.Profiles = append(result.Profiles, p)
+		if old {
+			outdated = append(outdated, id)
+		}
 	}
 
-	return result, nil
+	return result, outdated, nil
 }
 
-// loadUnsafe loads a profile without acquiring locks (caller must hold lock).
-func (s *Store) loadUnsafe(id string) (*Profile, error) {
-	path := filepath.Join(s.baseDir, id+".json") // ID already validated by caller
-	// #nosec G304 -- path uses UUID-validated id within baseDir
+// listSystem adds the system profiles to result and returns their IDs (caller must hold lock).
+func (s *Store) listSystem(result *ListResult) map[string]bool {
+	seen := make(map[string]bool)
+	for _, dir := range s.systemDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				result.Errors = append(result.Errors, ListError{
+					ProfileID: dir,
+					Err:       fmt.Errorf("failed to read system profile directory: %w", err),
+				})
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			id, ok := strings.CutSuffix(entry.Name(), ".json")
+			if entry.IsDir() || !ok || seen[id] {
+				continue
+			}
+			if _, err := uuid.Parse(id); err != nil {
+				result.Errors = append(result.Errors, ListError{
+					ProfileID: id,
+					Err:       fmt.Errorf("invalid profile ID in filename: %w", err),
+				})
+				continue
+			}
+
+			// Earlier directories win even if their copy is broken, matching Load
+			seen[id] = true
+			p, err := s.loadSystem(id, filepath.Join(dir, entry.Name()))
+			if err != nil {
+				result.Errors = append(result.Errors, ListError{
+					ProfileID: id,
+					Err:       fmt.Errorf("failed to load system profile: %w", err),
+				})
+				continue
+			}
+			result.Profiles = append(result.Profiles, p)
+		}
+	}
+	return seen
+}
+
+// loadSystem loads a system profile with the user's own values applied (caller must hold lock).
+func (s *Store) loadSystem(id, path string) (*Profile, error) {
+	// #nosec G304 -- path is within a configured system directory and uses a UUID-validated id
 	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile file: %w", err)
+	}
+	p, err := decodeSystemProfile(id, data)
 	if err != nil {
 		return nil, err
 	}
 
-	var p Profile
-	if err := json.Unmarshal(data, &p); err != nil {
+	overlayData, err := os.ReadFile(s.overlayPath(id))
+	switch {
+	case err == nil:
+		var o systemOverlay
+		if err := json.Unmarshal(overlayData, &o); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal user settings of system profile: %w", err)
+		}
+		p.applyOverlay(o)
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read user settings of system profile: %w", err)
+	}
+
+	p.Revision = revision(slices.Concat(data, overlayData))
+	return p, nil
+}
+
+// decodeSystemProfile decodes a system profile file. System directories are
+// read-only, so files written by older versions are only migrated in memory.
+func decodeSystemProfile(id string, data []byte) (*Profile, error) {
+	migrated, _, _, err := profileMigrations.Migrate(data)
+	if err != nil {
 		return nil, err
 	}
 
+	var p Profile
+	if err := json.Unmarshal(migrated, &p); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profile: %w", err)
+	}
+	// The file name identifies the profile, as it does for the user's own profiles
+	p.ID = id
+	p.System = true
 	return &p, nil
 }
 
+// DecodeSystemFile decodes a read-only profile an administrator provides outside
+// the system directories, such as the helper daemon's always-on profile.
+// Unlike the files in a system directory, the file sets the profile ID itself.
+func DecodeSystemFile(data []byte) (*Profile, error) {
+	var header struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profile: %w", err)
+	}
+	if header.ID == "" {
+		return nil, errors.New("profile ID is required")
+	}
+	return decodeSystemProfile(header.ID, data)
+}
+
+// loadUnsafe loads a profile without acquiring locks (caller must hold lock) and
+// reports whether its file was written by an older version.
+func (s *Store) loadUnsafe(id string) (*Profile, bool, error) {
+	path := filepath.Join(s.baseDir, id+".json") // ID already validated by caller
+	// #nosec G304 -- path uses UUID-validated id within baseDir
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	return decodeProfile(path, data)
+}
+
+// decodeProfile decodes a user profile file and reports whether it was written by
+// an older version. Such files are migrated in memory here; callers holding only
+// the shared locks leave rewriting them to migrate.
+func decodeProfile(path string, data []byte) (*Profile, bool, error) {
+	migrated, _, outdated, err := profileMigrations.Migrate(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var p Profile
+	if err := json.Unmarshal(migrated, &p); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal profile: %w", err)
+	}
+	p.Revision = revision(data)
+
+	return &p, outdated, nil
+}
+
 // Exists checks if a profile with the given ID exists.
 func (s *Store) Exists(id string) (bool, error) {
 	s.mu.RLock()
@@ -236,6 +656,10 @@ func (s *Store) Exists(id string) (bool, error) {
 		return false, err
 	}
 
+	if _, ok := s.systemPath(id); ok {
+		return true, nil
+	}
+
 	_, err = os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -6,9 +6,12 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/fileutil"
 )
 
 func setupTestStore(t *testing.T) (*Store, func()) {
@@ -388,3 +391,137 @@ func TestStore_ConcurrentAccess(t *testing.T) {
 	wg.Wait()
 }
 
+func TestStore_Load_SetsRevision(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	original := validTestProfile()
+	require.NoError(t, store.Save(original))
+	assert.NotEmpty(t, original.Revision)
+
+	loaded, err := store.Load(original.ID)
+	require.NoError(t, err)
+	assert.Equal(t, original.Revision, loaded.Revision)
+
+	result, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, result.Profiles, 1)
+	assert.Equal(t, original.Revision, result.Profiles[0].Revision)
+}
+
+func TestStore_Save_Conflict(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// A second store on the same directory stands in for another process.
+	other, err := NewStore(store.Dir())
+	require.NoError(t, err)
+
+	require.NoError(t, store.Save(validTestProfile()))
+
+	mine, err := store.Load(validTestProfile().ID)
+	require.NoError(t, err)
+	theirs, err := other.Load(validTestProfile().ID)
+	require.NoError(t, err)
+
+	theirs.Name = "Renamed elsewhere"
+	require.NoError(t, other.Save(theirs))
+
+	mine.Username = "changed"
+	err = store.Save(mine)
+	assert.ErrorIs(t, err, ErrStoreConflict)
+
+	loaded, err := store.Load(mine.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed elsewhere", loaded.Name)
+	assert.Equal(t, "testuser", loaded.Username)
+
+	// Reloading picks up the current revision, after which saving succeeds
+	loaded.Username = "changed"
+	require.NoError(t, store.Save(loaded))
+}
+
+func TestStore_Save_SequentialSavesDoNotConflict(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	p := validTestProfile()
+	require.NoError(t, store.Save(p))
+	p.Name = "Second"
+	require.NoError(t, store.Save(p))
+	p.Name = "Third"
+	require.NoError(t, store.Save(p))
+
+	loaded, err := store.Load(p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Third", loaded.Name)
+}
+
+func TestStore_Save_EmptyRevisionOverwrites(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	require.NoError(t, store.Save(validTestProfile()))
+
+	p := validTestProfile()
+	p.Name = "Overwritten"
+	require.NoError(t, store.Save(p))
+
+	loaded, err := store.Load(p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Overwritten", loaded.Name)
+}
+
+func TestStore_Save_RecreatesDeletedProfile(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	p := validTestProfile()
+	require.NoError(t, store.Save(p))
+	require.NoError(t, store.Delete(p.ID))
+
+	require.NoError(t, store.Save(p))
+	exists, err := store.Exists(p.ID)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestStore_Save_WaitsForLockHolder(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	held, err := fileutil.LockExclusive(filepath.Join(store.Dir(), lockFileName))
+	require.NoError(t, err)
+
+	saved := make(chan error, 1)
+	go func() {
+		saved <- store.Save(validTestProfile())
+	}()
+
+	select {
+	case <-saved:
+		t.Fatal("Save completed while another process held the lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, held.Unlock())
+	select {
+	case err := <-saved:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Save did not complete after the lock was released")
+	}
+}
+
+func TestStore_List_IgnoresLockFile(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	require.NoError(t, store.Save(validTestProfile()))
+	assert.FileExists(t, filepath.Join(store.Dir(), lockFileName))
+
+	result, err := store.List()
+	require.NoError(t, err)
+	assert.Len(t, result.Profiles, 1)
+	assert.Empty(t, result.Errors)
+}
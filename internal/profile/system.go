@@ -0,0 +1,54 @@
+package profile
+
+import (
+	"slices"
+)
+
+// userSettableFields lists the settings users may change on a system profile
+// unless the administrator locks them as well. Passwords live in the keyring
+// and are always the user's own.
+var userSettableFields = []string{"username"}
+
+// systemOverlay holds the user's own values for a system profile.
+// It is stored in the user's profile directory, since system profiles are read-only.
+type systemOverlay struct {
+	Username string `json:"username,omitempty"`
+	Favorite bool   `json:"favorite,omitempty"`
+}
+
+// IsLocked reports whether the user can't change the setting with the given JSON key.
+// Every setting of a system profile is locked except the username, which the
+// administrator can lock by listing it in Locked. User profiles have no locked settings.
+func (p *Profile) IsLocked(key string) bool {
+	if !p.System {
+		return false
+	}
+	if slices.Contains(userSettableFields, key) {
+		return slices.Contains(p.Locked, key)
+	}
+	return true
+}
+
+// IsLockedByAdmin reports whether the administrator explicitly locked the setting
+// with the given JSON key. For a system template this also applies to the profiles
+// based on it: they always inherit the locked settings and cannot override them.
+func (p *Profile) IsLockedByAdmin(key string) bool {
+	return p.System && slices.Contains(p.Locked, key)
+}
+
+// overlay returns the user's own values of a system profile.
+func (p *Profile) overlay() systemOverlay {
+	o := systemOverlay{Favorite: p.Favorite}
+	if !p.IsLocked("username") {
+		o.Username = p.Username
+	}
+	return o
+}
+
+// applyOverlay fills in the user's own values of a system profile.
+func (p *Profile) applyOverlay(o systemOverlay) {
+	p.Favorite = o.Favorite
+	if o.Username != "" && !p.IsLocked("username") {
+		p.Username = o.Username
+	}
+}
@@ -0,0 +1,333 @@
+package profile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const systemTestID = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+// writeSystemProfile writes an administrator-provided profile file to dir.
+func writeSystemProfile(t *testing.T, dir string, fields map[string]any) {
+	t.Helper()
+	fields["id"] = systemTestID
+	data, err := json.Marshal(fields)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, systemTestID+".json"), data, 0644))
+}
+
+// systemTestFields returns a typical system profile with locked connection settings.
+func systemTestFields() map[string]any {
+	return map[string]any{
+		"schema_version": CurrentSchemaVersion(),
+		"name":           "Corporate VPN",
+		"host":           "vpn.corp.example.com",
+		"port":           443,
+		"auth_method":    "password",
+		"trusted_cert":   "aa:bb",
+		"set_dns":        true,
+		"set_routes":     true,
+		"locked":         []string{"host", "trusted_cert", "set_routes"},
+	}
+}
+
+func setupSystemStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	systemDir := filepath.Join(t.TempDir(), "system")
+	store, err := NewStore(t.TempDir(), WithSystemDirs(systemDir))
+	require.NoError(t, err)
+	return store, systemDir
+}
+
+func TestProfile_IsLocked(t *testing.T) {
+	user := &Profile{Locked: []string{"host"}}
+	system := &Profile{System: true, Locked: []string{"host"}}
+	systemLockedUsername := &Profile{System: true, Locked: []string{"username"}}
+
+	tests := []struct {
+		name    string
+		profile *Profile
+		key     string
+		locked  bool
+		byAdmin bool
+	}{
+		{"user profile is never locked", user, "host", false, false},
+		{"system profile locked field", system, "host", true, true},
+		{"system profile unlisted field", system, "realm", true, false},
+		{"system profile username", system, "username", false, false},
+		{"system profile locked username", systemLockedUsername, "username", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.locked, tt.profile.IsLocked(tt.key))
+			assert.Equal(t, tt.byAdmin, tt.profile.IsLockedByAdmin(tt.key))
+		})
+	}
+}
+
+func TestStore_List_MergesSystemProfiles(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	writeSystemProfile(t, systemDir, systemTestFields())
+	require.NoError(t, store.Save(validTestProfile()))
+
+	result, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	require.Len(t, result.Profiles, 2)
+
+	byID := map[string]*Profile{}
+	for _, p := range result.Profiles {
+		byID[p.ID] = p
+	}
+	system := byID[systemTestID]
+	require.NotNil(t, system)
+	assert.True(t, system.System)
+	assert.Equal(t, "vpn.corp.example.com", system.Host)
+	assert.Equal(t, []string{"host", "trusted_cert", "set_routes"}, system.Locked)
+	assert.NotEmpty(t, system.Revision)
+	assert.False(t, byID[validTestProfile().ID].System)
+}
+
+func TestStore_SystemDirsPrecedence(t *testing.T) {
+	first := filepath.Join(t.TempDir(), "first")
+	second := filepath.Join(t.TempDir(), "second")
+	store, err := NewStore(t.TempDir(), WithSystemDirs(first, second, filepath.Join(t.TempDir(), "missing")))
+	require.NoError(t, err)
+
+	fields := systemTestFields()
+	fields["name"] = "From First"
+	writeSystemProfile(t, first, fields)
+	fields = systemTestFields()
+	fields["name"] = "From Second"
+	writeSystemProfile(t, second, fields)
+
+	result, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, result.Profiles, 1)
+	assert.Equal(t, "From First", result.Profiles[0].Name)
+
+	loaded, err := store.Load(systemTestID)
+	require.NoError(t, err)
+	assert.Equal(t, "From First", loaded.Name)
+}
+
+func TestStore_SystemProfileHidesUserProfile(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+
+	shadowed := validTestProfile()
+	shadowed.ID = systemTestID
+	shadowed.Host = "attacker.example.com"
+	require.NoError(t, store.Save(shadowed))
+	writeSystemProfile(t, systemDir, systemTestFields())
+
+	result, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, result.Profiles, 1)
+	assert.Equal(t, "vpn.corp.example.com", result.Profiles[0].Host)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, systemTestID, result.Errors[0].ProfileID)
+
+	loaded, err := store.Load(systemTestID)
+	require.NoError(t, err)
+	assert.True(t, loaded.System)
+	assert.Equal(t, "vpn.corp.example.com", loaded.Host)
+}
+
+func TestStore_SaveSystemProfile_OnlyStoresUserValues(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	writeSystemProfile(t, systemDir, systemTestFields())
+	original, err := os.ReadFile(filepath.Join(systemDir, systemTestID+".json"))
+	require.NoError(t, err)
+
+	p, err := store.Load(systemTestID)
+	require.NoError(t, err)
+	p.Username = "alice"
+	p.Favorite = true
+	p.Host = "evil.example.com"
+	p.TrustedCert = ""
+	p.SetRoutes = false
+	require.NoError(t, store.Save(p))
+
+	current, err := os.ReadFile(filepath.Join(systemDir, systemTestID+".json"))
+	require.NoError(t, err)
+	assert.Equal(t, original, current, "system profile files are never written")
+
+	loaded, err := store.Load(systemTestID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", loaded.Username)
+	assert.True(t, loaded.Favorite)
+	assert.Equal(t, "vpn.corp.example.com", loaded.Host)
+	assert.Equal(t, "aa:bb", loaded.TrustedCert)
+	assert.True(t, loaded.SetRoutes)
+	assert.Equal(t, p.Revision, loaded.Revision)
+}
+
+func TestStore_SaveSystemProfile_LockedUsername(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	fields := systemTestFields()
+	fields["username"] = "shared"
+	fields["locked"] = []string{"username"}
+	writeSystemProfile(t, systemDir, fields)
+
+	p, err := store.Load(systemTestID)
+	require.NoError(t, err)
+	p.Username = "alice"
+	require.NoError(t, store.Save(p))
+
+	loaded, err := store.Load(systemTestID)
+	require.NoError(t, err)
+	assert.Equal(t, "shared", loaded.Username)
+}
+
+func TestStore_SaveSystemProfile_Conflict(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	writeSystemProfile(t, systemDir, systemTestFields())
+	// A second store on the same directories stands in for another process.
+	other, err := NewStore(store.Dir(), WithSystemDirs(systemDir))
+	require.NoError(t, err)
+
+	mine, err := store.Load(systemTestID)
+	require.NoError(t, err)
+	theirs, err := other.Load(systemTestID)
+	require.NoError(t, err)
+
+	theirs.Username = "bob"
+	require.NoError(t, other.Save(theirs))
+
+	mine.Favorite = true
+	assert.ErrorIs(t, store.Save(mine), ErrStoreConflict)
+
+	loaded, err := store.Load(systemTestID)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", loaded.Username)
+	assert.False(t, loaded.Favorite)
+
+	// Reloading picks up the current revision, after which saving succeeds
+	loaded.Favorite = true
+	require.NoError(t, store.Save(loaded))
+}
+
+func TestStore_DeleteSystemProfile(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	writeSystemProfile(t, systemDir, systemTestFields())
+
+	err := store.Delete(systemTestID)
+	assert.ErrorIs(t, err, ErrStoreReadOnly)
+	assert.FileExists(t, filepath.Join(systemDir, systemTestID+".json"))
+
+	exists, err := store.Exists(systemTestID)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestStore_SystemProfileMigratedInMemory(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	// An unversioned file from an older release, missing defaults
+	writeSystemProfile(t, systemDir, map[string]any{"name": "Old", "host": "vpn.example.com"})
+	path := filepath.Join(systemDir, systemTestID+".json")
+	original, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	p, err := store.Load(systemTestID)
+	require.NoError(t, err)
+	assert.Equal(t, 443, p.Port)
+	assert.Equal(t, AuthMethodPassword, p.AuthMethod)
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, original, current)
+	backups, err := filepath.Glob(filepath.Join(systemDir, "*.bak"))
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestDecodeSystemFile(t *testing.T) {
+	// An unversioned file from an older release, missing defaults
+	data, err := json.Marshal(map[string]any{"id": systemTestID, "name": "Kiosk", "host": "vpn.example.com"})
+	require.NoError(t, err)
+
+	p, err := DecodeSystemFile(data)
+	require.NoError(t, err)
+	assert.Equal(t, systemTestID, p.ID)
+	assert.Equal(t, 443, p.Port)
+	assert.True(t, p.System)
+
+	_, err = DecodeSystemFile([]byte(`{"name": "Kiosk", "host": "vpn.example.com"}`))
+	assert.Error(t, err, "ID is required")
+	_, err = DecodeSystemFile([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestResolve_SystemTemplateLockedFields(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	fields := systemTestFields()
+	fields["is_template"] = true
+	writeSystemProfile(t, systemDir, fields)
+
+	child := NewProfile("Mine")
+	child.ParentID = systemTestID
+	child.Host = "other.example.com"
+	child.Realm = "staff"
+	child.SetOverridden("host", true)
+	child.SetOverridden("realm", true)
+
+	resolved, err := Resolve(child, store)
+	require.NoError(t, err)
+	assert.Equal(t, "vpn.corp.example.com", resolved.Host, "locked settings cannot be overridden")
+	assert.Equal(t, "aa:bb", resolved.TrustedCert)
+	assert.Equal(t, "staff", resolved.Realm, "unlocked settings can still be overridden")
+}
+
+func TestWatcher_ReportsSystemProfileChanges(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	require.NoError(t, os.MkdirAll(systemDir, 0755))
+	rec := startWatcher(t, store)
+
+	writeSystemProfile(t, systemDir, systemTestFields())
+
+	require.Eventually(t, func() bool { return len(rec.snapshot()) == 1 }, 2*time.Second, 10*time.Millisecond)
+	event := rec.snapshot()[0]
+	assert.Equal(t, WatchAdded, event.Type)
+	require.NotNil(t, event.Profile)
+	assert.True(t, event.Profile.System)
+}
+
+func TestWatcher_ReportsOverlayChanges(t *testing.T) {
+	store, systemDir := setupSystemStore(t)
+	writeSystemProfile(t, systemDir, systemTestFields())
+	rec := startWatcher(t, store)
+
+	// Another instance stores the user's values, creating the overlay directory
+	other, err := NewStore(store.Dir(), WithSystemDirs(systemDir))
+	require.NoError(t, err)
+	p, err := other.Load(systemTestID)
+	require.NoError(t, err)
+	p.Username = "alice"
+	require.NoError(t, other.Save(p))
+
+	require.Eventually(t, func() bool { return len(rec.snapshot()) == 1 }, 2*time.Second, 10*time.Millisecond)
+	event := rec.snapshot()[0]
+	assert.Equal(t, WatchChanged, event.Type)
+	assert.Equal(t, systemTestID, event.ID)
+	require.NotNil(t, event.Profile)
+	assert.Equal(t, "alice", event.Profile.Username)
+
+	// Later edits are seen in the directory created meanwhile
+	p.Favorite = true
+	require.NoError(t, other.Save(p))
+
+	require.Eventually(t, func() bool { return len(rec.snapshot()) == 2 }, 2*time.Second, 10*time.Millisecond)
+	event = rec.snapshot()[1]
+	assert.Equal(t, WatchChanged, event.Type)
+	assert.Equal(t, systemTestID, event.ID)
+	require.NotNil(t, event.Profile)
+	assert.True(t, event.Profile.Favorite)
+}
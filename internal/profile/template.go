@@ -0,0 +1,237 @@
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+var (
+	// ErrUnresolvedProfile is returned when a profile that inherits from a template
+	// is validated before its inherited values were resolved.
+	ErrUnresolvedProfile = errors.New("profile inherits from a template and must be resolved first")
+	// ErrParentNotTemplate is returned when a profile inherits from a profile
+	// that is not marked as a template.
+	ErrParentNotTemplate = errors.New("parent profile is not a template")
+	// ErrTemplateInheritance is returned when a template inherits from another profile.
+	ErrTemplateInheritance = errors.New("templates cannot inherit from another profile")
+	// ErrTemplateNotConnectable is returned when connecting with a template profile.
+	ErrTemplateNotConnectable = errors.New("template profiles cannot be connected")
+)
+
+// Loader loads profiles by ID. StoreInterface satisfies it.
+type Loader interface {
+	Load(id string) (*Profile, error)
+}
+
+// inheritableField describes a profile setting that child profiles can inherit.
+// Identity and organization fields (ID, name, description, group, tags, favorite)
+// always belong to the child.
+type inheritableField struct {
+	key   string
+	value func(p *Profile) any
+	set   func(dst, src *Profile)
+}
+
+// inheritableFields lists the inheritable settings by JSON key.
+var inheritableFields = []inheritableField{
+	{"host", func(p *Profile) any { return p.Host }, func(d, s *Profile) { d.Host = s.Host }},
+	{"port", func(p *Profile) any { return p.Port }, func(d, s *Profile) { d.Port = s.Port }},
+	{"gateways", func(p *Profile) any { return p.Gateways }, func(d, s *Profile) { d.Gateways = slices.Clone(s.Gateways) }},
+	{"gateway_selection", func(p *Profile) any { return p.GatewaySelection }, func(d, s *Profile) { d.GatewaySelection = s.GatewaySelection }},
+	{"auth_method", func(p *Profile) any { return p.AuthMethod }, func(d, s *Profile) { d.AuthMethod = s.AuthMethod }},
+	{"username", func(p *Profile) any { return p.Username }, func(d, s *Profile) { d.Username = s.Username }},
+	{"realm", func(p *Profile) any { return p.Realm }, func(d, s *Profile) { d.Realm = s.Realm }},
+	{"trusted_cert", func(p *Profile) any { return p.TrustedCert }, func(d, s *Profile) { d.TrustedCert = s.TrustedCert }},
+	{"client_cert_path", func(p *Profile) any { return p.ClientCertPath }, func(d, s *Profile) { d.ClientCertPath = s.ClientCertPath }},
+	{"client_key_path", func(p *Profile) any { return p.ClientKeyPath }, func(d, s *Profile) { d.ClientKeyPath = s.ClientKeyPath }},
+	{"set_dns", func(p *Profile) any { return p.SetDNS }, func(d, s *Profile) { d.SetDNS = s.SetDNS }},
+	{"set_routes", func(p *Profile) any { return p.SetRoutes }, func(d, s *Profile) { d.SetRoutes = s.SetRoutes }},
+	{"half_internet_routes", func(p *Profile) any { return p.HalfInternetRoutes }, func(d, s *Profile) { d.HalfInternetRoutes = s.HalfInternetRoutes }},
+	{"no_ftm_push", func(p *Profile) any { return p.NoFTMPush }, func(d, s *Profile) { d.NoFTMPush = s.NoFTMPush }},
+	{"auto_reconnect", func(p *Profile) any { return p.AutoReconnect }, func(d, s *Profile) { d.AutoReconnect = s.AutoReconnect }},
+	{"reconnect_policy", func(p *Profile) any { return p.ReconnectPolicy }, func(d, s *Profile) { d.ReconnectPolicy = s.ReconnectPolicy.clone() }},
+	{"disconnect_on_lock", func(p *Profile) any { return p.DisconnectOnLock }, func(d, s *Profile) { d.DisconnectOnLock = s.DisconnectOnLock }},
+	{"schedule", func(p *Profile) any { return p.Schedule }, func(d, s *Profile) { d.Schedule = s.Schedule.clone() }},
+	{"liveness_probe", func(p *Profile) any { return p.LivenessProbe }, func(d, s *Profile) { d.LivenessProbe = s.LivenessProbe.clone() }},
+	{"idle_disconnect", func(p *Profile) any { return p.IdleDisconnect }, func(d, s *Profile) { d.IdleDisconnect = s.IdleDisconnect.clone() }},
+	{"kill_switch", func(p *Profile) any { return p.KillSwitch }, func(d, s *Profile) { d.KillSwitch = s.KillSwitch.clone() }},
+	{"hooks", func(p *Profile) any { return p.Hooks }, func(d, s *Profile) { d.Hooks = s.Hooks.clone() }},
+}
+
+// findInheritableField returns the inheritable field with the given JSON key, or nil.
+func findInheritableField(key string) *inheritableField {
+	for i := range inheritableFields {
+		if inheritableFields[i].key == key {
+			return &inheritableFields[i]
+		}
+	}
+	return nil
+}
+
+// InheritableFields returns the JSON keys of all settings a child profile can inherit.
+func InheritableFields() []string {
+	keys := make([]string, 0, len(inheritableFields))
+	for _, f := range inheritableFields {
+		keys = append(keys, f.key)
+	}
+	return keys
+}
+
+// IsOverridden reports whether the profile sets the inheritable field itself.
+// Profiles without a parent own all of their fields.
+func (p *Profile) IsOverridden(key string) bool {
+	return p.ParentID == "" || slices.Contains(p.Overrides, key)
+}
+
+// SetOverridden marks an inheritable field as set by the profile (true)
+// or inherited from its template (false).
+func (p *Profile) SetOverridden(key string, overridden bool) {
+	i := slices.Index(p.Overrides, key)
+	switch {
+	case overridden && i < 0:
+		p.Overrides = append(p.Overrides, key)
+	case !overridden && i >= 0:
+		p.Overrides = slices.Delete(p.Overrides, i, i+1)
+	}
+}
+
+// FieldEqual reports whether the inheritable field has the same value in both profiles.
+// Unknown keys are never equal.
+func (p *Profile) FieldEqual(other *Profile, key string) bool {
+	f := findInheritableField(key)
+	return f != nil && reflect.DeepEqual(f.value(p), f.value(other))
+}
+
+// InheritField copies the inheritable field from the parent profile.
+func (p *Profile) InheritField(parent *Profile, key string) {
+	if f := findInheritableField(key); f != nil {
+		f.set(p, parent)
+	}
+}
+
+// Resolve returns a copy of the profile with all inherited fields filled in from its
+// template. Profiles without a parent are returned as a copy unchanged.
+// The result is marked as resolved so it can be validated and connected.
+func Resolve(p *Profile, loader Loader) (*Profile, error) {
+	resolved := *p
+	resolved.Tags = slices.Clone(p.Tags)
+	resolved.Gateways = slices.Clone(p.Gateways)
+	resolved.Overrides = slices.Clone(p.Overrides)
+	resolved.Hooks = p.Hooks.clone()
+	resolved.ReconnectPolicy = p.ReconnectPolicy.clone()
+	resolved.LivenessProbe = p.LivenessProbe.clone()
+	resolved.Schedule = p.Schedule.clone()
+	resolved.IdleDisconnect = p.IdleDisconnect.clone()
+	resolved.KillSwitch = p.KillSwitch.clone()
+	resolved.resolved = true
+
+	if p.ParentID == "" {
+		return &resolved, nil
+	}
+	if p.IsTemplate {
+		return nil, ErrTemplateInheritance
+	}
+
+	parent, err := loader.Load(p.ParentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template: %w", err)
+	}
+	if !parent.IsTemplate {
+		return nil, fmt.Errorf("%w: %s", ErrParentNotTemplate, parent.Name)
+	}
+
+	// Settings an administrator locked on a system template cannot be overridden
+	for _, f := range inheritableFields {
+		if !p.IsOverridden(f.key) || parent.IsLockedByAdmin(f.key) {
+			f.set(&resolved, parent)
+		}
+	}
+
+	return &resolved, nil
+}
+
+// Children returns the profiles that inherit from the template with the given ID.
+func Children(profiles []*Profile, templateID string) []*Profile {
+	var children []*Profile
+	for _, p := range profiles {
+		if p.ParentID == templateID {
+			children = append(children, p)
+		}
+	}
+	return children
+}
+
+// Templates returns the profiles that are marked as templates.
+func Templates(profiles []*Profile) []*Profile {
+	var templates []*Profile
+	for _, p := range profiles {
+		if p.IsTemplate {
+			templates = append(templates, p)
+		}
+	}
+	return templates
+}
+
+// profileJSON has the same fields as Profile without its JSON methods.
+type profileJSON Profile
+
+// MarshalJSON implements json.Marshaler.
+// Profiles that inherit from a template only store their overridden settings.
+func (p Profile) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(profileJSON(p))
+	if err != nil || p.ParentID == "" {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for _, f := range inheritableFields {
+		if !p.IsOverridden(f.key) {
+			delete(fields, f.key)
+			continue
+		}
+		// Overridden values are stored even if they are zero values omitted by default
+		value, err := json.Marshal(f.value(&p))
+		if err != nil {
+			return nil, err
+		}
+		fields[f.key] = value
+	}
+
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// For profiles that inherit from a template, the settings present in the data
+// are recorded as overrides.
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	var raw profileJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*p = Profile(raw)
+
+	if p.ParentID == "" {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	p.Overrides = nil
+	for _, f := range inheritableFields {
+		if _, ok := fields[f.key]; ok {
+			p.Overrides = append(p.Overrides, f.key)
+		}
+	}
+
+	return nil
+}
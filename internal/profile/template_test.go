@@ -0,0 +1,231 @@
+package profile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTemplatePair(t *testing.T) (*Store, *Profile, *Profile) {
+	t.Helper()
+
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	template := NewProfile("Office")
+	template.IsTemplate = true
+	template.Host = "vpn.example.com"
+	template.Port = 10443
+	template.Username = "jdoe"
+	template.Realm = "staff"
+	template.TrustedCert = "abc123"
+	require.NoError(t, store.Save(template))
+
+	child := NewProfile("Branch")
+	child.ParentID = template.ID
+	child.Host = "branch.example.com"
+	child.SetDNS = false
+	child.SetOverridden("host", true)
+	child.SetOverridden("set_dns", true)
+	require.NoError(t, store.Save(child))
+
+	return store, template, child
+}
+
+func TestResolve(t *testing.T) {
+	store, template, child := newTemplatePair(t)
+
+	loaded, err := store.Load(child.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"host", "set_dns"}, loaded.Overrides)
+
+	resolved, err := Resolve(loaded, store)
+	require.NoError(t, err)
+
+	// Overridden settings come from the child
+	assert.Equal(t, "branch.example.com", resolved.Host)
+	assert.False(t, resolved.SetDNS)
+	// Everything else comes from the template
+	assert.Equal(t, 10443, resolved.Port)
+	assert.Equal(t, "jdoe", resolved.Username)
+	assert.Equal(t, "staff", resolved.Realm)
+	assert.Equal(t, "abc123", resolved.TrustedCert)
+	assert.Equal(t, template.SetRoutes, resolved.SetRoutes)
+	// Identity stays with the child
+	assert.Equal(t, child.ID, resolved.ID)
+	assert.Equal(t, "Branch", resolved.Name)
+
+	require.NoError(t, resolved.Validate())
+	assert.ErrorIs(t, loaded.Validate(), ErrUnresolvedProfile)
+}
+
+func TestResolve_TemplateChangesPropagate(t *testing.T) {
+	store, template, child := newTemplatePair(t)
+
+	template.Realm = "contractors"
+	template.Host = "new.example.com"
+	require.NoError(t, store.Save(template))
+
+	loaded, err := store.Load(child.ID)
+	require.NoError(t, err)
+	resolved, err := Resolve(loaded, store)
+	require.NoError(t, err)
+
+	assert.Equal(t, "contractors", resolved.Realm)
+	assert.Equal(t, "branch.example.com", resolved.Host, "overridden host must not change")
+}
+
+func TestResolve_Errors(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	regular := NewProfile("Regular")
+	regular.Host = "vpn.example.com"
+	regular.Username = "user"
+	require.NoError(t, store.Save(regular))
+
+	t.Run("parent is not a template", func(t *testing.T) {
+		child := NewProfile("Child")
+		child.ParentID = regular.ID
+		_, err := Resolve(child, store)
+		assert.ErrorIs(t, err, ErrParentNotTemplate)
+	})
+
+	t.Run("parent missing", func(t *testing.T) {
+		child := NewProfile("Child")
+		child.ParentID = "550e8400-e29b-41d4-a716-446655440000"
+		_, err := Resolve(child, store)
+		assert.ErrorIs(t, err, ErrStoreNotFound)
+	})
+
+	t.Run("template with parent", func(t *testing.T) {
+		template := NewProfile("Nested")
+		template.IsTemplate = true
+		template.ParentID = regular.ID
+		_, err := Resolve(template, store)
+		assert.ErrorIs(t, err, ErrTemplateInheritance)
+	})
+
+	t.Run("no parent returns copy", func(t *testing.T) {
+		resolved, err := Resolve(regular, store)
+		require.NoError(t, err)
+		assert.NotSame(t, regular, resolved)
+		assert.Equal(t, regular.Host, resolved.Host)
+		require.NoError(t, resolved.Validate())
+	})
+}
+
+func TestProfile_MarshalJSON_ChildStoresOnlyOverrides(t *testing.T) {
+	child := NewProfile("Branch")
+	child.ParentID = "550e8400-e29b-41d4-a716-446655440000"
+	child.Host = "branch.example.com"
+	child.Username = "ignored"
+	child.AutoReconnect = false
+	child.SetOverridden("host", true)
+	child.SetOverridden("auto_reconnect", true)
+
+	data, err := json.Marshal(child)
+	require.NoError(t, err)
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(data, &fields))
+
+	assert.Equal(t, "branch.example.com", fields["host"])
+	assert.Equal(t, false, fields["auto_reconnect"], "overridden false values must be stored")
+	assert.Equal(t, "Branch", fields["name"])
+	assert.NotContains(t, fields, "username")
+	assert.NotContains(t, fields, "port")
+	assert.NotContains(t, fields, "set_dns")
+
+	var decoded Profile
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.ElementsMatch(t, []string{"host", "auto_reconnect"}, decoded.Overrides)
+	assert.Equal(t, "branch.example.com", decoded.Host)
+	assert.Empty(t, decoded.Username)
+}
+
+func TestProfile_MarshalJSON_RegularProfileUnchanged(t *testing.T) {
+	p := NewProfile("Regular")
+	p.Host = "vpn.example.com"
+
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+
+	var decoded Profile
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, *p, decoded)
+	assert.Nil(t, decoded.Overrides)
+	assert.True(t, decoded.IsOverridden("host"))
+}
+
+func TestProfile_SetOverridden(t *testing.T) {
+	p := NewProfile("Child")
+	p.ParentID = "550e8400-e29b-41d4-a716-446655440000"
+
+	assert.False(t, p.IsOverridden("realm"))
+	p.SetOverridden("realm", true)
+	p.SetOverridden("realm", true)
+	assert.Equal(t, []string{"realm"}, p.Overrides)
+	assert.True(t, p.IsOverridden("realm"))
+
+	p.SetOverridden("realm", false)
+	assert.Empty(t, p.Overrides)
+	assert.False(t, p.IsOverridden("realm"))
+}
+
+func TestProfile_InheritField(t *testing.T) {
+	parent := NewProfile("Parent")
+	parent.Realm = "staff"
+	child := NewProfile("Child")
+
+	assert.False(t, child.FieldEqual(parent, "realm"))
+	child.InheritField(parent, "realm")
+	assert.Equal(t, "staff", child.Realm)
+	assert.True(t, child.FieldEqual(parent, "realm"))
+	assert.False(t, child.FieldEqual(parent, "unknown"))
+}
+
+func TestProfile_Validate_Template(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(p *Profile)
+		wantErr bool
+	}{
+		{name: "template without host or username", modify: func(p *Profile) {}},
+		{name: "certificate template without paths", modify: func(p *Profile) { p.AuthMethod = AuthMethodCertificate }},
+		{name: "template with invalid host", modify: func(p *Profile) { p.Host = "bad;host" }, wantErr: true},
+		{name: "template with invalid port", modify: func(p *Profile) { p.Port = 0 }, wantErr: true},
+		{name: "template with parent", modify: func(p *Profile) { p.ParentID = "550e8400-e29b-41d4-a716-446655440000" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile("Template")
+			p.IsTemplate = true
+			tt.modify(p)
+
+			err := p.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestChildrenAndTemplates(t *testing.T) {
+	template := NewProfile("Template")
+	template.IsTemplate = true
+	child := NewProfile("Child")
+	child.ParentID = template.ID
+	other := NewProfile("Other")
+
+	profiles := []*Profile{template, child, other}
+
+	assert.Equal(t, []*Profile{child}, Children(profiles, template.ID))
+	assert.Empty(t, Children(profiles, other.ID))
+	assert.Equal(t, []*Profile{template}, Templates(profiles))
+}
@@ -0,0 +1,298 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/google/uuid"
+)
+
+// WatchEventType describes how a stored profile changed.
+type WatchEventType string
+
+const (
+	// WatchAdded is reported for a profile file that appeared.
+	WatchAdded WatchEventType = "added"
+	// WatchChanged is reported for a profile file whose contents changed.
+	WatchChanged WatchEventType = "changed"
+	// WatchRemoved is reported for a profile file that was deleted.
+	WatchRemoved WatchEventType = "removed"
+)
+
+// WatchEvent describes a change to the profile directory.
+type WatchEvent struct {
+	Type WatchEventType
+	ID   string
+	// Profile is the stored profile after the change. It is nil for WatchRemoved.
+	Profile *Profile
+}
+
+const (
+	// defaultWatchDebounce is how long the directory must be quiet before it is rescanned.
+	// Editors and configuration management tools often touch a file several times in a row.
+	defaultWatchDebounce = 200 * time.Millisecond
+
+	// watchMask selects the inotify events that can change the set of stored profiles.
+	// Store.Save replaces files by rename, other tools may write in place.
+	watchMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM |
+		syscall.IN_DELETE | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+	// baseWatchMask also reports the overlay directory being created in the
+	// user's profile directory, so it can be watched from then on.
+	baseWatchMask = watchMask | syscall.IN_CREATE
+
+	// watchBufferSize holds many events; each is at most a header plus NAME_MAX+1 bytes.
+	watchBufferSize = 64 * (syscall.SizeofInotifyEvent + syscall.NAME_MAX + 1)
+)
+
+// errWatchedDirGone is returned by Run when the profile directory is removed or moved.
+var errWatchedDirGone = errors.New("profile directory was removed")
+
+// WatcherOption configures a Watcher.
+type WatcherOption func(*Watcher)
+
+// WithDebounce sets how long the directory must be quiet before changes are reported.
+func WithDebounce(d time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.debounce = d
+	}
+}
+
+// Watcher reports profiles added, changed, or removed in a Store's directories
+// by other processes, such as configuration management or a second instance.
+// Changes are detected with inotify and confirmed by comparing file revisions,
+// so writes that leave a profile's contents unchanged are not reported, and
+// neither are saves and deletes made through the watched Store itself.
+type Watcher struct {
+	store     *Store
+	file      *os.File
+	baseWatch int32 // Watch descriptor of the user's profile directory
+	debounce  time.Duration
+
+	// known maps profile IDs to the revision last reported.
+	known map[string]string
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewWatcher starts watching the store's directory.
+// Profiles stored at this point are taken as known; only later changes are reported.
+func NewWatcher(store *Store, opts ...WatcherOption) (*Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+	baseWatch, err := syscall.InotifyAddWatch(fd, store.Dir(), baseWatchMask)
+	if err != nil {
+		_ = syscall.Close(fd)
+		return nil, fmt.Errorf("failed to watch profile directory: %w", err)
+	}
+	// System directories are optional; those missing at startup are not watched
+	for _, dir := range store.SystemDirs() {
+		_, _ = syscall.InotifyAddWatch(fd, dir, watchMask)
+	}
+	// The user's values for system profiles are named by the profile ID, so
+	// their changes show up as changes of the system profile. The directory
+	// is created with the first of them and watched once it appears.
+	_, _ = syscall.InotifyAddWatch(fd, store.overlayDir(), watchMask)
+
+	w := &Watcher{
+		store: store,
+		// A non-blocking descriptor is handed to the runtime poller, so Close
+		// interrupts a pending Read.
+		file:      os.NewFile(uintptr(fd), "inotify"),
+		baseWatch: int32(baseWatch),
+		debounce:  defaultWatchDebounce,
+		known:     make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	result, err := store.List()
+	if err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	for _, p := range result.Profiles {
+		w.known[p.ID] = p.Revision
+	}
+
+	return w, nil
+}
+
+// Run reports changes to handler until ctx is cancelled or the watcher is closed.
+// Changes found together are passed in one call, ordered by profile ID within each type.
+// The handler is called from Run's goroutine. Run closes the watcher on return
+// and returns nil when stopped by ctx or Close.
+func (w *Watcher) Run(ctx context.Context, handler func([]WatchEvent)) error {
+	defer func() { _ = w.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = w.Close() })
+	defer stop()
+
+	changed := make(chan struct{}, 1)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- w.read(changed)
+	}()
+
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if errors.Is(err, os.ErrClosed) {
+				return nil
+			}
+			return err
+		case <-changed:
+			timer.Reset(w.debounce)
+		case <-timer.C:
+			if events := w.rescan(); len(events) > 0 {
+				handler(events)
+			}
+		}
+	}
+}
+
+// Close stops watching. It is safe to call more than once.
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		w.closeErr = w.file.Close()
+	})
+	return w.closeErr
+}
+
+// read decodes inotify events and signals changed for those affecting profile files.
+// It returns when the descriptor is closed or the directory goes away.
+func (w *Watcher) read(changed chan<- struct{}) error {
+	buf := make([]byte, watchBufferSize)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return err
+		}
+
+		relevant := false
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			// #nosec G103 -- the kernel guarantees a complete header at this offset
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			nameEnd := min(nameStart+int(event.Len), n)
+			name := strings.TrimRight(string(buf[nameStart:nameEnd]), "\x00")
+			offset = nameEnd
+
+			switch {
+			case event.Mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0:
+				if event.Wd == w.baseWatch {
+					return errWatchedDirGone
+				}
+				// A system directory went away; its profiles are gone as well
+				relevant = true
+			case event.Mask&syscall.IN_Q_OVERFLOW != 0:
+				// Events were dropped; a rescan catches up regardless.
+				relevant = true
+			case event.Wd == w.baseWatch && name == systemOverlayDirName &&
+				event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+				// Values may have been written before the watch was added
+				w.watchOverlayDir()
+				relevant = true
+			case event.Mask&syscall.IN_CREATE != 0:
+				// Created files are reported once written
+			case isProfileFileName(name):
+				relevant = true
+			}
+		}
+
+		if relevant {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// watchOverlayDir starts watching the directory holding the user's values for system profiles.
+func (w *Watcher) watchOverlayDir() {
+	conn, err := w.file.SyscallConn()
+	if err != nil {
+		return
+	}
+	// The raw descriptor is used under Control, so it cannot be closed meanwhile
+	_ = conn.Control(func(fd uintptr) {
+		_, _ = syscall.InotifyAddWatch(int(fd), w.store.overlayDir(), watchMask)
+	})
+}
+
+// rescan lists the store and returns the differences to the known profiles.
+// Profiles that fail to load are left as they were, so a file being rewritten
+// by a tool that does not write atomically is not reported as removed.
+func (w *Watcher) rescan() []WatchEvent {
+	result, err := w.store.List()
+	if err != nil {
+		return nil
+	}
+
+	current := make(map[string]*Profile, len(result.Profiles))
+	for _, p := range result.Profiles {
+		current[p.ID] = p
+	}
+	unreadable := make(map[string]bool, len(result.Errors))
+	for _, e := range result.Errors {
+		unreadable[e.ProfileID] = true
+	}
+
+	var events []WatchEvent
+	for _, id := range slices.Sorted(maps.Keys(current)) {
+		p := current[id]
+		rev, ok := w.known[id]
+		if ok && rev == p.Revision {
+			continue
+		}
+		w.known[id] = p.Revision
+		if w.store.wrote(id, p.Revision) {
+			continue
+		}
+		if ok {
+			events = append(events, WatchEvent{Type: WatchChanged, ID: id, Profile: p})
+		} else {
+			events = append(events, WatchEvent{Type: WatchAdded, ID: id, Profile: p})
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(w.known)) {
+		if _, ok := current[id]; ok || unreadable[id] {
+			continue
+		}
+		delete(w.known, id)
+		if !w.store.wrote(id, "") {
+			events = append(events, WatchEvent{Type: WatchRemoved, ID: id})
+		}
+	}
+
+	return events
+}
+
+// isProfileFileName reports whether name is a file Store.List would load.
+func isProfileFileName(name string) bool {
+	id, ok := strings.CutSuffix(name, ".json")
+	if !ok {
+		return false
+	}
+	_, err := uuid.Parse(id)
+	return err == nil
+}
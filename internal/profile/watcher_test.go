@@ -0,0 +1,299 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchRecorder collects events reported by a running Watcher.
+type watchRecorder struct {
+	mu     sync.Mutex
+	events []WatchEvent
+}
+
+func (r *watchRecorder) record(events []WatchEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, events...)
+}
+
+func (r *watchRecorder) snapshot() []WatchEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]WatchEvent(nil), r.events...)
+}
+
+// startWatcher runs a watcher on store until the test ends.
+func startWatcher(t *testing.T, store *Store) *watchRecorder {
+	t.Helper()
+
+	w, err := NewWatcher(store, WithDebounce(20*time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rec := &watchRecorder{}
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, rec.record)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	return rec
+}
+
+// writeExternal writes a profile file the way another program would, bypassing store.
+func writeExternal(t *testing.T, store *Store, p *Profile) {
+	t.Helper()
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(store.Dir(), p.ID+".json"), data, 0600))
+}
+
+func TestWatcher_ReportsExternalChanges(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	// Another process uses its own store on the same directory.
+	other, err := NewStore(store.Dir())
+	require.NoError(t, err)
+
+	rec := startWatcher(t, store)
+
+	p := validTestProfile()
+	require.NoError(t, other.Save(p))
+	require.Eventually(t, func() bool { return len(rec.snapshot()) == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	p.Name = "Renamed"
+	require.NoError(t, other.Save(p))
+	require.Eventually(t, func() bool { return len(rec.snapshot()) == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, other.Delete(p.ID))
+	require.Eventually(t, func() bool { return len(rec.snapshot()) == 3 }, 2*time.Second, 10*time.Millisecond)
+
+	events := rec.snapshot()
+	assert.Equal(t, WatchAdded, events[0].Type)
+	assert.Equal(t, p.ID, events[0].ID)
+	require.NotNil(t, events[0].Profile)
+	assert.Equal(t, "Test VPN", events[0].Profile.Name)
+
+	assert.Equal(t, WatchChanged, events[1].Type)
+	require.NotNil(t, events[1].Profile)
+	assert.Equal(t, "Renamed", events[1].Profile.Name)
+	assert.Equal(t, p.Revision, events[1].Profile.Revision)
+
+	assert.Equal(t, WatchRemoved, events[2].Type)
+	assert.Equal(t, p.ID, events[2].ID)
+	assert.Nil(t, events[2].Profile)
+}
+
+func TestWatcher_ReportsFilesWrittenInPlace(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	rec := startWatcher(t, store)
+
+	writeExternal(t, store, validTestProfile())
+
+	require.Eventually(t, func() bool { return len(rec.snapshot()) == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, WatchAdded, rec.snapshot()[0].Type)
+}
+
+func TestWatcher_IgnoresOwnWrites(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	rec := startWatcher(t, store)
+
+	p := validTestProfile()
+	require.NoError(t, store.Save(p))
+	p.Name = "Renamed"
+	require.NoError(t, store.Save(p))
+	require.NoError(t, store.Delete(p.ID))
+
+	// An external write afterwards shows the watcher was running all along.
+	marker := NewProfile("Marker")
+	writeExternal(t, store, marker)
+	require.Eventually(t, func() bool { return len(rec.snapshot()) > 0 }, 2*time.Second, 10*time.Millisecond)
+
+	events := rec.snapshot()
+	require.Len(t, events, 1)
+	assert.Equal(t, marker.ID, events[0].ID)
+}
+
+func TestWatcher_IgnoresUnchangedContents(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	p := validTestProfile()
+	require.NoError(t, store.Save(p))
+	data, err := os.ReadFile(filepath.Join(store.Dir(), p.ID+".json"))
+	require.NoError(t, err)
+
+	rec := startWatcher(t, store)
+
+	// Rewriting identical contents, as configuration management runs do, is not a change.
+	require.NoError(t, os.WriteFile(filepath.Join(store.Dir(), p.ID+".json"), data, 0600))
+	marker := NewProfile("Marker")
+	writeExternal(t, store, marker)
+	require.Eventually(t, func() bool { return len(rec.snapshot()) > 0 }, 2*time.Second, 10*time.Millisecond)
+
+	events := rec.snapshot()
+	require.Len(t, events, 1)
+	assert.Equal(t, marker.ID, events[0].ID)
+}
+
+func TestWatcher_UnreadableFileIsNotRemoved(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	p := validTestProfile()
+	require.NoError(t, store.Save(p))
+	rec := startWatcher(t, store)
+
+	path := filepath.Join(store.Dir(), p.ID+".json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0600))
+	marker := NewProfile("Marker")
+	writeExternal(t, store, marker)
+	require.Eventually(t, func() bool { return len(rec.snapshot()) > 0 }, 2*time.Second, 10*time.Millisecond)
+
+	events := rec.snapshot()
+	require.Len(t, events, 1)
+	assert.Equal(t, WatchAdded, events[0].Type)
+	assert.Equal(t, marker.ID, events[0].ID)
+}
+
+func TestWatcher_IgnoresOtherFiles(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	w, err := NewWatcher(store, WithDebounce(time.Millisecond))
+	require.NoError(t, err)
+	defer func() { _ = w.Close() }()
+
+	changed := make(chan struct{}, 1)
+	go func() { _ = w.read(changed) }()
+
+	require.NoError(t, os.WriteFile(filepath.Join(store.Dir(), "notes.txt"), []byte("x"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(store.Dir(), "not-a-uuid.json"), []byte("{}"), 0600))
+	_, err = store.List() // touches the lock file
+
+	require.NoError(t, err)
+	select {
+	case <-changed:
+		t.Fatal("non-profile files must not trigger a rescan")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatcher_CloseStopsRun(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	w, err := NewWatcher(store)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(context.Background(), func([]WatchEvent) {})
+	}()
+
+	require.NoError(t, w.Close())
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after Close")
+	}
+	assert.NoError(t, w.Close())
+}
+
+func TestWatcher_DirectoryRemoved(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "profiles"))
+	require.NoError(t, err)
+
+	w, err := NewWatcher(store)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(context.Background(), func([]WatchEvent) {})
+	}()
+
+	require.NoError(t, os.RemoveAll(store.Dir()))
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, errWatchedDirGone)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after the directory was removed")
+	}
+}
+
+func TestNewWatcher_MissingDirectory(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "profiles"))
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(store.Dir()))
+
+	_, err = NewWatcher(store)
+	assert.Error(t, err)
+}
+
+func TestIsProfileFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"550e8400-e29b-41d4-a716-446655440000.json", true},
+		{"550e8400-e29b-41d4-a716-446655440000.json.tmp.123", false},
+		{"550e8400-e29b-41d4-a716-446655440000.json.v0.bak", false},
+		{".lock", false},
+		{"profile.json", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isProfileFileName(tt.name))
+		})
+	}
+}
+
+func TestWatcher_BatchesChangesWithinDebounce(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	w, err := NewWatcher(store, WithDebounce(100*time.Millisecond))
+	require.NoError(t, err)
+
+	batches := make(chan []WatchEvent, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(events []WatchEvent) { batches <- events })
+	}()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-done)
+	}()
+
+	first := NewProfile("First")
+	second := NewProfile("Second")
+	writeExternal(t, store, first)
+	writeExternal(t, store, second)
+
+	select {
+	case events := <-batches:
+		require.Len(t, events, 2)
+		assert.ElementsMatch(t, []string{first.ID, second.ID}, []string{events[0].ID, events[1].ID})
+	case <-time.After(2 * time.Second):
+		t.Fatal("no changes reported")
+	}
+}
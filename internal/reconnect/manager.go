@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"math/rand/v2"
 	"sync"
 	"time"
 
@@ -13,9 +14,58 @@ import (
 )
 
 // Config holds reconnection configuration.
+// A profile's reconnect policy replaces it for that profile's connections.
 type Config struct {
+	// MaxAttempts limits the consecutive attempts; profile.ReconnectForever retries until one succeeds.
 	MaxAttempts  int
 	DelaySeconds int
+	// Backoff doubles the delay with each further attempt, up to MaxDelay.
+	Backoff  bool
+	MaxDelay time.Duration
+	// JitterPercent randomly lengthens or shortens each delay by up to this share.
+	JitterPercent int
+	// ResetAfter is how long a connection must stay up before the attempts count
+	// starts over. Zero starts over as soon as a connection succeeds.
+	ResetAfter time.Duration
+}
+
+// ConfigFromPolicy returns the configuration for a profile's reconnect policy.
+func ConfigFromPolicy(policy *profile.ReconnectPolicy) Config {
+	return Config{
+		MaxAttempts:   policy.MaxAttempts,
+		DelaySeconds:  policy.DelaySeconds,
+		Backoff:       policy.Backoff,
+		MaxDelay:      policy.MaxDelay(),
+		JitterPercent: policy.JitterPercent,
+		ResetAfter:    time.Duration(policy.ResetAfterSeconds) * time.Second,
+	}
+}
+
+// retriesForever reports whether attempts are unlimited.
+func (c Config) retriesForever() bool {
+	return c.MaxAttempts == profile.ReconnectForever
+}
+
+// Delay returns how long to wait before the given attempt, starting at 1.
+// random returns a value in [0, 1) used for the jitter.
+func (c Config) Delay(attempt int, random func() float64) time.Duration {
+	delay := time.Duration(c.DelaySeconds) * time.Second
+	if c.Backoff {
+		maxDelay := c.MaxDelay
+		if maxDelay <= 0 {
+			maxDelay = profile.DefaultMaxReconnectDelay
+		}
+		for i := 1; i < attempt && delay < maxDelay; i++ {
+			delay *= 2
+		}
+		delay = min(delay, maxDelay)
+	}
+
+	if c.JitterPercent > 0 && delay > 0 {
+		spread := float64(delay) * float64(c.JitterPercent) / 100
+		delay += time.Duration((random()*2 - 1) * spread)
+	}
+	return delay
 }
 
 // DefaultConfig returns default reconnection configuration.
@@ -41,6 +91,12 @@ type Callbacks struct {
 	OnReconnecting func()
 	// OnFailed is called when reconnect fails and cannot continue.
 	OnFailed func(err error)
+	// OnScheduled is called when an attempt is scheduled, with the time it will start.
+	OnScheduled func(attempt int, at time.Time)
+	// OnWaitingForNetwork is called when losing the network pauses a scheduled attempt.
+	// Attempts that start out waiting, including those after Resume, are reported
+	// by IsWaitingForNetwork instead.
+	OnWaitingForNetwork func()
 }
 
 // Manager handles automatic VPN reconnection logic.
@@ -49,15 +105,25 @@ type Manager struct {
 	mu                      sync.Mutex
 	attemptCount            int
 	reconnectTimer          *time.Timer
+	resetTimer              *time.Timer // Starts the attempts count over once a connection proved stable
+	reconnecting            bool        // Set from the first attempt until the sequence ends
+	networkAvailable        bool
+	waitingForNetwork       bool // An attempt is paused until the network is back
+	cycling                 bool // The next attempt follows a deliberate cycle and starts right away
+	sleeping                bool // The system is suspending; its disconnect is intentional
+	resumeAfterSleep        bool // The tunnel closed for sleeping is restored after resuming
 	userInitiatedDisconnect bool
 	lastConnectedProfile    *profile.Profile
+	lastErrorCode           vpn.ErrorCode
 
 	config           Config
 	passwordProvider PasswordProvider
 	connectFunc      ConnectFunc
+	gatewayProber    vpn.GatewayProber
 	callbacks        Callbacks
 	ctx              context.Context
 	scheduleOnMain   func(func()) // Schedules function to run on main/UI thread
+	random           func() float64
 }
 
 // NewManager creates a new ReconnectManager.
@@ -65,8 +131,10 @@ type Manager struct {
 // (e.g., glib.IdleAdd in GTK applications).
 func NewManager(cfg Config, scheduleOnMain func(func())) *Manager {
 	return &Manager{
-		config:         cfg,
-		scheduleOnMain: scheduleOnMain,
+		config:           cfg,
+		scheduleOnMain:   scheduleOnMain,
+		random:           rand.Float64,
+		networkAvailable: true,
 	}
 }
 
@@ -84,6 +152,15 @@ func (m *Manager) SetConnectFunc(fn ConnectFunc) {
 	m.connectFunc = fn
 }
 
+// SetGatewayProber sets the prober used to skip unreachable gateways when
+// reconnecting a profile with backup gateways. Without a prober, reconnect
+// attempts simply move on to the next configured gateway.
+func (m *Manager) SetGatewayProber(prober vpn.GatewayProber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gatewayProber = prober
+}
+
 // SetContext sets the context for connection operations.
 func (m *Manager) SetContext(ctx context.Context) {
 	m.mu.Lock()
@@ -99,13 +176,17 @@ func (m *Manager) SetCallbacks(cb Callbacks) {
 }
 
 // OnConnectionSucceeded should be called when a connection succeeds.
-// Resets the attempt counter, clears user-initiated flag, and cancels any pending timer.
+// Clears the user-initiated flag and cancels any pending timer. The attempt
+// counter is reset right away, or once the connection stayed up for the
+// profile's reset window.
 func (m *Manager) OnConnectionSucceeded() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.attemptCount = 0
 	m.userInitiatedDisconnect = false
+	m.lastErrorCode = ""
+	m.waitingForNetwork = false
+	m.cycling = false
 
 	// Cancel any pending reconnect timer
 	if m.reconnectTimer != nil {
@@ -118,6 +199,51 @@ func (m *Manager) OnConnectionSucceeded() {
 		}
 		m.reconnectTimer = nil
 	}
+
+	m.stopResetTimerLocked()
+	resetAfter := m.configLocked().ResetAfter
+	if resetAfter <= 0 || m.attemptCount == 0 {
+		m.resetAttemptsLocked()
+		return
+	}
+
+	// A connection that drops again soon continues the backoff where it left off
+	var thisTimer *time.Timer
+	m.resetTimer = time.AfterFunc(resetAfter, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.resetTimer != thisTimer {
+			return
+		}
+		m.resetTimer = nil
+		slog.Debug("Connection stable, resetting reconnect attempts", "attempts", m.attemptCount)
+		m.resetAttemptsLocked()
+	})
+	thisTimer = m.resetTimer
+}
+
+// resetAttemptsLocked ends the reconnect sequence. The caller must hold m.mu.
+func (m *Manager) resetAttemptsLocked() {
+	m.attemptCount = 0
+	m.reconnecting = false
+}
+
+// stopResetTimerLocked keeps the attempts count, since the connection did not
+// stay up for the reset window. The caller must hold m.mu.
+func (m *Manager) stopResetTimerLocked() {
+	if m.resetTimer != nil {
+		m.resetTimer.Stop()
+		m.resetTimer = nil
+	}
+}
+
+// configLocked returns the reconnect configuration for the stored profile.
+// The caller must hold m.mu.
+func (m *Manager) configLocked() Config {
+	if p := m.lastConnectedProfile; p != nil && p.ReconnectPolicy != nil {
+		return ConfigFromPolicy(p.ReconnectPolicy)
+	}
+	return m.config
 }
 
 // SetUserDisconnect marks the next disconnect as user-initiated.
@@ -128,8 +254,31 @@ func (m *Manager) SetUserDisconnect() {
 	m.userInitiatedDisconnect = true
 }
 
+// OnConnectionError records a classified connection error.
+// Errors that cannot be resolved by retrying (e.g., rejected credentials or an
+// untrusted certificate) cancel any pending reconnect and suppress further
+// attempts until the next successful connection.
+func (m *Manager) OnConnectionError(code vpn.ErrorCode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastErrorCode = code
+	if code.Retryable() {
+		return
+	}
+
+	m.reconnecting = false
+	m.waitingForNetwork = false
+	if m.reconnectTimer != nil {
+		m.reconnectTimer.Stop()
+		m.reconnectTimer = nil
+		slog.Info("Cancelled pending reconnect: error is not retryable", "code", code)
+	}
+}
+
 // StoreConnectedProfile stores a copy of the profile for potential reconnection.
 // The profile is copied to prevent issues if the original is modified.
+// It is called for connections the user starts, which begin a new reconnect sequence.
 func (m *Manager) StoreConnectedProfile(p *profile.Profile) {
 	if p == nil {
 		return
@@ -138,22 +287,45 @@ func (m *Manager) StoreConnectedProfile(p *profile.Profile) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.stopResetTimerLocked()
+	m.resetAttemptsLocked()
+
 	// Store a copy to avoid mutations affecting reconnection
 	profileCopy := *p
 	m.lastConnectedProfile = &profileCopy
 }
 
 // ShouldReconnect determines if reconnection should be attempted based on state transition.
-// Returns true if the disconnect was unexpected and reconnection is allowed.
+// Returns true if the disconnect was unexpected and reconnection is allowed,
+// or if a reconnect attempt failed and the policy allows another one.
 func (m *Manager) ShouldReconnect(oldState, newState vpn.ConnectionState) bool {
-	// Only trigger on unexpected disconnect from Connected state
-	if oldState != vpn.StateConnected || newState != vpn.StateDisconnected {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dropped := oldState == vpn.StateConnected && newState == vpn.StateDisconnected
+	attemptFailed := m.reconnecting && oldState.IsTransitioning() &&
+		(newState == vpn.StateDisconnected || newState == vpn.StateFailed)
+	if !dropped && !attemptFailed {
 		return false
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	// Closing the tunnel before sleeping is not a failure; Resume restores it
+	if m.sleeping {
+		m.reconnecting = false
+		slog.Debug("Skipping auto-reconnect: system is going to sleep")
+		return false
+	}
 
+	if !m.shouldReconnectLocked() {
+		m.reconnecting = false
+		return false
+	}
+	return true
+}
+
+// shouldReconnectLocked checks whether the stored profile may be reconnected.
+// The caller must hold m.mu.
+func (m *Manager) shouldReconnectLocked() bool {
 	// Check for user-initiated disconnect
 	if m.userInitiatedDisconnect {
 		m.userInitiatedDisconnect = false // Reset flag
@@ -161,6 +333,12 @@ func (m *Manager) ShouldReconnect(oldState, newState vpn.ConnectionState) bool {
 		return false
 	}
 
+	// Errors such as rejected credentials will fail again on every attempt
+	if !m.lastErrorCode.Retryable() {
+		slog.Debug("Skipping auto-reconnect: last error is not retryable", "code", m.lastErrorCode)
+		return false
+	}
+
 	// Check if we have a profile to reconnect
 	p := m.lastConnectedProfile
 	if p == nil {
@@ -181,36 +359,78 @@ func (m *Manager) ShouldReconnect(oldState, newState vpn.ConnectionState) bool {
 	}
 
 	// Check attempt limit
-	if m.attemptCount >= m.config.MaxAttempts {
+	cfg := m.configLocked()
+	if !cfg.retriesForever() && m.attemptCount >= cfg.MaxAttempts {
 		slog.Warn("Max reconnect attempts reached",
 			"profile", p.Name,
 			"attempts", m.attemptCount,
-			"max", m.config.MaxAttempts)
+			"max", cfg.MaxAttempts)
 		return false
 	}
 
 	return true
 }
 
-// StartReconnect begins the reconnection sequence.
-// Increments attempt counter and schedules a reconnection after the configured delay.
+// StartReconnect begins the reconnection sequence, or continues it after a failed attempt.
+// Increments attempt counter and schedules a reconnection after the configured delay,
+// which grows with each attempt when the profile's policy uses backoff.
+// Profiles with backup gateways move on to the next gateway, since the one
+// that was just in use has dropped the tunnel.
+// Without a network the attempt waits until SetNetworkAvailable reports one.
 func (m *Manager) StartReconnect() {
 	m.mu.Lock()
 
+	m.stopResetTimerLocked()
 	m.attemptCount++
+	m.reconnecting = true
 	attempt := m.attemptCount
+	cfg := m.configLocked()
+	callbacks := m.callbacks
+	cycling := m.cycling
+	m.cycling = false
 	profileName := ""
 	if m.lastConnectedProfile != nil {
 		profileName = m.lastConnectedProfile.Name
+		// The gateway did not fail when the tunnel was cycled for a new uplink
+		if !cycling {
+			m.rotateGatewaysLocked()
+		}
 	}
 
 	// Stop any existing timer
 	if m.reconnectTimer != nil {
 		m.reconnectTimer.Stop()
+		m.reconnectTimer = nil
 	}
 
-	delay := time.Duration(m.config.DelaySeconds) * time.Second
+	if !m.networkAvailable {
+		m.waitingForNetwork = true
+		m.mu.Unlock()
+		slog.Info("Waiting for network before reconnecting", "profile", profileName, "attempt", attempt)
+		return
+	}
+
+	delay := max(cfg.Delay(attempt, m.random), 0)
+	if cycling {
+		delay = 0
+	}
+	at := m.scheduleLocked(delay)
+	m.mu.Unlock()
+
+	slog.Info("Scheduling reconnect attempt",
+		"profile", profileName,
+		"attempt", attempt,
+		"max", cfg.MaxAttempts,
+		"delay", delay)
 
+	if callbacks.OnScheduled != nil {
+		callbacks.OnScheduled(attempt, at)
+	}
+}
+
+// scheduleLocked starts the timer for the next attempt and returns when it fires.
+// The caller must hold m.mu.
+func (m *Manager) scheduleLocked(delay time.Duration) time.Time {
 	// Schedule reconnect on main thread.
 	// Capture timer reference to detect if it was cancelled/replaced before callback runs.
 	var thisTimer *time.Timer
@@ -223,6 +443,17 @@ func (m *Manager) StartReconnect() {
 		}
 		m.mu.Unlock()
 
+		m.skipUnreachableGateways(thisTimer)
+
+		// The attempt is no longer pending once it starts, unless it was cancelled while probing
+		m.mu.Lock()
+		if m.reconnectTimer != thisTimer {
+			m.mu.Unlock()
+			return
+		}
+		m.reconnectTimer = nil
+		m.mu.Unlock()
+
 		if m.scheduleOnMain != nil {
 			m.scheduleOnMain(m.performReconnect)
 		} else {
@@ -230,20 +461,210 @@ func (m *Manager) StartReconnect() {
 		}
 	})
 	thisTimer = m.reconnectTimer
+	return time.Now().Add(delay)
+}
+
+// SetNetworkAvailable reports whether the machine has a usable network uplink.
+// Losing the network pauses a pending attempt; once the network is back the
+// paused attempt starts right away instead of waiting out its delay.
+func (m *Manager) SetNetworkAvailable(available bool) {
+	m.mu.Lock()
+
+	if m.networkAvailable == available {
+		m.mu.Unlock()
+		return
+	}
+	m.networkAvailable = available
+	callbacks := m.callbacks
+	attempt := m.attemptCount
+
+	if !available {
+		if m.reconnectTimer == nil {
+			m.mu.Unlock()
+			return
+		}
+		m.reconnectTimer.Stop()
+		m.reconnectTimer = nil
+		m.waitingForNetwork = true
+		m.mu.Unlock()
+
+		slog.Info("Network lost, pausing reconnect", "attempt", attempt)
+		if callbacks.OnWaitingForNetwork != nil {
+			callbacks.OnWaitingForNetwork()
+		}
+		return
+	}
+
+	if !m.waitingForNetwork {
+		m.mu.Unlock()
+		return
+	}
+	m.waitingForNetwork = false
+	at := m.scheduleLocked(0)
 	m.mu.Unlock()
 
-	slog.Info("Scheduling reconnect attempt",
-		"profile", profileName,
-		"attempt", attempt,
-		"max", m.config.MaxAttempts,
-		"delay", delay)
+	slog.Info("Network available, reconnecting now", "attempt", attempt)
+	if callbacks.OnScheduled != nil {
+		callbacks.OnScheduled(attempt, at)
+	}
+}
+
+// IsWaitingForNetwork reports whether a reconnect attempt is paused until the network is back.
+func (m *Manager) IsWaitingForNetwork() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.waitingForNetwork
+}
+
+// PrepareCycle prepares an immediate reconnect after a disconnect the caller is
+// about to make, such as cycling a tunnel whose underlying uplink changed.
+// It returns false if the connected profile would not be reconnected, in which
+// case the caller should leave the connection alone.
+func (m *Manager) PrepareCycle() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.canReconnectLocked() {
+		return false
+	}
+	m.cycling = true
+	return true
+}
+
+// CanReconnect reports whether the connected profile would be reconnected if
+// its connection dropped now.
+func (m *Manager) CanReconnect() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.canReconnectLocked()
+}
+
+// canReconnectLocked reports whether the stored profile would be reconnected
+// after a drop. The caller must hold m.mu.
+func (m *Manager) canReconnectLocked() bool {
+	if !m.reconnectsAutomaticallyLocked() || m.userInitiatedDisconnect || !m.lastErrorCode.Retryable() {
+		return false
+	}
+	cfg := m.configLocked()
+	return cfg.retriesForever() || m.attemptCount < cfg.MaxAttempts
+}
+
+// reconnectsAutomaticallyLocked reports whether the stored profile can be
+// reconnected without the user. The caller must hold m.mu.
+func (m *Manager) reconnectsAutomaticallyLocked() bool {
+	p := m.lastConnectedProfile
+	return p != nil && p.AutoReconnect && p.AuthMethod != profile.AuthMethodOTP
+}
+
+// PrepareForSleep is called before the system sleeps. It stops any pending
+// attempt and marks the disconnect the caller is about to make as intentional,
+// so it neither counts as an attempt nor starts a reconnect. active tells
+// whether a connection is up or being established.
+// The tunnel is restored by Resume if it was active or being reconnected and
+// the profile reconnects automatically.
+func (m *Manager) PrepareForSleep(active bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sleeping {
+		return
+	}
+	m.sleeping = true
+	m.resumeAfterSleep = (active || m.reconnecting) && m.reconnectsAutomaticallyLocked() && !m.userInitiatedDisconnect
+
+	// Sleeping is not a failure, so attempts start over after resuming
+	m.stopResetTimerLocked()
+	m.resetAttemptsLocked()
+	m.waitingForNetwork = false
+	m.cycling = false
+	if m.reconnectTimer != nil {
+		m.reconnectTimer.Stop()
+		m.reconnectTimer = nil
+	}
+	slog.Debug("Preparing reconnect for sleep", "resume", m.resumeAfterSleep)
+}
+
+// Resume is called after the system woke up. It restores the tunnel closed by
+// PrepareForSleep right away on the same gateway, or once SetNetworkAvailable
+// reports a network. It returns whether a reconnect was started, in which case
+// IsWaitingForNetwork tells whether it waits for the network.
+func (m *Manager) Resume() bool {
+	m.mu.Lock()
+	resume := m.resumeAfterSleep
+	m.sleeping = false
+	m.resumeAfterSleep = false
+	// Like a cycled tunnel, the gateway did not fail and there is nothing to wait for
+	m.cycling = resume
+	m.mu.Unlock()
+
+	if !resume {
+		return false
+	}
+	slog.Info("Restoring connection after sleep")
+	m.StartReconnect()
+	return true
+}
+
+// WillResume reports whether Resume will restore the tunnel closed for sleeping.
+func (m *Manager) WillResume() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.resumeAfterSleep
+}
+
+// rotateGatewaysLocked moves the stored profile's current gateway behind its backup gateways.
+// The caller must hold m.mu.
+func (m *Manager) rotateGatewaysLocked() {
+	p := m.lastConnectedProfile
+	endpoints := p.Endpoints()
+	if len(endpoints) < 2 {
+		return
+	}
+
+	rotated := append(endpoints[1:], endpoints[0])
+	m.lastConnectedProfile = p.WithEndpoints(rotated)
+	slog.Info("Switching to next gateway for reconnect",
+		"profile", p.Name, "failed", endpoints[0].String(), "next", rotated[0].String())
 }
 
-// Cancel stops any pending reconnection attempt.
+// skipUnreachableGateways probes the stored profile's gateways and moves the
+// unreachable ones to the end, keeping the rotation order otherwise.
+// It runs off the main thread and only applies the result if the given
+// timer is still the active one.
+func (m *Manager) skipUnreachableGateways(timer *time.Timer) {
+	m.mu.Lock()
+	p := m.lastConnectedProfile
+	prober := m.gatewayProber
+	ctx := m.ctx
+	m.mu.Unlock()
+
+	if p == nil || prober == nil || len(p.Endpoints()) < 2 {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ordered := vpn.OrderGateways(ctx, p.Endpoints(), profile.GatewaySelectionOrdered, prober)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.reconnectTimer != timer || m.lastConnectedProfile != p {
+		return
+	}
+	m.lastConnectedProfile = p.WithEndpoints(ordered)
+}
+
+// Cancel stops any pending reconnection attempt and ends the reconnect sequence,
+// including the one Resume would start after sleeping.
 func (m *Manager) Cancel() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.reconnecting = false
+	m.waitingForNetwork = false
+	m.cycling = false
+	m.resumeAfterSleep = false
 	if m.reconnectTimer != nil {
 		m.reconnectTimer.Stop()
 		m.reconnectTimer = nil
@@ -251,6 +672,22 @@ func (m *Manager) Cancel() {
 	}
 }
 
+// IsPending reports whether a reconnect attempt is scheduled or waiting for
+// the network but has not started yet.
+func (m *Manager) IsPending() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reconnectTimer != nil || m.waitingForNetwork
+}
+
+// IsReconnecting reports whether a reconnect sequence is under way and was not
+// ended by Cancel, a user-initiated disconnect or the system going to sleep.
+func (m *Manager) IsReconnecting() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reconnecting && !m.userInitiatedDisconnect && !m.sleeping
+}
+
 // GetAttemptCount returns the current reconnection attempt count.
 func (m *Manager) GetAttemptCount() int {
 	m.mu.Lock()
@@ -263,6 +700,8 @@ func (m *Manager) performReconnect() {
 	p := m.lastConnectedProfile
 	attempt := m.attemptCount
 	userDisconnected := m.userInitiatedDisconnect
+	sleeping := m.sleeping
+	lastErrorCode := m.lastErrorCode
 	ctx := m.ctx
 	connectFunc := m.connectFunc
 	passwordProvider := m.passwordProvider
@@ -275,6 +714,16 @@ func (m *Manager) performReconnect() {
 		return
 	}
 
+	if sleeping {
+		slog.Debug("Skipping reconnect: system is going to sleep")
+		return
+	}
+
+	if !lastErrorCode.Retryable() {
+		slog.Debug("Skipping reconnect: last error is not retryable", "code", lastErrorCode)
+		return
+	}
+
 	if p == nil {
 		slog.Error("Cannot reconnect: no profile stored")
 		return
@@ -303,10 +752,11 @@ func (m *Manager) performReconnect() {
 
 		var err error
 		password, err = passwordProvider.Get(p.ID)
-		if err != nil || password == "" {
-			if err == nil {
-				err = errors.New("password is empty")
-			}
+		// A client certificate may authenticate on its own
+		if err == nil && password == "" && p.AuthMethod != profile.AuthMethodCertificate {
+			err = errors.New("password is empty")
+		}
+		if err != nil {
 			slog.Error("Cannot reconnect: password not available in keyring",
 				"profile", p.Name, "error", err)
 			if callbacks.OnFailed != nil {
@@ -168,6 +168,46 @@ func TestManager_ShouldReconnect_MaxAttemptsReached(t *testing.T) {
 	assert.False(t, result)
 }
 
+func TestManager_ShouldReconnect_NonRetryableError(t *testing.T) {
+	m := NewManager(DefaultConfig(), nil)
+	m.lastConnectedProfile = &profile.Profile{
+		AutoReconnect: true,
+		AuthMethod:    profile.AuthMethodPassword,
+	}
+
+	m.OnConnectionError(vpn.ErrorCodeAuthFailed)
+
+	assert.False(t, m.ShouldReconnect(vpn.StateConnected, vpn.StateDisconnected))
+
+	// A successful connection clears the recorded error
+	m.OnConnectionSucceeded()
+	assert.True(t, m.ShouldReconnect(vpn.StateConnected, vpn.StateDisconnected))
+}
+
+func TestManager_ShouldReconnect_RetryableError(t *testing.T) {
+	m := NewManager(DefaultConfig(), nil)
+	m.lastConnectedProfile = &profile.Profile{
+		AutoReconnect: true,
+		AuthMethod:    profile.AuthMethodPassword,
+	}
+
+	m.OnConnectionError(vpn.ErrorCodeGatewayUnreachable)
+
+	assert.True(t, m.ShouldReconnect(vpn.StateConnected, vpn.StateDisconnected))
+}
+
+func TestManager_OnConnectionError_CancelsPendingReconnect(t *testing.T) {
+	cfg := Config{MaxAttempts: 3, DelaySeconds: 10}
+	m := NewManager(cfg, nil)
+	m.lastConnectedProfile = &profile.Profile{Name: "Test"}
+
+	m.StartReconnect()
+	require.NotNil(t, m.reconnectTimer)
+
+	m.OnConnectionError(vpn.ErrorCodeCertificateMismatch)
+	assert.Nil(t, m.reconnectTimer)
+}
+
 func TestManager_StartReconnect(t *testing.T) {
 	scheduled := make(chan struct{})
 	scheduleOnMain := func(fn func()) {
@@ -194,6 +234,83 @@ func TestManager_StartReconnect(t *testing.T) {
 	}
 }
 
+// unreachableProber reports the listed hosts as unreachable.
+type unreachableProber struct {
+	hosts map[string]bool
+}
+
+func (u *unreachableProber) Probe(_ context.Context, gw profile.Gateway) (time.Duration, error) {
+	if u.hosts[gw.Host] {
+		return 0, errors.New("connection refused")
+	}
+	return time.Millisecond, nil
+}
+
+func haProfile() *profile.Profile {
+	return &profile.Profile{
+		ID:   "test-id",
+		Name: "HA",
+		Host: "vpn1.example.com",
+		Port: 443,
+		Gateways: []profile.Gateway{
+			{Host: "vpn2.example.com", Port: 443},
+			{Host: "vpn3.example.com", Port: 443},
+		},
+	}
+}
+
+func hosts(p *profile.Profile) []string {
+	var result []string
+	for _, gw := range p.Endpoints() {
+		result = append(result, gw.Host)
+	}
+	return result
+}
+
+func TestManager_StartReconnect_RotatesGateways(t *testing.T) {
+	cfg := Config{MaxAttempts: 3, DelaySeconds: 10}
+	m := NewManager(cfg, nil)
+	m.StoreConnectedProfile(haProfile())
+
+	m.StartReconnect()
+	assert.Equal(t, []string{"vpn2.example.com", "vpn3.example.com", "vpn1.example.com"}, hosts(m.lastConnectedProfile))
+
+	m.StartReconnect()
+	assert.Equal(t, []string{"vpn3.example.com", "vpn1.example.com", "vpn2.example.com"}, hosts(m.lastConnectedProfile))
+	m.Cancel()
+}
+
+func TestManager_StartReconnect_SkipsUnreachableGateways(t *testing.T) {
+	scheduled := make(chan struct{})
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 0}, func(fn func()) {
+		close(scheduled)
+	})
+	m.StoreConnectedProfile(haProfile())
+	m.SetGatewayProber(&unreachableProber{hosts: map[string]bool{"vpn2.example.com": true}})
+
+	m.StartReconnect()
+
+	select {
+	case <-scheduled:
+	case <-time.After(time.Second):
+		t.Fatal("scheduleOnMain was not called within timeout")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assert.Equal(t, []string{"vpn3.example.com", "vpn1.example.com", "vpn2.example.com"}, hosts(m.lastConnectedProfile))
+}
+
+func TestManager_StartReconnect_SingleGatewayUnchanged(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 10}, nil)
+	m.StoreConnectedProfile(&profile.Profile{ID: "test-id", Name: "Single", Host: "vpn.example.com", Port: 443})
+
+	m.StartReconnect()
+	assert.Equal(t, "vpn.example.com", m.lastConnectedProfile.Host)
+	assert.Empty(t, m.lastConnectedProfile.Gateways)
+	m.Cancel()
+}
+
 func TestManager_Cancel(t *testing.T) {
 	cfg := Config{MaxAttempts: 3, DelaySeconds: 10}
 	m := NewManager(cfg, nil)
@@ -270,6 +387,27 @@ func TestManager_PerformReconnect_SAML_NoPassword(t *testing.T) {
 	assert.Equal(t, "", connectedPassword)
 }
 
+func TestManager_PerformReconnect_Certificate_EmptyPassword(t *testing.T) {
+	connected := false
+
+	m := NewManager(DefaultConfig(), nil)
+	m.lastConnectedProfile = &profile.Profile{
+		ID:         "test-id",
+		Name:       "Certificate Profile",
+		AuthMethod: profile.AuthMethodCertificate,
+	}
+	m.passwordProvider = &mockPasswordProvider{passwords: map[string]string{"test-id": ""}}
+	m.connectFunc = func(ctx context.Context, p *profile.Profile, password string) error {
+		connected = true
+		assert.Empty(t, password)
+		return nil
+	}
+
+	m.performReconnect()
+
+	assert.True(t, connected)
+}
+
 func TestManager_PerformReconnect_NoProfile(t *testing.T) {
 	m := NewManager(DefaultConfig(), nil)
 	m.connectFunc = func(ctx context.Context, p *profile.Profile, password string) error {
@@ -356,3 +494,403 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 3, cfg.MaxAttempts)
 	assert.Equal(t, 5, cfg.DelaySeconds)
 }
+
+func TestConfig_Delay(t *testing.T) {
+	noJitter := func() float64 { return 0.5 }
+
+	fixed := Config{DelaySeconds: 5}
+	assert.Equal(t, 5*time.Second, fixed.Delay(1, noJitter))
+	assert.Equal(t, 5*time.Second, fixed.Delay(10, noJitter))
+
+	backoff := Config{DelaySeconds: 5, Backoff: true, MaxDelay: time.Minute}
+	assert.Equal(t, 5*time.Second, backoff.Delay(1, noJitter))
+	assert.Equal(t, 10*time.Second, backoff.Delay(2, noJitter))
+	assert.Equal(t, 40*time.Second, backoff.Delay(4, noJitter))
+	assert.Equal(t, time.Minute, backoff.Delay(5, noJitter))
+	assert.Equal(t, time.Minute, backoff.Delay(1000, noJitter))
+
+	defaultCap := Config{DelaySeconds: 5, Backoff: true}
+	assert.Equal(t, profile.DefaultMaxReconnectDelay, defaultCap.Delay(100, noJitter))
+
+	jitter := Config{DelaySeconds: 10, JitterPercent: 20}
+	assert.Equal(t, 8*time.Second, jitter.Delay(1, func() float64 { return 0 }))
+	assert.Equal(t, 10*time.Second, jitter.Delay(1, noJitter))
+	assert.InDelta(t, float64(12*time.Second), float64(jitter.Delay(1, func() float64 { return 0.999999 })), float64(time.Millisecond))
+}
+
+func TestConfigFromPolicy(t *testing.T) {
+	cfg := ConfigFromPolicy(&profile.ReconnectPolicy{
+		MaxAttempts:       profile.ReconnectForever,
+		DelaySeconds:      5,
+		Backoff:           true,
+		JitterPercent:     10,
+		ResetAfterSeconds: 120,
+	})
+
+	assert.Equal(t, Config{
+		MaxAttempts:   profile.ReconnectForever,
+		DelaySeconds:  5,
+		Backoff:       true,
+		MaxDelay:      profile.DefaultMaxReconnectDelay,
+		JitterPercent: 10,
+		ResetAfter:    2 * time.Minute,
+	}, cfg)
+}
+
+func TestManager_ShouldReconnect_ProfilePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *profile.ReconnectPolicy
+		attempts int
+		want     bool
+	}{
+		{name: "global config", policy: nil, attempts: 2, want: true},
+		{name: "global config exhausted", policy: nil, attempts: 3, want: false},
+		{name: "never", policy: &profile.ReconnectPolicy{MaxAttempts: 0}, attempts: 0, want: false},
+		{name: "forever", policy: &profile.ReconnectPolicy{MaxAttempts: profile.ReconnectForever}, attempts: 10000, want: true},
+		{name: "more attempts than global", policy: &profile.ReconnectPolicy{MaxAttempts: 10}, attempts: 5, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 1}, nil)
+			m.lastConnectedProfile = &profile.Profile{
+				AutoReconnect:   true,
+				AuthMethod:      profile.AuthMethodPassword,
+				ReconnectPolicy: tt.policy,
+			}
+			m.attemptCount = tt.attempts
+
+			assert.Equal(t, tt.want, m.ShouldReconnect(vpn.StateConnected, vpn.StateDisconnected))
+		})
+	}
+}
+
+func TestManager_ShouldReconnect_AfterFailedAttempt(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 2, DelaySeconds: 10}, nil)
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test", AutoReconnect: true, AuthMethod: profile.AuthMethodPassword})
+
+	// Without a reconnect in progress, a failed connect is left to the user
+	assert.False(t, m.ShouldReconnect(vpn.StateConnecting, vpn.StateDisconnected))
+
+	require.True(t, m.ShouldReconnect(vpn.StateConnected, vpn.StateDisconnected))
+	m.StartReconnect()
+
+	// The attempt fails before the tunnel comes up
+	assert.True(t, m.ShouldReconnect(vpn.StateConnecting, vpn.StateFailed))
+	m.StartReconnect()
+
+	// The attempt limit ends the sequence
+	assert.False(t, m.ShouldReconnect(vpn.StateConnecting, vpn.StateDisconnected))
+	assert.False(t, m.reconnecting)
+	m.Cancel()
+}
+
+func TestManager_Cancel_EndsSequence(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 10}, nil)
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test", AutoReconnect: true, AuthMethod: profile.AuthMethodPassword})
+
+	m.StartReconnect()
+	assert.True(t, m.IsPending())
+
+	m.Cancel()
+	assert.False(t, m.IsPending())
+	assert.False(t, m.ShouldReconnect(vpn.StateConnecting, vpn.StateDisconnected))
+}
+
+func TestManager_IsReconnecting(t *testing.T) {
+	newReconnecting := func() *Manager {
+		m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 10}, nil)
+		m.StoreConnectedProfile(&profile.Profile{Name: "Test", AutoReconnect: true, AuthMethod: profile.AuthMethodPassword})
+		m.StartReconnect()
+		return m
+	}
+
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 10}, nil)
+	assert.False(t, m.IsReconnecting())
+
+	m = newReconnecting()
+	assert.True(t, m.IsReconnecting())
+	m.Cancel()
+	assert.False(t, m.IsReconnecting())
+
+	m = newReconnecting()
+	m.SetUserDisconnect()
+	assert.False(t, m.IsReconnecting())
+
+	m = newReconnecting()
+	m.PrepareForSleep(true)
+	assert.False(t, m.IsReconnecting())
+}
+
+func TestManager_StartReconnect_OnScheduled(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 10, Backoff: true, MaxDelay: time.Minute}, nil)
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test"})
+
+	var attempts []int
+	var delays []time.Duration
+	m.SetCallbacks(Callbacks{OnScheduled: func(attempt int, at time.Time) {
+		attempts = append(attempts, attempt)
+		delays = append(delays, time.Until(at).Round(time.Second))
+	}})
+
+	m.StartReconnect()
+	m.StartReconnect()
+	m.Cancel()
+
+	assert.Equal(t, []int{1, 2}, attempts)
+	assert.Equal(t, []time.Duration{10 * time.Second, 20 * time.Second}, delays)
+}
+
+func TestManager_OnConnectionSucceeded_ResetWindow(t *testing.T) {
+	m := NewManager(DefaultConfig(), nil)
+	m.StoreConnectedProfile(&profile.Profile{
+		Name:            "Branch",
+		ReconnectPolicy: &profile.ReconnectPolicy{MaxAttempts: profile.ReconnectForever, DelaySeconds: 10, ResetAfterSeconds: 60},
+	})
+	m.StartReconnect()
+	m.StartReconnect()
+
+	// The connection came back but has not been up for the reset window yet
+	m.OnConnectionSucceeded()
+	assert.Equal(t, 2, m.GetAttemptCount())
+	require.NotNil(t, m.resetTimer)
+
+	// It drops again, so the backoff continues
+	m.StartReconnect()
+	assert.Equal(t, 3, m.GetAttemptCount())
+	assert.Nil(t, m.resetTimer)
+	m.Cancel()
+}
+
+func TestManager_OnConnectionSucceeded_ResetAfterStableConnection(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 10, ResetAfter: 10 * time.Millisecond}, nil)
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test"})
+	m.StartReconnect()
+
+	m.OnConnectionSucceeded()
+	assert.Equal(t, 1, m.GetAttemptCount())
+
+	assert.Eventually(t, func() bool { return m.GetAttemptCount() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestManager_StoreConnectedProfile_ResetsAttempts(t *testing.T) {
+	m := NewManager(DefaultConfig(), nil)
+	m.attemptCount = 3
+	m.reconnecting = true
+
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test"})
+
+	assert.Equal(t, 0, m.GetAttemptCount())
+	assert.False(t, m.reconnecting)
+}
+
+func TestManager_StartReconnect_WaitsForNetwork(t *testing.T) {
+	performed := make(chan struct{}, 1)
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 60}, func(fn func()) { performed <- struct{}{} })
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test", AutoReconnect: true, AuthMethod: profile.AuthMethodPassword})
+
+	var scheduled []time.Time
+	m.SetCallbacks(Callbacks{OnScheduled: func(_ int, at time.Time) { scheduled = append(scheduled, at) }})
+
+	m.SetNetworkAvailable(false)
+	m.StartReconnect()
+
+	assert.True(t, m.IsWaitingForNetwork())
+	assert.True(t, m.IsPending())
+	assert.Nil(t, m.reconnectTimer)
+	assert.Empty(t, scheduled)
+
+	// The route came back, so the attempt starts without waiting out its delay
+	m.SetNetworkAvailable(true)
+	assert.False(t, m.IsWaitingForNetwork())
+	require.Len(t, scheduled, 1)
+	assert.WithinDuration(t, time.Now(), scheduled[0], time.Second)
+
+	select {
+	case <-performed:
+	case <-time.After(time.Second):
+		t.Fatal("reconnect was not performed after the network came back")
+	}
+	assert.Equal(t, 1, m.GetAttemptCount(), "waiting for the network does not use up attempts")
+}
+
+func TestManager_SetNetworkAvailable_PausesPendingAttempt(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 60}, nil)
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test"})
+
+	waiting := false
+	m.SetCallbacks(Callbacks{OnWaitingForNetwork: func() { waiting = true }})
+
+	m.StartReconnect()
+	require.NotNil(t, m.reconnectTimer)
+
+	m.SetNetworkAvailable(false)
+	assert.True(t, waiting)
+	assert.True(t, m.IsWaitingForNetwork())
+	assert.Nil(t, m.reconnectTimer)
+
+	// Reporting the same availability again changes nothing
+	waiting = false
+	m.SetNetworkAvailable(false)
+	assert.False(t, waiting)
+
+	m.Cancel()
+	assert.False(t, m.IsPending())
+
+	// Without a paused attempt the network coming back does not start one
+	m.SetNetworkAvailable(true)
+	assert.Nil(t, m.reconnectTimer)
+}
+
+func TestManager_PrepareCycle(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 60}, nil)
+	assert.False(t, m.PrepareCycle(), "no connected profile")
+
+	m.StoreConnectedProfile(&profile.Profile{Name: "Never", AutoReconnect: true, ReconnectPolicy: &profile.ReconnectPolicy{MaxAttempts: 0}})
+	assert.False(t, m.PrepareCycle(), "profile never reconnects")
+
+	m.StoreConnectedProfile(&profile.Profile{Name: "OTP", AutoReconnect: true, AuthMethod: profile.AuthMethodOTP})
+	assert.False(t, m.PrepareCycle(), "OTP needs user input")
+
+	p := haProfile()
+	p.AutoReconnect = true
+	p.AuthMethod = profile.AuthMethodPassword
+	m.StoreConnectedProfile(p)
+
+	var delays []time.Duration
+	m.SetCallbacks(Callbacks{OnScheduled: func(_ int, at time.Time) { delays = append(delays, time.Until(at)) }})
+
+	require.True(t, m.PrepareCycle())
+	require.True(t, m.ShouldReconnect(vpn.StateConnected, vpn.StateDisconnected))
+	m.StartReconnect()
+	defer m.Cancel()
+
+	require.Len(t, delays, 1)
+	assert.LessOrEqual(t, delays[0], time.Duration(0), "a cycled tunnel reconnects right away")
+	// The gateway did not fail, so it stays first
+	assert.Equal(t, "vpn1.example.com", hosts(m.lastConnectedProfile)[0])
+}
+
+func TestManager_Sleep(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 60}, nil)
+	p := haProfile()
+	p.AutoReconnect = true
+	p.AuthMethod = profile.AuthMethodPassword
+	m.StoreConnectedProfile(p)
+
+	var delays []time.Duration
+	m.SetCallbacks(Callbacks{OnScheduled: func(_ int, at time.Time) { delays = append(delays, time.Until(at)) }})
+
+	// Closing the tunnel before sleeping neither reconnects nor uses up an attempt
+	m.PrepareForSleep(true)
+	assert.False(t, m.ShouldReconnect(vpn.StateConnected, vpn.StateDisconnected))
+	assert.Equal(t, 0, m.GetAttemptCount())
+	assert.False(t, m.IsPending())
+	assert.True(t, m.WillResume())
+
+	require.True(t, m.Resume())
+	defer m.Cancel()
+	assert.False(t, m.WillResume())
+	assert.Equal(t, 1, m.GetAttemptCount())
+	require.Len(t, delays, 1)
+	assert.LessOrEqual(t, delays[0], time.Duration(0), "the tunnel is restored right away")
+	assert.Equal(t, "vpn1.example.com", hosts(m.lastConnectedProfile)[0], "the gateway did not fail")
+
+	// A failed restore is retried like any reconnect
+	assert.True(t, m.ShouldReconnect(vpn.StateConnecting, vpn.StateFailed))
+}
+
+func TestManager_Sleep_CancelsPendingAttempt(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 60}, nil)
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test", AutoReconnect: true, AuthMethod: profile.AuthMethodPassword})
+	m.StartReconnect()
+	m.StartReconnect()
+
+	m.PrepareForSleep(false)
+	assert.False(t, m.IsPending())
+	assert.Equal(t, 0, m.GetAttemptCount())
+
+	// The tunnel was being reconnected, so it is restored after resuming
+	require.True(t, m.Resume())
+	m.Cancel()
+}
+
+func TestManager_Sleep_WaitsForNetwork(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 3, DelaySeconds: 60}, nil)
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test", AutoReconnect: true, AuthMethod: profile.AuthMethodPassword})
+
+	m.PrepareForSleep(true)
+	m.SetNetworkAvailable(false)
+
+	require.True(t, m.Resume())
+	assert.True(t, m.IsWaitingForNetwork())
+
+	m.SetNetworkAvailable(true)
+	assert.False(t, m.IsWaitingForNetwork())
+	assert.NotNil(t, m.reconnectTimer)
+	m.Cancel()
+}
+
+func TestManager_Sleep_NotRestored(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile *profile.Profile
+		active  bool
+		prepare func(m *Manager)
+	}{
+		{name: "not connected", profile: &profile.Profile{Name: "Test", AutoReconnect: true}},
+		{name: "no profile", active: true},
+		{name: "auto-reconnect disabled", profile: &profile.Profile{Name: "Test"}, active: true},
+		{name: "OTP", profile: &profile.Profile{Name: "Test", AutoReconnect: true, AuthMethod: profile.AuthMethodOTP}, active: true},
+		{name: "disconnected by the user", profile: &profile.Profile{Name: "Test", AutoReconnect: true}, active: true,
+			prepare: func(m *Manager) { m.SetUserDisconnect() }},
+		{name: "cancelled while sleeping", profile: &profile.Profile{Name: "Test", AutoReconnect: true}, active: true,
+			prepare: func(m *Manager) { m.PrepareForSleep(true); m.Cancel() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(DefaultConfig(), nil)
+			m.StoreConnectedProfile(tt.profile)
+			if tt.prepare != nil {
+				tt.prepare(m)
+			}
+			m.PrepareForSleep(tt.active)
+
+			assert.False(t, m.WillResume())
+			assert.False(t, m.Resume())
+			assert.False(t, m.IsPending())
+		})
+	}
+}
+
+func TestManager_PerformReconnect_Sleeping(t *testing.T) {
+	m := NewManager(DefaultConfig(), nil)
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test", AuthMethod: profile.AuthMethodSAML})
+
+	connected := false
+	m.SetConnectFunc(func(context.Context, *profile.Profile, string) error {
+		connected = true
+		return nil
+	})
+
+	// An attempt that was already on its way when the system went to sleep is dropped
+	m.PrepareForSleep(false)
+	m.performReconnect()
+	assert.False(t, connected)
+}
+
+func TestManager_CanReconnect(t *testing.T) {
+	m := NewManager(Config{MaxAttempts: 1, DelaySeconds: 60}, nil)
+	assert.False(t, m.CanReconnect(), "no connected profile")
+
+	m.StoreConnectedProfile(&profile.Profile{Name: "Test", AutoReconnect: true, AuthMethod: profile.AuthMethodPassword})
+	assert.True(t, m.CanReconnect())
+
+	// Asking does not change what happens on a drop
+	assert.True(t, m.ShouldReconnect(vpn.StateConnected, vpn.StateDisconnected))
+	m.StartReconnect()
+	defer m.Cancel()
+	assert.False(t, m.CanReconnect(), "attempts are used up")
+}
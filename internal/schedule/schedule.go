@@ -0,0 +1,256 @@
+// Package schedule tells when the windows of profile schedules start and end,
+// so profiles can be connected during working hours and disconnected after.
+//
+// Only the boundaries of windows are reported: a profile disconnected by hand
+// during its window stays down until the next window starts, and one connected
+// by hand outside its windows stays up until the next window ends.
+//
+// Timers do not advance while the system is suspended, so Reevaluate must be
+// called after resuming to report boundaries passed while asleep.
+package schedule
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+const (
+	// DefaultWarning is how long before a window ends the warning is reported.
+	DefaultWarning = 5 * time.Minute
+	// maxTimerDelay bounds the time between evaluations, so a change of the
+	// system clock is noticed within it.
+	maxTimerDelay = time.Hour
+)
+
+// Timer is a pending call scheduled by a Clock.
+type Timer interface {
+	// Stop prevents the call if it has not run yet.
+	Stop() bool
+}
+
+// Clock tells the time and schedules calls. Tests replace it with a fake.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine once d elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// systemClock is the Clock backed by package time.
+type systemClock struct{}
+
+// Now implements Clock.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// AfterFunc implements Clock.
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Handlers are called from a background goroutine when a window boundary passes.
+// Any of them may be nil.
+type Handlers struct {
+	// Start is called when a window of the profile starts.
+	Start func(profileID string)
+	// Warn is called shortly before a window of the profile ends at end.
+	Warn func(profileID string, end time.Time)
+	// End is called when a window of the profile ended.
+	End func(profileID string)
+}
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithClock sets the clock the scheduler uses.
+func WithClock(c Clock) Option {
+	return func(s *Scheduler) {
+		s.clock = c
+	}
+}
+
+// WithWarning sets how long before a window ends the warning is reported.
+// Zero disables the warning.
+func WithWarning(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.warning = d
+	}
+}
+
+// entry is the schedule of a profile and the window it is in.
+type entry struct {
+	schedule *profile.Schedule
+	window   time.Time // Start of the current window, zero outside the schedule's windows
+	warned   bool      // Whether the end of the current window was warned about
+}
+
+// Scheduler reports the window boundaries of profile schedules.
+// It is safe for concurrent use.
+type Scheduler struct {
+	clock   Clock
+	warning time.Duration
+
+	mu       sync.Mutex
+	handlers Handlers
+	entries  map[string]*entry
+	timer    Timer
+	started  bool
+}
+
+// NewScheduler creates a scheduler. Call Start to begin reporting.
+func NewScheduler(opts ...Option) *Scheduler {
+	s := &Scheduler{
+		clock:   systemClock{},
+		warning: DefaultWarning,
+		entries: make(map[string]*entry),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetHandlers registers the handlers for window boundaries.
+func (s *Scheduler) SetHandlers(h Handlers) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = h
+}
+
+// SetSchedules replaces the schedules, keyed by profile ID. A changed schedule
+// takes effect from its next boundary: once started, the scheduler does not
+// report a window the profile is already in when its schedule is set.
+func (s *Scheduler) SetSchedules(schedules map[string]*profile.Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	entries := make(map[string]*entry, len(schedules))
+	for id, schedule := range schedules {
+		if old, ok := s.entries[id]; ok && reflect.DeepEqual(old.schedule, schedule) {
+			entries[id] = old
+			continue
+		}
+		e := &entry{schedule: schedule}
+		if s.started {
+			e.window, _, _ = schedule.Window(now)
+		}
+		entries[id] = e
+	}
+	s.entries = entries
+
+	if s.started {
+		s.armLocked(now)
+	}
+}
+
+// Start reports the windows profiles are in now and then every boundary until
+// ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.started = true
+	s.mu.Unlock()
+
+	s.Reevaluate()
+
+	context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.started = false
+		if s.timer != nil {
+			s.timer.Stop()
+			s.timer = nil
+		}
+	})
+}
+
+// Reevaluate reports the boundaries passed since the last evaluation, such as
+// after the system resumed from sleep. A window that started and ended since
+// is not reported.
+func (s *Scheduler) Reevaluate() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+
+	now := s.clock.Now()
+	var starts, warns, ends []string
+	var warnEnds []time.Time
+	for _, id := range slices.Sorted(maps.Keys(s.entries)) {
+		e := s.entries[id]
+		start, end, ok := e.schedule.Window(now)
+		switch {
+		case ok && !start.Equal(e.window):
+			e.window, e.warned = start, false
+			starts = append(starts, id)
+		case !ok && !e.window.IsZero():
+			e.window = time.Time{}
+			ends = append(ends, id)
+		}
+		if ok && !e.warned && s.warning > 0 && !now.Before(end.Add(-s.warning)) {
+			e.warned = true
+			warns = append(warns, id)
+			warnEnds = append(warnEnds, end)
+		}
+	}
+	s.armLocked(now)
+	h := s.handlers
+	s.mu.Unlock()
+
+	// Windows ending first lets a profile whose window starts at the same time take over
+	for _, id := range ends {
+		slog.Info("Schedule window ended", "profile_id", id)
+		if h.End != nil {
+			h.End(id)
+		}
+	}
+	for _, id := range starts {
+		slog.Info("Schedule window started", "profile_id", id)
+		if h.Start != nil {
+			h.Start(id)
+		}
+	}
+	for i, id := range warns {
+		slog.Debug("Schedule window ends soon", "profile_id", id, "end", warnEnds[i])
+		if h.Warn != nil {
+			h.Warn(id, warnEnds[i])
+		}
+	}
+}
+
+// armLocked schedules the next evaluation at the earliest upcoming boundary.
+func (s *Scheduler) armLocked(now time.Time) {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if len(s.entries) == 0 {
+		return
+	}
+
+	next := now.Add(maxTimerDelay)
+	for _, e := range s.entries {
+		var boundaries []time.Time
+		if _, end, ok := e.schedule.Window(now); ok {
+			boundaries = append(boundaries, end)
+			if !e.warned && s.warning > 0 {
+				boundaries = append(boundaries, end.Add(-s.warning))
+			}
+		}
+		boundaries = append(boundaries, e.schedule.NextStart(now))
+		for _, b := range boundaries {
+			if b.After(now) && b.Before(next) {
+				next = b
+			}
+		}
+	}
+	s.timer = s.clock.AfterFunc(next.Sub(now), s.Reevaluate)
+}
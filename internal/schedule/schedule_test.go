@@ -0,0 +1,264 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// fakeClock is a manually advanced clock. Due calls run synchronously in Advance.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock   *fakeClock
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
+
+// Advance moves the clock forward, running each call that becomes due at its time.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+		var due *fakeTimer
+		for _, t := range c.timers {
+			if !t.stopped && !t.at.After(target) && (due == nil || t.at.Before(due.at)) {
+				due = t
+			}
+		}
+		if due == nil {
+			c.now = target
+			c.mu.Unlock()
+			return
+		}
+		due.stopped = true
+		c.now = due.at
+		c.mu.Unlock()
+		due.f()
+	}
+}
+
+// Jump moves the clock forward without running due calls, like a suspended system.
+func (c *fakeClock) Jump(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// recorder collects the reported boundaries as "start:id", "warn:id" and "end:id".
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) handlers() Handlers {
+	record := func(kind, id string) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events = append(r.events, fmt.Sprintf("%s:%s", kind, id))
+	}
+	return Handlers{
+		Start: func(id string) { record("start", id) },
+		Warn:  func(id string, _ time.Time) { record("warn", id) },
+		End:   func(id string) { record("end", id) },
+	}
+}
+
+// take returns the events recorded since the last call.
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+var office = &profile.Schedule{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:30", End: "18:00"}
+
+// newTestScheduler returns a started scheduler with the office schedule for
+// profile "work", at the given time on Monday, October 19th 2026.
+func newTestScheduler(t *testing.T, hour, minute int) (*Scheduler, *fakeClock, *recorder) {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2026, time.October, 19, hour, minute, 0, 0, time.Local)}
+	rec := &recorder{}
+
+	s := NewScheduler(WithClock(clock), WithWarning(10*time.Minute))
+	s.SetHandlers(rec.handlers())
+	s.SetSchedules(map[string]*profile.Schedule{"work": office})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s.Start(ctx)
+	return s, clock, rec
+}
+
+func TestScheduler_ReportsBoundaries(t *testing.T) {
+	_, clock, rec := newTestScheduler(t, 7, 0)
+	assert.Empty(t, rec.take(), "outside a window on start")
+
+	clock.Advance(90 * time.Minute)
+	assert.Equal(t, []string{"start:work"}, rec.take())
+
+	clock.Advance(9*time.Hour + 19*time.Minute)
+	assert.Empty(t, rec.take(), "eleven minutes before the end")
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, []string{"warn:work"}, rec.take())
+
+	clock.Advance(10 * time.Minute)
+	assert.Equal(t, []string{"end:work"}, rec.take())
+
+	// Tuesday's window
+	clock.Advance(24 * time.Hour)
+	assert.Equal(t, []string{"start:work", "warn:work", "end:work"}, rec.take())
+}
+
+func TestScheduler_StartsWithinWindow(t *testing.T) {
+	_, _, rec := newTestScheduler(t, 12, 0)
+	assert.Equal(t, []string{"start:work"}, rec.take())
+
+	_, _, rec = newTestScheduler(t, 17, 55)
+	assert.Equal(t, []string{"start:work", "warn:work"}, rec.take(), "started within the warning period")
+}
+
+func TestScheduler_Weekend(t *testing.T) {
+	_, clock, rec := newTestScheduler(t, 7, 0)
+
+	// From Friday night to Monday morning
+	clock.Advance(4*24*time.Hour + 12*time.Hour)
+	rec.take()
+	clock.Advance(61 * time.Hour)
+	assert.Empty(t, rec.take())
+
+	clock.Advance(30 * time.Minute)
+	assert.Equal(t, []string{"start:work"}, rec.take())
+}
+
+func TestScheduler_ReevaluateAfterSleep(t *testing.T) {
+	s, clock, rec := newTestScheduler(t, 12, 0)
+	rec.take()
+
+	// Asleep over the end of the window
+	clock.Jump(7 * time.Hour)
+	assert.Empty(t, rec.take())
+	s.Reevaluate()
+	assert.Equal(t, []string{"end:work"}, rec.take())
+
+	// Asleep over the night until the next window started
+	clock.Jump(14 * time.Hour)
+	s.Reevaluate()
+	assert.Equal(t, []string{"start:work"}, rec.take())
+
+	// Asleep from one window into the next
+	clock.Jump(24 * time.Hour)
+	s.Reevaluate()
+	assert.Equal(t, []string{"start:work"}, rec.take(), "a new window is reported as such")
+
+	// Asleep over a whole window
+	clock.Jump(12 * time.Hour)
+	s.Reevaluate()
+	rec.take()
+	clock.Jump(24 * time.Hour)
+	s.Reevaluate()
+	assert.Empty(t, rec.take())
+}
+
+func TestScheduler_SetSchedules(t *testing.T) {
+	s, clock, rec := newTestScheduler(t, 12, 0)
+	rec.take()
+
+	// Setting the same schedule again keeps the current window
+	s.SetSchedules(map[string]*profile.Schedule{"work": {Days: office.Days, Start: "08:30", End: "18:00"}})
+	clock.Advance(time.Minute)
+	assert.Empty(t, rec.take())
+
+	// A schedule added during its window takes effect from its next boundary
+	late := &profile.Schedule{Days: []string{"mon"}, Start: "11:00", End: "13:00"}
+	s.SetSchedules(map[string]*profile.Schedule{"work": office, "late": late})
+	clock.Advance(time.Minute)
+	assert.Empty(t, rec.take())
+	clock.Advance(time.Hour)
+	assert.Equal(t, []string{"warn:late", "end:late"}, rec.take())
+
+	// Removed schedules are no longer reported
+	s.SetSchedules(nil)
+	clock.Advance(24 * time.Hour)
+	assert.Empty(t, rec.take())
+}
+
+func TestScheduler_EndsBeforeStarts(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, time.October, 19, 7, 0, 0, 0, time.Local)}
+	rec := &recorder{}
+	s := NewScheduler(WithClock(clock), WithWarning(0))
+	s.SetHandlers(rec.handlers())
+	s.SetSchedules(map[string]*profile.Schedule{
+		"day":   {Days: []string{"mon"}, Start: "08:00", End: "18:00"},
+		"night": {Days: []string{"mon"}, Start: "18:00", End: "23:00"},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	clock.Advance(2 * time.Hour)
+	assert.Equal(t, []string{"start:day"}, rec.take())
+	clock.Advance(10 * time.Hour)
+	assert.Equal(t, []string{"end:day", "start:night"}, rec.take())
+}
+
+func TestScheduler_StopsWhenCancelled(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, time.October, 19, 7, 0, 0, 0, time.Local)}
+	rec := &recorder{}
+	s := NewScheduler(WithClock(clock))
+	s.SetHandlers(rec.handlers())
+	s.SetSchedules(map[string]*profile.Schedule{"work": office})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	cancel()
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return !s.started
+	}, time.Second, time.Millisecond)
+
+	clock.Advance(2 * time.Hour)
+	s.Reevaluate()
+	assert.Empty(t, rec.take())
+}
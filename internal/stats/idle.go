@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// IdleEvent is what an IdleDetector reports for a stats sample.
+type IdleEvent int
+
+const (
+	// IdleNone means nothing changed.
+	IdleNone IdleEvent = iota
+	// IdleWarn means the tunnel has been idle long enough to warn the user.
+	IdleWarn
+	// IdleResumed means traffic resumed after the user was warned.
+	IdleResumed
+	// IdleExpired means the tunnel stayed idle through the warning and should be disconnected.
+	IdleExpired
+)
+
+// IdleDetector tells from stats samples when a tunnel has been idle, with its
+// traffic below a threshold, for long enough to be disconnected.
+// It is safe for concurrent use.
+type IdleDetector struct {
+	threshold float64
+	idleAfter time.Duration
+	warning   time.Duration
+
+	mu      sync.Mutex
+	since   time.Time // Timestamp of the first idle sample, zero while the tunnel is used
+	warned  bool
+	expired bool
+}
+
+// NewIdleDetector creates a detector that warns once traffic, received and sent
+// together, stayed below threshold bytes per second for idleAfter, and expires
+// when it stays below for warning longer.
+func NewIdleDetector(threshold float64, idleAfter, warning time.Duration) *IdleDetector {
+	return &IdleDetector{threshold: threshold, idleAfter: idleAfter, warning: warning}
+}
+
+// Observe feeds a stats sample to the detector and returns what it caused.
+// IdleWarn and IdleExpired are reported once per idle period.
+func (d *IdleDetector) Observe(s NetworkStats) IdleEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if s.RxBytesPerSec+s.TxBytesPerSec >= d.threshold {
+		resumed := d.warned && !d.expired
+		d.since, d.warned, d.expired = time.Time{}, false, false
+		if resumed {
+			return IdleResumed
+		}
+		return IdleNone
+	}
+
+	if d.since.IsZero() {
+		d.since = s.Timestamp
+	}
+	idle := s.Timestamp.Sub(d.since)
+	switch {
+	case d.expired:
+		return IdleNone
+	case !d.warned && idle >= d.idleAfter:
+		d.warned = true
+		return IdleWarn
+	case d.warned && idle >= d.idleAfter+d.warning:
+		d.expired = true
+		return IdleExpired
+	}
+	return IdleNone
+}
+
+// Reset starts a new idle period with the next sample, such as when the user
+// asks to stay connected after the warning.
+func (d *IdleDetector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.since, d.warned, d.expired = time.Time{}, false, false
+}
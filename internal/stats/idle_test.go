@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// idleSamples feeds a sample with the given rate every minute from start and
+// returns the events, keyed by minute, other than IdleNone.
+func idleSamples(d *IdleDetector, start time.Time, from, to int, rate float64) map[int]IdleEvent {
+	events := make(map[int]IdleEvent)
+	for minute := from; minute <= to; minute++ {
+		s := NetworkStats{RxBytesPerSec: rate / 2, TxBytesPerSec: rate / 2, Timestamp: start.Add(time.Duration(minute) * time.Minute)}
+		if e := d.Observe(s); e != IdleNone {
+			events[minute] = e
+		}
+	}
+	return events
+}
+
+func TestIdleDetector_WarnsThenExpires(t *testing.T) {
+	start := time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC)
+	d := NewIdleDetector(100, 30*time.Minute, 2*time.Minute)
+
+	assert.Empty(t, idleSamples(d, start, 0, 9, 5000), "busy tunnel")
+	assert.Equal(t, map[int]IdleEvent{40: IdleWarn, 42: IdleExpired}, idleSamples(d, start, 10, 60, 99))
+}
+
+func TestIdleDetector_TrafficResumes(t *testing.T) {
+	start := time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC)
+	d := NewIdleDetector(100, 30*time.Minute, 2*time.Minute)
+
+	assert.Equal(t, map[int]IdleEvent{30: IdleWarn}, idleSamples(d, start, 0, 31, 0))
+	assert.Equal(t, map[int]IdleEvent{32: IdleResumed}, idleSamples(d, start, 32, 32, 100))
+
+	// The idle period starts over
+	assert.Equal(t, map[int]IdleEvent{63: IdleWarn}, idleSamples(d, start, 33, 64, 0))
+}
+
+func TestIdleDetector_TrafficBelowThreshold(t *testing.T) {
+	start := time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC)
+	d := NewIdleDetector(100, 30*time.Minute, time.Minute)
+
+	// Keepalives stay below the threshold
+	idleSamples(d, start, 0, 10, 0)
+	assert.Equal(t, map[int]IdleEvent{30: IdleWarn, 31: IdleExpired}, idleSamples(d, start, 11, 40, 40))
+}
+
+func TestIdleDetector_Reset(t *testing.T) {
+	start := time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC)
+	d := NewIdleDetector(100, 30*time.Minute, 2*time.Minute)
+
+	assert.Equal(t, map[int]IdleEvent{30: IdleWarn}, idleSamples(d, start, 0, 31, 0))
+	d.Reset()
+	assert.Equal(t, map[int]IdleEvent{62: IdleWarn, 64: IdleExpired}, idleSamples(d, start, 32, 70, 0))
+}
@@ -3,9 +3,11 @@ package ui
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	"os/exec"
+	"time"
 
 	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
 	"github.com/diamondburned/gotk4/pkg/gio/v2"
@@ -15,8 +17,12 @@ import (
 	"github.com/shini4i/openfortivpn-gui/internal/client"
 	"github.com/shini4i/openfortivpn-gui/internal/config"
 	"github.com/shini4i/openfortivpn-gui/internal/keyring"
+	"github.com/shini4i/openfortivpn-gui/internal/logind"
+	"github.com/shini4i/openfortivpn-gui/internal/netmon"
+	"github.com/shini4i/openfortivpn-gui/internal/netrules"
 	"github.com/shini4i/openfortivpn-gui/internal/profile"
 	"github.com/shini4i/openfortivpn-gui/internal/reconnect"
+	"github.com/shini4i/openfortivpn-gui/internal/schedule"
 	"github.com/shini4i/openfortivpn-gui/internal/stats"
 	"github.com/shini4i/openfortivpn-gui/internal/vpn"
 )
@@ -42,6 +48,10 @@ type App struct {
 	keyringStore  keyring.Store
 	vpnController vpn.VPNController
 
+	// Installed openfortivpn version (nil until detected, or if detection failed)
+	openfortivpnVersion *vpn.Version
+	versionDetector     *vpn.VersionDetector
+
 	// usingHelper indicates if we're using the helper daemon (for proper cleanup)
 	usingHelper bool
 
@@ -51,6 +61,9 @@ type App struct {
 	// Stats collector for network traffic statistics
 	statsCollector *stats.Collector
 
+	// Watches the profile directory for changes made by other programs
+	profileWatcher *profile.Watcher
+
 	// Application-level context for VPN operations
 	ctx       context.Context
 	ctxCancel context.CancelFunc
@@ -71,7 +84,8 @@ func NewApp(cfg *AppConfig) (*App, error) {
 	}
 
 	// Initialize profile store
-	profileStore, err := profile.NewStore(configManager.GetProfilesPath())
+	profileStore, err := profile.NewStore(configManager.GetProfilesPath(),
+		profile.WithSystemDirs(configManager.GetSystemProfilesPaths()...))
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +109,9 @@ func NewApp(cfg *AppConfig) (*App, error) {
 		slog.Debug("openfortivpn found", "path", openfortivpnPath)
 	}
 
+	// The installed openfortivpn version gates features and warns about old releases
+	versionDetector := vpn.NewVersionDetector(openfortivpnPath)
+
 	// Create application-level context for VPN operations
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -107,7 +124,7 @@ func NewApp(cfg *AppConfig) (*App, error) {
 		if err != nil {
 			slog.Warn("Helper daemon available but connection failed, falling back to pkexec mode",
 				"error", err)
-			vpnController = vpn.NewController(openfortivpnPath)
+			vpnController = vpn.NewController(openfortivpnPath, vpn.WithVersionDetector(versionDetector))
 		} else {
 			slog.Info("Using helper daemon for VPN operations (no password prompts)")
 			vpnController = helperClient
@@ -115,21 +132,22 @@ func NewApp(cfg *AppConfig) (*App, error) {
 		}
 	} else {
 		slog.Info("Helper daemon not available, using pkexec mode (password prompts required)")
-		vpnController = vpn.NewController(openfortivpnPath)
+		vpnController = vpn.NewController(openfortivpnPath, vpn.WithVersionDetector(versionDetector))
 	}
 
 	// Initialize stats collector
 	statsCollector := stats.NewCollector(0) // Use default poll interval
 
 	app := &App{
-		configManager:  configManager,
-		profileStore:   profileStore,
-		keyringStore:   keyringStore,
-		vpnController:  vpnController,
-		usingHelper:    usingHelper,
-		statsCollector: statsCollector,
-		ctx:            ctx,
-		ctxCancel:      cancel,
+		configManager:   configManager,
+		profileStore:    profileStore,
+		keyringStore:    keyringStore,
+		vpnController:   vpnController,
+		versionDetector: versionDetector,
+		usingHelper:     usingHelper,
+		statsCollector:  statsCollector,
+		ctx:             ctx,
+		ctxCancel:       cancel,
 	}
 
 	return app, nil
@@ -139,6 +157,7 @@ func NewApp(cfg *AppConfig) (*App, error) {
 // Returns the exit code from the GTK application.
 func (a *App) Run(args []string) int {
 	a.app = adw.NewApplication(AppID, gio.ApplicationFlagsNone)
+	a.detectOpenfortivpnVersion()
 
 	a.app.ConnectActivate(func() {
 		a.onActivate()
@@ -152,6 +171,26 @@ func (a *App) Run(args []string) int {
 	return a.app.Run(args)
 }
 
+// detectOpenfortivpnVersion detects the installed openfortivpn version in the
+// background, so running openfortivpn does not delay startup, and passes it to
+// the profile editor on the main loop once it is known.
+func (a *App) detectOpenfortivpnVersion() {
+	go func() {
+		v, err := a.versionDetector.Detect(a.ctx)
+		if err != nil {
+			slog.Warn("Failed to detect openfortivpn version", "error", err)
+			return
+		}
+		slog.Debug("Detected openfortivpn version", "version", v.String())
+		glib.IdleAdd(func() {
+			a.openfortivpnVersion = &v
+			if a.window != nil {
+				a.window.profileEditor.SetOpenfortivpnVersion(&v)
+			}
+		})
+	}()
+}
+
 // onActivate is called when the application is activated.
 // If profiles exist, the app starts in tray-only mode (window hidden).
 // The window is always created to ensure VPN callbacks are registered.
@@ -175,6 +214,11 @@ func (a *App) onActivate() {
 	// (callbacks handle tray updates, notifications, state display)
 	a.ensureWindow()
 
+	// Pick up profiles added or edited outside the application
+	if a.profileWatcher == nil {
+		a.startProfileWatcher()
+	}
+
 	// Keep app running even when window is hidden (tray mode)
 	a.app.Hold()
 
@@ -189,10 +233,32 @@ func (a *App) onActivate() {
 	}
 }
 
-// tryAutoConnect attempts to automatically connect to the default profile on startup.
-// This is called only when AutoConnect is enabled and a default profile is configured.
+// tryAutoConnect applies the network rule matching the current network on startup.
+// Without a matching rule it connects to the default profile if AutoConnect is
+// enabled and a default profile is configured.
 func (a *App) tryAutoConnect() {
 	cfg := a.configManager.GetConfig()
+	if len(cfg.NetworkRules) == 0 || a.window == nil {
+		a.connectDefaultProfile(cfg)
+		return
+	}
+
+	// Detecting the network may take a few seconds
+	window := a.window
+	go func() {
+		rule := window.matchNetworkRule(window.currentUplink())
+		glib.IdleAdd(func() {
+			if rule != nil {
+				window.applyNetworkRule(rule)
+				return
+			}
+			a.connectDefaultProfile(cfg)
+		})
+	}()
+}
+
+// connectDefaultProfile connects to the default profile if AutoConnect is enabled.
+func (a *App) connectDefaultProfile(cfg *config.Config) {
 	if !cfg.AutoConnect {
 		return
 	}
@@ -222,6 +288,30 @@ func (a *App) tryAutoConnect() {
 	})
 }
 
+// startProfileWatcher reloads the profile list and tray menu when profiles are changed
+// on disk by configuration management or another instance. It stops with the application context.
+func (a *App) startProfileWatcher() {
+	watcher, err := profile.NewWatcher(a.profileStore)
+	if err != nil {
+		slog.Warn("Profile changes made outside the application will not be shown", "error", err)
+		return
+	}
+	a.profileWatcher = watcher
+
+	go func() {
+		err := watcher.Run(a.ctx, func(events []profile.WatchEvent) {
+			glib.IdleAdd(func() {
+				if a.window != nil {
+					a.window.ApplyProfileChanges(events)
+				}
+			})
+		})
+		if err != nil {
+			slog.Warn("Stopped watching profile directory", "error", err)
+		}
+	}()
+}
+
 // registerActions registers the application-level actions for menu items.
 func (a *App) registerActions() {
 	// About action
@@ -231,6 +321,58 @@ func (a *App) registerActions() {
 	})
 	a.app.AddAction(aboutAction)
 
+	// Import action
+	importAction := gio.NewSimpleAction("import", nil)
+	importAction.ConnectActivate(func(param *glib.Variant) {
+		a.ShowImportDialog()
+	})
+	a.app.AddAction(importAction)
+
+	// NetworkManager bulk import action
+	importNMAction := gio.NewSimpleAction("import-networkmanager", nil)
+	importNMAction.ConnectActivate(func(param *glib.Variant) {
+		a.ShowNetworkManagerImportDialog()
+	})
+	a.app.AddAction(importNMAction)
+
+	// Export action
+	exportAction := gio.NewSimpleAction("export", nil)
+	exportAction.ConnectActivate(func(param *glib.Variant) {
+		a.ShowExportDialog()
+	})
+	a.app.AddAction(exportAction)
+
+	// Backup and restore actions
+	backupAction := gio.NewSimpleAction("backup", nil)
+	backupAction.ConnectActivate(func(param *glib.Variant) {
+		a.ShowBackupDialog()
+	})
+	a.app.AddAction(backupAction)
+
+	restoreAction := gio.NewSimpleAction("restore", nil)
+	restoreAction.ConnectActivate(func(param *glib.Variant) {
+		a.ShowRestoreDialog()
+	})
+	a.app.AddAction(restoreAction)
+
+	// Idle warning notification button
+	stayConnected := gio.NewSimpleAction(stayConnectedAction, nil)
+	stayConnected.ConnectActivate(func(param *glib.Variant) {
+		if a.window != nil {
+			a.window.stayConnected()
+		}
+	})
+	a.app.AddAction(stayConnected)
+
+	// Kill switch removal, for when the user gives up on a dropped connection
+	releaseKillSwitch := gio.NewSimpleAction(releaseKillSwitchAction, nil)
+	releaseKillSwitch.ConnectActivate(func(param *glib.Variant) {
+		if a.window != nil {
+			a.window.removeKillSwitch()
+		}
+	})
+	a.app.AddAction(releaseKillSwitch)
+
 	// Preferences action
 	prefsAction := gio.NewSimpleAction("preferences", nil)
 	prefsAction.ConnectActivate(func(param *glib.Variant) {
@@ -303,6 +445,61 @@ func (a *App) ShowAboutDialog() {
 	about.Present(a.window.window)
 }
 
+// ShowImportDialog displays the profile import file chooser.
+func (a *App) ShowImportDialog() {
+	a.ensureWindow()
+	if a.window == nil {
+		slog.Error("Window unexpectedly nil after creation", "action", "import_dialog")
+		return
+	}
+
+	a.window.ShowImportDialog()
+}
+
+// ShowNetworkManagerImportDialog displays the NetworkManager connections directory chooser.
+func (a *App) ShowNetworkManagerImportDialog() {
+	a.ensureWindow()
+	if a.window == nil {
+		slog.Error("Window unexpectedly nil after creation", "action", "import_networkmanager_dialog")
+		return
+	}
+
+	a.window.ShowNetworkManagerImportDialog()
+}
+
+// ShowExportDialog displays the profile export file chooser.
+func (a *App) ShowExportDialog() {
+	a.ensureWindow()
+	if a.window == nil {
+		slog.Error("Window unexpectedly nil after creation", "action", "export_dialog")
+		return
+	}
+
+	a.window.ShowExportDialog()
+}
+
+// ShowBackupDialog displays the backup passphrase dialog.
+func (a *App) ShowBackupDialog() {
+	a.ensureWindow()
+	if a.window == nil {
+		slog.Error("Window unexpectedly nil after creation", "action", "backup_dialog")
+		return
+	}
+
+	a.window.ShowBackupDialog()
+}
+
+// ShowRestoreDialog displays the backup file chooser for restoring.
+func (a *App) ShowRestoreDialog() {
+	a.ensureWindow()
+	if a.window == nil {
+		slog.Error("Window unexpectedly nil after creation", "action", "restore_dialog")
+		return
+	}
+
+	a.window.ShowRestoreDialog()
+}
+
 // ShowPreferencesDialog displays the application preferences window.
 func (a *App) ShowPreferencesDialog() {
 	a.ensureWindow()
@@ -317,6 +514,16 @@ func (a *App) ShowPreferencesDialog() {
 	cfg := a.configManager.GetConfig()
 	prefs.SetNotificationsEnabled(cfg.ShowNotifications)
 	prefs.SetAutoConnect(cfg.AutoConnect)
+	prefs.SetNetworkRules(cfg.NetworkRules, a.window.profileList.Profiles())
+
+	// Detecting the network may take a few seconds
+	window := a.window
+	go func() {
+		network := netrules.Detect(a.ctx, window.currentUplink())
+		glib.IdleAdd(func() {
+			prefs.SetCurrentNetwork(network)
+		})
+	}()
 
 	// Also sync notifier state with config value
 	if a.notifier != nil {
@@ -342,6 +549,14 @@ func (a *App) ShowPreferencesDialog() {
 		slog.Info("Auto-connect setting changed", "enabled", enabled)
 	})
 
+	// Handle network rule changes
+	prefs.OnNetworkRulesChanged(func(rules []netrules.Rule) {
+		a.updateConfigField(func(cfg *config.Config) {
+			cfg.NetworkRules = rules
+		})
+		slog.Info("Network rules changed", "rules", len(rules))
+	})
+
 	prefs.Present()
 }
 
@@ -374,6 +589,19 @@ func (a *App) initTray() {
 		slog.Error("Failed to register tray OnConnect callback", "error", err)
 	}
 
+	if err := a.tray.OnConnectProfile(func(profileID string) {
+		glib.IdleAdd(func() {
+			a.ensureWindow()
+			if a.window == nil {
+				slog.Error("Window unexpectedly nil after creation", "action", "tray_connect_profile")
+				return
+			}
+			a.window.triggerConnectProfile(profileID)
+		})
+	}); err != nil {
+		slog.Error("Failed to register tray OnConnectProfile callback", "error", err)
+	}
+
 	if err := a.tray.OnDisconnect(func() {
 		glib.IdleAdd(func() {
 			// Disconnect doesn't require window, but ensure it exists for state display
@@ -489,18 +717,58 @@ func (a *App) ensureWindow() {
 			glib.IdleAdd(fn)
 		}
 		reconnectManager := reconnect.NewManager(reconnectCfg, scheduleOnMain)
+		gatewayProber := &vpn.HandshakeProber{}
+		networkMonitor := netmon.NewMonitor()
+		sessionWatcher := logind.NewWatcher()
+		scheduler := schedule.NewScheduler()
 
 		// Configure reconnect manager
 		reconnectManager.SetPasswordProvider(a.keyringStore)
+		reconnectManager.SetGatewayProber(gatewayProber)
 		reconnectManager.SetContext(a.ctx)
 		reconnectManager.SetConnectFunc(func(ctx context.Context, p *profile.Profile, password string) error {
 			opts := &vpn.ConnectOptions{Password: password}
-			return a.vpnController.Connect(ctx, p, opts)
+			// Reconnects run on the main loop, so the window's hooks can be used directly
+			if a.window == nil {
+				return a.vpnController.Connect(ctx, p, opts)
+			}
+			w := a.window
+			w.hooks.beforeConnect(p, func(err error) {
+				if err != nil {
+					// Connecting anyway could bypass whatever the hook sets up, so the sequence ends
+					w.logDialog.AppendLog(fmt.Sprintf("Pre-connect hook failed, not reconnecting: %v", err))
+					w.cancelReconnect()
+					return
+				}
+				// The user may have disconnected, or the system gone to sleep, while the hook ran
+				if !reconnectManager.IsReconnecting() {
+					return
+				}
+				w.hooks.begin(p)
+				if err := a.vpnController.Connect(ctx, p, opts); err != nil {
+					slog.Error("Reconnect failed", "profile", p.Name, "error", err)
+				}
+			})
+			return nil
 		})
 		reconnectManager.SetCallbacks(reconnect.Callbacks{
 			OnReconnecting: func() {
 				// Callbacks are handled by state change handler in window.go
 			},
+			OnScheduled: func(attempt int, at time.Time) {
+				glib.IdleAdd(func() {
+					if a.window != nil {
+						a.window.statusDisplay.SetReconnectAt(at)
+					}
+				})
+			},
+			OnWaitingForNetwork: func() {
+				glib.IdleAdd(func() {
+					if a.window != nil {
+						a.window.showReconnectState(vpn.StateWaitingForNetwork)
+					}
+				})
+			},
 			OnFailed: func(err error) {
 				// When reconnect fails (e.g., password not available), update UI
 				glib.IdleAdd(func() {
@@ -515,17 +783,38 @@ func (a *App) ensureWindow() {
 		})
 
 		a.window = NewMainWindow(a.app, &MainWindowDeps{
-			ProfileStore:     a.profileStore,
-			KeyringStore:     a.keyringStore,
-			VPNController:    a.vpnController,
-			ConfigManager:    a.configManager,
-			Tray:             a.tray,
-			Notifier:         a.notifier,
-			StatsCollector:   a.statsCollector,
-			ReconnectManager: reconnectManager,
-			Ctx:              a.ctx,
+			ProfileStore:        a.profileStore,
+			KeyringStore:        a.keyringStore,
+			VPNController:       a.vpnController,
+			ConfigManager:       a.configManager,
+			Tray:                a.tray,
+			Notifier:            a.notifier,
+			StatsCollector:      a.statsCollector,
+			ReconnectManager:    reconnectManager,
+			GatewayProber:       gatewayProber,
+			NetworkMonitor:      networkMonitor,
+			SessionWatcher:      sessionWatcher,
+			Scheduler:           scheduler,
+			Ctx:                 a.ctx,
+			OpenfortivpnVersion: a.openfortivpnVersion,
 		})
 
+		// Reconnecting waits for a network and follows uplink changes
+		if err := networkMonitor.Start(a.ctx); err != nil {
+			slog.Warn("Network monitoring unavailable, reconnect attempts will not wait for a network", "error", err)
+		} else {
+			reconnectManager.SetNetworkAvailable(networkMonitor.Current().Available())
+		}
+
+		// The tunnel is closed before suspending and restored after resuming
+		if err := sessionWatcher.Start(a.ctx); err != nil {
+			slog.Warn("Suspend and screen lock are not followed", "error", err)
+		}
+
+		// Profiles are connected and disconnected by their schedules, starting
+		// with the windows they are in now
+		scheduler.Start(a.ctx)
+
 		// Register callback to track which profile is being connected to
 		// This updates DefaultProfileID for auto-connect feature
 		a.window.OnProfileConnecting(func(profileID string) {
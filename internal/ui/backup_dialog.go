@@ -0,0 +1,204 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+
+	"github.com/shini4i/openfortivpn-gui/internal/backup"
+)
+
+// BackupOptions are the choices made in the backup dialog.
+type BackupOptions struct {
+	Passphrase     string
+	IncludeSecrets bool
+}
+
+// BackupDialog asks for the passphrase protecting a new backup.
+type BackupDialog struct {
+	dialog        *adw.AlertDialog
+	passphraseRow *adw.PasswordEntryRow
+	confirmRow    *adw.PasswordEntryRow
+	secretsRow    *adw.SwitchRow
+
+	onBackup func(opts BackupOptions)
+}
+
+// NewBackupDialog creates a new backup dialog.
+func NewBackupDialog() *BackupDialog {
+	bd := &BackupDialog{}
+
+	bd.dialog = adw.NewAlertDialog("Back Up Profiles",
+		"Profiles and settings are saved to a single file encrypted with a passphrase. "+
+			"The passphrase is required to restore the backup and cannot be recovered.")
+
+	bd.passphraseRow = adw.NewPasswordEntryRow()
+	bd.passphraseRow.SetTitle("Passphrase")
+	bd.confirmRow = adw.NewPasswordEntryRow()
+	bd.confirmRow.SetTitle("Confirm Passphrase")
+	bd.secretsRow = adw.NewSwitchRow()
+	bd.secretsRow.SetTitle("Include Saved Passwords")
+	bd.secretsRow.SetSubtitle("Passwords from the keyring are stored in the encrypted backup")
+
+	group := adw.NewPreferencesGroup()
+	group.Add(bd.passphraseRow)
+	group.Add(bd.confirmRow)
+	group.Add(bd.secretsRow)
+	bd.dialog.SetExtraChild(group)
+
+	bd.dialog.AddResponse("cancel", "Cancel")
+	bd.dialog.AddResponse("backup", "Back Up…")
+	bd.dialog.SetResponseAppearance("backup", adw.ResponseSuggested)
+	bd.dialog.SetDefaultResponse("backup")
+	bd.dialog.SetCloseResponse("cancel")
+	bd.dialog.SetResponseEnabled("backup", false)
+
+	bd.passphraseRow.ConnectChanged(bd.validate)
+	bd.confirmRow.ConnectChanged(bd.validate)
+
+	bd.dialog.ConnectResponse(func(response string) {
+		if response != "backup" || bd.onBackup == nil {
+			return
+		}
+		bd.onBackup(BackupOptions{
+			Passphrase:     bd.passphraseRow.Text(),
+			IncludeSecrets: bd.secretsRow.Active(),
+		})
+	})
+
+	return bd
+}
+
+// validate enables the backup response once the passphrase is long enough and confirmed.
+func (bd *BackupDialog) validate() {
+	passphrase := bd.passphraseRow.Text()
+	confirm := bd.confirmRow.Text()
+
+	long := len([]rune(passphrase)) >= backup.MinPassphraseLength
+	matches := passphrase == confirm
+	setErrorClass(bd.passphraseRow, passphrase != "" && !long)
+	setErrorClass(bd.confirmRow, confirm != "" && !matches)
+
+	bd.dialog.SetResponseEnabled("backup", long && matches)
+}
+
+// OnBackup registers a callback for when the user confirms the backup.
+func (bd *BackupDialog) OnBackup(callback func(opts BackupOptions)) {
+	bd.onBackup = callback
+}
+
+// Present shows the dialog.
+func (bd *BackupDialog) Present(parent gtk.Widgetter) {
+	bd.dialog.Present(parent)
+}
+
+// RestoreOptions are the choices made in the restore dialog.
+type RestoreOptions struct {
+	Passphrase string
+	backup.RestoreOptions
+}
+
+// collisionChoices lists the collision policies in combo row order.
+var collisionChoices = []struct {
+	label  string
+	policy backup.CollisionPolicy
+}{
+	{label: "Keep Both", policy: backup.KeepBoth},
+	{label: "Replace Existing", policy: backup.Replace},
+}
+
+// RestoreDialog asks for the passphrase of a backup and how to restore it.
+type RestoreDialog struct {
+	dialog        *adw.AlertDialog
+	passphraseRow *adw.PasswordEntryRow
+	collisionRow  *adw.ComboRow
+	configRow     *adw.SwitchRow
+
+	onRestore func(opts RestoreOptions)
+}
+
+// NewRestoreDialog creates a new restore dialog for the named backup file.
+func NewRestoreDialog(fileName string) *RestoreDialog {
+	rd := &RestoreDialog{}
+
+	rd.dialog = adw.NewAlertDialog("Restore Backup",
+		fmt.Sprintf("Enter the passphrase for “%s”.", fileName))
+
+	rd.passphraseRow = adw.NewPasswordEntryRow()
+	rd.passphraseRow.SetTitle("Passphrase")
+
+	labels := make([]string, len(collisionChoices))
+	for i, c := range collisionChoices {
+		labels[i] = c.label
+	}
+	rd.collisionRow = adw.NewComboRow()
+	rd.collisionRow.SetTitle("Existing Profiles")
+	rd.collisionRow.SetSubtitle("When a backed up profile is already present")
+	rd.collisionRow.SetModel(gtk.NewStringList(labels))
+
+	rd.configRow = adw.NewSwitchRow()
+	rd.configRow.SetTitle("Restore Settings")
+	rd.configRow.SetSubtitle("Replace the current preferences with those in the backup")
+
+	group := adw.NewPreferencesGroup()
+	group.Add(rd.passphraseRow)
+	group.Add(rd.collisionRow)
+	group.Add(rd.configRow)
+	rd.dialog.SetExtraChild(group)
+
+	rd.dialog.AddResponse("cancel", "Cancel")
+	rd.dialog.AddResponse("restore", "Restore")
+	rd.dialog.SetResponseAppearance("restore", adw.ResponseSuggested)
+	rd.dialog.SetDefaultResponse("restore")
+	rd.dialog.SetCloseResponse("cancel")
+	rd.dialog.SetResponseEnabled("restore", false)
+
+	rd.passphraseRow.ConnectChanged(func() {
+		rd.dialog.SetResponseEnabled("restore", rd.passphraseRow.Text() != "")
+	})
+
+	rd.dialog.ConnectResponse(func(response string) {
+		if response != "restore" || rd.onRestore == nil {
+			return
+		}
+		opts := RestoreOptions{Passphrase: rd.passphraseRow.Text()}
+		if i := rd.collisionRow.Selected(); int(i) < len(collisionChoices) {
+			opts.Collisions = collisionChoices[i].policy
+		}
+		opts.RestoreConfig = rd.configRow.Active()
+		rd.onRestore(opts)
+	})
+
+	return rd
+}
+
+// OnRestore registers a callback for when the user confirms the restore.
+func (rd *RestoreDialog) OnRestore(callback func(opts RestoreOptions)) {
+	rd.onRestore = callback
+}
+
+// Present shows the dialog.
+func (rd *RestoreDialog) Present(parent gtk.Widgetter) {
+	rd.dialog.Present(parent)
+}
+
+// backupFileFilters returns the file chooser filters for backup archives.
+func backupFileFilters() *gio.ListStore {
+	filters := gio.NewListStore(gtk.GTypeFileFilter)
+	filter := gtk.NewFileFilter()
+	filter.SetName("openfortivpn-gui Backup")
+	filter.AddPattern("*" + backup.FileExtension)
+	filters.Append(filter.Object)
+	return filters
+}
+
+// setErrorClass adds or removes the error style class of a passphrase row.
+func setErrorClass(row *adw.PasswordEntryRow, failed bool) {
+	if failed {
+		row.AddCSSClass("error")
+	} else {
+		row.RemoveCSSClass("error")
+	}
+}
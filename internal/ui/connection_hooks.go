@@ -0,0 +1,258 @@
+package ui
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+
+	"github.com/shini4i/openfortivpn-gui/internal/hooks"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+	"github.com/shini4i/openfortivpn-gui/internal/vpn"
+)
+
+// connectionHooks runs the hooks of the profile being connected as the connection
+// changes state. Hooks run one at a time and in order, off the main loop, so a
+// post-disconnect hook never overtakes the post-connect hook it undoes.
+//
+// Post-disconnect hooks only run for connections that came up, and pre-disconnect
+// hooks only before a disconnect the user asked for. Automatic reconnects run
+// every hook a manual connection does; a failing pre-connect hook ends them.
+type connectionHooks struct {
+	runner     *hooks.Runner
+	controller vpn.VPNController
+	ctx        context.Context
+
+	// queue holds the hooks waiting to run, in order; wake signals the worker
+	// that it is no longer empty
+	queueMu sync.Mutex
+	queue   []func()
+	wake    chan struct{}
+
+	mu      sync.Mutex
+	current *hookSession // The current connection, nil before the first one
+}
+
+// hookSession is a connection hooks run for.
+// Its fields are guarded by the connectionHooks mutex.
+type hookSession struct {
+	profile *profile.Profile
+	conn    hooks.Connection
+	// connected is set once the tunnel came up, until it goes down again
+	connected bool
+}
+
+// newConnectionHooks creates the hook runner for a controller's connections.
+// Hook output is passed to output. Queued hooks are dropped once ctx is cancelled.
+func newConnectionHooks(ctx context.Context, controller vpn.VPNController, output func(line string)) *connectionHooks {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	h := &connectionHooks{
+		runner:     hooks.NewRunner(hooks.WithOutput(output)),
+		controller: controller,
+		ctx:        ctx,
+		wake:       make(chan struct{}, 1),
+	}
+	go h.work()
+	return h
+}
+
+// work runs queued hooks until the context is cancelled.
+func (h *connectionHooks) work() {
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-h.wake:
+		}
+		for job := h.dequeue(); job != nil; job = h.dequeue() {
+			if h.ctx.Err() != nil {
+				return
+			}
+			job()
+		}
+	}
+}
+
+// dequeue removes the next hook from the queue, returning nil if it is empty.
+func (h *connectionHooks) dequeue() func() {
+	h.queueMu.Lock()
+	defer h.queueMu.Unlock()
+	if len(h.queue) == 0 {
+		return nil
+	}
+	job := h.queue[0]
+	h.queue[0] = nil
+	h.queue = h.queue[1:]
+	return job
+}
+
+// run runs a hook and logs its failure.
+func (h *connectionHooks) run(event hooks.Event, p *profile.Profile, conn hooks.Connection) error {
+	err := h.runner.Run(h.ctx, event, p, conn)
+	if err != nil {
+		slog.Warn("Profile hook failed", "hook", event, "profile_id", p.ID, "error", err)
+	}
+	return err
+}
+
+// begin records the profile of a connection attempt, after its gateway was selected.
+func (h *connectionHooks) begin(p *profile.Profile) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.current = &hookSession{
+		profile: p,
+		conn:    hooks.Connection{Gateway: net.JoinHostPort(p.Host, strconv.Itoa(p.Port))},
+	}
+}
+
+// currentProfile returns the profile of the current connection, or nil before the first one.
+func (h *connectionHooks) currentProfile() *profile.Profile {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.current == nil {
+		return nil
+	}
+	return h.current.profile
+}
+
+// nameServers returns the name servers the VPN assigned to the current connection.
+func (h *connectionHooks) nameServers() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.current == nil {
+		return nil
+	}
+	return slices.Clone(h.current.conn.DNSServers)
+}
+
+// beforeConnect runs the profile's pre-connect hook and then calls done on the main loop.
+// done receives the hook's error; the connection should not be started if it is set.
+func (h *connectionHooks) beforeConnect(p *profile.Profile, done func(err error)) {
+	if hooks.Command(p, hooks.PreConnect) == "" {
+		done(nil)
+		return
+	}
+
+	conn := hooks.Connection{Gateway: net.JoinHostPort(p.Host, strconv.Itoa(p.Port))}
+	h.enqueue(func() {
+		err := h.run(hooks.PreConnect, p, conn)
+		glib.IdleAdd(func() { done(err) })
+	})
+}
+
+// beforeDisconnect runs the connected profile's pre-disconnect hook and then calls
+// done on the main loop. A failing hook does not prevent the disconnect.
+func (h *connectionHooks) beforeDisconnect(done func()) {
+	h.mu.Lock()
+	session := h.current
+	// The hook prepares for the tunnel going away, so it only runs while it is up
+	run := session != nil && session.connected && hooks.Command(session.profile, hooks.PreDisconnect) != ""
+	h.mu.Unlock()
+
+	if !run {
+		done()
+		return
+	}
+
+	h.enqueue(func() {
+		_ = h.run(hooks.PreDisconnect, session.profile, h.connection(session))
+		glib.IdleAdd(done)
+	})
+}
+
+// setAddresses records the addresses the VPN assigned to the current connection.
+func (h *connectionHooks) setAddresses(event *vpn.OutputEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.current == nil {
+		return
+	}
+	h.current.conn.IP = event.GetData("ip")
+	h.current.conn.DNSServers = nil
+	if dns := event.GetData(vpn.DataKeyDNS); dns != "" {
+		h.current.conn.DNSServers = strings.Split(dns, ",")
+	}
+}
+
+// stateChanged queues the post-connect hook once the tunnel is up and the
+// post-disconnect hook once a connection that was up went down.
+func (h *connectionHooks) stateChanged(newState vpn.ConnectionState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.current
+	if session == nil || session.profile.Hooks.IsEmpty() {
+		return
+	}
+
+	switch newState {
+	case vpn.StateConnected:
+		if session.connected {
+			return
+		}
+		session.connected = true
+		h.enqueue(func() {
+			// The interface is gone by the time the post-disconnect hook runs, so it is kept
+			ip := h.connection(session).IP
+			name := h.tunnelInterface(ip)
+			h.mu.Lock()
+			session.conn.Interface = name
+			h.mu.Unlock()
+
+			_ = h.run(hooks.PostConnect, session.profile, h.connection(session))
+		})
+
+	case vpn.StateDisconnected, vpn.StateFailed:
+		if !session.connected {
+			return
+		}
+		session.connected = false
+		h.enqueue(func() {
+			_ = h.run(hooks.PostDisconnect, session.profile, h.connection(session))
+		})
+	}
+}
+
+// connection returns the details of a session's connection.
+func (h *connectionHooks) connection(session *hookSession) hooks.Connection {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conn := session.conn
+	conn.DNSServers = slices.Clone(conn.DNSServers)
+	return conn
+}
+
+// enqueue queues a hook behind those already waiting, without blocking the
+// caller, which may be the main loop or hold the lock.
+func (h *connectionHooks) enqueue(job func()) {
+	h.queueMu.Lock()
+	h.queue = append(h.queue, job)
+	h.queueMu.Unlock()
+
+	select {
+	case h.wake <- struct{}{}:
+	default:
+		// The worker has not taken the previous signal yet and will see the job
+	}
+}
+
+// tunnelInterface returns the VPN interface, waiting briefly for it to be detected.
+func (h *connectionHooks) tunnelInterface(ip string) string {
+	if name := h.controller.GetInterface(); name != "" || ip == "" {
+		return name
+	}
+	name, err := vpn.DetectInterfaceWithRetry(ip, 5, 100*time.Millisecond, nil)
+	if err != nil {
+		slog.Warn("Failed to detect VPN interface for hook", "ip", ip, "error", err)
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+
+	"github.com/shini4i/openfortivpn-gui/internal/diagnose"
+)
+
+// diagnosticsMaxHeight limits the height of the step list before it scrolls.
+const diagnosticsMaxHeight = 420
+
+// diagnosticStatusIcons maps step results to icon names and style classes.
+var diagnosticStatusIcons = map[diagnose.Status]struct {
+	icon  string
+	class string
+}{
+	diagnose.StatusPass:    {"emblem-ok-symbolic", "success"},
+	diagnose.StatusWarn:    {"dialog-warning-symbolic", "warning"},
+	diagnose.StatusFail:    {"dialog-error-symbolic", "error"},
+	diagnose.StatusSkipped: {"action-unavailable-symbolic", "dim-label"},
+}
+
+// ShowDiagnosticsDialog presents the results of a connection test, one group per gateway.
+func ShowDiagnosticsDialog(parent gtk.Widgetter, reports []*diagnose.Report) {
+	dialog := adw.NewAlertDialog("Connection Test", diagnosticsBody(reports))
+
+	content := gtk.NewBox(gtk.OrientationVertical, 12)
+	for _, report := range reports {
+		group := adw.NewPreferencesGroup()
+		group.SetTitle(report.Gateway)
+		if len(reports) > 1 {
+			group.SetDescription(report.Summary())
+		}
+		for _, step := range report.Steps {
+			group.Add(newDiagnosticStepRow(step))
+		}
+		content.Append(group)
+	}
+
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+	scrolled.SetPropagateNaturalHeight(true)
+	scrolled.SetMaxContentHeight(diagnosticsMaxHeight)
+	scrolled.SetChild(content)
+	dialog.SetExtraChild(scrolled)
+
+	dialog.AddResponse("close", "Close")
+	dialog.SetDefaultResponse("close")
+	dialog.SetCloseResponse("close")
+	dialog.Present(parent)
+}
+
+// diagnosticsBody returns the dialog text: the conclusion for a single gateway,
+// or how many of several gateways passed.
+func diagnosticsBody(reports []*diagnose.Report) string {
+	if len(reports) == 1 {
+		return reports[0].Summary()
+	}
+
+	passed := 0
+	for _, r := range reports {
+		if r.OK() {
+			passed++
+		}
+	}
+	return fmt.Sprintf("%d of %d gateways passed all checks.", passed, len(reports))
+}
+
+// newDiagnosticStepRow creates a row showing the result of one diagnostic step.
+func newDiagnosticStepRow(step *diagnose.Step) *adw.ActionRow {
+	row := adw.NewActionRow()
+	// Details contain error messages and addresses, not markup
+	row.SetUseMarkup(false)
+	row.SetTitle(step.Name.Label())
+	row.SetSubtitle(step.Detail)
+	row.SetSubtitleLines(0)
+
+	if style, ok := diagnosticStatusIcons[step.Status]; ok {
+		icon := gtk.NewImageFromIconName(style.icon)
+		icon.AddCSSClass(style.class)
+		row.AddPrefix(icon)
+	}
+
+	if step.Duration > 0 {
+		duration := gtk.NewLabel(step.Duration.Round(time.Millisecond).String())
+		duration.AddCSSClass("dim-label")
+		row.AddSuffix(duration)
+	}
+
+	return row
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// exportFormat describes an external configuration format that a profile can be exported to.
+type exportFormat struct {
+	// name is shown in the file chooser filter list.
+	name string
+	// extension is appended to the suggested file name, including the leading dot.
+	extension string
+	// save writes the profile to path.
+	save func(path string, p *profile.Profile) error
+}
+
+// exportFormats lists the supported export formats. The first entry is the default.
+var exportFormats = []exportFormat{
+	{
+		name:      "openfortivpn Configuration",
+		extension: ".conf",
+		save:      profile.ExportOpenfortivpnConfigFile,
+	},
+	{
+		name:      "NetworkManager Connection",
+		extension: ".nmconnection",
+		save:      profile.ExportNetworkManagerFile,
+	},
+}
+
+// findExportFormat returns the export format matching the file extension,
+// falling back to the default format for unknown extensions.
+func findExportFormat(path string) *exportFormat {
+	ext := strings.ToLower(filepath.Ext(path))
+	for i := range exportFormats {
+		if exportFormats[i].extension == ext {
+			return &exportFormats[i]
+		}
+	}
+	return &exportFormats[0]
+}
+
+// exportFileName suggests a file name for exporting the profile in the given format.
+func exportFileName(p *profile.Profile, format *exportFormat) string {
+	name := strings.TrimSpace(p.Name)
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	if name == "" {
+		name = "profile"
+	}
+	return name + format.extension
+}
+
+// exportFileFilters creates file chooser filters for all export formats.
+func exportFileFilters() *gio.ListStore {
+	filters := gio.NewListStore(gtk.GTypeFileFilter)
+	for _, format := range exportFormats {
+		filter := gtk.NewFileFilter()
+		filter.SetName(format.name)
+		filter.AddSuffix(strings.TrimPrefix(format.extension, "."))
+		filters.Append(filter.Object)
+	}
+	return filters
+}
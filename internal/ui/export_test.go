@@ -0,0 +1,24 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+func TestFindExportFormat(t *testing.T) {
+	assert.Equal(t, ".conf", findExportFormat("/home/user/office.CONF").extension)
+	assert.Equal(t, ".nmconnection", findExportFormat("/home/user/Office.nmconnection").extension)
+	// Unknown extensions fall back to the default format
+	assert.Equal(t, ".conf", findExportFormat("/home/user/office").extension)
+}
+
+func TestExportFileName(t *testing.T) {
+	format := &exportFormats[0]
+
+	assert.Equal(t, "Office VPN.conf", exportFileName(&profile.Profile{Name: "Office VPN"}, format))
+	assert.Equal(t, "a_b.conf", exportFileName(&profile.Profile{Name: "a/b"}, format))
+	assert.Equal(t, "profile.conf", exportFileName(&profile.Profile{}, format))
+}
@@ -0,0 +1,226 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// importFormat describes an external configuration format that profiles can be imported from.
+type importFormat struct {
+	// name is shown in the file chooser filter list.
+	name string
+	// patterns are file name glob patterns identifying the format.
+	patterns []string
+	// load parses the file into importable profiles.
+	load func(path string) (*profile.ImportResult, error)
+}
+
+// importFormats lists the supported import formats in file chooser order.
+var importFormats = []importFormat{
+	{
+		name:     "FortiClient Configuration",
+		patterns: []string{"*.xml"},
+		load:     profile.ImportFortiClientFile,
+	},
+	{
+		name:     "openfortivpn Configuration",
+		patterns: []string{"*.conf", "config"},
+		load:     profile.ImportOpenfortivpnConfigFile,
+	},
+	{
+		name:     "NetworkManager Connection",
+		patterns: []string{"*.nmconnection"},
+		load:     profile.ImportNetworkManagerFile,
+	},
+}
+
+// findImportFormat returns the import format matching the file name, or nil if none matches.
+func findImportFormat(path string) *importFormat {
+	base := strings.ToLower(filepath.Base(path))
+	for i := range importFormats {
+		for _, pattern := range importFormats[i].patterns {
+			if matched, _ := filepath.Match(pattern, base); matched {
+				return &importFormats[i]
+			}
+		}
+	}
+	return nil
+}
+
+// loadImportFile parses a file using the import format matching its name.
+func loadImportFile(path string) (*profile.ImportResult, error) {
+	format := findImportFormat(path)
+	if format == nil {
+		return nil, fmt.Errorf("unsupported file type: %s", filepath.Base(path))
+	}
+	return format.load(path)
+}
+
+// importFileFilters creates file chooser filters for all import formats,
+// preceded by a filter matching any supported file.
+func importFileFilters() *gio.ListStore {
+	filters := gio.NewListStore(gtk.GTypeFileFilter)
+
+	all := gtk.NewFileFilter()
+	all.SetName("All Supported Files")
+	for _, format := range importFormats {
+		for _, pattern := range format.patterns {
+			all.AddPattern(pattern)
+		}
+	}
+	filters.Append(all.Object)
+
+	for _, format := range importFormats {
+		filter := gtk.NewFileFilter()
+		filter.SetName(format.name)
+		for _, pattern := range format.patterns {
+			filter.AddPattern(pattern)
+		}
+		filters.Append(filter.Object)
+	}
+
+	return filters
+}
+
+// importRow pairs an imported profile with its selection checkbox.
+type importRow struct {
+	imported profile.ImportedProfile
+	check    *gtk.CheckButton
+}
+
+// ImportDialog lets the user review imported profiles and choose which ones to save.
+type ImportDialog struct {
+	dialog *adw.AlertDialog
+	rows   []*importRow
+
+	// Callback with the selected profiles
+	onImport func(profiles []*profile.Profile)
+}
+
+// NewImportDialog creates a dialog listing the profiles in the import result.
+// All profiles are selected by default.
+func NewImportDialog(result *profile.ImportResult) *ImportDialog {
+	d := &ImportDialog{}
+	d.setupDialog(result)
+	return d
+}
+
+// setupDialog creates the import dialog UI.
+func (d *ImportDialog) setupDialog(result *profile.ImportResult) {
+	d.dialog = adw.NewAlertDialog("Import Profiles", "")
+	d.dialog.SetBody(fmt.Sprintf("Select the profiles to import. Found %d in the file.", len(result.Profiles)))
+
+	listBox := gtk.NewListBox()
+	listBox.SetSelectionMode(gtk.SelectionNone)
+	listBox.AddCSSClass("boxed-list")
+
+	for _, imported := range result.Profiles {
+		row := &importRow{imported: imported, check: gtk.NewCheckButton()}
+		row.check.SetActive(true)
+		row.check.SetVAlign(gtk.AlignCenter)
+		row.check.ConnectToggled(d.updateImportButton)
+		d.rows = append(d.rows, row)
+		listBox.Append(newImportProfileRow(row))
+	}
+
+	content := gtk.NewBox(gtk.OrientationVertical, 12)
+	content.Append(listBox)
+
+	// File-level warnings (e.g., skipped entries)
+	if len(result.Warnings) > 0 {
+		warnings := make([]string, 0, len(result.Warnings))
+		for _, w := range result.Warnings {
+			warnings = append(warnings, w.Reason)
+		}
+		label := gtk.NewLabel(strings.Join(warnings, "\n"))
+		label.SetWrap(true)
+		label.SetXAlign(0)
+		label.AddCSSClass("dim-label")
+		label.AddCSSClass("caption")
+		content.Append(label)
+	}
+
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+	scrolled.SetPropagateNaturalHeight(true)
+	scrolled.SetMaxContentHeight(400)
+	scrolled.SetChild(content)
+	d.dialog.SetExtraChild(scrolled)
+
+	d.dialog.AddResponse("cancel", "Cancel")
+	d.dialog.AddResponse("import", "Import")
+	d.dialog.SetResponseAppearance("import", adw.ResponseSuggested)
+	d.dialog.SetDefaultResponse("import")
+	d.dialog.SetCloseResponse("cancel")
+
+	d.dialog.ConnectResponse(func(response string) {
+		if response != "import" || d.onImport == nil {
+			return
+		}
+		d.onImport(d.selectedProfiles())
+	})
+}
+
+// newImportProfileRow creates a list row for an imported profile.
+// Profiles with warnings use an expander row listing each warning.
+func newImportProfileRow(row *importRow) gtk.Widgetter {
+	p := row.imported.Profile
+	subtitle := fmt.Sprintf("%s:%d", p.Host, p.Port)
+
+	if len(row.imported.Warnings) == 0 {
+		actionRow := adw.NewActionRow()
+		actionRow.SetTitle(p.Name)
+		actionRow.SetSubtitle(subtitle)
+		actionRow.AddPrefix(row.check)
+		actionRow.SetActivatableWidget(row.check)
+		return actionRow
+	}
+
+	expanderRow := adw.NewExpanderRow()
+	expanderRow.SetTitle(p.Name)
+	expanderRow.SetSubtitle(fmt.Sprintf("%s • %d setting(s) not imported", subtitle, len(row.imported.Warnings)))
+	expanderRow.AddPrefix(row.check)
+
+	for _, w := range row.imported.Warnings {
+		warningRow := adw.NewActionRow()
+		warningRow.SetTitle(w.Field)
+		warningRow.SetSubtitle(w.Reason)
+		warningRow.AddPrefix(gtk.NewImageFromIconName("dialog-warning-symbolic"))
+		expanderRow.AddRow(warningRow)
+	}
+
+	return expanderRow
+}
+
+// updateImportButton enables the Import response only when at least one profile is selected.
+func (d *ImportDialog) updateImportButton() {
+	d.dialog.SetResponseEnabled("import", len(d.selectedProfiles()) > 0)
+}
+
+// selectedProfiles returns the profiles whose checkbox is active.
+func (d *ImportDialog) selectedProfiles() []*profile.Profile {
+	var selected []*profile.Profile
+	for _, row := range d.rows {
+		if row.check.Active() {
+			selected = append(selected, row.imported.Profile)
+		}
+	}
+	return selected
+}
+
+// OnImport registers a callback invoked with the selected profiles when the user confirms.
+func (d *ImportDialog) OnImport(callback func(profiles []*profile.Profile)) {
+	d.onImport = callback
+}
+
+// Present shows the import dialog.
+func (d *ImportDialog) Present(parent gtk.Widgetter) {
+	d.dialog.Present(parent)
+}
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindImportFormat(t *testing.T) {
+	format := findImportFormat("/home/user/Downloads/FortiClient-Backup.XML")
+	require.NotNil(t, format)
+	assert.Equal(t, "FortiClient Configuration", format.name)
+
+	format = findImportFormat("/etc/openfortivpn/config")
+	require.NotNil(t, format)
+	assert.Equal(t, "openfortivpn Configuration", format.name)
+
+	format = findImportFormat("/home/user/office.conf")
+	require.NotNil(t, format)
+	assert.Equal(t, "openfortivpn Configuration", format.name)
+
+	format = findImportFormat("/etc/NetworkManager/system-connections/Office.nmconnection")
+	require.NotNil(t, format)
+	assert.Equal(t, "NetworkManager Connection", format.name)
+
+	assert.Nil(t, findImportFormat("/home/user/profile.json"))
+}
+
+func TestLoadImportFile_UnsupportedType(t *testing.T) {
+	_, err := loadImportFile("/home/user/profile.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported file type")
+}
@@ -0,0 +1,239 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+
+	"github.com/shini4i/openfortivpn-gui/internal/netrules"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// matchChoices lists the network matches in combo row order.
+var matchChoices = []struct {
+	label      string
+	match      netrules.MatchType
+	valueTitle string
+}{
+	{label: "Gateway MAC Address", match: netrules.MatchGatewayMAC, valueTitle: "MAC Address"},
+	{label: "Wi-Fi Network", match: netrules.MatchSSID, valueTitle: "SSID"},
+	{label: "Search Domain", match: netrules.MatchSearchDomain, valueTitle: "Domain"},
+	{label: "Reachable Host", match: netrules.MatchReachable, valueTitle: "Host and Port (e.g. 10.0.0.1:443)"},
+	{label: "Any Network", match: netrules.MatchAny},
+}
+
+// ruleActionChoices lists the rule actions in combo row order.
+var ruleActionChoices = []struct {
+	label  string
+	action netrules.Action
+}{
+	{label: "Disconnect and Stay Off", action: netrules.ActionDisconnect},
+	{label: "Connect Profile", action: netrules.ActionConnect},
+}
+
+// describeRule summarizes what a rule matches and does, naming the profile it connects.
+func describeRule(r netrules.Rule, profiles []*profile.Profile) string {
+	network := "Any network"
+	for _, c := range matchChoices {
+		if c.match == r.Match && r.Match != netrules.MatchAny {
+			network = fmt.Sprintf("%s %s", c.label, r.Value)
+		}
+	}
+	if r.Action == netrules.ActionDisconnect {
+		return network + " → Disconnect"
+	}
+
+	name := r.ProfileID
+	for _, p := range profiles {
+		if p.ID == r.ProfileID {
+			name = p.Name
+		}
+	}
+	return fmt.Sprintf("%s → Connect %s", network, name)
+}
+
+// NetworkRuleDialog adds or edits a network rule.
+type NetworkRuleDialog struct {
+	dialog     *adw.AlertDialog
+	nameRow    *adw.EntryRow
+	matchRow   *adw.ComboRow
+	valueRow   *adw.EntryRow
+	actionRow  *adw.ComboRow
+	profileRow *adw.ComboRow
+
+	profiles []*profile.Profile
+	current  netrules.Network
+
+	onSave func(rule netrules.Rule)
+}
+
+// NewNetworkRuleDialog creates a dialog editing rule, or adding a new rule if rule is nil.
+// The current network is offered as the value to match.
+func NewNetworkRuleDialog(rule *netrules.Rule, profiles []*profile.Profile, current netrules.Network) *NetworkRuleDialog {
+	nd := &NetworkRuleDialog{profiles: profiles, current: current}
+
+	title, save := "Add Network Rule", "Add"
+	if rule != nil {
+		title, save = "Edit Network Rule", "Save"
+	}
+	nd.dialog = adw.NewAlertDialog(title,
+		"Rules are checked in order whenever the network changes. The first rule matching the network applies.")
+
+	nd.nameRow = adw.NewEntryRow()
+	nd.nameRow.SetTitle("Name (e.g. Office)")
+
+	matchLabels := make([]string, len(matchChoices))
+	for i, c := range matchChoices {
+		matchLabels[i] = c.label
+	}
+	nd.matchRow = adw.NewComboRow()
+	nd.matchRow.SetTitle("Network")
+	nd.matchRow.SetModel(gtk.NewStringList(matchLabels))
+
+	nd.valueRow = adw.NewEntryRow()
+
+	actionLabels := make([]string, len(ruleActionChoices))
+	for i, c := range ruleActionChoices {
+		actionLabels[i] = c.label
+	}
+	nd.actionRow = adw.NewComboRow()
+	nd.actionRow.SetTitle("Action")
+	nd.actionRow.SetModel(gtk.NewStringList(actionLabels))
+
+	profileNames := make([]string, len(profiles))
+	for i, p := range profiles {
+		profileNames[i] = p.Name
+	}
+	nd.profileRow = adw.NewComboRow()
+	nd.profileRow.SetTitle("Profile")
+	nd.profileRow.SetModel(gtk.NewStringList(profileNames))
+
+	group := adw.NewPreferencesGroup()
+	group.Add(nd.nameRow)
+	group.Add(nd.matchRow)
+	group.Add(nd.valueRow)
+	group.Add(nd.actionRow)
+	group.Add(nd.profileRow)
+	nd.dialog.SetExtraChild(group)
+
+	if rule != nil {
+		nd.setRule(*rule)
+	} else {
+		nd.suggestValue()
+	}
+	nd.updateRows()
+
+	nd.dialog.AddResponse("cancel", "Cancel")
+	nd.dialog.AddResponse("save", save)
+	nd.dialog.SetResponseAppearance("save", adw.ResponseSuggested)
+	nd.dialog.SetDefaultResponse("save")
+	nd.dialog.SetCloseResponse("cancel")
+
+	nd.matchRow.NotifyProperty("selected", func() {
+		nd.valueRow.SetText("")
+		nd.suggestValue()
+		nd.updateRows()
+	})
+	nd.actionRow.NotifyProperty("selected", nd.updateRows)
+	nd.profileRow.NotifyProperty("selected", nd.validate)
+	nd.valueRow.ConnectChanged(nd.validate)
+
+	nd.dialog.ConnectResponse(func(response string) {
+		if response != "save" || nd.onSave == nil {
+			return
+		}
+		nd.onSave(nd.rule())
+	})
+
+	return nd
+}
+
+// setRule fills the rows from a rule.
+func (nd *NetworkRuleDialog) setRule(r netrules.Rule) {
+	nd.nameRow.SetText(r.Name)
+	for i, c := range matchChoices {
+		if c.match == r.Match {
+			nd.matchRow.SetSelected(uint(i))
+		}
+	}
+	nd.valueRow.SetText(r.Value)
+	for i, c := range ruleActionChoices {
+		if c.action == r.Action {
+			nd.actionRow.SetSelected(uint(i))
+		}
+	}
+	for i, p := range nd.profiles {
+		if p.ID == r.ProfileID {
+			nd.profileRow.SetSelected(uint(i))
+		}
+	}
+}
+
+// rule returns the rule described by the rows.
+func (nd *NetworkRuleDialog) rule() netrules.Rule {
+	r := netrules.Rule{
+		Name:   strings.TrimSpace(nd.nameRow.Text()),
+		Match:  matchChoices[nd.matchRow.Selected()].match,
+		Action: ruleActionChoices[nd.actionRow.Selected()].action,
+	}
+	if r.Match != netrules.MatchAny {
+		r.Value = strings.TrimSpace(nd.valueRow.Text())
+	}
+	if selected := int(nd.profileRow.Selected()); r.Action == netrules.ActionConnect && selected < len(nd.profiles) {
+		r.ProfileID = nd.profiles[selected].ID
+	}
+	return r
+}
+
+// suggestValue fills in the value of the current network for the selected match.
+func (nd *NetworkRuleDialog) suggestValue() {
+	var value string
+	switch matchChoices[nd.matchRow.Selected()].match {
+	case netrules.MatchGatewayMAC:
+		value = nd.current.GatewayMAC
+	case netrules.MatchSSID:
+		value = nd.current.SSID
+	case netrules.MatchSearchDomain:
+		if len(nd.current.SearchDomains) > 0 {
+			value = nd.current.SearchDomains[0]
+		}
+	}
+	if value != "" {
+		nd.valueRow.SetText(value)
+	}
+}
+
+// updateRows shows the rows the selected match and action need.
+func (nd *NetworkRuleDialog) updateRows() {
+	choice := matchChoices[nd.matchRow.Selected()]
+	nd.valueRow.SetVisible(choice.match != netrules.MatchAny)
+	nd.valueRow.SetTitle(choice.valueTitle)
+	nd.profileRow.SetVisible(ruleActionChoices[nd.actionRow.Selected()].action == netrules.ActionConnect)
+	nd.validate()
+}
+
+// validate enables the save response once the rule is complete.
+func (nd *NetworkRuleDialog) validate() {
+	r := nd.rule()
+	nd.dialog.SetResponseEnabled("save", r.Validate() == nil)
+
+	// Only a malformed value is marked, not a missing profile
+	r.Action = netrules.ActionDisconnect
+	if nd.valueRow.Text() != "" && r.Validate() != nil {
+		nd.valueRow.AddCSSClass("error")
+	} else {
+		nd.valueRow.RemoveCSSClass("error")
+	}
+}
+
+// OnSave registers a callback for when the user confirms the rule.
+func (nd *NetworkRuleDialog) OnSave(callback func(rule netrules.Rule)) {
+	nd.onSave = callback
+}
+
+// Present shows the dialog.
+func (nd *NetworkRuleDialog) Present(parent gtk.Widgetter) {
+	nd.dialog.Present(parent)
+}
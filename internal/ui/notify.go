@@ -22,8 +22,22 @@ const (
 	NotifyConnectionFailed
 	// NotifyReconnecting indicates the VPN is attempting to reconnect.
 	NotifyReconnecting
+	// NotifyDegraded indicates the tunnel stopped passing traffic.
+	NotifyDegraded
+	// NotifyScheduleEnding indicates the profile's schedule window ends soon.
+	NotifyScheduleEnding
+	// NotifyScheduleEnded indicates the VPN was disconnected at the end of its schedule window.
+	NotifyScheduleEnded
+	// NotifyIdle indicates the idle tunnel is about to be disconnected.
+	// It offers to stay connected.
+	NotifyIdle
+	// NotifyIdleDisconnected indicates the VPN was disconnected for being idle.
+	NotifyIdleDisconnected
 )
 
+// stayConnectedAction is the application action that keeps an idle tunnel connected.
+const stayConnectedAction = "stay-connected"
+
 // Notifier manages desktop notifications for VPN events.
 // All methods are safe for concurrent access.
 type Notifier struct {
@@ -62,6 +76,7 @@ func (n *Notifier) Notify(notifyType NotificationType, profileName string) {
 	}
 
 	var title, body, icon string
+	var buttonLabel, buttonAction string
 
 	switch notifyType {
 	case NotifyConnected:
@@ -80,6 +95,27 @@ func (n *Notifier) Notify(notifyType NotificationType, profileName string) {
 		title = "VPN Reconnecting"
 		body = "Reconnecting to " + profileName
 		icon = "network-vpn-acquiring-symbolic"
+	case NotifyDegraded:
+		title = "VPN Not Responding"
+		body = profileName + " stopped passing traffic"
+		icon = "network-vpn-no-route-symbolic"
+	case NotifyScheduleEnding:
+		title = "VPN Disconnecting Soon"
+		body = profileName + " disconnects soon as its schedule ends"
+		icon = "alarm-symbolic"
+	case NotifyScheduleEnded:
+		title = "VPN Disconnected"
+		body = "Disconnected from " + profileName + " as its schedule ended"
+		icon = "network-vpn-disconnected-symbolic"
+	case NotifyIdle:
+		title = "VPN Idle"
+		body = profileName + " has not been used for a while and disconnects soon"
+		icon = "network-vpn-symbolic"
+		buttonLabel, buttonAction = "Stay Connected", "app."+stayConnectedAction
+	case NotifyIdleDisconnected:
+		title = "VPN Disconnected"
+		body = "Disconnected from " + profileName + " as it was not used"
+		icon = "network-vpn-disconnected-symbolic"
 	default:
 		return
 	}
@@ -89,6 +125,9 @@ func (n *Notifier) Notify(notifyType NotificationType, profileName string) {
 		notification := gio.NewNotification(title)
 		notification.SetBody(body)
 		notification.SetIcon(gio.NewThemedIcon(icon))
+		if buttonAction != "" {
+			notification.AddButton(buttonLabel, buttonAction)
+		}
 
 		// Use a unique ID per notification type so they replace each other
 		notificationID := "vpn-status"
@@ -117,3 +156,29 @@ func (n *Notifier) NotifyConnectionFailed(profileName string) {
 func (n *Notifier) NotifyReconnecting(profileName string) {
 	n.Notify(NotifyReconnecting, profileName)
 }
+
+// NotifyDegraded sends a notification that the tunnel stopped passing traffic.
+func (n *Notifier) NotifyDegraded(profileName string) {
+	n.Notify(NotifyDegraded, profileName)
+}
+
+// NotifyScheduleEnding sends a notification that the profile's schedule ends soon.
+func (n *Notifier) NotifyScheduleEnding(profileName string) {
+	n.Notify(NotifyScheduleEnding, profileName)
+}
+
+// NotifyScheduleEnded sends a notification that the profile was disconnected by its schedule.
+func (n *Notifier) NotifyScheduleEnded(profileName string) {
+	n.Notify(NotifyScheduleEnded, profileName)
+}
+
+// NotifyIdle sends a notification that the idle tunnel disconnects soon, with a
+// button to stay connected.
+func (n *Notifier) NotifyIdle(profileName string) {
+	n.Notify(NotifyIdle, profileName)
+}
+
+// NotifyIdleDisconnected sends a notification that the profile was disconnected for being idle.
+func (n *Notifier) NotifyIdleDisconnected(profileName string) {
+	n.Notify(NotifyIdleDisconnected, profileName)
+}
@@ -38,6 +38,11 @@ func TestNotifier_Notify_DisabledNotifier(t *testing.T) {
 	notifier.Notify(NotifyDisconnected, "TestProfile")
 	notifier.Notify(NotifyConnectionFailed, "TestProfile")
 	notifier.Notify(NotifyReconnecting, "TestProfile")
+	notifier.Notify(NotifyDegraded, "TestProfile")
+	notifier.Notify(NotifyScheduleEnding, "TestProfile")
+	notifier.Notify(NotifyScheduleEnded, "TestProfile")
+	notifier.Notify(NotifyIdle, "TestProfile")
+	notifier.Notify(NotifyIdleDisconnected, "TestProfile")
 }
 
 func TestNotifier_Notify_NilApp(t *testing.T) {
@@ -48,6 +53,11 @@ func TestNotifier_Notify_NilApp(t *testing.T) {
 	notifier.Notify(NotifyDisconnected, "TestProfile")
 	notifier.Notify(NotifyConnectionFailed, "TestProfile")
 	notifier.Notify(NotifyReconnecting, "TestProfile")
+	notifier.Notify(NotifyDegraded, "TestProfile")
+	notifier.Notify(NotifyScheduleEnding, "TestProfile")
+	notifier.Notify(NotifyScheduleEnded, "TestProfile")
+	notifier.Notify(NotifyIdle, "TestProfile")
+	notifier.Notify(NotifyIdleDisconnected, "TestProfile")
 }
 
 func TestNotifier_Notify_InvalidType(t *testing.T) {
@@ -65,6 +75,11 @@ func TestNotifier_ConvenienceMethods_NilApp(t *testing.T) {
 	notifier.NotifyDisconnected("TestProfile")
 	notifier.NotifyConnectionFailed("TestProfile")
 	notifier.NotifyReconnecting("TestProfile")
+	notifier.NotifyDegraded("TestProfile")
+	notifier.NotifyScheduleEnding("TestProfile")
+	notifier.NotifyScheduleEnded("TestProfile")
+	notifier.NotifyIdle("TestProfile")
+	notifier.NotifyIdleDisconnected("TestProfile")
 }
 
 func TestNotifier_ConcurrentAccess(t *testing.T) {
@@ -2,7 +2,15 @@
 package ui
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+
+	"github.com/shini4i/openfortivpn-gui/internal/netrules"
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
 )
 
 // PreferencesWindow shows application preferences.
@@ -14,13 +22,24 @@ type PreferencesWindow struct {
 	notificationsSwitch *adw.SwitchRow
 	autoConnectSwitch   *adw.SwitchRow
 
+	// Network rule widgets
+	networkRow *adw.ActionRow
+	rulesGroup *adw.PreferencesGroup
+	ruleRows   []*adw.ActionRow
+
 	// Callbacks
 	onNotificationsChanged func(enabled bool)
 	onAutoConnectChanged   func(enabled bool)
+	onNetworkRulesChanged  func(rules []netrules.Rule)
 
 	// Track previous state to detect changes
 	prevNotifications bool
 	prevAutoConnect   bool
+	prevRules         []netrules.Rule
+
+	rules    []netrules.Rule
+	profiles []*profile.Profile
+	network  netrules.Network
 }
 
 // NewPreferencesWindow creates a new preferences window.
@@ -35,7 +54,7 @@ func (pw *PreferencesWindow) setupWindow(parent *MainWindow) {
 	pw.window = adw.NewPreferencesWindow() //nolint:staticcheck // PreferencesDialog not yet available
 	pw.window.SetTitle("Preferences")
 	pw.window.SetModal(true)
-	pw.window.SetDefaultSize(400, 300)
+	pw.window.SetDefaultSize(480, 520)
 
 	// Set transient parent
 	if parent != nil && parent.window != nil {
@@ -71,6 +90,8 @@ func (pw *PreferencesWindow) setupWindow(parent *MainWindow) {
 	generalPage.Add(behaviorGroup)
 	pw.window.Add(generalPage) //nolint:staticcheck // PreferencesDialog not yet available
 
+	pw.window.Add(pw.createNetworksPage()) //nolint:staticcheck // PreferencesDialog not yet available
+
 	// Handle window close to trigger callbacks
 	pw.window.ConnectCloseRequest(func() bool {
 		pw.handleClose()
@@ -78,6 +99,111 @@ func (pw *PreferencesWindow) setupWindow(parent *MainWindow) {
 	})
 }
 
+// createNetworksPage creates the page listing the network rules.
+func (pw *PreferencesWindow) createNetworksPage() *adw.PreferencesPage {
+	page := adw.NewPreferencesPage()
+	page.SetTitle("Networks")
+	page.SetIconName("network-wireless-symbolic")
+
+	currentGroup := adw.NewPreferencesGroup()
+	currentGroup.SetTitle("Current Network")
+	pw.networkRow = adw.NewActionRow()
+	pw.networkRow.SetTitle("Detecting…")
+	currentGroup.Add(pw.networkRow)
+	page.Add(currentGroup)
+
+	pw.rulesGroup = adw.NewPreferencesGroup()
+	pw.rulesGroup.SetTitle("Network Rules")
+	pw.rulesGroup.SetDescription("Connect or disconnect automatically depending on the network. " +
+		"Rules are checked in order whenever the network changes, and the first matching rule applies.")
+	addButton := gtk.NewButtonFromIconName("list-add-symbolic")
+	addButton.SetTooltipText("Add Rule")
+	addButton.AddCSSClass("flat")
+	addButton.ConnectClicked(func() {
+		pw.editRule(-1)
+	})
+	pw.rulesGroup.SetHeaderSuffix(addButton)
+	page.Add(pw.rulesGroup)
+
+	pw.rebuildRules()
+	return page
+}
+
+// rebuildRules recreates the rows of the network rules.
+func (pw *PreferencesWindow) rebuildRules() {
+	for _, row := range pw.ruleRows {
+		pw.rulesGroup.Remove(row)
+	}
+	pw.ruleRows = nil
+
+	if len(pw.rules) == 0 {
+		row := adw.NewActionRow()
+		row.SetTitle("No Rules")
+		row.SetSubtitle("The default profile is connected on startup if auto-connect is enabled")
+		pw.addRuleRow(row)
+		return
+	}
+
+	for i, rule := range pw.rules {
+		row := adw.NewActionRow()
+		row.SetTitle(rule.String())
+		row.SetSubtitle(describeRule(rule, pw.profiles))
+		row.SetActivatable(true)
+		row.ConnectActivated(func() {
+			pw.editRule(i)
+		})
+
+		if i > 0 {
+			upButton := gtk.NewButtonFromIconName("go-up-symbolic")
+			upButton.SetTooltipText("Check Earlier")
+			upButton.SetVAlign(gtk.AlignCenter)
+			upButton.AddCSSClass("flat")
+			upButton.ConnectClicked(func() {
+				pw.rules[i-1], pw.rules[i] = pw.rules[i], pw.rules[i-1]
+				pw.rebuildRules()
+			})
+			row.AddSuffix(upButton)
+		}
+
+		deleteButton := gtk.NewButtonFromIconName("user-trash-symbolic")
+		deleteButton.SetTooltipText("Remove Rule")
+		deleteButton.SetVAlign(gtk.AlignCenter)
+		deleteButton.AddCSSClass("flat")
+		deleteButton.ConnectClicked(func() {
+			pw.rules = slices.Delete(pw.rules, i, i+1)
+			pw.rebuildRules()
+		})
+		row.AddSuffix(deleteButton)
+
+		pw.addRuleRow(row)
+	}
+}
+
+// addRuleRow adds a row to the network rules group.
+func (pw *PreferencesWindow) addRuleRow(row *adw.ActionRow) {
+	pw.rulesGroup.Add(row)
+	pw.ruleRows = append(pw.ruleRows, row)
+}
+
+// editRule opens the dialog editing the rule at index, or adding a rule if index is negative.
+func (pw *PreferencesWindow) editRule(index int) {
+	var rule *netrules.Rule
+	if index >= 0 {
+		rule = &pw.rules[index]
+	}
+
+	dialog := NewNetworkRuleDialog(rule, pw.profiles, pw.network)
+	dialog.OnSave(func(r netrules.Rule) {
+		if index >= 0 {
+			pw.rules[index] = r
+		} else {
+			pw.rules = append(pw.rules, r)
+		}
+		pw.rebuildRules()
+	})
+	dialog.Present(pw.window)
+}
+
 // handleClose is called when the preferences window is closed.
 func (pw *PreferencesWindow) handleClose() {
 	// Check for notification changes
@@ -93,6 +219,13 @@ func (pw *PreferencesWindow) handleClose() {
 			pw.onAutoConnectChanged(pw.autoConnectSwitch.Active())
 		}
 	}
+
+	// Check for network rule changes
+	if !slices.Equal(pw.rules, pw.prevRules) {
+		if pw.onNetworkRulesChanged != nil {
+			pw.onNetworkRulesChanged(slices.Clone(pw.rules))
+		}
+	}
 }
 
 // Present shows the preferences window.
@@ -112,6 +245,38 @@ func (pw *PreferencesWindow) SetAutoConnect(enabled bool) {
 	pw.prevAutoConnect = enabled
 }
 
+// SetNetworkRules sets the network rules and the profiles they may connect.
+func (pw *PreferencesWindow) SetNetworkRules(rules []netrules.Rule, profiles []*profile.Profile) {
+	pw.rules = slices.Clone(rules)
+	pw.prevRules = slices.Clone(rules)
+	pw.profiles = profiles
+	pw.rebuildRules()
+}
+
+// SetCurrentNetwork shows the network the machine is on, whose details new rules are filled with.
+func (pw *PreferencesWindow) SetCurrentNetwork(n netrules.Network) {
+	pw.network = n
+	if !n.Uplink.Available() {
+		pw.networkRow.SetTitle("No Network")
+		pw.networkRow.SetSubtitle("")
+		return
+	}
+
+	title := n.Uplink.Interface
+	if n.SSID != "" {
+		title = fmt.Sprintf("%s (%s)", n.SSID, n.Uplink.Interface)
+	}
+	var details []string
+	if n.GatewayMAC != "" {
+		details = append(details, "Gateway "+n.GatewayMAC)
+	}
+	if len(n.SearchDomains) > 0 {
+		details = append(details, "Domains "+strings.Join(n.SearchDomains, ", "))
+	}
+	pw.networkRow.SetTitle(title)
+	pw.networkRow.SetSubtitle(strings.Join(details, " · "))
+}
+
 // OnNotificationsChanged registers a callback for notification setting changes.
 func (pw *PreferencesWindow) OnNotificationsChanged(callback func(enabled bool)) {
 	pw.onNotificationsChanged = callback
@@ -121,3 +286,8 @@ func (pw *PreferencesWindow) OnNotificationsChanged(callback func(enabled bool))
 func (pw *PreferencesWindow) OnAutoConnectChanged(callback func(enabled bool)) {
 	pw.onAutoConnectChanged = callback
 }
+
+// OnNetworkRulesChanged registers a callback for network rule changes.
+func (pw *PreferencesWindow) OnNetworkRulesChanged(callback func(rules []netrules.Rule)) {
+	pw.onNetworkRulesChanged = callback
+}
@@ -1,10 +1,16 @@
 package ui
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 
 	"github.com/shini4i/openfortivpn-gui/internal/profile"
+	"github.com/shini4i/openfortivpn-gui/internal/vpn"
 )
 
 // ProfileEditor provides a form for editing VPN profile settings.
@@ -12,24 +18,86 @@ type ProfileEditor struct {
 	widget *gtk.Box
 
 	// Form fields
-	nameRow         *adw.EntryRow
-	descriptionRow  *adw.EntryRow
-	hostRow         *adw.EntryRow
-	portRow         *adw.SpinRow
-	realmRow        *adw.EntryRow
-	usernameRow     *adw.EntryRow
-	authMethodRow   *adw.ComboRow
-	clientCertRow   *adw.EntryRow
-	clientKeyRow    *adw.EntryRow
-	trustedCertRow  *adw.EntryRow
-	setDNSRow       *adw.SwitchRow
-	setRoutesRow    *adw.SwitchRow
+	nameRow        *adw.EntryRow
+	descriptionRow *adw.EntryRow
+	groupRow       *adw.EntryRow
+	tagsRow        *adw.EntryRow
+	hostRow        *adw.EntryRow
+	portRow        *adw.SpinRow
+	gatewaysRow    *adw.EntryRow
+	gatewayModeRow *adw.ComboRow
+	realmRow       *adw.EntryRow
+	usernameRow    *adw.EntryRow
+	authMethodRow  *adw.ComboRow
+	clientCertRow  *adw.EntryRow
+	clientKeyRow   *adw.EntryRow
+	trustedCertRow *adw.EntryRow
+	setDNSRow      *adw.SwitchRow
+	setRoutesRow   *adw.SwitchRow
+	noFTMPushRow   *adw.SwitchRow
+	lockRow        *adw.SwitchRow
+
+	// Reconnect policy
+	reconnectModeRow     *adw.ComboRow
+	reconnectAttemptsRow *adw.SpinRow
+	reconnectDelayRow    *adw.SpinRow
+	reconnectBackoffRow  *adw.SwitchRow
+	reconnectMaxDelayRow *adw.SpinRow
+	reconnectJitterRow   *adw.SpinRow
+	reconnectResetRow    *adw.SpinRow
+
+	// Liveness probe
+	probeTypeRow     *adw.ComboRow
+	probeTargetRow   *adw.EntryRow
+	probeIntervalRow *adw.SpinRow
+	probeTimeoutRow  *adw.SpinRow
+	probeFailuresRow *adw.SpinRow
+
+	// Schedule
+	scheduleRow      *adw.ExpanderRow
+	scheduleDays     []*gtk.ToggleButton // Monday to Sunday
+	scheduleStartRow *adw.EntryRow
+	scheduleEndRow   *adw.EntryRow
+
+	// Idle disconnect
+	idleRow          *adw.SwitchRow
+	idleThresholdRow *adw.SpinRow
+	idleMinutesRow   *adw.SpinRow
+	idleWarningRow   *adw.SpinRow
+
+	// Kill switch
+	killSwitchRow  *adw.SwitchRow
+	allowedLANsRow *adw.EntryRow
+
+	// Hook commands
+	preConnectRow     *adw.EntryRow
+	postConnectRow    *adw.EntryRow
+	preDisconnectRow  *adw.EntryRow
+	postDisconnectRow *adw.EntryRow
+	hookTimeoutRow    *adw.SpinRow
+
+	// Template inheritance
+	templateRow   *adw.SwitchRow
+	parentRow     *adw.ComboRow
+	templates     []*profile.Profile // All templates, sorted by name
+	parentChoices []*profile.Profile // Templates offered by parentRow, after the "None" entry
+	indicators    []*inheritIndicator
+	overridden    map[string]bool // Inheritable fields the profile sets itself
+
+	// Notice shown for read-only profiles provided by an administrator
+	managedRow *adw.ActionRow
+
+	// Warning shown when the installed openfortivpn is too old for the selected auth method
+	versionWarningRow *adw.ActionRow
+	openfortivpnVer   *vpn.Version
 
 	// Certificate rows group (to show/hide)
 	certGroup *adw.PreferencesGroup
 
-	// Save button
+	// Save and connection test buttons
 	saveButton *gtk.Button
+	testButton *gtk.Button
+	testing    bool // True while a connection test runs
 
 	// Current profile
 	currentProfile *profile.Profile
@@ -39,12 +107,32 @@ type ProfileEditor struct {
 	populating bool // True when populating fields to prevent false dirty state
 
 	// Callbacks
-	onSave func(p *profile.Profile)
+	onSave func(p *profile.Profile) error
+	onTest func(p *profile.Profile)
+}
+
+// inheritIndicator marks an editor row whose value can be inherited from a template.
+// The label is shown while the value is inherited; the revert button while it is overridden.
+// The lock icon is shown while an administrator locked the value.
+type inheritIndicator struct {
+	key     string
+	row     inheritableRow
+	related []inheritableRow         // Further rows editing the same setting
+	load    func(p *profile.Profile) // Populates the rows from a profile
+	label   *gtk.Label
+	revert  *gtk.Button
+	lock    *gtk.Image
+}
+
+// inheritableRow is implemented by the libadwaita rows holding inheritable settings.
+type inheritableRow interface {
+	AddSuffix(widget gtk.Widgetter)
+	SetSensitive(sensitive bool)
 }
 
 // NewProfileEditor creates a new profile editor widget.
 func NewProfileEditor() *ProfileEditor {
-	pe := &ProfileEditor{}
+	pe := &ProfileEditor{overridden: make(map[string]bool)}
 	pe.setupWidget()
 	return pe
 }
@@ -59,7 +147,7 @@ func (pe *ProfileEditor) setupWidget() {
 	// Profile info group
 	profileGroup := adw.NewPreferencesGroup()
 	profileGroup.SetTitle("Profile")
-	profileGroup.SetDescription("Profile name and description")
+	profileGroup.SetDescription("Profile name, description, and organization")
 
 	pe.nameRow = adw.NewEntryRow()
 	pe.nameRow.SetTitle("Name")
@@ -71,8 +159,43 @@ func (pe *ProfileEditor) setupWidget() {
 	pe.descriptionRow.ConnectChanged(pe.markDirty)
 	profileGroup.Add(pe.descriptionRow)
 
+	pe.groupRow = adw.NewEntryRow()
+	pe.groupRow.SetTitle("Group")
+	pe.groupRow.ConnectChanged(pe.markDirty)
+	profileGroup.Add(pe.groupRow)
+
+	pe.tagsRow = adw.NewEntryRow()
+	pe.tagsRow.SetTitle("Tags (comma-separated)")
+	pe.tagsRow.ConnectChanged(pe.markDirty)
+	profileGroup.Add(pe.tagsRow)
+
+	pe.managedRow = adw.NewActionRow()
+	pe.managedRow.SetTitle("Managed by Your Administrator")
+	pe.managedRow.AddPrefix(gtk.NewImageFromIconName("changes-prevent-symbolic"))
+	pe.managedRow.SetVisible(false)
+	profileGroup.Add(pe.managedRow)
+
 	prefsPage.Add(profileGroup)
 
+	// Template settings group
+	templateGroup := adw.NewPreferencesGroup()
+	templateGroup.SetTitle("Template")
+	templateGroup.SetDescription("Share connection settings between profiles")
+
+	pe.templateRow = adw.NewSwitchRow()
+	pe.templateRow.SetTitle("Use as Template")
+	pe.templateRow.SetSubtitle("Other profiles can inherit settings from this one")
+	pe.templateRow.NotifyProperty("active", pe.onTemplateToggled)
+	templateGroup.Add(pe.templateRow)
+
+	pe.parentRow = adw.NewComboRow()
+	pe.parentRow.SetTitle("Inherit From")
+	pe.parentRow.SetModel(gtk.NewStringList([]string{"None"}))
+	pe.parentRow.NotifyProperty("selected", pe.onParentSelected)
+	templateGroup.Add(pe.parentRow)
+
+	prefsPage.Add(templateGroup)
+
 	// Connection settings group
 	connectionGroup := adw.NewPreferencesGroup()
 	connectionGroup.SetTitle("Connection")
@@ -81,18 +204,42 @@ func (pe *ProfileEditor) setupWidget() {
 	pe.hostRow = adw.NewEntryRow()
 	pe.hostRow.SetTitle("Server Host")
 	pe.hostRow.SetInputPurpose(gtk.InputPurposeURL)
-	pe.hostRow.ConnectChanged(pe.markDirty)
+	pe.hostRow.ConnectChanged(func() { pe.onInheritableChanged("host") })
+	pe.addInheritIndicator(pe.hostRow, "host", func(p *profile.Profile) { pe.hostRow.SetText(p.Host) })
 	connectionGroup.Add(pe.hostRow)
 
 	pe.portRow = adw.NewSpinRowWithRange(1, 65535, 1)
 	pe.portRow.SetTitle("Port")
 	pe.portRow.SetValue(443)
-	pe.portRow.ConnectChanged(pe.markDirty)
+	pe.portRow.ConnectChanged(func() { pe.onInheritableChanged("port") })
+	pe.addInheritIndicator(pe.portRow, "port", func(p *profile.Profile) { pe.portRow.SetValue(float64(p.Port)) })
 	connectionGroup.Add(pe.portRow)
 
+	pe.gatewaysRow = adw.NewEntryRow()
+	pe.gatewaysRow.SetTitle("Backup Gateways (comma-separated host:port)")
+	pe.gatewaysRow.SetInputPurpose(gtk.InputPurposeURL)
+	pe.gatewaysRow.ConnectChanged(func() {
+		pe.updateGatewayModeVisibility()
+		pe.onInheritableChanged("gateways")
+	})
+	pe.addInheritIndicator(pe.gatewaysRow, "gateways", func(p *profile.Profile) {
+		pe.gatewaysRow.SetText(profile.FormatGateways(p.Gateways))
+	})
+	connectionGroup.Add(pe.gatewaysRow)
+
+	// Order matches profile.ValidGatewaySelections()
+	pe.gatewayModeRow = adw.NewComboRow()
+	pe.gatewayModeRow.SetTitle("Gateway Selection")
+	pe.gatewayModeRow.SetSubtitle("Which gateway to try first when connecting")
+	pe.gatewayModeRow.SetModel(gtk.NewStringList([]string{"In Order", "Fastest Handshake"}))
+	pe.gatewayModeRow.NotifyProperty("selected", func() { pe.onInheritableChanged("gateway_selection") })
+	pe.addInheritIndicator(pe.gatewayModeRow, "gateway_selection", pe.setGatewaySelection)
+	connectionGroup.Add(pe.gatewayModeRow)
+
 	pe.realmRow = adw.NewEntryRow()
 	pe.realmRow.SetTitle("Realm")
-	pe.realmRow.ConnectChanged(pe.markDirty)
+	pe.realmRow.ConnectChanged(func() { pe.onInheritableChanged("realm") })
+	pe.addInheritIndicator(pe.realmRow, "realm", func(p *profile.Profile) { pe.realmRow.SetText(p.Realm) })
 	connectionGroup.Add(pe.realmRow)
 
 	prefsPage.Add(connectionGroup)
@@ -109,13 +256,22 @@ func (pe *ProfileEditor) setupWidget() {
 	pe.authMethodRow.SetModel(authMethods)
 	pe.authMethodRow.NotifyProperty("selected", func() {
 		pe.updateAuthMethodVisibility()
-		pe.markDirty()
+		pe.onInheritableChanged("auth_method")
 	})
+	pe.addInheritIndicator(pe.authMethodRow, "auth_method", pe.setAuthMethod)
 	authGroup.Add(pe.authMethodRow)
 
+	pe.versionWarningRow = adw.NewActionRow()
+	pe.versionWarningRow.SetTitle("openfortivpn Too Old")
+	pe.versionWarningRow.AddCSSClass("warning")
+	pe.versionWarningRow.AddPrefix(gtk.NewImageFromIconName("dialog-warning-symbolic"))
+	pe.versionWarningRow.SetVisible(false)
+	authGroup.Add(pe.versionWarningRow)
+
 	pe.usernameRow = adw.NewEntryRow()
 	pe.usernameRow.SetTitle("Username")
-	pe.usernameRow.ConnectChanged(pe.markDirty)
+	pe.usernameRow.ConnectChanged(func() { pe.onInheritableChanged("username") })
+	pe.addInheritIndicator(pe.usernameRow, "username", func(p *profile.Profile) { pe.usernameRow.SetText(p.Username) })
 	authGroup.Add(pe.usernameRow)
 
 	prefsPage.Add(authGroup)
@@ -128,13 +284,15 @@ func (pe *ProfileEditor) setupWidget() {
 	pe.clientCertRow = adw.NewEntryRow()
 	pe.clientCertRow.SetTitle("Client Certificate")
 	pe.clientCertRow.SetInputPurpose(gtk.InputPurposeURL)
-	pe.clientCertRow.ConnectChanged(pe.markDirty)
+	pe.clientCertRow.ConnectChanged(func() { pe.onInheritableChanged("client_cert_path") })
+	pe.addInheritIndicator(pe.clientCertRow, "client_cert_path", func(p *profile.Profile) { pe.clientCertRow.SetText(p.ClientCertPath) })
 	pe.certGroup.Add(pe.clientCertRow)
 
 	pe.clientKeyRow = adw.NewEntryRow()
 	pe.clientKeyRow.SetTitle("Client Key")
 	pe.clientKeyRow.SetInputPurpose(gtk.InputPurposeURL)
-	pe.clientKeyRow.ConnectChanged(pe.markDirty)
+	pe.clientKeyRow.ConnectChanged(func() { pe.onInheritableChanged("client_key_path") })
+	pe.addInheritIndicator(pe.clientKeyRow, "client_key_path", func(p *profile.Profile) { pe.clientKeyRow.SetText(p.ClientKeyPath) })
 	pe.certGroup.Add(pe.clientKeyRow)
 
 	prefsPage.Add(pe.certGroup)
@@ -147,25 +305,241 @@ func (pe *ProfileEditor) setupWidget() {
 	pe.trustedCertRow = adw.NewEntryRow()
 	pe.trustedCertRow.SetTitle("Trusted Certificate")
 	pe.trustedCertRow.SetInputPurpose(gtk.InputPurposeURL)
-	pe.trustedCertRow.ConnectChanged(pe.markDirty)
+	pe.trustedCertRow.ConnectChanged(func() { pe.onInheritableChanged("trusted_cert") })
+	pe.addInheritIndicator(pe.trustedCertRow, "trusted_cert", func(p *profile.Profile) { pe.trustedCertRow.SetText(p.TrustedCert) })
 	advancedGroup.Add(pe.trustedCertRow)
 
 	pe.setDNSRow = adw.NewSwitchRow()
 	pe.setDNSRow.SetTitle("Set DNS")
 	pe.setDNSRow.SetSubtitle("Configure system DNS when connected")
 	pe.setDNSRow.SetActive(true)
-	pe.setDNSRow.NotifyProperty("active", pe.markDirty)
+	pe.setDNSRow.NotifyProperty("active", func() { pe.onInheritableChanged("set_dns") })
+	pe.addInheritIndicator(pe.setDNSRow, "set_dns", func(p *profile.Profile) { pe.setDNSRow.SetActive(p.SetDNS) })
 	advancedGroup.Add(pe.setDNSRow)
 
 	pe.setRoutesRow = adw.NewSwitchRow()
 	pe.setRoutesRow.SetTitle("Set Routes")
 	pe.setRoutesRow.SetSubtitle("Configure routing table when connected")
 	pe.setRoutesRow.SetActive(true)
-	pe.setRoutesRow.NotifyProperty("active", pe.markDirty)
+	pe.setRoutesRow.NotifyProperty("active", func() { pe.onInheritableChanged("set_routes") })
+	pe.addInheritIndicator(pe.setRoutesRow, "set_routes", func(p *profile.Profile) { pe.setRoutesRow.SetActive(p.SetRoutes) })
 	advancedGroup.Add(pe.setRoutesRow)
 
+	pe.noFTMPushRow = adw.NewSwitchRow()
+	pe.noFTMPushRow.SetTitle("Disable FortiToken Push")
+	pe.noFTMPushRow.SetSubtitle("Enter the token code instead of approving a push notification")
+	pe.noFTMPushRow.NotifyProperty("active", func() { pe.onInheritableChanged("no_ftm_push") })
+	pe.addInheritIndicator(pe.noFTMPushRow, "no_ftm_push", func(p *profile.Profile) { pe.noFTMPushRow.SetActive(p.NoFTMPush) })
+	advancedGroup.Add(pe.noFTMPushRow)
+
+	pe.lockRow = adw.NewSwitchRow()
+	pe.lockRow.SetTitle("Disconnect When Screen Locks")
+	pe.lockRow.SetSubtitle("Close the tunnel while the session is locked")
+	pe.lockRow.NotifyProperty("active", func() { pe.onInheritableChanged("disconnect_on_lock") })
+	pe.addInheritIndicator(pe.lockRow, "disconnect_on_lock", func(p *profile.Profile) { pe.lockRow.SetActive(p.DisconnectOnLock) })
+	advancedGroup.Add(pe.lockRow)
+
 	prefsPage.Add(advancedGroup)
 
+	// Reconnect group
+	reconnectGroup := adw.NewPreferencesGroup()
+	reconnectGroup.SetTitle("Reconnect")
+	reconnectGroup.SetDescription("What to do when the connection drops")
+
+	pe.reconnectModeRow = adw.NewComboRow()
+	pe.reconnectModeRow.SetTitle("Reconnect")
+	pe.reconnectModeRow.SetModel(gtk.NewStringList([]string{"Application Default", "Never", "Limited Attempts", "Forever"}))
+	pe.reconnectModeRow.NotifyProperty("selected", func() {
+		pe.updateReconnectVisibility()
+		pe.onInheritableChanged("reconnect_policy")
+	})
+	reconnectGroup.Add(pe.reconnectModeRow)
+
+	newReconnectSpinRow := func(title, subtitle string, lower, upper, step float64) *adw.SpinRow {
+		row := adw.NewSpinRowWithRange(lower, upper, step)
+		row.SetTitle(title)
+		row.SetSubtitle(subtitle)
+		row.ConnectChanged(func() { pe.onInheritableChanged("reconnect_policy") })
+		reconnectGroup.Add(row)
+		return row
+	}
+	pe.reconnectAttemptsRow = newReconnectSpinRow("Attempts", "Attempts before giving up", 1, 100, 1)
+	pe.reconnectDelayRow = newReconnectSpinRow("Delay", "Seconds to wait before the first attempt", 0, 3600, 1)
+
+	pe.reconnectBackoffRow = adw.NewSwitchRow()
+	pe.reconnectBackoffRow.SetTitle("Exponential Backoff")
+	pe.reconnectBackoffRow.SetSubtitle("Double the delay after each failed attempt")
+	pe.reconnectBackoffRow.NotifyProperty("active", func() {
+		pe.updateReconnectVisibility()
+		pe.onInheritableChanged("reconnect_policy")
+	})
+	reconnectGroup.Add(pe.reconnectBackoffRow)
+
+	pe.reconnectMaxDelayRow = newReconnectSpinRow("Maximum Delay", "Seconds the delay grows to at most", 1, 3600, 10)
+	pe.reconnectJitterRow = newReconnectSpinRow("Jitter", "Vary each delay randomly by up to this percentage", 0, 100, 5)
+	pe.reconnectResetRow = newReconnectSpinRow("Reset After", "Seconds a connection must stay up before attempts start over", 0, 86400, 30)
+
+	pe.addInheritIndicator(pe.reconnectModeRow, "reconnect_policy", func(p *profile.Profile) { pe.setReconnectPolicy(p.ReconnectPolicy) },
+		pe.reconnectAttemptsRow, pe.reconnectDelayRow, pe.reconnectBackoffRow, pe.reconnectMaxDelayRow,
+		pe.reconnectJitterRow, pe.reconnectResetRow)
+
+	prefsPage.Add(reconnectGroup)
+
+	// Liveness probe group
+	probeGroup := adw.NewPreferencesGroup()
+	probeGroup.SetTitle("Liveness Probe")
+	probeGroup.SetDescription("Reconnect when a host behind the VPN stops answering while the tunnel seems up")
+
+	pe.probeTypeRow = adw.NewComboRow()
+	pe.probeTypeRow.SetTitle("Probe")
+	pe.probeTypeRow.SetModel(gtk.NewStringList([]string{"Off", "TCP Connection", "Ping", "DNS Query"}))
+	pe.probeTypeRow.NotifyProperty("selected", func() {
+		pe.updateProbeVisibility()
+		pe.onInheritableChanged("liveness_probe")
+	})
+	probeGroup.Add(pe.probeTypeRow)
+
+	pe.probeTargetRow = adw.NewEntryRow()
+	pe.probeTargetRow.ConnectChanged(func() { pe.onInheritableChanged("liveness_probe") })
+	probeGroup.Add(pe.probeTargetRow)
+
+	newProbeSpinRow := func(title, subtitle string, lower, upper, step float64) *adw.SpinRow {
+		row := adw.NewSpinRowWithRange(lower, upper, step)
+		row.SetTitle(title)
+		row.SetSubtitle(subtitle)
+		row.ConnectChanged(func() { pe.onInheritableChanged("liveness_probe") })
+		probeGroup.Add(row)
+		return row
+	}
+	pe.probeIntervalRow = newProbeSpinRow("Interval", "Seconds between probes", 1, 3600, 5)
+	pe.probeTimeoutRow = newProbeSpinRow("Timeout", "Seconds to wait for an answer", 1, 3600, 1)
+	pe.probeFailuresRow = newProbeSpinRow("Failures", "Probes in a row that must fail before reconnecting", 1, 100, 1)
+
+	pe.addInheritIndicator(pe.probeTypeRow, "liveness_probe", func(p *profile.Profile) { pe.setLivenessProbe(p.LivenessProbe) },
+		pe.probeTargetRow, pe.probeIntervalRow, pe.probeTimeoutRow, pe.probeFailuresRow)
+
+	prefsPage.Add(probeGroup)
+
+	// Schedule group
+	scheduleGroup := adw.NewPreferencesGroup()
+	scheduleGroup.SetTitle("Schedule")
+	scheduleGroup.SetDescription("Connect when a window starts and disconnect when it ends. Connecting or disconnecting by hand lasts until the next window")
+
+	pe.scheduleRow = adw.NewExpanderRow()
+	pe.scheduleRow.SetTitle("Connect on a Schedule")
+	pe.scheduleRow.SetShowEnableSwitch(true)
+	pe.scheduleRow.NotifyProperty("enable-expansion", func() { pe.onInheritableChanged("schedule") })
+	pe.addInheritIndicator(pe.scheduleRow, "schedule", func(p *profile.Profile) { pe.setSchedule(p.Schedule) })
+	scheduleGroup.Add(pe.scheduleRow)
+
+	daysBox := gtk.NewBox(gtk.OrientationHorizontal, 0)
+	daysBox.AddCSSClass("linked")
+	daysBox.SetVAlign(gtk.AlignCenter)
+	for _, day := range scheduleWeekdays {
+		button := gtk.NewToggleButtonWithLabel(day.String()[:3])
+		button.ConnectToggled(func() { pe.onInheritableChanged("schedule") })
+		daysBox.Append(button)
+		pe.scheduleDays = append(pe.scheduleDays, button)
+	}
+	daysRow := adw.NewActionRow()
+	daysRow.SetTitle("Days")
+	daysRow.AddSuffix(daysBox)
+	pe.scheduleRow.AddRow(daysRow)
+
+	newScheduleTimeRow := func(title string) *adw.EntryRow {
+		row := adw.NewEntryRow()
+		row.SetTitle(title)
+		row.ConnectChanged(func() { pe.onInheritableChanged("schedule") })
+		pe.scheduleRow.AddRow(row)
+		return row
+	}
+	pe.scheduleStartRow = newScheduleTimeRow("Start (HH:MM)")
+	pe.scheduleEndRow = newScheduleTimeRow("End (HH:MM, before the start to end the next day)")
+
+	prefsPage.Add(scheduleGroup)
+
+	// Idle disconnect group
+	idleGroup := adw.NewPreferencesGroup()
+	idleGroup.SetTitle("Idle Disconnect")
+	idleGroup.SetDescription("Free the gateway's tunnel when the VPN is not used, after a warning that offers to stay connected")
+
+	pe.idleRow = adw.NewSwitchRow()
+	pe.idleRow.SetTitle("Disconnect When Idle")
+	pe.idleRow.NotifyProperty("active", func() {
+		pe.updateIdleVisibility()
+		pe.onInheritableChanged("idle_disconnect")
+	})
+	idleGroup.Add(pe.idleRow)
+
+	newIdleSpinRow := func(title, subtitle string, lower, upper, step float64) *adw.SpinRow {
+		row := adw.NewSpinRowWithRange(lower, upper, step)
+		row.SetTitle(title)
+		row.SetSubtitle(subtitle)
+		row.ConnectChanged(func() { pe.onInheritableChanged("idle_disconnect") })
+		idleGroup.Add(row)
+		return row
+	}
+	pe.idleThresholdRow = newIdleSpinRow("Threshold", "Bytes/s received and sent together below which the VPN counts as unused", 1, 1000000, 256)
+	pe.idleMinutesRow = newIdleSpinRow("Idle Time", "Minutes below the threshold before warning", 1, 1440, 5)
+	pe.idleWarningRow = newIdleSpinRow("Warning", "Seconds between the warning and disconnecting", 1, 3600, 10)
+
+	pe.addInheritIndicator(pe.idleRow, "idle_disconnect", func(p *profile.Profile) { pe.setIdleDisconnect(p.IdleDisconnect) },
+		pe.idleThresholdRow, pe.idleMinutesRow, pe.idleWarningRow)
+
+	prefsPage.Add(idleGroup)
+
+	// Kill switch group
+	killSwitchGroup := adw.NewPreferencesGroup()
+	killSwitchGroup.SetTitle("Kill Switch")
+	killSwitchGroup.SetDescription("Block all traffic outside the VPN while the profile is active, including while it reconnects. Requires the helper daemon.")
+
+	pe.killSwitchRow = adw.NewSwitchRow()
+	pe.killSwitchRow.SetTitle("Block Traffic Outside the VPN")
+	pe.killSwitchRow.SetSubtitle("Lifted when you disconnect")
+	pe.killSwitchRow.NotifyProperty("active", func() {
+		pe.updateKillSwitchVisibility()
+		pe.onInheritableChanged("kill_switch")
+	})
+	killSwitchGroup.Add(pe.killSwitchRow)
+
+	pe.allowedLANsRow = adw.NewEntryRow()
+	pe.allowedLANsRow.SetTitle("Allowed LANs (comma-separated CIDR, e.g. 192.168.1.0/24)")
+	pe.allowedLANsRow.ConnectChanged(func() { pe.onInheritableChanged("kill_switch") })
+	killSwitchGroup.Add(pe.allowedLANsRow)
+
+	pe.addInheritIndicator(pe.killSwitchRow, "kill_switch", func(p *profile.Profile) { pe.setKillSwitch(p.KillSwitch) },
+		pe.allowedLANsRow)
+
+	prefsPage.Add(killSwitchGroup)
+
+	// Hooks group
+	hooksGroup := adw.NewPreferencesGroup()
+	hooksGroup.SetTitle("Hooks")
+	hooksGroup.SetDescription("Shell commands run as your user, with the connection details in OPENFORTIVPN_GUI_* variables")
+
+	newHookRow := func(title string) *adw.EntryRow {
+		row := adw.NewEntryRow()
+		row.SetTitle(title)
+		row.ConnectChanged(func() { pe.onInheritableChanged("hooks") })
+		hooksGroup.Add(row)
+		return row
+	}
+	pe.preConnectRow = newHookRow("Before Connecting")
+	pe.postConnectRow = newHookRow("After Connecting")
+	pe.preDisconnectRow = newHookRow("Before Disconnecting")
+	pe.postDisconnectRow = newHookRow("After Disconnecting")
+
+	pe.hookTimeoutRow = adw.NewSpinRowWithRange(0, 600, 5)
+	pe.hookTimeoutRow.SetTitle("Timeout")
+	pe.hookTimeoutRow.SetSubtitle(fmt.Sprintf("Seconds each hook may run, 0 for the default of %d", int(profile.DefaultHookTimeout.Seconds())))
+	pe.hookTimeoutRow.ConnectChanged(func() { pe.onInheritableChanged("hooks") })
+	hooksGroup.Add(pe.hookTimeoutRow)
+
+	pe.addInheritIndicator(pe.preConnectRow, "hooks", func(p *profile.Profile) { pe.setHooks(p.Hooks) },
+		pe.postConnectRow, pe.preDisconnectRow, pe.postDisconnectRow, pe.hookTimeoutRow)
+
+	prefsPage.Add(hooksGroup)
+
 	// Add clamp for proper width
 	clamp := adw.NewClamp()
 	clamp.SetMaximumSize(600)
@@ -173,12 +547,19 @@ func (pe *ProfileEditor) setupWidget() {
 
 	pe.widget.Append(clamp)
 
-	// Save button at the bottom
-	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 0)
+	// Connection test and save buttons at the bottom
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 12)
 	buttonBox.SetHAlign(gtk.AlignCenter)
 	buttonBox.SetMarginTop(16)
 	buttonBox.SetMarginBottom(16)
 
+	pe.testButton = gtk.NewButtonWithLabel("Test Connection")
+	pe.testButton.SetTooltipText("Check that the gateway is reachable without connecting")
+	pe.testButton.AddCSSClass("pill")
+	pe.testButton.SetSensitive(false)
+	pe.testButton.ConnectClicked(pe.onTestClicked)
+	buttonBox.Append(pe.testButton)
+
 	pe.saveButton = gtk.NewButtonWithLabel("Save")
 	pe.saveButton.AddCSSClass("suggested-action")
 	pe.saveButton.AddCSSClass("pill")
@@ -190,6 +571,212 @@ func (pe *ProfileEditor) setupWidget() {
 
 	// Initial visibility state
 	pe.updateAuthMethodVisibility()
+	pe.updateGatewayModeVisibility()
+	pe.updateReconnectVisibility()
+	pe.updateProbeVisibility()
+	pe.updateIdleVisibility()
+	pe.updateKillSwitchVisibility()
+	pe.updateInheritIndicators()
+}
+
+// addInheritIndicator adds the "Inherited" label and revert button to a row
+// whose value can be inherited from a template. Related rows edit further parts
+// of the same setting and are locked along with the row.
+func (pe *ProfileEditor) addInheritIndicator(row inheritableRow, key string, load func(p *profile.Profile), related ...inheritableRow) {
+	ind := &inheritIndicator{key: key, row: row, related: related, load: load}
+
+	ind.lock = gtk.NewImageFromIconName("changes-prevent-symbolic")
+	ind.lock.AddCSSClass("dim-label")
+	ind.lock.SetVAlign(gtk.AlignCenter)
+	ind.lock.SetTooltipText("Locked by Your Administrator")
+	ind.lock.SetVisible(false)
+	row.AddSuffix(ind.lock)
+
+	ind.label = gtk.NewLabel("Inherited")
+	ind.label.AddCSSClass("dim-label")
+	ind.label.AddCSSClass("caption")
+	ind.label.SetVAlign(gtk.AlignCenter)
+	row.AddSuffix(ind.label)
+
+	ind.revert = gtk.NewButtonFromIconName("edit-undo-symbolic")
+	ind.revert.AddCSSClass("flat")
+	ind.revert.SetVAlign(gtk.AlignCenter)
+	ind.revert.SetTooltipText("Revert to Template Value")
+	ind.revert.ConnectClicked(func() { pe.revertToInherited(ind) })
+	row.AddSuffix(ind.revert)
+
+	pe.indicators = append(pe.indicators, ind)
+}
+
+// parent returns the template selected in the "Inherit From" row, or nil.
+func (pe *ProfileEditor) parent() *profile.Profile {
+	if pe.templateRow.Active() {
+		return nil
+	}
+	selected := int(pe.parentRow.Selected())
+	if selected < 1 || selected > len(pe.parentChoices) {
+		return nil
+	}
+	return pe.parentChoices[selected-1]
+}
+
+// onInheritableChanged is called when a row with an inheritable value is edited.
+// Editing a value of a profile based on a template overrides the inherited value.
+func (pe *ProfileEditor) onInheritableChanged(key string) {
+	if pe.populating {
+		return
+	}
+	if pe.parent() != nil {
+		pe.overridden[key] = true
+		pe.updateInheritIndicators()
+	}
+	pe.markDirty()
+}
+
+// revertToInherited replaces an overridden value with the template's value.
+func (pe *ProfileEditor) revertToInherited(ind *inheritIndicator) {
+	parent := pe.parent()
+	if parent == nil {
+		return
+	}
+
+	pe.populating = true
+	ind.load(parent)
+	pe.populating = false
+
+	delete(pe.overridden, ind.key)
+	pe.updateAuthMethodVisibility()
+	pe.updateGatewayModeVisibility()
+	pe.updateInheritIndicators()
+	pe.markDirty()
+}
+
+// onParentSelected fills in the inherited values when a different template is chosen.
+func (pe *ProfileEditor) onParentSelected() {
+	if pe.populating {
+		return
+	}
+	if parent := pe.parent(); parent != nil {
+		pe.populating = true
+		for _, ind := range pe.indicators {
+			// Settings locked by a system template are always inherited
+			if parent.IsLockedByAdmin(ind.key) {
+				delete(pe.overridden, ind.key)
+			}
+			if !pe.overridden[ind.key] {
+				ind.load(parent)
+			}
+		}
+		pe.populating = false
+		pe.updateAuthMethodVisibility()
+		pe.updateGatewayModeVisibility()
+	}
+	pe.updateInheritIndicators()
+	pe.markDirty()
+}
+
+// onTemplateToggled hides the "Inherit From" row for templates, which cannot inherit.
+// The values shown stay as they are, so a profile turned into a template keeps its settings.
+func (pe *ProfileEditor) onTemplateToggled() {
+	pe.parentRow.SetVisible(!pe.templateRow.Active())
+	pe.updateInheritIndicators()
+	pe.markDirty()
+}
+
+// updateInheritIndicators shows which values are inherited from the selected template.
+func (pe *ProfileEditor) updateInheritIndicators() {
+	hasParent := pe.parent() != nil
+	for _, ind := range pe.indicators {
+		inherited := hasParent && !pe.overridden[ind.key]
+		ind.label.SetVisible(inherited)
+		ind.revert.SetVisible(hasParent && !inherited)
+	}
+	pe.updateLocks()
+}
+
+// updateLocks makes the settings an administrator manages read-only.
+// System profiles only let the user change the username (and password), and
+// profiles based on a system template cannot override the settings it locks.
+func (pe *ProfileEditor) updateLocks() {
+	p := pe.currentProfile
+	if p == nil {
+		pe.managedRow.SetVisible(false)
+		for _, ind := range pe.indicators {
+			ind.lock.SetVisible(false)
+		}
+		return
+	}
+
+	parent := pe.parent()
+	for _, ind := range pe.indicators {
+		lockedByParent := parent != nil && parent.IsLockedByAdmin(ind.key)
+		for _, row := range append([]inheritableRow{ind.row}, ind.related...) {
+			row.SetSensitive(!p.IsLocked(ind.key) && !lockedByParent)
+		}
+		ind.lock.SetVisible(p.IsLockedByAdmin(ind.key) || lockedByParent)
+	}
+
+	for _, row := range []interface{ SetSensitive(bool) }{
+		pe.nameRow, pe.descriptionRow, pe.groupRow, pe.tagsRow, pe.templateRow, pe.parentRow,
+	} {
+		row.SetSensitive(!p.System)
+	}
+
+	pe.managedRow.SetVisible(p.System)
+	if p.IsLocked("username") {
+		pe.managedRow.SetSubtitle("Only the password can be changed")
+	} else {
+		pe.managedRow.SetSubtitle("Only the username and password can be changed")
+	}
+}
+
+// SetTemplates sets the templates offered in the "Inherit From" row.
+func (pe *ProfileEditor) SetTemplates(templates []*profile.Profile) {
+	parentID := ""
+	if parent := pe.parent(); parent != nil {
+		parentID = parent.ID
+	}
+	pe.templates = profile.SortProfiles(slices.Clone(templates), profile.SortByName)
+	pe.refreshParentChoices(parentID)
+}
+
+// refreshParentChoices rebuilds the "Inherit From" choices and selects the given template.
+// The edited profile is never offered as its own template.
+func (pe *ProfileEditor) refreshParentChoices(parentID string) {
+	names := []string{"None"}
+	selected := 0
+	pe.parentChoices = pe.parentChoices[:0]
+	for _, t := range pe.templates {
+		if pe.currentProfile != nil && t.ID == pe.currentProfile.ID {
+			continue
+		}
+		pe.parentChoices = append(pe.parentChoices, t)
+		names = append(names, t.Name)
+		if t.ID == parentID {
+			selected = len(names) - 1
+		}
+	}
+
+	wasPopulating := pe.populating
+	pe.populating = true
+	pe.parentRow.SetModel(gtk.NewStringList(names))
+	pe.parentRow.SetSelected(uint(selected))
+	pe.populating = wasPopulating
+
+	pe.updateInheritIndicators()
+}
+
+// setAuthMethod selects the profile's auth method.
+// Index 0 = Password, 1 = Certificate, 2 = SAML/SSO
+func (pe *ProfileEditor) setAuthMethod(p *profile.Profile) {
+	switch p.AuthMethod {
+	case profile.AuthMethodCertificate:
+		pe.authMethodRow.SetSelected(1)
+	case profile.AuthMethodSAML:
+		pe.authMethodRow.SetSelected(2)
+	default:
+		pe.authMethodRow.SetSelected(0)
+	}
 }
 
 // updateAuthMethodVisibility shows/hides fields based on auth method.
@@ -203,6 +790,60 @@ func (pe *ProfileEditor) updateAuthMethodVisibility() {
 	pe.certGroup.SetVisible(isCertAuth)
 	// Username for password auth only (SAML doesn't need it upfront)
 	pe.usernameRow.SetVisible(!isCertAuth && !isSAMLAuth)
+
+	pe.updateVersionWarning()
+}
+
+// updateGatewayModeVisibility shows the gateway selection only when backup gateways are configured.
+func (pe *ProfileEditor) updateGatewayModeVisibility() {
+	pe.gatewayModeRow.SetVisible(strings.TrimSpace(pe.gatewaysRow.Text()) != "")
+}
+
+// setGatewaySelection selects the profile's gateway selection mode in the combo row.
+func (pe *ProfileEditor) setGatewaySelection(p *profile.Profile) {
+	if p.GatewaySelection == profile.GatewaySelectionFastest {
+		pe.gatewayModeRow.SetSelected(1)
+	} else {
+		pe.gatewayModeRow.SetSelected(0)
+	}
+}
+
+// parseGatewaysRow parses the backup gateways entered in the editor.
+// Entries that cannot be parsed are kept as typed so profile validation reports them.
+func parseGatewaysRow(text string, defaultPort int) []profile.Gateway {
+	var gateways []profile.Gateway
+	for _, entry := range strings.Split(text, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		gw, err := profile.ParseGateway(entry, defaultPort)
+		if err != nil {
+			gw = profile.Gateway{Host: entry, Port: defaultPort}
+		}
+		gateways = append(gateways, gw)
+	}
+	return gateways
+}
+
+// SetOpenfortivpnVersion sets the installed openfortivpn version used for compatibility warnings.
+// A nil version disables the warnings.
+func (pe *ProfileEditor) SetOpenfortivpnVersion(v *vpn.Version) {
+	pe.openfortivpnVer = v
+	pe.updateVersionWarning()
+}
+
+// updateVersionWarning shows a warning if the selected auth method needs a newer openfortivpn.
+func (pe *ProfileEditor) updateVersionWarning() {
+	isSAMLAuth := pe.authMethodRow.Selected() == 2
+	if pe.openfortivpnVer == nil || !isSAMLAuth || pe.openfortivpnVer.Supports(vpn.FeatureSAMLLogin) {
+		pe.versionWarningRow.SetVisible(false)
+		return
+	}
+
+	pe.versionWarningRow.SetSubtitle(fmt.Sprintf("SAML/SSO requires openfortivpn %s or newer; version %s is installed",
+		vpn.FeatureSAMLLogin.MinVersion(), pe.openfortivpnVer))
+	pe.versionWarningRow.SetVisible(true)
 }
 
 // markDirty is called when any field value changes.
@@ -218,14 +859,40 @@ func (pe *ProfileEditor) markDirty() {
 }
 
 // onSaveClicked is called when the Save button is clicked.
+// Changes stay marked as unsaved if the save callback fails.
 func (pe *ProfileEditor) onSaveClicked() {
 	if pe.onSave != nil && pe.currentProfile != nil {
-		pe.onSave(pe.GetProfile())
+		if err := pe.onSave(pe.GetProfile()); err != nil {
+			return
+		}
 		pe.isDirty = false
 		pe.saveButton.SetSensitive(false)
 	}
 }
 
+// IsDirty reports whether the editor has unsaved changes.
+func (pe *ProfileEditor) IsDirty() bool {
+	return pe.isDirty
+}
+
+// onTestClicked is called when the Test Connection button is clicked.
+func (pe *ProfileEditor) onTestClicked() {
+	if pe.onTest != nil && pe.currentProfile != nil {
+		pe.onTest(pe.GetProfile())
+	}
+}
+
+// SetTesting updates the Test Connection button while a connection test runs.
+func (pe *ProfileEditor) SetTesting(testing bool) {
+	pe.testing = testing
+	if testing {
+		pe.testButton.SetLabel("Testing...")
+	} else {
+		pe.testButton.SetLabel("Test Connection")
+	}
+	pe.testButton.SetSensitive(!testing && pe.currentProfile != nil)
+}
+
 // SetProfile loads a profile into the editor.
 func (pe *ProfileEditor) SetProfile(p *profile.Profile) {
 	pe.currentProfile = p
@@ -248,34 +915,45 @@ func (pe *ProfileEditor) SetProfile(p *profile.Profile) {
 
 	pe.setFieldsEnabled(true)
 
+	// Template inheritance
+	pe.templateRow.SetActive(p.IsTemplate)
+	pe.parentRow.SetVisible(!p.IsTemplate)
+	pe.refreshParentChoices(p.ParentID)
+	pe.overridden = make(map[string]bool, len(p.Overrides))
+	for _, key := range p.Overrides {
+		pe.overridden[key] = true
+	}
+
+	// Inherited values are shown as they resolve from the template
+	values := p
+	if parent := pe.parent(); parent != nil {
+		// Settings locked by a system template are always inherited
+		for key := range pe.overridden {
+			if parent.IsLockedByAdmin(key) {
+				delete(pe.overridden, key)
+			}
+		}
+		values = &profile.Profile{}
+		*values = *p
+		for _, key := range profile.InheritableFields() {
+			if !pe.overridden[key] {
+				values.InheritField(parent, key)
+			}
+		}
+	}
+
 	// Populate fields
 	pe.nameRow.SetText(p.Name)
 	pe.descriptionRow.SetText(p.Description)
-	pe.hostRow.SetText(p.Host)
-	pe.portRow.SetValue(float64(p.Port))
-	pe.realmRow.SetText(p.Realm)
-	pe.usernameRow.SetText(p.Username)
-
-	// Auth method: 0 = Password, 1 = Certificate, 2 = SAML
-	switch p.AuthMethod {
-	case profile.AuthMethodCertificate:
-		pe.authMethodRow.SetSelected(1)
-	case profile.AuthMethodSAML:
-		pe.authMethodRow.SetSelected(2)
-	default:
-		pe.authMethodRow.SetSelected(0)
+	pe.groupRow.SetText(p.Group)
+	pe.tagsRow.SetText(strings.Join(p.Tags, ", "))
+	for _, ind := range pe.indicators {
+		ind.load(values)
 	}
 
-	// Certificate fields
-	pe.clientCertRow.SetText(p.ClientCertPath)
-	pe.clientKeyRow.SetText(p.ClientKeyPath)
-	pe.trustedCertRow.SetText(p.TrustedCert)
-
-	// Switches
-	pe.setDNSRow.SetActive(p.SetDNS)
-	pe.setRoutesRow.SetActive(p.SetRoutes)
-
 	pe.updateAuthMethodVisibility()
+	pe.updateGatewayModeVisibility()
+	pe.updateInheritIndicators()
 
 }
 
@@ -292,6 +970,21 @@ func (pe *ProfileEditor) GetProfile() *profile.Profile {
 		Description: pe.descriptionRow.Text(),
 		Host:        pe.hostRow.Text(),
 		Port:        int(pe.portRow.Value()),
+		Group:       strings.TrimSpace(pe.groupRow.Text()),
+		Tags:        profile.ParseTags(pe.tagsRow.Text()),
+		IsTemplate:  pe.templateRow.Active(),
+		// Favorites are toggled from the profile list, not the editor
+		Favorite: pe.currentProfile.Favorite,
+		// Saving fails instead of overwriting changes made elsewhere since loading
+		Revision: pe.currentProfile.Revision,
+		// Settings without an editor row are carried along
+		HalfInternetRoutes: pe.currentProfile.HalfInternetRoutes,
+		AutoReconnect:      pe.currentProfile.AutoReconnect,
+	}
+
+	p.Gateways = parseGatewaysRow(pe.gatewaysRow.Text(), p.Port)
+	if pe.gatewayModeRow.Selected() == 1 {
+		p.GatewaySelection = profile.GatewaySelectionFastest
 	}
 
 	p.Realm = pe.realmRow.Text()
@@ -315,16 +1008,318 @@ func (pe *ProfileEditor) GetProfile() *profile.Profile {
 	// Switches
 	p.SetDNS = pe.setDNSRow.Active()
 	p.SetRoutes = pe.setRoutesRow.Active()
+	p.NoFTMPush = pe.noFTMPushRow.Active()
+	p.DisconnectOnLock = pe.lockRow.Active()
+
+	p.ReconnectPolicy = pe.getReconnectPolicy()
+	p.LivenessProbe = pe.getLivenessProbe()
+	p.Schedule = pe.getSchedule()
+	p.IdleDisconnect = pe.getIdleDisconnect()
+	p.KillSwitch = pe.getKillSwitch()
+	p.Hooks = pe.getHooks()
+
+	// Profiles based on a template only keep the values they override
+	if parent := pe.parent(); parent != nil {
+		p.ParentID = parent.ID
+		for _, key := range profile.InheritableFields() {
+			p.SetOverridden(key, pe.overridden[key])
+		}
+	}
 
 	return p
 }
 
+// Reconnect modes offered by reconnectModeRow, in order.
+const (
+	reconnectModeDefault uint = iota
+	reconnectModeNever
+	reconnectModeLimited
+	reconnectModeForever
+)
+
+// setReconnectPolicy populates the reconnect rows.
+func (pe *ProfileEditor) setReconnectPolicy(r *profile.ReconnectPolicy) {
+	mode := reconnectModeDefault
+	values := &profile.ReconnectPolicy{MaxAttempts: 3, DelaySeconds: 5}
+	if r != nil {
+		values = r
+		switch {
+		case r.MaxAttempts == profile.ReconnectForever:
+			mode = reconnectModeForever
+		case r.MaxAttempts == 0:
+			mode = reconnectModeNever
+		default:
+			mode = reconnectModeLimited
+		}
+	}
+
+	pe.reconnectModeRow.SetSelected(mode)
+	pe.reconnectAttemptsRow.SetValue(float64(max(values.MaxAttempts, 1)))
+	pe.reconnectDelayRow.SetValue(float64(values.DelaySeconds))
+	pe.reconnectBackoffRow.SetActive(values.Backoff)
+	pe.reconnectMaxDelayRow.SetValue(values.MaxDelay().Seconds())
+	pe.reconnectJitterRow.SetValue(float64(values.JitterPercent))
+	pe.reconnectResetRow.SetValue(float64(values.ResetAfterSeconds))
+	pe.updateReconnectVisibility()
+}
+
+// getReconnectPolicy returns the reconnect policy entered in the editor,
+// or nil if the profile uses the application's reconnect settings.
+func (pe *ProfileEditor) getReconnectPolicy() *profile.ReconnectPolicy {
+	mode := pe.reconnectModeRow.Selected()
+	switch mode {
+	case reconnectModeDefault:
+		return nil
+	case reconnectModeNever:
+		return &profile.ReconnectPolicy{MaxAttempts: 0}
+	}
+
+	r := &profile.ReconnectPolicy{
+		MaxAttempts:       int(pe.reconnectAttemptsRow.Value()),
+		DelaySeconds:      int(pe.reconnectDelayRow.Value()),
+		Backoff:           pe.reconnectBackoffRow.Active(),
+		JitterPercent:     int(pe.reconnectJitterRow.Value()),
+		ResetAfterSeconds: int(pe.reconnectResetRow.Value()),
+	}
+	if mode == reconnectModeForever {
+		r.MaxAttempts = profile.ReconnectForever
+	}
+	if r.Backoff {
+		r.MaxDelaySeconds = int(pe.reconnectMaxDelayRow.Value())
+	}
+	return r
+}
+
+// updateReconnectVisibility shows the reconnect settings that apply to the selected mode.
+func (pe *ProfileEditor) updateReconnectVisibility() {
+	mode := pe.reconnectModeRow.Selected()
+	retries := mode == reconnectModeLimited || mode == reconnectModeForever
+
+	pe.reconnectAttemptsRow.SetVisible(mode == reconnectModeLimited)
+	pe.reconnectDelayRow.SetVisible(retries)
+	pe.reconnectBackoffRow.SetVisible(retries)
+	pe.reconnectMaxDelayRow.SetVisible(retries && pe.reconnectBackoffRow.Active())
+	pe.reconnectJitterRow.SetVisible(retries)
+	pe.reconnectResetRow.SetVisible(retries)
+}
+
+// probeTypes lists the probe types offered by probeTypeRow after "Off", in order.
+var probeTypes = []profile.ProbeType{profile.ProbeTCP, profile.ProbeICMP, profile.ProbeDNS}
+
+// setLivenessProbe populates the liveness probe rows.
+func (pe *ProfileEditor) setLivenessProbe(l *profile.LivenessProbe) {
+	selected := 0
+	values := &profile.LivenessProbe{}
+	if l != nil {
+		values = l
+		if i := slices.Index(probeTypes, l.Type); i >= 0 {
+			selected = i + 1
+		}
+	}
+
+	pe.probeTypeRow.SetSelected(uint(selected))
+	pe.probeTargetRow.SetText(values.Target)
+	pe.probeIntervalRow.SetValue(values.Interval().Seconds())
+	pe.probeTimeoutRow.SetValue(values.Timeout().Seconds())
+	pe.probeFailuresRow.SetValue(float64(values.FailureThreshold()))
+	pe.updateProbeVisibility()
+}
+
+// getLivenessProbe returns the liveness probe entered in the editor, or nil if it is off.
+func (pe *ProfileEditor) getLivenessProbe() *profile.LivenessProbe {
+	selected := int(pe.probeTypeRow.Selected())
+	if selected == 0 || selected > len(probeTypes) {
+		return nil
+	}
+	return &profile.LivenessProbe{
+		Type:            probeTypes[selected-1],
+		Target:          strings.TrimSpace(pe.probeTargetRow.Text()),
+		IntervalSeconds: int(pe.probeIntervalRow.Value()),
+		TimeoutSeconds:  int(pe.probeTimeoutRow.Value()),
+		Failures:        int(pe.probeFailuresRow.Value()),
+	}
+}
+
+// scheduleWeekdays lists the weekdays of the schedule's day buttons, in order.
+var scheduleWeekdays = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+// setSchedule populates the schedule rows. Without a schedule the rows offer
+// weekdays from 08:30 to 18:00 for when it is switched on.
+func (pe *ProfileEditor) setSchedule(s *profile.Schedule) {
+	values := s
+	if values == nil {
+		values = &profile.Schedule{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:30", End: "18:00"}
+	}
+	pe.scheduleRow.SetEnableExpansion(s != nil)
+	for i, day := range scheduleWeekdays {
+		pe.scheduleDays[i].SetActive(slices.Contains(values.Days, profile.ScheduleDay(day)))
+	}
+	pe.scheduleStartRow.SetText(values.Start)
+	pe.scheduleEndRow.SetText(values.End)
+}
+
+// getSchedule returns the schedule entered in the editor, or nil if it is off.
+func (pe *ProfileEditor) getSchedule() *profile.Schedule {
+	if !pe.scheduleRow.EnableExpansion() {
+		return nil
+	}
+	s := &profile.Schedule{
+		Start: strings.TrimSpace(pe.scheduleStartRow.Text()),
+		End:   strings.TrimSpace(pe.scheduleEndRow.Text()),
+	}
+	for i, day := range scheduleWeekdays {
+		if pe.scheduleDays[i].Active() {
+			s.Days = append(s.Days, profile.ScheduleDay(day))
+		}
+	}
+	return s
+}
+
+// updateProbeVisibility shows the liveness probe settings while a probe is selected
+// and names the target the selected probe expects.
+func (pe *ProfileEditor) updateProbeVisibility() {
+	selected := int(pe.probeTypeRow.Selected())
+	enabled := selected > 0 && selected <= len(probeTypes)
+
+	pe.probeTargetRow.SetVisible(enabled)
+	pe.probeIntervalRow.SetVisible(enabled)
+	pe.probeTimeoutRow.SetVisible(enabled)
+	pe.probeFailuresRow.SetVisible(enabled)
+	if !enabled {
+		return
+	}
+
+	switch probeTypes[selected-1] {
+	case profile.ProbeTCP:
+		pe.probeTargetRow.SetTitle("Host and Port (e.g. git.corp.example:22)")
+	case profile.ProbeICMP:
+		pe.probeTargetRow.SetTitle("Host to Ping")
+	case profile.ProbeDNS:
+		pe.probeTargetRow.SetTitle("Name to Resolve via the VPN's DNS")
+	}
+}
+
+// setIdleDisconnect populates the idle disconnect rows. Without an idle rule the
+// rows offer 30 minutes below 1 KiB/s for when it is switched on.
+func (pe *ProfileEditor) setIdleDisconnect(d *profile.IdleDisconnect) {
+	values := d
+	if values == nil {
+		values = &profile.IdleDisconnect{ThresholdBytesPerSec: 1024, Minutes: 30}
+	}
+	pe.idleRow.SetActive(d != nil)
+	pe.idleThresholdRow.SetValue(float64(values.ThresholdBytesPerSec))
+	pe.idleMinutesRow.SetValue(float64(values.Minutes))
+	pe.idleWarningRow.SetValue(values.Warning().Seconds())
+	pe.updateIdleVisibility()
+}
+
+// getIdleDisconnect returns the idle rule entered in the editor, or nil if it is off.
+func (pe *ProfileEditor) getIdleDisconnect() *profile.IdleDisconnect {
+	if !pe.idleRow.Active() {
+		return nil
+	}
+	return &profile.IdleDisconnect{
+		ThresholdBytesPerSec: int(pe.idleThresholdRow.Value()),
+		Minutes:              int(pe.idleMinutesRow.Value()),
+		WarningSeconds:       int(pe.idleWarningRow.Value()),
+	}
+}
+
+// updateIdleVisibility shows the idle disconnect settings while it is switched on.
+func (pe *ProfileEditor) updateIdleVisibility() {
+	enabled := pe.idleRow.Active()
+	pe.idleThresholdRow.SetVisible(enabled)
+	pe.idleMinutesRow.SetVisible(enabled)
+	pe.idleWarningRow.SetVisible(enabled)
+}
+
+// setKillSwitch populates the kill switch rows.
+func (pe *ProfileEditor) setKillSwitch(k *profile.KillSwitch) {
+	pe.killSwitchRow.SetActive(k != nil)
+	if k != nil {
+		pe.allowedLANsRow.SetText(strings.Join(k.AllowedLANs, ", "))
+	} else {
+		pe.allowedLANsRow.SetText("")
+	}
+	pe.updateKillSwitchVisibility()
+}
+
+// getKillSwitch returns the kill switch entered in the editor, or nil if it is off.
+// Invalid LAN ranges are kept so validation reports them.
+func (pe *ProfileEditor) getKillSwitch() *profile.KillSwitch {
+	if !pe.killSwitchRow.Active() {
+		return nil
+	}
+	k := &profile.KillSwitch{}
+	for _, entry := range strings.Split(pe.allowedLANsRow.Text(), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			k.AllowedLANs = append(k.AllowedLANs, entry)
+		}
+	}
+	return k
+}
+
+// updateKillSwitchVisibility shows the allowed LANs while the kill switch is switched on.
+func (pe *ProfileEditor) updateKillSwitchVisibility() {
+	pe.allowedLANsRow.SetVisible(pe.killSwitchRow.Active())
+}
+
+// setHooks populates the hook rows.
+func (pe *ProfileEditor) setHooks(h *profile.Hooks) {
+	if h == nil {
+		h = &profile.Hooks{}
+	}
+	pe.preConnectRow.SetText(h.PreConnect)
+	pe.postConnectRow.SetText(h.PostConnect)
+	pe.preDisconnectRow.SetText(h.PreDisconnect)
+	pe.postDisconnectRow.SetText(h.PostDisconnect)
+	pe.hookTimeoutRow.SetValue(float64(h.TimeoutSeconds))
+}
+
+// getHooks returns the hooks entered in the editor, or nil if none are set.
+func (pe *ProfileEditor) getHooks() *profile.Hooks {
+	h := &profile.Hooks{
+		PreConnect:     strings.TrimSpace(pe.preConnectRow.Text()),
+		PostConnect:    strings.TrimSpace(pe.postConnectRow.Text()),
+		PreDisconnect:  strings.TrimSpace(pe.preDisconnectRow.Text()),
+		PostDisconnect: strings.TrimSpace(pe.postDisconnectRow.Text()),
+		TimeoutSeconds: int(pe.hookTimeoutRow.Value()),
+	}
+	if h.IsEmpty() && h.TimeoutSeconds == 0 {
+		return nil
+	}
+	return h
+}
+
+// SetFavorite updates the favorite state of the edited profile if it has the given ID.
+// Favorites are toggled from the profile list, so the editor only carries the value along.
+func (pe *ProfileEditor) SetFavorite(profileID string, favorite bool) {
+	if pe.currentProfile != nil && pe.currentProfile.ID == profileID {
+		pe.currentProfile.Favorite = favorite
+	}
+}
+
+// SetRevision updates the stored revision of the edited profile if it has the given ID.
+// It is called after the profile was saved outside the editor, so the next save
+// from the editor is not mistaken for overwriting someone else's changes.
+func (pe *ProfileEditor) SetRevision(profileID, revision string) {
+	if pe.currentProfile != nil && pe.currentProfile.ID == profileID {
+		pe.currentProfile.Revision = revision
+	}
+}
+
 // clearFields resets all fields to empty values.
 func (pe *ProfileEditor) clearFields() {
 	pe.nameRow.SetText("")
 	pe.descriptionRow.SetText("")
+	pe.groupRow.SetText("")
+	pe.tagsRow.SetText("")
 	pe.hostRow.SetText("")
 	pe.portRow.SetValue(443)
+	pe.gatewaysRow.SetText("")
+	pe.gatewayModeRow.SetSelected(0)
 	pe.realmRow.SetText("")
 	pe.usernameRow.SetText("")
 	pe.authMethodRow.SetSelected(0)
@@ -333,14 +1328,30 @@ func (pe *ProfileEditor) clearFields() {
 	pe.trustedCertRow.SetText("")
 	pe.setDNSRow.SetActive(true)
 	pe.setRoutesRow.SetActive(true)
+	pe.noFTMPushRow.SetActive(false)
+	pe.lockRow.SetActive(false)
+	pe.setReconnectPolicy(nil)
+	pe.setLivenessProbe(nil)
+	pe.setSchedule(nil)
+	pe.setIdleDisconnect(nil)
+	pe.setKillSwitch(nil)
+	pe.setHooks(nil)
+	pe.templateRow.SetActive(false)
+	pe.parentRow.SetSelected(0)
+	pe.overridden = make(map[string]bool)
+	pe.updateInheritIndicators()
 }
 
 // setFieldsEnabled enables or disables all form fields.
 func (pe *ProfileEditor) setFieldsEnabled(enabled bool) {
 	pe.nameRow.SetSensitive(enabled)
 	pe.descriptionRow.SetSensitive(enabled)
+	pe.groupRow.SetSensitive(enabled)
+	pe.tagsRow.SetSensitive(enabled)
 	pe.hostRow.SetSensitive(enabled)
 	pe.portRow.SetSensitive(enabled)
+	pe.gatewaysRow.SetSensitive(enabled)
+	pe.gatewayModeRow.SetSensitive(enabled)
 	pe.realmRow.SetSensitive(enabled)
 	pe.usernameRow.SetSensitive(enabled)
 	pe.authMethodRow.SetSensitive(enabled)
@@ -349,14 +1360,50 @@ func (pe *ProfileEditor) setFieldsEnabled(enabled bool) {
 	pe.trustedCertRow.SetSensitive(enabled)
 	pe.setDNSRow.SetSensitive(enabled)
 	pe.setRoutesRow.SetSensitive(enabled)
+	pe.noFTMPushRow.SetSensitive(enabled)
+	pe.lockRow.SetSensitive(enabled)
+	pe.reconnectModeRow.SetSensitive(enabled)
+	pe.reconnectAttemptsRow.SetSensitive(enabled)
+	pe.reconnectDelayRow.SetSensitive(enabled)
+	pe.reconnectBackoffRow.SetSensitive(enabled)
+	pe.reconnectMaxDelayRow.SetSensitive(enabled)
+	pe.reconnectJitterRow.SetSensitive(enabled)
+	pe.reconnectResetRow.SetSensitive(enabled)
+	pe.probeTypeRow.SetSensitive(enabled)
+	pe.probeTargetRow.SetSensitive(enabled)
+	pe.probeIntervalRow.SetSensitive(enabled)
+	pe.probeTimeoutRow.SetSensitive(enabled)
+	pe.probeFailuresRow.SetSensitive(enabled)
+	pe.scheduleRow.SetSensitive(enabled)
+	pe.idleRow.SetSensitive(enabled)
+	pe.idleThresholdRow.SetSensitive(enabled)
+	pe.idleMinutesRow.SetSensitive(enabled)
+	pe.idleWarningRow.SetSensitive(enabled)
+	pe.killSwitchRow.SetSensitive(enabled)
+	pe.allowedLANsRow.SetSensitive(enabled)
+	pe.preConnectRow.SetSensitive(enabled)
+	pe.postConnectRow.SetSensitive(enabled)
+	pe.preDisconnectRow.SetSensitive(enabled)
+	pe.postDisconnectRow.SetSensitive(enabled)
+	pe.hookTimeoutRow.SetSensitive(enabled)
+	pe.templateRow.SetSensitive(enabled)
+	pe.parentRow.SetSensitive(enabled)
 	pe.saveButton.SetSensitive(enabled && pe.isDirty)
+	pe.testButton.SetSensitive(enabled && !pe.testing)
 }
 
 // OnSave registers a callback for when the profile is saved.
-func (pe *ProfileEditor) OnSave(callback func(p *profile.Profile)) {
+// The callback returns an error if the profile was not saved; the changes then stay unsaved.
+func (pe *ProfileEditor) OnSave(callback func(p *profile.Profile) error) {
 	pe.onSave = callback
 }
 
+// OnTestConnection registers a callback for when a connection test is requested.
+// The profile contains the current editor values, which may not be saved yet.
+func (pe *ProfileEditor) OnTestConnection(callback func(p *profile.Profile)) {
+	pe.onTest = callback
+}
+
 // MarkNewProfile marks the current profile as new (unsaved) and enables the save button.
 // This should be called after SetProfile for newly created profiles.
 func (pe *ProfileEditor) MarkNewProfile() {
@@ -371,12 +1418,14 @@ func (pe *ProfileEditor) Widget() gtk.Widgetter {
 	return pe.widget
 }
 
-
 // ClearSelection clears text selection in all entry rows to prevent visual highlighting.
 func (pe *ProfileEditor) ClearSelection() {
 	pe.nameRow.SelectRegion(0, 0)
 	pe.descriptionRow.SelectRegion(0, 0)
+	pe.groupRow.SelectRegion(0, 0)
+	pe.tagsRow.SelectRegion(0, 0)
 	pe.hostRow.SelectRegion(0, 0)
+	pe.gatewaysRow.SelectRegion(0, 0)
 	pe.realmRow.SelectRegion(0, 0)
 	pe.usernameRow.SelectRegion(0, 0)
 	pe.clientCertRow.SelectRegion(0, 0)
@@ -1,8 +1,12 @@
 package ui
 
 import (
-	"github.com/diamondburned/gotk4/pkg/pango"
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
 
 	"github.com/shini4i/openfortivpn-gui/internal/profile"
 )
@@ -11,18 +15,58 @@ import (
 // Using SetOpacity instead of dim-label CSS class to ensure visibility in dark themes.
 const dimmedOpacity = 0.7
 
+// Group header row names. Profile rows are named after their UUID, so these never collide.
+const (
+	favoritesGroupKey = "favorites"
+	ungroupedGroupKey = "ungrouped"
+	groupKeyPrefix    = "group:"
+)
+
+// sortOrderChoices lists the sort orders in dropdown order.
+var sortOrderChoices = []struct {
+	order profile.SortOrder
+	label string
+}{
+	{profile.SortByName, "Name"},
+	{profile.SortByHost, "Host"},
+}
+
 // ProfileList displays a list of VPN profiles in the sidebar.
+// Profiles are organized into collapsible groups with favorites pinned at the top,
+// and can be filtered with a search entry.
 type ProfileList struct {
-	widget *gtk.Box
-	list   *gtk.ListBox
+	widget       *gtk.Box
+	searchEntry  *gtk.SearchEntry
+	sortDropDown *gtk.DropDown
+	list         *gtk.ListBox
+
+	// Placeholders for an empty list and for searches without results
+	emptyPlaceholder   gtk.Widgetter
+	noMatchPlaceholder gtk.Widgetter
 
 	// Profile data
 	profiles   []*profile.Profile
 	profileMap map[string]*profileRow
+	headers    []*groupHeader
+	// alwaysOn is the always-on profile the helper manages, listed read-only
+	// unless a stored profile has its ID; nil if none is configured
+	alwaysOn *profile.Profile
+
+	// View state
+	sortOrder profile.SortOrder
+	collapsed map[string]bool
+	query     string
+
+	// rebuilding suppresses selection callbacks while rows are recreated
+	rebuilding bool
+	// settingSortOrder suppresses the sort order callback for programmatic changes
+	settingSortOrder bool
 
 	// Callbacks
-	onSelected func(p *profile.Profile)
-	onDeleted  func(p *profile.Profile)
+	onSelected         func(p *profile.Profile)
+	onDeleted          func(p *profile.Profile)
+	onFavoriteToggled  func(p *profile.Profile)
+	onSortOrderChanged func(order profile.SortOrder)
 }
 
 // profileRow holds the GTK row and associated profile data.
@@ -33,10 +77,20 @@ type profileRow struct {
 	subtitleLabel *gtk.Label
 }
 
+// groupHeader is a collapsible section header and the rows it contains.
+type groupHeader struct {
+	key   string
+	row   *gtk.ListBoxRow
+	arrow *gtk.Image
+	rows  []*profileRow
+}
+
 // NewProfileList creates a new profile list widget.
 func NewProfileList() *ProfileList {
 	pl := &ProfileList{
 		profileMap: make(map[string]*profileRow),
+		sortOrder:  profile.SortByName,
+		collapsed:  make(map[string]bool),
 	}
 
 	pl.setupWidget()
@@ -48,34 +102,74 @@ func (pl *ProfileList) setupWidget() {
 	// Create container box
 	pl.widget = gtk.NewBox(gtk.OrientationVertical, 0)
 
+	// Search and sort controls
+	controls := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	controls.SetMarginTop(6)
+	controls.SetMarginBottom(6)
+	controls.SetMarginStart(6)
+	controls.SetMarginEnd(6)
+
+	pl.searchEntry = gtk.NewSearchEntry()
+	pl.searchEntry.SetPlaceholderText("Search profiles")
+	pl.searchEntry.SetHExpand(true)
+	pl.searchEntry.ConnectSearchChanged(func() {
+		pl.query = strings.TrimSpace(pl.searchEntry.Text())
+		pl.applyVisibility()
+	})
+	controls.Append(pl.searchEntry)
+
+	labels := make([]string, 0, len(sortOrderChoices))
+	for _, choice := range sortOrderChoices {
+		labels = append(labels, choice.label)
+	}
+	pl.sortDropDown = gtk.NewDropDownFromStrings(labels)
+	pl.sortDropDown.SetTooltipText("Sort Profiles By")
+	pl.sortDropDown.NotifyProperty("selected", pl.onSortSelected)
+	controls.Append(pl.sortDropDown)
+
+	pl.widget.Append(controls)
+
 	// Create list box
 	pl.list = gtk.NewListBox()
 	pl.list.SetSelectionMode(gtk.SelectionSingle)
 
 	// Handle selection changes
 	pl.list.ConnectRowSelected(func(row *gtk.ListBoxRow) {
-		if row == nil {
+		if row == nil || pl.rebuilding {
 			return
 		}
 
-		// Find the profile for this row by comparing row indices
-		rowIndex := row.Index()
-		if rowIndex >= 0 && rowIndex < len(pl.profiles) {
-			p := pl.profiles[rowIndex]
-			if pl.onSelected != nil {
-				pl.onSelected(p)
+		if pr, ok := pl.profileMap[row.Name()]; ok && pl.onSelected != nil {
+			pl.onSelected(pr.profile)
+		}
+	})
+
+	// Group headers are activatable but not selectable; activating one toggles it
+	pl.list.ConnectRowActivated(func(row *gtk.ListBoxRow) {
+		for _, h := range pl.headers {
+			if h.key == row.Name() {
+				pl.collapsed[h.key] = !pl.collapsed[h.key]
+				pl.applyVisibility()
+				return
 			}
 		}
 	})
 
-	// Add placeholder for empty state
-	pl.list.SetPlaceholder(pl.createEmptyPlaceholder())
+	// Add placeholders for empty states
+	pl.emptyPlaceholder = pl.createPlaceholder("No Profiles", "Click + to add a VPN profile")
+	pl.noMatchPlaceholder = pl.createPlaceholder("No Matching Profiles", "Try a different name, host, group, or tag")
+	pl.list.SetPlaceholder(pl.emptyPlaceholder)
+
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+	scrolled.SetVExpand(true)
+	scrolled.SetChild(pl.list)
 
-	pl.widget.Append(pl.list)
+	pl.widget.Append(scrolled)
 }
 
-// createEmptyPlaceholder creates a placeholder widget for when there are no profiles.
-func (pl *ProfileList) createEmptyPlaceholder() gtk.Widgetter {
+// createPlaceholder creates a placeholder widget shown when no profile rows are visible.
+func (pl *ProfileList) createPlaceholder(title, subtitle string) gtk.Widgetter {
 	box := gtk.NewBox(gtk.OrientationVertical, 12)
 	box.SetVAlign(gtk.AlignCenter)
 	box.SetHAlign(gtk.AlignCenter)
@@ -89,11 +183,13 @@ func (pl *ProfileList) createEmptyPlaceholder() gtk.Widgetter {
 	icon.SetOpacity(dimmedOpacity) // Subtle dimming without being invisible in dark themes
 	box.Append(icon)
 
-	label := gtk.NewLabel("No Profiles")
+	label := gtk.NewLabel(title)
 	label.AddCSSClass("title-2")
 	box.Append(label)
 
-	sublabel := gtk.NewLabel("Click + to add a VPN profile")
+	sublabel := gtk.NewLabel(subtitle)
+	sublabel.SetWrap(true)
+	sublabel.SetJustify(gtk.JustifyCenter)
 	sublabel.SetOpacity(dimmedOpacity) // Subtle dimming without being invisible in dark themes
 	box.Append(sublabel)
 
@@ -102,15 +198,100 @@ func (pl *ProfileList) createEmptyPlaceholder() gtk.Widgetter {
 
 // SetProfiles updates the profile list with new profiles.
 func (pl *ProfileList) SetProfiles(profiles []*profile.Profile) {
+	pl.profiles = profiles
+	pl.rebuild()
+}
+
+// SetAlwaysOn lists the always-on profile the helper manages as a read-only
+// system entry, or removes it if p is nil. It is not among Profiles.
+func (pl *ProfileList) SetAlwaysOn(p *profile.Profile) {
+	pl.alwaysOn = p
+	pl.rebuild()
+}
+
+// SetSortOrder changes how profiles are ordered within each group.
+// It does not invoke the OnSortOrderChanged callback.
+func (pl *ProfileList) SetSortOrder(order profile.SortOrder) {
+	for i, choice := range sortOrderChoices {
+		if choice.order == order {
+			pl.settingSortOrder = true
+			pl.sortDropDown.SetSelected(uint(i))
+			pl.settingSortOrder = false
+			break
+		}
+	}
+
+	if order != pl.sortOrder {
+		pl.sortOrder = order
+		pl.rebuild()
+	}
+}
+
+// onSortSelected applies the sort order chosen in the dropdown.
+func (pl *ProfileList) onSortSelected() {
+	if pl.settingSortOrder {
+		return
+	}
+
+	index := int(pl.sortDropDown.Selected())
+	if index < 0 || index >= len(sortOrderChoices) {
+		return
+	}
+	order := sortOrderChoices[index].order
+	if order == pl.sortOrder {
+		return
+	}
+
+	pl.sortOrder = order
+	pl.rebuild()
+
+	if pl.onSortOrderChanged != nil {
+		pl.onSortOrderChanged(order)
+	}
+}
+
+// rebuild recreates all rows from the current profiles, keeping the selection.
+func (pl *ProfileList) rebuild() {
+	selectedID := ""
+	if row := pl.list.SelectedRow(); row != nil {
+		selectedID = row.Name()
+	}
+
+	pl.rebuilding = true
+	defer func() { pl.rebuilding = false }()
+
 	// Clear existing rows
 	pl.clearRows()
-
-	pl.profiles = profiles
 	pl.profileMap = make(map[string]*profileRow)
+	pl.headers = nil
 
-	// Add rows for each profile
-	for _, p := range profiles {
-		pl.addProfileRow(p)
+	// Sort a copy so the caller's slice order is left untouched
+	sorted := append([]*profile.Profile(nil), pl.profiles...)
+	if pl.alwaysOn != nil && !slices.ContainsFunc(sorted, func(p *profile.Profile) bool { return p.ID == pl.alwaysOn.ID }) {
+		sorted = append(sorted, pl.alwaysOn)
+	}
+	groups := profile.GroupProfiles(sorted, pl.sortOrder)
+
+	// Headers are only useful when profiles are actually organized
+	showHeaders := len(groups) > 1 || (len(groups) == 1 && (groups[0].Favorites || groups[0].Name != ""))
+
+	for _, g := range groups {
+		var header *groupHeader
+		if showHeaders {
+			header = pl.addGroupHeader(g)
+		}
+		for _, p := range g.Profiles {
+			pr := pl.addProfileRow(p)
+			if header != nil {
+				header.rows = append(header.rows, pr)
+			}
+		}
+	}
+
+	pl.applyVisibility()
+
+	if pr, ok := pl.profileMap[selectedID]; ok {
+		pl.list.SelectRow(pr.row)
 	}
 }
 
@@ -125,10 +306,69 @@ func (pl *ProfileList) clearRows() {
 	}
 }
 
+// groupKey returns the header row name for a group.
+func groupKey(g profile.ProfileGroup) string {
+	switch {
+	case g.Favorites:
+		return favoritesGroupKey
+	case g.Name == "":
+		return ungroupedGroupKey
+	default:
+		return groupKeyPrefix + g.Name
+	}
+}
+
+// addGroupHeader adds a collapsible header row for a group.
+func (pl *ProfileList) addGroupHeader(g profile.ProfileGroup) *groupHeader {
+	row := gtk.NewListBoxRow()
+	row.SetSelectable(false)
+	row.SetActivatable(true)
+
+	hbox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	hbox.SetMarginTop(6)
+	hbox.SetMarginBottom(6)
+	hbox.SetMarginStart(12)
+	hbox.SetMarginEnd(12)
+
+	arrow := gtk.NewImageFromIconName("pan-down-symbolic")
+	hbox.Append(arrow)
+
+	title := g.Name
+	switch {
+	case g.Favorites:
+		title = "Favorites"
+		hbox.Append(gtk.NewImageFromIconName("starred-symbolic"))
+	case g.Name == "":
+		title = "Other"
+	}
+
+	label := gtk.NewLabel(title)
+	label.SetXAlign(0)
+	label.SetHExpand(true)
+	label.SetEllipsize(pango.EllipsizeEnd)
+	label.AddCSSClass("heading")
+	hbox.Append(label)
+
+	count := gtk.NewLabel(fmt.Sprintf("%d", len(g.Profiles)))
+	count.AddCSSClass("caption")
+	count.SetOpacity(dimmedOpacity)
+	hbox.Append(count)
+
+	row.SetChild(hbox)
+
+	header := &groupHeader{key: groupKey(g), row: row, arrow: arrow}
+	row.SetName(header.key)
+	pl.headers = append(pl.headers, header)
+
+	pl.list.Append(row)
+	return header
+}
+
 // addProfileRow adds a single profile row to the list.
-func (pl *ProfileList) addProfileRow(p *profile.Profile) {
+func (pl *ProfileList) addProfileRow(p *profile.Profile) *profileRow {
 	row := gtk.NewListBoxRow()
 	row.SetActivatable(true)
+	row.SetName(p.ID)
 
 	// Main horizontal container
 	hbox := gtk.NewBox(gtk.OrientationHorizontal, 12)
@@ -137,8 +377,12 @@ func (pl *ProfileList) addProfileRow(p *profile.Profile) {
 	hbox.SetMarginStart(12)
 	hbox.SetMarginEnd(12)
 
-	// VPN icon (prefix)
+	// VPN icon (prefix); templates get their own icon since they cannot be connected
 	icon := gtk.NewImageFromIconName("network-vpn-symbolic")
+	if p.IsTemplate {
+		icon.SetFromIconName("folder-templates-symbolic")
+		icon.SetTooltipText("Template")
+	}
 	icon.SetVAlign(gtk.AlignCenter)
 	hbox.Append(icon)
 
@@ -153,12 +397,7 @@ func (pl *ProfileList) addProfileRow(p *profile.Profile) {
 	titleLabel.AddCSSClass("heading")
 	textBox.Append(titleLabel)
 
-	// Show description if available, otherwise show host
-	subtitle := p.Host
-	if p.Description != "" {
-		subtitle = p.Description
-	}
-	subtitleLabel := gtk.NewLabel(subtitle)
+	subtitleLabel := gtk.NewLabel(profileSubtitle(p, pl.templateName(p)))
 	subtitleLabel.AddCSSClass("caption")
 	subtitleLabel.SetOpacity(dimmedOpacity) // Subtle dimming without being invisible
 	subtitleLabel.SetXAlign(0)
@@ -167,14 +406,44 @@ func (pl *ProfileList) addProfileRow(p *profile.Profile) {
 
 	hbox.Append(textBox)
 
+	// Capture profile for closures
+	captured := p
+
+	// Favorite button (suffix)
+	favoriteButton := gtk.NewButtonFromIconName("non-starred-symbolic")
+	favoriteButton.SetTooltipText("Add to Favorites")
+	if p.Favorite {
+		favoriteButton.SetIconName("starred-symbolic")
+		favoriteButton.SetTooltipText("Remove from Favorites")
+	}
+	favoriteButton.SetVAlign(gtk.AlignCenter)
+	favoriteButton.AddCSSClass("flat")
+	favoriteButton.SetVisible(p != pl.alwaysOn)
+	favoriteButton.ConnectClicked(func() {
+		if pl.onFavoriteToggled != nil {
+			pl.onFavoriteToggled(captured)
+		}
+	})
+	hbox.Append(favoriteButton)
+
+	// Profiles provided by an administrator cannot be deleted
+	if p.System {
+		lockIcon := gtk.NewImageFromIconName("changes-prevent-symbolic")
+		lockIcon.SetVAlign(gtk.AlignCenter)
+		lockIcon.SetTooltipText("Managed by Your Administrator")
+		if p == pl.alwaysOn {
+			lockIcon.SetTooltipText("Always-On VPN Managed by the System")
+		}
+		lockIcon.SetOpacity(dimmedOpacity)
+		hbox.Append(lockIcon)
+	}
+
 	// Delete button (suffix)
 	deleteButton := gtk.NewButtonFromIconName("edit-delete-symbolic")
 	deleteButton.SetVAlign(gtk.AlignCenter)
 	deleteButton.AddCSSClass("flat")
 	deleteButton.SetTooltipText("Delete Profile")
-
-	// Capture profile for closure
-	captured := p
+	deleteButton.SetVisible(!p.System)
 	deleteButton.ConnectClicked(func() {
 		if pl.onDeleted != nil {
 			pl.onDeleted(captured)
@@ -185,21 +454,105 @@ func (pl *ProfileList) addProfileRow(p *profile.Profile) {
 	row.SetChild(hbox)
 
 	// Store mapping
-	pl.profileMap[p.ID] = &profileRow{
+	pr := &profileRow{
 		row:           row,
 		profile:       p,
 		titleLabel:    titleLabel,
 		subtitleLabel: subtitleLabel,
 	}
+	pl.profileMap[p.ID] = pr
 
 	pl.list.Append(row)
+	return pr
+}
+
+// templateName returns the name of the template the profile is based on,
+// or an empty string if it has none.
+func (pl *ProfileList) templateName(p *profile.Profile) string {
+	if p.ParentID == "" {
+		return ""
+	}
+	for _, other := range pl.profiles {
+		if other.ID == p.ParentID {
+			return other.Name
+		}
+	}
+	return ""
+}
+
+// profileSubtitle returns the description if available, otherwise the host,
+// followed by the profile's tags. Profiles that inherit their host show the
+// template they are based on instead.
+func profileSubtitle(p *profile.Profile, templateName string) string {
+	subtitle := p.Host
+	if !p.IsOverridden("host") && templateName != "" {
+		subtitle = "Based on " + templateName
+	}
+	if p.Description != "" {
+		subtitle = p.Description
+	}
+	if len(p.Tags) > 0 {
+		subtitle += " · " + strings.Join(p.Tags, ", ")
+	}
+	return subtitle
+}
+
+// applyVisibility shows the rows matching the search query. Without a query,
+// rows of collapsed groups are hidden; while searching, all groups are expanded
+// and groups without matches are hidden.
+func (pl *ProfileList) applyVisibility() {
+	searching := pl.query != ""
+
+	for _, pr := range pl.profileMap {
+		pr.row.SetVisible(pr.profile.Matches(pl.query))
+	}
+
+	for _, h := range pl.headers {
+		expanded := searching || !pl.collapsed[h.key]
+		if expanded {
+			h.arrow.SetFromIconName("pan-down-symbolic")
+		} else {
+			h.arrow.SetFromIconName("pan-end-symbolic")
+		}
+
+		anyVisible := false
+		for _, pr := range h.rows {
+			visible := expanded && pr.profile.Matches(pl.query)
+			pr.row.SetVisible(visible)
+			anyVisible = anyVisible || visible
+		}
+		h.row.SetVisible(!searching || anyVisible)
+	}
+
+	if searching {
+		pl.list.SetPlaceholder(pl.noMatchPlaceholder)
+	} else {
+		pl.list.SetPlaceholder(pl.emptyPlaceholder)
+	}
 }
 
 // SelectProfile selects the profile with the given ID.
+// Collapsed groups and active searches hiding the profile are cleared so the row is shown.
 func (pl *ProfileList) SelectProfile(id string) {
-	if pr, ok := pl.profileMap[id]; ok {
-		pl.list.SelectRow(pr.row)
+	pr, ok := pl.profileMap[id]
+	if !ok {
+		return
+	}
+
+	if !pr.row.Visible() {
+		pl.searchEntry.SetText("")
+		pl.query = ""
+		for _, h := range pl.headers {
+			for _, r := range h.rows {
+				if r == pr {
+					pl.collapsed[h.key] = false
+				}
+			}
+		}
+		pl.applyVisibility()
 	}
+
+	pl.list.SelectRow(pr.row)
 }
 
 // ClearSelection deselects any currently selected profile.
@@ -214,10 +567,8 @@ func (pl *ProfileList) GetSelectedProfile() *profile.Profile {
 		return nil
 	}
 
-	// Use row index to find the profile
-	rowIndex := row.Index()
-	if rowIndex >= 0 && rowIndex < len(pl.profiles) {
-		return pl.profiles[rowIndex]
+	if pr, ok := pl.profileMap[row.Name()]; ok {
+		return pr.profile
 	}
 
 	return nil
@@ -233,28 +584,40 @@ func (pl *ProfileList) OnProfileDeleted(callback func(p *profile.Profile)) {
 	pl.onDeleted = callback
 }
 
+// OnFavoriteToggled registers a callback for when a profile's favorite star is clicked.
+// The callback is responsible for persisting the change and calling UpdateProfile.
+func (pl *ProfileList) OnFavoriteToggled(callback func(p *profile.Profile)) {
+	pl.onFavoriteToggled = callback
+}
+
+// OnSortOrderChanged registers a callback for when the user picks a different sort order.
+func (pl *ProfileList) OnSortOrderChanged(callback func(order profile.SortOrder)) {
+	pl.onSortOrderChanged = callback
+}
+
 // Widget returns the root GTK widget for the profile list.
 func (pl *ProfileList) Widget() gtk.Widgetter {
 	return pl.widget
 }
 
 // UpdateProfile updates the display of a specific profile in the list.
+// The list is rebuilt because a changed name, host, group, or favorite
+// state can move the profile to a different position or section.
 func (pl *ProfileList) UpdateProfile(p *profile.Profile) {
-	if pr, ok := pl.profileMap[p.ID]; ok {
-		// Update custom labels (SetTitle/SetSubtitle don't work with custom child)
-		pr.titleLabel.SetText(p.Name)
-
-		// Show description if available, otherwise show host
-		subtitle := p.Host
-		if p.Description != "" {
-			subtitle = p.Description
+	for i, existing := range pl.profiles {
+		if existing.ID == p.ID {
+			pl.profiles[i] = p
+			pl.rebuild()
+			return
 		}
-		pr.subtitleLabel.SetText(subtitle)
-
-		pr.profile = p
 	}
 }
 
+// Profiles returns the stored profiles shown in the list, in no particular order.
+func (pl *ProfileList) Profiles() []*profile.Profile {
+	return pl.profiles
+}
+
 // GetProfileByID returns the profile with the given ID, or nil if not found.
 func (pl *ProfileList) GetProfileByID(id string) *profile.Profile {
 	if pr, ok := pl.profileMap[id]; ok {
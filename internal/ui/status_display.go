@@ -2,6 +2,8 @@ package ui
 
 import (
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
@@ -16,11 +18,25 @@ type StatusDisplay struct {
 	// Status components
 	stateLabel   *gtk.Label
 	profileLabel *gtk.Label
+	gatewayLabel *gtk.Label
 	ipLabel      *gtk.Label
 
+	// Connection progress components (visible only while connecting)
+	phaseLabel *gtk.Label
+	phaseBar   *gtk.LevelBar
+
+	// Cancels the pending reconnect (visible only while waiting for it)
+	cancelReconnectButton *gtk.Button
+
 	// State
-	state      vpn.ConnectionState
-	assignedIP string
+	state       vpn.ConnectionState
+	assignedIP  string
+	gateway     string
+	phase       vpn.Phase
+	reconnectAt time.Time         // When the pending reconnect attempt starts
+	countdown   glib.SourceHandle // Ticks the reconnect countdown, 0 when stopped
+
+	onCancelReconnect func()
 }
 
 // NewStatusDisplay creates a new status display widget.
@@ -61,23 +77,89 @@ func (sd *StatusDisplay) setupWidget() {
 	sd.profileLabel.SetOpacity(dimmedOpacity) // Subtle dimming without being invisible in dark themes
 	sd.widget.Append(sd.profileLabel)
 
+	// Gateway label (hidden by default)
+	sd.gatewayLabel = gtk.NewLabel("")
+	sd.gatewayLabel.SetOpacity(dimmedOpacity)
+	sd.gatewayLabel.SetVisible(false)
+	sd.widget.Append(sd.gatewayLabel)
+
 	// IP label (hidden by default)
 	sd.ipLabel = gtk.NewLabel("")
 	sd.ipLabel.SetOpacity(dimmedOpacity) // Subtle dimming without being invisible in dark themes
 	sd.ipLabel.SetVisible(false)
 	sd.widget.Append(sd.ipLabel)
 
+	// Connection progress (hidden by default)
+	sd.phaseBar = gtk.NewLevelBarForInterval(0, float64(vpn.PhaseCount()))
+	sd.phaseBar.SetMode(gtk.LevelBarModeDiscrete)
+	sd.phaseBar.SetSizeRequest(70, -1)
+	sd.phaseBar.SetVAlign(gtk.AlignCenter)
+	sd.phaseBar.SetVisible(false)
+	sd.widget.Append(sd.phaseBar)
+
+	sd.phaseLabel = gtk.NewLabel("")
+	sd.phaseLabel.SetOpacity(dimmedOpacity)
+	sd.phaseLabel.SetVisible(false)
+	sd.widget.Append(sd.phaseLabel)
+
+	sd.cancelReconnectButton = gtk.NewButtonWithLabel("Cancel")
+	sd.cancelReconnectButton.SetTooltipText("Stop reconnecting")
+	sd.cancelReconnectButton.AddCSSClass("flat")
+	sd.cancelReconnectButton.SetVAlign(gtk.AlignCenter)
+	sd.cancelReconnectButton.SetVisible(false)
+	sd.cancelReconnectButton.ConnectClicked(func() {
+		if sd.onCancelReconnect != nil {
+			sd.onCancelReconnect()
+		}
+	})
+	sd.widget.Append(sd.cancelReconnectButton)
+
 	sd.updateStateDisplay()
 }
 
 // SetState updates the displayed connection state.
 func (sd *StatusDisplay) SetState(state vpn.ConnectionState) {
 	glib.IdleAdd(func() {
+		// Phases only describe progress within a single connection attempt.
+		if state == vpn.StateConnecting || !state.IsTransitioning() {
+			sd.phase = vpn.PhaseNone
+		}
+		// The gateway is reported again by the next connection attempt.
+		if state == vpn.StateDisconnected {
+			sd.gateway = ""
+		}
+		// The countdown only applies while waiting for the attempt it counts down to.
+		if state != vpn.StateReconnecting {
+			sd.reconnectAt = time.Time{}
+		}
 		sd.state = state
 		sd.updateStateDisplay()
 	})
 }
 
+// SetPhase updates the displayed connection progress.
+func (sd *StatusDisplay) SetPhase(phase vpn.Phase) {
+	glib.IdleAdd(func() {
+		sd.phase = phase
+		sd.updatePhaseDisplay()
+	})
+}
+
+// updatePhaseDisplay shows the step indicator while a connection attempt is in progress.
+func (sd *StatusDisplay) updatePhaseDisplay() {
+	ordinal := sd.phase.Ordinal()
+	visible := ordinal > 0 && (sd.state == vpn.StateConnecting || sd.state == vpn.StateAuthenticating)
+
+	sd.phaseBar.SetVisible(visible)
+	sd.phaseLabel.SetVisible(visible)
+	if !visible {
+		return
+	}
+
+	sd.phaseBar.SetValue(float64(ordinal))
+	sd.phaseLabel.SetText(fmt.Sprintf("Step %d/%d: %s", ordinal, vpn.PhaseCount(), sd.phase.Label()))
+}
+
 // updateStateDisplay updates the UI based on the current state.
 func (sd *StatusDisplay) updateStateDisplay() {
 	var stateText string
@@ -96,8 +178,15 @@ func (sd *StatusDisplay) updateStateDisplay() {
 			sd.ipLabel.SetText(fmt.Sprintf("• %s", sd.assignedIP))
 			sd.ipLabel.SetVisible(true)
 		}
+	case vpn.StateDegraded:
+		stateText = "Not responding"
 	case vpn.StateReconnecting:
 		stateText = "Reconnecting..."
+		if remaining := time.Until(sd.reconnectAt); remaining > 0 {
+			stateText = fmt.Sprintf("Reconnecting, next attempt in %ds", int(math.Ceil(remaining.Seconds())))
+		}
+	case vpn.StateWaitingForNetwork:
+		stateText = "Waiting for network..."
 	case vpn.StateFailed:
 		stateText = "Failed"
 		sd.ipLabel.SetVisible(false)
@@ -117,9 +206,63 @@ func (sd *StatusDisplay) updateStateDisplay() {
 		sd.stateLabel.AddCSSClass("success")
 	case vpn.StateFailed:
 		sd.stateLabel.AddCSSClass("error")
-	case vpn.StateConnecting, vpn.StateAuthenticating, vpn.StateReconnecting:
+	case vpn.StateConnecting, vpn.StateAuthenticating, vpn.StateDegraded, vpn.StateReconnecting, vpn.StateWaitingForNetwork:
 		sd.stateLabel.AddCSSClass("warning")
 	}
+
+	sd.updateGatewayDisplay()
+	sd.updatePhaseDisplay()
+	sd.updateCountdown()
+}
+
+// updateCountdown shows the Cancel button and keeps the countdown ticking
+// while a reconnect attempt is pending or waiting for the network.
+func (sd *StatusDisplay) updateCountdown() {
+	pending := sd.state == vpn.StateReconnecting && time.Until(sd.reconnectAt) > 0
+	sd.cancelReconnectButton.SetVisible(pending || sd.state == vpn.StateWaitingForNetwork)
+
+	if !pending {
+		if sd.countdown != 0 {
+			glib.SourceRemove(sd.countdown)
+			sd.countdown = 0
+		}
+		return
+	}
+	if sd.countdown == 0 {
+		sd.countdown = glib.TimeoutAdd(1000, func() bool {
+			if sd.state != vpn.StateReconnecting || time.Until(sd.reconnectAt) <= 0 {
+				// Returning false removes the source
+				sd.countdown = 0
+				sd.updateStateDisplay()
+				return false
+			}
+			sd.updateStateDisplay()
+			return true
+		})
+	}
+}
+
+// updateGatewayDisplay shows the gateway in use while a connection is active or in progress.
+func (sd *StatusDisplay) updateGatewayDisplay() {
+	visible := sd.gateway != "" && sd.state != vpn.StateDisconnected && sd.state != vpn.StateFailed
+	sd.gatewayLabel.SetVisible(visible)
+	if visible {
+		sd.gatewayLabel.SetText(fmt.Sprintf("via %s", sd.gateway))
+	}
+}
+
+// SetReconnectAt sets when the pending reconnect attempt starts, shown as a
+// countdown while the state is Reconnecting. The zero time hides the countdown.
+func (sd *StatusDisplay) SetReconnectAt(at time.Time) {
+	glib.IdleAdd(func() {
+		sd.reconnectAt = at
+		sd.updateStateDisplay()
+	})
+}
+
+// OnCancelReconnect sets the callback for the Cancel button shown during the countdown.
+func (sd *StatusDisplay) OnCancelReconnect(callback func()) {
+	sd.onCancelReconnect = callback
 }
 
 // SetProfileInfo sets the profile name to display.
@@ -146,6 +289,14 @@ func (sd *StatusDisplay) SetAssignedIP(ip string) {
 	})
 }
 
+// SetGateway sets the gateway endpoint (host:port) used by the connection.
+func (sd *StatusDisplay) SetGateway(gateway string) {
+	glib.IdleAdd(func() {
+		sd.gateway = gateway
+		sd.updateGatewayDisplay()
+	})
+}
+
 // Widget returns the root GTK widget for the status display.
 func (sd *StatusDisplay) Widget() gtk.Widgetter {
 	return sd.widget
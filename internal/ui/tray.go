@@ -9,6 +9,7 @@ import (
 
 	"fyne.io/systray"
 
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
 	"github.com/shini4i/openfortivpn-gui/internal/stats"
 	"github.com/shini4i/openfortivpn-gui/internal/vpn"
 )
@@ -29,20 +30,29 @@ type TrayIcon struct {
 	// State
 	state       vpn.ConnectionState
 	profileName string
+	profiles    []*profile.Profile
+	sortOrder   profile.SortOrder
 
 	// Menu items
 	menuStatus      *systray.MenuItem
 	menuTrafficRate *systray.MenuItem
 	menuConnect     *systray.MenuItem
+	menuConnectTo   *systray.MenuItem
 	menuDisconnect  *systray.MenuItem
 	menuShow        *systray.MenuItem
 	menuQuit        *systray.MenuItem
 
+	// Top-level items of the "Connect To" submenu, recreated whenever the profiles change.
+	// Group items are listed without their profile items, which are removed along with them.
+	profileMenuMu sync.Mutex // Serializes rebuilds from onReady and SetProfiles
+	profileItems  []*systray.MenuItem
+
 	// Callbacks - must be set before Run() is called
-	onConnect    func()
-	onDisconnect func()
-	onShow       func()
-	onQuit       func()
+	onConnect        func()
+	onConnectProfile func(profileID string)
+	onDisconnect     func()
+	onShow           func()
+	onQuit           func()
 
 	// Icons (set once in NewTrayIcon, read-only after initialization)
 	iconDisconnected []byte
@@ -80,6 +90,19 @@ func (t *TrayIcon) OnConnect(callback func()) error {
 	return nil
 }
 
+// OnConnectProfile registers a callback for when a profile is clicked in the
+// "Connect To" submenu. This callback is optional.
+// Must be called before Run(). Returns ErrTrayAlreadyRunning if called after Run().
+func (t *TrayIcon) OnConnectProfile(callback func(profileID string)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running {
+		return ErrTrayAlreadyRunning
+	}
+	t.onConnectProfile = callback
+	return nil
+}
+
 // OnDisconnect registers a callback for when Disconnect is clicked in tray.
 // Must be called before Run(). Returns ErrTrayAlreadyRunning if called after Run().
 func (t *TrayIcon) OnDisconnect(callback func()) error {
@@ -133,6 +156,25 @@ func (t *TrayIcon) SetProfileName(name string) {
 	t.updateMenu()
 }
 
+// SetProfiles updates the "Connect To" submenu, organized by the same groups
+// as the profile list: favorites first, then one submenu per group.
+// Templates are left out since they cannot be connected.
+func (t *TrayIcon) SetProfiles(profiles []*profile.Profile, order profile.SortOrder) {
+	connectable := make([]*profile.Profile, 0, len(profiles))
+	for _, p := range profiles {
+		if !p.IsTemplate {
+			connectable = append(connectable, p)
+		}
+	}
+
+	t.mu.Lock()
+	t.profiles = connectable
+	t.sortOrder = order
+	t.mu.Unlock()
+	t.rebuildProfileMenu()
+	t.updateMenu()
+}
+
 // SetStats updates the traffic rate display in the tray menu.
 func (t *TrayIcon) SetStats(s stats.NetworkStats) {
 	if t.menuTrafficRate == nil {
@@ -198,6 +240,8 @@ func (t *TrayIcon) onReady() {
 	systray.AddSeparator()
 
 	t.menuConnect = systray.AddMenuItem("Connect", "Connect to VPN")
+	t.menuConnectTo = systray.AddMenuItem("Connect To", "Connect to a specific profile")
+	t.menuConnectTo.Hide()
 	t.menuDisconnect = systray.AddMenuItem("Disconnect", "Disconnect from VPN")
 	t.menuDisconnect.Disable()
 
@@ -209,6 +253,10 @@ func (t *TrayIcon) onReady() {
 	// Handle menu clicks in a goroutine
 	go t.handleMenuClicks()
 
+	// Populate the profile submenu with profiles set before the tray was ready
+	t.rebuildProfileMenu()
+	t.updateMenu()
+
 	slog.Info("System tray initialized")
 }
 
@@ -255,6 +303,75 @@ func (t *TrayIcon) handleMenuClicks() {
 	}
 }
 
+// rebuildProfileMenu recreates the "Connect To" submenu from the current profiles.
+func (t *TrayIcon) rebuildProfileMenu() {
+	if t.menuConnectTo == nil {
+		return // Not initialized yet
+	}
+
+	t.mu.RLock()
+	groups := profile.GroupProfiles(append([]*profile.Profile(nil), t.profiles...), t.sortOrder)
+	t.mu.RUnlock()
+
+	t.profileMenuMu.Lock()
+	defer t.profileMenuMu.Unlock()
+
+	// Removing an item closes its click channel, and those of its children,
+	// which stops their handler goroutines
+	for _, item := range t.profileItems {
+		item.Remove()
+	}
+	t.profileItems = nil
+
+	for _, g := range groups {
+		parent := t.menuConnectTo
+		// Favorites and ungrouped profiles are listed directly in the submenu
+		if !g.Favorites && g.Name != "" {
+			parent = t.menuConnectTo.AddSubMenuItem(g.Name, fmt.Sprintf("Profiles in %s", g.Name))
+			t.profileItems = append(t.profileItems, parent)
+		}
+
+		for _, p := range g.Profiles {
+			title := p.Name
+			if g.Favorites {
+				title = "★ " + p.Name
+			}
+			item := parent.AddSubMenuItem(title, p.Host)
+			if parent == t.menuConnectTo {
+				t.profileItems = append(t.profileItems, item)
+			}
+			go t.handleProfileClicks(item, p.ID)
+		}
+	}
+
+	if len(groups) > 0 {
+		t.menuConnectTo.Show()
+	} else {
+		t.menuConnectTo.Hide()
+	}
+}
+
+// handleProfileClicks invokes the OnConnectProfile callback for clicks on a profile item.
+// It returns when the item is removed or the tray quits.
+func (t *TrayIcon) handleProfileClicks(item *systray.MenuItem, profileID string) {
+	for {
+		select {
+		case <-t.done:
+			return
+		case _, ok := <-item.ClickedCh:
+			if !ok {
+				return
+			}
+			t.mu.RLock()
+			callback := t.onConnectProfile
+			t.mu.RUnlock()
+			if callback != nil {
+				callback(profileID)
+			}
+		}
+	}
+}
+
 // updateIcon updates the tray icon based on current state.
 func (t *TrayIcon) updateIcon() {
 	if t.menuStatus == nil {
@@ -279,6 +396,12 @@ func (t *TrayIcon) updateIcon() {
 	case vpn.StateConnecting, vpn.StateAuthenticating, vpn.StateReconnecting:
 		icon = t.iconConnecting
 		tooltip = "OpenFortiVPN GUI - Connecting..."
+	case vpn.StateDegraded:
+		icon = t.iconConnecting
+		tooltip = "OpenFortiVPN GUI - Not responding"
+	case vpn.StateWaitingForNetwork:
+		icon = t.iconDisconnected
+		tooltip = "OpenFortiVPN GUI - Waiting for network..."
 	default:
 		icon = t.iconDisconnected
 		tooltip = "OpenFortiVPN GUI - Disconnected"
@@ -311,8 +434,12 @@ func (t *TrayIcon) updateMenu() {
 		statusText = "Status: Connecting..."
 	case vpn.StateAuthenticating:
 		statusText = "Status: Authenticating..."
+	case vpn.StateDegraded:
+		statusText = "Status: Not responding"
 	case vpn.StateReconnecting:
 		statusText = "Status: Reconnecting..."
+	case vpn.StateWaitingForNetwork:
+		statusText = "Status: Waiting for network..."
 	case vpn.StateFailed:
 		statusText = "Status: Connection Failed"
 	default:
@@ -339,8 +466,10 @@ func (t *TrayIcon) updateMenu() {
 	// Enable/disable connect/disconnect based on state
 	if state.CanConnect() {
 		t.menuConnect.Enable()
+		t.menuConnectTo.Enable()
 	} else {
 		t.menuConnect.Disable()
+		t.menuConnectTo.Disable()
 	}
 
 	if state.CanDisconnect() {
@@ -4,8 +4,10 @@ import (
 	"sync"
 	"testing"
 
+	"fyne.io/systray"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
 	"github.com/shini4i/openfortivpn-gui/internal/vpn"
 )
 
@@ -90,7 +92,9 @@ func TestTrayIcon_SetState(t *testing.T) {
 		vpn.StateConnecting,
 		vpn.StateAuthenticating,
 		vpn.StateConnected,
+		vpn.StateDegraded,
 		vpn.StateReconnecting,
+		vpn.StateWaitingForNetwork,
 		vpn.StateFailed,
 		vpn.StateDisconnected,
 	}
@@ -115,6 +119,68 @@ func TestTrayIcon_SetProfileName(t *testing.T) {
 	tray.mu.RUnlock()
 }
 
+func TestTrayIcon_SetProfilesBeforeReady(t *testing.T) {
+	tray := NewTrayIcon()
+
+	profiles := []*profile.Profile{profile.NewProfile("Office"), profile.NewProfile("Lab")}
+	tray.SetProfiles(profiles, profile.SortByHost)
+
+	// Modifying the caller's slice must not affect the tray
+	profiles[0] = nil
+
+	tray.mu.RLock()
+	defer tray.mu.RUnlock()
+	assert.Len(t, tray.profiles, 2)
+	assert.NotNil(t, tray.profiles[0])
+	assert.Equal(t, profile.SortByHost, tray.sortOrder)
+	assert.Empty(t, tray.profileItems, "menu items are created once the tray is ready")
+}
+
+func TestTrayIcon_RebuildProfileMenuWithGroups(t *testing.T) {
+	tray := NewTrayIcon()
+	tray.menuConnectTo = systray.AddMenuItem("Connect To", "")
+	defer tray.menuConnectTo.Remove()
+
+	grouped := profile.NewProfile("Branch")
+	grouped.Group = "Offices"
+	favorite := profile.NewProfile("Home")
+	favorite.Favorite = true
+	tray.profiles = []*profile.Profile{grouped, favorite, profile.NewProfile("Lab")}
+
+	// Removing a group item removes its profile items too; they must not be removed again
+	assert.NotPanics(t, tray.rebuildProfileMenu)
+	assert.NotPanics(t, tray.rebuildProfileMenu)
+	assert.Len(t, tray.profileItems, 3, "the favorite, the Offices group, and the ungrouped profile")
+}
+
+func TestTrayIcon_SetProfilesSkipsTemplates(t *testing.T) {
+	tray := NewTrayIcon()
+
+	template := profile.NewProfile("Office Template")
+	template.IsTemplate = true
+	child := profile.NewProfile("Branch")
+	child.ParentID = template.ID
+	tray.SetProfiles([]*profile.Profile{template, child}, profile.SortByName)
+
+	tray.mu.RLock()
+	defer tray.mu.RUnlock()
+	assert.Equal(t, []*profile.Profile{child}, tray.profiles)
+}
+
+func TestTrayIcon_OnConnectProfile(t *testing.T) {
+	tray := NewTrayIcon()
+
+	var connected string
+	assert.NoError(t, tray.OnConnectProfile(func(id string) { connected = id }))
+	tray.onConnectProfile("profile-id")
+	assert.Equal(t, "profile-id", connected)
+
+	tray.mu.Lock()
+	tray.running = true
+	tray.mu.Unlock()
+	assert.ErrorIs(t, tray.OnConnectProfile(func(string) {}), ErrTrayAlreadyRunning)
+}
+
 func TestTrayIcon_QuitSafeToCallMultipleTimes(t *testing.T) {
 	tray := NewTrayIcon()
 
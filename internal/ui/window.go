@@ -2,8 +2,14 @@ package ui
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
@@ -11,10 +17,19 @@ import (
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 
+	"github.com/shini4i/openfortivpn-gui/internal/backup"
 	"github.com/shini4i/openfortivpn-gui/internal/config"
+	"github.com/shini4i/openfortivpn-gui/internal/diagnose"
+	"github.com/shini4i/openfortivpn-gui/internal/fileutil"
 	"github.com/shini4i/openfortivpn-gui/internal/keyring"
+	"github.com/shini4i/openfortivpn-gui/internal/liveness"
+	"github.com/shini4i/openfortivpn-gui/internal/logind"
+	"github.com/shini4i/openfortivpn-gui/internal/migrate"
+	"github.com/shini4i/openfortivpn-gui/internal/netmon"
+	"github.com/shini4i/openfortivpn-gui/internal/netrules"
 	"github.com/shini4i/openfortivpn-gui/internal/profile"
 	"github.com/shini4i/openfortivpn-gui/internal/reconnect"
+	"github.com/shini4i/openfortivpn-gui/internal/schedule"
 	"github.com/shini4i/openfortivpn-gui/internal/stats"
 	"github.com/shini4i/openfortivpn-gui/internal/vpn"
 )
@@ -27,6 +42,9 @@ const (
 	// after window presentation. GTK's internal focus handling needs time to complete;
 	// 50ms was empirically determined to be sufficient.
 	focusClearDelayMs = 50
+
+	// releaseKillSwitchAction is the application action that removes the kill switch.
+	releaseKillSwitchAction = "release-kill-switch"
 )
 
 // MainWindowDeps holds the dependencies required by MainWindow.
@@ -39,6 +57,21 @@ type MainWindowDeps struct {
 	Notifier         *Notifier
 	StatsCollector   *stats.Collector
 	ReconnectManager *reconnect.Manager
+	// GatewayProber picks the gateway to connect to for profiles with backup gateways.
+	// If nil, the configured order is used without probing.
+	GatewayProber vpn.GatewayProber
+	// NetworkMonitor reports uplink changes, which pause reconnecting while
+	// offline and cycle tunnels left on a previous uplink. Optional.
+	NetworkMonitor *netmon.Monitor
+	// SessionWatcher reports suspend, resume and screen lock, which close the
+	// tunnel cleanly before sleeping and restore it afterwards. Optional.
+	SessionWatcher *logind.Watcher
+	// Scheduler reports the windows of profile schedules, which connect profiles
+	// when a window starts and disconnect them when it ends. Optional.
+	Scheduler *schedule.Scheduler
+	// OpenfortivpnVersion is the installed openfortivpn version (nil if unknown).
+	// It is used to warn when a profile needs a newer release.
+	OpenfortivpnVersion *vpn.Version
 	// Ctx is the application-level context for VPN operations.
 	// When cancelled, ongoing VPN connections should be terminated.
 	Ctx context.Context
@@ -58,8 +91,35 @@ type MainWindow struct {
 	connectButton *gtk.Button
 	logDialog     *LogDialog
 
+	// Profile hooks run around connection changes
+	hooks *connectionHooks
+
+	// sleepRelease lets the system suspend once the tunnel closed for sleeping is down
+	sleepMu      sync.Mutex
+	sleepRelease func()
+
+	// stopLiveness stops probing the connected tunnel; nil while not probing
+	livenessMu   sync.Mutex
+	stopLiveness context.CancelFunc
+
+	// idle tells when the connected tunnel has not been used for long enough to
+	// disconnect it; nil while not connected or without an idle rule
+	idleMu      sync.Mutex
+	idle        *stats.IdleDetector
+	idleProfile *profile.Profile
+
+	// disconnectNotice replaces the notification of the next disconnect, one
+	// the application caused itself; nil for the usual notification
+	noticeMu         sync.Mutex
+	disconnectNotice *NotificationType
+
 	// State
 	selectedProfile *profile.Profile
+	// alwaysOn is the profile the helper keeps connected for the whole system;
+	// nil unless one is configured. The user cannot connect or disconnect meanwhile.
+	// It follows the helper's status through syncAlwaysOn; alwaysOnBanner explains it.
+	alwaysOn       *profile.Profile
+	alwaysOnBanner *adw.Banner
 
 	// Callbacks
 	onProfileConnecting func(profileID string)
@@ -73,6 +133,7 @@ func NewMainWindow(app *adw.Application, deps *MainWindowDeps) *MainWindow {
 
 	w.setupWindow(app)
 	w.setupLayout()
+	w.hooks = newConnectionHooks(deps.Ctx, deps.VPNController, w.logDialog.AppendLog)
 	w.setupCallbacks()
 	w.loadProfiles()
 
@@ -107,7 +168,9 @@ func (w *MainWindow) setupLayout() {
 
 	// Create content area (profile editor + status)
 	w.profileEditor = NewProfileEditor()
+	w.profileEditor.SetOpenfortivpnVersion(w.deps.OpenfortivpnVersion)
 	w.statusDisplay = NewStatusDisplay()
+	w.statusDisplay.OnCancelReconnect(w.cancelReconnect)
 	w.statsDisplay = NewStatsDisplay()
 	contentPage := w.createContentPage()
 	w.splitView.SetContent(contentPage)
@@ -199,6 +262,12 @@ func (w *MainWindow) createContentPage() *adw.NavigationPage {
 	toolbarView.AddTopBar(headerBar)
 	toolbarView.SetContent(contentBox)
 
+	// Explain why connecting is unavailable while the system keeps its own VPN up
+	w.alwaysOnBanner = adw.NewBanner("")
+	w.alwaysOnBanner.SetUseMarkup(false)
+	toolbarView.AddTopBar(w.alwaysOnBanner)
+	w.syncAlwaysOn()
+
 	// Create navigation page
 	page := adw.NewNavigationPage(toolbarView, "Profile")
 	page.SetTag("content")
@@ -206,11 +275,48 @@ func (w *MainWindow) createContentPage() *adw.NavigationPage {
 	return page
 }
 
+// syncAlwaysOn follows the always-on profile the helper reports: the banner
+// names it, the profile list shows it read-only and connecting is unavailable.
+// It must run on the main thread.
+func (w *MainWindow) syncAlwaysOn() {
+	var p *profile.Profile
+	if reporter, ok := w.deps.VPNController.(vpn.AlwaysOnReporter); ok {
+		p = reporter.AlwaysOnProfile()
+	}
+	if sameAlwaysOn(p, w.alwaysOn) {
+		return
+	}
+	w.alwaysOn = p
+
+	if p != nil {
+		w.alwaysOnBanner.SetTitle(fmt.Sprintf("The always-on VPN “%s” is managed by the system", p.Name))
+	}
+	w.alwaysOnBanner.SetRevealed(p != nil)
+	w.profileList.SetAlwaysOn(p)
+	w.updateConnectButton(w.deps.VPNController.GetState())
+}
+
+// sameAlwaysOn reports whether two always-on profiles are listed the same way.
+func sameAlwaysOn(a, b *profile.Profile) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID && a.Name == b.Name && a.Host == b.Host && a.Port == b.Port
+}
+
 // createMainMenu creates the application menu model.
 func (w *MainWindow) createMainMenu() *gio.Menu {
 	menu := gio.NewMenu()
 
 	// Add menu items
+	menu.Append("Import…", "app.import")
+	menu.Append("Import NetworkManager Connections…", "app.import-networkmanager")
+	menu.Append("Export Profile…", "app.export")
+	menu.Append("Back Up Profiles…", "app.backup")
+	menu.Append("Restore Backup…", "app.restore")
+	if _, ok := w.deps.VPNController.(vpn.KillSwitchController); ok {
+		menu.Append("Remove Kill Switch", "app."+releaseKillSwitchAction)
+	}
 	menu.Append("Preferences", "app.preferences")
 	menu.Append("About", "app.about")
 	menu.Append("Quit", "app.quit")
@@ -232,31 +338,54 @@ func (w *MainWindow) setupCallbacks() {
 	})
 
 	// Profile save callback - save changes when user clicks Save
-	w.profileEditor.OnSave(func(p *profile.Profile) {
-		if err := w.deps.ProfileStore.Save(p); err != nil {
-			w.showError("Error Saving Profile", err.Error())
-			return
-		}
+	w.profileEditor.OnSave(w.saveProfile)
 
-		// Check if this is a new profile (not in list yet)
-		if w.profileList.GetProfileByID(p.ID) == nil {
-			// New profile - refresh the list and select it
-			w.loadProfiles()
-			w.profileList.SelectProfile(p.ID)
-		} else {
-			// Existing profile - just update the display
-			w.profileList.UpdateProfile(p)
-		}
-
-		// Keep selected profile reference in sync
-		w.selectedProfile = p
-	})
+	// Connection test callback
+	w.profileEditor.OnTestConnection(w.testConnection)
 
 	// Profile deletion callback
 	w.profileList.OnProfileDeleted(func(p *profile.Profile) {
 		w.onDeleteProfile(p)
 	})
 
+	// Favorite toggle callback - persisted immediately, independent of unsaved editor changes
+	w.profileList.OnFavoriteToggled(w.toggleFavorite)
+
+	// Sort order callback - persist the choice for the next start
+	w.profileList.OnSortOrderChanged(func(order profile.SortOrder) {
+		if w.deps.ConfigManager != nil {
+			if err := w.deps.ConfigManager.UpdateField(func(cfg *config.Config) {
+				cfg.ProfileSortOrder = order
+			}); err != nil {
+				slog.Error("Failed to save profile sort order", "error", err)
+			}
+		}
+		w.refreshTrayProfiles()
+	})
+
+	// Network uplink changes pause reconnecting and cycle stale tunnels
+	if w.deps.NetworkMonitor != nil {
+		w.deps.NetworkMonitor.OnChange(w.onUplinkChanged)
+	}
+
+	// Suspending closes the tunnel cleanly, and locking the screen may close it too
+	if w.deps.SessionWatcher != nil {
+		w.deps.SessionWatcher.SetHandlers(logind.Handlers{
+			BeforeSleep: func(release func()) { glib.IdleAdd(func() { w.prepareForSleep(release) }) },
+			AfterResume: w.resumeFromSleep,
+			Lock:        func() { glib.IdleAdd(w.onScreenLocked) },
+		})
+	}
+
+	// Profile schedules connect at the start of a window and disconnect at its end
+	if w.deps.Scheduler != nil {
+		w.deps.Scheduler.SetHandlers(schedule.Handlers{
+			Start: func(id string) { glib.IdleAdd(func() { w.onScheduleStarted(id) }) },
+			Warn:  func(id string, end time.Time) { glib.IdleAdd(func() { w.onScheduleEnding(id, end) }) },
+			End:   w.onScheduleEnded,
+		})
+	}
+
 	// VPN state change callback
 	w.deps.VPNController.OnStateChange(func(oldState, newState vpn.ConnectionState) {
 		// Reset reconnect state on successful connection
@@ -269,11 +398,32 @@ func (w *MainWindow) setupCallbacks() {
 		if w.deps.ReconnectManager != nil && w.deps.ReconnectManager.ShouldReconnect(oldState, newState) {
 			w.deps.ReconnectManager.StartReconnect()
 			displayState = vpn.StateReconnecting
+			if w.deps.ReconnectManager.IsWaitingForNetwork() {
+				displayState = vpn.StateWaitingForNetwork
+			}
+		}
+
+		// Run the profile's post-connect and post-disconnect hooks
+		w.hooks.stateChanged(newState)
+
+		// A tunnel closed for sleeping is down, so the system may suspend
+		if newState.CanConnect() {
+			w.releaseSleep()
+		}
+
+		// Established tunnels are probed for traffic if the profile asks for it
+		if newState == vpn.StateConnected {
+			w.startLivenessProbe()
+		} else {
+			w.stopLivenessProbe()
 		}
 
 		// Update UI on main thread
 		w.statusDisplay.SetState(displayState)
 
+		// The helper reports its always-on profile along with each state change
+		glib.IdleAdd(w.syncAlwaysOn)
+
 		// Update connect button state
 		w.updateConnectButton(displayState)
 
@@ -292,6 +442,8 @@ func (w *MainWindow) setupCallbacks() {
 		}
 
 		// Send notifications
+		// A notice set for a disconnect applies to the state change that follows it
+		notice := w.takeDisconnectNotice()
 		if w.deps.Notifier != nil {
 			if profileName == "" {
 				profileName = "VPN"
@@ -300,7 +452,11 @@ func (w *MainWindow) setupCallbacks() {
 			case vpn.StateConnected:
 				w.deps.Notifier.NotifyConnected(profileName)
 			case vpn.StateDisconnected:
-				w.deps.Notifier.NotifyDisconnected(profileName)
+				if notice != nil {
+					w.deps.Notifier.Notify(*notice, profileName)
+				} else {
+					w.deps.Notifier.NotifyDisconnected(profileName)
+				}
 			case vpn.StateFailed:
 				w.deps.Notifier.NotifyConnectionFailed(profileName)
 			case vpn.StateReconnecting:
@@ -313,10 +469,12 @@ func (w *MainWindow) setupCallbacks() {
 		switch newState {
 		case vpn.StateConnected:
 			w.statsDisplay.SetVisible(true)
+			w.startIdleWatch()
 			w.startStatsCollector()
 		case vpn.StateDisconnected, vpn.StateFailed:
 			w.statsDisplay.SetVisible(false)
 			w.stopStatsCollector()
+			w.stopIdleWatch()
 		}
 	})
 
@@ -325,9 +483,23 @@ func (w *MainWindow) setupCallbacks() {
 		w.logDialog.AppendLog(line)
 	})
 
-	// VPN error callback
+	// VPN error callback - classified errors get an actionable title and hint
 	w.deps.VPNController.OnError(func(err error) {
-		w.showError("VPN Error", err.Error())
+		var connErr *vpn.ConnectionError
+		if !errors.As(err, &connErr) {
+			w.showError("VPN Error", err.Error())
+			return
+		}
+
+		if w.deps.ReconnectManager != nil {
+			w.deps.ReconnectManager.OnConnectionError(connErr.Code)
+		}
+
+		message := connErr.Message
+		if hint := connErr.Code.Hint(); hint != "" {
+			message = hint + "\n\n" + connErr.Message
+		}
+		w.showError(connErr.Code.Title(), message)
 	})
 
 	// VPN event callback for IP assignment and SAML authentication
@@ -336,7 +508,12 @@ func (w *MainWindow) setupCallbacks() {
 		case vpn.EventGotIP:
 			if ip := event.GetData("ip"); ip != "" {
 				w.statusDisplay.SetAssignedIP(ip)
+				w.hooks.setAddresses(event)
 			}
+		case vpn.EventPhase:
+			w.statusDisplay.SetPhase(vpn.Phase(event.GetData(vpn.DataKeyPhase)))
+		case vpn.EventGateway:
+			w.statusDisplay.SetGateway(net.JoinHostPort(event.GetData(vpn.DataKeyGatewayHost), event.GetData(vpn.DataKeyGatewayPort)))
 		case vpn.EventAuthenticate:
 			// Open browser for SAML/web authentication
 			if url := event.GetData("url"); url != "" {
@@ -346,6 +523,212 @@ func (w *MainWindow) setupCallbacks() {
 	})
 }
 
+// saveProfile saves the profile from the editor and updates the list.
+// If the profile changed on disk since it was loaded, the user decides which version to keep.
+func (w *MainWindow) saveProfile(p *profile.Profile) error {
+	existing := w.profileList.GetProfileByID(p.ID)
+
+	// Profiles based on a template would lose their inherited settings
+	if existing != nil && existing.IsTemplate && !p.IsTemplate {
+		if children := profile.Children(w.profileList.Profiles(), p.ID); len(children) > 0 {
+			w.showError("Template In Use",
+				fmt.Sprintf("%d profile(s) are based on this template. Change or remove their template first.", len(children)))
+			return fmt.Errorf("template %s is in use", p.ID)
+		}
+	}
+
+	if err := w.deps.ProfileStore.Save(p); err != nil {
+		if errors.Is(err, profile.ErrStoreConflict) {
+			w.showSaveConflictDialog(p)
+		} else {
+			w.showError("Error Saving Profile", err.Error())
+		}
+		return err
+	}
+	w.profileEditor.SetRevision(p.ID, p.Revision)
+
+	// Check if this is a new profile (not in list yet)
+	if existing == nil || p.IsTemplate || existing.IsTemplate {
+		// New profile or template change - refresh the list so profiles
+		// based on the template and the template choices are up to date
+		w.loadProfiles()
+		w.profileList.SelectProfile(p.ID)
+	} else {
+		// Existing profile - just update the display
+		w.profileList.UpdateProfile(p)
+		w.refreshTrayProfiles()
+		w.updateSchedules()
+	}
+
+	// Keep selected profile reference in sync
+	w.selectedProfile = p
+	return nil
+}
+
+// showSaveConflictDialog asks whether to overwrite a profile that was changed on disk
+// since it was loaded, or to discard the edits and show the stored version.
+func (w *MainWindow) showSaveConflictDialog(p *profile.Profile) {
+	dialog := adw.NewAlertDialog("Profile Changed on Disk", "")
+	dialog.SetBody(fmt.Sprintf("\"%s\" was changed by another program since you opened it. Overwrite it with your changes, or discard them and load the stored version?", p.Name))
+	dialog.AddResponse("cancel", "Cancel")
+	dialog.AddResponse("discard", "Discard My Changes")
+	dialog.AddResponse("overwrite", "Overwrite")
+	dialog.SetResponseAppearance("discard", adw.ResponseDestructive)
+	dialog.SetDefaultResponse("cancel")
+	dialog.SetCloseResponse("cancel")
+
+	dialog.ConnectResponse(func(response string) {
+		switch response {
+		case "discard":
+			w.reloadProfile(p.ID)
+		case "overwrite":
+			// Saving against the current revision replaces the stored version
+			stored, err := w.deps.ProfileStore.Load(p.ID)
+			switch {
+			case err == nil:
+				p.Revision = stored.Revision
+			case errors.Is(err, profile.ErrStoreNotFound):
+				p.Revision = ""
+			default:
+				w.showError("Error Saving Profile", err.Error())
+				return
+			}
+			if err := w.saveProfile(p); err == nil {
+				w.profileEditor.SetProfile(p)
+			}
+		}
+	})
+
+	dialog.Present(w.window)
+}
+
+// reloadProfile shows the stored version of a profile, dropping unsaved edits.
+// A profile that no longer exists is deselected.
+func (w *MainWindow) reloadProfile(id string) {
+	w.refreshProfiles()
+
+	stored := w.profileList.GetProfileByID(id)
+	if stored == nil {
+		w.clearSelectedProfile()
+		return
+	}
+
+	w.selectedProfile = stored
+	w.profileEditor.SetProfile(stored)
+	w.updateStatusForProfile(stored)
+	if w.deps.Tray != nil {
+		w.deps.Tray.SetProfileName(stored.Name)
+	}
+}
+
+// ApplyProfileChanges updates the window for profiles added, changed, or removed
+// on disk by another program. Unsaved edits are never dropped: the edited profile
+// is only reloaded or deselected when the editor has no changes, otherwise the
+// next save reports the conflict.
+func (w *MainWindow) ApplyProfileChanges(events []profile.WatchEvent) {
+	w.refreshProfiles()
+
+	for _, event := range events {
+		slog.Info("Profile changed on disk", "profile_id", event.ID, "change", event.Type)
+
+		if w.selectedProfile == nil || w.selectedProfile.ID != event.ID || w.profileEditor.IsDirty() {
+			continue
+		}
+		switch event.Type {
+		case profile.WatchChanged:
+			w.reloadProfile(event.ID)
+		case profile.WatchRemoved:
+			w.clearSelectedProfile()
+		}
+	}
+}
+
+// refreshProfiles reloads the profile list from the store, keeping the selection.
+// Unlike loadProfiles it never creates or selects a profile.
+func (w *MainWindow) refreshProfiles() {
+	result, err := w.deps.ProfileStore.List()
+	if err != nil {
+		slog.Warn("Failed to refresh profiles", "error", err)
+		return
+	}
+	for _, listErr := range result.Errors {
+		slog.Warn("Failed to load profile", "profile_id", listErr.ProfileID, "error", listErr.Err)
+	}
+
+	w.profileList.SetProfiles(result.Profiles)
+	w.profileEditor.SetTemplates(profile.Templates(result.Profiles))
+	w.refreshTrayProfiles()
+	w.updateSchedules()
+}
+
+// clearSelectedProfile deselects the current profile and clears it from the status display and tray.
+func (w *MainWindow) clearSelectedProfile() {
+	w.selectedProfile = nil
+	w.profileEditor.SetProfile(nil)
+	w.statusDisplay.SetProfileInfo("")
+	if w.deps.Tray != nil {
+		w.deps.Tray.SetProfileName("")
+	}
+}
+
+// toggleFavorite pins or unpins a profile and saves it.
+func (w *MainWindow) toggleFavorite(p *profile.Profile) {
+	p.Favorite = !p.Favorite
+	if err := w.deps.ProfileStore.Save(p); err != nil {
+		p.Favorite = !p.Favorite
+		w.showError("Error Saving Profile", err.Error())
+		return
+	}
+
+	// The editor keeps its own copy; keep its favorite state in sync so a later
+	// save from the editor does not revert the change
+	w.profileEditor.SetFavorite(p.ID, p.Favorite)
+	w.profileEditor.SetRevision(p.ID, p.Revision)
+	w.profileList.UpdateProfile(p)
+	w.refreshTrayProfiles()
+}
+
+// sortOrder returns the configured profile sort order.
+func (w *MainWindow) sortOrder() profile.SortOrder {
+	if w.deps.ConfigManager == nil {
+		return profile.SortByName
+	}
+	if order := w.deps.ConfigManager.GetConfig().ProfileSortOrder; order != "" {
+		return order
+	}
+	return profile.SortByName
+}
+
+// refreshTrayProfiles updates the tray's profile menu from the profile list.
+func (w *MainWindow) refreshTrayProfiles() {
+	if w.deps.Tray != nil {
+		w.deps.Tray.SetProfiles(w.profileList.Profiles(), w.sortOrder())
+	}
+}
+
+// updateSchedules hands the schedules of the listed profiles, including inherited
+// ones, to the scheduler.
+func (w *MainWindow) updateSchedules() {
+	if w.deps.Scheduler == nil {
+		return
+	}
+	schedules := make(map[string]*profile.Schedule)
+	for _, p := range w.profileList.Profiles() {
+		if p.IsTemplate {
+			continue
+		}
+		resolved, err := profile.Resolve(p, w.deps.ProfileStore)
+		if err != nil {
+			slog.Warn("Failed to resolve profile schedule", "profile", p.Name, "error", err)
+			continue
+		}
+		if resolved.Schedule != nil {
+			schedules[p.ID] = resolved.Schedule
+		}
+	}
+	w.deps.Scheduler.SetSchedules(schedules)
+}
+
 // loadProfiles loads all profiles from the store and populates the list.
 func (w *MainWindow) loadProfiles() {
 	result, err := w.deps.ProfileStore.List()
@@ -355,13 +738,26 @@ func (w *MainWindow) loadProfiles() {
 	}
 
 	// Log any partial load errors (corrupted or unreadable profile files)
-	if len(result.Errors) > 0 {
-		for _, listErr := range result.Errors {
-			slog.Warn("Failed to load profile", "profile_id", listErr.ProfileID, "error", listErr.Err)
+	var newerProfiles []string
+	for _, listErr := range result.Errors {
+		slog.Warn("Failed to load profile", "profile_id", listErr.ProfileID, "error", listErr.Err)
+		if errors.Is(listErr.Err, migrate.ErrNewerVersion) {
+			newerProfiles = append(newerProfiles, listErr.ProfileID)
 		}
 	}
 
+	// Profiles from a newer version are skipped rather than loaded with missing settings
+	if len(newerProfiles) > 0 {
+		w.showError("Profiles From a Newer Version",
+			fmt.Sprintf("%d profile(s) were saved by a newer version of openfortivpn-gui and were not loaded. Update the application to use them.",
+				len(newerProfiles)))
+	}
+
+	w.profileList.SetSortOrder(w.sortOrder())
 	w.profileList.SetProfiles(result.Profiles)
+	w.profileEditor.SetTemplates(profile.Templates(result.Profiles))
+	w.refreshTrayProfiles()
+	w.updateSchedules()
 
 	if len(result.Profiles) == 0 {
 		// No profiles - auto-create a new one for first-time users
@@ -419,6 +815,13 @@ func (w *MainWindow) onAddProfile() {
 
 // onDeleteProfile handles profile deletion.
 func (w *MainWindow) onDeleteProfile(p *profile.Profile) {
+	// Deleting a template would leave the profiles based on it without their settings
+	if children := profile.Children(w.profileList.Profiles(), p.ID); len(children) > 0 {
+		w.showError("Template In Use",
+			fmt.Sprintf("%d profile(s) are based on \"%s\". Change or remove their template before deleting it.", len(children), p.Name))
+		return
+	}
+
 	// Show confirmation dialog
 	dialog := adw.NewAlertDialog("Delete Profile?", "")
 	dialog.SetBody("Are you sure you want to delete the profile \"" + p.Name + "\"? This action cannot be undone.")
@@ -437,6 +840,279 @@ func (w *MainWindow) onDeleteProfile(p *profile.Profile) {
 	dialog.Present(w.window)
 }
 
+// ShowImportDialog asks for a configuration file and imports the profiles it contains.
+func (w *MainWindow) ShowImportDialog() {
+	fileDialog := gtk.NewFileDialog()
+	fileDialog.SetTitle("Import Profiles")
+	fileDialog.SetModal(true)
+	fileDialog.SetFilters(importFileFilters())
+
+	fileDialog.Open(context.Background(), &w.window.Window, func(res gio.AsyncResulter) {
+		file, err := fileDialog.OpenFinish(res)
+		if err != nil {
+			// Dismissing the file chooser is reported as an error
+			slog.Debug("Import file selection cancelled", "error", err)
+			return
+		}
+		w.importFromFile(file.Path())
+	})
+}
+
+// ShowNetworkManagerImportDialog asks for a directory and imports every
+// NetworkManager-fortisslvpn connection keyfile found in it.
+func (w *MainWindow) ShowNetworkManagerImportDialog() {
+	fileDialog := gtk.NewFileDialog()
+	fileDialog.SetTitle("Import NetworkManager Connections")
+	fileDialog.SetModal(true)
+	fileDialog.SetInitialFolder(gio.NewFileForPath(profile.NetworkManagerSystemConnectionsDir))
+
+	fileDialog.SelectFolder(context.Background(), &w.window.Window, func(res gio.AsyncResulter) {
+		folder, err := fileDialog.SelectFolderFinish(res)
+		if err != nil {
+			// Dismissing the file chooser is reported as an error
+			slog.Debug("Import directory selection cancelled", "error", err)
+			return
+		}
+		result, err := profile.ImportNetworkManagerDir(folder.Path())
+		w.presentImportResult(result, err)
+	})
+}
+
+// importFromFile parses a configuration file and lets the user pick the profiles to save.
+func (w *MainWindow) importFromFile(path string) {
+	result, err := loadImportFile(path)
+	w.presentImportResult(result, err)
+}
+
+// presentImportResult shows the import dialog for the result, or the error if loading failed.
+func (w *MainWindow) presentImportResult(result *profile.ImportResult, err error) {
+	if err != nil {
+		w.showError("Import Failed", err.Error())
+		return
+	}
+
+	dialog := NewImportDialog(result)
+	dialog.OnImport(w.saveImportedProfiles)
+	dialog.Present(w.window)
+}
+
+// saveImportedProfiles persists the selected imported profiles and selects the first one.
+func (w *MainWindow) saveImportedProfiles(profiles []*profile.Profile) {
+	var saved []*profile.Profile
+	var failures []string
+	for _, p := range profiles {
+		if err := w.deps.ProfileStore.Save(p); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", p.Name, err))
+			continue
+		}
+		saved = append(saved, p)
+	}
+
+	if len(saved) > 0 {
+		w.loadProfiles()
+		w.profileList.SelectProfile(saved[0].ID)
+	}
+
+	if len(failures) > 0 {
+		w.showError("Some Profiles Were Not Imported", strings.Join(failures, "\n"))
+	}
+}
+
+// ShowExportDialog asks for a destination file and exports the selected profile to it.
+// Passwords are never exported.
+func (w *MainWindow) ShowExportDialog() {
+	p := w.selectedProfile
+	if p == nil || w.profileList.GetProfileByID(p.ID) == nil {
+		w.showError("Export Failed", "Select a saved profile to export.")
+		return
+	}
+
+	fileDialog := gtk.NewFileDialog()
+	fileDialog.SetTitle("Export Profile")
+	fileDialog.SetModal(true)
+	fileDialog.SetFilters(exportFileFilters())
+	fileDialog.SetInitialName(exportFileName(p, &exportFormats[0]))
+
+	fileDialog.Save(context.Background(), &w.window.Window, func(res gio.AsyncResulter) {
+		file, err := fileDialog.SaveFinish(res)
+		if err != nil {
+			// Dismissing the file chooser is reported as an error
+			slog.Debug("Export file selection cancelled", "error", err)
+			return
+		}
+
+		// Exported files are standalone: inherited settings are written out in full
+		exported, err := profile.Resolve(p, w.deps.ProfileStore)
+		if err != nil {
+			w.showError("Export Failed", err.Error())
+			return
+		}
+		exported.ParentID = ""
+		exported.Overrides = nil
+
+		path := file.Path()
+		if err := findExportFormat(path).save(path, exported); err != nil {
+			w.showError("Export Failed", err.Error())
+			return
+		}
+		slog.Info("Profile exported", "profile_id", p.ID, "path", path)
+	})
+}
+
+// ShowBackupDialog asks for a passphrase and a destination file and backs up all
+// profiles, the settings, and optionally the saved passwords to it.
+func (w *MainWindow) ShowBackupDialog() {
+	dialog := NewBackupDialog()
+	dialog.OnBackup(func(opts BackupOptions) {
+		fileDialog := gtk.NewFileDialog()
+		fileDialog.SetTitle("Back Up Profiles")
+		fileDialog.SetModal(true)
+		fileDialog.SetFilters(backupFileFilters())
+		fileDialog.SetInitialName("openfortivpn-gui-" + time.Now().Format("2006-01-02") + backup.FileExtension)
+
+		fileDialog.Save(context.Background(), &w.window.Window, func(res gio.AsyncResulter) {
+			file, err := fileDialog.SaveFinish(res)
+			if err != nil {
+				// Dismissing the file chooser is reported as an error
+				slog.Debug("Backup file selection cancelled", "error", err)
+				return
+			}
+			path := file.Path()
+
+			// Key derivation is deliberately slow, so it runs off the main loop
+			go func() {
+				listErrs, err := w.writeBackup(path, opts)
+				glib.IdleAdd(func() {
+					if err != nil {
+						w.showError("Backup Failed", err.Error())
+						return
+					}
+					slog.Info("Backup created", "path", path, "include_secrets", opts.IncludeSecrets)
+					if len(listErrs) > 0 {
+						w.showError("Some Profiles Were Not Backed Up",
+							fmt.Sprintf("%d profile(s) could not be read and are missing from the backup.", len(listErrs)))
+					}
+				})
+			}()
+		})
+	})
+	dialog.Present(w.window)
+}
+
+// writeBackup collects the profiles, settings, and optionally the saved passwords
+// and writes them to path, encrypted with the passphrase.
+// It returns the profiles that could not be read and were left out.
+func (w *MainWindow) writeBackup(path string, opts BackupOptions) ([]profile.ListError, error) {
+	var cfg *config.Config
+	if w.deps.ConfigManager != nil {
+		cfg = w.deps.ConfigManager.GetConfig()
+	}
+
+	archive, listErrs, err := backup.Collect(w.deps.ProfileStore, cfg, w.deps.KeyringStore, opts.IncludeSecrets)
+	if err != nil {
+		return nil, err
+	}
+	data, err := backup.Seal(archive, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	// The archive may hold passwords; keep it private like the keyring would
+	if err := fileutil.AtomicWrite(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	return listErrs, nil
+}
+
+// ShowRestoreDialog asks for a backup file and its passphrase and restores the
+// profiles, and optionally the settings, it contains.
+func (w *MainWindow) ShowRestoreDialog() {
+	fileDialog := gtk.NewFileDialog()
+	fileDialog.SetTitle("Restore Backup")
+	fileDialog.SetModal(true)
+	fileDialog.SetFilters(backupFileFilters())
+
+	fileDialog.Open(context.Background(), &w.window.Window, func(res gio.AsyncResulter) {
+		file, err := fileDialog.OpenFinish(res)
+		if err != nil {
+			// Dismissing the file chooser is reported as an error
+			slog.Debug("Backup file selection cancelled", "error", err)
+			return
+		}
+		path := file.Path()
+
+		dialog := NewRestoreDialog(filepath.Base(path))
+		dialog.OnRestore(func(opts RestoreOptions) {
+			// Key derivation is deliberately slow, so it runs off the main loop
+			go func() {
+				result, err := w.restoreBackup(path, opts)
+				glib.IdleAdd(func() {
+					w.presentRestoreResult(result, err)
+				})
+			}()
+		})
+		dialog.Present(w.window)
+	})
+}
+
+// restoreBackup decrypts the backup at path and restores it.
+func (w *MainWindow) restoreBackup(path string, opts RestoreOptions) (*backup.RestoreResult, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path chosen by the user in the file dialog
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	archive, err := backup.Open(data, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg backup.ConfigUpdater
+	if w.deps.ConfigManager != nil {
+		cfg = w.deps.ConfigManager
+	}
+	return backup.Restore(archive, w.deps.ProfileStore, w.deps.KeyringStore, cfg, opts.RestoreOptions)
+}
+
+// presentRestoreResult refreshes the window after a restore and summarizes what was restored.
+func (w *MainWindow) presentRestoreResult(result *backup.RestoreResult, err error) {
+	if err != nil {
+		w.showError("Restore Failed", err.Error())
+		return
+	}
+
+	w.loadProfiles()
+	// A replaced profile shown in the editor is reloaded unless it has unsaved changes
+	for _, p := range result.Restored {
+		if w.selectedProfile != nil && w.selectedProfile.ID == p.ID && !w.profileEditor.IsDirty() {
+			w.reloadProfile(p.ID)
+		}
+	}
+
+	lines := []string{fmt.Sprintf("%d profile(s) restored.", len(result.Restored))}
+	if len(result.Renamed) > 0 {
+		lines = append(lines, fmt.Sprintf("%d profile(s) were kept alongside existing ones and marked “Restored”.", len(result.Renamed)))
+	}
+	if result.SecretsRestored > 0 {
+		lines = append(lines, fmt.Sprintf("%d saved password(s) restored.", result.SecretsRestored))
+	}
+	if result.ConfigRestored {
+		lines = append(lines, "Settings restored.")
+	}
+	for _, s := range result.Skipped {
+		lines = append(lines, fmt.Sprintf("Skipped %s: %v", s.Name, s.Err))
+	}
+	for _, e := range result.Errors {
+		lines = append(lines, e.Error())
+	}
+	slog.Info("Backup restored", "restored", len(result.Restored), "renamed", len(result.Renamed),
+		"skipped", len(result.Skipped), "secrets", result.SecretsRestored, "config", result.ConfigRestored)
+
+	title := "Backup Restored"
+	if len(result.Skipped) > 0 || len(result.Errors) > 0 {
+		title = "Backup Partially Restored"
+	}
+	w.showError(title, strings.Join(lines, "\n"))
+}
+
 // performDeleteProfile actually deletes the profile after confirmation.
 func (w *MainWindow) performDeleteProfile(p *profile.Profile) {
 	// Delete password from keyring
@@ -452,13 +1128,7 @@ func (w *MainWindow) performDeleteProfile(p *profile.Profile) {
 
 	// Clear selection if this was the selected profile
 	if w.selectedProfile != nil && w.selectedProfile.ID == p.ID {
-		w.selectedProfile = nil
-		w.profileEditor.SetProfile(nil)
-		// Clear profile name from status display and tray
-		w.statusDisplay.SetProfileInfo("")
-		if w.deps.Tray != nil {
-			w.deps.Tray.SetProfileName("")
-		}
+		w.clearSelectedProfile()
 	}
 
 	// Refresh the list
@@ -467,6 +1137,12 @@ func (w *MainWindow) performDeleteProfile(p *profile.Profile) {
 
 // onConnectClicked handles the connect/disconnect button click.
 func (w *MainWindow) onConnectClicked() {
+	// While waiting for a reconnect the button reads "Disconnect" but the tunnel is already down
+	if w.deps.ReconnectManager != nil && w.deps.ReconnectManager.IsPending() {
+		w.cancelReconnect()
+		return
+	}
+
 	state := w.deps.VPNController.GetState()
 
 	if state.CanDisconnect() {
@@ -478,6 +1154,10 @@ func (w *MainWindow) onConnectClicked() {
 
 // connect initiates a VPN connection with the selected profile.
 func (w *MainWindow) connect() {
+	if w.alwaysOn != nil {
+		slog.Info("Not connecting: the always-on VPN is managed by the system", "profile", w.alwaysOn.Name)
+		return
+	}
 	if w.selectedProfile == nil {
 		w.showError("No Profile Selected", "Please select a profile to connect.")
 		return
@@ -490,17 +1170,44 @@ func (w *MainWindow) connect() {
 		return
 	}
 
+	if currentProfile.IsTemplate {
+		w.showError("Cannot Connect", "Templates only provide settings for other profiles. Connect with a profile based on this template instead.")
+		return
+	}
+
+	// Fill in settings inherited from the template before validating
+	resolvedProfile, err := profile.Resolve(currentProfile, w.deps.ProfileStore)
+	if err != nil {
+		w.showError("Invalid Profile", err.Error())
+		return
+	}
+
 	// Validate the profile before attempting connection
-	if err := currentProfile.Validate(); err != nil {
+	if err := resolvedProfile.Validate(); err != nil {
 		w.showError("Validation Error", err.Error())
 		return
 	}
 
-	// Save any changes to the profile
+	// Connecting without it would leak the traffic the kill switch should block
+	if _, ok := w.deps.VPNController.(vpn.KillSwitchController); resolvedProfile.KillSwitch != nil && !ok {
+		w.showError("Kill Switch Unavailable", "The kill switch requires the helper daemon. Install and start openfortivpn-gui-helper, or turn off the kill switch for this profile.")
+		return
+	}
+
+	// Save any changes to the profile; inherited settings are not stored
 	if err := w.deps.ProfileStore.Save(currentProfile); err != nil {
-		w.showError("Error Saving Profile", err.Error())
+		if errors.Is(err, profile.ErrStoreConflict) {
+			w.showSaveConflictDialog(currentProfile)
+		} else {
+			w.showError("Error Saving Profile", err.Error())
+		}
 		return
 	}
+	w.profileEditor.SetRevision(currentProfile.ID, currentProfile.Revision)
+	if listed := w.profileList.GetProfileByID(currentProfile.ID); listed != nil {
+		listed.Revision = currentProfile.Revision
+	}
+	currentProfile = resolvedProfile
 
 	// Notify that we're connecting to this profile (for auto-connect tracking)
 	if w.onProfileConnecting != nil {
@@ -579,39 +1286,621 @@ func (w *MainWindow) showOTPDialog(p *profile.Profile, password string) {
 	})
 }
 
+// testConnection checks that the profile's gateways are reachable without connecting.
+// The diagnostics run in the background and the results are shown in a dialog.
+func (w *MainWindow) testConnection(p *profile.Profile) {
+	if p.IsTemplate {
+		w.showError("Cannot Test Connection", "Templates only provide settings for other profiles. Test a profile based on this template instead.")
+		return
+	}
+
+	resolved, err := profile.Resolve(p, w.deps.ProfileStore)
+	if err != nil {
+		w.showError("Invalid Profile", err.Error())
+		return
+	}
+	if err := resolved.Validate(); err != nil {
+		w.showError("Validation Error", err.Error())
+		return
+	}
+
+	ctx := w.deps.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	w.profileEditor.SetTesting(true)
+	go func() {
+		reports := diagnose.NewDiagnoser().Run(ctx, resolved)
+		glib.IdleAdd(func() {
+			w.profileEditor.SetTesting(false)
+			ShowDiagnosticsDialog(w.window, reports)
+		})
+	}()
+}
+
 // doConnect performs the actual VPN connection.
+// Profiles with backup gateways are probed first, off the main thread,
+// so the connection starts with the gateway chosen by the profile's selection mode.
 func (w *MainWindow) doConnect(p *profile.Profile, opts *vpn.ConnectOptions) {
 	// Clear previous logs
 	w.logDialog.Clear()
 
+	// Prevent a second attempt while the pre-connect hook runs
+	w.connectButton.SetSensitive(false)
+	w.hooks.beforeConnect(p, func(err error) {
+		w.connectButton.SetSensitive(true)
+		if err != nil {
+			w.showError("Pre-Connect Hook Failed", err.Error()+"\n\nSee the connection log for its output.")
+			return
+		}
+		w.connectWithGateway(p, opts)
+	})
+}
+
+// connectWithGateway picks the gateway to use, probing them if configured, and connects.
+func (w *MainWindow) connectWithGateway(p *profile.Profile, opts *vpn.ConnectOptions) {
+	// Use app-level context for VPN connection (cancelled on app shutdown)
+	ctx := w.deps.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if w.deps.GatewayProber == nil || len(p.Endpoints()) < 2 {
+		w.startConnection(ctx, p, opts)
+		return
+	}
+
+	// Prevent a second attempt while the gateways are probed
+	w.connectButton.SetSensitive(false)
+	go func() {
+		selected := vpn.SelectGateway(ctx, p, w.deps.GatewayProber)
+		glib.IdleAdd(func() {
+			w.connectButton.SetSensitive(true)
+			w.startConnection(ctx, selected, opts)
+		})
+	}()
+}
+
+// startConnection stores the profile for reconnects and starts the VPN connection.
+func (w *MainWindow) startConnection(ctx context.Context, p *profile.Profile, opts *vpn.ConnectOptions) {
 	// Store profile for potential reconnect
 	if w.deps.ReconnectManager != nil {
 		w.deps.ReconnectManager.StoreConnectedProfile(p)
 	}
+	w.hooks.begin(p)
+
+	if err := w.deps.VPNController.Connect(ctx, p, opts); err != nil {
+		w.showError("Connection Error", err.Error())
+	}
+}
+
+// cancelReconnect stops a pending automatic reconnect and shows the actual connection state.
+// The kill switch of the dropped connection is removed, since nothing reconnects it anymore.
+func (w *MainWindow) cancelReconnect() {
+	if w.deps.ReconnectManager != nil {
+		w.deps.ReconnectManager.Cancel()
+	}
+	state := w.deps.VPNController.GetState()
+	if p := w.hooks.currentProfile(); p != nil && p.KillSwitch != nil && !state.CanDisconnect() {
+		if err := w.releaseKillSwitch(); err != nil {
+			slog.Error("Failed to remove kill switch", "error", err)
+			w.logDialog.AppendLog(fmt.Sprintf("Failed to remove kill switch: %v", err))
+		}
+	}
+	w.showReconnectState(state)
+}
+
+// releaseKillSwitch removes the kill switch while no tunnel is active.
+// It does nothing for controllers without kill switch support.
+func (w *MainWindow) releaseKillSwitch() error {
+	ks, ok := w.deps.VPNController.(vpn.KillSwitchController)
+	if !ok {
+		return nil
+	}
+	return ks.ReleaseKillSwitch(context.Background())
+}
+
+// removeKillSwitch lets traffic outside the tunnel through again on the user's
+// request. An active or reconnecting tunnel is disconnected first, since the
+// kill switch protects it.
+func (w *MainWindow) removeKillSwitch() {
+	if (w.deps.ReconnectManager != nil && w.deps.ReconnectManager.IsPending()) || w.deps.VPNController.GetState().CanDisconnect() {
+		w.triggerDisconnect()
+		return
+	}
+	if err := w.releaseKillSwitch(); err != nil {
+		w.showError("Kill Switch Error", err.Error())
+		return
+	}
+	w.logDialog.AppendLog("Kill switch removed")
+}
+
+// closeForReconnect closes the tunnel to reconnect it, keeping the kill switch
+// in place so no traffic leaks while the tunnel is down.
+func (w *MainWindow) closeForReconnect() error {
+	if ks, ok := w.deps.VPNController.(vpn.KillSwitchController); ok {
+		return ks.DisconnectForReconnect(context.Background())
+	}
+	return w.deps.VPNController.Disconnect(context.Background())
+}
+
+// showReconnectState shows a state the reconnect manager entered between connection attempts.
+func (w *MainWindow) showReconnectState(state vpn.ConnectionState) {
+	w.statusDisplay.SetState(state)
+	w.updateConnectButton(state)
+	if w.deps.Tray != nil {
+		w.deps.Tray.SetState(state)
+	}
+}
+
+// onUplinkChanged tells the reconnect manager whether a network is available,
+// applies the network rules and cycles a tunnel whose uplink was replaced.
+// It is called from a background goroutine.
+func (w *MainWindow) onUplinkChanged(from, to netmon.Uplink) {
+	if w.deps.ReconnectManager != nil {
+		w.deps.ReconnectManager.SetNetworkAvailable(to.Available())
+	}
+
+	rule := w.matchNetworkRule(to)
+	stayOff := rule != nil && rule.Action == netrules.ActionDisconnect
+	if stayOff && w.deps.ReconnectManager != nil {
+		// Settled before a reconnect, such as one resuming from sleep, can start
+		w.deps.ReconnectManager.Cancel()
+	}
+
+	glib.IdleAdd(func() {
+		// Losing or regaining the only uplink is handled by the tunnel dropping and waiting
+		if !stayOff && w.deps.ReconnectManager != nil && from.Available() && to.Available() {
+			w.cycleConnection(from, to)
+		}
+		if rule != nil {
+			w.applyNetworkRule(rule)
+		}
+	})
+}
+
+// matchNetworkRule returns the first network rule matching the network behind
+// the uplink, or nil if none does. Detecting the network may take a few seconds,
+// so it is called from a background goroutine.
+func (w *MainWindow) matchNetworkRule(uplink netmon.Uplink) *netrules.Rule {
+	if w.deps.ConfigManager == nil {
+		return nil
+	}
+	rules := w.deps.ConfigManager.GetConfig().NetworkRules
+	if len(rules) == 0 || !uplink.Available() {
+		return nil
+	}
 
-	// Use app-level context for VPN connection (cancelled on app shutdown)
 	ctx := w.deps.Ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	rule := netrules.Select(ctx, rules, netrules.Detect(ctx, uplink))
+	if rule == nil {
+		slog.Debug("No network rule matches", "uplink", uplink)
+		return nil
+	}
+	slog.Info("Network rule matches", "rule", rule, "action", rule.Action, "uplink", uplink)
+	return rule
+}
 
-	if err := w.deps.VPNController.Connect(ctx, p, opts); err != nil {
-		w.showError("Connection Error", err.Error())
+// currentUplink returns the network uplink, as of the last change when it is monitored.
+func (w *MainWindow) currentUplink() netmon.Uplink {
+	if w.deps.NetworkMonitor != nil {
+		return w.deps.NetworkMonitor.Current()
+	}
+	uplink, err := netmon.ReadUplink()
+	if err != nil {
+		slog.Warn("Failed to read network uplink", "error", err)
+	}
+	return uplink
+}
+
+// applyNetworkRule connects or disconnects as the matching network rule asks.
+// A disconnect is not reconnected automatically, and a connect leaves an active
+// or reconnecting connection alone.
+func (w *MainWindow) applyNetworkRule(rule *netrules.Rule) {
+	state := w.deps.VPNController.GetState()
+	pending := w.deps.ReconnectManager != nil && w.deps.ReconnectManager.IsPending()
+
+	switch rule.Action {
+	case netrules.ActionDisconnect:
+		// Also shows the actual state if a pending reconnect was cancelled when the rule matched
+		if w.deps.ReconnectManager != nil {
+			w.cancelReconnect()
+		}
+		if !state.CanDisconnect() {
+			return
+		}
+		w.logDialog.AppendLog(fmt.Sprintf("On %s, disconnecting", rule))
+		w.disconnect()
+	case netrules.ActionConnect:
+		if pending || !state.CanConnect() {
+			return
+		}
+		w.logDialog.AppendLog(fmt.Sprintf("On %s, connecting", rule))
+		w.triggerConnectProfile(rule.ProfileID)
+	}
+}
+
+// cycleConnection reconnects right away after the uplink changed under an
+// established tunnel, whose traffic may still be bound to the previous network.
+// Profiles that do not reconnect automatically are left alone.
+func (w *MainWindow) cycleConnection(from, to netmon.Uplink) {
+	if w.deps.VPNController.GetState() != vpn.StateConnected || !w.deps.ReconnectManager.PrepareCycle() {
+		return
+	}
+
+	slog.Info("Network uplink changed under the tunnel, reconnecting", "old", from, "new", to)
+	w.logDialog.AppendLog(fmt.Sprintf("Network changed from %s to %s, reconnecting", from, to))
+	if err := w.closeForReconnect(); err != nil {
+		slog.Error("Failed to disconnect stale tunnel", "error", err)
+		w.deps.ReconnectManager.Cancel()
+	}
+}
+
+// prepareForSleep closes the tunnel before the system sleeps, so no stale ppp
+// interface survives the suspend. The disconnect does not count as a dropped
+// connection and the tunnel is restored after resuming. Suspending waits for
+// release until the tunnel is down.
+func (w *MainWindow) prepareForSleep(release func()) {
+	state := w.deps.VPNController.GetState()
+	if w.deps.ReconnectManager != nil {
+		w.deps.ReconnectManager.PrepareForSleep(state.CanDisconnect())
+		// A pending attempt was cancelled
+		w.showReconnectState(state)
+	}
+	if !state.CanDisconnect() {
+		release()
+		return
+	}
+
+	w.sleepMu.Lock()
+	w.sleepRelease = release
+	w.sleepMu.Unlock()
+
+	slog.Info("Disconnecting before the system sleeps")
+	w.logDialog.AppendLog("System is going to sleep, disconnecting")
+	w.hooks.beforeDisconnect(func() {
+		if err := w.closeForReconnect(); err != nil {
+			slog.Error("Failed to disconnect before sleep", "error", err)
+			w.releaseSleep()
+		}
+	})
+}
+
+// releaseSleep lets a suspend waiting for the tunnel to close go ahead.
+// It is called from any goroutine.
+func (w *MainWindow) releaseSleep() {
+	w.sleepMu.Lock()
+	release := w.sleepRelease
+	w.sleepRelease = nil
+	w.sleepMu.Unlock()
+
+	if release != nil {
+		release()
+	}
+}
+
+// resumeFromSleep restores the tunnel closed before sleeping, once the network is back.
+// It is called from a background goroutine.
+func (w *MainWindow) resumeFromSleep() {
+	if w.deps.ReconnectManager == nil {
+		return
+	}
+	// The uplink usually changed while asleep without the monitor noticing yet
+	if w.deps.NetworkMonitor != nil {
+		w.deps.NetworkMonitor.Refresh()
+	}
+	// Windows that started or ended while asleep decide whether to restore the tunnel
+	if w.deps.Scheduler != nil {
+		w.deps.Scheduler.Reevaluate()
+	}
+	if !w.deps.ReconnectManager.Resume() {
+		return
+	}
+
+	state := vpn.StateReconnecting
+	if w.deps.ReconnectManager.IsWaitingForNetwork() {
+		state = vpn.StateWaitingForNetwork
+	}
+	glib.IdleAdd(func() { w.showReconnectState(state) })
+}
+
+// onScreenLocked closes the tunnel when the connected profile asks for it.
+// Like a disconnect by the user, it is not reconnected automatically.
+func (w *MainWindow) onScreenLocked() {
+	p := w.hooks.currentProfile()
+	if p == nil || !p.DisconnectOnLock {
+		return
+	}
+	pending := w.deps.ReconnectManager != nil && w.deps.ReconnectManager.IsPending()
+	if !pending && !w.deps.VPNController.GetState().CanDisconnect() {
+		return
+	}
+
+	slog.Info("Disconnecting because the screen was locked", "profile", p.Name)
+	w.logDialog.AppendLog("Screen locked, disconnecting")
+	w.triggerDisconnect()
+}
+
+// onScheduleStarted connects a profile whose schedule window started, unless
+// a connection is already active or reconnecting.
+func (w *MainWindow) onScheduleStarted(profileID string) {
+	pending := w.deps.ReconnectManager != nil && w.deps.ReconnectManager.IsPending()
+	if pending || !w.deps.VPNController.GetState().CanConnect() {
+		return
+	}
+	p := w.profileList.GetProfileByID(profileID)
+	if p == nil {
+		return
+	}
+
+	slog.Info("Connecting at the start of the profile's schedule", "profile", p.Name)
+	w.logDialog.AppendLog(fmt.Sprintf("Schedule of %s started, connecting", p.Name))
+	w.triggerConnectProfile(profileID)
+}
+
+// scheduledConnection returns the profile of the connection if it is active or
+// reconnecting for the given profile, or nil otherwise.
+func (w *MainWindow) scheduledConnection(profileID string) *profile.Profile {
+	p := w.hooks.currentProfile()
+	if p == nil || p.ID != profileID {
+		return nil
+	}
+	// Includes a tunnel closed for sleeping that is restored after resuming
+	pending := w.deps.ReconnectManager != nil &&
+		(w.deps.ReconnectManager.IsPending() || w.deps.ReconnectManager.WillResume())
+	if !pending && !w.deps.VPNController.GetState().CanDisconnect() {
+		return nil
+	}
+	return p
+}
+
+// onScheduleEnding warns that the connected profile is disconnected when its schedule window ends.
+func (w *MainWindow) onScheduleEnding(profileID string, end time.Time) {
+	p := w.scheduledConnection(profileID)
+	if p == nil {
+		return
 	}
+	w.logDialog.AppendLog(fmt.Sprintf("Schedule of %s ends at %s, disconnecting then", p.Name, end.Format("15:04")))
+	if w.deps.Notifier != nil {
+		w.deps.Notifier.NotifyScheduleEnding(p.Name)
+	}
+}
+
+// onScheduleEnded disconnects a profile whose schedule window ended. Like a
+// disconnect by the user, it is not reconnected automatically.
+// It is called from a background goroutine.
+func (w *MainWindow) onScheduleEnded(profileID string) {
+	p := w.scheduledConnection(profileID)
+	if p == nil {
+		return
+	}
+	if w.deps.ReconnectManager != nil {
+		// Settled before a reconnect, such as one resuming from sleep, can start
+		w.deps.ReconnectManager.Cancel()
+	}
+
+	glib.IdleAdd(func() {
+		slog.Info("Disconnecting at the end of the profile's schedule", "profile", p.Name)
+		w.logDialog.AppendLog(fmt.Sprintf("Schedule of %s ended, disconnecting", p.Name))
+		if w.deps.ReconnectManager != nil {
+			w.cancelReconnect()
+		}
+		if w.deps.VPNController.GetState().CanDisconnect() {
+			w.disconnectWithNotice(NotifyScheduleEnded)
+		} else if w.deps.Notifier != nil {
+			w.deps.Notifier.NotifyScheduleEnded(p.Name)
+		}
+	})
+}
+
+// startLivenessProbe starts probing the connected tunnel if its profile has a liveness probe.
+// It is called from a background goroutine.
+func (w *MainWindow) startLivenessProbe() {
+	w.stopLivenessProbe()
+
+	p := w.hooks.currentProfile()
+	if p == nil || p.LivenessProbe == nil {
+		return
+	}
+	probe := p.LivenessProbe
+	prober, err := liveness.NewProber(probe, w.hooks.nameServers())
+	if err != nil {
+		slog.Warn("Liveness probe disabled", "profile", p.Name, "error", err)
+		w.logDialog.AppendLog(fmt.Sprintf("Liveness probe disabled: %v", err))
+		return
+	}
+
+	ctx := w.deps.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w.livenessMu.Lock()
+	w.stopLiveness = cancel
+	w.livenessMu.Unlock()
+
+	checker := liveness.NewChecker(prober,
+		liveness.WithInterval(probe.Interval()),
+		liveness.WithTimeout(probe.Timeout()),
+		liveness.WithFailureThreshold(probe.FailureThreshold()))
+	checker.OnChange(func(alive bool) {
+		glib.IdleAdd(func() {
+			// Results of a probe stopped in the meantime are stale
+			if ctx.Err() == nil {
+				w.onLivenessChanged(p, alive)
+			}
+		})
+	})
+	checker.Start(ctx)
+	slog.Debug("Liveness probe started", "profile", p.Name, "type", probe.Type, "target", probe.Target)
 }
 
-// disconnect terminates the active VPN connection.
+// stopLivenessProbe stops probing the tunnel. It is called from any goroutine.
+func (w *MainWindow) stopLivenessProbe() {
+	w.livenessMu.Lock()
+	stop := w.stopLiveness
+	w.stopLiveness = nil
+	w.livenessMu.Unlock()
+
+	if stop != nil {
+		stop()
+	}
+}
+
+// onLivenessChanged marks a tunnel that stopped passing traffic as degraded and
+// reconnects it the way a dropped connection is reconnected. Profiles that do not
+// reconnect automatically stay degraded until the probe gets through again.
+func (w *MainWindow) onLivenessChanged(p *profile.Profile, alive bool) {
+	if w.deps.VPNController.GetState() != vpn.StateConnected {
+		return
+	}
+	if alive {
+		w.logDialog.AppendLog("Liveness probe succeeded again")
+		w.showReconnectState(vpn.StateConnected)
+		return
+	}
+
+	w.logDialog.AppendLog(fmt.Sprintf("Liveness probe failed %d times in a row, the tunnel is not passing traffic",
+		p.LivenessProbe.FailureThreshold()))
+	w.showReconnectState(vpn.StateDegraded)
+	if w.deps.Notifier != nil {
+		w.deps.Notifier.NotifyDegraded(p.Name)
+	}
+
+	if w.deps.ReconnectManager == nil || !w.deps.ReconnectManager.CanReconnect() {
+		return
+	}
+	slog.Info("Reconnecting tunnel that stopped passing traffic", "profile", p.Name)
+	// The drop is reconnected by the state change handler, counting as an attempt
+	if err := w.closeForReconnect(); err != nil {
+		slog.Error("Failed to disconnect dead tunnel", "error", err)
+	}
+}
+
+// disconnect terminates the active VPN connection after the profile's pre-disconnect hook.
 // Sets userInitiatedDisconnect flag to prevent auto-reconnect.
 func (w *MainWindow) disconnect() {
+	if w.alwaysOn != nil {
+		slog.Info("Not disconnecting: the always-on VPN is managed by the system", "profile", w.alwaysOn.Name)
+		return
+	}
+
 	// Mark as user-initiated and cancel any pending reconnect
 	if w.deps.ReconnectManager != nil {
 		w.deps.ReconnectManager.SetUserDisconnect()
 		w.deps.ReconnectManager.Cancel()
 	}
 
-	if err := w.deps.VPNController.Disconnect(context.Background()); err != nil {
-		w.showError("Disconnect Error", err.Error())
+	// Prevent a second request while the pre-disconnect hook runs
+	w.connectButton.SetSensitive(false)
+	w.hooks.beforeDisconnect(func() {
+		w.connectButton.SetSensitive(true)
+		if err := w.deps.VPNController.Disconnect(context.Background()); err != nil {
+			w.showError("Disconnect Error", err.Error())
+		}
+	})
+}
+
+// disconnectWithNotice disconnects like the user did, sending the given
+// notification instead of the usual one once the tunnel is down.
+func (w *MainWindow) disconnectWithNotice(notice NotificationType) {
+	w.noticeMu.Lock()
+	w.disconnectNotice = &notice
+	w.noticeMu.Unlock()
+	w.disconnect()
+}
+
+// takeDisconnectNotice returns and clears the notification replacing the usual
+// one for the disconnect, or nil. It is called from any goroutine.
+func (w *MainWindow) takeDisconnectNotice() *NotificationType {
+	w.noticeMu.Lock()
+	defer w.noticeMu.Unlock()
+	notice := w.disconnectNotice
+	w.disconnectNotice = nil
+	return notice
+}
+
+// startIdleWatch starts watching the connected tunnel's traffic if its profile
+// has an idle rule. It is called from a background goroutine.
+func (w *MainWindow) startIdleWatch() {
+	w.idleMu.Lock()
+	defer w.idleMu.Unlock()
+
+	w.idle, w.idleProfile = nil, nil
+	p := w.hooks.currentProfile()
+	if p == nil || p.IdleDisconnect == nil {
+		return
 	}
+	rule := p.IdleDisconnect
+	w.idle = stats.NewIdleDetector(float64(rule.ThresholdBytesPerSec), rule.IdleAfter(), rule.Warning())
+	w.idleProfile = p
+	slog.Debug("Idle watch started", "profile", p.Name, "threshold", rule.ThresholdBytesPerSec, "after", rule.IdleAfter())
+}
+
+// stopIdleWatch stops watching the tunnel's traffic. It is called from any goroutine.
+func (w *MainWindow) stopIdleWatch() {
+	w.idleMu.Lock()
+	defer w.idleMu.Unlock()
+	w.idle, w.idleProfile = nil, nil
+}
+
+// observeIdle feeds a stats sample to the idle watch, warning about and then
+// disconnecting a tunnel that is not used. It is called from the polling goroutine.
+func (w *MainWindow) observeIdle(s stats.NetworkStats) {
+	w.idleMu.Lock()
+	idle, p := w.idle, w.idleProfile
+	w.idleMu.Unlock()
+	if idle == nil {
+		return
+	}
+
+	switch idle.Observe(s) {
+	case stats.IdleWarn:
+		glib.IdleAdd(func() { w.onIdleWarning(p) })
+	case stats.IdleResumed:
+		glib.IdleAdd(func() { w.logDialog.AppendLog("Traffic resumed, staying connected") })
+	case stats.IdleExpired:
+		glib.IdleAdd(func() { w.onIdleExpired(p) })
+	}
+}
+
+// onIdleWarning warns that the tunnel disconnects soon unless it is used, or
+// the user asks to stay connected.
+func (w *MainWindow) onIdleWarning(p *profile.Profile) {
+	rule := p.IdleDisconnect
+	w.logDialog.AppendLog(fmt.Sprintf("Less than %d bytes/s for %d minutes, disconnecting in %s unless the VPN is used",
+		rule.ThresholdBytesPerSec, rule.Minutes, rule.Warning()))
+	if w.deps.Notifier != nil {
+		w.deps.Notifier.NotifyIdle(p.Name)
+	}
+}
+
+// onIdleExpired disconnects a tunnel that stayed idle through the warning.
+// Like a disconnect by the user, it is not reconnected automatically.
+func (w *MainWindow) onIdleExpired(p *profile.Profile) {
+	if w.deps.VPNController.GetState() != vpn.StateConnected {
+		return
+	}
+	slog.Info("Disconnecting idle tunnel", "profile", p.Name)
+	w.logDialog.AppendLog("VPN was not used, disconnecting")
+	w.disconnectWithNotice(NotifyIdleDisconnected)
+}
+
+// stayConnected restarts the idle time of the connected tunnel after the
+// user chose to stay connected from the idle warning.
+func (w *MainWindow) stayConnected() {
+	w.idleMu.Lock()
+	idle := w.idle
+	w.idleMu.Unlock()
+	if idle == nil {
+		return
+	}
+	idle.Reset()
+	w.logDialog.AppendLog("Staying connected although the VPN is idle")
 }
 
 // updateStatusForProfile updates the status display for the selected profile.
@@ -631,6 +1920,10 @@ func (w *MainWindow) updateConnectButton(state vpn.ConnectionState) {
 		}
 
 		switch {
+		case w.alwaysOn != nil:
+			// The system keeps its own VPN up
+			w.connectButton.SetLabel("Connect")
+			w.connectButton.SetSensitive(false)
 		case state.CanDisconnect():
 			// Connected or connecting - show Disconnect
 			w.connectButton.SetLabel("Disconnect")
@@ -697,8 +1990,28 @@ func (w *MainWindow) triggerConnect() {
 	w.connect()
 }
 
+// triggerConnectProfile selects a profile and connects to it from external sources
+// (e.g., the tray's profile menu).
+func (w *MainWindow) triggerConnectProfile(profileID string) {
+	if !w.deps.VPNController.GetState().CanConnect() {
+		slog.Debug("Ignoring connect request while a connection is active", "profile_id", profileID)
+		return
+	}
+	if w.profileList.GetProfileByID(profileID) == nil {
+		slog.Warn("Cannot connect: profile not found", "profile_id", profileID)
+		return
+	}
+
+	w.selectProfileByID(profileID)
+	w.connect()
+}
+
 // triggerDisconnect terminates the VPN connection from external sources (e.g., system tray).
 func (w *MainWindow) triggerDisconnect() {
+	if w.deps.ReconnectManager != nil && w.deps.ReconnectManager.IsPending() {
+		w.cancelReconnect()
+		return
+	}
 	w.disconnect()
 }
 
@@ -749,6 +2062,7 @@ func (w *MainWindow) startStatsCollector() {
 	w.deps.StatsCollector.OnStats(func(s stats.NetworkStats) {
 		// Update stats display widget (already marshals via glib.IdleAdd internally)
 		w.statsDisplay.SetStats(s)
+		w.observeIdle(s)
 
 		// Update tray menu - must marshal to GTK main thread
 		if w.deps.Tray != nil {
@@ -27,11 +27,16 @@ type Controller struct {
 	openfortivpnPath string
 	executor         ProcessExecutor
 	directMode       bool // When true, run openfortivpn directly without pkexec
+	versionDetector  *VersionDetector
+
+	// Installed openfortivpn version (nil if unknown). Set on each Connect.
+	version *Version
 
 	mu            sync.RWMutex
 	state         ConnectionState
 	assignedIP    string
 	interfaceName string
+	phase         Phase
 
 	// Process management
 	process Process
@@ -65,6 +70,15 @@ func WithDirectMode() ControllerOption {
 	}
 }
 
+// WithVersionDetector enables openfortivpn version detection.
+// The detected version is used to gate command-line options and select output parsing.
+// Without a detector, the controller assumes all features are supported.
+func WithVersionDetector(detector *VersionDetector) ControllerOption {
+	return func(c *Controller) {
+		c.versionDetector = detector
+	}
+}
+
 // NewController creates a new VPN controller instance.
 // By default, it uses RealExecutor with pkexec for privilege escalation.
 // Use WithExecutor or WithDirectMode options to customize behavior.
@@ -98,6 +112,10 @@ func (c *Controller) setState(newState ConnectionState) error {
 
 	oldState := c.state
 	c.state = newState
+	// Phases only describe progress within a single connection attempt.
+	if newState == StateConnecting || !newState.IsTransitioning() {
+		c.phase = PhaseNone
+	}
 	callback := c.onStateChange
 	c.mu.Unlock()
 
@@ -175,6 +193,23 @@ func (c *Controller) setInterface(name string) {
 	c.interfaceName = name
 }
 
+// GetPhase returns the most recent connection phase reached by the current attempt.
+// Returns PhaseNone when no connection attempt is in progress.
+func (c *Controller) GetPhase() Phase {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.phase
+}
+
+// setPhase records the connection phase if a connection attempt is still in progress.
+func (c *Controller) setPhase(phase Phase) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state.IsTransitioning() {
+		c.phase = phase
+	}
+}
+
 // detectInterface attempts to detect the VPN interface by the assigned IP.
 // It uses DetectInterfaceWithRetry for retry logic, then verifies the connection
 // state is still valid before setting the interface name.
@@ -275,7 +310,10 @@ func (c *Controller) processOutput(line string) {
 		}
 
 	case EventError:
-		c.emitError(errors.New(event.Message))
+		c.emitError(&ConnectionError{
+			Code:    ErrorCode(event.GetData(DataKeyErrorCode)),
+			Message: event.Message,
+		})
 		// Only transition to Failed if we're still in a connecting state.
 		// If the process has already exited and transitioned to Disconnected,
 		// there's no point in transitioning to Failed.
@@ -286,6 +324,9 @@ func (c *Controller) processOutput(line string) {
 			}
 		}
 
+	case EventPhase:
+		c.setPhase(Phase(event.GetData(DataKeyPhase)))
+
 	case EventAuthenticate:
 		if err := c.setState(StateAuthenticating); err != nil {
 			c.emitError(fmt.Errorf("state transition failed: %w", err))
@@ -293,6 +334,52 @@ func (c *Controller) processOutput(line string) {
 	}
 }
 
+// detectVersion determines the installed openfortivpn version.
+// Detection failures are logged and treated as an unknown version.
+func (c *Controller) detectVersion(ctx context.Context) {
+	if c.versionDetector == nil {
+		return
+	}
+
+	var version *Version
+	if v, err := c.versionDetector.Detect(ctx); err != nil {
+		slog.Warn("Failed to detect openfortivpn version, assuming all features are supported", "error", err)
+	} else {
+		version = &v
+	}
+
+	c.mu.Lock()
+	c.version = version
+	c.mu.Unlock()
+}
+
+// supports reports whether the installed openfortivpn provides the feature.
+// Unknown versions are assumed to support everything.
+func (c *Controller) supports(f Feature) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.version == nil || c.version.Supports(f)
+}
+
+// checkRequiredFeatures returns an error if the connection needs a feature
+// that the installed openfortivpn does not provide.
+func (c *Controller) checkRequiredFeatures(p *profile.Profile, opts *ConnectOptions) error {
+	var required []Feature
+	if p.AuthMethod == profile.AuthMethodSAML {
+		required = append(required, FeatureSAMLLogin)
+	}
+
+	for _, f := range required {
+		if !c.supports(f) {
+			c.mu.RLock()
+			installed := *c.version
+			c.mu.RUnlock()
+			return &UnsupportedFeatureError{Feature: f, Installed: installed}
+		}
+	}
+	return nil
+}
+
 // buildCommandArgs constructs the command-line arguments for openfortivpn.
 func (c *Controller) buildCommandArgs(p *profile.Profile, opts *ConnectOptions) []string {
 	args := []string{
@@ -346,8 +433,17 @@ func (c *Controller) buildCommandArgs(p *profile.Profile, opts *ConnectOptions)
 	}
 
 	// Add SAML/SSO authentication
-	if p.AuthMethod == profile.AuthMethodSAML {
-		args = append(args, "--saml-login")
+	if p.AuthMethod == profile.AuthMethodSAML && c.supports(FeatureSAMLLogin) {
+		args = append(args, FeatureSAMLLogin.Flag())
+	}
+
+	// Disable FortiToken Mobile push notifications (optional; skipped on older releases)
+	if p.NoFTMPush {
+		if c.supports(FeatureNoFTMPush) {
+			args = append(args, FeatureNoFTMPush.Flag())
+		} else {
+			slog.Warn("Installed openfortivpn does not support --no-ftm-push, ignoring option")
+		}
 	}
 
 	// Add trusted certificate hash
@@ -375,27 +471,39 @@ func (c *Controller) Connect(ctx context.Context, p *profile.Profile, opts *Conn
 	}
 
 	// Validate profile before proceeding
+	if p.IsTemplate {
+		return profile.ErrTemplateNotConnectable
+	}
 	if err := p.Validate(); err != nil {
 		return fmt.Errorf("invalid profile: %w", err)
 	}
 
-	// Transition to connecting state
-	if err := c.setState(StateConnecting); err != nil {
-		return fmt.Errorf("failed to set connecting state: %w", err)
-	}
-
 	// Handle nil options
 	if opts == nil {
 		opts = &ConnectOptions{}
 	}
 
+	// Adapt to the installed openfortivpn release
+	c.detectVersion(ctx)
+	if err := c.checkRequiredFeatures(p, opts); err != nil {
+		return err
+	}
+
+	// Transition to connecting state
+	if err := c.setState(StateConnecting); err != nil {
+		return fmt.Errorf("failed to set connecting state: %w", err)
+	}
+
 	// Start the VPN process
 	process, err := c.startProcess(ctx, p, opts)
 	if err != nil {
 		return err
 	}
 
-	// Set up password input for non-SAML authentication
+	// Report which gateway endpoint is used
+	c.emitEvent(gatewayEvent(p))
+
+	// Set up password input via stdin (for non-SAML authentication)
 	c.setupPasswordInput(p, opts.Password)
 
 	// Set up stdout/stderr processing
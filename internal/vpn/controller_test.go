@@ -195,23 +195,51 @@ func TestController_ProcessOutput_GotIP(t *testing.T) {
 	assert.Equal(t, "10.0.0.50", ctrl.GetAssignedIP())
 }
 
+func TestController_ProcessOutput_Phase(t *testing.T) {
+	ctrl := NewController("/usr/bin/openfortivpn")
+	assert.Equal(t, PhaseNone, ctrl.GetPhase())
+
+	_ = ctrl.setState(StateConnecting)
+	ctrl.processOutput("INFO:   Connected to gateway.")
+	assert.Equal(t, PhaseGatewayConnected, ctrl.GetPhase())
+
+	ctrl.processOutput("INFO:   Remote gateway has allocated a VPN.")
+	assert.Equal(t, PhaseVPNAllocated, ctrl.GetPhase())
+
+	// Phase is cleared once the attempt completes
+	ctrl.processOutput("Tunnel is up and running.")
+	assert.Equal(t, StateConnected, ctrl.GetState())
+	assert.Equal(t, PhaseNone, ctrl.GetPhase())
+}
+
+func TestController_ProcessOutput_PhaseIgnoredWhenNotConnecting(t *testing.T) {
+	ctrl := NewController("/usr/bin/openfortivpn")
+
+	ctrl.processOutput("INFO:   Connected to gateway.")
+	assert.Equal(t, PhaseNone, ctrl.GetPhase())
+}
+
 func TestController_ProcessOutput_Error(t *testing.T) {
 	ctrl := NewController("/usr/bin/openfortivpn")
 	_ = ctrl.setState(StateConnecting)
 
-	var lastError string
+	var lastError error
 	var mu sync.Mutex
 
 	ctrl.OnError(func(err error) {
 		mu.Lock()
 		defer mu.Unlock()
-		lastError = err.Error()
+		lastError = err
 	})
 
 	ctrl.processOutput("ERROR:  VPN authentication failed.")
 
 	mu.Lock()
-	assert.Contains(t, lastError, "VPN authentication failed")
+	require.Error(t, lastError)
+	assert.Contains(t, lastError.Error(), "VPN authentication failed")
+	var connErr *ConnectionError
+	require.ErrorAs(t, lastError, &connErr)
+	assert.Equal(t, ErrorCodeAuthFailed, connErr.Code)
 	mu.Unlock()
 
 	assert.Equal(t, StateFailed, ctrl.GetState())
@@ -438,6 +466,139 @@ func TestController_Connect_SAML_NoPasswordWritten(t *testing.T) {
 	executor.GetProcess().CompleteProcess()
 }
 
+func TestController_Connect_SAML_UnsupportedVersion(t *testing.T) {
+	executor := NewMockExecutor()
+	ctrl := NewController("/usr/bin/openfortivpn",
+		WithExecutor(executor),
+		WithVersionDetector(newFixedVersionDetector("1.16.0")))
+
+	p := &profile.Profile{
+		ID:         "550e8400-e29b-41d4-a716-446655440000",
+		Name:       "Test VPN",
+		Host:       "vpn.example.com",
+		Port:       443,
+		AuthMethod: profile.AuthMethodSAML,
+		SetDNS:     true,
+		SetRoutes:  true,
+	}
+
+	err := ctrl.Connect(context.Background(), p, &ConnectOptions{})
+	require.Error(t, err)
+
+	var unsupportedErr *UnsupportedFeatureError
+	require.ErrorAs(t, err, &unsupportedErr)
+	assert.Equal(t, FeatureSAMLLogin, unsupportedErr.Feature)
+	assert.Equal(t, Version{1, 16, 0}, unsupportedErr.Installed)
+
+	// No process should be started and the state should be unchanged
+	assert.Empty(t, executor.GetLastName())
+	assert.False(t, executor.GetProcess().IsStarted())
+	assert.Equal(t, StateDisconnected, ctrl.GetState())
+}
+
+func TestController_Connect_RejectsTemplatesAndUnresolvedProfiles(t *testing.T) {
+	template := &profile.Profile{
+		ID:         "550e8400-e29b-41d4-a716-446655440000",
+		Name:       "Office Template",
+		Host:       "vpn.example.com",
+		Port:       443,
+		AuthMethod: profile.AuthMethodPassword,
+		IsTemplate: true,
+	}
+	child := &profile.Profile{
+		ID:         "550e8400-e29b-41d4-a716-446655440001",
+		Name:       "Branch",
+		AuthMethod: profile.AuthMethodPassword,
+		ParentID:   template.ID,
+	}
+
+	tests := []struct {
+		name    string
+		profile *profile.Profile
+		wantErr error
+	}{
+		{name: "template", profile: template, wantErr: profile.ErrTemplateNotConnectable},
+		{name: "unresolved child", profile: child, wantErr: profile.ErrUnresolvedProfile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewMockExecutor()
+			ctrl := NewController("/usr/bin/openfortivpn", WithExecutor(executor))
+
+			err := ctrl.Connect(context.Background(), tt.profile, &ConnectOptions{Password: "secret"})
+			require.ErrorIs(t, err, tt.wantErr)
+
+			assert.Empty(t, executor.GetLastName())
+			assert.Equal(t, StateDisconnected, ctrl.GetState())
+		})
+	}
+}
+
+func TestController_Connect_NoFTMPush(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		wantFlag bool
+	}{
+		{name: "supported", version: "1.21.0", wantFlag: true},
+		{name: "unsupported", version: "1.13.0", wantFlag: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewMockExecutor()
+			ctrl := NewController("/usr/bin/openfortivpn",
+				WithExecutor(executor),
+				WithVersionDetector(newFixedVersionDetector(tt.version)))
+
+			p := &profile.Profile{
+				ID:         "550e8400-e29b-41d4-a716-446655440000",
+				Name:       "Test VPN",
+				Host:       "vpn.example.com",
+				Port:       443,
+				Username:   "testuser",
+				AuthMethod: profile.AuthMethodPassword,
+				NoFTMPush:  true,
+			}
+
+			err := ctrl.Connect(context.Background(), p, &ConnectOptions{Password: "secret"})
+			require.NoError(t, err)
+
+			if tt.wantFlag {
+				assert.Contains(t, executor.GetLastArgs(), "--no-ftm-push")
+			} else {
+				assert.NotContains(t, executor.GetLastArgs(), "--no-ftm-push")
+			}
+
+			executor.GetProcess().CompleteProcess()
+		})
+	}
+}
+
+func TestController_Connect_UnknownVersionAssumesSupport(t *testing.T) {
+	executor := NewMockExecutor()
+	detector := NewVersionDetector("/usr/bin/openfortivpn")
+	detector.run = func(_ context.Context, _ string) ([]byte, error) {
+		return nil, errors.New("not found")
+	}
+	ctrl := NewController("/usr/bin/openfortivpn", WithExecutor(executor), WithVersionDetector(detector))
+
+	p := &profile.Profile{
+		ID:         "550e8400-e29b-41d4-a716-446655440000",
+		Name:       "Test VPN",
+		Host:       "vpn.example.com",
+		Port:       443,
+		AuthMethod: profile.AuthMethodSAML,
+	}
+
+	err := ctrl.Connect(context.Background(), p, &ConnectOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, executor.GetLastArgs(), "--saml-login")
+
+	executor.GetProcess().CompleteProcess()
+}
+
 func TestController_Connect_SAML_PasswordIgnored(t *testing.T) {
 	executor := NewMockExecutor()
 	ctrl := NewController("/usr/bin/openfortivpn", WithExecutor(executor))
@@ -632,6 +793,14 @@ func TestController_Connect_Success(t *testing.T) {
 	assert.Contains(t, args, "-u")
 	assert.Contains(t, args, "testuser")
 
+	// The gateway in use is reported before any output is processed
+	mu.Lock()
+	require.NotEmpty(t, events)
+	assert.Equal(t, EventGateway, events[0].Type)
+	assert.Equal(t, "vpn.example.com", events[0].GetData(DataKeyGatewayHost))
+	assert.Equal(t, "443", events[0].GetData(DataKeyGatewayPort))
+	mu.Unlock()
+
 	// Complete the process
 	process.CompleteProcess()
 
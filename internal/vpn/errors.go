@@ -0,0 +1,156 @@
+package vpn
+
+import (
+	"regexp"
+)
+
+// ErrorCode is a stable, machine-readable category for an openfortivpn or pppd failure.
+// Codes are carried in OutputEvent.Data under DataKeyErrorCode and in helper protocol
+// events, so their string values must not change once released.
+type ErrorCode string
+
+const (
+	// ErrorCodeUnknown indicates an error line that did not match any known category.
+	ErrorCodeUnknown ErrorCode = "unknown"
+	// ErrorCodeAuthFailed indicates the gateway rejected the username or password.
+	ErrorCodeAuthFailed ErrorCode = "auth_failed"
+	// ErrorCodeOTPRejected indicates the gateway rejected the one-time password.
+	ErrorCodeOTPRejected ErrorCode = "otp_rejected"
+	// ErrorCodeGatewayUnreachable indicates the gateway could not be reached over TCP.
+	ErrorCodeGatewayUnreachable ErrorCode = "gateway_unreachable"
+	// ErrorCodeDNSFailure indicates the gateway hostname could not be resolved.
+	ErrorCodeDNSFailure ErrorCode = "dns_failure"
+	// ErrorCodeCertificateMismatch indicates the gateway certificate is not trusted.
+	ErrorCodeCertificateMismatch ErrorCode = "certificate_mismatch"
+	// ErrorCodeTLSFailure indicates the TLS handshake with the gateway failed.
+	ErrorCodeTLSFailure ErrorCode = "tls_failure"
+	// ErrorCodePermissionDenied indicates openfortivpn or pppd lacked the required privileges.
+	ErrorCodePermissionDenied ErrorCode = "permission_denied"
+	// ErrorCodePPPDNotFound indicates the pppd binary could not be executed.
+	ErrorCodePPPDNotFound ErrorCode = "pppd_not_found"
+	// ErrorCodePPPDFailed indicates pppd exited with an error after it was started.
+	ErrorCodePPPDFailed ErrorCode = "pppd_failed"
+	// ErrorCodeAllocationFailed indicates the gateway could not allocate a tunnel or memory ran out.
+	ErrorCodeAllocationFailed ErrorCode = "allocation_failed"
+)
+
+// DataKeyErrorCode is the OutputEvent.Data key holding the ErrorCode of an EventError.
+const DataKeyErrorCode = "code"
+
+// errorRule maps an error message pattern to its category.
+type errorRule struct {
+	pattern *regexp.Regexp
+	code    ErrorCode
+}
+
+// errorRules are evaluated in order; the first match wins.
+// More specific categories must come before broader ones (e.g., OTP before
+// generic authentication, pppd-not-found before generic pppd failures).
+var errorRules = []errorRule{
+	{regexp.MustCompile(`(?i)\b(otp|two-factor|2fa|token)\b.*(fail|invalid|reject|incorrect|expired)|(invalid|incorrect|wrong|expired).*\b(otp|two-factor|token)\b`), ErrorCodeOTPRejected},
+	{regexp.MustCompile(`(?i)certificate validation failed|trusted-cert|certificate digest|certificate verify failed|certificate.*(mismatch|not trusted|untrusted)`), ErrorCodeCertificateMismatch},
+	{regexp.MustCompile(`(?i)could not authenticate|authentication failed|invalid (credentials|password|username)|login failed|bad (credentials|password)`), ErrorCodeAuthFailed},
+	{regexp.MustCompile(`(?i)getaddrinfo|could not resolve|name or service not known|temporary failure in name resolution|no address associated with hostname`), ErrorCodeDNSFailure},
+	{regexp.MustCompile(`(?i)(execv?|pppd).*no such file or directory|could not (find|start|exec(ute)?) pppd|pppd.*not found`), ErrorCodePPPDNotFound},
+	{regexp.MustCompile(`(?i)not spawned with root privileges|not executed as root|must be root|permission denied|operation not permitted`), ErrorCodePermissionDenied},
+	{regexp.MustCompile(`(?i)allocat|malloc|out of memory|cannot allocate memory`), ErrorCodeAllocationFailed},
+	{regexp.MustCompile(`(?i)connection refused|no route to host|network is unreachable|connection timed out|could not connect to gateway|host is unreachable`), ErrorCodeGatewayUnreachable},
+	{regexp.MustCompile(`(?i)ssl_connect|ssl_read|ssl_write|tls|handshake`), ErrorCodeTLSFailure},
+	{regexp.MustCompile(`(?i)pppd`), ErrorCodePPPDFailed},
+}
+
+// ClassifyError determines the ErrorCode for an openfortivpn or pppd error message.
+// The message should be the text following the "ERROR:" prefix.
+// Returns ErrorCodeUnknown if the message does not match any known category.
+func ClassifyError(message string) ErrorCode {
+	for _, rule := range errorRules {
+		if rule.pattern.MatchString(message) {
+			return rule.code
+		}
+	}
+	return ErrorCodeUnknown
+}
+
+// Title returns a short, human-readable summary of the error category.
+func (c ErrorCode) Title() string {
+	switch c {
+	case ErrorCodeAuthFailed:
+		return "Authentication Failed"
+	case ErrorCodeOTPRejected:
+		return "One-Time Password Rejected"
+	case ErrorCodeGatewayUnreachable:
+		return "Gateway Unreachable"
+	case ErrorCodeDNSFailure:
+		return "Host Not Found"
+	case ErrorCodeCertificateMismatch:
+		return "Untrusted Gateway Certificate"
+	case ErrorCodeTLSFailure:
+		return "Secure Connection Failed"
+	case ErrorCodePermissionDenied:
+		return "Permission Denied"
+	case ErrorCodePPPDNotFound:
+		return "pppd Not Found"
+	case ErrorCodePPPDFailed:
+		return "PPP Negotiation Failed"
+	case ErrorCodeAllocationFailed:
+		return "Tunnel Allocation Failed"
+	default:
+		return "VPN Error"
+	}
+}
+
+// Hint returns an actionable suggestion for resolving the error.
+// Returns an empty string for ErrorCodeUnknown.
+func (c ErrorCode) Hint() string {
+	switch c {
+	case ErrorCodeAuthFailed:
+		return "Check the username and password for this profile. The stored password may be outdated."
+	case ErrorCodeOTPRejected:
+		return "The one-time password was not accepted. Wait for a new code and try again."
+	case ErrorCodeGatewayUnreachable:
+		return "The VPN server did not respond. Check the host and port, and your internet connection."
+	case ErrorCodeDNSFailure:
+		return "The server hostname could not be resolved. Check the host name and your DNS settings."
+	case ErrorCodeCertificateMismatch:
+		return "The server certificate is not trusted. Verify it with your administrator and set the Trusted Certificate digest."
+	case ErrorCodeTLSFailure:
+		return "The secure connection to the server could not be established. The server may be misconfigured or intercepted."
+	case ErrorCodePermissionDenied:
+		return "openfortivpn requires root privileges. Make sure the helper service is running or pkexec is available."
+	case ErrorCodePPPDNotFound:
+		return "openfortivpn requires pppd. Install the ppp package for your distribution."
+	case ErrorCodePPPDFailed:
+		return "The tunnel could not be negotiated. Check the connection log for details from pppd."
+	case ErrorCodeAllocationFailed:
+		return "The server could not allocate a tunnel. It may have reached its connection limit; try again later."
+	default:
+		return ""
+	}
+}
+
+// Retryable reports whether an automatic retry could plausibly succeed without user action.
+// Credential, certificate, and local installation problems are not retryable.
+func (c ErrorCode) Retryable() bool {
+	switch c {
+	case ErrorCodeAuthFailed, ErrorCodeOTPRejected, ErrorCodeCertificateMismatch,
+		ErrorCodePermissionDenied, ErrorCodePPPDNotFound:
+		return false
+	default:
+		return true
+	}
+}
+
+// ConnectionError is an openfortivpn failure with its classified ErrorCode.
+// It is passed to OnError callbacks so consumers can react per category
+// using errors.As.
+type ConnectionError struct {
+	// Code is the classified error category.
+	Code ErrorCode
+	// Message is the original error text from openfortivpn.
+	Message string
+}
+
+// Error implements the error interface, returning the original message.
+func (e *ConnectionError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,161 @@
+package vpn
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    ErrorCode
+	}{
+		{
+			name:    "bad credentials",
+			message: "Could not authenticate to gateway. Please check the password, client certificate, etc.",
+			want:    ErrorCodeAuthFailed,
+		},
+		{
+			name:    "VPN authentication failed",
+			message: "VPN authentication failed.",
+			want:    ErrorCodeAuthFailed,
+		},
+		{
+			name:    "OTP rejected",
+			message: "Two-factor authentication failed: invalid token.",
+			want:    ErrorCodeOTPRejected,
+		},
+		{
+			name:    "invalid OTP",
+			message: "Invalid OTP code.",
+			want:    ErrorCodeOTPRejected,
+		},
+		{
+			name:    "connection refused",
+			message: "connect: Connection refused",
+			want:    ErrorCodeGatewayUnreachable,
+		},
+		{
+			name:    "no route to host",
+			message: "connect: No route to host",
+			want:    ErrorCodeGatewayUnreachable,
+		},
+		{
+			name:    "could not connect",
+			message: "Could not connect to gateway.",
+			want:    ErrorCodeGatewayUnreachable,
+		},
+		{
+			name:    "DNS failure",
+			message: "getaddrinfo: Name or service not known",
+			want:    ErrorCodeDNSFailure,
+		},
+		{
+			name:    "certificate mismatch",
+			message: "Gateway certificate validation failed, and the certificate digest is not in the local whitelist. If you trust it, rerun with:",
+			want:    ErrorCodeCertificateMismatch,
+		},
+		{
+			name:    "trusted cert hint line",
+			message: "--trusted-cert 5e3b1a0c9f7d",
+			want:    ErrorCodeCertificateMismatch,
+		},
+		{
+			name:    "TLS handshake failure",
+			message: "SSL_connect: error:0A000410:SSL routines::sslv3 alert handshake failure",
+			want:    ErrorCodeTLSFailure,
+		},
+		{
+			name:    "not root",
+			message: "This process was not spawned with root privileges, which are required.",
+			want:    ErrorCodePermissionDenied,
+		},
+		{
+			name:    "pppd must be root",
+			message: "pppd: Not executed as root or setuid.",
+			want:    ErrorCodePermissionDenied,
+		},
+		{
+			name:    "pppd not found",
+			message: "execv: No such file or directory",
+			want:    ErrorCodePPPDNotFound,
+		},
+		{
+			name:    "pppd negotiation failed",
+			message: "pppd: The PPP negotiation failed.",
+			want:    ErrorCodePPPDFailed,
+		},
+		{
+			name:    "allocation failure",
+			message: "Could not allocate memory for the tunnel.",
+			want:    ErrorCodeAllocationFailed,
+		},
+		{
+			name:    "unknown",
+			message: "Something went wrong",
+			want:    ErrorCodeUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyError(tt.message))
+		})
+	}
+}
+
+func TestErrorCode_TitleAndHint(t *testing.T) {
+	codes := []ErrorCode{
+		ErrorCodeAuthFailed,
+		ErrorCodeOTPRejected,
+		ErrorCodeGatewayUnreachable,
+		ErrorCodeDNSFailure,
+		ErrorCodeCertificateMismatch,
+		ErrorCodeTLSFailure,
+		ErrorCodePermissionDenied,
+		ErrorCodePPPDNotFound,
+		ErrorCodePPPDFailed,
+		ErrorCodeAllocationFailed,
+	}
+
+	for _, code := range codes {
+		t.Run(string(code), func(t *testing.T) {
+			assert.NotEqual(t, "VPN Error", code.Title(), "known codes should have a specific title")
+			assert.NotEmpty(t, code.Hint(), "known codes should have a hint")
+		})
+	}
+
+	assert.Equal(t, "VPN Error", ErrorCodeUnknown.Title())
+	assert.Empty(t, ErrorCodeUnknown.Hint())
+}
+
+func TestErrorCode_Retryable(t *testing.T) {
+	assert.False(t, ErrorCodeAuthFailed.Retryable())
+	assert.False(t, ErrorCodeOTPRejected.Retryable())
+	assert.False(t, ErrorCodeCertificateMismatch.Retryable())
+	assert.False(t, ErrorCodePermissionDenied.Retryable())
+	assert.False(t, ErrorCodePPPDNotFound.Retryable())
+
+	assert.True(t, ErrorCodeGatewayUnreachable.Retryable())
+	assert.True(t, ErrorCodeDNSFailure.Retryable())
+	assert.True(t, ErrorCodeAllocationFailed.Retryable())
+	assert.True(t, ErrorCodeUnknown.Retryable())
+	assert.True(t, ErrorCode("").Retryable())
+}
+
+func TestConnectionError_ErrorsAs(t *testing.T) {
+	var err error = fmt.Errorf("wrapped: %w", &ConnectionError{
+		Code:    ErrorCodeDNSFailure,
+		Message: "getaddrinfo: Name or service not known",
+	})
+
+	var connErr *ConnectionError
+	require.True(t, errors.As(err, &connErr))
+	assert.Equal(t, ErrorCodeDNSFailure, connErr.Code)
+	assert.Equal(t, "getaddrinfo: Name or service not known", connErr.Error())
+}
@@ -0,0 +1,176 @@
+package vpn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shini4i/openfortivpn-gui/internal/profile"
+)
+
+// fakeProber returns fixed latencies; gateways without an entry are unreachable.
+type fakeProber struct {
+	latencies map[string]time.Duration
+}
+
+func (f *fakeProber) Probe(_ context.Context, gw profile.Gateway) (time.Duration, error) {
+	latency, ok := f.latencies[gw.Host]
+	if !ok {
+		return 0, errors.New("connection refused")
+	}
+	return latency, nil
+}
+
+func gateways(hosts ...string) []profile.Gateway {
+	gws := make([]profile.Gateway, 0, len(hosts))
+	for _, host := range hosts {
+		gws = append(gws, profile.Gateway{Host: host, Port: 443})
+	}
+	return gws
+}
+
+func TestOrderGateways(t *testing.T) {
+	prober := &fakeProber{latencies: map[string]time.Duration{
+		"a.example.com": 80 * time.Millisecond,
+		"b.example.com": 20 * time.Millisecond,
+		"c.example.com": 50 * time.Millisecond,
+	}}
+
+	tests := []struct {
+		name      string
+		endpoints []profile.Gateway
+		selection profile.GatewaySelection
+		want      []profile.Gateway
+	}{
+		{
+			name:      "ordered keeps configured order",
+			endpoints: gateways("a.example.com", "b.example.com", "c.example.com"),
+			selection: profile.GatewaySelectionOrdered,
+			want:      gateways("a.example.com", "b.example.com", "c.example.com"),
+		},
+		{
+			name:      "default selection is ordered",
+			endpoints: gateways("c.example.com", "b.example.com"),
+			want:      gateways("c.example.com", "b.example.com"),
+		},
+		{
+			name:      "fastest sorts by handshake time",
+			endpoints: gateways("a.example.com", "b.example.com", "c.example.com"),
+			selection: profile.GatewaySelectionFastest,
+			want:      gateways("b.example.com", "c.example.com", "a.example.com"),
+		},
+		{
+			name:      "unreachable gateways are tried last",
+			endpoints: gateways("down.example.com", "a.example.com", "b.example.com"),
+			selection: profile.GatewaySelectionOrdered,
+			want:      gateways("a.example.com", "b.example.com", "down.example.com"),
+		},
+		{
+			name:      "all unreachable keeps configured order",
+			endpoints: gateways("down1.example.com", "down2.example.com"),
+			selection: profile.GatewaySelectionFastest,
+			want:      gateways("down1.example.com", "down2.example.com"),
+		},
+		{
+			name:      "single endpoint is not probed",
+			endpoints: gateways("down.example.com"),
+			selection: profile.GatewaySelectionFastest,
+			want:      gateways("down.example.com"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := OrderGateways(context.Background(), tt.endpoints, tt.selection, prober)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSelectGateway(t *testing.T) {
+	p := profile.NewProfile("HA")
+	p.Host = "a.example.com"
+	p.Gateways = gateways("b.example.com")
+	p.GatewaySelection = profile.GatewaySelectionFastest
+
+	prober := &fakeProber{latencies: map[string]time.Duration{
+		"a.example.com": 80 * time.Millisecond,
+		"b.example.com": 20 * time.Millisecond,
+	}}
+
+	selected := SelectGateway(context.Background(), p, prober)
+	assert.Equal(t, "b.example.com", selected.Host)
+	assert.Equal(t, gateways("a.example.com"), selected.Gateways)
+	assert.Equal(t, "a.example.com", p.Host, "original profile must not change")
+}
+
+func listenerGateway(t *testing.T, addr string) profile.Gateway {
+	t.Helper()
+	host, portStr, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+	return profile.Gateway{Host: host, Port: port}
+}
+
+func TestHandshakeProber(t *testing.T) {
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+
+	prober := &HandshakeProber{Timeout: 2 * time.Second}
+
+	t.Run("TLS gateway", func(t *testing.T) {
+		latency, err := prober.Probe(context.Background(), listenerGateway(t, server.Listener.Addr().String()))
+		require.NoError(t, err)
+		assert.Positive(t, latency)
+	})
+
+	t.Run("closed port", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		gw := listenerGateway(t, listener.Addr().String())
+		require.NoError(t, listener.Close())
+
+		_, err = prober.Probe(context.Background(), gw)
+		assert.Error(t, err)
+	})
+
+	t.Run("no TLS handshake", func(t *testing.T) {
+		// Accepts TCP connections but never speaks TLS
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer func() { _ = listener.Close() }()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+			}
+		}()
+
+		silent := &HandshakeProber{Timeout: 200 * time.Millisecond}
+		_, err = silent.Probe(context.Background(), listenerGateway(t, listener.Addr().String()))
+		assert.Error(t, err)
+	})
+}
+
+func TestGatewayEvent(t *testing.T) {
+	p := profile.NewProfile("HA")
+	p.Host = "vpn2.example.com"
+	p.Port = 10443
+
+	event := gatewayEvent(p)
+	assert.Equal(t, EventGateway, event.Type)
+	assert.Equal(t, "vpn2.example.com", event.GetData(DataKeyGatewayHost))
+	assert.Equal(t, "10443", event.GetData(DataKeyGatewayPort))
+	assert.Contains(t, event.Message, "vpn2.example.com:10443")
+}
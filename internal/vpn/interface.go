@@ -23,6 +23,10 @@ type VPNController interface {
 	// Returns empty string if not connected or interface not detected.
 	GetInterface() string
 
+	// GetPhase returns the most recent connection phase reached while connecting.
+	// Returns PhaseNone if no connection attempt is in progress.
+	GetPhase() Phase
+
 	// CanConnect returns true if a connection can be initiated from the current state.
 	CanConnect() bool
 
@@ -58,3 +62,24 @@ type VPNController interface {
 
 // Ensure Controller implements VPNController interface.
 var _ VPNController = (*Controller)(nil)
+
+// KillSwitchController is implemented by controllers that can block traffic
+// outside the tunnel for profiles with a kill switch. The kill switch is
+// installed by Connect and removed by Disconnect.
+type KillSwitchController interface {
+	// DisconnectForReconnect terminates the active VPN connection but keeps
+	// the kill switch in place, since the tunnel is about to be reconnected.
+	DisconnectForReconnect(ctx context.Context) error
+
+	// ReleaseKillSwitch removes the kill switch while no tunnel is active,
+	// such as when the user gives up reconnecting.
+	ReleaseKillSwitch(ctx context.Context) error
+}
+
+// AlwaysOnReporter is implemented by controllers that report an always-on
+// profile the system keeps connected, which the user can neither connect nor disconnect.
+type AlwaysOnReporter interface {
+	// AlwaysOnProfile returns the always-on profile, marked as a system
+	// profile, or nil if none is configured.
+	AlwaysOnProfile() *profile.Profile
+}
@@ -2,6 +2,7 @@ package vpn
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -18,15 +19,31 @@ const (
 	// EventDisconnected indicates the tunnel has gone down.
 	EventDisconnected EventType = "disconnected"
 	// EventGotIP indicates the VPN assigned an IP address.
+	// The VPN name servers and DNS suffix, if any, are stored in Data under
+	// DataKeyDNS and DataKeyDNSSuffix.
 	EventGotIP EventType = "got_ip"
 	// EventError indicates an error occurred.
+	// The classified ErrorCode is stored in Data under DataKeyErrorCode.
 	EventError EventType = "error"
 	// EventOTPRequired indicates OTP/2FA input is needed.
 	EventOTPRequired EventType = "otp_required"
 	// EventPasswordRequired indicates password input is needed.
 	EventPasswordRequired EventType = "password_required"
+	// EventPhase indicates an intermediate connection phase was reached.
+	// The Phase and its ordinal are stored in Data under DataKeyPhase and DataKeyPhaseOrdinal.
+	EventPhase EventType = "phase"
+	// EventGateway indicates which gateway endpoint the connection uses.
+	// It is emitted by the controller rather than parsed from output.
+	// The endpoint is stored in Data under DataKeyGatewayHost and DataKeyGatewayPort.
+	EventGateway EventType = "gateway"
 )
 
+// DataKeyDNS holds the comma-separated VPN name servers for EventGotIP.
+const DataKeyDNS = "dns"
+
+// DataKeyDNSSuffix holds the DNS search domain the VPN assigned for EventGotIP.
+const DataKeyDNSSuffix = "dns_suffix"
+
 // OutputEvent represents a parsed event from openfortivpn output.
 type OutputEvent struct {
 	Type    EventType
@@ -65,6 +82,12 @@ var (
 	// Matches: Got addresses: [10.0.0.100], ns [...]
 	gotAddressesPattern = regexp.MustCompile(`Got addresses: \[([^\]]+)\]`)
 
+	// Matches the name servers of a Got addresses line: ns [10.0.0.1, 10.0.0.2]
+	nameServersPattern = regexp.MustCompile(`\bns \[([^\]]*)\]`)
+
+	// Matches the DNS suffix of a Got addresses line: ns_suffix [example.com]
+	nameSuffixPattern = regexp.MustCompile(`\bns_suffix \[([^\]]*)\]`)
+
 	// Matches: ERROR: message
 	errorPattern = regexp.MustCompile(`ERROR:\s*(.+)`)
 
@@ -76,8 +99,43 @@ var (
 
 	// Matches password prompts
 	passwordPattern = regexp.MustCompile(`(?i)(password:)`)
+
+	// Matches: Interface ppp0 is UP.
+	interfaceUpPattern = regexp.MustCompile(`Interface (\S+) is UP`)
 )
 
+// phasePatterns maps intermediate openfortivpn progress lines to connection phases.
+// PhaseInterfaceUp is handled separately because it captures the interface name.
+var phasePatterns = []struct {
+	pattern *regexp.Regexp
+	phase   Phase
+}{
+	// Matches: Connected to gateway.
+	{regexp.MustCompile(`Connected to gateway`), PhaseGatewayConnected},
+	// Matches: Authenticated.
+	{regexp.MustCompile(`\bAuthenticated\b`), PhaseAuthenticated},
+	// Matches: Remote gateway has allocated a VPN.
+	{regexp.MustCompile(`Remote gateway has allocated a VPN`), PhaseVPNAllocated},
+	// Matches: Negotiation complete.
+	{regexp.MustCompile(`Negotiation complete`), PhaseNegotiationComplete},
+	// Matches: Setting new routes...
+	{regexp.MustCompile(`Setting new routes`), PhaseRoutesSet},
+	// Matches: Adding VPN nameservers...
+	{regexp.MustCompile(`Adding VPN nameservers`), PhaseDNSSet},
+}
+
+// newPhaseEvent creates an EventPhase event for the given phase.
+func newPhaseEvent(line string, phase Phase) *OutputEvent {
+	return &OutputEvent{
+		Type:    EventPhase,
+		Message: line,
+		Data: map[string]string{
+			DataKeyPhase:        string(phase),
+			DataKeyPhaseOrdinal: strconv.Itoa(phase.Ordinal()),
+		},
+	}
+}
+
 // ParseLine parses a single line of openfortivpn output and returns an event if recognized.
 // Returns nil if the line doesn't match any known pattern.
 func ParseLine(line string) *OutputEvent {
@@ -114,18 +172,39 @@ func ParseLine(line string) *OutputEvent {
 
 	// Check for IP address assignment
 	if matches := gotAddressesPattern.FindStringSubmatch(line); matches != nil {
-		return &OutputEvent{
+		event := &OutputEvent{
 			Type:    EventGotIP,
 			Message: line,
 			Data:    map[string]string{"ip": matches[1]},
 		}
+		if ns := parseNameServers(line); ns != "" {
+			event.Data[DataKeyDNS] = ns
+		}
+		if matches := nameSuffixPattern.FindStringSubmatch(line); matches != nil && matches[1] != "" {
+			event.Data[DataKeyDNSSuffix] = matches[1]
+		}
+		return event
 	}
 
 	// Check for errors
 	if matches := errorPattern.FindStringSubmatch(line); matches != nil {
+		message := strings.TrimSpace(matches[1])
 		return &OutputEvent{
 			Type:    EventError,
-			Message: strings.TrimSpace(matches[1]),
+			Message: message,
+			Data:    map[string]string{DataKeyErrorCode: string(ClassifyError(message))},
+		}
+	}
+
+	// Check for intermediate connection phases
+	if matches := interfaceUpPattern.FindStringSubmatch(line); matches != nil {
+		event := newPhaseEvent(line, PhaseInterfaceUp)
+		event.Data[DataKeyInterface] = matches[1]
+		return event
+	}
+	for _, pp := range phasePatterns {
+		if pp.pattern.MatchString(line) {
+			return newPhaseEvent(line, pp.phase)
 		}
 	}
 
@@ -156,3 +235,21 @@ func ParseLine(line string) *OutputEvent {
 	// Unrecognized line
 	return nil
 }
+
+// parseNameServers returns the comma-separated name servers of a Got addresses line.
+// openfortivpn reports unset name servers as 0.0.0.0; those are left out.
+func parseNameServers(line string) string {
+	matches := nameServersPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return ""
+	}
+
+	var servers []string
+	for _, ns := range strings.Split(matches[1], ",") {
+		ns = strings.TrimSpace(ns)
+		if ns != "" && ns != "0.0.0.0" {
+			servers = append(servers, ns)
+		}
+	}
+	return strings.Join(servers, ",")
+}
@@ -70,19 +70,33 @@ func TestParseLine_TunnelDown(t *testing.T) {
 
 func TestParseLine_GotAddresses(t *testing.T) {
 	tests := []struct {
-		name   string
-		line   string
-		wantIP string
+		name    string
+		line    string
+		wantIP  string
+		wantDNS string
 	}{
 		{
-			name:   "IPv4 address",
-			line:   "Got addresses: [10.0.0.100], ns [10.0.0.1, 10.0.0.2]",
-			wantIP: "10.0.0.100",
+			name:    "IPv4 address",
+			line:    "Got addresses: [10.0.0.100], ns [10.0.0.1, 10.0.0.2]",
+			wantIP:  "10.0.0.100",
+			wantDNS: "10.0.0.1,10.0.0.2",
+		},
+		{
+			name:    "Different IP format",
+			line:    "INFO:   Got addresses: [192.168.1.50], ns [8.8.8.8]",
+			wantIP:  "192.168.1.50",
+			wantDNS: "8.8.8.8",
 		},
 		{
-			name:   "Different IP format",
-			line:   "INFO:   Got addresses: [192.168.1.50], ns [8.8.8.8]",
-			wantIP: "192.168.1.50",
+			name:    "With DNS suffix",
+			line:    "INFO:   Got addresses: [10.0.0.100], ns [10.0.0.1, 0.0.0.0], ns_suffix [corp.example.com]",
+			wantIP:  "10.0.0.100",
+			wantDNS: "10.0.0.1",
+		},
+		{
+			name:   "Without name servers",
+			line:   "Got addresses: [10.0.0.100], ns [0.0.0.0, 0.0.0.0]",
+			wantIP: "10.0.0.100",
 		},
 	}
 
@@ -92,6 +106,7 @@ func TestParseLine_GotAddresses(t *testing.T) {
 			require.NotNil(t, event)
 			assert.Equal(t, EventGotIP, event.Type)
 			assert.Equal(t, tt.wantIP, event.Data["ip"])
+			assert.Equal(t, tt.wantDNS, event.GetData(DataKeyDNS))
 		})
 	}
 }
@@ -101,21 +116,25 @@ func TestParseLine_Error(t *testing.T) {
 		name        string
 		line        string
 		wantMessage string
+		wantCode    ErrorCode
 	}{
 		{
 			name:        "Connection error",
 			line:        "ERROR:  Could not connect to gateway.",
 			wantMessage: "Could not connect to gateway.",
+			wantCode:    ErrorCodeGatewayUnreachable,
 		},
 		{
 			name:        "Authentication error",
 			line:        "ERROR:  VPN authentication failed.",
 			wantMessage: "VPN authentication failed.",
+			wantCode:    ErrorCodeAuthFailed,
 		},
 		{
 			name:        "Generic error",
 			line:        "ERROR:  Something went wrong",
 			wantMessage: "Something went wrong",
+			wantCode:    ErrorCodeUnknown,
 		},
 	}
 
@@ -125,6 +144,7 @@ func TestParseLine_Error(t *testing.T) {
 			require.NotNil(t, event)
 			assert.Equal(t, EventError, event.Type)
 			assert.Equal(t, tt.wantMessage, event.Message)
+			assert.Equal(t, string(tt.wantCode), event.GetData(DataKeyErrorCode))
 		})
 	}
 }
@@ -144,6 +164,45 @@ func TestParseLine_ConnectingToGateway(t *testing.T) {
 	}
 }
 
+func TestParseLine_Phase(t *testing.T) {
+	tests := []struct {
+		line        string
+		wantPhase   Phase
+		wantOrdinal string
+	}{
+		{"INFO:   Connected to gateway.", PhaseGatewayConnected, "1"},
+		{"INFO:   Authenticated.", PhaseAuthenticated, "2"},
+		{"INFO:   Remote gateway has allocated a VPN.", PhaseVPNAllocated, "3"},
+		{"INFO:   Negotiation complete.", PhaseNegotiationComplete, "4"},
+		{"INFO:   Interface ppp0 is UP.", PhaseInterfaceUp, "5"},
+		{"INFO:   Setting new routes...", PhaseRoutesSet, "6"},
+		{"INFO:   Adding VPN nameservers...", PhaseDNSSet, "7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			event := ParseLine(tt.line)
+			require.NotNil(t, event)
+			assert.Equal(t, EventPhase, event.Type)
+			assert.Equal(t, tt.line, event.Message)
+			assert.Equal(t, string(tt.wantPhase), event.GetData(DataKeyPhase))
+			assert.Equal(t, tt.wantOrdinal, event.GetData(DataKeyPhaseOrdinal))
+		})
+	}
+}
+
+func TestParseLine_PhaseInterfaceName(t *testing.T) {
+	event := ParseLine("INFO:   Interface ppp1 is UP.")
+	require.NotNil(t, event)
+	assert.Equal(t, "ppp1", event.GetData(DataKeyInterface))
+}
+
+func TestParseLine_ErrorTakesPrecedenceOverPhase(t *testing.T) {
+	event := ParseLine("ERROR:  Could not authenticate to gateway. Authenticated session expired.")
+	require.NotNil(t, event)
+	assert.Equal(t, EventError, event.Type)
+}
+
 func TestParseLine_UnrecognizedLine(t *testing.T) {
 	lines := []string{
 		"Some random log message",
@@ -0,0 +1,89 @@
+package vpn
+
+// Phase identifies an intermediate step of tunnel establishment reported by openfortivpn
+// between StateConnecting and StateConnected.
+type Phase string
+
+const (
+	// PhaseNone indicates no phase has been reached yet (or no connection is in progress).
+	PhaseNone Phase = ""
+	// PhaseGatewayConnected indicates the TLS connection to the gateway is established.
+	PhaseGatewayConnected Phase = "gateway_connected"
+	// PhaseAuthenticated indicates the gateway accepted the credentials.
+	PhaseAuthenticated Phase = "authenticated"
+	// PhaseVPNAllocated indicates the gateway allocated a VPN tunnel for this session.
+	PhaseVPNAllocated Phase = "vpn_allocated"
+	// PhaseNegotiationComplete indicates PPP negotiation with the gateway finished.
+	PhaseNegotiationComplete Phase = "negotiation_complete"
+	// PhaseInterfaceUp indicates the tunnel network interface is up.
+	PhaseInterfaceUp Phase = "interface_up"
+	// PhaseRoutesSet indicates openfortivpn is configuring routes.
+	PhaseRoutesSet Phase = "routes_set"
+	// PhaseDNSSet indicates openfortivpn is configuring VPN name servers.
+	PhaseDNSSet Phase = "dns_set"
+)
+
+// Data keys used by EventPhase events.
+const (
+	// DataKeyPhase holds the Phase value.
+	DataKeyPhase = "phase"
+	// DataKeyPhaseOrdinal holds the 1-based position of the phase as a decimal string.
+	DataKeyPhaseOrdinal = "ordinal"
+	// DataKeyInterface holds the interface name for PhaseInterfaceUp.
+	DataKeyInterface = "interface"
+)
+
+// orderedPhases lists all phases in the order openfortivpn reports them.
+var orderedPhases = []Phase{
+	PhaseGatewayConnected,
+	PhaseAuthenticated,
+	PhaseVPNAllocated,
+	PhaseNegotiationComplete,
+	PhaseInterfaceUp,
+	PhaseRoutesSet,
+	PhaseDNSSet,
+}
+
+// AllPhases returns all connection phases in the order they occur.
+func AllPhases() []Phase {
+	phases := make([]Phase, len(orderedPhases))
+	copy(phases, orderedPhases)
+	return phases
+}
+
+// PhaseCount returns the total number of connection phases.
+func PhaseCount() int {
+	return len(orderedPhases)
+}
+
+// Ordinal returns the 1-based position of the phase, or 0 for PhaseNone and unknown phases.
+func (p Phase) Ordinal() int {
+	for i, phase := range orderedPhases {
+		if phase == p {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Label returns a short, human-readable description of the phase.
+func (p Phase) Label() string {
+	switch p {
+	case PhaseGatewayConnected:
+		return "Connected to gateway"
+	case PhaseAuthenticated:
+		return "Authenticated"
+	case PhaseVPNAllocated:
+		return "VPN allocated"
+	case PhaseNegotiationComplete:
+		return "Negotiation complete"
+	case PhaseInterfaceUp:
+		return "Interface up"
+	case PhaseRoutesSet:
+		return "Setting routes"
+	case PhaseDNSSet:
+		return "Configuring DNS"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,36 @@
+package vpn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhase_Ordinal(t *testing.T) {
+	for i, phase := range AllPhases() {
+		assert.Equal(t, i+1, phase.Ordinal(), "phase %s", phase)
+	}
+
+	assert.Equal(t, 0, PhaseNone.Ordinal())
+	assert.Equal(t, 0, Phase("unknown").Ordinal())
+}
+
+func TestPhase_Label(t *testing.T) {
+	for _, phase := range AllPhases() {
+		assert.NotEmpty(t, phase.Label(), "phase %s should have a label", phase)
+	}
+
+	assert.Empty(t, PhaseNone.Label())
+}
+
+func TestPhaseCount(t *testing.T) {
+	assert.Equal(t, 7, PhaseCount())
+	assert.Len(t, AllPhases(), PhaseCount())
+}
+
+func TestAllPhases_ReturnsCopy(t *testing.T) {
+	phases := AllPhases()
+	phases[0] = PhaseNone
+
+	assert.Equal(t, PhaseGatewayConnected, AllPhases()[0])
+}
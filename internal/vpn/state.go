@@ -13,8 +13,12 @@ const (
 	StateConnecting ConnectionState = "connecting"
 	// StateConnected indicates the VPN tunnel is active.
 	StateConnected ConnectionState = "connected"
+	// StateDegraded indicates the tunnel is up but liveness probes stopped getting through.
+	StateDegraded ConnectionState = "degraded"
 	// StateReconnecting indicates the VPN is attempting to reconnect after a drop.
 	StateReconnecting ConnectionState = "reconnecting"
+	// StateWaitingForNetwork indicates reconnecting is paused until a network uplink is available.
+	StateWaitingForNetwork ConnectionState = "waiting_for_network"
 	// StateFailed indicates the connection attempt failed.
 	StateFailed ConnectionState = "failed"
 )
@@ -36,7 +40,8 @@ func (s ConnectionState) CanConnect() bool {
 
 // CanDisconnect returns true if the connection can be terminated from this state.
 func (s ConnectionState) CanDisconnect() bool {
-	return s == StateAuthenticating || s == StateConnecting || s == StateConnected || s == StateReconnecting
+	return s == StateAuthenticating || s == StateConnecting || s == StateConnected ||
+		s == StateDegraded || s == StateReconnecting || s == StateWaitingForNetwork
 }
 
 // validTransitions defines the allowed state transitions.
@@ -59,12 +64,26 @@ var validTransitions = map[ConnectionState][]ConnectionState{
 	},
 	StateConnected: {
 		StateDisconnected,
+		StateDegraded,
 		StateReconnecting,
+		StateWaitingForNetwork,
+	},
+	StateDegraded: {
+		StateConnected, // Probes get through again
+		StateDisconnected,
+		StateReconnecting,
+		StateWaitingForNetwork,
 	},
 	StateReconnecting: {
 		StateConnecting,
 		StateDisconnected,
 		StateFailed,
+		StateWaitingForNetwork,
+	},
+	StateWaitingForNetwork: {
+		StateReconnecting,
+		StateConnecting,
+		StateDisconnected,
 	},
 	StateFailed: {
 		StateDisconnected,
@@ -94,7 +113,9 @@ func AllStates() []ConnectionState {
 		StateAuthenticating,
 		StateConnecting,
 		StateConnected,
+		StateDegraded,
 		StateReconnecting,
+		StateWaitingForNetwork,
 		StateFailed,
 	}
 }
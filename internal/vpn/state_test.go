@@ -15,7 +15,9 @@ func TestConnectionState_String(t *testing.T) {
 		{StateAuthenticating, "authenticating"},
 		{StateConnecting, "connecting"},
 		{StateConnected, "connected"},
+		{StateDegraded, "degraded"},
 		{StateReconnecting, "reconnecting"},
+		{StateWaitingForNetwork, "waiting_for_network"},
 		{StateFailed, "failed"},
 	}
 
@@ -35,7 +37,9 @@ func TestConnectionState_IsConnected(t *testing.T) {
 		{StateAuthenticating, false},
 		{StateConnecting, false},
 		{StateConnected, true},
+		{StateDegraded, false},
 		{StateReconnecting, false},
+		{StateWaitingForNetwork, false},
 		{StateFailed, false},
 	}
 
@@ -55,7 +59,9 @@ func TestConnectionState_IsTransitioning(t *testing.T) {
 		{StateAuthenticating, true},
 		{StateConnecting, true},
 		{StateConnected, false},
+		{StateDegraded, false},
 		{StateReconnecting, true},
+		{StateWaitingForNetwork, false},
 		{StateFailed, false},
 	}
 
@@ -75,7 +81,9 @@ func TestConnectionState_CanConnect(t *testing.T) {
 		{StateAuthenticating, false},
 		{StateConnecting, false},
 		{StateConnected, false},
+		{StateDegraded, false},
 		{StateReconnecting, false},
+		{StateWaitingForNetwork, false},
 		{StateFailed, true},
 	}
 
@@ -95,7 +103,9 @@ func TestConnectionState_CanDisconnect(t *testing.T) {
 		{StateAuthenticating, true},
 		{StateConnecting, true},
 		{StateConnected, true},
+		{StateDegraded, true},
 		{StateReconnecting, true},
+		{StateWaitingForNetwork, true},
 		{StateFailed, false},
 	}
 
@@ -129,12 +139,26 @@ func TestValidTransitions(t *testing.T) {
 
 		// From Connected
 		{StateConnected, StateDisconnected},
+		{StateConnected, StateDegraded},
 		{StateConnected, StateReconnecting},
+		{StateConnected, StateWaitingForNetwork},
+
+		// From Degraded
+		{StateDegraded, StateConnected},
+		{StateDegraded, StateDisconnected},
+		{StateDegraded, StateReconnecting},
+		{StateDegraded, StateWaitingForNetwork},
 
 		// From Reconnecting
 		{StateReconnecting, StateConnecting},
 		{StateReconnecting, StateDisconnected},
 		{StateReconnecting, StateFailed},
+		{StateReconnecting, StateWaitingForNetwork},
+
+		// From WaitingForNetwork
+		{StateWaitingForNetwork, StateReconnecting},
+		{StateWaitingForNetwork, StateConnecting},
+		{StateWaitingForNetwork, StateDisconnected},
 
 		// From Failed
 		{StateFailed, StateDisconnected},
@@ -163,6 +187,14 @@ func TestInvalidTransitions(t *testing.T) {
 		{StateAuthenticating, StateReconnecting},
 		{StateConnecting, StateReconnecting},
 
+		// Waiting for the network only pauses reconnecting
+		{StateDisconnected, StateWaitingForNetwork},
+		{StateWaitingForNetwork, StateConnected},
+
+		// Only an established tunnel can stop passing traffic
+		{StateConnecting, StateDegraded},
+		{StateDisconnected, StateDegraded},
+
 		// Cannot go backward to Authenticating from Connected
 		{StateConnected, StateAuthenticating},
 		{StateConnected, StateConnecting},
@@ -183,12 +215,14 @@ func TestInvalidTransitions(t *testing.T) {
 func TestAllStates(t *testing.T) {
 	states := AllStates()
 
-	assert.Len(t, states, 6)
+	assert.Len(t, states, 8)
 	assert.Contains(t, states, StateDisconnected)
 	assert.Contains(t, states, StateAuthenticating)
 	assert.Contains(t, states, StateConnecting)
 	assert.Contains(t, states, StateConnected)
+	assert.Contains(t, states, StateDegraded)
 	assert.Contains(t, states, StateReconnecting)
+	assert.Contains(t, states, StateWaitingForNetwork)
 	assert.Contains(t, states, StateFailed)
 }
 
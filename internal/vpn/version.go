@@ -0,0 +1,180 @@
+package vpn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// versionTimeout bounds how long `openfortivpn --version` may run.
+const versionTimeout = 5 * time.Second
+
+// versionPattern matches the first dotted version number in `openfortivpn --version` output.
+// Distribution builds may append suffixes (e.g., "1.21.0-1ubuntu1"), which are ignored.
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ErrVersionNotFound is returned when no version number can be found in the output.
+var ErrVersionNotFound = errors.New("no version number found in openfortivpn output")
+
+// Version is a parsed openfortivpn release number.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion extracts the openfortivpn version from `openfortivpn --version` output.
+func ParseVersion(output string) (Version, error) {
+	matches := versionPattern.FindStringSubmatch(output)
+	if matches == nil {
+		return Version{}, ErrVersionNotFound
+	}
+
+	var v Version
+	v.Major, _ = strconv.Atoi(matches[1])
+	v.Minor, _ = strconv.Atoi(matches[2])
+	if matches[3] != "" {
+		v.Patch, _ = strconv.Atoi(matches[3])
+	}
+	return v, nil
+}
+
+// String returns the version in "major.minor.patch" form.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0, or 1 if v is older than, equal to, or newer than other.
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Major != other.Major:
+		return compareInt(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return compareInt(v.Minor, other.Minor)
+	default:
+		return compareInt(v.Patch, other.Patch)
+	}
+}
+
+// AtLeast reports whether v is the same as or newer than other.
+func (v Version) AtLeast(other Version) bool {
+	return v.Compare(other) >= 0
+}
+
+// Supports reports whether this openfortivpn version provides the given feature.
+func (v Version) Supports(f Feature) bool {
+	return v.AtLeast(f.MinVersion())
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Feature identifies an openfortivpn capability that is only available in newer releases.
+type Feature string
+
+const (
+	// FeatureSAMLLogin is the --saml-login option used for SAML/SSO authentication.
+	FeatureSAMLLogin Feature = "saml_login"
+	// FeatureNoFTMPush is the --no-ftm-push option that disables FortiToken Mobile push.
+	FeatureNoFTMPush Feature = "no_ftm_push"
+)
+
+// featureVersions maps each feature to the first openfortivpn release that provides it.
+var featureVersions = map[Feature]Version{
+	FeatureSAMLLogin: {Major: 1, Minor: 17, Patch: 0},
+	FeatureNoFTMPush: {Major: 1, Minor: 14, Patch: 0},
+}
+
+// MinVersion returns the first openfortivpn release that provides the feature.
+func (f Feature) MinVersion() Version {
+	return featureVersions[f]
+}
+
+// Flag returns the openfortivpn command-line option for the feature.
+func (f Feature) Flag() string {
+	switch f {
+	case FeatureSAMLLogin:
+		return "--saml-login"
+	case FeatureNoFTMPush:
+		return "--no-ftm-push"
+	default:
+		return ""
+	}
+}
+
+// UnsupportedFeatureError is returned when a connection requires a feature
+// that the installed openfortivpn does not provide.
+type UnsupportedFeatureError struct {
+	Feature   Feature
+	Installed Version
+}
+
+// Error implements the error interface.
+func (e *UnsupportedFeatureError) Error() string {
+	return fmt.Sprintf("openfortivpn %s does not support %s (requires %s or newer)",
+		e.Installed, e.Feature.Flag(), e.Feature.MinVersion())
+}
+
+// VersionDetector runs `openfortivpn --version` and caches the detected version.
+// It is safe for concurrent use.
+type VersionDetector struct {
+	path string
+	run  func(ctx context.Context, path string) ([]byte, error)
+
+	mu      sync.Mutex
+	version *Version
+}
+
+// NewVersionDetector creates a detector for the openfortivpn binary at path.
+func NewVersionDetector(path string) *VersionDetector {
+	return &VersionDetector{
+		path: path,
+		run:  runVersionCommand,
+	}
+}
+
+// Detect returns the installed openfortivpn version.
+// The command runs until it succeeds once; later calls return the cached version.
+// Failures are not cached, so a timeout or a binary installed later is retried.
+func (d *VersionDetector) Detect(ctx context.Context) (Version, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.version != nil {
+		return *d.version, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+
+	output, err := d.run(ctx, d.path)
+	if err != nil {
+		return Version{}, fmt.Errorf("failed to run %s --version: %w", d.path, err)
+	}
+	v, err := ParseVersion(string(output))
+	if err != nil {
+		return Version{}, err
+	}
+	d.version = &v
+
+	return v, nil
+}
+
+// runVersionCommand executes `openfortivpn --version` without privilege escalation.
+func runVersionCommand(ctx context.Context, path string) ([]byte, error) {
+	// #nosec G204 -- path is the configured openfortivpn binary, not user input
+	return exec.CommandContext(ctx, path, "--version").CombinedOutput()
+}
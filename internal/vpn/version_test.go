@@ -0,0 +1,127 @@
+package vpn
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   Version
+	}{
+		{name: "plain", output: "1.21.0\n", want: Version{1, 21, 0}},
+		{name: "with program name", output: "openfortivpn 1.17.1", want: Version{1, 17, 1}},
+		{name: "distribution suffix", output: "1.20.5-1ubuntu1", want: Version{1, 20, 5}},
+		{name: "git describe", output: "1.22.1-3-g6b3a2f0", want: Version{1, 22, 1}},
+		{name: "no patch", output: "1.14", want: Version{1, 14, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVersion(tt.output)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseVersion_NoVersion(t *testing.T) {
+	_, err := ParseVersion("openfortivpn: unrecognized option")
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+func TestVersion_Compare(t *testing.T) {
+	v := Version{1, 17, 0}
+
+	assert.Equal(t, 0, v.Compare(Version{1, 17, 0}))
+	assert.Equal(t, -1, v.Compare(Version{1, 17, 1}))
+	assert.Equal(t, -1, v.Compare(Version{2, 0, 0}))
+	assert.Equal(t, 1, v.Compare(Version{1, 16, 9}))
+	assert.True(t, v.AtLeast(Version{1, 17, 0}))
+	assert.False(t, v.AtLeast(Version{1, 18, 0}))
+	assert.Equal(t, "1.17.0", v.String())
+}
+
+func TestVersion_Supports(t *testing.T) {
+	tests := []struct {
+		version Version
+		feature Feature
+		want    bool
+	}{
+		{Version{1, 16, 0}, FeatureSAMLLogin, false},
+		{Version{1, 17, 0}, FeatureSAMLLogin, true},
+		{Version{1, 13, 3}, FeatureNoFTMPush, false},
+		{Version{1, 14, 0}, FeatureNoFTMPush, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version.String()+"/"+string(tt.feature), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.version.Supports(tt.feature))
+		})
+	}
+}
+
+func TestFeature_Flag(t *testing.T) {
+	assert.Equal(t, "--saml-login", FeatureSAMLLogin.Flag())
+	assert.Equal(t, "--no-ftm-push", FeatureNoFTMPush.Flag())
+}
+
+func TestUnsupportedFeatureError(t *testing.T) {
+	err := &UnsupportedFeatureError{Feature: FeatureSAMLLogin, Installed: Version{1, 15, 0}}
+	assert.Equal(t, "openfortivpn 1.15.0 does not support --saml-login (requires 1.17.0 or newer)", err.Error())
+}
+
+func TestVersionDetector_CachesResult(t *testing.T) {
+	calls := 0
+	detector := NewVersionDetector("/usr/bin/openfortivpn")
+	detector.run = func(_ context.Context, path string) ([]byte, error) {
+		calls++
+		assert.Equal(t, "/usr/bin/openfortivpn", path)
+		return []byte("1.21.0\n"), nil
+	}
+
+	for i := 0; i < 3; i++ {
+		v, err := detector.Detect(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, Version{1, 21, 0}, v)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestVersionDetector_RetriesAfterError(t *testing.T) {
+	calls := 0
+	detector := NewVersionDetector("/usr/bin/openfortivpn")
+	detector.run = func(_ context.Context, _ string) ([]byte, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("executable file not found")
+		}
+		return []byte("1.21.0\n"), nil
+	}
+
+	_, err := detector.Detect(context.Background())
+	require.Error(t, err)
+
+	// The binary was installed meanwhile
+	v, err := detector.Detect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Version{1, 21, 0}, v)
+	_, err = detector.Detect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+// newFixedVersionDetector returns a detector that reports the given version without running a command.
+func newFixedVersionDetector(output string) *VersionDetector {
+	detector := NewVersionDetector("/usr/bin/openfortivpn")
+	detector.run = func(_ context.Context, _ string) ([]byte, error) {
+		return []byte(output), nil
+	}
+	return detector
+}